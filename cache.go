@@ -0,0 +1,316 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nats
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nats-io/nats.go/subjects"
+)
+
+// Default response cache settings.
+const (
+	DefaultCacheTTL        = time.Minute
+	DefaultCacheMaxEntries = 10000
+)
+
+// ResponseCacheOpt configures a ResponseCache.
+type ResponseCacheOpt func(*responseCacheOpts) error
+
+type responseCacheOpts struct {
+	ttl           time.Duration
+	maxEntries    int
+	invalidations []string
+	kv            KeyValue
+}
+
+// ResponseCache caches responses to requests keyed by subject and payload.
+// Entries expire after a TTL and can be invalidated explicitly, by messages
+// published on invalidation subjects or by updates of a KV bucket.
+type ResponseCache struct {
+	nc      *Conn
+	opts    responseCacheOpts
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	order   *list.List // entries by expiration, oldest first, protected by mu
+	gen     uint64     // incremented on invalidation, protected by mu
+	subs    []*Subscription
+	watcher KeyWatcher
+	hits    uint64
+	misses  uint64
+	done    chan struct{}
+	stop    sync.Once
+	wg      sync.WaitGroup
+}
+
+type cacheEntry struct {
+	key     string
+	subject string
+	msg     *Msg
+	expires time.Time
+	elem    *list.Element
+}
+
+// CacheTTL sets how long responses are cached. Defaults to 1m.
+func CacheTTL(ttl time.Duration) ResponseCacheOpt {
+	return func(o *responseCacheOpts) error {
+		if ttl <= 0 {
+			return fmt.Errorf("%w: cache TTL must be positive", ErrInvalidArg)
+		}
+		o.ttl = ttl
+		return nil
+	}
+}
+
+// CacheMaxEntries sets the maximum number of cached responses. Defaults to 10000.
+func CacheMaxEntries(max int) ResponseCacheOpt {
+	return func(o *responseCacheOpts) error {
+		if max < 1 {
+			return fmt.Errorf("%w: cache max entries must be at least 1", ErrInvalidArg)
+		}
+		o.maxEntries = max
+		return nil
+	}
+}
+
+// CacheInvalidationSubject subscribes to a subject on which invalidations are
+// published. The payload of an invalidation is the request subject, possibly
+// containing wildcards, for which cached responses are dropped. An empty
+// payload drops all cached responses.
+func CacheInvalidationSubject(subject string) ResponseCacheOpt {
+	return func(o *responseCacheOpts) error {
+		if subject == _EMPTY_ {
+			return fmt.Errorf("%w: invalidation subject is required", ErrInvalidArg)
+		}
+		o.invalidations = append(o.invalidations, subject)
+		return nil
+	}
+}
+
+// CacheInvalidationKV watches a KV bucket for changes. An update or delete
+// of a key drops the cached responses for the request subject equal to the key.
+func CacheInvalidationKV(kv KeyValue) ResponseCacheOpt {
+	return func(o *responseCacheOpts) error {
+		o.kv = kv
+		return nil
+	}
+}
+
+// NewResponseCache creates a response cache for requests sent on this connection.
+func (nc *Conn) NewResponseCache(opts ...ResponseCacheOpt) (*ResponseCache, error) {
+	o := responseCacheOpts{
+		ttl:        DefaultCacheTTL,
+		maxEntries: DefaultCacheMaxEntries,
+	}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+	c := &ResponseCache{
+		nc:      nc,
+		opts:    o,
+		entries: make(map[string]*cacheEntry),
+		order:   list.New(),
+		done:    make(chan struct{}),
+	}
+	for _, subject := range o.invalidations {
+		sub, err := nc.Subscribe(subject, func(m *Msg) {
+			c.Invalidate(string(m.Data))
+		})
+		if err != nil {
+			c.Stop()
+			return nil, err
+		}
+		c.subs = append(c.subs, sub)
+	}
+	if o.kv != nil {
+		w, err := o.kv.WatchAll(MetaOnly())
+		if err != nil {
+			c.Stop()
+			return nil, err
+		}
+		c.watcher = w
+		c.wg.Add(1)
+		go c.watch(w)
+	}
+	return c, nil
+}
+
+func (c *ResponseCache) watch(w KeyWatcher) {
+	defer c.wg.Done()
+	initial := true
+	for {
+		select {
+		case entry, ok := <-w.Updates():
+			if !ok {
+				return
+			}
+			if entry == nil {
+				initial = false
+				continue
+			}
+			if !initial {
+				c.Invalidate(entry.Key())
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// Request sends a request, unless a response for the same subject and
+// payload is cached.
+func (c *ResponseCache) Request(subj string, data []byte, timeout time.Duration) (*Msg, error) {
+	key := cacheKey(subj, data)
+	m, gen := c.get(key)
+	if m != nil {
+		return m, nil
+	}
+	m, err := c.nc.Request(subj, data, timeout)
+	if err != nil {
+		return nil, err
+	}
+	c.put(key, subj, m, gen)
+	return m, nil
+}
+
+// RequestWithContext sends a request, unless a response for the same subject
+// and payload is cached.
+func (c *ResponseCache) RequestWithContext(ctx context.Context, subj string, data []byte) (*Msg, error) {
+	key := cacheKey(subj, data)
+	m, gen := c.get(key)
+	if m != nil {
+		return m, nil
+	}
+	m, err := c.nc.RequestWithContext(ctx, subj, data)
+	if err != nil {
+		return nil, err
+	}
+	c.put(key, subj, m, gen)
+	return m, nil
+}
+
+// Invalidate drops the cached responses for requests on subjects matching
+// the given subject, which may contain wildcards. An empty subject drops
+// all cached responses.
+func (c *ResponseCache) Invalidate(subject string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	if subject == _EMPTY_ {
+		c.entries = make(map[string]*cacheEntry)
+		c.order.Init()
+		return
+	}
+	for _, e := range c.entries {
+		if subjects.Match(subject, e.subject) {
+			c.remove(e)
+		}
+	}
+}
+
+// Len returns the number of cached responses.
+func (c *ResponseCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Hits returns the number of requests answered from the cache.
+func (c *ResponseCache) Hits() uint64 {
+	return atomic.LoadUint64(&c.hits)
+}
+
+// Misses returns the number of requests sent on the wire.
+func (c *ResponseCache) Misses() uint64 {
+	return atomic.LoadUint64(&c.misses)
+}
+
+// Stop removes the invalidation subscriptions and watcher.
+// Cached responses are still served until they expire.
+func (c *ResponseCache) Stop() error {
+	c.stop.Do(func() { close(c.done) })
+	c.wg.Wait()
+	var err error
+	for _, sub := range c.subs {
+		if uerr := sub.Unsubscribe(); uerr != nil && err == nil {
+			err = uerr
+		}
+	}
+	c.subs = nil
+	if c.watcher != nil {
+		if werr := c.watcher.Stop(); werr != nil && err == nil {
+			err = werr
+		}
+		c.watcher = nil
+	}
+	return err
+}
+
+// get returns a copy of the cached response, if any, and the current
+// invalidation generation.
+func (c *ResponseCache) get(key string) (*Msg, uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if ok && time.Now().Before(e.expires) {
+		atomic.AddUint64(&c.hits, 1)
+		return copyMsg(e.msg), c.gen
+	}
+	if ok {
+		c.remove(e)
+	}
+	atomic.AddUint64(&c.misses, 1)
+	return nil, c.gen
+}
+
+// put caches a response, unless an invalidation happened since the
+// request was sent.
+func (c *ResponseCache) put(key, subject string, m *Msg, gen uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.gen {
+		return
+	}
+	if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+	// All entries have the same TTL, so the first ones in order are
+	// the first to expire and are evicted when the cache is full.
+	for len(c.entries) >= c.opts.maxEntries {
+		c.remove(c.order.Front().Value.(*cacheEntry))
+	}
+	e := &cacheEntry{key: key, subject: subject, msg: copyMsg(m), expires: time.Now().Add(c.opts.ttl)}
+	e.elem = c.order.PushBack(e)
+	c.entries[key] = e
+}
+
+// remove drops a cached response. Lock should be held.
+func (c *ResponseCache) remove(e *cacheEntry) {
+	delete(c.entries, e.key)
+	c.order.Remove(e.elem)
+}
+
+func cacheKey(subj string, data []byte) string {
+	sum := sha256.Sum256(data)
+	return subj + " " + hex.EncodeToString(sum[:])
+}
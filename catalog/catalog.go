@@ -0,0 +1,425 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package catalog is a registry of subjects stored in a key value bucket.
+// Producers register the subjects they publish on together with their owner
+// and the schema of the payloads, and consumers discover the registered
+// subjects and validate the subjects they subscribe to against them:
+//
+//	cat, err := catalog.New(ctx, js)
+//	_, err = cat.Register(ctx, catalog.Entry{Subject: "orders.*.created", Owner: "orders", Schema: "orders.v1.Created"})
+//	err = cat.Validate(ctx, "orders.eu.created")
+package catalog
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/nats-io/nats.go/subjects"
+)
+
+// Notice: Experimental Preview
+//
+// This functionality is EXPERIMENTAL and may be changed in later releases.
+
+type (
+	// Catalog registers subjects in a key value bucket shared by all
+	// applications using the same bucket name.
+	Catalog struct {
+		kv jetstream.KeyValue
+	}
+
+	// Entry describes a registered subject.
+	Entry struct {
+		// Subject is the subject messages are published on. It can contain
+		// wildcards to register a whole subject space at once.
+		Subject string `json:"subject"`
+		// Owner identifies the team or application publishing on the subject.
+		// Only the owner can update or remove the entry, and subjects
+		// overlapping it can only be registered by the same owner.
+		Owner string `json:"owner"`
+		// Schema identifies the schema of the payloads, e.g. a type name, a
+		// URL or a schema document.
+		Schema string `json:"schema,omitempty"`
+		// Description describes the messages published on the subject.
+		Description string `json:"description,omitempty"`
+		// Metadata is additional information about the subject.
+		Metadata map[string]string `json:"metadata,omitempty"`
+
+		// Revision is the revision of the entry in the bucket, set when
+		// the entry is read from the catalog.
+		Revision uint64 `json:"-"`
+		// Updated is the time the entry was last registered, set when
+		// the entry is read from the catalog.
+		Updated time.Time `json:"-"`
+	}
+
+	// Update is a change of the catalog received by a [Watcher].
+	Update struct {
+		// Entry is the registered entry. Only its Subject and Revision are
+		// set if the entry was removed.
+		Entry Entry
+		// Removed is set if the entry was removed from the catalog.
+		Removed bool
+	}
+
+	// Watcher receives the changes of a catalog.
+	Watcher struct {
+		w        jetstream.KeyWatcher
+		updates  chan *Update
+		done     chan struct{}
+		stopOnce sync.Once
+	}
+
+	// Opt configures a [Catalog].
+	Opt func(*catalogOpts) error
+
+	catalogOpts struct {
+		bucket   string
+		replicas int
+	}
+)
+
+// DefaultBucket is the name of the key value bucket holding the catalog.
+const DefaultBucket = "SUBJECT_CATALOG"
+
+var (
+	// ErrInvalidEntry is returned when registering an entry without a valid
+	// subject or without an owner.
+	ErrInvalidEntry = errors.New("invalid catalog entry")
+
+	// ErrNotRegistered is returned when a subject is not covered by any
+	// entry of the catalog.
+	ErrNotRegistered = errors.New("subject not registered")
+
+	// ErrOwnerMismatch is returned when registering a subject overlapping
+	// a subject registered by another owner, or removing an entry of
+	// another owner.
+	ErrOwnerMismatch = errors.New("subject registered by another owner")
+
+	// ErrInvalidOption is returned when an option of [New] is invalid.
+	ErrInvalidOption = errors.New("invalid catalog option")
+)
+
+// WithBucket sets the name of the key value bucket holding the catalog.
+// Defaults to [DefaultBucket].
+func WithBucket(bucket string) Opt {
+	return func(opts *catalogOpts) error {
+		if bucket == "" {
+			return fmt.Errorf("%w: bucket name cannot be empty", ErrInvalidOption)
+		}
+		opts.bucket = bucket
+		return nil
+	}
+}
+
+// WithReplicas sets the number of replicas of the bucket if it is created.
+func WithReplicas(replicas int) Opt {
+	return func(opts *catalogOpts) error {
+		if replicas < 1 {
+			return fmt.Errorf("%w: replicas must be at least 1", ErrInvalidOption)
+		}
+		opts.replicas = replicas
+		return nil
+	}
+}
+
+// New returns the catalog stored in the bucket, creating the bucket if it
+// does not exist.
+func New(ctx context.Context, js jetstream.JetStream, opts ...Opt) (*Catalog, error) {
+	o := catalogOpts{bucket: DefaultBucket, replicas: 1}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+	kv, err := js.KeyValue(ctx, o.bucket)
+	if errors.Is(err, jetstream.ErrBucketNotFound) {
+		kv, err = js.CreateKeyValue(ctx, jetstream.KeyValueConfig{
+			Bucket:      o.bucket,
+			Description: "subject catalog",
+			History:     5,
+			Replicas:    o.replicas,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Catalog{kv: kv}, nil
+}
+
+// Register adds the entry to the catalog or updates it if the subject is
+// already registered by the same owner. It returns the revision of the
+// entry. Registering a subject overlapping a subject of another owner
+// fails with [ErrOwnerMismatch]. This includes subjects registered
+// concurrently: if both registrations are written before either checks the
+// other, both are rolled back and fail, so that they can be retried. Of
+// concurrent registrations of the same new subject, all but one fail with
+// [jetstream.ErrKeyExists].
+func (c *Catalog) Register(ctx context.Context, e Entry) (uint64, error) {
+	if err := subjects.Validate(e.Subject, true); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidEntry, err)
+	}
+	if e.Owner == "" {
+		return 0, fmt.Errorf("%w: owner is required", ErrInvalidEntry)
+	}
+	entries, err := c.Entries(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkOwner(e, entries); err != nil {
+		return 0, err
+	}
+	var revision uint64
+	for _, other := range entries {
+		if other.Subject == e.Subject {
+			revision = other.Revision
+		}
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return 0, err
+	}
+	// Updating with the read revision fails if the entry was changed
+	// concurrently, instead of overwriting the change. An update does not
+	// change the subjects of the owner, so it cannot conflict with others.
+	if revision > 0 {
+		return c.kv.Update(ctx, key(e.Subject), data, revision)
+	}
+	revision, err = c.kv.Create(ctx, key(e.Subject), data)
+	if err != nil {
+		return 0, err
+	}
+
+	// Overlapping subjects of other owners may have been created since the
+	// entries were read, so they are checked again now that the entry is
+	// visible to others.
+	entries, err = c.Entries(ctx)
+	if err == nil {
+		err = checkOwner(e, entries)
+	}
+	if err != nil {
+		if derr := c.kv.Delete(ctx, key(e.Subject), jetstream.LastRevision(revision)); derr != nil {
+			return 0, fmt.Errorf("%w; rolling back registration: %v", err, derr)
+		}
+		return 0, err
+	}
+	return revision, nil
+}
+
+// checkOwner returns an error if the subject of the entry overlaps a subject
+// of another owner.
+func checkOwner(e Entry, entries []Entry) error {
+	for _, other := range entries {
+		if other.Owner != e.Owner && subjects.Overlap(e.Subject, other.Subject) {
+			return fmt.Errorf("%w: %q overlaps %q of %q", ErrOwnerMismatch, e.Subject, other.Subject, other.Owner)
+		}
+	}
+	return nil
+}
+
+// Unregister removes the entry of the subject from the catalog. It fails
+// with [ErrNotRegistered] if the subject is not registered and with
+// [ErrOwnerMismatch] if it is registered by another owner.
+func (c *Catalog) Unregister(ctx context.Context, subject, owner string) error {
+	e, err := c.Get(ctx, subject)
+	if err != nil {
+		return err
+	}
+	if e.Owner != owner {
+		return fmt.Errorf("%w: %q is registered by %q", ErrOwnerMismatch, subject, e.Owner)
+	}
+	return c.kv.Delete(ctx, key(subject), jetstream.LastRevision(e.Revision))
+}
+
+// Get returns the entry registered for exactly the given subject.
+func (c *Catalog) Get(ctx context.Context, subject string) (*Entry, error) {
+	if err := subjects.Validate(subject, true); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotRegistered, err)
+	}
+	kve, err := c.kv.Get(ctx, key(subject))
+	if errors.Is(err, jetstream.ErrKeyNotFound) {
+		return nil, fmt.Errorf("%w: %q", ErrNotRegistered, subject)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decode(kve)
+}
+
+// Entries returns all entries of the catalog, ordered by subject.
+func (c *Catalog) Entries(ctx context.Context) ([]Entry, error) {
+	w, err := c.kv.WatchAll(ctx, jetstream.IgnoreDeletes())
+	if err != nil {
+		return nil, err
+	}
+	defer w.Stop()
+	var entries []Entry
+	for {
+		select {
+		case kve := <-w.Updates():
+			if kve == nil {
+				sort.Slice(entries, func(i, j int) bool { return entries[i].Subject < entries[j].Subject })
+				return entries, nil
+			}
+			e, err := decode(kve)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, *e)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Lookup returns the entries whose subjects match messages published on
+// subject, which can contain wildcards to look up a whole subject space.
+func (c *Catalog) Lookup(ctx context.Context, subject string) ([]Entry, error) {
+	entries, err := c.Entries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var matching []Entry
+	for _, e := range entries {
+		if subjects.Overlap(subject, e.Subject) {
+			matching = append(matching, e)
+		}
+	}
+	return matching, nil
+}
+
+// Validate checks that all messages matching the given filters are
+// published on registered subjects, i.e. that each filter is covered by the
+// subjects of the catalog. It returns an error wrapping [ErrNotRegistered]
+// for the first filter which is not.
+func (c *Catalog) Validate(ctx context.Context, filters ...string) error {
+	entries, err := c.Entries(ctx)
+	if err != nil {
+		return err
+	}
+	registered := make([]string, 0, len(entries))
+	for _, e := range entries {
+		registered = append(registered, e.Subject)
+	}
+	for _, filter := range filters {
+		if err := subjects.Validate(filter, true); err != nil {
+			return fmt.Errorf("%w: %v", ErrNotRegistered, err)
+		}
+		if !subjects.CoveredBy(filter, registered) {
+			return fmt.Errorf("%w: %q", ErrNotRegistered, filter)
+		}
+	}
+	return nil
+}
+
+// Watch watches the catalog for changes. All current entries are sent
+// first, followed by a nil update, and then each registered or removed
+// entry. The watcher is stopped when ctx is done.
+func (c *Catalog) Watch(ctx context.Context) (*Watcher, error) {
+	w, err := c.kv.WatchAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cw := &Watcher{w: w, updates: make(chan *Update, 64), done: make(chan struct{})}
+	go cw.forward()
+	go func() {
+		select {
+		case <-ctx.Done():
+			cw.Stop()
+		case <-cw.done:
+		}
+	}()
+	return cw, nil
+}
+
+// forward decodes the bucket updates, skipping values which are not
+// catalog entries and entries removed before the watcher was created.
+func (w *Watcher) forward() {
+	defer close(w.updates)
+	initDone := false
+	for kve := range w.w.Updates() {
+		var u *Update
+		switch {
+		case kve == nil:
+			initDone = true
+		case kve.Operation() != jetstream.KeyValuePut:
+			if !initDone {
+				continue
+			}
+			subject, err := subject(kve.Key())
+			if err != nil {
+				continue
+			}
+			u = &Update{Entry: Entry{Subject: subject, Revision: kve.Revision(), Updated: kve.Created()}, Removed: true}
+		default:
+			e, err := decode(kve)
+			if err != nil {
+				continue
+			}
+			u = &Update{Entry: *e}
+		}
+		select {
+		case w.updates <- u:
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// Updates returns the channel receiving the changes of the catalog.
+func (w *Watcher) Updates() <-chan *Update {
+	return w.updates
+}
+
+// Stop stops watching the catalog and closes the updates channel.
+func (w *Watcher) Stop() error {
+	var err error
+	w.stopOnce.Do(func() {
+		close(w.done)
+		err = w.w.Stop()
+	})
+	return err
+}
+
+// key returns the bucket key of a subject. Subjects are encoded, as they
+// can contain wildcards and characters which are not valid in keys.
+func key(subject string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(subject))
+}
+
+// subject returns the subject of a bucket key.
+func subject(key string) (string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(key)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func decode(kve jetstream.KeyValueEntry) (*Entry, error) {
+	var e Entry
+	if err := json.Unmarshal(kve.Value(), &e); err != nil {
+		return nil, fmt.Errorf("%w: key %q: %v", ErrInvalidEntry, kve.Key(), err)
+	}
+	e.Revision = kve.Revision()
+	e.Updated = kve.Created()
+	return &e, nil
+}
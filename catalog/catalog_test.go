@@ -0,0 +1,53 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package catalog
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+)
+
+func TestKey(t *testing.T) {
+	keyRe := regexp.MustCompile(`\A[-/_=a-zA-Z0-9]+\z`)
+	for _, subj := range []string{"orders", "orders.*.created", "orders.>", "héllo.wörld"} {
+		k := key(subj)
+		if !keyRe.MatchString(k) {
+			t.Fatalf("Invalid key %q for %q", k, subj)
+		}
+		decoded, err := subject(k)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if decoded != subj {
+			t.Fatalf("Expected subject %q; got %q", subj, decoded)
+		}
+	}
+	if _, err := subject("not base64!"); err == nil {
+		t.Fatalf("Expected error decoding invalid key")
+	}
+}
+
+func TestOptions(t *testing.T) {
+	opts := catalogOpts{}
+	if err := WithBucket("")(&opts); !errors.Is(err, ErrInvalidOption) {
+		t.Fatalf("Expected error: %v; got: %v", ErrInvalidOption, err)
+	}
+	if err := WithReplicas(0)(&opts); !errors.Is(err, ErrInvalidOption) {
+		t.Fatalf("Expected error: %v; got: %v", ErrInvalidOption, err)
+	}
+	if err := WithBucket("CATALOG")(&opts); err != nil || opts.bucket != "CATALOG" {
+		t.Fatalf("Unexpected result: %v, %+v", err, opts)
+	}
+}
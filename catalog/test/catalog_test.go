@@ -0,0 +1,266 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/catalog"
+	"github.com/nats-io/nats.go/internal/testutil"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+func TestCatalog(t *testing.T) {
+	s := testutil.RunBasicJetStreamServer(t)
+	defer testutil.ShutdownJSServerAndRemoveStorage(t, s)
+
+	nc, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cat, err := catalog.New(ctx, js)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if entries, err := cat.Entries(ctx); err != nil || len(entries) != 0 {
+		t.Fatalf("Expected empty catalog; got: %v, %v", entries, err)
+	}
+
+	if _, err := cat.Register(ctx, catalog.Entry{Subject: "orders..created", Owner: "orders"}); !errors.Is(err, catalog.ErrInvalidEntry) {
+		t.Fatalf("Expected error: %v; got: %v", catalog.ErrInvalidEntry, err)
+	}
+	if _, err := cat.Register(ctx, catalog.Entry{Subject: "orders.*.created"}); !errors.Is(err, catalog.ErrInvalidEntry) {
+		t.Fatalf("Expected error: %v; got: %v", catalog.ErrInvalidEntry, err)
+	}
+	if _, err := cat.Register(ctx, catalog.Entry{Subject: "orders.*.created", Owner: "orders", Schema: "orders.v1.Created"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := cat.Register(ctx, catalog.Entry{Subject: "payments.>", Owner: "payments", Schema: "payments.v1"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// overlapping subjects of another owner are rejected
+	if _, err := cat.Register(ctx, catalog.Entry{Subject: "orders.eu.*", Owner: "billing"}); !errors.Is(err, catalog.ErrOwnerMismatch) {
+		t.Fatalf("Expected error: %v; got: %v", catalog.ErrOwnerMismatch, err)
+	}
+	// the owner can update its entry
+	rev, err := cat.Register(ctx, catalog.Entry{Subject: "orders.*.created", Owner: "orders", Schema: "orders.v2.Created"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	e, err := cat.Get(ctx, "orders.*.created")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if e.Schema != "orders.v2.Created" || e.Owner != "orders" || e.Revision != rev {
+		t.Fatalf("Unexpected entry: %+v", e)
+	}
+	if _, err := cat.Get(ctx, "orders.eu.created"); !errors.Is(err, catalog.ErrNotRegistered) {
+		t.Fatalf("Expected error: %v; got: %v", catalog.ErrNotRegistered, err)
+	}
+
+	entries, err := cat.Lookup(ctx, "orders.eu.created")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Subject != "orders.*.created" {
+		t.Fatalf("Unexpected entries: %+v", entries)
+	}
+
+	if err := cat.Validate(ctx, "orders.eu.created", "payments.>"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, filter := range []string{"orders.>", "orders.eu.deleted", "users.*"} {
+		if err := cat.Validate(ctx, filter); !errors.Is(err, catalog.ErrNotRegistered) {
+			t.Fatalf("Expected error for %q: %v; got: %v", filter, catalog.ErrNotRegistered, err)
+		}
+	}
+
+	if err := cat.Unregister(ctx, "payments.>", "orders"); !errors.Is(err, catalog.ErrOwnerMismatch) {
+		t.Fatalf("Expected error: %v; got: %v", catalog.ErrOwnerMismatch, err)
+	}
+	if err := cat.Unregister(ctx, "payments.>", "payments"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := cat.Unregister(ctx, "payments.>", "payments"); !errors.Is(err, catalog.ErrNotRegistered) {
+		t.Fatalf("Expected error: %v; got: %v", catalog.ErrNotRegistered, err)
+	}
+	// once removed, the subject can be registered by another owner
+	if _, err := cat.Register(ctx, catalog.Entry{Subject: "payments.>", Owner: "billing"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	entries, err = cat.Entries(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Subject != "orders.*.created" || entries[1].Owner != "billing" {
+		t.Fatalf("Unexpected entries: %+v", entries)
+	}
+}
+
+func TestCatalogConcurrentRegister(t *testing.T) {
+	s := testutil.RunBasicJetStreamServer(t)
+	defer testutil.ShutdownJSServerAndRemoveStorage(t, s)
+
+	nc, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	cat, err := catalog.New(ctx, js)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Overlapping subjects of different owners are never both registered.
+	for i := 0; i < 20; i++ {
+		entries := []catalog.Entry{
+			{Subject: fmt.Sprintf("orders%d.*", i), Owner: "orders"},
+			{Subject: fmt.Sprintf("orders%d.eu", i), Owner: "billing"},
+		}
+		errs := make([]error, len(entries))
+		var wg sync.WaitGroup
+		for j, e := range entries {
+			wg.Add(1)
+			go func(j int, e catalog.Entry) {
+				defer wg.Done()
+				_, errs[j] = cat.Register(ctx, e)
+			}(j, e)
+		}
+		wg.Wait()
+		for _, err := range errs {
+			if err != nil && !errors.Is(err, catalog.ErrOwnerMismatch) {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+		if errs[0] == nil && errs[1] == nil {
+			t.Fatalf("Expected overlapping registrations not to both succeed")
+		}
+		registered, err := cat.Lookup(ctx, fmt.Sprintf("orders%d.>", i))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var succeeded int
+		for _, err := range errs {
+			if err == nil {
+				succeeded++
+			}
+		}
+		if len(registered) != succeeded {
+			t.Fatalf("Expected %d registered entries; got: %+v", succeeded, registered)
+		}
+	}
+}
+
+func TestCatalogWatch(t *testing.T) {
+	s := testutil.RunBasicJetStreamServer(t)
+	defer testutil.ShutdownJSServerAndRemoveStorage(t, s)
+
+	nc, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cat, err := catalog.New(ctx, js, catalog.WithBucket("CATALOG"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := cat.Register(ctx, catalog.Entry{Subject: "orders.created", Owner: "orders"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := cat.Register(ctx, catalog.Entry{Subject: "orders.deleted", Owner: "orders"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := cat.Unregister(ctx, "orders.deleted", "orders"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	watchCtx, watchCancel := context.WithCancel(ctx)
+	w, err := cat.Watch(watchCtx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	next := func() *catalog.Update {
+		t.Helper()
+		select {
+		case u, ok := <-w.Updates():
+			if !ok {
+				t.Fatalf("Updates channel closed")
+			}
+			return u
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Did not receive update")
+		}
+		return nil
+	}
+
+	// removed entries are not part of the initial values
+	if u := next(); u == nil || u.Entry.Subject != "orders.created" || u.Removed {
+		t.Fatalf("Unexpected update: %+v", u)
+	}
+	if u := next(); u != nil {
+		t.Fatalf("Expected initial values marker; got: %+v", u)
+	}
+
+	if _, err := cat.Register(ctx, catalog.Entry{Subject: "orders.shipped", Owner: "orders", Schema: "orders.v1.Shipped"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if u := next(); u == nil || u.Entry.Subject != "orders.shipped" || u.Entry.Schema != "orders.v1.Shipped" || u.Removed {
+		t.Fatalf("Unexpected update: %+v", u)
+	}
+	if err := cat.Unregister(ctx, "orders.created", "orders"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if u := next(); u == nil || u.Entry.Subject != "orders.created" || !u.Removed {
+		t.Fatalf("Unexpected update: %+v", u)
+	}
+
+	watchCancel()
+	select {
+	case _, ok := <-w.Updates():
+		if ok {
+			t.Fatalf("Expected updates channel to be closed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Updates channel not closed")
+	}
+}
@@ -0,0 +1,378 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cdc publishes database change data capture feeds to JetStream.
+// Changes are read from a pluggable [Source], such as a PostgreSQL logical
+// replication stream decoded with pgoutput or wal2json, and published in
+// order with message IDs derived from their log sequence number (LSN), so
+// that changes replayed after a restart are deduplicated by the stream.
+// The LSN of the last published change is checkpointed in a KV bucket.
+package cdc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Notice: Experimental Preview
+//
+// This functionality is EXPERIMENTAL and may be changed in later releases.
+
+type (
+	// Source is a feed of changes, e.g. a logical replication stream.
+	// Implementations wrap a database driver.
+	Source interface {
+		// Start starts streaming changes following the given LSN.
+		// Zero LSN starts from the oldest change available.
+		Start(ctx context.Context, from uint64) error
+
+		// Next blocks until the next change is available. LSNs of
+		// consecutive changes must be increasing.
+		Next(ctx context.Context) (*Change, error)
+
+		// Ack confirms that all changes up to the LSN were stored,
+		// allowing the database to release the log they were read from.
+		Ack(ctx context.Context, lsn uint64) error
+
+		// Close stops streaming changes.
+		Close() error
+	}
+
+	// Change is a single row change.
+	Change struct {
+		// LSN is the log sequence number of the change.
+		LSN uint64 `json:"lsn"`
+		// Op is the kind of change.
+		Op Op `json:"op"`
+		// Schema is the schema of the changed table.
+		Schema string `json:"schema"`
+		// Table is the name of the changed table.
+		Table string `json:"table"`
+		// Row holds the column values after an insert or update.
+		Row map[string]interface{} `json:"row,omitempty"`
+		// Old holds the identity column values before an update or delete.
+		Old map[string]interface{} `json:"old,omitempty"`
+	}
+
+	// Op is the kind of a change.
+	Op string
+
+	// Config is a configuration of a change feed replicator.
+	Config struct {
+		// Name identifies the replicator. It prefixes message IDs
+		// and is the key of the checkpoint in the Checkpoints bucket.
+		Name string
+
+		// Source is the feed changes are read from.
+		Source Source
+
+		// Checkpoints is the bucket the LSN of the last published change is stored in.
+		Checkpoints nats.KeyValue
+
+		// Subject is the pattern of the subjects changes are published on.
+		// "{schema}", "{table}" and "{op}" are replaced with the values of
+		// the change. Defaults to "cdc.{schema}.{table}".
+		Subject string
+
+		// CheckpointInterval is the minimum interval between checkpoints.
+		// Defaults to 1s.
+		CheckpointInterval time.Duration
+
+		// RetryWait is the delay before publishing a change is retried.
+		// Changes are retried until published, to preserve their order.
+		// Defaults to 1s.
+		RetryWait time.Duration
+
+		// ErrorHandler is invoked when publishing or checkpointing fails.
+		ErrorHandler func(error)
+	}
+
+	// Replicator exposes methods to operate on a running replicator.
+	Replicator interface {
+		// Checkpoint returns the last checkpointed LSN.
+		Checkpoint() uint64
+
+		// Stats returns replication statistics.
+		Stats() Stats
+
+		// Done is closed when the replicator stops, either after
+		// [Replicator.Stop] or when the source fails.
+		Done() <-chan struct{}
+
+		// Stop stops reading changes, checkpoints the last published
+		// change and closes the source. It returns the error which
+		// stopped the replicator, if any.
+		Stop() error
+	}
+
+	// Stats contains replication statistics.
+	Stats struct {
+		// Published is the number of changes published.
+		Published uint64 `json:"published"`
+		// Skipped is the number of changes skipped as already checkpointed.
+		Skipped uint64 `json:"skipped"`
+		// Errors is the number of errors handled by the replicator.
+		Errors uint64 `json:"errors"`
+	}
+
+	replicator struct {
+		js     jetstream.Publisher
+		cfg    Config
+		stats  Stats
+		cancel context.CancelFunc
+		done   chan struct{}
+		once   sync.Once
+
+		sync.Mutex
+		published    uint64
+		checkpointed uint64
+		err          error
+	}
+)
+
+// Change kinds.
+const (
+	OpInsert   Op = "insert"
+	OpUpdate   Op = "update"
+	OpDelete   Op = "delete"
+	OpTruncate Op = "truncate"
+)
+
+const (
+	DefaultSubject            = "cdc.{schema}.{table}"
+	DefaultCheckpointInterval = time.Second
+	DefaultRetryWait          = time.Second
+)
+
+var (
+	// ErrConfigValidation is returned when replicator configuration is invalid.
+	ErrConfigValidation = errors.New("validation")
+
+	// ErrInvalidLSN is returned when parsing a malformed LSN.
+	ErrInvalidLSN = errors.New("invalid LSN")
+)
+
+var validName = regexp.MustCompile(`\A[-_a-zA-Z0-9]+\z`)
+
+// Run reads the checkpoint, starts the source following it and publishes
+// changes in the background until [Replicator.Stop] is called.
+func Run(js jetstream.Publisher, config Config) (Replicator, error) {
+	if err := config.valid(); err != nil {
+		return nil, err
+	}
+	if config.Subject == "" {
+		config.Subject = DefaultSubject
+	}
+	if config.CheckpointInterval == 0 {
+		config.CheckpointInterval = DefaultCheckpointInterval
+	}
+	if config.RetryWait == 0 {
+		config.RetryWait = DefaultRetryWait
+	}
+
+	from, err := loadCheckpoint(config.Checkpoints, config.Name)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := config.Source.Start(ctx, from); err != nil {
+		cancel()
+		return nil, err
+	}
+	r := &replicator{
+		js:           js,
+		cfg:          config,
+		cancel:       cancel,
+		done:         make(chan struct{}),
+		published:    from,
+		checkpointed: from,
+	}
+	go r.run(ctx)
+	return r, nil
+}
+
+func (c Config) valid() error {
+	if !validName.MatchString(c.Name) {
+		return fmt.Errorf("%w: invalid name %q", ErrConfigValidation, c.Name)
+	}
+	if c.Source == nil {
+		return fmt.Errorf("%w: source is required", ErrConfigValidation)
+	}
+	if c.Checkpoints == nil {
+		return fmt.Errorf("%w: checkpoints bucket is required", ErrConfigValidation)
+	}
+	if c.CheckpointInterval < 0 {
+		return fmt.Errorf("%w: checkpoint interval cannot be negative", ErrConfigValidation)
+	}
+	if c.RetryWait < 0 {
+		return fmt.Errorf("%w: retry wait cannot be negative", ErrConfigValidation)
+	}
+	return nil
+}
+
+func loadCheckpoint(kv nats.KeyValue, name string) (uint64, error) {
+	entry, err := kv.Get(name)
+	if errors.Is(err, nats.ErrKeyNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return ParseLSN(string(entry.Value()))
+}
+
+// Subject returns the subject the change is published on for the given pattern.
+func (c *Change) Subject(pattern string) string {
+	return strings.NewReplacer(
+		"{schema}", c.Schema,
+		"{table}", c.Table,
+		"{op}", string(c.Op),
+	).Replace(pattern)
+}
+
+// MsgID returns the ID of the message the change is published in.
+func (c *Change) MsgID(name string) string {
+	return name + ":" + FormatLSN(c.LSN)
+}
+
+func (r *replicator) run(ctx context.Context) {
+	defer close(r.done)
+	lastCheckpoint := time.Now()
+	for {
+		change, err := r.cfg.Source.Next(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				r.Lock()
+				r.err = err
+				r.Unlock()
+			}
+			break
+		}
+		r.Lock()
+		published := r.published
+		r.Unlock()
+		if change.LSN <= published {
+			atomic.AddUint64(&r.stats.Skipped, 1)
+			continue
+		}
+		if err := r.publish(ctx, change); err != nil {
+			break
+		}
+		r.Lock()
+		r.published = change.LSN
+		r.Unlock()
+		atomic.AddUint64(&r.stats.Published, 1)
+		if time.Since(lastCheckpoint) >= r.cfg.CheckpointInterval {
+			r.checkpoint(ctx)
+			lastCheckpoint = time.Now()
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	r.checkpoint(ctx)
+}
+
+// publish publishes the change, retrying until it succeeds or the context is done.
+func (r *replicator) publish(ctx context.Context, change *Change) error {
+	data, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	subject := change.Subject(r.cfg.Subject)
+	for {
+		_, err := r.js.Publish(ctx, subject, data, jetstream.WithMsgID(change.MsgID(r.cfg.Name)))
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		r.handleError(fmt.Errorf("publishing change %s: %w", FormatLSN(change.LSN), err))
+		select {
+		case <-time.After(r.cfg.RetryWait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// checkpoint stores the LSN of the last published change and acknowledges it to the source.
+func (r *replicator) checkpoint(ctx context.Context) {
+	r.Lock()
+	lsn := r.published
+	checkpointed := r.checkpointed
+	r.Unlock()
+	if lsn == checkpointed {
+		return
+	}
+	if _, err := r.cfg.Checkpoints.PutString(r.cfg.Name, FormatLSN(lsn)); err != nil {
+		r.handleError(fmt.Errorf("storing checkpoint: %w", err))
+		return
+	}
+	r.Lock()
+	r.checkpointed = lsn
+	r.Unlock()
+	if err := r.cfg.Source.Ack(ctx, lsn); err != nil {
+		r.handleError(fmt.Errorf("acknowledging checkpoint: %w", err))
+	}
+}
+
+func (r *replicator) handleError(err error) {
+	atomic.AddUint64(&r.stats.Errors, 1)
+	if r.cfg.ErrorHandler != nil {
+		r.cfg.ErrorHandler(err)
+	}
+}
+
+func (r *replicator) Checkpoint() uint64 {
+	r.Lock()
+	defer r.Unlock()
+	return r.checkpointed
+}
+
+func (r *replicator) Stats() Stats {
+	return Stats{
+		Published: atomic.LoadUint64(&r.stats.Published),
+		Skipped:   atomic.LoadUint64(&r.stats.Skipped),
+		Errors:    atomic.LoadUint64(&r.stats.Errors),
+	}
+}
+
+func (r *replicator) Done() <-chan struct{} {
+	return r.done
+}
+
+func (r *replicator) Stop() error {
+	var closeErr error
+	r.once.Do(func() {
+		r.cancel()
+		<-r.done
+		closeErr = r.cfg.Source.Close()
+	})
+	r.Lock()
+	defer r.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	return closeErr
+}
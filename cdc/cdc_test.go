@@ -0,0 +1,82 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestLSN(t *testing.T) {
+	for _, s := range []string{"16/B374D848", "0/0", "FFFFFFFF/FFFFFFFF"} {
+		lsn, err := ParseLSN(s)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if FormatLSN(lsn) != s {
+			t.Fatalf("Expected %q; got: %q", s, FormatLSN(lsn))
+		}
+	}
+	if lsn, _ := ParseLSN("16/B374D848"); lsn != 0x16B374D848 {
+		t.Fatalf("Unexpected LSN: %X", lsn)
+	}
+	for _, s := range []string{"", "16", "16/", "G/1", "1/100000000"} {
+		if _, err := ParseLSN(s); !errors.Is(err, ErrInvalidLSN) {
+			t.Fatalf("Expected error: %v for %q; got: %v", ErrInvalidLSN, s, err)
+		}
+	}
+}
+
+func TestParseWal2JSON(t *testing.T) {
+	change, err := ParseWal2JSON([]byte(`{"action":"U","lsn":"0/1634C78","schema":"public","table":"users",`+
+		`"columns":[{"name":"id","type":"integer","value":1},{"name":"name","type":"text","value":"bob"}],`+
+		`"identity":[{"name":"id","type":"integer","value":1}]}`), 5)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := &Change{
+		LSN:    0x1634C78,
+		Op:     OpUpdate,
+		Schema: "public",
+		Table:  "users",
+		Row:    map[string]interface{}{"id": float64(1), "name": "bob"},
+		Old:    map[string]interface{}{"id": float64(1)},
+	}
+	if !reflect.DeepEqual(change, expected) {
+		t.Fatalf("Expected %+v; got: %+v", expected, change)
+	}
+	if subj := change.Subject("db.{schema}.{table}.{op}"); subj != "db.public.users.update" {
+		t.Fatalf("Unexpected subject: %q", subj)
+	}
+	if id := change.MsgID("orders"); id != "orders:0/1634C78" {
+		t.Fatalf("Unexpected msg ID: %q", id)
+	}
+
+	// The given LSN is used when the record has none.
+	change, err = ParseWal2JSON([]byte(`{"action":"D","schema":"public","table":"users","identity":[{"name":"id","value":2}]}`), 5)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if change.LSN != 5 || change.Op != OpDelete || change.Row != nil {
+		t.Fatalf("Unexpected change: %+v", change)
+	}
+
+	if change, err := ParseWal2JSON([]byte(`{"action":"B"}`), 5); change != nil || err != nil {
+		t.Fatalf("Expected no change; got: %+v, %v", change, err)
+	}
+	if _, err := ParseWal2JSON([]byte(`{"action":"X"}`), 5); err == nil {
+		t.Fatalf("Expected error for unknown action")
+	}
+}
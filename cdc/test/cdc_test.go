@@ -0,0 +1,185 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/cdc"
+	"github.com/nats-io/nats.go/internal/testutil"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// sliceSource replays a fixed list of changes following the start LSN.
+type sliceSource struct {
+	sync.Mutex
+	changes []*cdc.Change
+	next    int
+	acked   uint64
+}
+
+func (s *sliceSource) Start(_ context.Context, from uint64) error {
+	s.Lock()
+	defer s.Unlock()
+	s.next = 0
+	for s.next < len(s.changes) && s.changes[s.next].LSN <= from {
+		s.next++
+	}
+	return nil
+}
+
+func (s *sliceSource) Next(ctx context.Context) (*cdc.Change, error) {
+	s.Lock()
+	if s.next < len(s.changes) {
+		c := s.changes[s.next]
+		s.next++
+		s.Unlock()
+		return c, nil
+	}
+	s.Unlock()
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (s *sliceSource) Ack(_ context.Context, lsn uint64) error {
+	s.Lock()
+	defer s.Unlock()
+	s.acked = lsn
+	return nil
+}
+
+func (s *sliceSource) Close() error {
+	return nil
+}
+
+func TestReplicator(t *testing.T) {
+	srv := testutil.RunBasicJetStreamServer(t)
+	defer testutil.ShutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "CDC", Subjects: []string{"db.>"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	legacy, err := nc.JetStream()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	kv, err := legacy.CreateKeyValue(&nats.KeyValueConfig{Bucket: "CHECKPOINTS"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	src := &sliceSource{changes: []*cdc.Change{
+		{LSN: 10, Op: cdc.OpInsert, Schema: "public", Table: "users", Row: map[string]interface{}{"id": 1}},
+		{LSN: 20, Op: cdc.OpUpdate, Schema: "public", Table: "users", Row: map[string]interface{}{"id": 1}},
+		{LSN: 30, Op: cdc.OpInsert, Schema: "public", Table: "orders", Row: map[string]interface{}{"id": 7}},
+	}}
+	config := cdc.Config{
+		Name:        "pg",
+		Source:      src,
+		Checkpoints: kv,
+		Subject:     "db.{schema}.{table}.{op}",
+	}
+	if _, err := cdc.Run(js, cdc.Config{Name: "a.b", Source: src, Checkpoints: kv}); !errors.Is(err, cdc.ErrConfigValidation) {
+		t.Fatalf("Expected error: %v; got: %v", cdc.ErrConfigValidation, err)
+	}
+
+	r, err := cdc.Run(js, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	waitForMsgs := func(msgs uint64) {
+		t.Helper()
+		for {
+			info, err := s.Info(ctx)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if info.State.Msgs == msgs {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				t.Fatalf("Expected %d messages; got: %d", msgs, info.State.Msgs)
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+	waitForMsgs(3)
+	if err := r.Stop(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if r.Checkpoint() != 30 || src.acked != 30 {
+		t.Fatalf("Expected checkpoint 30; got: %d, acked: %d", r.Checkpoint(), src.acked)
+	}
+	entry, err := kv.Get("pg")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(entry.Value()) != "0/1E" {
+		t.Fatalf("Unexpected checkpoint: %s", entry.Value())
+	}
+
+	msg, err := s.GetMsg(ctx, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if msg.Subject != "db.public.users.update" {
+		t.Fatalf("Unexpected subject: %s", msg.Subject)
+	}
+	if id := msg.Header.Get(jetstream.MsgIDHeader); id != "pg:0/14" {
+		t.Fatalf("Unexpected msg ID: %s", id)
+	}
+	var change cdc.Change
+	if err := json.Unmarshal(msg.Data, &change); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if change.LSN != 20 || change.Op != cdc.OpUpdate {
+		t.Fatalf("Unexpected change: %+v", change)
+	}
+
+	// Restarting resumes after the checkpoint.
+	src.changes = append(src.changes, &cdc.Change{LSN: 40, Op: cdc.OpDelete, Schema: "public", Table: "orders"})
+	r, err = cdc.Run(js, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	waitForMsgs(4)
+	if err := r.Stop(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if stats := r.Stats(); stats.Published != 1 {
+		t.Fatalf("Expected 1 published change; got: %+v", stats)
+	}
+	if r.Checkpoint() != 40 {
+		t.Fatalf("Expected checkpoint 40; got: %d", r.Checkpoint())
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nats
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CoalescingRequester sends requests on a connection, making concurrent
+// identical requests, i.e. requests with the same subject, headers and
+// payload, share a single request on the wire and its response. It protects
+// hot lookup services from bursts of identical requests. Only requests made
+// through the requester are coalesced, other requests on the connection are
+// sent as usual.
+//
+// Requests to the JetStream API ("$JS." subjects) and requests carrying a
+// Nats-Msg-Id or Nats-Expected-* header are never coalesced. Requests
+// publishing to streams without such headers are, so the requester should
+// only be used for idempotent requests.
+//
+// The shared request carries the values of the context of the caller which
+// sent it, i.e. the trace headers set with TraceContext and the fail fast
+// behavior of FailFastContext, but not its deadline: it lasts until it is
+// answered or all callers stopped waiting for it.
+type CoalescingRequester struct {
+	nc       *Conn
+	mu       sync.Mutex
+	inflight map[string]*coalescedRequest
+}
+
+// coalescedRequest is a request on the wire shared by identical requests.
+type coalescedRequest struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int
+	msg     *Msg
+	err     error
+}
+
+// NewCoalescingRequester returns a CoalescingRequester sending requests on nc.
+func NewCoalescingRequester(nc *Conn) *CoalescingRequester {
+	return &CoalescingRequester{nc: nc, inflight: make(map[string]*coalescedRequest)}
+}
+
+// Request sends a request like Conn.Request, sharing it with concurrent
+// identical requests.
+func (r *CoalescingRequester) Request(subj string, data []byte, timeout time.Duration) (*Msg, error) {
+	return r.RequestMsg(&Msg{Subject: subj, Data: data}, timeout)
+}
+
+// RequestMsg sends a request like Conn.RequestMsg, sharing it with concurrent
+// identical requests.
+func (r *CoalescingRequester) RequestMsg(msg *Msg, timeout time.Duration) (*Msg, error) {
+	if r.nc == nil {
+		return nil, ErrInvalidConnection
+	}
+	if msg == nil {
+		return nil, ErrInvalidMsg
+	}
+	if !coalescable(msg) {
+		return r.nc.RequestMsg(msg, timeout)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	m, err := r.request(ctx, msg)
+	if err == context.DeadlineExceeded {
+		err = ErrTimeout
+	}
+	return m, err
+}
+
+// RequestWithContext sends a request like Conn.RequestWithContext, sharing
+// it with concurrent identical requests.
+func (r *CoalescingRequester) RequestWithContext(ctx context.Context, subj string, data []byte) (*Msg, error) {
+	return r.RequestMsgWithContext(ctx, &Msg{Subject: subj, Data: data})
+}
+
+// RequestMsgWithContext sends a request like Conn.RequestMsgWithContext,
+// sharing it with concurrent identical requests.
+func (r *CoalescingRequester) RequestMsgWithContext(ctx context.Context, msg *Msg) (*Msg, error) {
+	if ctx == nil {
+		return nil, ErrInvalidContext
+	}
+	if r.nc == nil {
+		return nil, ErrInvalidConnection
+	}
+	if msg == nil {
+		return nil, ErrInvalidMsg
+	}
+	if !coalescable(msg) {
+		return r.nc.RequestMsgWithContext(ctx, msg)
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err := r.nc.checkFailFast(ctx); err != nil {
+		return nil, err
+	}
+	return r.request(ctx, msg)
+}
+
+// coalescable reports whether a request can be shared with identical ones.
+// Requests whose every copy has to reach the server are not.
+func coalescable(msg *Msg) bool {
+	if strings.HasPrefix(msg.Subject, "$JS.") {
+		return false
+	}
+	for key := range msg.Header {
+		if strings.EqualFold(key, MsgIdHdr) || strings.HasPrefix(strings.ToLower(key), "nats-expected-") {
+			return false
+		}
+	}
+	return true
+}
+
+// request joins an identical request in flight or sends a new one.
+// The shared request is canceled once all callers stopped waiting for it.
+// Each caller gets its own copy of the response.
+func (r *CoalescingRequester) request(ctx context.Context, msg *Msg) (*Msg, error) {
+	hdr, err := msg.headerBytes()
+	if err != nil {
+		return nil, err
+	}
+	// Trace headers are not part of the key, identical requests
+	// made while handling different messages are still shared.
+	h := sha256.New()
+	h.Write(hdr)
+	h.Write([]byte{0})
+	h.Write(msg.Data)
+	key := msg.Subject + " " + hex.EncodeToString(h.Sum(nil))
+
+	r.mu.Lock()
+	req, ok := r.inflight[key]
+	if !ok {
+		if hdr, err = r.nc.headerWithTrace(ctx, msg.Header); err != nil {
+			r.mu.Unlock()
+			return nil, err
+		}
+		reqCtx, cancel := context.WithCancel(valuesContext{ctx})
+		req = &coalescedRequest{done: make(chan struct{}), cancel: cancel}
+		r.inflight[key] = req
+		subj, data := msg.Subject, msg.Data
+		go func() {
+			req.msg, req.err = r.nc.requestWithContext(reqCtx, subj, hdr, data)
+			req.cancel()
+			r.mu.Lock()
+			if r.inflight[key] == req {
+				delete(r.inflight, key)
+			}
+			r.mu.Unlock()
+			close(req.done)
+		}()
+	}
+	req.waiters++
+	r.mu.Unlock()
+
+	select {
+	case <-req.done:
+		if req.err != nil {
+			return nil, req.err
+		}
+		return copyMsg(req.msg), nil
+	case <-ctx.Done():
+		r.mu.Lock()
+		req.waiters--
+		if req.waiters == 0 {
+			req.cancel()
+			if r.inflight[key] == req {
+				delete(r.inflight, key)
+			}
+		}
+		r.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// valuesContext carries the values of a context, without its deadline
+// and cancellation.
+type valuesContext struct {
+	context.Context
+}
+
+func (valuesContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (valuesContext) Done() <-chan struct{}       { return nil }
+func (valuesContext) Err() error                  { return nil }
+
+func copyMsg(m *Msg) *Msg {
+	c := &Msg{
+		Subject: m.Subject,
+		Reply:   m.Reply,
+		Data:    append([]byte(nil), m.Data...),
+		Sub:     m.Sub,
+	}
+	if m.Header != nil {
+		c.Header = make(Header, len(m.Header))
+		for k, v := range m.Header {
+			c.Header[k] = append([]string(nil), v...)
+		}
+	}
+	return c
+}
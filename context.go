@@ -16,15 +16,154 @@ package nats
 import (
 	"context"
 	"reflect"
+	"strings"
 )
 
+type failFastKey struct{}
+
+// FailFastContext returns a context making publishes and requests using it
+// fail with ErrDisconnected if the connection is not connected at the time
+// of the call, instead of buffering them until reconnected.
+// See the FailFastOnDisconnect option to enable this for all calls.
+func FailFastContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, failFastKey{}, true)
+}
+
+type traceHeadersKey struct{}
+
+// TraceHeaders are the headers propagated by TraceContext: the W3C trace
+// context and the destination of server side message traces.
+var TraceHeaders = []string{"traceparent", "tracestate", "Nats-Trace-Dest"}
+
+// TraceContext returns a context carrying the TraceHeaders found in hdr,
+// matched ignoring case, e.g. the headers of a message being processed in a
+// message handler. Messages sent with the context using PublishMsgWithContext,
+// RequestWithContext or RequestMsgWithContext, including JetStream API
+// requests, carry these headers unless they set them already, so that server
+// side tracing can follow the flow from the message to the messages and API
+// calls sent while processing it. Headers are not added if the server does
+// not support them. Deriving ctx from the context
+// the message is processed with also makes the API requests inherit its
+// deadline. If hdr has none of the headers, ctx is returned unchanged.
+func TraceContext(ctx context.Context, hdr Header) context.Context {
+	var th Header
+	for key, values := range hdr {
+		if len(values) == 0 || !isTraceHeader(key) {
+			continue
+		}
+		if th == nil {
+			th = Header{}
+		}
+		th[key] = append([]string(nil), values...)
+	}
+	if th == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, traceHeadersKey{}, th)
+}
+
+// isTraceHeader reports whether key is one of the TraceHeaders, ignoring case.
+func isTraceHeader(key string) bool {
+	for _, th := range TraceHeaders {
+		if strings.EqualFold(key, th) {
+			return true
+		}
+	}
+	return false
+}
+
+// TraceHeadersFromContext returns a copy of the headers carried by a context
+// created with TraceContext, or nil if there are none.
+func TraceHeadersFromContext(ctx context.Context) Header {
+	th, _ := ctx.Value(traceHeadersKey{}).(Header)
+	if th == nil {
+		return nil
+	}
+	hdr := make(Header, len(th))
+	for key, values := range th {
+		hdr[key] = append([]string(nil), values...)
+	}
+	return hdr
+}
+
+// headerWithTrace returns the encoded header of a message sent with ctx,
+// adding the trace headers carried by ctx which hdr does not set.
+func (nc *Conn) headerWithTrace(ctx context.Context, hdr Header) ([]byte, error) {
+	if th, _ := ctx.Value(traceHeadersKey{}).(Header); th != nil && nc != nil && nc.HeadersSupported() {
+		merged := make(Header, len(hdr)+len(th))
+		for key, values := range hdr {
+			merged[key] = values
+		}
+		for key, values := range th {
+			if !hasHeaderFold(hdr, key) {
+				merged[key] = values
+			}
+		}
+		hdr = merged
+	}
+	m := Msg{Header: hdr}
+	return m.headerBytes()
+}
+
+// hasHeaderFold reports whether hdr sets key, ignoring case.
+func hasHeaderFold(hdr Header, key string) bool {
+	for k := range hdr {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkFailFast returns ErrDisconnected if the context was created with
+// FailFastContext and the connection is not connected.
+func (nc *Conn) checkFailFast(ctx context.Context) error {
+	if ff, _ := ctx.Value(failFastKey{}).(bool); !ff {
+		return nil
+	}
+	nc.mu.RLock()
+	defer nc.mu.RUnlock()
+	if !nc.isClosed() && !nc.isConnected() {
+		return ErrDisconnected
+	}
+	return nil
+}
+
+// PublishMsgWithContext publishes the Msg structure unless the context is
+// done. Use FailFastContext to fail instead of buffering while reconnecting.
+func (nc *Conn) PublishMsgWithContext(ctx context.Context, m *Msg) error {
+	if ctx == nil {
+		return ErrInvalidContext
+	}
+	if nc == nil {
+		return ErrInvalidConnection
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err := nc.checkFailFast(ctx); err != nil {
+		return err
+	}
+	if m == nil {
+		return ErrInvalidMsg
+	}
+	hdr, err := nc.headerWithTrace(ctx, m.Header)
+	if err != nil {
+		return err
+	}
+	return nc.publish(m.Subject, m.Reply, hdr, m.Data)
+}
+
 // RequestMsgWithContext takes a context, a subject and payload
 // in bytes and request expecting a single response.
 func (nc *Conn) RequestMsgWithContext(ctx context.Context, msg *Msg) (*Msg, error) {
 	if msg == nil {
 		return nil, ErrInvalidMsg
 	}
-	hdr, err := msg.headerBytes()
+	if ctx == nil {
+		return nil, ErrInvalidContext
+	}
+	hdr, err := nc.headerWithTrace(ctx, msg.Header)
 	if err != nil {
 		return nil, err
 	}
@@ -34,7 +173,14 @@ func (nc *Conn) RequestMsgWithContext(ctx context.Context, msg *Msg) (*Msg, erro
 // RequestWithContext takes a context, a subject and payload
 // in bytes and request expecting a single response.
 func (nc *Conn) RequestWithContext(ctx context.Context, subj string, data []byte) (*Msg, error) {
-	return nc.requestWithContext(ctx, subj, nil, data)
+	if ctx == nil {
+		return nil, ErrInvalidContext
+	}
+	hdr, err := nc.headerWithTrace(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return nc.requestWithContext(ctx, subj, hdr, data)
 }
 
 func (nc *Conn) requestWithContext(ctx context.Context, subj string, hdr, data []byte) (*Msg, error) {
@@ -49,7 +195,15 @@ func (nc *Conn) requestWithContext(ctx context.Context, subj string, hdr, data [
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
 	}
+	if err := nc.checkFailFast(ctx); err != nil {
+		return nil, err
+	}
+	m, err := nc.sendRequestWithContext(ctx, subj, hdr, data)
+	nc.recordRequest(subj, hdr, data, m, err)
+	return m, err
+}
 
+func (nc *Conn) sendRequestWithContext(ctx context.Context, subj string, hdr, data []byte) (*Msg, error) {
 	var m *Msg
 	var err error
 
@@ -214,6 +368,41 @@ func (nc *Conn) FlushWithContext(ctx context.Context) error {
 	return err
 }
 
+// SubscribeWithLifetime will express interest in the given subject until
+// the context is done, at which point the subscription is drained.
+func (nc *Conn) SubscribeWithLifetime(ctx context.Context, subj string, cb MsgHandler) (*Subscription, error) {
+	return nc.QueueSubscribeWithLifetime(ctx, subj, _EMPTY_, cb)
+}
+
+// QueueSubscribeWithLifetime creates an asynchronous queue subscriber on the
+// given subject, which is drained once the context is done.
+func (nc *Conn) QueueSubscribeWithLifetime(ctx context.Context, subj, queue string, cb MsgHandler) (*Subscription, error) {
+	if ctx == nil {
+		return nil, ErrInvalidContext
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	sub, err := nc.QueueSubscribe(subj, queue, cb)
+	if err != nil {
+		return nil, err
+	}
+	// Closed once the subscription's delivery Go routine exits, so that
+	// we do not wait for the context when unsubscribed otherwise.
+	done := make(chan struct{})
+	sub.mu.Lock()
+	sub.pDone = func() { close(done) }
+	sub.mu.Unlock()
+	go func() {
+		select {
+		case <-ctx.Done():
+			sub.Drain()
+		case <-done:
+		}
+	}()
+	return sub, nil
+}
+
 // RequestWithContext will create an Inbox and perform a Request
 // using the provided cancellation context with the Inbox reply
 // for the data v. A response will be decoded into the vPtr last parameter.
@@ -0,0 +1,307 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package contract implements consumer driven contract testing of services
+// over NATS. A [Recorder] captures the requests a client makes and the
+// responses it relies on into a contract file, which [Verify] replays
+// against the service implementation to check that it still honors them.
+package contract
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/subjects"
+)
+
+// Notice: Experimental Preview
+//
+// This functionality is EXPERIMENTAL and may be changed in later releases.
+
+type (
+	// Contract is a list of interactions a client expects from services.
+	Contract struct {
+		// Name identifies the contract, e.g. the name of the client.
+		Name         string        `json:"name"`
+		Interactions []Interaction `json:"interactions"`
+	}
+
+	// Interaction is a request and the response it was answered with.
+	Interaction struct {
+		Subject        string      `json:"subject"`
+		Header         nats.Header `json:"header,omitempty"`
+		Request        []byte      `json:"request,omitempty"`
+		ResponseHeader nats.Header `json:"response_header,omitempty"`
+		Response       []byte      `json:"response,omitempty"`
+		// Error is set if the request failed, e.g. with no responders.
+		Error string `json:"error,omitempty"`
+	}
+
+	// Recorder records the requests made with connections using [Recorder.Option].
+	Recorder struct {
+		sync.Mutex
+		subjects     []string
+		interactions []Interaction
+	}
+
+	// VerifyOpt configures [Verify].
+	VerifyOpt func(*verifyOpts) error
+
+	// PayloadMatcher reports whether the actual response payload satisfies
+	// the recorded one.
+	PayloadMatcher func(expected, actual []byte) bool
+
+	verifyOpts struct {
+		timeout time.Duration
+		matcher PayloadMatcher
+	}
+
+	// VerifyError lists the interactions a service did not honor.
+	VerifyError struct {
+		Contract   string
+		Mismatches []Mismatch
+	}
+
+	// Mismatch describes an interaction a service did not honor.
+	Mismatch struct {
+		Interaction Interaction
+		Reason      string
+	}
+)
+
+// DefaultVerifyTimeout is the default timeout of replayed requests.
+const DefaultVerifyTimeout = 2 * time.Second
+
+var (
+	// ErrContractBroken is wrapped by [VerifyError].
+	ErrContractBroken = errors.New("contract broken")
+
+	// ErrInvalidOption is returned when an invalid option is provided.
+	ErrInvalidOption = errors.New("invalid option")
+)
+
+// NewRecorder creates a [Recorder] recording requests on subjects matching
+// any of the given subjects, which can contain wildcards. If none are
+// given, all requests are recorded, including JetStream API requests.
+func NewRecorder(subjects ...string) *Recorder {
+	return &Recorder{subjects: subjects}
+}
+
+// Option returns the [nats.Option] making a connection report its requests
+// to the recorder. Requests are recorded after applying the redactor of the
+// connection, see [nats.TraceRedactor], so a redactor masking payloads of
+// recorded subjects makes their contracts fail verification.
+func (r *Recorder) Option() nats.Option {
+	return nats.RecordRequests(r.Record)
+}
+
+// Record records a request and its response. It is a [nats.RequestRecordHandler].
+func (r *Recorder) Record(req *nats.Msg, resp *nats.Msg, err error) {
+	if !r.matches(req.Subject) {
+		return
+	}
+	i := Interaction{
+		Subject: req.Subject,
+		Header:  cloneHeader(req.Header),
+		Request: append([]byte(nil), req.Data...),
+	}
+	if err != nil {
+		i.Error = err.Error()
+	} else {
+		i.ResponseHeader = cloneHeader(resp.Header)
+		i.Response = append([]byte(nil), resp.Data...)
+	}
+	r.Lock()
+	r.interactions = append(r.interactions, i)
+	r.Unlock()
+}
+
+// Contract returns the recorded interactions as a contract with the given name.
+func (r *Recorder) Contract(name string) *Contract {
+	r.Lock()
+	defer r.Unlock()
+	return &Contract{Name: name, Interactions: append([]Interaction(nil), r.interactions...)}
+}
+
+// Reset discards the recorded interactions.
+func (r *Recorder) Reset() {
+	r.Lock()
+	r.interactions = nil
+	r.Unlock()
+}
+
+// Save writes the recorded interactions to a contract file.
+func (r *Recorder) Save(name, path string) error {
+	return r.Contract(name).Save(path)
+}
+
+func (r *Recorder) matches(subject string) bool {
+	if len(r.subjects) == 0 {
+		return true
+	}
+	for _, s := range r.subjects {
+		if subjects.Match(s, subject) {
+			return true
+		}
+	}
+	return false
+}
+
+// Save writes the contract to a file as JSON.
+func (c *Contract) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Load reads a contract file written by [Contract.Save].
+func Load(path string) (*Contract, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Contract
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid contract file %q: %w", path, err)
+	}
+	return &c, nil
+}
+
+// WithVerifyTimeout sets the timeout of each replayed request.
+func WithVerifyTimeout(timeout time.Duration) VerifyOpt {
+	return func(opts *verifyOpts) error {
+		if timeout <= 0 {
+			return fmt.Errorf("%w: timeout must be positive", ErrInvalidOption)
+		}
+		opts.timeout = timeout
+		return nil
+	}
+}
+
+// WithPayloadMatcher sets the function comparing response payloads, e.g. to
+// ignore generated identifiers or timestamps. By default, JSON payloads
+// have to be equal ignoring formatting and key order, and other payloads
+// have to be identical.
+func WithPayloadMatcher(matcher PayloadMatcher) VerifyOpt {
+	return func(opts *verifyOpts) error {
+		if matcher == nil {
+			return fmt.Errorf("%w: payload matcher cannot be nil", ErrInvalidOption)
+		}
+		opts.matcher = matcher
+		return nil
+	}
+}
+
+// Verify replays the requests of the contract using the connection and
+// checks the responses against the recorded ones. Headers of recorded
+// responses have to be present with the same values, while additional
+// headers are ignored. Interactions which failed when recorded have to
+// fail with the same error. If any interaction is not honored, a
+// [*VerifyError] is returned.
+//
+// Available options:
+// [WithVerifyTimeout] - sets the timeout of each replayed request, default is 2s
+// [WithPayloadMatcher] - sets the function comparing response payloads
+func Verify(nc *nats.Conn, c *Contract, opts ...VerifyOpt) error {
+	o := verifyOpts{timeout: DefaultVerifyTimeout, matcher: MatchJSON}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return err
+		}
+	}
+	verr := &VerifyError{Contract: c.Name}
+	for _, i := range c.Interactions {
+		req := nats.NewMsg(i.Subject)
+		req.Data = i.Request
+		for k, v := range i.Header {
+			req.Header[k] = v
+		}
+		resp, err := nc.RequestMsg(req, o.timeout)
+		if reason := compare(i, resp, err, o.matcher); reason != "" {
+			verr.Mismatches = append(verr.Mismatches, Mismatch{Interaction: i, Reason: reason})
+		}
+	}
+	if len(verr.Mismatches) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// compare returns the reason a response does not match the interaction, or
+// an empty string if it does.
+func compare(i Interaction, resp *nats.Msg, err error, matcher PayloadMatcher) string {
+	if i.Error != "" {
+		if err == nil {
+			return fmt.Sprintf("expected error %q, got a response", i.Error)
+		}
+		if err.Error() != i.Error {
+			return fmt.Sprintf("expected error %q, got %q", i.Error, err)
+		}
+		return ""
+	}
+	if err != nil {
+		return fmt.Sprintf("request failed: %v", err)
+	}
+	for k, v := range i.ResponseHeader {
+		if got := resp.Header.Values(k); !reflect.DeepEqual(got, v) {
+			return fmt.Sprintf("expected header %s %q, got %q", k, v, got)
+		}
+	}
+	if !matcher(i.Response, resp.Data) {
+		return fmt.Sprintf("expected response %q, got %q", i.Response, resp.Data)
+	}
+	return ""
+}
+
+// MatchJSON is the default [PayloadMatcher]. JSON payloads have to be equal
+// ignoring formatting and key order, other payloads have to be identical.
+func MatchJSON(expected, actual []byte) bool {
+	var e, a interface{}
+	if json.Unmarshal(expected, &e) == nil && json.Unmarshal(actual, &a) == nil {
+		return reflect.DeepEqual(e, a)
+	}
+	return bytes.Equal(expected, actual)
+}
+
+func (e *VerifyError) Error() string {
+	reasons := make([]string, 0, len(e.Mismatches))
+	for _, m := range e.Mismatches {
+		reasons = append(reasons, fmt.Sprintf("%s: %s", m.Interaction.Subject, m.Reason))
+	}
+	return fmt.Sprintf("%v: %q: %s", ErrContractBroken, e.Contract, strings.Join(reasons, "; "))
+}
+
+func (e *VerifyError) Unwrap() error {
+	return ErrContractBroken
+}
+
+func cloneHeader(h nats.Header) nats.Header {
+	if len(h) == 0 {
+		return nil
+	}
+	c := nats.Header{}
+	for k, v := range h {
+		c[k] = append([]string(nil), v...)
+	}
+	return c
+}
@@ -0,0 +1,134 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	natsserver "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/contract"
+)
+
+func TestRecordAndVerify(t *testing.T) {
+	opts := natsserver.DefaultTestOptions
+	opts.Port = -1
+	s := natsserver.RunServer(&opts)
+	defer s.Shutdown()
+
+	// serve responds to user requests with the given payload
+	serve := func(t *testing.T, payload string, header nats.Header) *nats.Conn {
+		t.Helper()
+		nc, err := nats.Connect(s.ClientURL())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		_, err = nc.Subscribe("users.get", func(msg *nats.Msg) {
+			resp := nats.NewMsg(msg.Reply)
+			for k, v := range header {
+				resp.Header[k] = v
+			}
+			if msg.Header.Get("Tenant") != "acme" {
+				resp.Data = []byte(`{"error":"unknown tenant"}`)
+			} else {
+				resp.Data = []byte(payload)
+			}
+			msg.RespondMsg(resp)
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := nc.Flush(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return nc
+	}
+
+	svc := serve(t, `{"id":1,"name":"Jane"}`, nats.Header{"Content-Type": []string{"application/json"}})
+
+	recorder := contract.NewRecorder("users.>")
+	nc, err := nats.Connect(s.ClientURL(), recorder.Option())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	req := nats.NewMsg("users.get")
+	req.Header.Set("Tenant", "acme")
+	req.Data = []byte(`{"id":1}`)
+	if _, err := nc.RequestMsg(req, time.Second); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := nc.Request("users.delete", []byte(`{"id":1}`), time.Second); !errors.Is(err, nats.ErrNoResponders) {
+		t.Fatalf("Expected error: %v; got: %v", nats.ErrNoResponders, err)
+	}
+	// not matching the recorded subjects
+	if _, err := nc.Request("orders.get", nil, time.Second); !errors.Is(err, nats.ErrNoResponders) {
+		t.Fatalf("Expected error: %v; got: %v", nats.ErrNoResponders, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "contract.json")
+	if err := recorder.Save("users-client", path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	c, err := contract.Load(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if c.Name != "users-client" || len(c.Interactions) != 2 {
+		t.Fatalf("Unexpected contract: %+v", c)
+	}
+	if i := c.Interactions[0]; i.Subject != "users.get" || i.Header.Get("Tenant") != "acme" || string(i.Response) != `{"id":1,"name":"Jane"}` {
+		t.Fatalf("Unexpected interaction: %+v", i)
+	}
+	if i := c.Interactions[1]; i.Subject != "users.delete" || i.Error != nats.ErrNoResponders.Error() {
+		t.Fatalf("Unexpected interaction: %+v", i)
+	}
+
+	verifier, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer verifier.Close()
+	if err := contract.Verify(verifier, c); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	svc.Close()
+
+	// key order, formatting and additional headers do not matter
+	svc = serve(t, `{"name": "Jane", "id": 1}`, nats.Header{"Content-Type": []string{"application/json"}, "Version": []string{"2"}})
+	if err := contract.Verify(verifier, c); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	svc.Close()
+
+	svc = serve(t, `{"id":1,"full_name":"Jane"}`, nil)
+	defer svc.Close()
+	err = contract.Verify(verifier, c, contract.WithVerifyTimeout(time.Second))
+	if !errors.Is(err, contract.ErrContractBroken) {
+		t.Fatalf("Expected error: %v; got: %v", contract.ErrContractBroken, err)
+	}
+	var verr *contract.VerifyError
+	if !errors.As(err, &verr) || len(verr.Mismatches) != 1 || verr.Mismatches[0].Interaction.Subject != "users.get" {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// a custom matcher can relax payload checks
+	err = contract.Verify(verifier, c, contract.WithPayloadMatcher(func(expected, actual []byte) bool { return true }))
+	if !errors.As(err, &verr) || len(verr.Mismatches) != 1 {
+		t.Fatalf("Expected missing header mismatch; got: %v", err)
+	}
+}
@@ -0,0 +1,401 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cron publishes messages to JetStream on cron schedules. Any
+// number of instances can run the same jobs: the last fire time of every
+// job is stored in a KV bucket and updated with compare-and-set before
+// publishing, so that each scheduled fire is published by one instance.
+package cron
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/nats-io/nuid"
+)
+
+// Notice: Experimental Preview
+//
+// This functionality is EXPERIMENTAL and may be changed in later releases.
+
+type (
+	// Job is a message published on a schedule.
+	Job struct {
+		// Name identifies the job. It is the key of the job state in the
+		// Locks bucket and prefixes the IDs of published messages.
+		Name string
+
+		// Schedule is a cron expression, see [Parse].
+		Schedule string
+
+		// Subject is the subject the message is published on.
+		Subject string
+
+		// Data is the payload of the message.
+		Data []byte
+
+		// Header holds headers added to the message.
+		Header nats.Header
+
+		// MissedFire determines how fires missed while no instance was
+		// running are handled. Defaults to [MissedFireSkip].
+		MissedFire MissedFirePolicy
+	}
+
+	// MissedFirePolicy determines how missed fires of a job are handled.
+	MissedFirePolicy int
+
+	// Config is a configuration of a scheduler.
+	Config struct {
+		// Jobs are the scheduled jobs.
+		Jobs []Job
+
+		// Locks is the bucket the last fire time of every job is stored in.
+		// It has to be shared by all instances running the jobs.
+		Locks nats.KeyValue
+
+		// Instance identifies this scheduler in job statuses.
+		// Defaults to a unique ID.
+		Instance string
+
+		// Location is the time zone schedules are evaluated in.
+		// Defaults to UTC.
+		Location *time.Location
+
+		// PublishTimeout limits the time spent publishing a message.
+		// Defaults to 5s.
+		PublishTimeout time.Duration
+
+		// ErrorHandler is invoked when claiming a fire or publishing fails.
+		ErrorHandler func(job string, err error)
+	}
+
+	// Scheduler exposes methods to operate on a running scheduler.
+	Scheduler interface {
+		// Status returns the statuses of all jobs.
+		Status() []JobStatus
+
+		// Stop stops scheduling jobs and waits for fires in progress.
+		Stop()
+	}
+
+	// JobStatus describes the runs of a job.
+	JobStatus struct {
+		// Name is the name of the job.
+		Name string `json:"name"`
+		// LastRun is the scheduled time of the last fire, by any instance.
+		LastRun time.Time `json:"last_run,omitempty"`
+		// LastInstance is the instance which published the last fire.
+		LastInstance string `json:"last_instance,omitempty"`
+		// NextRun is the scheduled time of the next fire.
+		NextRun time.Time `json:"next_run,omitempty"`
+		// Fired is the number of fires published by this instance.
+		Fired uint64 `json:"fired"`
+		// Missed is the number of fires skipped by this instance as missed.
+		Missed uint64 `json:"missed"`
+		// LastError is the last error encountered by this instance, if any.
+		LastError string `json:"last_error,omitempty"`
+	}
+
+	// jobState is the value stored in the Locks bucket.
+	jobState struct {
+		LastRun  time.Time `json:"last_run"`
+		Instance string    `json:"instance"`
+	}
+
+	scheduler struct {
+		js     jetstream.Publisher
+		cfg    Config
+		cancel context.CancelFunc
+		wg     sync.WaitGroup
+		jobs   []*runner
+	}
+
+	runner struct {
+		s        *scheduler
+		job      Job
+		schedule Schedule
+
+		sync.Mutex
+		status JobStatus
+	}
+)
+
+const (
+	// MissedFireSkip skips missed fires, waiting for the next scheduled one.
+	MissedFireSkip MissedFirePolicy = iota
+	// MissedFireOnce publishes the latest missed fire once.
+	MissedFireOnce
+	// MissedFireAll publishes every missed fire, up to [MaxMissedFires].
+	MissedFireAll
+)
+
+const (
+	DefaultPublishTimeout = 5 * time.Second
+
+	// MaxMissedFires is the maximum number of missed fires published
+	// with [MissedFireAll]. Older missed fires are skipped.
+	MaxMissedFires = 100
+
+	// ScheduledTimeHeader holds the scheduled time of a fire, in RFC 3339 format.
+	ScheduledTimeHeader = "Nats-Cron-Scheduled-Time"
+)
+
+// missedFireGrace is how late a fire can be before it is considered missed.
+const missedFireGrace = time.Second
+
+var (
+	// ErrConfigValidation is returned when scheduler configuration is invalid.
+	ErrConfigValidation = errors.New("validation")
+
+	// ErrInvalidSchedule is returned when parsing an invalid cron expression.
+	ErrInvalidSchedule = errors.New("invalid schedule")
+)
+
+var validName = regexp.MustCompile(`\A[-_a-zA-Z0-9]+\z`)
+
+// Run starts scheduling the configured jobs in the background until
+// [Scheduler.Stop] is called.
+func Run(js jetstream.Publisher, config Config) (Scheduler, error) {
+	if err := config.valid(); err != nil {
+		return nil, err
+	}
+	if config.Instance == "" {
+		config.Instance = nuid.Next()
+	}
+	if config.Location == nil {
+		config.Location = time.UTC
+	}
+	if config.PublishTimeout == 0 {
+		config.PublishTimeout = DefaultPublishTimeout
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &scheduler{js: js, cfg: config, cancel: cancel}
+	for _, job := range config.Jobs {
+		schedule, err := Parse(job.Schedule)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("%w: job %q: %s", ErrConfigValidation, job.Name, err)
+		}
+		s.jobs = append(s.jobs, &runner{s: s, job: job, schedule: schedule, status: JobStatus{Name: job.Name}})
+	}
+	start := time.Now().In(config.Location)
+	for _, r := range s.jobs {
+		s.wg.Add(1)
+		go func(r *runner) {
+			defer s.wg.Done()
+			r.run(ctx, start)
+		}(r)
+	}
+	return s, nil
+}
+
+func (c Config) valid() error {
+	if len(c.Jobs) == 0 {
+		return fmt.Errorf("%w: at least one job is required", ErrConfigValidation)
+	}
+	if c.Locks == nil {
+		return fmt.Errorf("%w: locks bucket is required", ErrConfigValidation)
+	}
+	if c.PublishTimeout < 0 {
+		return fmt.Errorf("%w: publish timeout cannot be negative", ErrConfigValidation)
+	}
+	names := make(map[string]struct{}, len(c.Jobs))
+	for _, job := range c.Jobs {
+		if !validName.MatchString(job.Name) {
+			return fmt.Errorf("%w: invalid job name %q", ErrConfigValidation, job.Name)
+		}
+		if _, ok := names[job.Name]; ok {
+			return fmt.Errorf("%w: duplicate job name %q", ErrConfigValidation, job.Name)
+		}
+		names[job.Name] = struct{}{}
+		if job.Subject == "" {
+			return fmt.Errorf("%w: job %q: subject is required", ErrConfigValidation, job.Name)
+		}
+		if job.MissedFire < MissedFireSkip || job.MissedFire > MissedFireAll {
+			return fmt.Errorf("%w: job %q: invalid missed fire policy", ErrConfigValidation, job.Name)
+		}
+	}
+	return nil
+}
+
+func (s *scheduler) Status() []JobStatus {
+	statuses := make([]JobStatus, 0, len(s.jobs))
+	for _, r := range s.jobs {
+		r.Lock()
+		statuses = append(statuses, r.status)
+		r.Unlock()
+	}
+	return statuses
+}
+
+func (s *scheduler) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// run fires the job until the context is done. Every iteration reads the
+// job state, waits until the next fire and claims it by updating the state
+// with the revision it was read at, so that only one instance publishes it.
+func (r *runner) run(ctx context.Context, start time.Time) {
+	for ctx.Err() == nil {
+		state, revision, err := r.load()
+		if err != nil {
+			r.handleError(fmt.Errorf("loading job state: %w", err))
+			if !sleep(ctx, time.Second) {
+				return
+			}
+			continue
+		}
+		r.Lock()
+		r.status.LastRun = state.LastRun
+		r.status.LastInstance = state.Instance
+		r.Unlock()
+
+		due := r.nextFire(state.LastRun, start)
+		if due.IsZero() {
+			return
+		}
+		r.Lock()
+		r.status.NextRun = due
+		r.Unlock()
+		if !sleep(ctx, time.Until(due)) {
+			return
+		}
+		r.fire(ctx, due, revision)
+	}
+}
+
+// nextFire returns the time of the next fire following the last one,
+// applying the missed fire policy if it is already overdue.
+func (r *runner) nextFire(last, start time.Time) time.Time {
+	now := time.Now().In(r.s.cfg.Location)
+	if last.IsZero() {
+		// Jobs never fired before start with the first fire after startup.
+		last = start
+	}
+	due := r.schedule.Next(last.In(r.s.cfg.Location))
+	if due.IsZero() || !due.Before(now.Add(-missedFireGrace)) {
+		return due
+	}
+
+	// Collect the missed fires, up to the limit.
+	var missed []time.Time
+	var skipped uint64
+	for t := due; !t.IsZero() && !t.After(now); t = r.schedule.Next(t) {
+		if len(missed) == MaxMissedFires {
+			missed = missed[1:]
+			skipped++
+		}
+		missed = append(missed, t)
+	}
+	switch r.job.MissedFire {
+	case MissedFireOnce:
+		skipped += uint64(len(missed) - 1)
+		due = missed[len(missed)-1]
+	case MissedFireAll:
+		due = missed[0]
+	default:
+		skipped += uint64(len(missed))
+		due = r.schedule.Next(now)
+	}
+	r.Lock()
+	r.status.Missed += skipped
+	r.Unlock()
+	return due
+}
+
+func (r *runner) load() (jobState, uint64, error) {
+	var state jobState
+	entry, err := r.s.cfg.Locks.Get(r.job.Name)
+	if errors.Is(err, nats.ErrKeyNotFound) {
+		return state, 0, nil
+	}
+	if err != nil {
+		return state, 0, err
+	}
+	if err := json.Unmarshal(entry.Value(), &state); err != nil {
+		return state, 0, err
+	}
+	return state, entry.Revision(), nil
+}
+
+// fire claims the fire and publishes the message. Losing the claim to
+// another instance is not an error.
+func (r *runner) fire(ctx context.Context, due time.Time, revision uint64) {
+	data, err := json.Marshal(jobState{LastRun: due, Instance: r.s.cfg.Instance})
+	if err != nil {
+		r.handleError(err)
+		return
+	}
+	if revision == 0 {
+		_, err = r.s.cfg.Locks.Create(r.job.Name, data)
+	} else {
+		_, err = r.s.cfg.Locks.Update(r.job.Name, data, revision)
+	}
+	if err != nil {
+		if !errors.Is(err, nats.ErrKeyExists) {
+			r.handleError(fmt.Errorf("claiming fire: %w", err))
+		}
+		return
+	}
+
+	msg := nats.NewMsg(r.job.Subject)
+	msg.Data = r.job.Data
+	for k, v := range r.job.Header {
+		msg.Header[k] = v
+	}
+	scheduled := due.UTC().Format(time.RFC3339)
+	msg.Header.Set(ScheduledTimeHeader, scheduled)
+	pctx, cancel := context.WithTimeout(ctx, r.s.cfg.PublishTimeout)
+	defer cancel()
+	if _, err := r.s.js.PublishMsg(pctx, msg, jetstream.WithMsgID(r.job.Name+":"+scheduled)); err != nil {
+		r.handleError(fmt.Errorf("publishing fire %s: %w", scheduled, err))
+		return
+	}
+	r.Lock()
+	r.status.Fired++
+	r.Unlock()
+}
+
+func (r *runner) handleError(err error) {
+	r.Lock()
+	r.status.LastError = err.Error()
+	r.Unlock()
+	if r.s.cfg.ErrorHandler != nil {
+		r.s.cfg.ErrorHandler(r.job.Name, err)
+	}
+}
+
+// sleep waits for the duration, returning false if the context is done first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
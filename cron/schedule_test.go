@@ -0,0 +1,91 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cron
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	base := time.Date(2023, time.March, 15, 10, 30, 0, 0, time.UTC) // Wednesday
+	tests := []struct {
+		expr     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2023, time.March, 15, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2023, time.March, 15, 10, 45, 0, 0, time.UTC)},
+		{"0 9-17 * * mon-fri", time.Date(2023, time.March, 15, 11, 0, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2023, time.March, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2023, time.March, 19, 0, 0, 0, 0, time.UTC)},
+		{"5,10 8 1 * *", time.Date(2023, time.April, 1, 8, 5, 0, 0, time.UTC)},
+		{"0 0 1 jan *", time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		// Day of month or day of week match when both are restricted.
+		{"0 0 1 * fri", time.Date(2023, time.March, 17, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2023, time.March, 15, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2023, time.March, 16, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2023, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{"@every 20m", time.Date(2023, time.March, 15, 10, 40, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			s, err := Parse(test.expr)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if next := s.Next(base); !next.Equal(test.expected) {
+				t.Fatalf("Expected next fire at %s; got: %s", test.expected, next)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"* * * foo *",
+		"@every 10ms",
+		"@every soon",
+	} {
+		t.Run(expr, func(t *testing.T) {
+			if _, err := Parse(expr); !errors.Is(err, ErrInvalidSchedule) {
+				t.Fatalf("Expected error: %v; got: %v", ErrInvalidSchedule, err)
+			}
+		})
+	}
+}
+
+func TestScheduleLocation(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	s, err := Parse("0 9 * * *")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	next := s.Next(time.Date(2023, time.March, 15, 10, 0, 0, 0, loc))
+	expected := time.Date(2023, time.March, 16, 7, 0, 0, 0, time.UTC)
+	if !next.Equal(expected) {
+		t.Fatalf("Expected next fire at %s; got: %s", expected, next)
+	}
+}
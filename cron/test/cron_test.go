@@ -0,0 +1,240 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/cron"
+	"github.com/nats-io/nats.go/internal/testutil"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+func setup(t *testing.T, srv *server.Server) (*nats.Conn, jetstream.JetStream, jetstream.Stream, nats.KeyValue) {
+	t.Helper()
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "CRON", Subjects: []string{"cron.>"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	legacy, err := nc.JetStream()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	kv, err := legacy.CreateKeyValue(&nats.KeyValueConfig{Bucket: "CRON_LOCKS"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return nc, js, s, kv
+}
+
+func TestSchedulerSingleFire(t *testing.T) {
+	srv := testutil.RunBasicJetStreamServer(t)
+	defer testutil.ShutdownJSServerAndRemoveStorage(t, srv)
+	nc, js, s, kv := setup(t, srv)
+	defer nc.Close()
+
+	cfg := cron.Config{
+		Jobs: []cron.Job{{
+			Name:     "tick",
+			Schedule: "@every 1s",
+			Subject:  "cron.tick",
+			Data:     []byte("tick"),
+			Header:   nats.Header{"X-Job": []string{"tick"}},
+		}},
+		Locks: kv,
+	}
+	var schedulers []cron.Scheduler
+	for _, instance := range []string{"a", "b", "c"} {
+		cfg.Instance = instance
+		sched, err := cron.Run(js, cfg)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer sched.Stop()
+		schedulers = append(schedulers, sched)
+	}
+
+	time.Sleep(3500 * time.Millisecond)
+	for _, sched := range schedulers {
+		sched.Stop()
+	}
+
+	var fired uint64
+	for _, sched := range schedulers {
+		status := sched.Status()
+		if len(status) != 1 || status[0].Name != "tick" {
+			t.Fatalf("Unexpected status: %+v", status)
+		}
+		if status[0].LastError != "" {
+			t.Fatalf("Unexpected error: %s", status[0].LastError)
+		}
+		fired += status[0].Fired
+	}
+	if fired < 3 {
+		t.Fatalf("Expected at least 3 fires; got: %d", fired)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	info, err := s.Info(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info.State.Msgs != fired {
+		t.Fatalf("Expected %d messages; got: %d", fired, info.State.Msgs)
+	}
+	seen := make(map[string]struct{})
+	for seq := uint64(1); seq <= info.State.LastSeq; seq++ {
+		msg, err := s.GetMsg(ctx, seq)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		scheduled := msg.Header.Get(cron.ScheduledTimeHeader)
+		if _, ok := seen[scheduled]; ok {
+			t.Fatalf("Fire at %s published more than once", scheduled)
+		}
+		seen[scheduled] = struct{}{}
+		if string(msg.Data) != "tick" || msg.Header.Get("X-Job") != "tick" {
+			t.Fatalf("Unexpected message: %q %v", msg.Data, msg.Header)
+		}
+	}
+}
+
+func TestSchedulerMissedFires(t *testing.T) {
+	srv := testutil.RunBasicJetStreamServer(t)
+	defer testutil.ShutdownJSServerAndRemoveStorage(t, srv)
+	nc, js, s, kv := setup(t, srv)
+	defer nc.Close()
+
+	// Stay clear of the next scheduled fire while checking missed ones.
+	if now := time.Now(); now.Second() >= 55 {
+		time.Sleep(now.Truncate(time.Minute).Add(time.Minute + time.Second).Sub(now))
+	}
+	// The job last fired 10 minutes ago.
+	last := time.Now().UTC().Truncate(time.Minute).Add(-10 * time.Minute)
+	state, err := json.Marshal(map[string]interface{}{"last_run": last, "instance": "old"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, key := range []string{"skip", "once"} {
+		if _, err := kv.Put(key, state); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	sched, err := cron.Run(js, cron.Config{
+		Jobs: []cron.Job{
+			{Name: "skip", Schedule: "* * * * *", Subject: "cron.skip", MissedFire: cron.MissedFireSkip},
+			{Name: "once", Schedule: "* * * * *", Subject: "cron.once", MissedFire: cron.MissedFireOnce},
+		},
+		Locks:    kv,
+		Instance: "new",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer sched.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var msg *jetstream.RawStreamMsg
+	for {
+		msg, err = s.GetLastMsgForSubject(ctx, "cron.once")
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, jetstream.ErrMsgNotFound) {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("Missed fire was not published")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	scheduled, err := time.Parse(time.RFC3339, msg.Header.Get(cron.ScheduledTimeHeader))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !scheduled.After(last.Add(8 * time.Minute)) {
+		t.Fatalf("Expected the latest missed fire to be published; got: %s", scheduled)
+	}
+
+	// The fire is counted once the publish is acknowledged.
+	statuses := make(map[string]cron.JobStatus)
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		for _, status := range sched.Status() {
+			statuses[status.Name] = status
+		}
+		if statuses["once"].Fired > 0 {
+			break
+		}
+	}
+	if status := statuses["once"]; status.Fired != 1 || status.Missed < 8 || status.LastInstance != "new" {
+		t.Fatalf("Unexpected status: %+v", status)
+	}
+	status := statuses["skip"]
+	if status.Fired != 0 || status.Missed < 9 || status.LastInstance != "old" || !status.LastRun.Equal(last) {
+		t.Fatalf("Unexpected status: %+v", status)
+	}
+	if !status.NextRun.After(time.Now()) {
+		t.Fatalf("Expected next run in the future; got: %s", status.NextRun)
+	}
+	if _, err := s.GetLastMsgForSubject(ctx, "cron.skip"); !errors.Is(err, jetstream.ErrMsgNotFound) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrMsgNotFound, err)
+	}
+}
+
+func TestSchedulerConfigValidation(t *testing.T) {
+	srv := testutil.RunBasicJetStreamServer(t)
+	defer testutil.ShutdownJSServerAndRemoveStorage(t, srv)
+	nc, js, _, kv := setup(t, srv)
+	defer nc.Close()
+
+	job := cron.Job{Name: "job", Schedule: "@daily", Subject: "cron.job"}
+	tests := []struct {
+		name string
+		cfg  cron.Config
+	}{
+		{"no jobs", cron.Config{Locks: kv}},
+		{"no locks", cron.Config{Jobs: []cron.Job{job}}},
+		{"invalid name", cron.Config{Locks: kv, Jobs: []cron.Job{{Name: "a.b", Schedule: "@daily", Subject: "cron.job"}}}},
+		{"duplicate name", cron.Config{Locks: kv, Jobs: []cron.Job{job, job}}},
+		{"no subject", cron.Config{Locks: kv, Jobs: []cron.Job{{Name: "job", Schedule: "@daily"}}}},
+		{"invalid schedule", cron.Config{Locks: kv, Jobs: []cron.Job{{Name: "job", Schedule: "@often", Subject: "cron.job"}}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := cron.Run(js, test.cfg); !errors.Is(err, cron.ErrConfigValidation) {
+				t.Fatalf("Expected error: %v; got: %v", cron.ErrConfigValidation, err)
+			}
+		})
+	}
+}
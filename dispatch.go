@@ -0,0 +1,324 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nats
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/nats-io/nats.go/subjects"
+)
+
+// Dispatcher routes messages received on a single wildcard subscription to
+// many handlers registered for subject patterns, so that processes with
+// many fine grained subscriptions hold a single subscription on the server.
+// Alternatively, the dispatcher maintains the minimal set of subscriptions
+// covering the patterns of its handlers, see [Conn.NewAutoDispatcher].
+type Dispatcher struct {
+	nc        *Conn
+	subject   string // empty if subscriptions are computed from the patterns
+	subs      map[string]*Subscription
+	mu        sync.RWMutex
+	literals  map[string][]*DispatchHandler
+	wildcards []*DispatchHandler
+	unmatched uint64
+}
+
+// DispatchHandler is a handler registered on a [Dispatcher].
+type DispatchHandler struct {
+	d       *Dispatcher
+	pattern string
+	owner   string // subject of the subscription delivering to the handler
+	cb      MsgHandler
+	msgs    uint64
+	bytes   uint64
+}
+
+// DispatchStats contains the number of messages and payload bytes
+// delivered to a handler.
+type DispatchStats struct {
+	Msgs  uint64
+	Bytes uint64
+}
+
+// NewDispatcher creates a dispatcher subscribing to subject, which usually
+// contains wildcards. Handlers can only be registered for patterns matching
+// a subset of subject.
+func (nc *Conn) NewDispatcher(subject string) (*Dispatcher, error) {
+	d := &Dispatcher{
+		nc:       nc,
+		subject:  subject,
+		subs:     make(map[string]*Subscription),
+		literals: make(map[string][]*DispatchHandler),
+	}
+	sub, err := nc.Subscribe(subject, d.handler(subject))
+	if err != nil {
+		return nil, err
+	}
+	d.subs[subject] = sub
+	return d, nil
+}
+
+// NewAutoDispatcher creates a dispatcher which subscribes to the minimal set
+// of subjects covering the patterns of its handlers. Subscriptions are
+// updated as handlers are registered and removed, e.g. registering a handler
+// for "orders.>" replaces the subscriptions of handlers for "orders.*.created"
+// and "orders.eu.shipped".
+func (nc *Conn) NewAutoDispatcher() *Dispatcher {
+	return &Dispatcher{
+		nc:       nc,
+		subs:     make(map[string]*Subscription),
+		literals: make(map[string][]*DispatchHandler),
+	}
+}
+
+// Handle registers a handler for messages on subjects matching pattern.
+// A message matching several patterns is delivered to each of their
+// handlers, which share the same *Msg. Handlers are invoked sequentially
+// from the delivery goroutine of the dispatcher subscription.
+func (d *Dispatcher) Handle(pattern string, cb MsgHandler) (*DispatchHandler, error) {
+	if cb == nil {
+		return nil, ErrBadSubscription
+	}
+	if badSubject(pattern) {
+		return nil, ErrBadSubject
+	}
+	if d.subject != _EMPTY_ && !subjects.IsSubset(pattern, d.subject) {
+		return nil, fmt.Errorf("%w: pattern %q is not covered by dispatcher subject %q", ErrInvalidArg, pattern, d.subject)
+	}
+	h := &DispatchHandler{d: d, pattern: pattern, owner: d.subject, cb: cb}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.add(h)
+	if d.subject == _EMPTY_ {
+		if err := d.updateInterest(); err != nil {
+			d.remove(h)
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// Subjects returns the subjects the dispatcher is subscribed to.
+func (d *Dispatcher) Subjects() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	subjects := make([]string, 0, len(d.subs))
+	for subject := range d.subs {
+		subjects = append(subjects, subject)
+	}
+	sort.Strings(subjects)
+	return subjects
+}
+
+// Unmatched returns the number of messages received which did not match
+// any handler.
+func (d *Dispatcher) Unmatched() uint64 {
+	return atomic.LoadUint64(&d.unmatched)
+}
+
+// Close unsubscribes the dispatcher subscriptions.
+func (d *Dispatcher) Close() error {
+	return d.unsubscribe((*Subscription).Unsubscribe)
+}
+
+// Drain drains the dispatcher subscriptions, letting handlers process
+// pending messages.
+func (d *Dispatcher) Drain() error {
+	return d.unsubscribe((*Subscription).Drain)
+}
+
+func (d *Dispatcher) unsubscribe(fn func(*Subscription) error) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var err error
+	for subject, sub := range d.subs {
+		if uerr := fn(sub); uerr != nil && err == nil {
+			err = uerr
+		}
+		delete(d.subs, subject)
+	}
+	return err
+}
+
+// handler returns the callback of the subscription on subject.
+func (d *Dispatcher) handler(subject string) MsgHandler {
+	return func(m *Msg) {
+		d.dispatch(subject, m)
+	}
+}
+
+// dispatch delivers a message received on the subscription on subject to
+// the matching handlers owned by that subscription. While subscriptions
+// are being replaced, a message may be received on more than one.
+func (d *Dispatcher) dispatch(subject string, m *Msg) {
+	var matched bool
+	var handlers []*DispatchHandler
+	d.mu.RLock()
+	for _, h := range d.literals[m.Subject] {
+		matched = true
+		if h.owner == subject {
+			handlers = append(handlers, h)
+		}
+	}
+	for _, h := range d.wildcards {
+		if subjects.Match(h.pattern, m.Subject) {
+			matched = true
+			if h.owner == subject {
+				handlers = append(handlers, h)
+			}
+		}
+	}
+	d.mu.RUnlock()
+
+	if !matched {
+		atomic.AddUint64(&d.unmatched, 1)
+		return
+	}
+	for _, h := range handlers {
+		atomic.AddUint64(&h.msgs, 1)
+		atomic.AddUint64(&h.bytes, uint64(len(m.Data)))
+		h.cb(m)
+	}
+}
+
+// Pattern returns the subject pattern of the handler.
+func (h *DispatchHandler) Pattern() string {
+	return h.pattern
+}
+
+// Stats returns the number of messages and bytes delivered to the handler.
+func (h *DispatchHandler) Stats() DispatchStats {
+	return DispatchStats{
+		Msgs:  atomic.LoadUint64(&h.msgs),
+		Bytes: atomic.LoadUint64(&h.bytes),
+	}
+}
+
+// Remove unregisters the handler. It is not invoked for messages
+// dispatched afterwards.
+func (h *DispatchHandler) Remove() error {
+	d := h.d
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.remove(h)
+	if d.subject == _EMPTY_ {
+		return d.updateInterest()
+	}
+	return nil
+}
+
+// Lock should be held.
+func (d *Dispatcher) add(h *DispatchHandler) {
+	if subjects.HasWildcard(h.pattern) {
+		d.wildcards = append(d.wildcards, h)
+	} else {
+		d.literals[h.pattern] = append(d.literals[h.pattern], h)
+	}
+}
+
+// Lock should be held.
+func (d *Dispatcher) remove(h *DispatchHandler) {
+	if subjects.HasWildcard(h.pattern) {
+		d.wildcards = removeHandler(d.wildcards, h)
+		return
+	}
+	if hs := removeHandler(d.literals[h.pattern], h); len(hs) > 0 {
+		d.literals[h.pattern] = hs
+	} else {
+		delete(d.literals, h.pattern)
+	}
+}
+
+// updateInterest subscribes to the minimal set of subjects covering the
+// patterns of the handlers. New subscriptions are flushed before handlers
+// are moved to them and the replaced subscriptions are removed, so that no
+// messages are missed.
+// Lock should be held.
+func (d *Dispatcher) updateInterest() error {
+	patterns := make([]string, 0, len(d.literals)+len(d.wildcards))
+	for pattern := range d.literals {
+		patterns = append(patterns, pattern)
+	}
+	for _, h := range d.wildcards {
+		patterns = append(patterns, h.pattern)
+	}
+	cover := subjects.Covering(patterns)
+
+	var added []string
+	for _, subject := range cover {
+		if _, ok := d.subs[subject]; ok {
+			continue
+		}
+		sub, err := d.nc.Subscribe(subject, d.handler(subject))
+		if err != nil {
+			for _, subject := range added {
+				d.subs[subject].Unsubscribe()
+				delete(d.subs, subject)
+			}
+			return err
+		}
+		d.subs[subject] = sub
+		added = append(added, subject)
+	}
+	if len(added) > 0 {
+		// Make sure the server registered the new interest. On failure,
+		// e.g. while reconnecting, subscriptions are resent on reconnect.
+		d.nc.FlushTimeout(d.nc.timeout())
+	}
+
+	owner := func(pattern string) string {
+		for _, subject := range cover {
+			if subjects.IsSubset(pattern, subject) {
+				return subject
+			}
+		}
+		return _EMPTY_
+	}
+	for _, hs := range d.literals {
+		for _, h := range hs {
+			h.owner = owner(h.pattern)
+		}
+	}
+	for _, h := range d.wildcards {
+		h.owner = owner(h.pattern)
+	}
+
+	var err error
+	for subject, sub := range d.subs {
+		i := sort.SearchStrings(cover, subject)
+		if i < len(cover) && cover[i] == subject {
+			continue
+		}
+		if uerr := sub.Unsubscribe(); uerr != nil && err == nil {
+			err = uerr
+		}
+		delete(d.subs, subject)
+	}
+	return err
+}
+
+// removeHandler returns a copy of handlers without h, so that slices
+// grabbed by dispatch are not modified.
+func removeHandler(handlers []*DispatchHandler, h *DispatchHandler) []*DispatchHandler {
+	res := make([]*DispatchHandler, 0, len(handlers))
+	for _, dh := range handlers {
+		if dh != h {
+			res = append(res, dh)
+		}
+	}
+	return res
+}
@@ -206,9 +206,9 @@ func (c *EncodedConn) subscribe(subject, queue string, cb Handler) (*Subscriptio
 				oPtr = reflect.New(argType.Elem())
 			}
 			if err := c.Enc.Decode(m.Subject, m.Data, oPtr.Interface()); err != nil {
-				if c.Conn.Opts.AsyncErrorCB != nil {
+				if errCB := c.Conn.ErrorHandler(); errCB != nil {
 					c.Conn.ach.push(func() {
-						c.Conn.Opts.AsyncErrorCB(c.Conn, m.Sub, errors.New("nats: Got an error trying to unmarshal: "+err.Error()))
+						errCB(c.Conn, m.Sub, errors.New("nats: Got an error trying to unmarshal: "+err.Error()))
 					})
 				}
 				return
@@ -0,0 +1,537 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package httpbridge provides an HTTP handler delivering messages from NATS
+// subscriptions to clients which cannot use websockets, such as browsers
+// behind restrictive proxies. Clients register the subjects they are
+// interested in and then receive messages either by long polling or as
+// server-sent events.
+//
+// The handler serves the following routes, relative to where it is mounted:
+//
+//	POST   /clients?subject=...       register a client, responds with a [Client]
+//	GET    /clients/{id}/poll          long poll, responds with a [Batch]
+//	GET    /clients/{id}/events        server-sent event stream of [Message]s
+//	DELETE /clients/{id}               remove a client
+//
+// [NewStreamHandler] serves the messages of a JetStream stream as
+// server-sent events, resuming from the stream sequence of the last
+// event received.
+package httpbridge
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/subjects"
+	"github.com/nats-io/nuid"
+)
+
+// Notice: Experimental Preview
+//
+// This functionality is EXPERIMENTAL and may be changed in later releases.
+
+type (
+	// Config is a configuration of a bridge.
+	Config struct {
+		// Subjects lists the subjects clients are allowed to subscribe to.
+		// Wildcards are allowed, a client subject has to be a subset of
+		// one of them.
+		Subjects []string
+
+		// Authorize is invoked for every request with the subjects of the
+		// client. Returning an error rejects the request with 403.
+		Authorize func(r *http.Request, subjects []string) error
+
+		// QueueSize is the number of messages buffered for every client.
+		// Messages received when the queue is full are dropped.
+		// Defaults to 256.
+		QueueSize int
+
+		// MaxBatch is the maximum number of messages returned by a poll.
+		// Defaults to 100.
+		MaxBatch int
+
+		// PollTimeout is the maximum time a poll waits for messages.
+		// Clients can request shorter timeouts. Defaults to 30s.
+		PollTimeout time.Duration
+
+		// ClientTTL is the time after which clients which did not poll
+		// are removed. Defaults to 1m.
+		ClientTTL time.Duration
+
+		// MaxClients limits the number of registered clients.
+		// Unlimited by default.
+		MaxClients int
+
+		// ErrorHandler is invoked when a request is rejected.
+		ErrorHandler func(r *http.Request, err error)
+	}
+
+	// Bridge is an HTTP handler bridging clients to NATS subscriptions.
+	Bridge interface {
+		http.Handler
+
+		// Close removes all clients and unsubscribes from their subjects.
+		Close()
+	}
+
+	// Client is the JSON body of the response registering a client.
+	Client struct {
+		ID       string   `json:"id"`
+		Subjects []string `json:"subjects"`
+	}
+
+	// Batch is the JSON body of poll responses.
+	Batch struct {
+		Messages []Message `json:"messages"`
+		// Dropped is the number of messages dropped because the client
+		// queue was full since the previous poll.
+		Dropped uint64 `json:"dropped,omitempty"`
+	}
+
+	// Message is a message delivered to a client.
+	Message struct {
+		Subject string              `json:"subject"`
+		Reply   string              `json:"reply,omitempty"`
+		Header  map[string][]string `json:"headers,omitempty"`
+		Data    string              `json:"data"`
+		// Sequence and Time are set for messages read from a stream.
+		Sequence uint64     `json:"seq,omitempty"`
+		Time     *time.Time `json:"time,omitempty"`
+	}
+
+	bridge struct {
+		nc  *nats.Conn
+		cfg Config
+
+		sync.Mutex
+		clients map[string]*client
+		done    chan struct{}
+		closed  bool
+	}
+
+	client struct {
+		id       string
+		subjects []string
+		subs     []*nats.Subscription
+		msgs     chan *nats.Msg
+		dropped  uint64
+		seq      uint64
+		// done is closed once the client is removed.
+		done chan struct{}
+
+		sync.Mutex
+		lastSeen time.Time
+		active   int
+	}
+)
+
+const (
+	DefaultQueueSize   = 256
+	DefaultMaxBatch    = 100
+	DefaultPollTimeout = 30 * time.Second
+	DefaultClientTTL   = time.Minute
+)
+
+var (
+	// ErrConfigValidation is returned when bridge configuration is invalid.
+	ErrConfigValidation = errors.New("validation")
+
+	// ErrSubjectNotAllowed is passed to the error handler when a client
+	// requests a subject outside of the allow-list.
+	ErrSubjectNotAllowed = errors.New("subject not allowed")
+
+	// ErrClientNotFound is passed to the error handler when a request
+	// refers to an unknown or expired client.
+	ErrClientNotFound = errors.New("client not found")
+
+	// ErrTooManyClients is passed to the error handler when MaxClients is reached.
+	ErrTooManyClients = errors.New("too many clients")
+)
+
+// New returns a bridge delivering messages received on nc. Idle clients
+// are removed in the background until [Bridge.Close] is called.
+func New(nc *nats.Conn, config Config) (Bridge, error) {
+	if nc == nil {
+		return nil, fmt.Errorf("%w: connection is required", ErrConfigValidation)
+	}
+	if len(config.Subjects) == 0 {
+		return nil, fmt.Errorf("%w: at least one subject is required", ErrConfigValidation)
+	}
+	for _, subject := range config.Subjects {
+		if !subjects.Valid(subject, true) {
+			return nil, fmt.Errorf("%w: invalid subject %q", ErrConfigValidation, subject)
+		}
+	}
+	if config.QueueSize < 0 || config.MaxBatch < 0 || config.MaxClients < 0 {
+		return nil, fmt.Errorf("%w: limits cannot be negative", ErrConfigValidation)
+	}
+	if config.PollTimeout < 0 || config.ClientTTL < 0 {
+		return nil, fmt.Errorf("%w: timeouts cannot be negative", ErrConfigValidation)
+	}
+	if config.QueueSize == 0 {
+		config.QueueSize = DefaultQueueSize
+	}
+	if config.MaxBatch == 0 {
+		config.MaxBatch = DefaultMaxBatch
+	}
+	if config.PollTimeout == 0 {
+		config.PollTimeout = DefaultPollTimeout
+	}
+	if config.ClientTTL == 0 {
+		config.ClientTTL = DefaultClientTTL
+	}
+	b := &bridge{
+		nc:      nc,
+		cfg:     config,
+		clients: make(map[string]*client),
+		done:    make(chan struct{}),
+	}
+	go b.expireClients()
+	return b, nil
+}
+
+func (b *bridge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+	parts := strings.Split(path, "/")
+	if parts[0] != "clients" || len(parts) > 3 {
+		http.NotFound(w, r)
+		return
+	}
+	if len(parts) == 1 {
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w, http.MethodPost)
+			return
+		}
+		b.register(w, r)
+		return
+	}
+
+	c, err := b.client(parts[1])
+	if err != nil {
+		b.fail(w, r, http.StatusNotFound, err)
+		return
+	}
+	if b.cfg.Authorize != nil {
+		if err := b.cfg.Authorize(r, c.subjects); err != nil {
+			b.fail(w, r, http.StatusForbidden, err)
+			return
+		}
+	}
+	route := ""
+	if len(parts) == 3 {
+		route = parts[2]
+	}
+	switch {
+	case route == "" && r.Method == http.MethodDelete:
+		b.remove(c.id)
+		w.WriteHeader(http.StatusNoContent)
+	case route == "":
+		methodNotAllowed(w, http.MethodDelete)
+	case route != "poll" && route != "events":
+		http.NotFound(w, r)
+	case r.Method != http.MethodGet:
+		methodNotAllowed(w, http.MethodGet)
+	case route == "poll":
+		b.poll(w, r, c)
+	default:
+		b.events(w, r, c)
+	}
+}
+
+func (b *bridge) Close() {
+	b.Lock()
+	if b.closed {
+		b.Unlock()
+		return
+	}
+	b.closed = true
+	close(b.done)
+	clients := b.clients
+	b.clients = make(map[string]*client)
+	b.Unlock()
+	for _, c := range clients {
+		c.unsubscribe()
+	}
+}
+
+func (b *bridge) register(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		b.fail(w, r, http.StatusBadRequest, err)
+		return
+	}
+	subjects := r.Form["subject"]
+	if len(subjects) == 0 {
+		b.fail(w, r, http.StatusBadRequest, fmt.Errorf("%w: no subjects", ErrSubjectNotAllowed))
+		return
+	}
+	for _, subject := range subjects {
+		if !allowed(b.cfg.Subjects, subject) {
+			b.fail(w, r, http.StatusForbidden, fmt.Errorf("%w: %q", ErrSubjectNotAllowed, subject))
+			return
+		}
+	}
+	if b.cfg.Authorize != nil {
+		if err := b.cfg.Authorize(r, subjects); err != nil {
+			b.fail(w, r, http.StatusForbidden, err)
+			return
+		}
+	}
+
+	c := &client{
+		id:       nuid.Next(),
+		subjects: subjects,
+		msgs:     make(chan *nats.Msg, b.cfg.QueueSize),
+		done:     make(chan struct{}),
+		lastSeen: time.Now(),
+	}
+	for _, subject := range subjects {
+		sub, err := b.nc.Subscribe(subject, c.enqueue)
+		if err != nil {
+			c.unsubscribe()
+			b.fail(w, r, http.StatusServiceUnavailable, err)
+			return
+		}
+		c.subs = append(c.subs, sub)
+	}
+	b.Lock()
+	if b.closed || (b.cfg.MaxClients > 0 && len(b.clients) >= b.cfg.MaxClients) {
+		b.Unlock()
+		c.unsubscribe()
+		b.fail(w, r, http.StatusServiceUnavailable, ErrTooManyClients)
+		return
+	}
+	b.clients[c.id] = c
+	b.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(Client{ID: c.id, Subjects: subjects})
+}
+
+// poll responds with queued messages as soon as there are any, or with
+// an empty batch once the timeout elapses.
+func (b *bridge) poll(w http.ResponseWriter, r *http.Request, c *client) {
+	timeout := b.cfg.PollTimeout
+	if t := r.URL.Query().Get("timeout"); t != "" {
+		d, err := time.ParseDuration(t)
+		if err != nil {
+			b.fail(w, r, http.StatusBadRequest, err)
+			return
+		}
+		if d < timeout {
+			timeout = d
+		}
+	}
+	c.begin()
+	defer c.end()
+
+	batch := Batch{Messages: []Message{}}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case msg := <-c.msgs:
+		batch.Messages = append(batch.Messages, newMessage(msg))
+	drain:
+		for len(batch.Messages) < b.cfg.MaxBatch {
+			select {
+			case msg := <-c.msgs:
+				batch.Messages = append(batch.Messages, newMessage(msg))
+			default:
+				break drain
+			}
+		}
+	case <-timer.C:
+	case <-c.done:
+		b.fail(w, r, http.StatusNotFound, fmt.Errorf("%w: %q", ErrClientNotFound, c.id))
+		return
+	case <-r.Context().Done():
+		return
+	}
+	batch.Dropped = atomic.SwapUint64(&c.dropped, 0)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(batch)
+}
+
+// events streams queued messages as server-sent events until the request
+// is canceled or the client is removed.
+func (b *bridge) events(w http.ResponseWriter, r *http.Request, c *client) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		b.fail(w, r, http.StatusNotImplemented, errors.New("streaming not supported"))
+		return
+	}
+	c.begin()
+	defer c.end()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// Comments keep intermediaries from closing idle streams.
+	keepAlive := time.NewTicker(b.cfg.PollTimeout / 2)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case msg := <-c.msgs:
+			data, err := json.Marshal(newMessage(msg))
+			if err != nil {
+				continue
+			}
+			seq := atomic.AddUint64(&c.seq, 1)
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: message\ndata: %s\n\n", seq, data); err != nil {
+				return
+			}
+			if dropped := atomic.SwapUint64(&c.dropped, 0); dropped > 0 {
+				fmt.Fprintf(w, "event: dropped\ndata: %d\n\n", dropped)
+			}
+			flusher.Flush()
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ":\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (b *bridge) client(id string) (*client, error) {
+	b.Lock()
+	defer b.Unlock()
+	c, ok := b.clients[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrClientNotFound, id)
+	}
+	return c, nil
+}
+
+func (b *bridge) remove(id string) {
+	b.Lock()
+	c, ok := b.clients[id]
+	delete(b.clients, id)
+	b.Unlock()
+	if ok {
+		c.unsubscribe()
+	}
+}
+
+// expireClients removes clients which were not polled for ClientTTL.
+func (b *bridge) expireClients() {
+	ticker := time.NewTicker(b.cfg.ClientTTL / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-b.done:
+			return
+		}
+		var expired []*client
+		b.Lock()
+		for id, c := range b.clients {
+			if c.idle(b.cfg.ClientTTL) {
+				delete(b.clients, id)
+				expired = append(expired, c)
+			}
+		}
+		b.Unlock()
+		for _, c := range expired {
+			c.unsubscribe()
+		}
+	}
+}
+
+// allowed reports whether subject is a subset of one of the patterns.
+func allowed(patterns []string, subject string) bool {
+	if !subjects.Valid(subject, true) {
+		return false
+	}
+	for _, pattern := range patterns {
+		if subjects.IsSubset(subject, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *bridge) fail(w http.ResponseWriter, r *http.Request, code int, err error) {
+	if b.cfg.ErrorHandler != nil {
+		b.cfg.ErrorHandler(r, err)
+	}
+	http.Error(w, http.StatusText(code), code)
+}
+
+// enqueue queues a message for the client, dropping it if the queue is full.
+func (c *client) enqueue(msg *nats.Msg) {
+	select {
+	case c.msgs <- msg:
+	default:
+		atomic.AddUint64(&c.dropped, 1)
+	}
+}
+
+// unsubscribe is called once the client is removed.
+func (c *client) unsubscribe() {
+	for _, sub := range c.subs {
+		sub.Unsubscribe()
+	}
+	close(c.done)
+}
+
+// begin marks the client as active, preventing its expiry while a
+// request is in progress.
+func (c *client) begin() {
+	c.Lock()
+	c.active++
+	c.Unlock()
+}
+
+func (c *client) end() {
+	c.Lock()
+	c.active--
+	c.lastSeen = time.Now()
+	c.Unlock()
+}
+
+func (c *client) idle(ttl time.Duration) bool {
+	c.Lock()
+	defer c.Unlock()
+	return c.active == 0 && time.Since(c.lastSeen) > ttl
+}
+
+func newMessage(msg *nats.Msg) Message {
+	return Message{
+		Subject: msg.Subject,
+		Reply:   msg.Reply,
+		Header:  msg.Header,
+		Data:    string(msg.Data),
+	}
+}
+
+func methodNotAllowed(w http.ResponseWriter, allow string) {
+	w.Header().Set("Allow", allow)
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
@@ -0,0 +1,214 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpbridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/nats-io/nats.go/subjects"
+)
+
+type (
+	// StreamConfig is a configuration of a stream event handler.
+	StreamConfig struct {
+		// Stream is the name of the stream messages are read from.
+		Stream string
+
+		// Subjects lists the subjects clients are allowed to filter on.
+		// Wildcards are allowed, a client subject has to be a subset of
+		// one of them. If empty, clients can only read the whole stream.
+		Subjects []string
+
+		// Authorize is invoked for every request with the subjects the
+		// client filters on. Returning an error rejects the request with 403.
+		Authorize func(r *http.Request, subjects []string) error
+
+		// DeliverPolicy determines where clients which do not resume
+		// start reading. Only [jetstream.DeliverAllPolicy],
+		// [jetstream.DeliverLastPolicy] and [jetstream.DeliverNewPolicy]
+		// are supported. Defaults to [jetstream.DeliverAllPolicy].
+		DeliverPolicy jetstream.DeliverPolicy
+
+		// KeepAlive is the interval of comments sent on idle streams.
+		// Defaults to 15s.
+		KeepAlive time.Duration
+
+		// ErrorHandler is invoked when a request is rejected or reading
+		// from the stream fails.
+		ErrorHandler func(r *http.Request, err error)
+	}
+
+	streamHandler struct {
+		js  jetstream.StreamConsumerManager
+		cfg StreamConfig
+	}
+)
+
+const DefaultKeepAlive = 15 * time.Second
+
+// LastEventIDHeader is the header browsers send when reconnecting to an
+// event stream, holding the ID of the last received event.
+const LastEventIDHeader = "Last-Event-ID"
+
+// NewStreamHandler returns an HTTP handler streaming messages of a stream
+// as server-sent events, read using an ordered consumer created for every
+// request. Optional "subject" query parameters filter the messages.
+//
+// The ID of every event is the stream sequence of its message. Clients
+// resume after the last event they received by sending its ID in the
+// Last-Event-ID header, as browsers do when reconnecting, or in the
+// "last_event_id" query parameter.
+func NewStreamHandler(js jetstream.StreamConsumerManager, config StreamConfig) (http.Handler, error) {
+	if config.Stream == "" {
+		return nil, fmt.Errorf("%w: stream is required", ErrConfigValidation)
+	}
+	for _, subject := range config.Subjects {
+		if !subjects.Valid(subject, true) {
+			return nil, fmt.Errorf("%w: invalid subject %q", ErrConfigValidation, subject)
+		}
+	}
+	switch config.DeliverPolicy {
+	case jetstream.DeliverAllPolicy, jetstream.DeliverLastPolicy, jetstream.DeliverNewPolicy:
+	default:
+		return nil, fmt.Errorf("%w: unsupported deliver policy %s", ErrConfigValidation, config.DeliverPolicy)
+	}
+	if config.KeepAlive < 0 {
+		return nil, fmt.Errorf("%w: keep alive cannot be negative", ErrConfigValidation)
+	}
+	if config.KeepAlive == 0 {
+		config.KeepAlive = DefaultKeepAlive
+	}
+	return &streamHandler{js: js, cfg: config}, nil
+}
+
+func (h *streamHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		h.fail(w, r, http.StatusNotImplemented, errors.New("streaming not supported"))
+		return
+	}
+	query := r.URL.Query()
+	subjects := query["subject"]
+	for _, subject := range subjects {
+		if !allowed(h.cfg.Subjects, subject) {
+			h.fail(w, r, http.StatusForbidden, fmt.Errorf("%w: %q", ErrSubjectNotAllowed, subject))
+			return
+		}
+	}
+	if h.cfg.Authorize != nil {
+		if err := h.cfg.Authorize(r, subjects); err != nil {
+			h.fail(w, r, http.StatusForbidden, err)
+			return
+		}
+	}
+
+	cfg := jetstream.OrderedConsumerConfig{
+		FilterSubjects: subjects,
+		DeliverPolicy:  h.cfg.DeliverPolicy,
+	}
+	lastID := r.Header.Get(LastEventIDHeader)
+	if lastID == "" {
+		lastID = query.Get("last_event_id")
+	}
+	if lastID != "" {
+		seq, err := strconv.ParseUint(lastID, 10, 64)
+		if err != nil {
+			h.fail(w, r, http.StatusBadRequest, fmt.Errorf("invalid last event ID %q", lastID))
+			return
+		}
+		cfg.DeliverPolicy = jetstream.DeliverByStartSequencePolicy
+		cfg.OptStartSeq = seq + 1
+	}
+
+	ctx := r.Context()
+	cons, err := h.js.OrderedConsumer(ctx, h.cfg.Stream, cfg)
+	if err != nil {
+		code := http.StatusServiceUnavailable
+		if errors.Is(err, jetstream.ErrStreamNotFound) {
+			code = http.StatusNotFound
+		}
+		h.fail(w, r, code, err)
+		return
+	}
+	it, err := cons.Messages()
+	if err != nil {
+		h.fail(w, r, http.StatusServiceUnavailable, err)
+		return
+	}
+	defer it.Stop()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		nextCtx, cancel := context.WithTimeout(ctx, h.cfg.KeepAlive)
+		msg, err := it.NextWithContext(nextCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			// Comments keep intermediaries from closing idle streams.
+			if _, err := fmt.Fprint(w, ":\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+			continue
+		}
+		if err != nil {
+			if h.cfg.ErrorHandler != nil {
+				h.cfg.ErrorHandler(r, err)
+			}
+			return
+		}
+		meta, err := msg.Metadata()
+		if err != nil {
+			continue
+		}
+		data, err := json.Marshal(Message{
+			Subject:  msg.Subject(),
+			Header:   msg.Headers(),
+			Data:     string(msg.Data()),
+			Sequence: meta.Sequence.Stream,
+			Time:     &meta.Timestamp,
+		})
+		if err != nil {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: message\ndata: %s\n\n", meta.Sequence.Stream, data); err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+func (h *streamHandler) fail(w http.ResponseWriter, r *http.Request, code int, err error) {
+	if h.cfg.ErrorHandler != nil {
+		h.cfg.ErrorHandler(r, err)
+	}
+	http.Error(w, http.StatusText(code), code)
+}
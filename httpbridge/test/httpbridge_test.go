@@ -0,0 +1,293 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/httpbridge"
+	"github.com/nats-io/nats.go/internal/testutil"
+)
+
+func register(t *testing.T, srv *httptest.Server, subjects ...string) (httpbridge.Client, int) {
+	t.Helper()
+	resp, err := http.PostForm(srv.URL+"/clients", url.Values{"subject": subjects})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	var c httpbridge.Client
+	if resp.StatusCode == http.StatusCreated {
+		if err := json.NewDecoder(resp.Body).Decode(&c); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	return c, resp.StatusCode
+}
+
+func poll(t *testing.T, srv *httptest.Server, id, timeout string) (httpbridge.Batch, int) {
+	t.Helper()
+	resp, err := http.Get(srv.URL + "/clients/" + id + "/poll?timeout=" + timeout)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	var batch httpbridge.Batch
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&batch); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	return batch, resp.StatusCode
+}
+
+func TestBridgeLongPoll(t *testing.T) {
+	s := testutil.RunBasicServer()
+	defer s.Shutdown()
+	nc, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+
+	errDenied := errors.New("denied")
+	bridge, err := httpbridge.New(nc, httpbridge.Config{
+		Subjects:  []string{"events.>"},
+		QueueSize: 2,
+		Authorize: func(r *http.Request, subjects []string) error {
+			for _, subject := range subjects {
+				if strings.HasPrefix(subject, "events.admin") {
+					return errDenied
+				}
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer bridge.Close()
+	srv := httptest.NewServer(bridge)
+	defer srv.Close()
+
+	if _, code := register(t, srv, "events.admin.*"); code != http.StatusForbidden {
+		t.Fatalf("Expected status %d; got: %d", http.StatusForbidden, code)
+	}
+	if _, code := register(t, srv, "other.>"); code != http.StatusForbidden {
+		t.Fatalf("Expected status %d; got: %d", http.StatusForbidden, code)
+	}
+	if _, code := register(t, srv); code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d; got: %d", http.StatusBadRequest, code)
+	}
+
+	c, code := register(t, srv, "events.orders", "events.users.*")
+	if code != http.StatusCreated {
+		t.Fatalf("Expected status %d; got: %d", http.StatusCreated, code)
+	}
+	if _, code := poll(t, srv, "unknown", "1s"); code != http.StatusNotFound {
+		t.Fatalf("Expected status %d; got: %d", http.StatusNotFound, code)
+	}
+
+	// An empty batch is returned once the timeout elapses.
+	start := time.Now()
+	batch, code := poll(t, srv, c.ID, "100ms")
+	if code != http.StatusOK || len(batch.Messages) != 0 {
+		t.Fatalf("Expected empty batch; got: %d %+v", code, batch)
+	}
+	if time.Since(start) < 100*time.Millisecond {
+		t.Fatalf("Poll returned before the timeout")
+	}
+
+	// A waiting poll returns as soon as a message is received.
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		msg := nats.NewMsg("events.users.1")
+		msg.Header.Set("X-Event", "created")
+		msg.Data = []byte(`{"id":1}`)
+		nc.PublishMsg(msg)
+	}()
+	batch, code = poll(t, srv, c.ID, "5s")
+	if code != http.StatusOK || len(batch.Messages) != 1 {
+		t.Fatalf("Expected single message; got: %d %+v", code, batch)
+	}
+	if m := batch.Messages[0]; m.Subject != "events.users.1" || m.Data != `{"id":1}` || m.Header["X-Event"][0] != "created" {
+		t.Fatalf("Unexpected message: %+v", m)
+	}
+
+	// Messages overflowing the queue are dropped and reported.
+	for i := 0; i < 5; i++ {
+		nc.Publish("events.orders", []byte("order"))
+	}
+	nc.Publish("events.other", []byte("ignored"))
+	if err := nc.Flush(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	batch, _ = poll(t, srv, c.ID, "1s")
+	if len(batch.Messages) != 2 || batch.Dropped != 3 {
+		t.Fatalf("Expected 2 messages and 3 dropped; got: %+v", batch)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, srv.URL+"/clients/"+c.ID, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("Expected status %d; got: %d", http.StatusNoContent, resp.StatusCode)
+	}
+	if _, code := poll(t, srv, c.ID, "1s"); code != http.StatusNotFound {
+		t.Fatalf("Expected status %d; got: %d", http.StatusNotFound, code)
+	}
+}
+
+func TestBridgeEvents(t *testing.T) {
+	s := testutil.RunBasicServer()
+	defer s.Shutdown()
+	nc, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+
+	bridge, err := httpbridge.New(nc, httpbridge.Config{Subjects: []string{"events.*"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer bridge.Close()
+	srv := httptest.NewServer(bridge)
+	defer srv.Close()
+
+	c, code := register(t, srv, "events.*")
+	if code != http.StatusCreated {
+		t.Fatalf("Expected status %d; got: %d", http.StatusCreated, code)
+	}
+	resp, err := http.Get(srv.URL + "/clients/" + c.ID + "/events")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Unexpected content type: %s", ct)
+	}
+
+	for _, subject := range []string{"events.a", "events.b"} {
+		nc.Publish(subject, []byte(subject))
+	}
+	reader := bufio.NewReader(resp.Body)
+	for i, subject := range []string{"events.a", "events.b"} {
+		var id, data string
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			line = strings.TrimSuffix(line, "\n")
+			if line == "" && data != "" {
+				break
+			}
+			if strings.HasPrefix(line, "id: ") {
+				id = strings.TrimPrefix(line, "id: ")
+			}
+			if strings.HasPrefix(line, "data: ") {
+				data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+		var m httpbridge.Message
+		if err := json.Unmarshal([]byte(data), &m); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if m.Subject != subject || id != string(rune('1'+i)) {
+			t.Fatalf("Unexpected event %s: %+v", id, m)
+		}
+	}
+}
+
+func TestBridgeClientExpiry(t *testing.T) {
+	s := testutil.RunBasicServer()
+	defer s.Shutdown()
+	nc, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+
+	bridge, err := httpbridge.New(nc, httpbridge.Config{
+		Subjects:   []string{"events.*"},
+		ClientTTL:  100 * time.Millisecond,
+		MaxClients: 1,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer bridge.Close()
+	srv := httptest.NewServer(bridge)
+	defer srv.Close()
+
+	c, code := register(t, srv, "events.*")
+	if code != http.StatusCreated {
+		t.Fatalf("Expected status %d; got: %d", http.StatusCreated, code)
+	}
+	if _, code := register(t, srv, "events.*"); code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status %d; got: %d", http.StatusServiceUnavailable, code)
+	}
+	// A poll in progress keeps the client alive.
+	if _, code := poll(t, srv, c.ID, "200ms"); code != http.StatusOK {
+		t.Fatalf("Expected status %d; got: %d", http.StatusOK, code)
+	}
+	time.Sleep(300 * time.Millisecond)
+	if _, code := poll(t, srv, c.ID, "1s"); code != http.StatusNotFound {
+		t.Fatalf("Expected status %d; got: %d", http.StatusNotFound, code)
+	}
+	if subs := nc.NumSubscriptions(); subs != 0 {
+		t.Fatalf("Expected no subscriptions; got: %d", subs)
+	}
+	if _, code := register(t, srv, "events.*"); code != http.StatusCreated {
+		t.Fatalf("Expected status %d; got: %d", http.StatusCreated, code)
+	}
+}
+
+func TestBridgeConfigValidation(t *testing.T) {
+	s := testutil.RunBasicServer()
+	defer s.Shutdown()
+	nc, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+
+	for name, cfg := range map[string]httpbridge.Config{
+		"no subjects":       {},
+		"invalid subject":   {Subjects: []string{"events..x"}},
+		"negative queue":    {Subjects: []string{"events.*"}, QueueSize: -1},
+		"negative ttl":      {Subjects: []string{"events.*"}, ClientTTL: -time.Second},
+		"negative max poll": {Subjects: []string{"events.*"}, PollTimeout: -time.Second},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := httpbridge.New(nc, cfg); !errors.Is(err, httpbridge.ErrConfigValidation) {
+				t.Fatalf("Expected error: %v; got: %v", httpbridge.ErrConfigValidation, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/httpbridge"
+	"github.com/nats-io/nats.go/internal/testutil"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+type event struct {
+	id  string
+	msg httpbridge.Message
+}
+
+// readEvent reads the next message event, skipping comments.
+func readEvent(t *testing.T, r *bufio.Reader) event {
+	t.Helper()
+	var e event
+	var data string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" && data != "" {
+			break
+		}
+		if strings.HasPrefix(line, "id: ") {
+			e.id = strings.TrimPrefix(line, "id: ")
+		}
+		if strings.HasPrefix(line, "data: ") {
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	if err := json.Unmarshal([]byte(data), &e.msg); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return e
+}
+
+func TestStreamHandler(t *testing.T) {
+	srv := testutil.RunBasicJetStreamServer(t)
+	defer testutil.ShutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "EVENTS", Subjects: []string{"events.>"}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := 1; i <= 4; i++ {
+		subject := "events.orders"
+		if i%2 == 0 {
+			subject = "events.users"
+		}
+		if _, err := js.Publish(ctx, subject, []byte(fmt.Sprintf("%d", i))); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	handler, err := httpbridge.NewStreamHandler(js, httpbridge.StreamConfig{
+		Stream:    "EVENTS",
+		Subjects:  []string{"events.*"},
+		KeepAlive: 100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	hs := httptest.NewServer(handler)
+	defer hs.Close()
+
+	get := func(t *testing.T, path, lastID string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, hs.URL+path, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if lastID != "" {
+			req.Header.Set(httpbridge.LastEventIDHeader, lastID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return resp
+	}
+
+	t.Run("whole stream", func(t *testing.T) {
+		resp := get(t, "/", "")
+		defer resp.Body.Close()
+		if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("Unexpected content type: %s", ct)
+		}
+		r := bufio.NewReader(resp.Body)
+		for i := 1; i <= 4; i++ {
+			e := readEvent(t, r)
+			if e.id != fmt.Sprintf("%d", i) || e.msg.Sequence != uint64(i) || e.msg.Data != fmt.Sprintf("%d", i) {
+				t.Fatalf("Unexpected event %s: %+v", e.id, e.msg)
+			}
+		}
+		// Idle streams receive keep alive comments.
+		line, err := r.ReadString('\n')
+		if err != nil || line != ":\n" {
+			t.Fatalf("Expected keep alive comment; got: %q %v", line, err)
+		}
+		// New messages are streamed.
+		if _, err := js.Publish(ctx, "events.orders", []byte("5")); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if e := readEvent(t, r); e.id != "5" || e.msg.Subject != "events.orders" {
+			t.Fatalf("Unexpected event %s: %+v", e.id, e.msg)
+		}
+	})
+
+	t.Run("resume filtered", func(t *testing.T) {
+		resp := get(t, "/?subject=events.users", "2")
+		defer resp.Body.Close()
+		r := bufio.NewReader(resp.Body)
+		if e := readEvent(t, r); e.id != "4" || e.msg.Subject != "events.users" {
+			t.Fatalf("Unexpected event %s: %+v", e.id, e.msg)
+		}
+	})
+
+	t.Run("resume with query parameter", func(t *testing.T) {
+		resp := get(t, "/?last_event_id=4", "")
+		defer resp.Body.Close()
+		r := bufio.NewReader(resp.Body)
+		if e := readEvent(t, r); e.id != "5" {
+			t.Fatalf("Unexpected event %s: %+v", e.id, e.msg)
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		for path, code := range map[string]int{
+			"/?subject=events.users.1": http.StatusForbidden,
+			"/?subject=other":          http.StatusForbidden,
+			"/?last_event_id=x":        http.StatusBadRequest,
+		} {
+			resp := get(t, path, "")
+			resp.Body.Close()
+			if resp.StatusCode != code {
+				t.Fatalf("%s: expected status %d; got: %d", path, code, resp.StatusCode)
+			}
+		}
+	})
+
+	t.Run("config validation", func(t *testing.T) {
+		for name, cfg := range map[string]httpbridge.StreamConfig{
+			"no stream":       {},
+			"invalid subject": {Stream: "EVENTS", Subjects: []string{"events.>.x"}},
+			"deliver policy":  {Stream: "EVENTS", DeliverPolicy: jetstream.DeliverByStartSequencePolicy},
+		} {
+			if _, err := httpbridge.NewStreamHandler(js, cfg); !errors.Is(err, httpbridge.ErrConfigValidation) {
+				t.Fatalf("%s: expected error: %v; got: %v", name, httpbridge.ErrConfigValidation, err)
+			}
+		}
+	})
+}
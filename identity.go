@@ -0,0 +1,109 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nats
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ClientIdentity identifies the deployment a connection belongs to, so that
+// server-side monitoring can attribute traffic to it. It is sent as the
+// connection name and added to metadata of JetStream consumers and services
+// created using the connection.
+type ClientIdentity struct {
+	// Service is the name of the application. Required.
+	Service string
+	// Version is the version of the application.
+	Version string
+	// Instance identifies the running instance of the application,
+	// e.g. a pod name. Defaults to the host name.
+	Instance string
+}
+
+// Metadata keys holding the client identity.
+const (
+	IdentityServiceKey  = "client_service"
+	IdentityVersionKey  = "client_version"
+	IdentityInstanceKey = "client_instance"
+)
+
+// String returns the connection name of the identity, formatted as
+// "service@version/instance". Empty version and instance are omitted.
+func (id ClientIdentity) String() string {
+	var sb strings.Builder
+	sb.WriteString(id.Service)
+	if id.Version != _EMPTY_ {
+		sb.WriteString("@")
+		sb.WriteString(id.Version)
+	}
+	if id.Instance != _EMPTY_ {
+		sb.WriteString("/")
+		sb.WriteString(id.Instance)
+	}
+	return sb.String()
+}
+
+// Metadata returns the identity as metadata, omitting empty fields.
+func (id ClientIdentity) Metadata() map[string]string {
+	md := map[string]string{IdentityServiceKey: id.Service}
+	if id.Version != _EMPTY_ {
+		md[IdentityVersionKey] = id.Version
+	}
+	if id.Instance != _EMPTY_ {
+		md[IdentityInstanceKey] = id.Instance
+	}
+	return md
+}
+
+func (id ClientIdentity) validate() error {
+	if id.Service == _EMPTY_ {
+		return fmt.Errorf("nats: client identity service is required")
+	}
+	for _, field := range []string{id.Service, id.Version, id.Instance} {
+		if strings.ContainsAny(field, "@/ \t\r\n") {
+			return fmt.Errorf("nats: invalid client identity %q", field)
+		}
+	}
+	return nil
+}
+
+// Identity is an Option to set the identity of the client. The connection
+// name is derived from it, replacing a name set using [Name].
+// If the instance is not set, the host name is used.
+func Identity(id ClientIdentity) Option {
+	return func(o *Options) error {
+		if id.Instance == _EMPTY_ {
+			id.Instance, _ = os.Hostname()
+		}
+		if err := id.validate(); err != nil {
+			return err
+		}
+		o.Identity = &id
+		o.Name = id.String()
+		return nil
+	}
+}
+
+// Identity returns the identity of the client set using the [Identity]
+// option, or nil if not set.
+func (nc *Conn) Identity() *ClientIdentity {
+	if nc == nil {
+		return nil
+	}
+	nc.mu.RLock()
+	defer nc.mu.RUnlock()
+	return nc.Opts.Identity
+}
@@ -0,0 +1,106 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nats
+
+import (
+	"runtime/debug"
+	"time"
+)
+
+// HandlerOutcome is the outcome of a single message handler invocation.
+type HandlerOutcome int
+
+const (
+	// HandlerOK indicates the handler returned normally.
+	HandlerOK HandlerOutcome = iota
+	// HandlerPanicked indicates the handler panicked. The panic was recovered.
+	HandlerPanicked
+)
+
+func (o HandlerOutcome) String() string {
+	switch o {
+	case HandlerOK:
+		return "ok"
+	case HandlerPanicked:
+		return "panicked"
+	}
+	return "unknown"
+}
+
+// HandlerObservation describes a single message handler invocation.
+type HandlerObservation struct {
+	Subject     string
+	PayloadSize int
+	Latency     time.Duration
+	Outcome     HandlerOutcome
+	// Panic holds the recovered value if the handler panicked.
+	Panic interface{}
+}
+
+// HandlerMetrics receives an observation after each instrumented handler invocation.
+// Implementations are called from the delivery goroutines and have to be safe
+// for concurrent use.
+type HandlerMetrics interface {
+	ObserveHandler(HandlerObservation)
+}
+
+// HandlerMetricsFunc is an adapter allowing the use of a function as [HandlerMetrics].
+type HandlerMetricsFunc func(HandlerObservation)
+
+// ObserveHandler calls f(o).
+func (f HandlerMetricsFunc) ObserveHandler(o HandlerObservation) {
+	f(o)
+}
+
+// HandlerLogger is used by instrumented handlers to report recovered panics.
+// It is satisfied by *log.Logger.
+type HandlerLogger interface {
+	Printf(format string, v ...interface{})
+}
+
+// InstrumentHandler wraps a message handler so that the latency, payload size
+// and outcome of each invocation are reported to metrics. Panics in the handler
+// are recovered and logged along with the stack trace, so a faulty message does
+// not bring down the application. Either metrics or logger may be nil.
+func InstrumentHandler(h MsgHandler, metrics HandlerMetrics, logger HandlerLogger) MsgHandler {
+	return func(m *Msg) {
+		RunInstrumented(m.Subject, len(m.Data), func() { h(m) }, metrics, logger)
+	}
+}
+
+// RunInstrumented invokes fn handling a message received on subject with the
+// given payload size, the same way handlers wrapped with [InstrumentHandler] are.
+// It allows instrumenting handlers of other signatures, such as JetStream consume callbacks.
+func RunInstrumented(subject string, size int, fn func(), metrics HandlerMetrics, logger HandlerLogger) {
+	start := time.Now()
+	defer func() {
+		obs := HandlerObservation{
+			Subject:     subject,
+			PayloadSize: size,
+			Latency:     time.Since(start),
+			Outcome:     HandlerOK,
+		}
+		if r := recover(); r != nil {
+			obs.Outcome = HandlerPanicked
+			obs.Panic = r
+			if logger != nil {
+				logger.Printf("nats: recovered panic in handler for subject %q: %v\n%s", subject, r, debug.Stack())
+			}
+		}
+		if metrics != nil {
+			metrics.ObserveHandler(obs)
+		}
+	}()
+	fn()
+}
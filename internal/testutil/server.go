@@ -0,0 +1,60 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package testutil holds the server fixtures shared by the test packages
+// of this module. Like the tests themselves, it is built with go_test.mod.
+package testutil
+
+import (
+	"os"
+	"testing"
+
+	"github.com/nats-io/nats-server/v2/server"
+	natsserver "github.com/nats-io/nats-server/v2/test"
+)
+
+// RunBasicServer starts a server on a random port.
+func RunBasicServer() *server.Server {
+	opts := natsserver.DefaultTestOptions
+	opts.Port = -1
+	return natsserver.RunServer(&opts)
+}
+
+// RunBasicJetStreamServer starts a JetStream enabled server on a random port.
+// The storage directory is private to the test, since test packages run in
+// parallel and each removes its server's storage when shutting down.
+func RunBasicJetStreamServer(t testing.TB) *server.Server {
+	t.Helper()
+	opts := natsserver.DefaultTestOptions
+	opts.Port = -1
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	return natsserver.RunServer(&opts)
+}
+
+// ShutdownJSServerAndRemoveStorage shuts the server down and removes its
+// JetStream storage directory.
+func ShutdownJSServerAndRemoveStorage(t testing.TB, s *server.Server) {
+	t.Helper()
+	var sd string
+	if config := s.JetStreamConfig(); config != nil {
+		sd = config.StoreDir
+	}
+	s.Shutdown()
+	if sd != "" {
+		if err := os.RemoveAll(sd); err != nil {
+			t.Fatalf("Unable to remove storage %q: %v", sd, err)
+		}
+	}
+	s.WaitForShutdown()
+}
@@ -16,7 +16,12 @@ package jetstream
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/retry"
 )
 
 type (
@@ -63,6 +68,9 @@ const (
 	// apiConsumerDeleteT is used to delete consumers.
 	apiConsumerDeleteT = "CONSUMER.DELETE.%s.%s"
 
+	// apiConsumerLeaderStepDownT is used to step down the leader of a consumer.
+	apiConsumerLeaderStepDownT = "CONSUMER.LEADER.STEPDOWN.%s.%s"
+
 	// apiConsumerListT is used to return all detailed consumer information
 	apiConsumerListT = "CONSUMER.LIST.%s"
 
@@ -87,6 +95,12 @@ const (
 	// apiStreamPurgeT is the endpoint to purge streams.
 	apiStreamPurgeT = "STREAM.PURGE.%s"
 
+	// apiStreamLeaderStepDownT is the endpoint to step down the leader of a stream.
+	apiStreamLeaderStepDownT = "STREAM.LEADER.STEPDOWN.%s"
+
+	// apiStreamRemovePeerT is the endpoint to remove a peer from a clustered stream.
+	apiStreamRemovePeerT = "STREAM.PEER.REMOVE.%s"
+
 	// apiStreamListT is the endpoint that will return all detailed stream information
 	apiStreamListT = "STREAM.LIST"
 
@@ -104,14 +118,40 @@ const (
 )
 
 func (js *jetStream) apiRequestJSON(ctx context.Context, subject string, resp interface{}, data ...[]byte) (*jetStreamMsg, error) {
-	jsMsg, err := js.apiRequest(ctx, subject, data...)
-	if err != nil {
-		return nil, err
+	backoff := js.retry.backoff()
+	for {
+		jsMsg, err := js.apiRequest(ctx, subject, data...)
+		if err != nil {
+			return nil, err
+		}
+		if isUnavailable(jsMsg.Data()) {
+			err := backoff.Wait(ctx)
+			if err == nil {
+				continue
+			}
+			if !errors.Is(err, retry.ErrExhausted) {
+				return nil, err
+			}
+		}
+		if err := json.Unmarshal(jsMsg.Data(), resp); err != nil {
+			return nil, err
+		}
+		return jsMsg, nil
 	}
-	if err := json.Unmarshal(jsMsg.Data(), resp); err != nil {
-		return nil, err
+}
+
+// isUnavailable returns true if the API response is a transient
+// "temporarily unavailable" error, returned e.g. during leader elections.
+func isUnavailable(data []byte) bool {
+	var resp apiResponse
+	if err := json.Unmarshal(data, &resp); err != nil || resp.Error == nil {
+		return false
 	}
-	return jsMsg, err
+	switch resp.Error.ErrorCode {
+	case JSErrCodeJetStreamNotEnabled, JSErrCodeJetStreamNotEnabledForAccount:
+		return false
+	}
+	return resp.Error.Code == 503
 }
 
 // a RequestWithContext with tracing via TraceCB
@@ -120,26 +160,87 @@ func (js *jetStream) apiRequest(ctx context.Context, subj string, data ...[]byte
 	if len(data) > 0 {
 		req = data[0]
 	}
+	if _, ok := ctx.Deadline(); !ok {
+		if timeout := js.apiTimeout(subj); timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+	}
 	if js.clientTrace != nil {
 		ctrace := js.clientTrace
 		if ctrace.RequestSent != nil {
-			ctrace.RequestSent(subj, req)
+			payload, _ := js.redact(subj, req, nil)
+			ctrace.RequestSent(subj, payload)
 		}
 	}
+	sent := time.Now()
+	// trace headers carried by ctx are added by the connection
 	resp, err := js.conn.RequestWithContext(ctx, subj, req)
+	received := time.Now()
+	if js.clientTrace != nil && js.clientTrace.RequestCompleted != nil {
+		request, _ := js.redact(subj, req, nil)
+		var response []byte
+		if err == nil {
+			response, _ = js.redact(subj, resp.Data, resp.Header)
+		}
+		js.clientTrace.RequestCompleted(subj, request, response, received.Sub(sent), err)
+	}
 	if err != nil {
 		return nil, err
 	}
+	js.sampleClockSkew(resp.Data, sent, received)
 	if js.clientTrace != nil {
 		ctrace := js.clientTrace
 		if ctrace.ResponseReceived != nil {
-			ctrace.ResponseReceived(subj, resp.Data, resp.Header)
+			payload, hdr := js.redact(subj, resp.Data, resp.Header)
+			ctrace.ResponseReceived(subj, payload, hdr)
 		}
 	}
 
 	return js.toJSMsg(resp), nil
 }
 
+// redact applies the trace redactor, if any, to the data passed to the trace callbacks.
+func (js *jetStream) redact(subj string, payload []byte, hdr nats.Header) ([]byte, nats.Header) {
+	r := js.clientTrace.Redactor
+	if r == nil {
+		r = js.conn.TraceRedactor()
+	}
+	if r == nil {
+		return payload, hdr
+	}
+	return r.RedactPayload(subj, payload), r.RedactHeader(subj, hdr)
+}
+
+// apiTimeout returns the timeout applied to a request on the given API subject.
+func (js *jetStream) apiTimeout(subj string) time.Duration {
+	op := strings.TrimPrefix(subj, js.apiPrefix)
+	switch {
+	case strings.HasPrefix(op, "STREAM.CREATE."),
+		strings.HasPrefix(op, "STREAM.UPDATE."),
+		strings.HasPrefix(op, "STREAM.DELETE."),
+		strings.HasPrefix(op, "STREAM.PURGE."),
+		strings.HasPrefix(op, "STREAM.MSG.DELETE."),
+		strings.HasPrefix(op, "CONSUMER.CREATE."),
+		strings.HasPrefix(op, "STREAM.LEADER.STEPDOWN."),
+		strings.HasPrefix(op, "STREAM.PEER.REMOVE."),
+		strings.HasPrefix(op, "CONSUMER.DELETE."),
+		strings.HasPrefix(op, "CONSUMER.LEADER.STEPDOWN."):
+		return js.timeouts.Create
+	case op == apiStreams,
+		op == apiStreamListT,
+		strings.HasPrefix(op, "CONSUMER.LIST."),
+		strings.HasPrefix(op, "CONSUMER.NAMES."):
+		return js.timeouts.List
+	case strings.HasPrefix(op, "STREAM.SNAPSHOT."),
+		strings.HasPrefix(op, "STREAM.RESTORE."):
+		return js.timeouts.Snapshot
+	default:
+		return js.timeouts.Get
+	}
+}
+
 func apiSubj(prefix, subject string) string {
 	if prefix == "" {
 		return subject
@@ -149,3 +250,21 @@ func apiSubj(prefix, subject string) string {
 	b.WriteString(subject)
 	return b.String()
 }
+
+// leaderStepDown sends a leader step down request for a stream or consumer.
+func (js *jetStream) leaderStepDown(ctx context.Context, subj string) error {
+	var resp leaderStepDownResponse
+	if _, err := js.apiRequestJSON(ctx, subj, &resp); err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		switch resp.Error.ErrorCode {
+		case JSErrCodeStreamNotFound:
+			return ErrStreamNotFound
+		case JSErrCodeConsumerNotFound:
+			return ErrConsumerNotFound
+		}
+		return resp.Error
+	}
+	return nil
+}
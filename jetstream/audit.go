@@ -0,0 +1,215 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type (
+	// AuditReport lists resources which were not used within the idle
+	// threshold and are candidates for cleanup.
+	AuditReport struct {
+		Time          time.Time
+		IdleThreshold time.Duration
+		// IdleStreams are streams without consumers which did not
+		// receive messages within the threshold. Streams backing
+		// key-value buckets are reported as [AuditReport.IdleBuckets].
+		IdleStreams []IdleStream
+		// IdleConsumers are consumers which neither delivered nor got
+		// acknowledged messages within the threshold and have no
+		// pending pull requests or bound push subscriptions.
+		IdleConsumers []IdleConsumer
+		// IdleBuckets are key-value buckets without watchers which were
+		// not updated within the threshold. The server does not track
+		// [KeyValue.Get] requests, so buckets read only this way are
+		// reported as well.
+		IdleBuckets []IdleBucket
+	}
+
+	// IdleStream is a stream reported by [Audit].
+	IdleStream struct {
+		Name    string
+		Created time.Time
+		// LastActive is the time of the last message, zero if the stream is empty.
+		LastActive time.Time
+		Msgs       uint64
+		Bytes      uint64
+	}
+
+	// IdleConsumer is a consumer reported by [Audit].
+	IdleConsumer struct {
+		Stream  string
+		Name    string
+		Created time.Time
+		// LastActive is the time of the last delivery or acknowledgement,
+		// zero if no messages were delivered.
+		LastActive time.Time
+		NumPending uint64
+	}
+
+	// IdleBucket is a key-value bucket reported by [Audit].
+	IdleBucket struct {
+		Bucket  string
+		Created time.Time
+		// LastActive is the time of the last update, zero if the bucket is empty.
+		LastActive time.Time
+		Values     uint64
+	}
+
+	// AuditOpt configures [Audit].
+	AuditOpt func(*auditOpts) error
+
+	auditOpts struct {
+		idleThreshold time.Duration
+	}
+)
+
+// DefaultAuditIdleThreshold is the default time after which unused resources are reported.
+const DefaultAuditIdleThreshold = 24 * time.Hour
+
+// WithAuditIdleThreshold sets the time after which unused resources are reported.
+func WithAuditIdleThreshold(threshold time.Duration) AuditOpt {
+	return func(opts *auditOpts) error {
+		if threshold <= 0 {
+			return fmt.Errorf("%w: idle threshold must be positive", ErrInvalidOption)
+		}
+		opts.idleThreshold = threshold
+		return nil
+	}
+}
+
+// Audit reports streams, consumers and key-value buckets which were not
+// used within the idle threshold. Resources created within the threshold
+// are not reported.
+//
+// Available options:
+// [WithAuditIdleThreshold] - sets the idle threshold, default is 24h
+func Audit(ctx context.Context, js JetStream, opts ...AuditOpt) (*AuditReport, error) {
+	o := auditOpts{
+		idleThreshold: DefaultAuditIdleThreshold,
+	}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+	report := &AuditReport{
+		Time:          time.Now(),
+		IdleThreshold: o.idleThreshold,
+	}
+	cutoff := report.Time.Add(-o.idleThreshold)
+
+	var streams []*StreamInfo
+	lister := js.ListStreams(ctx)
+Streams:
+	for {
+		select {
+		case info := <-lister.Info():
+			streams = append(streams, info)
+		case err := <-lister.Err():
+			if errors.Is(err, ErrEndOfData) {
+				break Streams
+			}
+			return nil, err
+		}
+	}
+
+	for _, info := range streams {
+		if info.Created.After(cutoff) {
+			continue
+		}
+		idle := info.State.Consumers == 0 && !info.State.LastTime.After(cutoff)
+		if bucket := strings.TrimPrefix(info.Config.Name, kvBucketNamePre); bucket != info.Config.Name {
+			if idle {
+				report.IdleBuckets = append(report.IdleBuckets, IdleBucket{
+					Bucket:     bucket,
+					Created:    info.Created,
+					LastActive: info.State.LastTime,
+					Values:     info.State.Msgs,
+				})
+			}
+		} else if idle {
+			report.IdleStreams = append(report.IdleStreams, IdleStream{
+				Name:       info.Config.Name,
+				Created:    info.Created,
+				LastActive: info.State.LastTime,
+				Msgs:       info.State.Msgs,
+				Bytes:      info.State.Bytes,
+			})
+		}
+		if info.State.Consumers == 0 {
+			continue
+		}
+		consumers, err := idleConsumers(ctx, js, info.Config.Name, cutoff)
+		if err != nil {
+			return nil, err
+		}
+		report.IdleConsumers = append(report.IdleConsumers, consumers...)
+	}
+	return report, nil
+}
+
+// idleConsumers returns consumers of the stream which were not active since cutoff.
+func idleConsumers(ctx context.Context, js JetStream, stream string, cutoff time.Time) ([]IdleConsumer, error) {
+	s, err := js.Stream(ctx, stream)
+	if err != nil {
+		if errors.Is(err, ErrStreamNotFound) {
+			// deleted in the meantime
+			return nil, nil
+		}
+		return nil, err
+	}
+	var idle []IdleConsumer
+	lister := s.ListConsumers(ctx)
+	for {
+		select {
+		case info := <-lister.Info():
+			if info.Created.After(cutoff) || info.NumWaiting > 0 || info.PushBound {
+				continue
+			}
+			lastActive := lastConsumerActivity(info)
+			if lastActive.After(cutoff) {
+				continue
+			}
+			idle = append(idle, IdleConsumer{
+				Stream:     stream,
+				Name:       info.Name,
+				Created:    info.Created,
+				LastActive: lastActive,
+				NumPending: info.NumPending,
+			})
+		case err := <-lister.Err():
+			if errors.Is(err, ErrEndOfData) {
+				return idle, nil
+			}
+			return nil, err
+		}
+	}
+}
+
+// lastConsumerActivity returns the time of the last delivery or acknowledgement.
+func lastConsumerActivity(info *ConsumerInfo) time.Time {
+	var last time.Time
+	for _, t := range []*time.Time{info.Delivered.Last, info.AckFloor.Last} {
+		if t != nil && t.After(last) {
+			last = *t
+		}
+	}
+	return last
+}
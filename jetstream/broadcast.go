@@ -0,0 +1,214 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/retry"
+)
+
+type (
+	// Broadcaster publishes a single message to many destinations,
+	// bounding the number of publishes in flight.
+	Broadcaster struct {
+		publisher    Publisher
+		destinations []Destination
+		opts         broadcasterOpts
+	}
+
+	// Destination is a subject a [Broadcaster] publishes to.
+	Destination struct {
+		// Subject has to be bound to a stream.
+		Subject string
+		// Retry overrides the default retry policy of the broadcaster.
+		Retry *RetryPolicy
+	}
+
+	// RetryPolicy defines how failed requests are retried.
+	RetryPolicy struct {
+		// Attempts is the number of retries after the first failed request.
+		Attempts int
+		// Wait is the delay between attempts.
+		Wait time.Duration
+		// MaxWait, if set, doubles the delay after each attempt up to MaxWait.
+		MaxWait time.Duration
+	}
+
+	// BroadcastResult contains the outcome of a broadcast per destination subject.
+	BroadcastResult struct {
+		Acks   map[string]*PubAck
+		Errors map[string]error
+	}
+
+	// BroadcasterOpt configures a [Broadcaster].
+	BroadcasterOpt func(*broadcasterOpts) error
+
+	broadcasterOpts struct {
+		concurrency int
+		retry       RetryPolicy
+	}
+)
+
+const (
+	// DefaultBroadcastConcurrency is the default maximum number of publishes in flight.
+	DefaultBroadcastConcurrency = 16
+)
+
+// WithBroadcastConcurrency sets the maximum number of publishes in flight for a single broadcast.
+func WithBroadcastConcurrency(max int) BroadcasterOpt {
+	return func(opts *broadcasterOpts) error {
+		if max < 1 {
+			return fmt.Errorf("%w: concurrency must be at least 1", ErrInvalidOption)
+		}
+		opts.concurrency = max
+		return nil
+	}
+}
+
+// WithBroadcastRetry sets the retry policy for destinations without one.
+func WithBroadcastRetry(policy RetryPolicy) BroadcasterOpt {
+	return func(opts *broadcasterOpts) error {
+		if err := policy.validate(); err != nil {
+			return err
+		}
+		opts.retry = policy
+		return nil
+	}
+}
+
+// NewBroadcaster creates a [Broadcaster] publishing to the given destinations.
+//
+// Available options:
+// [WithBroadcastConcurrency] - sets the maximum number of publishes in flight, default is 16
+// [WithBroadcastRetry] - sets the default retry policy, by default failed publishes are not retried
+func NewBroadcaster(js Publisher, destinations []Destination, opts ...BroadcasterOpt) (*Broadcaster, error) {
+	if len(destinations) == 0 {
+		return nil, fmt.Errorf("%w: at least one destination is required", ErrInvalidOption)
+	}
+	o := broadcasterOpts{concurrency: DefaultBroadcastConcurrency}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+	for _, d := range destinations {
+		if d.Subject == "" {
+			return nil, fmt.Errorf("%w: destination subject is required", ErrInvalidOption)
+		}
+		if d.Retry != nil {
+			if err := d.Retry.validate(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &Broadcaster{
+		publisher:    js,
+		destinations: append([]Destination(nil), destinations...),
+		opts:         o,
+	}, nil
+}
+
+// Broadcast publishes the message data and headers to all destinations and waits
+// for the acks. Publishing blocks while the maximum number of publishes is in flight.
+// If any destination fails, [ErrBroadcastFailed] is returned along with the
+// result listing acks and errors per destination.
+//
+// Message ID set on msg or using [WithMsgID] is used for all destinations,
+// so destinations bound to the same stream are deduplicated.
+func (b *Broadcaster) Broadcast(ctx context.Context, msg *nats.Msg, opts ...PublishOpt) (*BroadcastResult, error) {
+	res := &BroadcastResult{
+		Acks:   make(map[string]*PubAck, len(b.destinations)),
+		Errors: make(map[string]error),
+	}
+	// Retries are handled per destination.
+	opts = append([]PublishOpt{WithRetryAttempts(0)}, opts...)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, b.opts.concurrency)
+	for _, d := range b.destinations {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			mu.Lock()
+			res.Errors[d.Subject] = ctx.Err()
+			mu.Unlock()
+			continue
+		}
+		wg.Add(1)
+		go func(d Destination) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			ack, err := b.publish(ctx, d, msg, opts)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				res.Errors[d.Subject] = err
+				return
+			}
+			res.Acks[d.Subject] = ack
+		}(d)
+	}
+	wg.Wait()
+
+	if len(res.Errors) > 0 {
+		return res, fmt.Errorf("%w: %d of %d destinations failed", ErrBroadcastFailed, len(res.Errors), len(b.destinations))
+	}
+	return res, nil
+}
+
+func (b *Broadcaster) publish(ctx context.Context, d Destination, msg *nats.Msg, opts []PublishOpt) (*PubAck, error) {
+	policy := b.opts.retry
+	if d.Retry != nil {
+		policy = *d.Retry
+	}
+	backoff := policy.backoff()
+	for {
+		m := nats.NewMsg(d.Subject)
+		m.Data = msg.Data
+		for k, v := range msg.Header {
+			m.Header[k] = append([]string(nil), v...)
+		}
+		ack, err := b.publisher.PublishMsg(ctx, m, opts...)
+		if err == nil {
+			return ack, nil
+		}
+		if werr := backoff.Wait(ctx); werr != nil {
+			if errors.Is(werr, retry.ErrExhausted) {
+				return ack, err
+			}
+			return nil, werr
+		}
+	}
+}
+
+func (p RetryPolicy) validate() error {
+	if p.Attempts < 0 || p.Wait < 0 || p.MaxWait < 0 {
+		return fmt.Errorf("%w: retry attempts and wait cannot be negative", ErrInvalidOption)
+	}
+	return nil
+}
+
+// backoff returns a [retry.Backoff] following the policy.
+func (p RetryPolicy) backoff() *retry.Backoff {
+	return retry.NewBackoff(retry.Policy{Attempts: p.Attempts, Wait: p.Wait, MaxWait: p.MaxWait})
+}
@@ -0,0 +1,231 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/subjects"
+)
+
+type (
+	// BrowseOpt is a function setting options for [Stream.Browse]
+	BrowseOpt func(*browseOpts) error
+
+	// BrowseResult is a page of messages returned by [Stream.Browse].
+	BrowseResult struct {
+		// Messages are the messages on the page, in browsing order.
+		Messages []*RawStreamMsg
+
+		// Cursor continues browsing after the last message on the page,
+		// when passed to [WithBrowseCursor]. It is empty if there were
+		// no more messages to browse.
+		Cursor string
+	}
+
+	browseOpts struct {
+		subject     string
+		startSeq    uint64
+		startTime   *time.Time
+		backward    bool
+		limit       int
+		headersOnly bool
+	}
+)
+
+// DefaultBrowseLimit is the number of messages returned by [Stream.Browse]
+// unless [WithBrowseLimit] is set.
+const DefaultBrowseLimit = 100
+
+// Browse returns a page of messages read directly from the stream, without
+// creating a consumer, using direct gets if the stream allows them. Messages are read forward from the first message in
+// the stream unless options specify otherwise.
+//
+// Cursors identify positions by stream sequence, so pages stay stable when
+// messages are added to the stream while browsing.
+//
+// Available options:
+// [WithBrowseSubject] - only returns messages with matching subjects.
+// [WithBrowseStartSeq] - starts browsing at a stream sequence.
+// [WithBrowseStartTime] - starts browsing at the first message stored at or after a time.
+// [WithBrowseBackward] - browses from newer to older messages.
+// [WithBrowseLimit] - sets the maximum number of messages on a page.
+// [WithBrowseHeadersOnly] - returns messages without payloads.
+// [WithBrowseCursor] - continues browsing from a previous page.
+func (s *stream) Browse(ctx context.Context, opts ...BrowseOpt) (*BrowseResult, error) {
+	o := browseOpts{limit: DefaultBrowseLimit}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+	if o.startSeq != 0 && o.startTime != nil {
+		return nil, fmt.Errorf("%w: both start sequence and start time cannot be provided", ErrInvalidOption)
+	}
+	info, err := s.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+	first, last := info.State.FirstSeq, info.State.LastSeq
+
+	start := o.startSeq
+	if o.startTime != nil {
+		if start, err = s.seqAtTime(ctx, *o.startTime, first, last); err != nil {
+			return nil, err
+		}
+		if o.backward {
+			// The message before the first one stored at the start time.
+			start--
+		}
+	}
+
+	res := &BrowseResult{Messages: make([]*RawStreamMsg, 0)}
+	var next uint64
+	if o.backward {
+		if start == 0 && o.startTime == nil || start > last {
+			start = last
+		}
+		next, err = s.browseBackward(ctx, res, &o, start, first)
+	} else {
+		next, err = s.browseForward(ctx, res, &o, start)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if next != 0 {
+		res.Cursor = encodeBrowseCursor(o.backward, next, o.subject)
+	}
+	if o.headersOnly {
+		for _, msg := range res.Messages {
+			if msg.Header == nil {
+				msg.Header = nats.Header{}
+			}
+			msg.Header.Set(MsgSizeHeader, strconv.Itoa(len(msg.Data)))
+			msg.Data = nil
+		}
+	}
+	return res, nil
+}
+
+// browseForward reads messages starting at seq, returning the sequence the
+// next page starts at, or 0 if there are no more messages.
+func (s *stream) browseForward(ctx context.Context, res *BrowseResult, o *browseOpts, seq uint64) (uint64, error) {
+	filter := o.subject
+	if filter == "" {
+		filter = ">"
+	}
+	if seq == 0 {
+		seq = 1
+	}
+	for len(res.Messages) < o.limit {
+		msg, err := s.getMsg(ctx, &apiMsgGetRequest{Seq: seq, NextFor: filter}, true)
+		if errors.Is(err, ErrMsgNotFound) {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		res.Messages = append(res.Messages, msg)
+		seq = msg.Sequence + 1
+	}
+	return seq, nil
+}
+
+// browseBackward reads messages from seq down to first, returning the
+// sequence the next page starts at, or 0 if there are no more messages.
+// The server cannot look up previous messages, so messages are read one
+// sequence at a time, skipping deleted ones.
+func (s *stream) browseBackward(ctx context.Context, res *BrowseResult, o *browseOpts, seq, first uint64) (uint64, error) {
+	for ; seq >= first && seq > 0; seq-- {
+		if len(res.Messages) == o.limit {
+			return seq, nil
+		}
+		msg, err := s.getMsg(ctx, &apiMsgGetRequest{Seq: seq}, true)
+		if errors.Is(err, ErrMsgNotFound) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if o.subject != "" && !subjects.Match(o.subject, msg.Subject) {
+			continue
+		}
+		res.Messages = append(res.Messages, msg)
+	}
+	return 0, nil
+}
+
+// seqAtTime returns the sequence of the first message stored at or after t,
+// or last+1 if there is none, using a binary search over stored messages.
+func (s *stream) seqAtTime(ctx context.Context, t time.Time, first, last uint64) (uint64, error) {
+	if first == 0 {
+		first = 1
+	}
+	res := last + 1
+	lo, hi := first, last
+	for lo <= hi {
+		mid := lo + (hi-lo)/2
+		msg, err := s.getMsg(ctx, &apiMsgGetRequest{Seq: mid, NextFor: ">"}, true)
+		if errors.Is(err, ErrMsgNotFound) {
+			hi = mid - 1
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if msg.Time.Before(t) {
+			lo = msg.Sequence + 1
+			continue
+		}
+		// No messages are stored between mid and the returned one.
+		res = msg.Sequence
+		hi = mid - 1
+	}
+	return res, nil
+}
+
+func encodeBrowseCursor(backward bool, seq uint64, subject string) string {
+	dir := "f"
+	if backward {
+		dir = "b"
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(dir + ":" + strconv.FormatUint(seq, 10) + ":" + subject))
+}
+
+func decodeBrowseCursor(cursor string, o *browseOpts) error {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return fmt.Errorf("%w: invalid browse cursor", ErrInvalidOption)
+	}
+	parts := strings.SplitN(string(b), ":", 3)
+	if len(parts) != 3 || (parts[0] != "f" && parts[0] != "b") {
+		return fmt.Errorf("%w: invalid browse cursor", ErrInvalidOption)
+	}
+	seq, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil || seq == 0 {
+		return fmt.Errorf("%w: invalid browse cursor", ErrInvalidOption)
+	}
+	o.backward = parts[0] == "b"
+	o.startSeq = seq
+	o.startTime = nil
+	o.subject = parts[2]
+	return nil
+}
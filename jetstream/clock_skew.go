@@ -0,0 +1,149 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type (
+	// ClockSkew is the estimated offset of the server clock from the client clock.
+	//
+	// The server includes its current time in stream and consumer info
+	// responses (nats-server 2.10+). Each response is a sample, assuming the
+	// server time was taken halfway through the request. The estimate is the
+	// sample with the lowest round trip time out of the most recent ones,
+	// accurate within half of its RTT.
+	ClockSkew struct {
+		// Skew is the server time minus the client time, positive if the
+		// server clock is ahead.
+		Skew time.Duration
+		// RTT is the round trip time of the request the estimate is based on.
+		RTT time.Duration
+		// Samples is the total number of samples taken.
+		Samples int
+		// Updated is the time of the last sample.
+		Updated time.Time
+	}
+
+	// ClockSkewHandler is invoked when the estimated clock skew exceeds the
+	// threshold set using [WithClockSkewHandler].
+	ClockSkewHandler func(ClockSkew)
+
+	clockSkewSample struct {
+		skew time.Duration
+		rtt  time.Duration
+	}
+
+	clockSkewTracker struct {
+		sync.Mutex
+		samples [clockSkewWindow]clockSkewSample
+		count   int
+		updated time.Time
+	}
+)
+
+// clockSkewWindow is the number of recent samples the estimate is chosen from.
+const clockSkewWindow = 8
+
+// WithClockSkewHandler sets a handler invoked whenever a new clock skew
+// estimate exceeds the threshold by more than its uncertainty. Skew breaks
+// e.g. consumers with a start time and message TTLs.
+func WithClockSkewHandler(threshold time.Duration, cb ClockSkewHandler) JetStreamOpt {
+	return func(opts *jsOpts) error {
+		if threshold <= 0 {
+			return fmt.Errorf("%w: clock skew threshold must be positive", ErrInvalidOption)
+		}
+		if cb == nil {
+			return fmt.Errorf("%w: clock skew handler cannot be nil", ErrInvalidOption)
+		}
+		opts.clockSkewThreshold = threshold
+		opts.clockSkewHandler = cb
+		return nil
+	}
+}
+
+// ClockSkew returns the estimated clock skew, with zero samples if no
+// response carrying the server time was received yet.
+func (js *jetStream) ClockSkew() ClockSkew {
+	return js.clockSkew.estimate()
+}
+
+// sampleClockSkew records a clock skew sample if the API response
+// carries the server time.
+func (js *jetStream) sampleClockSkew(data []byte, sent, received time.Time) {
+	if !bytes.Contains(data, []byte(`"ts"`)) {
+		return
+	}
+	var resp struct {
+		TimeStamp time.Time `json:"ts"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil || resp.TimeStamp.IsZero() {
+		return
+	}
+	rtt := received.Sub(sent)
+	skew := resp.TimeStamp.Sub(sent.Add(rtt / 2))
+	estimate := js.clockSkew.add(clockSkewSample{skew: skew, rtt: rtt}, received)
+	if js.clockSkewHandler == nil {
+		return
+	}
+	abs := estimate.Skew
+	if abs < 0 {
+		abs = -abs
+	}
+	if abs-estimate.RTT/2 > js.clockSkewThreshold {
+		js.clockSkewHandler(estimate)
+	}
+}
+
+func (t *clockSkewTracker) add(sample clockSkewSample, now time.Time) ClockSkew {
+	t.Lock()
+	defer t.Unlock()
+	t.samples[t.count%clockSkewWindow] = sample
+	t.count++
+	t.updated = now
+	return t.estimateLocked()
+}
+
+func (t *clockSkewTracker) estimate() ClockSkew {
+	t.Lock()
+	defer t.Unlock()
+	return t.estimateLocked()
+}
+
+func (t *clockSkewTracker) estimateLocked() ClockSkew {
+	if t.count == 0 {
+		return ClockSkew{}
+	}
+	n := t.count
+	if n > clockSkewWindow {
+		n = clockSkewWindow
+	}
+	best := t.samples[0]
+	for _, s := range t.samples[1:n] {
+		if s.rtt < best.rtt {
+			best = s
+		}
+	}
+	return ClockSkew{
+		Skew:    best.skew,
+		RTT:     best.rtt,
+		Samples: t.count,
+		Updated: t.updated,
+	}
+}
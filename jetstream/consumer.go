@@ -19,7 +19,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/subjects"
 	"github.com/nats-io/nuid"
 )
 
@@ -50,6 +53,17 @@ type (
 		Info(context.Context) (*ConsumerInfo, error)
 		// CachedInfo returns [*ConsumerInfo] cached on a consumer struct
 		CachedInfo() *ConsumerInfo
+		// WatchInfo polls consumer info every interval and emits changes of the delivered sequence,
+		// ack floor, pending counts and cluster leader as [ConsumerInfoEvent]s on the returned channel.
+		// The channel is closed when ctx is done or the consumer is deleted.
+		WatchInfo(ctx context.Context, interval time.Duration) (<-chan ConsumerInfoEvent, error)
+		// LeaderStepDown makes the current leader of a clustered consumer step down,
+		// triggering the election of a new leader among the other replicas.
+		LeaderStepDown(context.Context) error
+		// AnalyzeStuck compares the ack floor, delivered sequence and pending counts of the consumer,
+		// fetches the first message holding back the ack floor and reports the likely causes
+		// of the consumer not making progress.
+		AnalyzeStuck(context.Context) (*StuckReport, error)
 	}
 )
 
@@ -72,6 +86,13 @@ func (p *pullConsumer) Info(ctx context.Context) (*ConsumerInfo, error) {
 	return resp.ConsumerInfo, nil
 }
 
+// LeaderStepDown makes the current leader of the consumer step down. It fails
+// with an [APIError] if the server does not run in clustered mode.
+func (p *pullConsumer) LeaderStepDown(ctx context.Context) error {
+	subj := apiSubj(p.jetStream.apiPrefix, fmt.Sprintf(apiConsumerLeaderStepDownT, p.stream, p.name))
+	return p.jetStream.leaderStepDown(ctx, subj)
+}
+
 // CachedInfo returns [ConsumerInfo] fetched when initializing/updating a consumer
 //
 // NOTE: The returned object might not be up to date with the most recent updates on the server
@@ -81,6 +102,26 @@ func (p *pullConsumer) CachedInfo() *ConsumerInfo {
 }
 
 func upsertConsumer(ctx context.Context, js *jetStream, stream string, cfg ConsumerConfig) (Consumer, error) {
+	if cfg.FilterSubject != "" && len(cfg.FilterSubjects) > 0 {
+		return nil, ErrConflictingFilterSubjects
+	}
+	if err := validateFilterSubjects(cfg.FilterSubjects); err != nil {
+		return nil, err
+	}
+	if err := validateConsumerConfig(cfg); err != nil {
+		return nil, err
+	}
+	if err := js.checkInterest(ctx, stream, cfg); err != nil {
+		return nil, err
+	}
+	// A single filter is sent as FilterSubject, which all servers support.
+	if len(cfg.FilterSubjects) == 1 {
+		cfg.FilterSubject = cfg.FilterSubjects[0]
+		cfg.FilterSubjects = nil
+	}
+	if id := js.conn.Identity(); id != nil {
+		cfg.Metadata = withIdentity(cfg.Metadata, id)
+	}
 	req := createConsumerRequest{
 		Stream: stream,
 		Config: &cfg,
@@ -114,11 +155,20 @@ func upsertConsumer(ctx context.Context, js *jetStream, stream string, cfg Consu
 		return nil, err
 	}
 	if resp.Error != nil {
-		if resp.Error.ErrorCode == JSErrCodeStreamNotFound {
+		switch resp.Error.ErrorCode {
+		case JSErrCodeStreamNotFound:
 			return nil, ErrStreamNotFound
+		case JSErrCodeConsumerReplicasExceedsStream:
+			return nil, ErrConsumerReplicasExceedsStream
+		case JSErrCodeConsumerReplicasShouldMatchStream:
+			return nil, ErrConsumerReplicasShouldMatchStream
 		}
 		return nil, resp.Error
 	}
+	// Servers which do not support multiple filters ignore them.
+	if len(cfg.FilterSubjects) > 0 && len(resp.Config.FilterSubjects) == 0 {
+		return nil, ErrConsumerMultipleFilterSubjectsNotSupported
+	}
 
 	return &pullConsumer{
 		jetStream:     js,
@@ -130,6 +180,122 @@ func upsertConsumer(ctx context.Context, js *jetStream, stream string, cfg Consu
 	}, nil
 }
 
+// updateConsumer updates an existing consumer. The consumer create API
+// creates consumers which do not exist, so their existence is checked first.
+func updateConsumer(ctx context.Context, js *jetStream, stream string, cfg ConsumerConfig) (Consumer, error) {
+	name := cfg.Name
+	if name == "" {
+		name = cfg.Durable
+	}
+	if name == "" {
+		return nil, ErrConsumerNameRequired
+	}
+	if _, err := getConsumer(ctx, js, stream, name); err != nil {
+		return nil, err
+	}
+	return upsertConsumer(ctx, js, stream, cfg)
+}
+
+// MigrateConsumerStorage moves the state of a durable consumer to the given storage by
+// recreating it, as the storage of a consumer cannot be updated. The recreated consumer
+// starts delivering after the ack floor of the consumer, so acknowledgements of messages
+// above the ack floor and redelivery counts are lost and these messages are redelivered.
+// Pending pull requests and push subscriptions are interrupted.
+// If the consumer cannot be recreated, it is restored with its original storage.
+func MigrateConsumerStorage(ctx context.Context, js JetStream, stream, consumer string, storage StorageType) (Consumer, error) {
+	c, err := js.Consumer(ctx, stream, consumer)
+	if err != nil {
+		return nil, err
+	}
+	info := c.CachedInfo()
+	memory := storage == MemoryStorage
+	if info.Config.MemoryStorage == memory {
+		return c, nil
+	}
+	if info.Config.Durable == "" {
+		return nil, fmt.Errorf("%w: consumer %q is not durable", ErrInvalidOption, consumer)
+	}
+
+	restore := resumeConfig(info)
+	cfg := restore
+	cfg.MemoryStorage = memory
+	return recreateConsumer(ctx, js, stream, cfg, restore)
+}
+
+// MigratePushConsumer converts a durable push consumer to an equivalent pull consumer,
+// which can be used with [Consumer.Consume], [Consumer.Messages] and [Consumer.Fetch].
+// The pull consumer starts delivering after the ack floor of the push consumer, so
+// acknowledgements of messages above the ack floor and redelivery counts are lost and
+// these messages are redelivered. Push only settings, i.e. deliver subject and group,
+// flow control, idle heartbeat and rate limit, are dropped.
+//
+// If name is empty or matches the push consumer, the push consumer is deleted and
+// recreated as a pull consumer with the same name. If it cannot be recreated, the push
+// consumer is restored. Otherwise, the pull consumer is created with the given name
+// before deleting the push consumer, so both exist during the cutover. In both cases,
+// push subscriptions on the consumer should be stopped before migrating, as messages
+// acknowledged after reading the ack floor are delivered again by the pull consumer.
+func MigratePushConsumer(ctx context.Context, js JetStream, stream, consumer, name string) (Consumer, error) {
+	c, err := js.Consumer(ctx, stream, consumer)
+	if err != nil {
+		return nil, err
+	}
+	info := c.CachedInfo()
+	if info.Config.DeliverSubject == "" {
+		return nil, fmt.Errorf("%w: consumer %q is not a push consumer", ErrInvalidOption, consumer)
+	}
+	if info.Config.Durable == "" {
+		return nil, fmt.Errorf("%w: consumer %q is not durable", ErrInvalidOption, consumer)
+	}
+
+	restore := resumeConfig(info)
+	cfg := restore
+	cfg.DeliverSubject = ""
+	cfg.DeliverGroup = ""
+	cfg.FlowControl = false
+	cfg.Heartbeat = 0
+	cfg.RateLimit = 0
+	if name == "" || name == consumer {
+		return recreateConsumer(ctx, js, stream, cfg, restore)
+	}
+
+	cfg.Durable = name
+	cfg.Name = ""
+	migrated, err := js.AddConsumer(ctx, stream, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := js.DeleteConsumer(ctx, stream, consumer); err != nil {
+		return nil, err
+	}
+	return migrated, nil
+}
+
+// resumeConfig returns the config of the consumer starting after its ack floor.
+func resumeConfig(info *ConsumerInfo) ConsumerConfig {
+	cfg := info.Config
+	cfg.DeliverPolicy = DeliverByStartSequencePolicy
+	cfg.OptStartSeq = info.AckFloor.Stream + 1
+	cfg.OptStartTime = nil
+	return cfg
+}
+
+// recreateConsumer deletes the durable consumer and creates it with cfg.
+// If that fails, it is restored using the restore config.
+func recreateConsumer(ctx context.Context, js JetStream, stream string, cfg, restore ConsumerConfig) (Consumer, error) {
+	if err := js.DeleteConsumer(ctx, stream, cfg.Durable); err != nil {
+		return nil, err
+	}
+	migrated, err := js.AddConsumer(ctx, stream, cfg)
+	if err != nil {
+		if _, restoreErr := js.AddConsumer(ctx, stream, restore); restoreErr != nil {
+			return nil, fmt.Errorf("%w; restoring consumer: %v", err, restoreErr)
+		}
+		return nil, err
+	}
+	return migrated, nil
+}
+
 func generateConsName() string {
 	name := nuid.Next()
 	sha := sha256.New()
@@ -141,6 +307,16 @@ func generateConsName() string {
 	return string(b[:8])
 }
 
+// withIdentity returns a copy of metadata with the client identity added,
+// keeping values set by the user.
+func withIdentity(metadata map[string]string, id *nats.ClientIdentity) map[string]string {
+	md := id.Metadata()
+	for k, v := range metadata {
+		md[k] = v
+	}
+	return md
+}
+
 func getConsumer(ctx context.Context, js *jetStream, stream, name string) (Consumer, error) {
 	if err := validateConsumerName(name); err != nil {
 		return nil, err
@@ -191,6 +367,49 @@ func deleteConsumer(ctx context.Context, js *jetStream, stream, consumer string)
 	return nil
 }
 
+// validateFilterSubjects checks that filter subjects are not empty and
+// do not overlap, as a message can only be matched by a single filter.
+func validateFilterSubjects(filters []string) error {
+	for i, filter := range filters {
+		if filter == "" {
+			return ErrEmptyFilter
+		}
+		for _, other := range filters[:i] {
+			if filter == other {
+				return fmt.Errorf("%w: %q", ErrDuplicateFilterSubjects, filter)
+			}
+			if subjects.Overlap(filter, other) {
+				return fmt.Errorf("%w: %q and %q", ErrOverlappingFilterSubjects, other, filter)
+			}
+		}
+	}
+	return nil
+}
+
+// validateConsumerConfig checks the replicas, inactivity and pull request
+// limits of the consumer, so that invalid values are reported before
+// the consumer is created.
+func validateConsumerConfig(cfg ConsumerConfig) error {
+	switch {
+	case cfg.Replicas < 0:
+		return fmt.Errorf("%w: replicas cannot be negative", ErrInvalidConsumerConfig)
+	case cfg.InactiveThreshold < 0:
+		return fmt.Errorf("%w: inactive threshold cannot be negative", ErrInvalidConsumerConfig)
+	case cfg.MaxRequestBatch < 0:
+		return fmt.Errorf("%w: max request batch cannot be negative", ErrInvalidConsumerConfig)
+	case cfg.MaxRequestMaxBytes < 0:
+		return fmt.Errorf("%w: max request max bytes cannot be negative", ErrInvalidConsumerConfig)
+	case cfg.MaxRequestExpires < 0:
+		return fmt.Errorf("%w: max request expires cannot be negative", ErrInvalidConsumerConfig)
+	case cfg.MaxRequestExpires > 0 && cfg.MaxRequestExpires < time.Millisecond:
+		return fmt.Errorf("%w: max request expires must be at least 1ms", ErrInvalidConsumerConfig)
+	}
+	if cfg.DeliverSubject != "" && (cfg.MaxWaiting > 0 || cfg.MaxRequestBatch > 0 || cfg.MaxRequestExpires > 0 || cfg.MaxRequestMaxBytes > 0) {
+		return fmt.Errorf("%w: pull request limits cannot be set with a deliver subject", ErrInvalidConsumerConfig)
+	}
+	return nil
+}
+
 func validateConsumerName(dur string) error {
 	if strings.Contains(dur, ".") {
 		return fmt.Errorf("%w: '%s'", ErrInvalidConsumerName, dur)
@@ -34,6 +34,7 @@ type (
 		NumPending     uint64         `json:"num_pending"`
 		Cluster        *ClusterInfo   `json:"cluster,omitempty"`
 		PushBound      bool           `json:"push_bound,omitempty"`
+		TimeStamp      time.Time      `json:"ts"`
 	}
 
 	// ConsumerConfig is the configuration of a JetStream consumer.
@@ -59,7 +60,11 @@ type (
 		Heartbeat       time.Duration   `json:"idle_heartbeat,omitempty"`
 		HeadersOnly     bool            `json:"headers_only,omitempty"`
 
-		// Pull based options.
+		// Pull based options, limiting the pull requests the consumer accepts.
+		// MaxRequestBatch is the maximum number of messages of a single request,
+		// MaxRequestExpires the maximum expiry of a request (at least 1ms), and
+		// MaxRequestMaxBytes the maximum size of a single request in bytes.
+		// They cannot be set together with DeliverSubject.
 		MaxRequestBatch    int           `json:"max_batch,omitempty"`
 		MaxRequestExpires  time.Duration `json:"max_expires,omitempty"`
 		MaxRequestMaxBytes int           `json:"max_bytes,omitempty"`
@@ -68,13 +73,23 @@ type (
 		DeliverSubject string `json:"deliver_subject,omitempty"`
 		DeliverGroup   string `json:"deliver_group,omitempty"`
 
-		// Inactivity threshold.
+		// InactiveThreshold is the time after which a consumer without activity is removed.
+		// Ephemeral consumers default to 5s if not set.
 		InactiveThreshold time.Duration `json:"inactive_threshold,omitempty"`
 
-		// Generally inherited by parent stream and other markers, now can be configured directly.
+		// Replicas is the number of replicas of the consumer state. If not set, durable consumers
+		// and consumers of streams with interest or work queue retention use the replicas of the
+		// stream, other consumers use a single replica. It must not exceed the replicas of the stream
+		// and must match them for streams with interest or work queue retention.
 		Replicas int `json:"num_replicas"`
-		// Force memory storage.
+		// MemoryStorage forces the consumer state to be stored in memory, regardless of the stream storage.
+		// It cannot be changed by an update, see [MigrateConsumerStorage].
 		MemoryStorage bool `json:"mem_storage,omitempty"`
+
+		// Metadata is additional metadata for the consumer, e.g. owner or team labels.
+		// Identity of the client is added to it, see [nats.ClientIdentity].
+		// Requires nats-server v2.10.0 or later, older servers ignore it.
+		Metadata map[string]string `json:"metadata,omitempty"`
 	}
 
 	OrderedConsumerConfig struct {
@@ -84,6 +99,7 @@ type (
 		OptStartTime      *time.Time    `json:"opt_start_time,omitempty"`
 		ReplayPolicy      ReplayPolicy  `json:"replay_policy"`
 		InactiveThreshold time.Duration `json:"inactive_threshold,omitempty"`
+		HeadersOnly       bool          `json:"headers_only,omitempty"`
 
 		// Maximum number of attempts for the consumer to be recreated
 		// Defaults to unlimited
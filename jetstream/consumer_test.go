@@ -0,0 +1,94 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidateFilterSubjects(t *testing.T) {
+	tests := []struct {
+		name      string
+		filters   []string
+		withError error
+	}{
+		{name: "no filters"},
+		{name: "disjoint filters", filters: []string{"FOO.A", "FOO.B", "BAR.*", "BAZ.>"}},
+		{name: "different lengths", filters: []string{"FOO.*", "FOO.A.B", "FOO"}},
+		{name: "empty filter", filters: []string{"FOO.A", ""}, withError: ErrEmptyFilter},
+		{name: "duplicate filters", filters: []string{"FOO.A", "FOO.B", "FOO.A"}, withError: ErrDuplicateFilterSubjects},
+		{name: "wildcard overlap", filters: []string{"FOO.A", "FOO.*"}, withError: ErrOverlappingFilterSubjects},
+		{name: "full wildcard overlap", filters: []string{"FOO.>", "FOO.A.B"}, withError: ErrOverlappingFilterSubjects},
+		{name: "partial wildcards overlap", filters: []string{"FOO.*.A", "FOO.B.*"}, withError: ErrOverlappingFilterSubjects},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateFilterSubjects(test.filters)
+			if test.withError != nil {
+				if !errors.Is(err, test.withError) {
+					t.Fatalf("Expected error: %v; got: %v", test.withError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateConsumerConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       ConsumerConfig
+		withError bool
+	}{
+		{name: "empty config"},
+		{
+			name: "ephemeral tuning",
+			cfg: ConsumerConfig{
+				Replicas:           1,
+				MemoryStorage:      true,
+				InactiveThreshold:  time.Minute,
+				MaxRequestBatch:    100,
+				MaxRequestExpires:  time.Second,
+				MaxRequestMaxBytes: 1024,
+			},
+		},
+		{name: "negative replicas", cfg: ConsumerConfig{Replicas: -1}, withError: true},
+		{name: "negative inactive threshold", cfg: ConsumerConfig{InactiveThreshold: -time.Second}, withError: true},
+		{name: "negative max request batch", cfg: ConsumerConfig{MaxRequestBatch: -1}, withError: true},
+		{name: "negative max request max bytes", cfg: ConsumerConfig{MaxRequestMaxBytes: -1}, withError: true},
+		{name: "negative max request expires", cfg: ConsumerConfig{MaxRequestExpires: -time.Second}, withError: true},
+		{name: "max request expires below 1ms", cfg: ConsumerConfig{MaxRequestExpires: time.Microsecond}, withError: true},
+		{name: "pull limits on push consumer", cfg: ConsumerConfig{DeliverSubject: "deliver", MaxRequestBatch: 10}, withError: true},
+		{name: "max waiting on push consumer", cfg: ConsumerConfig{DeliverSubject: "deliver", MaxWaiting: 10}, withError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateConsumerConfig(test.cfg)
+			if test.withError {
+				if !errors.Is(err, ErrInvalidConsumerConfig) {
+					t.Fatalf("Expected error: %v; got: %v", ErrInvalidConsumerConfig, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type (
+	// ConsumerInfoEvent is a change of consumer state emitted by [Consumer.WatchInfo].
+	// It is one of [ConsumerDeliveredEvent], [ConsumerAckFloorEvent],
+	// [ConsumerPendingEvent], [ConsumerLeaderEvent] or [ConsumerInfoErrorEvent].
+	ConsumerInfoEvent interface {
+		// ConsumerInfo returns the consumer info the change was detected in.
+		// It is nil for [ConsumerInfoErrorEvent].
+		ConsumerInfo() *ConsumerInfo
+	}
+
+	// ConsumerDeliveredEvent is emitted when the delivered sequence moved.
+	ConsumerDeliveredEvent struct {
+		Info     *ConsumerInfo
+		From, To SequenceInfo
+	}
+
+	// ConsumerAckFloorEvent is emitted when the ack floor moved.
+	ConsumerAckFloorEvent struct {
+		Info     *ConsumerInfo
+		From, To SequenceInfo
+	}
+
+	// ConsumerPendingEvent is emitted when any of the pending counts changed.
+	ConsumerPendingEvent struct {
+		Info     *ConsumerInfo
+		From, To PendingCounts
+	}
+
+	// ConsumerLeaderEvent is emitted when the consumer leader changed.
+	ConsumerLeaderEvent struct {
+		Info     *ConsumerInfo
+		From, To string
+	}
+
+	// ConsumerInfoErrorEvent is emitted when fetching consumer info failed.
+	// If the consumer was deleted, the error is [ErrConsumerNotFound] and
+	// the watch ends.
+	ConsumerInfoErrorEvent struct {
+		Err error
+	}
+
+	// PendingCounts groups the pending counts of a consumer.
+	PendingCounts struct {
+		NumPending     uint64
+		NumAckPending  int
+		NumRedelivered int
+		NumWaiting     int
+	}
+)
+
+func (e *ConsumerDeliveredEvent) ConsumerInfo() *ConsumerInfo { return e.Info }
+func (e *ConsumerAckFloorEvent) ConsumerInfo() *ConsumerInfo  { return e.Info }
+func (e *ConsumerPendingEvent) ConsumerInfo() *ConsumerInfo   { return e.Info }
+func (e *ConsumerLeaderEvent) ConsumerInfo() *ConsumerInfo    { return e.Info }
+func (e *ConsumerInfoErrorEvent) ConsumerInfo() *ConsumerInfo { return nil }
+
+// WatchInfo polls consumer info every interval and emits the changes on the returned channel
+func (p *pullConsumer) WatchInfo(ctx context.Context, interval time.Duration) (<-chan ConsumerInfoEvent, error) {
+	return watchConsumerInfo(ctx, interval, p.Info)
+}
+
+// WatchInfo polls consumer info every interval and emits the changes on the returned channel
+func (c *orderedConsumer) WatchInfo(ctx context.Context, interval time.Duration) (<-chan ConsumerInfoEvent, error) {
+	return watchConsumerInfo(ctx, interval, c.Info)
+}
+
+func watchConsumerInfo(ctx context.Context, interval time.Duration, info func(context.Context) (*ConsumerInfo, error)) (<-chan ConsumerInfoEvent, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("%w: watch interval must be positive", ErrInvalidOption)
+	}
+	prev, err := info(ctx)
+	if err != nil {
+		return nil, err
+	}
+	events := make(chan ConsumerInfoEvent, 64)
+	go func() {
+		defer close(events)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		emit := func(e ConsumerInfoEvent) bool {
+			select {
+			case events <- e:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+			cur, err := info(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				if !emit(&ConsumerInfoErrorEvent{Err: err}) || errors.Is(err, ErrConsumerNotFound) {
+					return
+				}
+				continue
+			}
+			for _, e := range consumerInfoChanges(prev, cur) {
+				if !emit(e) {
+					return
+				}
+			}
+			prev = cur
+		}
+	}()
+	return events, nil
+}
+
+// consumerInfoChanges returns the events describing the changes between two consumer infos.
+func consumerInfoChanges(prev, cur *ConsumerInfo) []ConsumerInfoEvent {
+	var events []ConsumerInfoEvent
+	if prev.Delivered.Consumer != cur.Delivered.Consumer || prev.Delivered.Stream != cur.Delivered.Stream {
+		events = append(events, &ConsumerDeliveredEvent{Info: cur, From: prev.Delivered, To: cur.Delivered})
+	}
+	if prev.AckFloor.Consumer != cur.AckFloor.Consumer || prev.AckFloor.Stream != cur.AckFloor.Stream {
+		events = append(events, &ConsumerAckFloorEvent{Info: cur, From: prev.AckFloor, To: cur.AckFloor})
+	}
+	if from, to := pendingCounts(prev), pendingCounts(cur); from != to {
+		events = append(events, &ConsumerPendingEvent{Info: cur, From: from, To: to})
+	}
+	if from, to := consumerLeader(prev), consumerLeader(cur); from != to {
+		events = append(events, &ConsumerLeaderEvent{Info: cur, From: from, To: to})
+	}
+	return events
+}
+
+func pendingCounts(info *ConsumerInfo) PendingCounts {
+	return PendingCounts{
+		NumPending:     info.NumPending,
+		NumAckPending:  info.NumAckPending,
+		NumRedelivered: info.NumRedelivered,
+		NumWaiting:     info.NumWaiting,
+	}
+}
+
+func consumerLeader(info *ConsumerInfo) string {
+	if info.Cluster == nil {
+		return ""
+	}
+	return info.Cluster.Leader
+}
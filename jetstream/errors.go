@@ -16,6 +16,8 @@ package jetstream
 import (
 	"errors"
 	"fmt"
+
+	"github.com/nats-io/nats.go"
 )
 
 type (
@@ -29,6 +31,8 @@ type (
 	jsError struct {
 		apiErr  *APIError
 		message string
+		// wrapped is an error of the nats package the error is matched to.
+		wrapped error
 	}
 
 	// APIError is included in all API responses if there was an error.
@@ -40,23 +44,39 @@ type (
 
 	// ErrorCode represents error_code returned in response from JetStream API
 	ErrorCode uint16
+
+	// ErrStreamLimitsExceeded is returned when a message is rejected by a stream
+	// with [DiscardNew] policy because one of its limits was reached.
+	// It wraps the [APIError] returned by the server.
+	ErrStreamLimitsExceeded struct {
+		// Limit is the limit which was reached.
+		Limit  UsageLimit
+		apiErr *APIError
+	}
 )
 
 const (
 	JSErrCodeJetStreamNotEnabledForAccount ErrorCode = 10039
 	JSErrCodeJetStreamNotEnabled           ErrorCode = 10076
 
-	JSErrCodeStreamNotFound  ErrorCode = 10059
-	JSErrCodeStreamNameInUse ErrorCode = 10058
+	JSErrCodeStreamNotFound    ErrorCode = 10059
+	JSErrCodeStreamNameInUse   ErrorCode = 10058
+	JSErrCodeStreamStoreFailed ErrorCode = 10077
 
-	JSErrCodeConsumerCreate        ErrorCode = 10012
-	JSErrCodeConsumerNotFound      ErrorCode = 10014
-	JSErrCodeConsumerNameExists    ErrorCode = 10013
-	JSErrCodeConsumerAlreadyExists ErrorCode = 10105
+	JSErrCodeConsumerCreate                    ErrorCode = 10012
+	JSErrCodeConsumerNotFound                  ErrorCode = 10014
+	JSErrCodeConsumerNameExists                ErrorCode = 10013
+	JSErrCodeConsumerAlreadyExists             ErrorCode = 10105
+	JSErrCodeConsumerReplicasExceedsStream     ErrorCode = 10126
+	JSErrCodeConsumerReplicasShouldMatchStream ErrorCode = 10134
 
 	JSErrCodeMessageNotFound ErrorCode = 10037
 
+	JSErrCodeStreamWrongLastSequence ErrorCode = 10071
+
 	JSErrCodeBadRequest ErrorCode = 10003
+
+	JSErrCodeClusterPeerNotMember ErrorCode = 10040
 )
 
 var (
@@ -83,9 +103,24 @@ var (
 	// ErrBadRequest is returned when invalid request is sent to JetStream API.
 	ErrBadRequest JetStreamError = &jsError{apiErr: &APIError{ErrorCode: JSErrCodeBadRequest, Description: "bad request", Code: 400}}
 
+	// ErrConsumerReplicasExceedsStream is returned when the consumer replicas exceed the replicas of its stream.
+	ErrConsumerReplicasExceedsStream JetStreamError = &jsError{apiErr: &APIError{ErrorCode: JSErrCodeConsumerReplicasExceedsStream, Description: "consumer config replica count exceeds parent stream", Code: 400}}
+
+	// ErrConsumerReplicasShouldMatchStream is returned when the consumer replicas differ from the replicas
+	// of its stream with interest or work queue retention.
+	ErrConsumerReplicasShouldMatchStream JetStreamError = &jsError{apiErr: &APIError{ErrorCode: JSErrCodeConsumerReplicasShouldMatchStream, Description: "consumer config replicas must match interest retention stream's replicas", Code: 400}}
+
 	// ErrConsumerCreate is returned when nats-server reports error when creating consumer (e.g. illegal update).
 	ErrConsumerCreate JetStreamError = &jsError{apiErr: &APIError{ErrorCode: JSErrCodeConsumerCreate, Description: "could not create consumer", Code: 500}}
 
+	// ErrPeerNotMember is returned by [Stream.RemovePeer] when the peer does not
+	// hold a replica of the stream.
+	ErrPeerNotMember JetStreamError = &jsError{apiErr: &APIError{ErrorCode: JSErrCodeClusterPeerNotMember, Description: "peer not a member", Code: 400}}
+
+	// ErrKeyExists is returned when attempting to create a key which already exists
+	// or to update a key whose latest revision does not match.
+	ErrKeyExists JetStreamError = &jsError{apiErr: &APIError{ErrorCode: JSErrCodeStreamWrongLastSequence, Code: 400}, message: "key exists"}
+
 	// Client errors
 
 	// ErrConsumerNotFound is an error returned when consumer with given name does not exist.
@@ -101,7 +136,8 @@ var (
 	ErrMsgAlreadyAckd JetStreamError = &jsError{message: "message was already acknowledged"}
 
 	// ErrNoStreamResponse is returned when there is no response from stream (e.g. no responders error).
-	ErrNoStreamResponse JetStreamError = &jsError{message: "no response from stream"}
+	// It matches [nats.ErrNoResponders] using errors.Is.
+	ErrNoStreamResponse JetStreamError = &jsError{message: "no response from stream", wrapped: nats.ErrNoResponders}
 
 	// ErrNotJSMessage is returned when attempting to get metadata from non JetStream message.
 	ErrNotJSMessage JetStreamError = &jsError{message: "not a jetstream message"}
@@ -109,9 +145,59 @@ var (
 	// ErrInvalidStreamName is returned when the provided stream name is invalid (contains '.').
 	ErrInvalidStreamName JetStreamError = &jsError{message: "invalid stream name"}
 
+	// ErrStreamSubjectTransformNotSupported is returned when the connected nats-server version does not support setting
+	// the stream subject transform. If this error is returned when executing CreateStream(), the stream with invalid
+	// configuration was already created in the server.
+	ErrStreamSubjectTransformNotSupported JetStreamError = &jsError{message: "stream subject transformation not supported by nats-server"}
+
+	// ErrStreamCompressionNotSupported is returned when the connected nats-server version does not support setting
+	// the stream compression. If this error is returned when executing CreateStream(), the stream with invalid
+	// configuration was already created in the server.
+	ErrStreamCompressionNotSupported JetStreamError = &jsError{message: "stream compression not supported by nats-server"}
+
+	// ErrStreamMsgTTLNotSupported is returned when the connected nats-server version does not support per message TTLs.
+	// If this error is returned when executing CreateStream(), the stream with invalid configuration was already created in the server.
+	ErrStreamMsgTTLNotSupported JetStreamError = &jsError{message: "stream message TTL not supported by nats-server"}
+
+	// ErrConsumerNameRequired is returned when the provided consumer config has neither name nor durable name set.
+	ErrConsumerNameRequired JetStreamError = &jsError{message: "consumer name is required"}
+
 	// ErrInvalidConsumerName is returned when the provided consumer name is invalid (contains '.').
 	ErrInvalidConsumerName JetStreamError = &jsError{message: "invalid consumer name"}
 
+	// ErrInvalidConsumerConfig is returned when the consumer configuration contains values the server would reject.
+	ErrInvalidConsumerConfig JetStreamError = &jsError{message: "invalid consumer config"}
+
+	// ErrSubjectsRequired is returned when no subjects are provided to [Stream.GetLastMsgsForSubjects].
+	ErrSubjectsRequired JetStreamError = &jsError{message: "at least one subject is required"}
+
+	// ErrUnsafeInterestConsumers is returned by [InterestReport.Err] when consumers of a stream
+	// with interest or work queue retention lose or strand messages.
+	ErrUnsafeInterestConsumers JetStreamError = &jsError{message: "consumers lose or strand messages"}
+
+	// ErrSnapshotConflict is returned by [Stream.SnapshotSubjects] when the subjects kept
+	// receiving messages while the snapshot was read.
+	ErrSnapshotConflict JetStreamError = &jsError{message: "subjects changed while reading snapshot"}
+
+	// ErrPeerRequired is returned by [Stream.RemovePeer] when the peer name is empty.
+	ErrPeerRequired JetStreamError = &jsError{message: "peer name is required"}
+
+	// ErrConflictingFilterSubjects is returned when both FilterSubject and FilterSubjects are set in consumer config.
+	ErrConflictingFilterSubjects JetStreamError = &jsError{message: "consumer filter subject and filter subjects cannot both be set"}
+
+	// ErrEmptyFilter is returned when one of the consumer filter subjects is empty.
+	ErrEmptyFilter JetStreamError = &jsError{message: "consumer filter subject cannot be empty"}
+
+	// ErrDuplicateFilterSubjects is returned when consumer filter subjects contain duplicates.
+	ErrDuplicateFilterSubjects JetStreamError = &jsError{message: "consumer cannot have duplicate filter subjects"}
+
+	// ErrOverlappingFilterSubjects is returned when consumer filter subjects overlap.
+	ErrOverlappingFilterSubjects JetStreamError = &jsError{message: "consumer subject filters cannot overlap"}
+
+	// ErrConsumerMultipleFilterSubjectsNotSupported is returned when the server does not support
+	// multiple filter subjects and created or updated the consumer without them.
+	ErrConsumerMultipleFilterSubjectsNotSupported JetStreamError = &jsError{message: "multiple consumer filter subjects not supported by nats-server"}
+
 	// ErrNoMessages is returned when no messages are currently available for a consumer.
 	ErrNoMessages = &jsError{message: "no messages"}
 
@@ -124,6 +210,10 @@ var (
 	// ErrConsumerLeadershipChanged is returned when pending requests are no longer valid after leadership has changed.
 	ErrConsumerLeadershipChanged JetStreamError = &jsError{message: "leadership change"}
 
+	// ErrServerShutdown is passed to the consume error handler when the
+	// server the pull requests were sent to is shutting down.
+	ErrServerShutdown JetStreamError = &jsError{message: "server shutdown"}
+
 	// ErrHandlerRequired is returned when no handler func is provided in Stream().
 	ErrHandlerRequired = &jsError{message: "handler cannot be empty"}
 
@@ -133,6 +223,11 @@ var (
 	// ErrNoHeartbeat is received when no message is received in IdleHeartbeat time (if set).
 	ErrNoHeartbeat = &jsError{message: "no heartbeat received"}
 
+	// ErrConsumerStalled is passed to the consume error handler when no
+	// messages or heartbeats were received within the stall timeout while
+	// the consumer has pending messages. The pull request is reissued.
+	ErrConsumerStalled = &jsError{message: "consumer stalled"}
+
 	// ErrConsumerHasActiveSubscription is returned when a consumer is already subscribed to a stream.
 	ErrConsumerHasActiveSubscription = &jsError{message: "consumer has active subscription"}
 
@@ -151,6 +246,9 @@ var (
 	// ErrTooManyStalledMsgs is returned when too many outstanding async messages are waiting for ack.
 	ErrTooManyStalledMsgs = &jsError{message: "stalled with too many outstanding async published messages"}
 
+	// ErrPublishBatchFailed is returned by [Publisher.PublishBatch] when publishing any message of the batch failed.
+	ErrPublishBatchFailed JetStreamError = &jsError{message: "publishing batch failed"}
+
 	// ErrInvalidOption is returned when there is a collision between options.
 	ErrInvalidOption = &jsError{message: "invalid jetstream option"}
 
@@ -175,6 +273,98 @@ var (
 	// ErrOrderedConsumerNotCreated is returned when trying to get consumer info of an
 	// ordered consumer which was not yet created.
 	ErrOrderedConsumerNotCreated = &jsError{message: "consumer instance not yet created"}
+
+	// ErrStreamNotMirror is returned when attempting to monitor mirror lag of a stream
+	// which is not a mirror.
+	ErrStreamNotMirror JetStreamError = &jsError{message: "stream is not a mirror"}
+
+	// ErrStreamQuotaExceeded is returned by [QuotaGuard] when publishing to a stream
+	// which is projected to reach its limits.
+	ErrStreamQuotaExceeded JetStreamError = &jsError{message: "stream quota exceeded"}
+
+	// ErrBroadcastFailed is returned when publishing to at least one of the broadcast destinations failed.
+	ErrBroadcastFailed = &jsError{message: "broadcast failed"}
+
+	// ErrKeyedPublisherClosed is returned when publishing with a closed [KeyedPublisher].
+	ErrKeyedPublisherClosed JetStreamError = &jsError{message: "keyed publisher closed"}
+
+	// ErrKeyRequired is returned when publishing with a [KeyedPublisher] without a key.
+	ErrKeyRequired JetStreamError = &jsError{message: "key is required"}
+
+	// ErrPublishLost is returned by a [SequencedPublisher] for messages which
+	// were not acknowledged while messages published after them were.
+	ErrPublishLost JetStreamError = &jsError{message: "publish lost"}
+
+	// ErrInvalidBucketName is returned when the provided bucket name is invalid.
+	ErrInvalidBucketName JetStreamError = &jsError{message: "invalid bucket name"}
+
+	// ErrInvalidKey is returned when the provided key is invalid.
+	ErrInvalidKey JetStreamError = &jsError{message: "invalid key"}
+
+	// ErrBucketNotFound is returned when the key-value bucket does not exist.
+	ErrBucketNotFound JetStreamError = &jsError{message: "bucket not found"}
+
+	// ErrBadBucket is returned when the stream backing a bucket is not a valid key-value store.
+	ErrBadBucket JetStreamError = &jsError{message: "bucket not valid key-value store"}
+
+	// ErrKeyNotFound is returned when the key does not exist or was deleted.
+	ErrKeyNotFound JetStreamError = &jsError{message: "key not found"}
+
+	// ErrHistoryTooLarge is returned when the requested history exceeds [KeyValueMaxHistory].
+	ErrHistoryTooLarge JetStreamError = &jsError{message: "history limited to a max of 64"}
+
+	// ErrKeyTTLNotEnabled is returned by [KeyValue.PutWithTTL] when the bucket
+	// was not created with [KeyValueConfig.LimitMarkerTTL].
+	ErrKeyTTLNotEnabled JetStreamError = &jsError{message: "per-key TTL not enabled for bucket"}
+
+	// ErrNoKeysFound is returned when the bucket holds no keys.
+	ErrNoKeysFound JetStreamError = &jsError{message: "no keys found"}
+
+	// ErrInvalidStoreName is returned when the provided object store name is invalid.
+	ErrInvalidStoreName JetStreamError = &jsError{message: "invalid object-store name"}
+
+	// ErrObjectNotFound is returned when the object does not exist or was deleted.
+	ErrObjectNotFound JetStreamError = &jsError{message: "object not found"}
+
+	// ErrObjectNameRequired is returned when the provided object name is empty.
+	ErrObjectNameRequired JetStreamError = &jsError{message: "object name is required"}
+
+	// ErrBadObjectMeta is returned when object meta information is invalid.
+	ErrBadObjectMeta JetStreamError = &jsError{message: "object-store meta information invalid"}
+
+	// ErrLinkNotAllowed is returned when putting an object with a link set in its options.
+	ErrLinkNotAllowed JetStreamError = &jsError{message: "link cannot be set when putting the object in bucket"}
+
+	// ErrObjectIsLink is returned when getting the contents of a link to a bucket, or of
+	// a link to an object which is a link itself.
+	ErrObjectIsLink JetStreamError = &jsError{message: "object is a link"}
+
+	// ErrObjectRequired is returned when linking to an object without a name.
+	ErrObjectRequired JetStreamError = &jsError{message: "object required"}
+
+	// ErrObjectAlreadyExists is returned when a link or a renamed object would replace an existing object.
+	ErrObjectAlreadyExists JetStreamError = &jsError{message: "an object already exists with that name"}
+
+	// ErrNoLinkToDeleted is returned when linking to a deleted object.
+	ErrNoLinkToDeleted JetStreamError = &jsError{message: "not allowed to link to a deleted object"}
+
+	// ErrNoLinkToLink is returned when linking to a link.
+	ErrNoLinkToLink JetStreamError = &jsError{message: "not allowed to link to another link"}
+
+	// ErrBucketRequired is returned when linking to a nil object store.
+	ErrBucketRequired JetStreamError = &jsError{message: "bucket required"}
+
+	// ErrUpdateMetaDeleted is returned when updating the meta of a deleted object.
+	ErrUpdateMetaDeleted JetStreamError = &jsError{message: "cannot update meta for a deleted object"}
+
+	// ErrDigestMismatch is returned when the digest of a received object does not match its info.
+	ErrDigestMismatch JetStreamError = &jsError{message: "received a corrupt object, digests do not match"}
+
+	// ErrInvalidDigestFormat is returned when the object digest has an invalid format.
+	ErrInvalidDigestFormat JetStreamError = &jsError{message: "object digest hash has invalid format"}
+
+	// ErrNoObjectsFound is returned when the bucket holds no objects.
+	ErrNoObjectsFound JetStreamError = &jsError{message: "no objects found"}
 )
 
 // Error prints the JetStream API error code and description
@@ -201,6 +391,39 @@ func (e *APIError) Is(err error) bool {
 	return e.ErrorCode == aerr.ErrorCode
 }
 
+// APIError implements the JetStreamError interface.
+func (e *ErrStreamLimitsExceeded) APIError() *APIError {
+	return e.apiErr
+}
+
+func (e *ErrStreamLimitsExceeded) Error() string {
+	return fmt.Sprintf("nats: stream %s limit exceeded", e.Limit)
+}
+
+func (e *ErrStreamLimitsExceeded) Unwrap() error {
+	return e.apiErr
+}
+
+// streamLimitsError returns [ErrStreamLimitsExceeded] if the API error
+// reports a reached stream limit, based on its description.
+func streamLimitsError(apiErr *APIError) error {
+	if apiErr.ErrorCode != JSErrCodeStreamStoreFailed {
+		return nil
+	}
+	var limit UsageLimit
+	switch apiErr.Description {
+	case "maximum messages exceeded":
+		limit = UsageLimitMsgs
+	case "maximum bytes exceeded":
+		limit = UsageLimitBytes
+	case "maximum messages per subject exceeded":
+		limit = UsageLimitMsgsPerSubject
+	default:
+		return nil
+	}
+	return &ErrStreamLimitsExceeded{Limit: limit, apiErr: apiErr}
+}
+
 func (err *jsError) APIError() *APIError {
 	return err.apiErr
 }
@@ -215,7 +438,7 @@ func (err *jsError) Error() string {
 func (err *jsError) Unwrap() error {
 	// Allow matching to embedded APIError in case there is one.
 	if err.apiErr == nil {
-		return nil
+		return err.wrapped
 	}
 	return err.apiErr
 }
@@ -0,0 +1,180 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+)
+
+type (
+	// IdempotentPublisher is a [Publisher] setting a deterministic
+	// [MsgIDHeader] on every message, so that messages published more than
+	// once, e.g. when retrying after a timeout, are deduplicated by the
+	// stream within its duplicate window.
+	//
+	// By default the message ID is derived from a hash of the subject and
+	// payload, so identical messages published to the same subject within
+	// the duplicate window are stored only once. Use [WithMsgIDFunc] to
+	// derive it from e.g. a business key instead. Message IDs already set
+	// on a message are kept.
+	IdempotentPublisher struct {
+		Publisher
+		opts idempotentPublisherOpts
+	}
+
+	// IdempotentPublisherOpt configures an [IdempotentPublisher].
+	IdempotentPublisherOpt func(*idempotentPublisherOpts) error
+
+	// MsgIDFunc derives the message ID of a message published with an [IdempotentPublisher].
+	MsgIDFunc func(*nats.Msg) (string, error)
+
+	idempotentPublisherOpts struct {
+		msgID  MsgIDFunc
+		stream string
+	}
+)
+
+// WithMsgIDFunc sets the function deriving message IDs. The function has to
+// return the same ID for every publish of a logical message.
+func WithMsgIDFunc(fn MsgIDFunc) IdempotentPublisherOpt {
+	return func(opts *idempotentPublisherOpts) error {
+		if fn == nil {
+			return fmt.Errorf("%w: message ID function cannot be nil", ErrInvalidOption)
+		}
+		opts.msgID = fn
+		return nil
+	}
+}
+
+// WithIdempotentExpectStream sets the stream expected to store published
+// messages, as deduplication only works within a single stream.
+func WithIdempotentExpectStream(stream string) IdempotentPublisherOpt {
+	return func(opts *idempotentPublisherOpts) error {
+		if err := validateStreamName(stream); err != nil {
+			return err
+		}
+		opts.stream = stream
+		return nil
+	}
+}
+
+// HashMsgID returns the hex encoded SHA-256 hash of the message subject and payload.
+// It is the default [MsgIDFunc] of an [IdempotentPublisher].
+func HashMsgID(msg *nats.Msg) (string, error) {
+	h := sha256.New()
+	h.Write([]byte(msg.Subject))
+	h.Write([]byte{0})
+	h.Write(msg.Data)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// NewIdempotentPublisher creates an [IdempotentPublisher] publishing with the given publisher.
+//
+// Available options:
+// [WithMsgIDFunc] - sets the function deriving message IDs, default is [HashMsgID]
+// [WithIdempotentExpectStream] - sets the stream expected to store published messages
+func NewIdempotentPublisher(js Publisher, opts ...IdempotentPublisherOpt) (*IdempotentPublisher, error) {
+	o := idempotentPublisherOpts{msgID: HashMsgID}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+	return &IdempotentPublisher{
+		Publisher: js,
+		opts:      o,
+	}, nil
+}
+
+// Publish performs a synchronous publish with a derived message ID.
+func (p *IdempotentPublisher) Publish(ctx context.Context, subject string, data []byte, opts ...PublishOpt) (*PubAck, error) {
+	return p.PublishMsg(ctx, &nats.Msg{Subject: subject, Data: data}, opts...)
+}
+
+// PublishMsg performs a synchronous publish with a derived message ID.
+func (p *IdempotentPublisher) PublishMsg(ctx context.Context, msg *nats.Msg, opts ...PublishOpt) (*PubAck, error) {
+	m, err := p.withMsgID(msg)
+	if err != nil {
+		return nil, err
+	}
+	return p.Publisher.PublishMsg(ctx, m, opts...)
+}
+
+// PublishAsync performs an asynchronous publish with a derived message ID.
+func (p *IdempotentPublisher) PublishAsync(ctx context.Context, subject string, data []byte, opts ...PublishOpt) (PubAckFuture, error) {
+	return p.PublishMsgAsync(ctx, &nats.Msg{Subject: subject, Data: data}, opts...)
+}
+
+// PublishMsgAsync performs an asynchronous publish with a derived message ID.
+func (p *IdempotentPublisher) PublishMsgAsync(ctx context.Context, msg *nats.Msg, opts ...PublishOpt) (PubAckFuture, error) {
+	m, err := p.withMsgID(msg)
+	if err != nil {
+		return nil, err
+	}
+	return p.Publisher.PublishMsgAsync(ctx, m, opts...)
+}
+
+// PublishAsyncWithCallback performs an asynchronous publish with a derived message ID.
+func (p *IdempotentPublisher) PublishAsyncWithCallback(ctx context.Context, msg *nats.Msg, cb PubAckHandler, opts ...PublishOpt) error {
+	m, err := p.withMsgID(msg)
+	if err != nil {
+		return err
+	}
+	return p.Publisher.PublishAsyncWithCallback(ctx, m, cb, opts...)
+}
+
+// PublishBatch publishes the messages with derived message IDs and waits for all acks.
+func (p *IdempotentPublisher) PublishBatch(ctx context.Context, msgs []*nats.Msg) ([]PublishBatchResult, error) {
+	batch := make([]*nats.Msg, len(msgs))
+	for i, msg := range msgs {
+		m, err := p.withMsgID(msg)
+		if err != nil {
+			return nil, err
+		}
+		batch[i] = m
+	}
+	return p.Publisher.PublishBatch(ctx, batch)
+}
+
+// withMsgID returns a copy of the message with the message ID and expected
+// stream headers set. Message IDs already set on the message are kept.
+func (p *IdempotentPublisher) withMsgID(msg *nats.Msg) (*nats.Msg, error) {
+	id := msg.Header.Get(MsgIDHeader)
+	if id != "" && p.opts.stream == "" {
+		return msg, nil
+	}
+	if id == "" {
+		var err error
+		if id, err = p.opts.msgID(msg); err != nil {
+			return nil, err
+		}
+		if id == "" {
+			return nil, fmt.Errorf("%w: message ID cannot be empty", ErrInvalidOption)
+		}
+	}
+	hdr := nats.Header{}
+	for k, v := range msg.Header {
+		hdr[k] = v
+	}
+	hdr.Set(MsgIDHeader, id)
+	if p.opts.stream != "" {
+		hdr.Set(ExpectedStreamHeader, p.opts.stream)
+	}
+	return &nats.Msg{Subject: msg.Subject, Reply: msg.Reply, Header: hdr, Data: msg.Data}, nil
+}
@@ -0,0 +1,26 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import "github.com/nats-io/nats.go"
+
+// InstrumentHandler wraps a [MessageHandler] used in [Consume] the same way
+// [nats.InstrumentHandler] wraps core subscription handlers, reporting latency,
+// payload size and outcome to metrics and recovering panics.
+// Either metrics or logger may be nil.
+func InstrumentHandler(h MessageHandler, metrics nats.HandlerMetrics, logger nats.HandlerLogger) MessageHandler {
+	return func(msg Msg) {
+		nats.RunInstrumented(msg.Subject(), len(msg.Data()), func() { h(msg) }, metrics, logger)
+	}
+}
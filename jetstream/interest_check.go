@@ -0,0 +1,221 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nats-io/nats.go/subjects"
+)
+
+type (
+	// InterestReport lists consumer configurations of a stream with
+	// [InterestPolicy] or [WorkQueuePolicy] retention which lose or strand
+	// messages.
+	InterestReport struct {
+		// Stream is the name of the stream.
+		Stream string
+		// Retention is the retention policy of the stream.
+		Retention RetentionPolicy
+		// Uncovered are stream subjects not entirely matched by the filter
+		// of any consumer. Messages no consumer is interested in are removed
+		// right away from interest streams, while they are never removed
+		// from work queue streams.
+		Uncovered []string
+		// Overlapping are pairs of consumers of a work queue stream whose
+		// filters match common subjects, which the server rejects.
+		Overlapping [][2]string
+	}
+
+	// InterestCheckHandler is invoked by [WithInterestCheck] with the report
+	// of the consumers a stream would have after creating or updating a
+	// consumer. Returning an error aborts the request.
+	InterestCheckHandler func(report *InterestReport) error
+)
+
+// WithInterestCheck makes creating or updating a consumer on a stream with
+// [InterestPolicy] or [WorkQueuePolicy] retention check the resulting set of
+// consumers and invoke the handler if any of them lose or strand messages.
+// The handler can log a warning, or return [InterestReport.Err] to reject the
+// configuration. Checking requires fetching the stream info and listing its
+// consumers.
+func WithInterestCheck(cb InterestCheckHandler) JetStreamOpt {
+	return func(opts *jsOpts) error {
+		if cb == nil {
+			return fmt.Errorf("%w: interest check handler cannot be nil", ErrInvalidOption)
+		}
+		opts.interestCheck = cb
+		return nil
+	}
+}
+
+// OK reports whether no issues were found.
+func (r *InterestReport) OK() bool {
+	return len(r.Uncovered) == 0 && len(r.Overlapping) == 0
+}
+
+// Err returns an error wrapping [ErrUnsafeInterestConsumers] describing the
+// issues, or nil if none were found.
+func (r *InterestReport) Err() error {
+	if r.OK() {
+		return nil
+	}
+	var issues []string
+	if len(r.Uncovered) > 0 {
+		issues = append(issues, fmt.Sprintf("no consumer for subjects %s", strings.Join(r.Uncovered, ", ")))
+	}
+	for _, pair := range r.Overlapping {
+		issues = append(issues, fmt.Sprintf("consumers %q and %q overlap", pair[0], pair[1]))
+	}
+	return fmt.Errorf("%w: stream %q: %s", ErrUnsafeInterestConsumers, r.Stream, strings.Join(issues, "; "))
+}
+
+// CheckInterestConsumers checks the consumers of a stream with
+// [InterestPolicy] or [WorkQueuePolicy] retention for subjects no consumer is
+// interested in and, on work queue streams, for overlapping filters. Streams
+// with other retention policies, or without subjects such as mirrors, always
+// yield an empty report.
+func CheckInterestConsumers(stream StreamConfig, consumers []ConsumerConfig) *InterestReport {
+	report := &InterestReport{Stream: stream.Name, Retention: stream.Retention}
+	if stream.Retention != InterestPolicy && stream.Retention != WorkQueuePolicy {
+		return report
+	}
+
+	filters := make([][]string, len(consumers))
+	for i, cfg := range consumers {
+		filters[i] = consumerFilters(cfg)
+	}
+	for _, subject := range stream.Subjects {
+		covered := false
+		for _, fs := range filters {
+			for _, filter := range fs {
+				if subjects.IsSubset(subject, filter) {
+					covered = true
+					break
+				}
+			}
+			if covered {
+				break
+			}
+		}
+		if !covered {
+			report.Uncovered = append(report.Uncovered, subject)
+		}
+	}
+
+	if stream.Retention == WorkQueuePolicy {
+		for i := range consumers {
+			for j := i + 1; j < len(consumers); j++ {
+				if filtersOverlap(filters[i], filters[j]) {
+					report.Overlapping = append(report.Overlapping, [2]string{consumerName(consumers[i]), consumerName(consumers[j])})
+				}
+			}
+		}
+	}
+	return report
+}
+
+// CheckStreamInterest fetches the configuration of the stream and its
+// consumers and checks them using [CheckInterestConsumers].
+func CheckStreamInterest(ctx context.Context, stream Stream) (*InterestReport, error) {
+	info, err := stream.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+	consumers, err := listConsumerConfigs(ctx, stream)
+	if err != nil {
+		return nil, err
+	}
+	return CheckInterestConsumers(info.Config, consumers), nil
+}
+
+// checkInterest invokes the interest check handler with the consumers the
+// stream would have once the consumer is created or updated.
+func (js *jetStream) checkInterest(ctx context.Context, streamName string, cfg ConsumerConfig) error {
+	if js.interestCheck == nil {
+		return nil
+	}
+	s, err := js.Stream(ctx, streamName)
+	if err != nil {
+		return err
+	}
+	info := s.CachedInfo()
+	if info.Config.Retention != InterestPolicy && info.Config.Retention != WorkQueuePolicy {
+		return nil
+	}
+	existing, err := listConsumerConfigs(ctx, s)
+	if err != nil {
+		return err
+	}
+	name := consumerName(cfg)
+	consumers := []ConsumerConfig{cfg}
+	for _, c := range existing {
+		if name == "" || consumerName(c) != name {
+			consumers = append(consumers, c)
+		}
+	}
+	report := CheckInterestConsumers(info.Config, consumers)
+	if report.OK() {
+		return nil
+	}
+	return js.interestCheck(report)
+}
+
+func listConsumerConfigs(ctx context.Context, stream Stream) ([]ConsumerConfig, error) {
+	var consumers []ConsumerConfig
+	l := stream.ListConsumers(ctx)
+	for {
+		select {
+		case info := <-l.Info():
+			consumers = append(consumers, info.Config)
+		case err := <-l.Err():
+			if errors.Is(err, ErrEndOfData) {
+				return consumers, nil
+			}
+			return nil, err
+		}
+	}
+}
+
+// consumerFilters returns the filter subjects of a consumer, ">" if unfiltered.
+func consumerFilters(cfg ConsumerConfig) []string {
+	if cfg.FilterSubject != "" {
+		return []string{cfg.FilterSubject}
+	}
+	if len(cfg.FilterSubjects) > 0 {
+		return cfg.FilterSubjects
+	}
+	return []string{">"}
+}
+
+func consumerName(cfg ConsumerConfig) string {
+	if cfg.Name != "" {
+		return cfg.Name
+	}
+	return cfg.Durable
+}
+
+func filtersOverlap(a, b []string) bool {
+	for _, fa := range a {
+		for _, fb := range b {
+			if subjects.Overlap(fa, fb) {
+				return true
+			}
+		}
+	}
+	return false
+}
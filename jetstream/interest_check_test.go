@@ -0,0 +1,89 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCheckInterestConsumers(t *testing.T) {
+	tests := []struct {
+		name        string
+		retention   RetentionPolicy
+		consumers   []ConsumerConfig
+		uncovered   []string
+		overlapping [][2]string
+	}{
+		{
+			name:      "limits retention is not checked",
+			retention: LimitsPolicy,
+		},
+		{
+			name:      "no consumers",
+			retention: InterestPolicy,
+			uncovered: []string{"orders.>", "invoices.*"},
+		},
+		{
+			name:      "unfiltered consumer covers all subjects",
+			retention: InterestPolicy,
+			consumers: []ConsumerConfig{{Durable: "all"}},
+		},
+		{
+			name:      "partially covered subject",
+			retention: InterestPolicy,
+			consumers: []ConsumerConfig{
+				{Durable: "new", FilterSubject: "orders.new"},
+				{Durable: "invoices", FilterSubjects: []string{"invoices.>"}},
+			},
+			uncovered: []string{"orders.>"},
+		},
+		{
+			name:      "overlapping filters on interest stream",
+			retention: InterestPolicy,
+			consumers: []ConsumerConfig{
+				{Durable: "orders", FilterSubject: "orders.>"},
+				{Durable: "new", FilterSubject: "orders.new"},
+				{Durable: "invoices", FilterSubject: "invoices.*"},
+			},
+		},
+		{
+			name:      "overlapping filters on work queue stream",
+			retention: WorkQueuePolicy,
+			consumers: []ConsumerConfig{
+				{Name: "orders", FilterSubject: "orders.>"},
+				{Name: "new", FilterSubject: "orders.new"},
+				{Name: "invoices", FilterSubjects: []string{"invoices.a", "invoices.*"}},
+			},
+			overlapping: [][2]string{{"orders", "new"}},
+		},
+	}
+	stream := StreamConfig{Name: "S", Subjects: []string{"orders.>", "invoices.*"}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stream.Retention = test.retention
+			report := CheckInterestConsumers(stream, test.consumers)
+			if !reflect.DeepEqual(report.Uncovered, test.uncovered) {
+				t.Fatalf("Invalid uncovered subjects; want: %v; got: %v", test.uncovered, report.Uncovered)
+			}
+			if !reflect.DeepEqual(report.Overlapping, test.overlapping) {
+				t.Fatalf("Invalid overlapping consumers; want: %v; got: %v", test.overlapping, report.Overlapping)
+			}
+			if err := report.Err(); report.OK() != (err == nil) || (err != nil && !errors.Is(err, ErrUnsafeInterestConsumers)) {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nuid"
@@ -37,8 +38,13 @@ type (
 		// Returns *AccountInfo, containing details about the account associated with this JetStream connection
 		AccountInfo(ctx context.Context) (*AccountInfo, error)
 
+		// ClockSkew returns the estimated offset of the server clock from the client clock
+		ClockSkew() ClockSkew
+
 		StreamConsumerManager
 		StreamManager
+		KeyValueManager
+		ObjectStoreManager
 		Publisher
 	}
 
@@ -53,12 +59,19 @@ type (
 		// It accepts subject name (which must be bound to a stream) and message data
 		PublishAsync(context.Context, string, []byte, ...PublishOpt) (PubAckFuture, error)
 		// PublishMsgAsync performs a asynchronous publish to a stream and returns [PubAckFuture] interface
-		// It accepts subject name (which must be bound to a stream) and nats.Message
+		// It accepts subject name (which must be bound to a stream) and nats.Message.
+		// A message over the maximum payload is rejected with [nats.ErrMaxPayloadExceeded].
 		PublishMsgAsync(context.Context, *nats.Msg, ...PublishOpt) (PubAckFuture, error)
 		// PublishAsyncPending returns the number of async publishes outstanding for this context
 		PublishAsyncPending() int
 		// PublishAsyncComplete returns a channel that will be closed when all outstanding messages are ack'd
 		PublishAsyncComplete() <-chan struct{}
+		// PublishAsyncWithCallback performs an asynchronous publish to a stream and delivers
+		// the ack or error to the provided callback instead of returning a [PubAckFuture]
+		PublishAsyncWithCallback(context.Context, *nats.Msg, PubAckHandler, ...PublishOpt) error
+		// PublishBatch publishes the messages asynchronously and waits for all acks,
+		// returning a result for each message, in order.
+		PublishBatch(context.Context, []*nats.Msg) ([]PublishBatchResult, error)
 	}
 
 	StreamManager interface {
@@ -81,6 +94,9 @@ type (
 		// If consumer already exists, it will be updated (if possible).
 		// Consumer interface is returned, serving as a hook to operate on a consumer (e.g. fetch messages)
 		AddConsumer(context.Context, string, ConsumerConfig) (Consumer, error)
+		// UpdateConsumer updates the configuration of an existing consumer on a given stream.
+		// ErrConsumerNotFound is returned if the consumer does not exist.
+		UpdateConsumer(context.Context, string, ConsumerConfig) (Consumer, error)
 		// OrderedConsumer returns an OrderedConsumer instance.
 		// OrderedConsumer allows fetching messages from a stream (just like standard consumer),
 		// for in order delivery of messages. Underlying consumer is re-created when necessary,
@@ -93,13 +109,26 @@ type (
 	}
 
 	// AccountInfo contains info about the JetStream usage from the current account.
+	// Usage and limits of accounts with tiered limits, i.e. limits set per
+	// replica count, are reported per tier in Tiers.
 	AccountInfo struct {
+		Memory    uint64          `json:"memory"`
+		Store     uint64          `json:"storage"`
+		Streams   int             `json:"streams"`
+		Consumers int             `json:"consumers"`
+		Domain    string          `json:"domain"`
+		API       APIStats        `json:"api"`
+		Limits    AccountLimits   `json:"limits"`
+		Tiers     map[string]Tier `json:"tiers"`
+	}
+
+	// Tier contains the JetStream usage and limits of a tier, named after the
+	// replica count of the streams it applies to, e.g. "R1" or "R3".
+	Tier struct {
 		Memory    uint64        `json:"memory"`
 		Store     uint64        `json:"storage"`
 		Streams   int           `json:"streams"`
 		Consumers int           `json:"consumers"`
-		Domain    string        `json:"domain"`
-		API       APIStats      `json:"api"`
 		Limits    AccountLimits `json:"limits"`
 	}
 
@@ -110,11 +139,16 @@ type (
 	}
 
 	// AccountLimits includes the JetStream limits of the current account.
+	// Negative values denote no limit.
 	AccountLimits struct {
-		MaxMemory    int64 `json:"max_memory"`
-		MaxStore     int64 `json:"max_storage"`
-		MaxStreams   int   `json:"max_streams"`
-		MaxConsumers int   `json:"max_consumers"`
+		MaxMemory            int64 `json:"max_memory"`
+		MaxStore             int64 `json:"max_storage"`
+		MaxStreams           int   `json:"max_streams"`
+		MaxConsumers         int   `json:"max_consumers"`
+		MaxAckPending        int   `json:"max_ack_pending"`
+		MemoryMaxStreamBytes int64 `json:"memory_max_stream_bytes"`
+		StoreMaxStreamBytes  int64 `json:"storage_max_stream_bytes"`
+		MaxBytesRequired     bool  `json:"max_bytes_required"`
 	}
 
 	jetStream struct {
@@ -122,6 +156,7 @@ type (
 		jsOpts
 
 		publisher *jetStreamClient
+		clockSkew clockSkewTracker
 	}
 
 	JetStreamOpt func(*jsOpts) error
@@ -130,12 +165,43 @@ type (
 		publisherOpts asyncPublisherOpts
 		apiPrefix     string
 		clientTrace   *ClientTrace
+		timeouts      Timeouts
+		retry         RetryPolicy
+
+		clockSkewThreshold time.Duration
+		clockSkewHandler   ClockSkewHandler
+
+		interestCheck InterestCheckHandler
+	}
+
+	// Timeouts sets the timeouts of JetStream API requests made with a
+	// context without a deadline, see [WithTimeouts]. A zero value waits
+	// until the context is canceled.
+	Timeouts struct {
+		// Get applies to requests reading stream, consumer and account
+		// information and to getting messages.
+		Get time.Duration
+		// Create applies to requests creating, updating, purging and
+		// deleting streams, consumers and messages.
+		Create time.Duration
+		// List applies to each page of stream and consumer listings.
+		List time.Duration
+		// Snapshot applies to stream snapshot and restore requests.
+		Snapshot time.Duration
 	}
 
 	// ClientTrace can be used to trace API interactions for the JetStream Context.
 	ClientTrace struct {
 		RequestSent      func(subj string, payload []byte)
 		ResponseReceived func(subj string, payload []byte, hdr nats.Header)
+		// RequestCompleted is invoked once each API round trip completed,
+		// with the request and response payloads and the time elapsed
+		// between sending the request and receiving the response. If the
+		// request failed, e.g. timed out, response is nil and err is set.
+		RequestCompleted func(subj string, request, response []byte, elapsed time.Duration, err error)
+		// Redactor masks payloads and headers before they are passed to the
+		// callbacks. Defaults to the redactor set with [nats.TraceRedactor], if any.
+		Redactor nats.Redactor
 	}
 	streamInfoResponse struct {
 		apiResponse
@@ -194,12 +260,16 @@ type (
 // [WithClientTrace] - enables request/response tracing
 // [WithPublishAsyncErrHandler] - sets error handler for async message publish
 // [WithPublishAsyncMaxPending] - sets the maximum outstanding async publishes that can be inflight at one time.
+// [WithPublishAsyncRetry] - sets the default retries of async publishes getting no responders.
 // [WithDirectGet] - specifies whether client should use direct get requests.
+// [WithTimeouts] - sets the timeouts of API requests made without a context deadline.
+// [WithUnavailableRetry] - retries API requests failing while JetStream is temporarily unavailable.
 func New(nc *nats.Conn, opts ...JetStreamOpt) (JetStream, error) {
 	jsOpts := jsOpts{
 		apiPrefix: DefaultAPIPrefix,
 		publisherOpts: asyncPublisherOpts{
-			maxpa: defaultAsyncPubAckInflight,
+			maxpa:     defaultAsyncPubAckInflight,
+			retryWait: DefaultPubRetryWait,
 		},
 	}
 	for _, opt := range opts {
@@ -221,17 +291,31 @@ const (
 	defaultAsyncPubAckInflight = 4000
 )
 
+// DefaultTimeouts are recommended API request timeouts, to be set with
+// [WithTimeouts]. Without it, requests made with a context without a
+// deadline wait until the context is canceled.
+var DefaultTimeouts = Timeouts{
+	Get:      2 * time.Second,
+	Create:   10 * time.Second,
+	List:     10 * time.Second,
+	Snapshot: 10 * time.Minute,
+}
+
 // NewWithAPIPrefix returns a new JetStream instance and sets the API prefix to be used in requests to JetStream API
 //
 // Available options:
 // [WithClientTrace] - enables request/response tracing
 // [WithPublishAsyncErrHandler] - sets error handler for async message publish
 // [WithPublishAsyncMaxPending] - sets the maximum outstanding async publishes that can be inflight at one time.
+// [WithPublishAsyncRetry] - sets the default retries of async publishes getting no responders.
 // [WithDirectGet] - specifies whether client should use direct get requests.
+// [WithTimeouts] - sets the timeouts of API requests made without a context deadline.
+// [WithUnavailableRetry] - retries API requests failing while JetStream is temporarily unavailable.
 func NewWithAPIPrefix(nc *nats.Conn, apiPrefix string, opts ...JetStreamOpt) (JetStream, error) {
 	jsOpts := jsOpts{
 		publisherOpts: asyncPublisherOpts{
-			maxpa: defaultAsyncPubAckInflight,
+			maxpa:     defaultAsyncPubAckInflight,
+			retryWait: DefaultPubRetryWait,
 		},
 	}
 	for _, opt := range opts {
@@ -259,11 +343,15 @@ func NewWithAPIPrefix(nc *nats.Conn, apiPrefix string, opts ...JetStreamOpt) (Je
 // [WithClientTrace] - enables request/response tracing
 // [WithPublishAsyncErrHandler] - sets error handler for async message publish
 // [WithPublishAsyncMaxPending] - sets the maximum outstanding async publishes that can be inflight at one time.
+// [WithPublishAsyncRetry] - sets the default retries of async publishes getting no responders.
 // [WithDirectGet] - specifies whether client should use direct get requests.
+// [WithTimeouts] - sets the timeouts of API requests made without a context deadline.
+// [WithUnavailableRetry] - retries API requests failing while JetStream is temporarily unavailable.
 func NewWithDomain(nc *nats.Conn, domain string, opts ...JetStreamOpt) (JetStream, error) {
 	jsOpts := jsOpts{
 		publisherOpts: asyncPublisherOpts{
-			maxpa: defaultAsyncPubAckInflight,
+			maxpa:     defaultAsyncPubAckInflight,
+			retryWait: DefaultPubRetryWait,
 		},
 	}
 	for _, opt := range opts {
@@ -329,6 +417,19 @@ func (js *jetStream) CreateStream(ctx context.Context, cfg StreamConfig) (Stream
 		return nil, resp.Error
 	}
 
+	// check that the subject transform was applied, older servers silently ignore it
+	if cfg.SubjectTransform != nil && resp.StreamInfo.Config.SubjectTransform == nil {
+		return nil, ErrStreamSubjectTransformNotSupported
+	}
+	// check that compression was applied, older servers silently ignore it
+	if cfg.Compression != NoCompression && resp.StreamInfo.Config.Compression != cfg.Compression {
+		return nil, ErrStreamCompressionNotSupported
+	}
+	// check that message TTLs were allowed, older servers silently ignore it
+	if cfg.AllowMsgTTL && !resp.StreamInfo.Config.AllowMsgTTL {
+		return nil, ErrStreamMsgTTLNotSupported
+	}
+
 	return &stream{
 		jetStream: js,
 		name:      cfg.Name,
@@ -388,6 +489,19 @@ func (js *jetStream) UpdateStream(ctx context.Context, cfg StreamConfig) (Stream
 		return nil, resp.Error
 	}
 
+	// check that the subject transform was applied, older servers silently ignore it
+	if cfg.SubjectTransform != nil && resp.StreamInfo.Config.SubjectTransform == nil {
+		return nil, ErrStreamSubjectTransformNotSupported
+	}
+	// check that compression was applied, older servers silently ignore it
+	if cfg.Compression != NoCompression && resp.StreamInfo.Config.Compression != cfg.Compression {
+		return nil, ErrStreamCompressionNotSupported
+	}
+	// check that message TTLs were allowed, older servers silently ignore it
+	if cfg.AllowMsgTTL && !resp.StreamInfo.Config.AllowMsgTTL {
+		return nil, ErrStreamMsgTTLNotSupported
+	}
+
 	return &stream{
 		jetStream: js,
 		name:      cfg.Name,
@@ -450,16 +564,27 @@ func (js *jetStream) AddConsumer(ctx context.Context, stream string, cfg Consume
 	return upsertConsumer(ctx, js, stream, cfg)
 }
 
+// UpdateConsumer updates the configuration of an existing consumer on a given stream
+func (js *jetStream) UpdateConsumer(ctx context.Context, stream string, cfg ConsumerConfig) (Consumer, error) {
+	if err := validateStreamName(stream); err != nil {
+		return nil, err
+	}
+	return updateConsumer(ctx, js, stream, cfg)
+}
+
 func (js *jetStream) OrderedConsumer(ctx context.Context, stream string, cfg OrderedConsumerConfig) (Consumer, error) {
 	if err := validateStreamName(stream); err != nil {
 		return nil, err
 	}
+	if err := validateFilterSubjects(cfg.FilterSubjects); err != nil {
+		return nil, err
+	}
 	oc := &orderedConsumer{
 		jetStream:  js,
 		cfg:        &cfg,
 		stream:     stream,
 		namePrefix: nuid.Next(),
-		doReset:    make(chan struct{}, 1),
+		doReset:    make(chan error, 1),
 	}
 	if cfg.OptStartSeq != 0 {
 		oc.cursor.streamSeq = cfg.OptStartSeq - 1
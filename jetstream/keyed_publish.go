@@ -0,0 +1,239 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+type (
+	// KeyedPublisher publishes messages asynchronously while preserving
+	// their order per key, e.g. per entity in a change data capture stream.
+	// At most one message per key is in flight at a time, so that retries
+	// cannot reorder messages of a key. Messages of different keys are
+	// published concurrently.
+	//
+	// Each message gets a [KeySequenceHeader] header holding a sequence
+	// increasing by one with every message of its key, starting at 1, which
+	// lets consumers detect gaps left by failed publishes.
+	KeyedPublisher struct {
+		publisher Publisher
+		opts      keyedPublisherOpts
+
+		sync.Mutex
+		keys   map[string]*keyedQueue
+		closed bool
+		wg     sync.WaitGroup
+	}
+
+	// KeyedPublisherOpt configures a [KeyedPublisher].
+	KeyedPublisherOpt func(*keyedPublisherOpts) error
+
+	keyedPublisherOpts struct {
+		retry   RetryPolicy
+		timeout time.Duration
+	}
+
+	keyedQueue struct {
+		seq     uint64
+		pending []*keyedPubAckFuture
+		active  bool
+	}
+
+	keyedPubAckFuture struct {
+		msg   *nats.Msg
+		opts  []PublishOpt
+		okCh  chan *PubAck
+		errCh chan error
+	}
+)
+
+const (
+	// KeySequenceHeader holds the per key sequence of messages published with a [KeyedPublisher].
+	KeySequenceHeader = "Nats-Key-Sequence"
+
+	// DefaultKeyedPublishTimeout is the default timeout of a single publish attempt.
+	DefaultKeyedPublishTimeout = 5 * time.Second
+)
+
+// WithKeyedPublishRetry sets the retry policy of failed publishes. Publishes
+// rejected by the server are not retried. By default publishes are not retried.
+func WithKeyedPublishRetry(policy RetryPolicy) KeyedPublisherOpt {
+	return func(opts *keyedPublisherOpts) error {
+		if err := policy.validate(); err != nil {
+			return err
+		}
+		opts.retry = policy
+		return nil
+	}
+}
+
+// WithKeyedPublishTimeout sets the timeout of a single publish attempt.
+func WithKeyedPublishTimeout(timeout time.Duration) KeyedPublisherOpt {
+	return func(opts *keyedPublisherOpts) error {
+		if timeout <= 0 {
+			return fmt.Errorf("%w: timeout must be positive", ErrInvalidOption)
+		}
+		opts.timeout = timeout
+		return nil
+	}
+}
+
+// NewKeyedPublisher creates a [KeyedPublisher] publishing with the given publisher.
+//
+// Available options:
+// [WithKeyedPublishRetry] - sets the retry policy, by default failed publishes are not retried
+// [WithKeyedPublishTimeout] - sets the timeout of a single publish attempt, default is 5s
+func NewKeyedPublisher(js Publisher, opts ...KeyedPublisherOpt) (*KeyedPublisher, error) {
+	o := keyedPublisherOpts{timeout: DefaultKeyedPublishTimeout}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+	return &KeyedPublisher{
+		publisher: js,
+		opts:      o,
+		keys:      make(map[string]*keyedQueue),
+	}, nil
+}
+
+// PublishAsync publishes data to the subject after all previously
+// published messages of the key were acknowledged or failed.
+func (p *KeyedPublisher) PublishAsync(key, subject string, data []byte, opts ...PublishOpt) (PubAckFuture, error) {
+	return p.PublishMsgAsync(key, &nats.Msg{Subject: subject, Data: data}, opts...)
+}
+
+// PublishMsgAsync publishes the message after all previously published
+// messages of the key were acknowledged or failed. The message must not
+// be modified until the returned future completes.
+func (p *KeyedPublisher) PublishMsgAsync(key string, msg *nats.Msg, opts ...PublishOpt) (PubAckFuture, error) {
+	if key == "" {
+		return nil, ErrKeyRequired
+	}
+	p.Lock()
+	defer p.Unlock()
+	if p.closed {
+		return nil, ErrKeyedPublisherClosed
+	}
+	q, ok := p.keys[key]
+	if !ok {
+		q = &keyedQueue{}
+		p.keys[key] = q
+	}
+	q.seq++
+	if msg.Header == nil {
+		msg.Header = nats.Header{}
+	}
+	msg.Header.Set(KeySequenceHeader, strconv.FormatUint(q.seq, 10))
+	paf := &keyedPubAckFuture{
+		msg:   msg,
+		opts:  opts,
+		okCh:  make(chan *PubAck, 1),
+		errCh: make(chan error, 1),
+	}
+	q.pending = append(q.pending, paf)
+	if !q.active {
+		q.active = true
+		p.wg.Add(1)
+		go p.publishPending(q)
+	}
+	return paf, nil
+}
+
+// Sequence returns the sequence assigned to the last message published with the key.
+func (p *KeyedPublisher) Sequence(key string) uint64 {
+	p.Lock()
+	defer p.Unlock()
+	if q, ok := p.keys[key]; ok {
+		return q.seq
+	}
+	return 0
+}
+
+// Close stops accepting new messages and waits until all pending
+// messages are acknowledged or failed, or the context is done.
+func (p *KeyedPublisher) Close(ctx context.Context) error {
+	p.Lock()
+	p.closed = true
+	p.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// publishPending publishes the messages of a key one at a time until none are pending.
+func (p *KeyedPublisher) publishPending(q *keyedQueue) {
+	defer p.wg.Done()
+	for {
+		p.Lock()
+		if len(q.pending) == 0 {
+			q.active = false
+			p.Unlock()
+			return
+		}
+		paf := q.pending[0]
+		q.pending[0] = nil
+		q.pending = q.pending[1:]
+		p.Unlock()
+
+		ack, err := p.publish(paf)
+		if err != nil {
+			paf.errCh <- err
+			continue
+		}
+		paf.okCh <- ack
+	}
+}
+
+func (p *KeyedPublisher) publish(paf *keyedPubAckFuture) (*PubAck, error) {
+	backoff := p.opts.retry.backoff()
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), p.opts.timeout)
+		ack, err := p.publisher.PublishMsg(ctx, paf.msg, paf.opts...)
+		cancel()
+		var apiErr *APIError
+		if err == nil || errors.As(err, &apiErr) || backoff.Wait(context.Background()) != nil {
+			return ack, err
+		}
+	}
+}
+
+func (paf *keyedPubAckFuture) Ok() <-chan *PubAck {
+	return paf.okCh
+}
+
+func (paf *keyedPubAckFuture) Err() <-chan error {
+	return paf.errCh
+}
+
+func (paf *keyedPubAckFuture) Msg() *nats.Msg {
+	return paf.msg
+}
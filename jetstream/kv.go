@@ -0,0 +1,978 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+type (
+	// KeyValueManager is used to manage KeyValue stores.
+	KeyValueManager interface {
+		// KeyValue will lookup and bind to an existing KeyValue store.
+		KeyValue(ctx context.Context, bucket string) (KeyValue, error)
+		// CreateKeyValue will create a KeyValue store with the given configuration.
+		// If a bucket with identical configuration already exists, it is returned.
+		CreateKeyValue(ctx context.Context, cfg KeyValueConfig) (KeyValue, error)
+		// DeleteKeyValue will delete the KeyValue store (JetStream stream).
+		DeleteKeyValue(ctx context.Context, bucket string) error
+		// WatchBuckets will watch for all updates in the given buckets,
+		// merging them into a single [KeyWatcher].
+		WatchBuckets(ctx context.Context, buckets ...string) (KeyWatcher, error)
+	}
+
+	// KeyValue contains methods to operate on a KeyValue store.
+	KeyValue interface {
+		// Get returns the latest value for the key.
+		Get(ctx context.Context, key string) (KeyValueEntry, error)
+		// GetRevision returns a specific revision value for the key.
+		GetRevision(ctx context.Context, key string, revision uint64) (KeyValueEntry, error)
+		// Put will place the new value for the key into the store.
+		Put(ctx context.Context, key string, value []byte) (uint64, error)
+		// PutString will place the string for the key into the store.
+		PutString(ctx context.Context, key string, value string) (uint64, error)
+		// PutWithTTL will place the new value for the key into the store,
+		// removing it after the TTL regardless of the bucket TTL. The bucket
+		// has to be created with LimitMarkerTTL set.
+		PutWithTTL(ctx context.Context, key string, value []byte, ttl time.Duration) (uint64, error)
+		// Create will add the key/value pair if it does not exist.
+		Create(ctx context.Context, key string, value []byte) (uint64, error)
+		// Update will update the value if the latest revision matches.
+		Update(ctx context.Context, key string, value []byte, revision uint64) (uint64, error)
+		// Delete will place a delete marker and leave all revisions.
+		Delete(ctx context.Context, key string, opts ...KVDeleteOpt) error
+		// Purge will place a delete marker and remove all previous revisions.
+		Purge(ctx context.Context, key string, opts ...KVDeleteOpt) error
+		// Watch for any updates to keys that match the keys argument which could include wildcards.
+		// Watch will send a nil entry when it has received all initial values.
+		// The watcher is stopped when ctx is done.
+		Watch(ctx context.Context, keys string, opts ...WatchOpt) (KeyWatcher, error)
+		// WatchAll will watch for all updates in the bucket.
+		WatchAll(ctx context.Context, opts ...WatchOpt) (KeyWatcher, error)
+		// Keys will return all keys.
+		Keys(ctx context.Context, opts ...WatchOpt) ([]string, error)
+		// History will return all historical values for the key.
+		History(ctx context.Context, key string, opts ...WatchOpt) ([]KeyValueEntry, error)
+		// Bucket returns the current bucket name.
+		Bucket() string
+		// Status retrieves the status and configuration of a bucket.
+		Status(ctx context.Context) (KeyValueStatus, error)
+	}
+
+	// KeyValueConfig is for configuring a KeyValue store.
+	KeyValueConfig struct {
+		Bucket       string
+		Description  string
+		MaxValueSize int32
+		History      uint8
+		TTL          time.Duration
+		MaxBytes     int64
+		Storage      StorageType
+		Replicas     int
+		Placement    *Placement
+		RePublish    *RePublish
+		Mirror       *StreamSource
+		Sources      []*StreamSource
+
+		// LimitMarkerTTL enables per-key TTLs using [KeyValue.PutWithTTL].
+		// When a key expires, a purge marker is placed which is kept for
+		// the given duration, so that watchers are notified.
+		// Requires nats-server v2.11.0 or later.
+		LimitMarkerTTL time.Duration
+	}
+
+	// KeyValueStatus is run-time status about a Key-Value bucket.
+	KeyValueStatus interface {
+		// Bucket the name of the bucket
+		Bucket() string
+		// Values is how many messages are in the bucket, including historical values
+		Values() uint64
+		// History returns the configured history kept per key
+		History() int64
+		// TTL is how long the bucket keeps values for
+		TTL() time.Duration
+		// BackingStore indicates what technology is used for storage of the bucket
+		BackingStore() string
+		// Bytes returns the size in bytes of the bucket
+		Bytes() uint64
+	}
+
+	// KeyValueBucketStatus represents status of a Bucket, implements [KeyValueStatus].
+	KeyValueBucketStatus struct {
+		nfo    *StreamInfo
+		bucket string
+	}
+
+	// KeyValueEntry is a retrieved entry for Get, History or Watch.
+	KeyValueEntry interface {
+		// Bucket is the bucket the data was loaded from.
+		Bucket() string
+		// Key is the key that was retrieved.
+		Key() string
+		// Value is the retrieved value.
+		Value() []byte
+		// Revision is a unique sequence for this value.
+		Revision() uint64
+		// Created is the time the data was put in the bucket.
+		Created() time.Time
+		// Delta is distance from the latest value.
+		Delta() uint64
+		// Operation returns Put or Delete or Purge.
+		Operation() KeyValueOp
+	}
+
+	// KeyValueCodec transforms values stored in a bucket, e.g. to encrypt or
+	// compress them, see [KeyValueWithCodec]. The key is passed e.g. to be
+	// used as additional authenticated data.
+	KeyValueCodec interface {
+		// Encode transforms the value before it is stored.
+		Encode(key string, value []byte) ([]byte, error)
+		// Decode restores the value encoded by Encode.
+		Decode(key string, value []byte) ([]byte, error)
+	}
+
+	// KeyValueOp is the kind of operation an entry was stored with.
+	KeyValueOp uint8
+
+	// KeyWatcher is what is returned when doing a watch.
+	KeyWatcher interface {
+		// Updates returns a channel to read any updates to entries.
+		Updates() <-chan KeyValueEntry
+		// Stop will stop this watcher.
+		Stop() error
+	}
+
+	// WatchOpt configures [KeyValue.Watch].
+	WatchOpt func(*watchOpts) error
+
+	watchOpts struct {
+		// Do not send delete markers to the update channel.
+		ignoreDeletes bool
+		// Include all history per subject, not just last one.
+		includeHistory bool
+		// Include only updates for keys.
+		updatesOnly bool
+		// Retrieve only the meta data of the entry
+		metaOnly bool
+	}
+
+	// KVDeleteOpt configures [KeyValue.Delete] and [KeyValue.Purge].
+	KVDeleteOpt func(*deleteOpts) error
+
+	deleteOpts struct {
+		// Remove all previous revisions.
+		purge bool
+		// Delete only if the latest revision matches.
+		revision uint64
+	}
+
+	kvs struct {
+		name   string
+		stream Stream
+		pre    string
+		putPre string
+		js     *jetStream
+		// If true, it means that APIPrefix/Domain was set
+		// and we need to add it to the subjects of put and delete operations.
+		useJSPfx bool
+		codec    KeyValueCodec
+	}
+
+	kve struct {
+		bucket   string
+		key      string
+		value    []byte
+		revision uint64
+		delta    uint64
+		created  time.Time
+		op       KeyValueOp
+	}
+
+	watcher struct {
+		sync.Mutex
+		updates     chan KeyValueEntry
+		cons        ConsumeContext
+		done        chan struct{}
+		stopped     bool
+		initDone    bool
+		initPending uint64
+		received    uint64
+		stopOnce    sync.Once
+	}
+)
+
+const (
+	KeyValuePut KeyValueOp = iota
+	KeyValueDelete
+	KeyValuePurge
+)
+
+const (
+	// KeyValueMaxHistory is the maximum number of historical values kept per key.
+	KeyValueMaxHistory = 64
+	// AllKeys is used to watch all keys.
+	AllKeys = ">"
+
+	kvLatestRevision = 0
+	kvop             = "KV-Operation"
+	kvdel            = "DEL"
+	kvpurge          = "PURGE"
+
+	// reasons for subject delete markers placed by the server
+	markerReasonMaxAge = "MaxAge"
+	markerReasonPurge  = "Purge"
+	markerReasonRemove = "Remove"
+
+	kvBucketNamePre         = "KV_"
+	kvBucketNameTmpl        = "KV_%s"
+	kvSubjectsTmpl          = "$KV.%s.>"
+	kvSubjectsPreTmpl       = "$KV.%s."
+	kvSubjectsPreDomainTmpl = "%s.$KV.%s."
+)
+
+// errKeyDeleted is returned by get when the latest revision is a delete or purge marker.
+var errKeyDeleted = &jsError{message: "key was deleted"}
+
+var (
+	validBucketRe = regexp.MustCompile(`\A[a-zA-Z0-9_-]+\z`)
+	validKeyRe    = regexp.MustCompile(`\A[-/_=\.a-zA-Z0-9]+\z`)
+)
+
+func (op KeyValueOp) String() string {
+	switch op {
+	case KeyValuePut:
+		return "KeyValuePutOp"
+	case KeyValueDelete:
+		return "KeyValueDeleteOp"
+	case KeyValuePurge:
+		return "KeyValuePurgeOp"
+	default:
+		return "Unknown Operation"
+	}
+}
+
+// IncludeHistory instructs the key watcher to include historical values as well.
+func IncludeHistory() WatchOpt {
+	return func(opts *watchOpts) error {
+		opts.includeHistory = true
+		return nil
+	}
+}
+
+// IgnoreDeletes will have the key watcher not pass any deleted keys.
+func IgnoreDeletes() WatchOpt {
+	return func(opts *watchOpts) error {
+		opts.ignoreDeletes = true
+		return nil
+	}
+}
+
+// UpdatesOnly instructs the key watcher to only include updates on values
+// (without latest values when started). No initial values marker is sent.
+func UpdatesOnly() WatchOpt {
+	return func(opts *watchOpts) error {
+		opts.updatesOnly = true
+		return nil
+	}
+}
+
+// MetaOnly instructs the key watcher to retrieve only the entry meta data, not the entry value.
+func MetaOnly() WatchOpt {
+	return func(opts *watchOpts) error {
+		opts.metaOnly = true
+		return nil
+	}
+}
+
+// LastRevision deletes if the latest revision matches.
+func LastRevision(revision uint64) KVDeleteOpt {
+	return func(opts *deleteOpts) error {
+		opts.revision = revision
+		return nil
+	}
+}
+
+func purge() KVDeleteOpt {
+	return func(opts *deleteOpts) error {
+		opts.purge = true
+		return nil
+	}
+}
+
+// KeyValueWithCodec returns a handle to the same bucket as kv, encoding
+// values with the codec before they are stored and decoding them when
+// retrieved, including entries of watchers and history. Entries which
+// cannot be decoded are skipped by watchers.
+func KeyValueWithCodec(kv KeyValue, codec KeyValueCodec) (KeyValue, error) {
+	if codec == nil {
+		return nil, fmt.Errorf("%w: codec cannot be nil", ErrInvalidOption)
+	}
+	k, ok := kv.(*kvs)
+	if !ok {
+		return nil, fmt.Errorf("%w: codec can only be set on a bucket handle", ErrInvalidOption)
+	}
+	withCodec := *k
+	withCodec.codec = codec
+	return &withCodec, nil
+}
+
+// KeyValue will lookup and bind to an existing KeyValue store.
+func (js *jetStream) KeyValue(ctx context.Context, bucket string) (KeyValue, error) {
+	if !validBucketRe.MatchString(bucket) {
+		return nil, ErrInvalidBucketName
+	}
+	s, err := js.Stream(ctx, fmt.Sprintf(kvBucketNameTmpl, bucket))
+	if err != nil {
+		if errors.Is(err, ErrStreamNotFound) {
+			err = ErrBucketNotFound
+		}
+		return nil, err
+	}
+	// Do some quick sanity checks that this is a correctly formed stream for KV.
+	// Max msgs per subject should be > 0.
+	if s.CachedInfo().Config.MaxMsgsPerSubject < 1 {
+		return nil, ErrBadBucket
+	}
+	return mapStreamToKVS(js, s), nil
+}
+
+// CreateKeyValue will create a KeyValue store with the given configuration.
+func (js *jetStream) CreateKeyValue(ctx context.Context, cfg KeyValueConfig) (KeyValue, error) {
+	if !validBucketRe.MatchString(cfg.Bucket) {
+		return nil, ErrInvalidBucketName
+	}
+
+	// Default to 1 for history. Max is 64 for now.
+	history := int64(1)
+	if cfg.History > 0 {
+		if cfg.History > KeyValueMaxHistory {
+			return nil, ErrHistoryTooLarge
+		}
+		history = int64(cfg.History)
+	}
+
+	replicas := cfg.Replicas
+	if replicas == 0 {
+		replicas = 1
+	}
+
+	// We will set explicitly some values so that we can do comparison
+	// if we get an "already in use" error and need to check if it is same.
+	maxBytes := cfg.MaxBytes
+	if maxBytes == 0 {
+		maxBytes = -1
+	}
+	maxMsgSize := cfg.MaxValueSize
+	if maxMsgSize == 0 {
+		maxMsgSize = -1
+	}
+	// The duplicate window is capped to 2 minutes, or to the TTL if lower.
+	duplicateWindow := 2 * time.Minute
+	if cfg.TTL > 0 && cfg.TTL < duplicateWindow {
+		duplicateWindow = cfg.TTL
+	}
+	scfg := StreamConfig{
+		Name:              fmt.Sprintf(kvBucketNameTmpl, cfg.Bucket),
+		Description:       cfg.Description,
+		MaxMsgsPerSubject: history,
+		MaxBytes:          maxBytes,
+		MaxAge:            cfg.TTL,
+		MaxMsgSize:        maxMsgSize,
+		Storage:           cfg.Storage,
+		Replicas:          replicas,
+		Placement:         cfg.Placement,
+		AllowRollup:       true,
+		DenyDelete:        true,
+		Duplicates:        duplicateWindow,
+		MaxMsgs:           -1,
+		MaxConsumers:      -1,
+		AllowDirect:       true,
+		RePublish:         cfg.RePublish,
+		Discard:           DiscardNew,
+	}
+	if cfg.LimitMarkerTTL > 0 {
+		scfg.AllowMsgTTL = true
+		scfg.SubjectDeleteMarkerTTL = cfg.LimitMarkerTTL
+	}
+	if cfg.Mirror != nil {
+		// Copy in case we need to make changes so we do not change caller's version.
+		m := cfg.Mirror.copy()
+		if !strings.HasPrefix(m.Name, kvBucketNamePre) {
+			m.Name = fmt.Sprintf(kvBucketNameTmpl, m.Name)
+		}
+		scfg.Mirror = m
+		scfg.MirrorDirect = true
+	} else if len(cfg.Sources) > 0 {
+		for _, ss := range cfg.Sources {
+			if !strings.HasPrefix(ss.Name, kvBucketNamePre) {
+				ss = ss.copy()
+				ss.Name = fmt.Sprintf(kvBucketNameTmpl, ss.Name)
+			}
+			scfg.Sources = append(scfg.Sources, ss)
+		}
+	} else {
+		scfg.Subjects = []string{fmt.Sprintf(kvSubjectsTmpl, cfg.Bucket)}
+	}
+
+	s, err := js.CreateStream(ctx, scfg)
+	if err != nil {
+		if !errors.Is(err, ErrStreamNameAlreadyInUse) {
+			return nil, err
+		}
+		// The bucket may have been created by an older client, in which
+		// case the stream is updated if only discard policy and direct
+		// get setting differ.
+		s, err = js.Stream(ctx, scfg.Name)
+		if err != nil {
+			return nil, err
+		}
+		existing := s.CachedInfo().Config
+		existing.Discard = scfg.Discard
+		existing.AllowDirect = scfg.AllowDirect
+		if !reflect.DeepEqual(existing, scfg) {
+			return nil, ErrStreamNameAlreadyInUse
+		}
+		if s, err = js.UpdateStream(ctx, scfg); err != nil {
+			return nil, err
+		}
+	}
+	return mapStreamToKVS(js, s), nil
+}
+
+// DeleteKeyValue will delete the KeyValue store (JetStream stream).
+func (js *jetStream) DeleteKeyValue(ctx context.Context, bucket string) error {
+	if !validBucketRe.MatchString(bucket) {
+		return ErrInvalidBucketName
+	}
+	err := js.DeleteStream(ctx, fmt.Sprintf(kvBucketNameTmpl, bucket))
+	if errors.Is(err, ErrStreamNotFound) {
+		return ErrBucketNotFound
+	}
+	return err
+}
+
+func mapStreamToKVS(js *jetStream, s Stream) *kvs {
+	info := s.CachedInfo()
+	bucket := strings.TrimPrefix(info.Config.Name, kvBucketNamePre)
+	kv := &kvs{
+		name:     bucket,
+		stream:   s,
+		pre:      fmt.Sprintf(kvSubjectsPreTmpl, bucket),
+		js:       js,
+		useJSPfx: js.apiPrefix != DefaultAPIPrefix,
+	}
+
+	// If we are mirroring, writes go to the mirrored bucket.
+	if m := info.Config.Mirror; m != nil {
+		bucket := strings.TrimPrefix(m.Name, kvBucketNamePre)
+		if m.External != nil && m.External.APIPrefix != "" {
+			kv.useJSPfx = false
+			kv.pre = fmt.Sprintf(kvSubjectsPreTmpl, bucket)
+			kv.putPre = fmt.Sprintf(kvSubjectsPreDomainTmpl, m.External.APIPrefix, bucket)
+		} else {
+			kv.putPre = fmt.Sprintf(kvSubjectsPreTmpl, bucket)
+		}
+	}
+	return kv
+}
+
+func keyValid(key string) bool {
+	if len(key) == 0 || key[0] == '.' || key[len(key)-1] == '.' {
+		return false
+	}
+	return validKeyRe.MatchString(key)
+}
+
+// Get returns the latest value for the key.
+func (kv *kvs) Get(ctx context.Context, key string) (KeyValueEntry, error) {
+	e, err := kv.get(ctx, key, kvLatestRevision)
+	if err != nil {
+		if errors.Is(err, errKeyDeleted) {
+			return nil, ErrKeyNotFound
+		}
+		return nil, err
+	}
+	return e, nil
+}
+
+// GetRevision returns a specific revision value for the key.
+func (kv *kvs) GetRevision(ctx context.Context, key string, revision uint64) (KeyValueEntry, error) {
+	e, err := kv.get(ctx, key, revision)
+	if err != nil {
+		if errors.Is(err, errKeyDeleted) {
+			return nil, ErrKeyNotFound
+		}
+		return nil, err
+	}
+	return e, nil
+}
+
+func (kv *kvs) get(ctx context.Context, key string, revision uint64) (KeyValueEntry, error) {
+	if !keyValid(key) {
+		return nil, ErrInvalidKey
+	}
+	subject := kv.pre + key
+
+	var m *RawStreamMsg
+	var err error
+	if revision == kvLatestRevision {
+		m, err = kv.stream.GetLastMsgForSubjectDirect(ctx, subject)
+	} else {
+		m, err = kv.stream.GetMsgDirect(ctx, revision)
+		// If a sequence was provided, just make sure that the retrieved
+		// message subject matches the request.
+		if err == nil && m.Subject != subject {
+			return nil, ErrKeyNotFound
+		}
+	}
+	if err != nil {
+		if errors.Is(err, ErrMsgNotFound) {
+			err = ErrKeyNotFound
+		}
+		return nil, err
+	}
+
+	entry := &kve{
+		bucket:   kv.name,
+		key:      key,
+		value:    m.Data,
+		revision: m.Sequence,
+		created:  m.Time,
+	}
+
+	// Double check here that this is not a DEL Operation marker.
+	entry.op = kvOperation(m.Header)
+	if entry.op != KeyValuePut {
+		return entry, errKeyDeleted
+	}
+	if entry.value, err = kv.decode(key, entry.value); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (kv *kvs) encode(key string, value []byte) ([]byte, error) {
+	if kv.codec == nil {
+		return value, nil
+	}
+	return kv.codec.Encode(key, value)
+}
+
+func (kv *kvs) decode(key string, value []byte) ([]byte, error) {
+	if kv.codec == nil {
+		return value, nil
+	}
+	return kv.codec.Decode(key, value)
+}
+
+// kvOperation returns the operation of a stored message, either set by
+// the client or by the server placing a subject delete marker.
+func kvOperation(hdr nats.Header) KeyValueOp {
+	switch hdr.Get(kvop) {
+	case kvdel:
+		return KeyValueDelete
+	case kvpurge:
+		return KeyValuePurge
+	}
+	switch hdr.Get(MarkerReasonHeader) {
+	case markerReasonMaxAge, markerReasonPurge:
+		return KeyValuePurge
+	case markerReasonRemove:
+		return KeyValueDelete
+	}
+	return KeyValuePut
+}
+
+// putSubject returns the subject put and delete operations for the key are published on.
+func (kv *kvs) putSubject(key string) string {
+	var b strings.Builder
+	if kv.useJSPfx {
+		b.WriteString(kv.js.apiPrefix)
+	}
+	if kv.putPre != "" {
+		b.WriteString(kv.putPre)
+	} else {
+		b.WriteString(kv.pre)
+	}
+	b.WriteString(key)
+	return b.String()
+}
+
+// Put will place the new value for the key into the store.
+func (kv *kvs) Put(ctx context.Context, key string, value []byte) (uint64, error) {
+	if !keyValid(key) {
+		return 0, ErrInvalidKey
+	}
+	value, err := kv.encode(key, value)
+	if err != nil {
+		return 0, err
+	}
+	pa, err := kv.js.Publish(ctx, kv.putSubject(key), value)
+	if err != nil {
+		return 0, err
+	}
+	return pa.Sequence, nil
+}
+
+// PutWithTTL will place the new value for the key into the store, removing
+// it after the TTL.
+func (kv *kvs) PutWithTTL(ctx context.Context, key string, value []byte, ttl time.Duration) (uint64, error) {
+	if !keyValid(key) {
+		return 0, ErrInvalidKey
+	}
+	// servers not supporting message TTLs ignore the header,
+	// so check the bucket config to not silently keep the key
+	if !kv.stream.CachedInfo().Config.AllowMsgTTL {
+		return 0, ErrKeyTTLNotEnabled
+	}
+	value, err := kv.encode(key, value)
+	if err != nil {
+		return 0, err
+	}
+	pa, err := kv.js.Publish(ctx, kv.putSubject(key), value, WithMsgTTL(ttl))
+	if err != nil {
+		return 0, err
+	}
+	return pa.Sequence, nil
+}
+
+// PutString will place the string for the key into the store.
+func (kv *kvs) PutString(ctx context.Context, key string, value string) (uint64, error) {
+	return kv.Put(ctx, key, []byte(value))
+}
+
+// Create will add the key/value pair if it does not exist.
+func (kv *kvs) Create(ctx context.Context, key string, value []byte) (uint64, error) {
+	v, err := kv.Update(ctx, key, value, 0)
+	if err == nil {
+		return v, nil
+	}
+
+	// The key may exist only as a delete or purge marker, in which
+	// case it can be created on top of it.
+	if e, getErr := kv.get(ctx, key, kvLatestRevision); errors.Is(getErr, errKeyDeleted) {
+		return kv.Update(ctx, key, value, e.Revision())
+	}
+	return 0, err
+}
+
+// Update will update the value if the latest revision matches.
+func (kv *kvs) Update(ctx context.Context, key string, value []byte, revision uint64) (uint64, error) {
+	if !keyValid(key) {
+		return 0, ErrInvalidKey
+	}
+	value, err := kv.encode(key, value)
+	if err != nil {
+		return 0, err
+	}
+	m := nats.NewMsg(kv.putSubject(key))
+	m.Data = value
+	m.Header.Set(ExpectedLastSubjSeqHeader, strconv.FormatUint(revision, 10))
+
+	pa, err := kv.js.PublishMsg(ctx, m)
+	if err != nil {
+		if errors.Is(err, ErrKeyExists) {
+			return 0, ErrKeyExists
+		}
+		return 0, err
+	}
+	return pa.Sequence, nil
+}
+
+// Delete will place a delete marker and leave all revisions.
+func (kv *kvs) Delete(ctx context.Context, key string, opts ...KVDeleteOpt) error {
+	if !keyValid(key) {
+		return ErrInvalidKey
+	}
+	var o deleteOpts
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return err
+		}
+	}
+
+	// DEL op marker. For watch functionality.
+	m := nats.NewMsg(kv.putSubject(key))
+	if o.purge {
+		m.Header.Set(kvop, kvpurge)
+		m.Header.Set(MsgRollup, MsgRollupSubject)
+	} else {
+		m.Header.Set(kvop, kvdel)
+	}
+	if o.revision != 0 {
+		m.Header.Set(ExpectedLastSubjSeqHeader, strconv.FormatUint(o.revision, 10))
+	}
+
+	_, err := kv.js.PublishMsg(ctx, m)
+	if errors.Is(err, ErrKeyExists) {
+		return ErrKeyExists
+	}
+	return err
+}
+
+// Purge will place a delete marker and remove all previous revisions.
+func (kv *kvs) Purge(ctx context.Context, key string, opts ...KVDeleteOpt) error {
+	return kv.Delete(ctx, key, append(opts, purge())...)
+}
+
+// Keys will return all keys.
+func (kv *kvs) Keys(ctx context.Context, opts ...WatchOpt) ([]string, error) {
+	opts = append(opts, IgnoreDeletes(), MetaOnly())
+	watcher, err := kv.WatchAll(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer watcher.Stop()
+
+	var keys []string
+	for entry := range watcher.Updates() {
+		if entry == nil {
+			break
+		}
+		keys = append(keys, entry.Key())
+	}
+	if len(keys) == 0 {
+		return nil, ErrNoKeysFound
+	}
+	return keys, nil
+}
+
+// History will return all values for the key.
+func (kv *kvs) History(ctx context.Context, key string, opts ...WatchOpt) ([]KeyValueEntry, error) {
+	opts = append(opts, IncludeHistory())
+	watcher, err := kv.Watch(ctx, key, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer watcher.Stop()
+
+	var entries []KeyValueEntry
+	for entry := range watcher.Updates() {
+		if entry == nil {
+			break
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return nil, ErrKeyNotFound
+	}
+	return entries, nil
+}
+
+// WatchAll watches all keys.
+func (kv *kvs) WatchAll(ctx context.Context, opts ...WatchOpt) (KeyWatcher, error) {
+	return kv.Watch(ctx, AllKeys, opts...)
+}
+
+// Watch will send updates of keys matching the keys pattern to the watcher.
+// keys needs to be a valid NATS subject.
+func (kv *kvs) Watch(ctx context.Context, keys string, opts ...WatchOpt) (KeyWatcher, error) {
+	var o watchOpts
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+	if o.includeHistory && o.updatesOnly {
+		return nil, fmt.Errorf("%w: include history can not be used with updates only", ErrInvalidOption)
+	}
+
+	// Could be a pattern so don't check for validity as we normally do.
+	cfg := OrderedConsumerConfig{
+		FilterSubjects: []string{kv.pre + keys},
+		DeliverPolicy:  DeliverLastPerSubjectPolicy,
+		HeadersOnly:    o.metaOnly,
+	}
+	if o.includeHistory {
+		cfg.DeliverPolicy = DeliverAllPolicy
+	}
+	if o.updatesOnly {
+		cfg.DeliverPolicy = DeliverNewPolicy
+	}
+	cons, err := kv.stream.OrderedConsumer(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// We will block below on placing items on the chan. That is by design.
+	w := &watcher{updates: make(chan KeyValueEntry, 256), done: make(chan struct{})}
+
+	update := func(m Msg) {
+		meta, err := m.Metadata()
+		if err != nil {
+			return
+		}
+		if len(m.Subject()) <= len(kv.pre) {
+			return
+		}
+		op := kvOperation(m.Headers())
+		key := m.Subject()[len(kv.pre):]
+		value := m.Data()
+		// entries which cannot be decoded are skipped, but still
+		// count towards initial values
+		decoded := true
+		if op == KeyValuePut && !o.metaOnly {
+			value, err = kv.decode(key, value)
+			decoded = err == nil
+		}
+
+		w.Lock()
+		defer w.Unlock()
+		if w.stopped {
+			return
+		}
+		if decoded && (!o.ignoreDeletes || op == KeyValuePut) {
+			entry := &kve{
+				bucket:   kv.name,
+				key:      key,
+				value:    value,
+				revision: meta.Sequence.Stream,
+				created:  meta.Timestamp,
+				delta:    meta.NumPending,
+				op:       op,
+			}
+			if !w.send(entry) {
+				return
+			}
+		}
+		// Check if done and initial values.
+		if !w.initDone {
+			w.received++
+			// We set this on the first trip through..
+			if w.initPending == 0 {
+				w.initPending = meta.NumPending
+			}
+			if w.received > w.initPending || meta.NumPending == 0 {
+				w.initDone = true
+				w.send(nil)
+			}
+		}
+	}
+
+	// Start consuming and check the initial pending count under the lock,
+	// preventing the race between this code and the update callback.
+	w.Lock()
+	defer w.Unlock()
+	cc, err := cons.Consume(update)
+	if err != nil {
+		return nil, err
+	}
+	w.cons = cc
+	// If there were no pending messages at the time of the creation
+	// of the consumer, send the marker.
+	// Skip if UpdatesOnly() is set, since there will never be updates initially.
+	if o.updatesOnly {
+		w.initDone = true
+	} else if info := cons.CachedInfo(); info != nil && info.NumPending == 0 {
+		w.initDone = true
+		w.updates <- nil
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			w.Stop()
+		case <-w.done:
+		}
+	}()
+	return w, nil
+}
+
+// send blocks until the entry is placed on the updates channel
+// or the watcher is stopped. It has to be called with the lock held.
+func (w *watcher) send(entry KeyValueEntry) bool {
+	select {
+	case w.updates <- entry:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+// Updates returns the interior channel.
+func (w *watcher) Updates() <-chan KeyValueEntry {
+	if w == nil {
+		return nil
+	}
+	return w.updates
+}
+
+// Stop will stop the watcher and close its updates channel.
+func (w *watcher) Stop() error {
+	if w == nil {
+		return nil
+	}
+	w.stopOnce.Do(func() {
+		// Unblock a pending update before acquiring the lock.
+		close(w.done)
+		w.cons.Stop()
+		w.Lock()
+		w.stopped = true
+		close(w.updates)
+		w.Unlock()
+	})
+	return nil
+}
+
+// Bucket returns the current bucket name.
+func (kv *kvs) Bucket() string {
+	return kv.name
+}
+
+// Status retrieves the status and configuration of a bucket.
+func (kv *kvs) Status(ctx context.Context) (KeyValueStatus, error) {
+	nfo, err := kv.stream.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyValueBucketStatus{nfo: nfo, bucket: kv.name}, nil
+}
+
+// Bucket the name of the bucket
+func (s *KeyValueBucketStatus) Bucket() string { return s.bucket }
+
+// Values is how many messages are in the bucket, including historical values
+func (s *KeyValueBucketStatus) Values() uint64 { return s.nfo.State.Msgs }
+
+// History returns the configured history kept per key
+func (s *KeyValueBucketStatus) History() int64 { return s.nfo.Config.MaxMsgsPerSubject }
+
+// TTL is how long the bucket keeps values for
+func (s *KeyValueBucketStatus) TTL() time.Duration { return s.nfo.Config.MaxAge }
+
+// BackingStore indicates what technology is used for storage of the bucket
+func (s *KeyValueBucketStatus) BackingStore() string { return "JetStream" }
+
+// StreamInfo is the stream info retrieved to create the status
+func (s *KeyValueBucketStatus) StreamInfo() *StreamInfo { return s.nfo }
+
+// Bytes is the size of the stream
+func (s *KeyValueBucketStatus) Bytes() uint64 { return s.nfo.State.Bytes }
+
+func (e *kve) Bucket() string        { return e.bucket }
+func (e *kve) Key() string           { return e.key }
+func (e *kve) Value() []byte         { return e.value }
+func (e *kve) Revision() uint64      { return e.revision }
+func (e *kve) Created() time.Time    { return e.created }
+func (e *kve) Delta() uint64         { return e.delta }
+func (e *kve) Operation() KeyValueOp { return e.op }
@@ -0,0 +1,232 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type (
+	// CachedKeyValue serves Get from a local copy of the bucket, kept up
+	// to date by a watcher. Other operations are passed to the wrapped
+	// bucket.
+	//
+	// The cache is periodically verified to have caught up with the
+	// latest revision in the bucket. If it was not verified within the
+	// max staleness, e.g. while disconnected, Get falls back to reading
+	// from the bucket. Values written using the handle are visible in the
+	// cache only once received by the watcher.
+	CachedKeyValue struct {
+		KeyValue
+		kv     *kvs
+		opts   cachedKeyValueOpts
+		cancel context.CancelFunc
+		done   chan struct{}
+
+		sync.RWMutex
+		entries  map[string]KeyValueEntry
+		revision uint64
+		verified time.Time
+		// latest revision not yet received by the watcher
+		// when verifying, and the time verification started
+		target     uint64
+		targetTime time.Time
+	}
+
+	// CachedKeyValueOpt configures a [CachedKeyValue].
+	CachedKeyValueOpt func(*cachedKeyValueOpts) error
+
+	cachedKeyValueOpts struct {
+		maxStaleness time.Duration
+		errHandler   func(error)
+	}
+)
+
+// DefaultCacheMaxStaleness is the default max staleness of a [CachedKeyValue].
+const DefaultCacheMaxStaleness = 5 * time.Second
+
+// WithCacheMaxStaleness sets how long ago the cache may have been last
+// verified to be up to date for Get to be served from it. The cache is
+// verified twice within the max staleness.
+func WithCacheMaxStaleness(maxStaleness time.Duration) CachedKeyValueOpt {
+	return func(opts *cachedKeyValueOpts) error {
+		if maxStaleness <= 0 {
+			return fmt.Errorf("%w: max staleness must be positive", ErrInvalidOption)
+		}
+		opts.maxStaleness = maxStaleness
+		return nil
+	}
+}
+
+// WithCacheErrHandler sets the handler invoked when the cache cannot be verified.
+func WithCacheErrHandler(cb func(error)) CachedKeyValueOpt {
+	return func(opts *cachedKeyValueOpts) error {
+		opts.errHandler = cb
+		return nil
+	}
+}
+
+// NewCachedKeyValue creates a [CachedKeyValue] for the bucket, returning
+// once the current values were loaded. The watcher is stopped when ctx is
+// done or [CachedKeyValue.Stop] is called.
+//
+// Available options:
+// [WithCacheMaxStaleness] - sets the max staleness of cached values, default is 5s
+// [WithCacheErrHandler] - sets the handler for errors verifying the cache
+func NewCachedKeyValue(ctx context.Context, kv KeyValue, opts ...CachedKeyValueOpt) (*CachedKeyValue, error) {
+	o := cachedKeyValueOpts{maxStaleness: DefaultCacheMaxStaleness}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+	k, ok := kv.(*kvs)
+	if !ok {
+		return nil, fmt.Errorf("%w: cache can only be created for a bucket handle", ErrInvalidOption)
+	}
+
+	// current values delivered by the watcher are at least as recent as
+	// the time it was created
+	started := time.Now()
+	ctx, cancel := context.WithCancel(ctx)
+	w, err := kv.WatchAll(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	c := &CachedKeyValue{
+		KeyValue: kv,
+		kv:       k,
+		opts:     o,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+		entries:  make(map[string]KeyValueEntry),
+	}
+	for loaded := false; !loaded; {
+		select {
+		case entry := <-w.Updates():
+			if entry == nil {
+				loaded = true
+				continue
+			}
+			c.update(entry)
+		case <-ctx.Done():
+			cancel()
+			w.Stop()
+			return nil, ctx.Err()
+		}
+	}
+	c.verified = started
+	go c.run(ctx, w)
+	return c, nil
+}
+
+// Get returns the latest value for the key from the cache, or from the
+// bucket if the cache was not verified within the max staleness.
+func (c *CachedKeyValue) Get(ctx context.Context, key string) (KeyValueEntry, error) {
+	if !keyValid(key) {
+		return nil, ErrInvalidKey
+	}
+	c.RLock()
+	if time.Since(c.verified) > c.opts.maxStaleness {
+		c.RUnlock()
+		return c.KeyValue.Get(ctx, key)
+	}
+	entry, ok := c.entries[key]
+	c.RUnlock()
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return entry, nil
+}
+
+// Stop stops updating the cache, after which Get reads from the bucket.
+func (c *CachedKeyValue) Stop() {
+	c.cancel()
+	<-c.done
+	c.Lock()
+	c.verified = time.Time{}
+	c.Unlock()
+}
+
+func (c *CachedKeyValue) run(ctx context.Context, w KeyWatcher) {
+	defer close(c.done)
+	defer w.Stop()
+	t := time.NewTicker(c.opts.maxStaleness / 2)
+	defer t.Stop()
+	for {
+		select {
+		case entry, ok := <-w.Updates():
+			if !ok {
+				return
+			}
+			c.update(entry)
+		case <-t.C:
+			if err := c.verify(ctx); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				if c.opts.errHandler != nil {
+					c.opts.errHandler(err)
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// verify checks whether the watcher received the latest revision in the
+// bucket, or marks it as the target to be received.
+func (c *CachedKeyValue) verify(ctx context.Context) error {
+	started := time.Now()
+	var latest uint64
+	m, err := c.kv.stream.GetLastMsgForSubjectDirect(ctx, c.kv.pre+AllKeys)
+	if err != nil && !errors.Is(err, ErrMsgNotFound) {
+		return err
+	}
+	if m != nil {
+		latest = m.Sequence
+	}
+	c.Lock()
+	defer c.Unlock()
+	if c.revision >= latest {
+		c.verified = started
+		c.target = 0
+	} else {
+		c.target, c.targetTime = latest, started
+	}
+	return nil
+}
+
+func (c *CachedKeyValue) update(entry KeyValueEntry) {
+	c.Lock()
+	defer c.Unlock()
+	if entry.Operation() == KeyValuePut {
+		c.entries[entry.Key()] = entry
+	} else {
+		delete(c.entries, entry.Key())
+	}
+	if entry.Revision() > c.revision {
+		c.revision = entry.Revision()
+	}
+	if c.target > 0 && c.revision >= c.target {
+		c.verified = c.targetTime
+		c.target = 0
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+)
+
+// bucketsWatcher merges the updates of watchers on multiple buckets.
+type bucketsWatcher struct {
+	updates     chan KeyValueEntry
+	watchers    []KeyWatcher
+	done        chan struct{}
+	wg          sync.WaitGroup
+	initMu      sync.Mutex
+	initPending int
+	stopOnce    sync.Once
+}
+
+// WatchBuckets watches all keys of the given buckets, merging their updates
+// into a single channel. Entries are tagged with the bucket they belong to,
+// see [KeyValueEntry.Bucket]. Each bucket is watched with its own ordered
+// consumer, which is recreated independently of the others after
+// reconnects or missed heartbeats, resuming after the last received entry.
+// A nil entry is sent once all initial values of all buckets were received.
+// The watcher is stopped when ctx is done.
+func (js *jetStream) WatchBuckets(ctx context.Context, buckets ...string) (KeyWatcher, error) {
+	if len(buckets) == 0 {
+		return nil, fmt.Errorf("%w: at least one bucket is required", nats.ErrInvalidArg)
+	}
+	w := &bucketsWatcher{
+		updates: make(chan KeyValueEntry, 256),
+		done:    make(chan struct{}),
+	}
+	seen := make(map[string]struct{}, len(buckets))
+	for _, bucket := range buckets {
+		if _, ok := seen[bucket]; ok {
+			continue
+		}
+		seen[bucket] = struct{}{}
+		kv, err := js.KeyValue(ctx, bucket)
+		if err == nil {
+			var bw KeyWatcher
+			bw, err = kv.WatchAll(ctx)
+			if err == nil {
+				w.watchers = append(w.watchers, bw)
+				continue
+			}
+		}
+		for _, bw := range w.watchers {
+			bw.Stop()
+		}
+		return nil, fmt.Errorf("%w: bucket %q", err, bucket)
+	}
+
+	w.initPending = len(w.watchers)
+	w.wg.Add(len(w.watchers))
+	for _, bw := range w.watchers {
+		go w.forward(bw)
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			w.Stop()
+		case <-w.done:
+		}
+	}()
+	return w, nil
+}
+
+// forward passes the updates of a single bucket on to the merged channel,
+// sending the initial values marker once all buckets sent theirs.
+func (w *bucketsWatcher) forward(bw KeyWatcher) {
+	defer w.wg.Done()
+	for entry := range bw.Updates() {
+		if entry == nil {
+			w.initMu.Lock()
+			w.initPending--
+			initDone := w.initPending == 0
+			w.initMu.Unlock()
+			if !initDone {
+				continue
+			}
+		}
+		select {
+		case w.updates <- entry:
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// Updates returns the channel receiving the updates of all buckets.
+func (w *bucketsWatcher) Updates() <-chan KeyValueEntry {
+	return w.updates
+}
+
+// Stop stops watching all buckets and closes the updates channel.
+func (w *bucketsWatcher) Stop() error {
+	w.stopOnce.Do(func() {
+		close(w.done)
+		for _, bw := range w.watchers {
+			bw.Stop()
+		}
+		w.wg.Wait()
+		close(w.updates)
+	})
+	return nil
+}
@@ -0,0 +1,181 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/nats-io/nats.go"
+)
+
+type (
+	msgLister struct {
+		msgs chan *RawStreamMsg
+		errs chan error
+	}
+
+	multiLastMsgGetRequest struct {
+		MultiLastFor []string `json:"multi_last"`
+		UpToSeq      uint64   `json:"up_to_seq,omitempty"`
+	}
+)
+
+const eobStatus = "204"
+
+// errMultiLastNotSupported is returned when the server does not support
+// batched direct gets.
+var errMultiLastNotSupported = errors.New("multi last get not supported")
+
+// GetLastMsgsForSubjects returns [RawStreamMsgLister] enabling iterating over the last messages
+// of all subjects matching the given subjects, in stream sequence order. Subjects can contain wildcards.
+//
+// If the stream allows direct gets, the messages are retrieved using a single batched direct get request.
+// Otherwise, or if the server does not support batched direct gets, the matching subjects are looked up
+// in the stream info and their last messages are retrieved one at a time.
+//
+// Once all messages are delivered, [ErrEndOfData] is sent on the error channel.
+func (s *stream) GetLastMsgsForSubjects(ctx context.Context, subjects ...string) RawStreamMsgLister {
+	l := &msgLister{
+		msgs: make(chan *RawStreamMsg),
+		errs: make(chan error, 1),
+	}
+	allowDirect := s.info.Config.AllowDirect
+	go func() {
+		if len(subjects) == 0 {
+			l.errs <- ErrSubjectsRequired
+			return
+		}
+		err := errMultiLastNotSupported
+		if allowDirect {
+			err = s.multiLastMsgs(ctx, &multiLastMsgGetRequest{MultiLastFor: subjects}, l.msgs)
+		}
+		if errors.Is(err, errMultiLastNotSupported) {
+			err = s.lastMsgsBySubject(ctx, subjects, l.msgs)
+		}
+		if err != nil {
+			l.errs <- err
+			return
+		}
+		l.errs <- ErrEndOfData
+	}()
+	return l
+}
+
+// Messages returns a channel allowing retrieval of messages returned by [Stream.GetLastMsgsForSubjects]
+func (l *msgLister) Messages() <-chan *RawStreamMsg {
+	return l.msgs
+}
+
+// Err returns an error channel which will be populated with error from [Stream.GetLastMsgsForSubjects]
+func (l *msgLister) Err() <-chan error {
+	return l.errs
+}
+
+// multiLastMsgs sends a batched direct get request and delivers the responses
+// until the end of batch is received.
+func (s *stream) multiLastMsgs(ctx context.Context, mreq *multiLastMsgGetRequest, msgs chan<- *RawStreamMsg) error {
+	req, err := json.Marshal(mreq)
+	if err != nil {
+		return err
+	}
+	subj := apiSubj(s.jetStream.apiPrefix, fmt.Sprintf(apiDirectMsgGetT, s.name))
+	if _, ok := ctx.Deadline(); !ok {
+		if timeout := s.jetStream.apiTimeout(subj); timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+	}
+	inbox := nats.NewInbox()
+	sub, err := s.jetStream.conn.SubscribeSync(inbox)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+	if err := s.jetStream.conn.PublishRequest(subj, inbox, req); err != nil {
+		return err
+	}
+
+	for first := true; ; first = false {
+		resp, err := sub.NextMsgWithContext(ctx)
+		if err != nil {
+			return err
+		}
+		if len(resp.Data) == 0 {
+			switch resp.Header.Get(statusHdr) {
+			case eobStatus:
+				return nil
+			case noMessages:
+				// no messages on any of the subjects
+				return nil
+			case reqTimeout:
+				// servers not supporting batched gets reject the request as empty
+				if first {
+					return errMultiLastNotSupported
+				}
+			}
+		}
+		msg, err := convertDirectGetMsgResponseToMsg(s.name, resp)
+		if err != nil {
+			return err
+		}
+		select {
+		case msgs <- msg:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// lastMsgsBySubject looks up subjects matching the given ones and retrieves
+// their last messages one at a time.
+func (s *stream) lastMsgsBySubject(ctx context.Context, subjects []string, msgs chan<- *RawStreamMsg) error {
+	matched := make(map[string]struct{})
+	for _, subject := range subjects {
+		info, err := s.Info(ctx, WithSubjectFilter(subject))
+		if err != nil {
+			return err
+		}
+		for subj := range info.State.Subjects {
+			matched[subj] = struct{}{}
+		}
+	}
+	last := make([]*RawStreamMsg, 0, len(matched))
+	for subj := range matched {
+		msg, err := s.getMsg(ctx, &apiMsgGetRequest{LastFor: subj}, true)
+		if err != nil {
+			if errors.Is(err, ErrMsgNotFound) {
+				// subject was purged in the meantime
+				continue
+			}
+			return err
+		}
+		last = append(last, msg)
+	}
+	sort.Slice(last, func(i, j int) bool {
+		return last[i].Sequence < last[j].Sequence
+	})
+	for _, msg := range last {
+		select {
+		case msgs <- msg:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
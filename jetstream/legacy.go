@@ -0,0 +1,601 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+type (
+	// legacyContext implements [nats.JetStreamContext] on top of [JetStream].
+	// Subscriptions, publishing, key-value and object stores are handled by the
+	// embedded legacy context, which uses the same connection and API prefix.
+	legacyContext struct {
+		nats.JetStreamContext
+		js *jetStream
+	}
+
+	legacyRequestOpts struct {
+		ctx   context.Context
+		purge *nats.StreamPurgeRequest
+		info  *nats.StreamInfoRequest
+	}
+
+	streamNamesRequest struct {
+		Subject string `json:"subject,omitempty"`
+	}
+)
+
+// LegacyContext returns a [nats.JetStreamContext] for code still using the
+// legacy API, allowing incremental migration to [JetStream].
+//
+// Stream and consumer management ([nats.JetStreamManager]) is implemented on top
+// of js, so requests are sent the same way, using the API prefix or domain, the
+// timeouts and the retries js was created with, and configs are converted between
+// both APIs. Errors are mapped to their legacy counterparts, e.g. [ErrStreamNotFound]
+// to [nats.ErrStreamNotFound]. Requests accept the [nats.Context], [nats.MaxWait],
+// [nats.StreamPurgeRequest] and [nats.StreamInfoRequest] options, other options
+// are rejected. Config fields only known to one of the APIs are dropped.
+//
+// Publishing, subscriptions, key-value and object stores, whose legacy types
+// cannot be built on top of js, are handled by a legacy context using the
+// connection and API prefix of js. The given options apply to it.
+//
+// js has to be created using [New], [NewWithAPIPrefix] or [NewWithDomain].
+func LegacyContext(js JetStream, opts ...nats.JSOpt) (nats.JetStreamContext, error) {
+	jsi, ok := js.(*jetStream)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported JetStream implementation %T", ErrInvalidOption, js)
+	}
+	opts = append([]nats.JSOpt{
+		nats.APIPrefix(jsi.apiPrefix),
+		nats.PublishAsyncMaxPending(jsi.publisher.maxpa),
+	}, opts...)
+	legacy, err := jsi.conn.JetStream(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &legacyContext{JetStreamContext: legacy, js: jsi}, nil
+}
+
+// AddStream creates a stream.
+func (l *legacyContext) AddStream(cfg *nats.StreamConfig, opts ...nats.JSOpt) (*nats.StreamInfo, error) {
+	if cfg == nil {
+		return nil, nats.ErrStreamConfigRequired
+	}
+	var streamCfg StreamConfig
+	if err := convertLegacy(cfg, &streamCfg); err != nil {
+		return nil, err
+	}
+	o, cancel, err := getLegacyRequestOpts(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	s, err := l.js.CreateStream(o.ctx, streamCfg)
+	if err != nil {
+		return nil, legacyError(err)
+	}
+	return legacyStreamInfo(s.CachedInfo())
+}
+
+// UpdateStream updates a stream.
+func (l *legacyContext) UpdateStream(cfg *nats.StreamConfig, opts ...nats.JSOpt) (*nats.StreamInfo, error) {
+	if cfg == nil {
+		return nil, nats.ErrStreamConfigRequired
+	}
+	var streamCfg StreamConfig
+	if err := convertLegacy(cfg, &streamCfg); err != nil {
+		return nil, err
+	}
+	o, cancel, err := getLegacyRequestOpts(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	s, err := l.js.UpdateStream(o.ctx, streamCfg)
+	if err != nil {
+		return nil, legacyError(err)
+	}
+	return legacyStreamInfo(s.CachedInfo())
+}
+
+// DeleteStream deletes a stream.
+func (l *legacyContext) DeleteStream(name string, opts ...nats.JSOpt) error {
+	o, cancel, err := getLegacyRequestOpts(opts)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	return legacyError(l.js.DeleteStream(o.ctx, name))
+}
+
+// StreamInfo retrieves information from a stream.
+func (l *legacyContext) StreamInfo(name string, opts ...nats.JSOpt) (*nats.StreamInfo, error) {
+	o, cancel, err := getLegacyRequestOpts(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	s, err := l.stream(name)
+	if err != nil {
+		return nil, err
+	}
+	var infoOpts []StreamInfoOpt
+	if o.info != nil {
+		infoOpts = append(infoOpts, WithDeletedDetails(o.info.DeletedDetails), WithSubjectFilter(o.info.SubjectsFilter))
+	}
+	info, err := s.Info(o.ctx, infoOpts...)
+	if err != nil {
+		return nil, legacyError(err)
+	}
+	return legacyStreamInfo(info)
+}
+
+// PurgeStream purges a stream, optionally as described by a [nats.StreamPurgeRequest] option.
+func (l *legacyContext) PurgeStream(name string, opts ...nats.JSOpt) error {
+	o, cancel, err := getLegacyRequestOpts(opts)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	s, err := l.stream(name)
+	if err != nil {
+		return err
+	}
+	var purgeOpts []StreamPurgeOpt
+	if o.purge != nil {
+		if o.purge.Subject != "" {
+			purgeOpts = append(purgeOpts, WithPurgeSubject(o.purge.Subject))
+		}
+		if o.purge.Sequence > 0 {
+			purgeOpts = append(purgeOpts, WithPurgeSequence(o.purge.Sequence))
+		}
+		if o.purge.Keep > 0 {
+			purgeOpts = append(purgeOpts, WithPurgeKeep(o.purge.Keep))
+		}
+	}
+	_, err = s.Purge(o.ctx, purgeOpts...)
+	return legacyError(err)
+}
+
+// Streams can be used to retrieve a list of StreamInfo objects.
+func (l *legacyContext) Streams(opts ...nats.JSOpt) <-chan *nats.StreamInfo {
+	o, cancel, err := getLegacyRequestOpts(opts)
+	if err != nil {
+		return nil
+	}
+	ch := make(chan *nats.StreamInfo)
+	go func() {
+		defer cancel()
+		defer close(ch)
+		lister := l.js.ListStreams(o.ctx)
+		for {
+			select {
+			case info := <-lister.Info():
+				legacyInfo, err := legacyStreamInfo(info)
+				if err != nil {
+					return
+				}
+				select {
+				case ch <- legacyInfo:
+				case <-o.ctx.Done():
+					return
+				}
+			case <-lister.Err():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// StreamsInfo can be used to retrieve a list of StreamInfo objects.
+func (l *legacyContext) StreamsInfo(opts ...nats.JSOpt) <-chan *nats.StreamInfo {
+	return l.Streams(opts...)
+}
+
+// StreamNames is used to retrieve a list of Stream names.
+func (l *legacyContext) StreamNames(opts ...nats.JSOpt) <-chan string {
+	o, cancel, err := getLegacyRequestOpts(opts)
+	if err != nil {
+		return nil
+	}
+	ch := make(chan string)
+	go func() {
+		defer cancel()
+		defer close(ch)
+		lister := l.js.StreamNames(o.ctx)
+		for {
+			select {
+			case name := <-lister.Name():
+				select {
+				case ch <- name:
+				case <-o.ctx.Done():
+					return
+				}
+			case <-lister.Err():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// GetMsg retrieves a raw stream message stored in JetStream by sequence number.
+func (l *legacyContext) GetMsg(name string, seq uint64, opts ...nats.JSOpt) (*nats.RawStreamMsg, error) {
+	o, cancel, err := getLegacyRequestOpts(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	s, err := l.stream(name)
+	if err != nil {
+		return nil, err
+	}
+	// The handle carries no stream info, so the regular get API is used.
+	msg, err := s.getMsg(o.ctx, &apiMsgGetRequest{Seq: seq}, false)
+	if err != nil {
+		return nil, legacyError(err)
+	}
+	return legacyRawStreamMsg(msg), nil
+}
+
+// GetLastMsg retrieves the last raw stream message stored in JetStream by subject.
+func (l *legacyContext) GetLastMsg(name, subject string, opts ...nats.JSOpt) (*nats.RawStreamMsg, error) {
+	o, cancel, err := getLegacyRequestOpts(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	s, err := l.stream(name)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := s.getMsg(o.ctx, &apiMsgGetRequest{LastFor: subject}, false)
+	if err != nil {
+		return nil, legacyError(err)
+	}
+	return legacyRawStreamMsg(msg), nil
+}
+
+// DeleteMsg deletes a message from a stream.
+func (l *legacyContext) DeleteMsg(name string, seq uint64, opts ...nats.JSOpt) error {
+	o, cancel, err := getLegacyRequestOpts(opts)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	s, err := l.stream(name)
+	if err != nil {
+		return err
+	}
+	return legacyError(s.DeleteMsg(o.ctx, seq))
+}
+
+// SecureDeleteMsg deletes a message from a stream and overwrites it with random data.
+func (l *legacyContext) SecureDeleteMsg(name string, seq uint64, opts ...nats.JSOpt) error {
+	o, cancel, err := getLegacyRequestOpts(opts)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	s, err := l.stream(name)
+	if err != nil {
+		return err
+	}
+	return legacyError(s.SecureDeleteMsg(o.ctx, seq))
+}
+
+// AddConsumer adds a consumer to a stream.
+func (l *legacyContext) AddConsumer(stream string, cfg *nats.ConsumerConfig, opts ...nats.JSOpt) (*nats.ConsumerInfo, error) {
+	if cfg == nil {
+		return nil, nats.ErrConsumerConfigRequired
+	}
+	var consumerCfg ConsumerConfig
+	if err := convertLegacy(cfg, &consumerCfg); err != nil {
+		return nil, err
+	}
+	o, cancel, err := getLegacyRequestOpts(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	c, err := l.js.AddConsumer(o.ctx, stream, consumerCfg)
+	if err != nil {
+		return nil, legacyError(err)
+	}
+	return legacyConsumerInfo(c.CachedInfo())
+}
+
+// UpdateConsumer updates an existing consumer.
+func (l *legacyContext) UpdateConsumer(stream string, cfg *nats.ConsumerConfig, opts ...nats.JSOpt) (*nats.ConsumerInfo, error) {
+	if cfg == nil {
+		return nil, nats.ErrConsumerConfigRequired
+	}
+	var consumerCfg ConsumerConfig
+	if err := convertLegacy(cfg, &consumerCfg); err != nil {
+		return nil, err
+	}
+	o, cancel, err := getLegacyRequestOpts(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	c, err := l.js.UpdateConsumer(o.ctx, stream, consumerCfg)
+	if err != nil {
+		return nil, legacyError(err)
+	}
+	return legacyConsumerInfo(c.CachedInfo())
+}
+
+// DeleteConsumer deletes a consumer.
+func (l *legacyContext) DeleteConsumer(stream, consumer string, opts ...nats.JSOpt) error {
+	o, cancel, err := getLegacyRequestOpts(opts)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	return legacyError(l.js.DeleteConsumer(o.ctx, stream, consumer))
+}
+
+// ConsumerInfo retrieves information of a consumer from a stream.
+func (l *legacyContext) ConsumerInfo(stream, name string, opts ...nats.JSOpt) (*nats.ConsumerInfo, error) {
+	o, cancel, err := getLegacyRequestOpts(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	c, err := l.js.Consumer(o.ctx, stream, name)
+	if err != nil {
+		return nil, legacyError(err)
+	}
+	return legacyConsumerInfo(c.CachedInfo())
+}
+
+// Consumers is used to retrieve a list of ConsumerInfo objects.
+func (l *legacyContext) Consumers(stream string, opts ...nats.JSOpt) <-chan *nats.ConsumerInfo {
+	o, cancel, err := getLegacyRequestOpts(opts)
+	if err != nil {
+		return nil
+	}
+	s, err := l.stream(stream)
+	if err != nil {
+		cancel()
+		return nil
+	}
+	ch := make(chan *nats.ConsumerInfo)
+	go func() {
+		defer cancel()
+		defer close(ch)
+		lister := s.ListConsumers(o.ctx)
+		for {
+			select {
+			case info := <-lister.Info():
+				legacyInfo, err := legacyConsumerInfo(info)
+				if err != nil {
+					return
+				}
+				select {
+				case ch <- legacyInfo:
+				case <-o.ctx.Done():
+					return
+				}
+			case <-lister.Err():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// ConsumersInfo is used to retrieve a list of ConsumerInfo objects.
+func (l *legacyContext) ConsumersInfo(stream string, opts ...nats.JSOpt) <-chan *nats.ConsumerInfo {
+	return l.Consumers(stream, opts...)
+}
+
+// ConsumerNames is used to retrieve a list of Consumer names.
+func (l *legacyContext) ConsumerNames(stream string, opts ...nats.JSOpt) <-chan string {
+	o, cancel, err := getLegacyRequestOpts(opts)
+	if err != nil {
+		return nil
+	}
+	s, err := l.stream(stream)
+	if err != nil {
+		cancel()
+		return nil
+	}
+	ch := make(chan string)
+	go func() {
+		defer cancel()
+		defer close(ch)
+		lister := s.ConsumerNames(o.ctx)
+		for {
+			select {
+			case name := <-lister.Name():
+				select {
+				case ch <- name:
+				case <-o.ctx.Done():
+					return
+				}
+			case <-lister.Err():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// AccountInfo retrieves info about the JetStream usage from an account.
+func (l *legacyContext) AccountInfo(opts ...nats.JSOpt) (*nats.AccountInfo, error) {
+	o, cancel, err := getLegacyRequestOpts(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	info, err := l.js.AccountInfo(o.ctx)
+	if err != nil {
+		return nil, legacyError(err)
+	}
+	var legacyInfo nats.AccountInfo
+	if err := convertLegacy(info, &legacyInfo); err != nil {
+		return nil, err
+	}
+	return &legacyInfo, nil
+}
+
+// StreamNameBySubject returns a stream name that matches the subject.
+func (l *legacyContext) StreamNameBySubject(subject string, opts ...nats.JSOpt) (string, error) {
+	o, cancel, err := getLegacyRequestOpts(opts)
+	if err != nil {
+		return "", err
+	}
+	defer cancel()
+	req, err := json.Marshal(streamNamesRequest{Subject: subject})
+	if err != nil {
+		return "", err
+	}
+	var resp streamNamesResponse
+	if _, err := l.js.apiRequestJSON(o.ctx, apiSubj(l.js.apiPrefix, apiStreams), &resp, req); err != nil {
+		if errors.Is(err, nats.ErrNoResponders) {
+			return "", nats.ErrJetStreamNotEnabled
+		}
+		return "", err
+	}
+	if resp.Error != nil || len(resp.Streams) != 1 {
+		return "", nats.ErrNoMatchingStream
+	}
+	return resp.Streams[0], nil
+}
+
+// stream returns a handle of the stream without fetching its info.
+// Methods relying on [Stream.CachedInfo] must not be used on it.
+func (l *legacyContext) stream(name string) (*stream, error) {
+	if err := validateStreamName(name); err != nil {
+		return nil, legacyError(err)
+	}
+	return &stream{jetStream: l.js, name: name}, nil
+}
+
+// getLegacyRequestOpts returns the options of a request made through the legacy API.
+// Without a context or max wait option, the timeouts set on the JetStream instance
+// with [WithTimeouts] apply, if any.
+// The returned cancel function is never nil.
+func getLegacyRequestOpts(opts []nats.JSOpt) (*legacyRequestOpts, context.CancelFunc, error) {
+	o := &legacyRequestOpts{ctx: context.Background()}
+	var wait time.Duration
+	for _, opt := range opts {
+		switch opt := opt.(type) {
+		case nats.ContextOpt:
+			o.ctx = opt.Context
+		case nats.MaxWait:
+			wait = time.Duration(opt)
+		case *nats.StreamPurgeRequest:
+			o.purge = opt
+		case *nats.StreamInfoRequest:
+			o.info = opt
+		default:
+			return nil, nil, fmt.Errorf("%w: option %T is not supported by the legacy context", ErrInvalidOption, opt)
+		}
+	}
+	if wait > 0 {
+		if _, ok := o.ctx.Deadline(); !ok {
+			ctx, cancel := context.WithTimeout(o.ctx, wait)
+			o.ctx = ctx
+			return o, cancel, nil
+		}
+	}
+	return o, func() {}, nil
+}
+
+// legacyErrors maps errors to their legacy counterparts.
+var legacyErrors = []struct {
+	err, legacy error
+}{
+	{ErrJetStreamNotEnabled, nats.ErrJetStreamNotEnabled},
+	{ErrJetStreamNotEnabledForAccount, nats.ErrJetStreamNotEnabledForAccount},
+	{ErrStreamNotFound, nats.ErrStreamNotFound},
+	{ErrStreamNameAlreadyInUse, nats.ErrStreamNameAlreadyInUse},
+	{ErrConsumerNotFound, nats.ErrConsumerNotFound},
+	{ErrMsgNotFound, nats.ErrMsgNotFound},
+	{ErrBadRequest, nats.ErrBadRequest},
+	{ErrStreamNameRequired, nats.ErrStreamNameRequired},
+	{ErrInvalidStreamName, nats.ErrInvalidStreamName},
+	{ErrConsumerNameRequired, nats.ErrConsumerNameRequired},
+	{ErrInvalidConsumerName, nats.ErrInvalidConsumerName},
+}
+
+// legacyError maps err to the legacy error or API error it matches.
+func legacyError(err error) error {
+	if err == nil {
+		return nil
+	}
+	for _, e := range legacyErrors {
+		if errors.Is(err, e.err) {
+			return e.legacy
+		}
+	}
+	var jsErr JetStreamError
+	if errors.As(err, &jsErr) && jsErr.APIError() != nil {
+		apiErr := jsErr.APIError()
+		return &nats.APIError{
+			Code:        apiErr.Code,
+			ErrorCode:   nats.ErrorCode(apiErr.ErrorCode),
+			Description: apiErr.Description,
+		}
+	}
+	return err
+}
+
+// convertLegacy converts between types of both APIs, which share the
+// JSON representation of the JetStream API.
+func convertLegacy(from, to interface{}) error {
+	b, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, to)
+}
+
+func legacyStreamInfo(info *StreamInfo) (*nats.StreamInfo, error) {
+	var legacyInfo nats.StreamInfo
+	if err := convertLegacy(info, &legacyInfo); err != nil {
+		return nil, err
+	}
+	return &legacyInfo, nil
+}
+
+func legacyConsumerInfo(info *ConsumerInfo) (*nats.ConsumerInfo, error) {
+	var legacyInfo nats.ConsumerInfo
+	if err := convertLegacy(info, &legacyInfo); err != nil {
+		return nil, err
+	}
+	return &legacyInfo, nil
+}
+
+func legacyRawStreamMsg(msg *RawStreamMsg) *nats.RawStreamMsg {
+	return &nats.RawStreamMsg{
+		Subject:  msg.Subject,
+		Sequence: msg.Sequence,
+		Header:   msg.Header,
+		Data:     msg.Data,
+		Time:     msg.Time,
+	}
+}
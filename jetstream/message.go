@@ -55,6 +55,14 @@ type (
 		InProgress() error
 		// Term tells the server to not redeliver this message, regardless of the value of nats.MaxDeliver
 		Term() error
+		// AckAfterPublish publishes a message derived from this one, waits for ack from server
+		// and only then acknowledges this message, so that a failure in between results in a redelivery
+		// rather than a lost output. Unless set, the output message ID is derived from the stream
+		// and sequence of this message, so that redelivered input does not produce duplicates.
+		AckAfterPublish(context.Context, Publisher, *nats.Msg, ...PublishOpt) (*PubAck, error)
+		// FetchBody retrieves the payload of a message delivered without it, e.g. by a headers only consumer,
+		// using direct get if the stream allows it. For messages delivered with payload, the data is returned as is.
+		FetchBody(context.Context) ([]byte, error)
 	}
 
 	// MsgMetadata is the JetStream metadata associated with received messages.
@@ -66,6 +74,10 @@ type (
 		Stream       string
 		Consumer     string
 		Domain       string
+		// HeadersOnly is set if the message was delivered without payload,
+		// in which case PayloadSize holds the size of the original payload.
+		HeadersOnly bool
+		PayloadSize int
 	}
 
 	// SequencePair includes the consumer and stream sequence info from a JetStream consumer.
@@ -108,8 +120,13 @@ const (
 	ExpectedLastSubjSeqHeader = "Nats-Expected-Last-Subject-Sequence"
 	ExpectedLastMsgIDHeader   = "Nats-Expected-Last-Msg-Id"
 	MsgRollup                 = "Nats-Rollup"
+	MsgTTLHeader              = "Nats-TTL"
 )
 
+// MarkerReasonHeader is set on subject delete markers placed by the server,
+// see [StreamConfig.SubjectDeleteMarkerTTL].
+const MarkerReasonHeader = "Nats-Marker-Reason"
+
 // Headers for republished messages and direct gets.
 const (
 	StreamHeader       = "Nats-Stream"
@@ -119,6 +136,10 @@ const (
 	LastSequenceHeader = "Nats-Last-Sequence"
 )
 
+// MsgSizeHeader holds the size of the payload of messages delivered
+// without it, e.g. by headers only consumers or when browsing headers only.
+const MsgSizeHeader = "Nats-Msg-Size"
+
 // Rollups, can be subject only or all messages.
 const (
 	MsgRollupSubject = "sub"
@@ -153,6 +174,13 @@ func (m *jetStreamMsg) Metadata() (*MsgMetadata, error) {
 	}
 	meta.Sequence.Stream = parser.ParseNum(tokens[parser.AckStreamSeqTokenPos])
 	meta.Sequence.Consumer = parser.ParseNum(tokens[parser.AckConsumerSeqTokenPos])
+	if size := m.msg.Header.Get(MsgSizeHeader); size != "" {
+		meta.HeadersOnly = true
+		meta.PayloadSize, err = strconv.Atoi(size)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid %s header: %q", ErrNotJSMessage, MsgSizeHeader, size)
+		}
+	}
 	return meta, nil
 }
 
@@ -212,6 +240,61 @@ func (m *jetStreamMsg) Term() error {
 	return m.ackReply(context.Background(), ackTerm, false, ackOpts{})
 }
 
+// AckAfterPublish publishes out using the given publisher and acknowledges the message
+// once the publish was acknowledged by the server.
+// Context has to have a deadline, as the ack is sent using [Msg.DoubleAck].
+func (m *jetStreamMsg) AckAfterPublish(ctx context.Context, js Publisher, out *nats.Msg, opts ...PublishOpt) (*PubAck, error) {
+	meta, err := m.Metadata()
+	if err != nil {
+		return nil, err
+	}
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		return nil, nats.ErrNoDeadlineContext
+	}
+	if out.Header.Get(MsgIDHeader) == "" {
+		hdr := nats.Header{}
+		for k, v := range out.Header {
+			hdr[k] = v
+		}
+		hdr.Set(MsgIDHeader, fmt.Sprintf("%s.%d", meta.Stream, meta.Sequence.Stream))
+		out = &nats.Msg{Subject: out.Subject, Reply: out.Reply, Header: hdr, Data: out.Data}
+	}
+	ack, err := js.PublishMsg(ctx, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.DoubleAck(ctx); err != nil {
+		return ack, err
+	}
+	return ack, nil
+}
+
+// FetchBody retrieves the payload of a message delivered without it and sets it as message data.
+// Direct get is used if the stream allows it, otherwise the message is retrieved from the stream leader.
+// As stream info is looked up first, this results in two API requests.
+// For messages delivered with payload, the data is returned as is.
+func (m *jetStreamMsg) FetchBody(ctx context.Context) ([]byte, error) {
+	meta, err := m.Metadata()
+	if err != nil {
+		return nil, err
+	}
+	if !meta.HeadersOnly {
+		return m.msg.Data, nil
+	}
+	stream, err := m.js.Stream(ctx, meta.Stream)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := stream.GetMsgDirect(ctx, meta.Sequence.Stream)
+	if err != nil {
+		return nil, err
+	}
+	m.Lock()
+	m.msg.Data = msg.Data
+	m.Unlock()
+	return msg.Data, nil
+}
+
 func (m *jetStreamMsg) ackReply(ctx context.Context, ackType ackType, sync bool, opts ackOpts) error {
 	err := m.checkReply()
 	if err != nil {
@@ -304,6 +387,9 @@ func checkMsg(msg *nats.Msg) (bool, error) {
 		if strings.Contains(strings.ToLower(descr), "leadership change") {
 			return false, ErrConsumerLeadershipChanged
 		}
+		if strings.Contains(strings.ToLower(descr), "server shutdown") {
+			return false, ErrServerShutdown
+		}
 	}
 	return false, fmt.Errorf("nats: %s", msg.Header.Get("Description"))
 }
@@ -0,0 +1,277 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type (
+	// MirrorSLO defines the maximum replication lag tolerated for a mirror.
+	// Zero values disable the respective check.
+	MirrorSLO struct {
+		// MaxLag is the maximum number of messages the mirror may be behind its origin.
+		MaxLag uint64
+		// MaxDelay is the maximum time since the mirror was last active
+		// with its origin, while it is behind.
+		MaxDelay time.Duration
+	}
+
+	// MirrorLagSample is a single observation of mirror replication lag.
+	MirrorLagSample struct {
+		Time     time.Time
+		Lag      uint64
+		Delay    time.Duration
+		Breached bool
+	}
+
+	// MirrorMonitorOpt configures a [MirrorMonitor].
+	MirrorMonitorOpt func(*mirrorMonitorOpts) error
+
+	// MirrorLagHandler is invoked on SLO state transitions of a [MirrorMonitor].
+	MirrorLagHandler func(MirrorLagSample)
+
+	mirrorMonitorOpts struct {
+		interval   time.Duration
+		history    int
+		breachCB   MirrorLagHandler
+		recoverCB  MirrorLagHandler
+		errHandler func(error)
+	}
+
+	// MirrorMonitor periodically samples the lag of a mirror stream and
+	// compares it against a [MirrorSLO].
+	MirrorMonitor struct {
+		sync.Mutex
+		stream   Stream
+		slo      MirrorSLO
+		opts     mirrorMonitorOpts
+		samples  []MirrorLagSample
+		breached bool
+		since    time.Time
+		cancel   context.CancelFunc
+		done     chan struct{}
+	}
+)
+
+const (
+	// DefaultMirrorMonitorInterval is the default interval between lag samples.
+	DefaultMirrorMonitorInterval = 5 * time.Second
+
+	// DefaultMirrorMonitorHistory is the default number of samples kept by the monitor.
+	DefaultMirrorMonitorHistory = 120
+)
+
+// WithMirrorMonitorInterval sets the interval between lag samples.
+func WithMirrorMonitorInterval(interval time.Duration) MirrorMonitorOpt {
+	return func(opts *mirrorMonitorOpts) error {
+		if interval <= 0 {
+			return fmt.Errorf("%w: interval must be positive", ErrInvalidOption)
+		}
+		opts.interval = interval
+		return nil
+	}
+}
+
+// WithMirrorMonitorHistory sets the number of most recent samples kept by the monitor.
+func WithMirrorMonitorHistory(samples int) MirrorMonitorOpt {
+	return func(opts *mirrorMonitorOpts) error {
+		if samples < 1 {
+			return fmt.Errorf("%w: history must be at least 1", ErrInvalidOption)
+		}
+		opts.history = samples
+		return nil
+	}
+}
+
+// WithMirrorBreachHandler sets the handler invoked when the SLO becomes breached.
+func WithMirrorBreachHandler(cb MirrorLagHandler) MirrorMonitorOpt {
+	return func(opts *mirrorMonitorOpts) error {
+		opts.breachCB = cb
+		return nil
+	}
+}
+
+// WithMirrorRecoverHandler sets the handler invoked when a breached SLO is met again.
+func WithMirrorRecoverHandler(cb MirrorLagHandler) MirrorMonitorOpt {
+	return func(opts *mirrorMonitorOpts) error {
+		opts.recoverCB = cb
+		return nil
+	}
+}
+
+// WithMirrorMonitorErrHandler sets the handler invoked when stream info cannot be retrieved.
+func WithMirrorMonitorErrHandler(cb func(error)) MirrorMonitorOpt {
+	return func(opts *mirrorMonitorOpts) error {
+		opts.errHandler = cb
+		return nil
+	}
+}
+
+// MonitorMirror starts monitoring replication lag of the given mirror stream.
+// Monitoring stops when ctx is done or [MirrorMonitor.Stop] is called.
+//
+// Available options:
+// [WithMirrorMonitorInterval] - sets the interval between samples, default is 5s
+// [WithMirrorMonitorHistory] - sets the number of samples kept, default is 120
+// [WithMirrorBreachHandler] - sets the handler invoked when the SLO becomes breached
+// [WithMirrorRecoverHandler] - sets the handler invoked when the SLO is met again
+// [WithMirrorMonitorErrHandler] - sets the handler for errors retrieving stream info
+func MonitorMirror(ctx context.Context, stream Stream, slo MirrorSLO, opts ...MirrorMonitorOpt) (*MirrorMonitor, error) {
+	o := mirrorMonitorOpts{
+		interval: DefaultMirrorMonitorInterval,
+		history:  DefaultMirrorMonitorHistory,
+	}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+	info, err := stream.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if info.Config.Mirror == nil {
+		return nil, ErrStreamNotMirror
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	m := &MirrorMonitor{
+		stream: stream,
+		slo:    slo,
+		opts:   o,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	m.record(info)
+	go m.run(ctx)
+	return m, nil
+}
+
+// Stop stops sampling the mirror.
+func (m *MirrorMonitor) Stop() {
+	m.cancel()
+	<-m.done
+}
+
+// Breached reports whether the SLO was breached by the latest sample.
+func (m *MirrorMonitor) Breached() bool {
+	m.Lock()
+	defer m.Unlock()
+	return m.breached
+}
+
+// BreachedSince returns the time of the sample which first breached the SLO,
+// or zero time if the SLO is met.
+func (m *MirrorMonitor) BreachedSince() time.Time {
+	m.Lock()
+	defer m.Unlock()
+	return m.since
+}
+
+// Samples returns the most recent lag samples, oldest first.
+func (m *MirrorMonitor) Samples() []MirrorLagSample {
+	m.Lock()
+	defer m.Unlock()
+	return append([]MirrorLagSample(nil), m.samples...)
+}
+
+// Compliance returns the fraction of kept samples which met the SLO.
+func (m *MirrorMonitor) Compliance() float64 {
+	m.Lock()
+	defer m.Unlock()
+	if len(m.samples) == 0 {
+		return 1
+	}
+	var ok int
+	for _, s := range m.samples {
+		if !s.Breached {
+			ok++
+		}
+	}
+	return float64(ok) / float64(len(m.samples))
+}
+
+func (m *MirrorMonitor) run(ctx context.Context) {
+	defer close(m.done)
+	t := time.NewTicker(m.opts.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			info, err := m.stream.Info(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				if m.opts.errHandler != nil {
+					m.opts.errHandler(err)
+				}
+				continue
+			}
+			m.record(info)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// record stores a sample taken from stream info and invokes the
+// handlers on SLO state transitions.
+func (m *MirrorMonitor) record(info *StreamInfo) {
+	sample := m.slo.sample(info, time.Now())
+
+	m.Lock()
+	m.samples = append(m.samples, sample)
+	if len(m.samples) > m.opts.history {
+		m.samples = m.samples[len(m.samples)-m.opts.history:]
+	}
+	wasBreached := m.breached
+	m.breached = sample.Breached
+	if sample.Breached && !wasBreached {
+		m.since = sample.Time
+	} else if !sample.Breached {
+		m.since = time.Time{}
+	}
+	m.Unlock()
+
+	if sample.Breached && !wasBreached && m.opts.breachCB != nil {
+		m.opts.breachCB(sample)
+	}
+	if !sample.Breached && wasBreached && m.opts.recoverCB != nil {
+		m.opts.recoverCB(sample)
+	}
+}
+
+func (slo MirrorSLO) sample(info *StreamInfo, now time.Time) MirrorLagSample {
+	sample := MirrorLagSample{Time: now}
+	if info.Mirror == nil {
+		// Mirror info is not reported until the origin was reached.
+		sample.Breached = slo.MaxLag > 0 || slo.MaxDelay > 0
+		return sample
+	}
+	sample.Lag = info.Mirror.Lag
+	if sample.Lag > 0 {
+		sample.Delay = info.Mirror.Active
+	}
+	if slo.MaxLag > 0 && sample.Lag > slo.MaxLag {
+		sample.Breached = true
+	}
+	if slo.MaxDelay > 0 && sample.Delay > slo.MaxDelay {
+		sample.Breached = true
+	}
+	return sample
+}
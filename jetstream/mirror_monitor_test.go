@@ -0,0 +1,138 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type mirrorInfoStream struct {
+	Stream
+	sync.Mutex
+	info *StreamInfo
+}
+
+func (s *mirrorInfoStream) Info(context.Context, ...StreamInfoOpt) (*StreamInfo, error) {
+	s.Lock()
+	defer s.Unlock()
+	info := *s.info
+	return &info, nil
+}
+
+func (s *mirrorInfoStream) setMirror(lag uint64, active time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+	s.info.Mirror = &StreamSourceInfo{Name: "ORIGIN", Lag: lag, Active: active}
+}
+
+func TestMirrorSLOSample(t *testing.T) {
+	tests := []struct {
+		name     string
+		slo      MirrorSLO
+		mirror   *StreamSourceInfo
+		breached bool
+	}{
+		{
+			name:   "in sync",
+			slo:    MirrorSLO{MaxLag: 10, MaxDelay: time.Second},
+			mirror: &StreamSourceInfo{Lag: 0, Active: time.Minute},
+		},
+		{
+			name:     "lag exceeded",
+			slo:      MirrorSLO{MaxLag: 10},
+			mirror:   &StreamSourceInfo{Lag: 11},
+			breached: true,
+		},
+		{
+			name:     "delay exceeded",
+			slo:      MirrorSLO{MaxDelay: time.Second},
+			mirror:   &StreamSourceInfo{Lag: 1, Active: 2 * time.Second},
+			breached: true,
+		},
+		{
+			name:   "within limits",
+			slo:    MirrorSLO{MaxLag: 10, MaxDelay: time.Second},
+			mirror: &StreamSourceInfo{Lag: 10, Active: time.Second},
+		},
+		{
+			name:     "no mirror info",
+			slo:      MirrorSLO{MaxLag: 10},
+			breached: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sample := test.slo.sample(&StreamInfo{Mirror: test.mirror}, time.Now())
+			if sample.Breached != test.breached {
+				t.Fatalf("Expected breached to be %v; got: %v", test.breached, sample.Breached)
+			}
+		})
+	}
+}
+
+func TestMirrorMonitorTransitions(t *testing.T) {
+	s := &mirrorInfoStream{info: &StreamInfo{Config: StreamConfig{Mirror: &StreamSource{Name: "ORIGIN"}}}}
+	s.setMirror(0, 0)
+
+	breached := make(chan MirrorLagSample, 1)
+	recovered := make(chan MirrorLagSample, 1)
+	m, err := MonitorMirror(context.Background(), s, MirrorSLO{MaxLag: 5},
+		WithMirrorMonitorInterval(10*time.Millisecond),
+		WithMirrorMonitorHistory(3),
+		WithMirrorBreachHandler(func(sample MirrorLagSample) { breached <- sample }),
+		WithMirrorRecoverHandler(func(sample MirrorLagSample) { recovered <- sample }),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer m.Stop()
+
+	s.setMirror(100, time.Second)
+	select {
+	case sample := <-breached:
+		if sample.Lag != 100 {
+			t.Fatalf("Expected lag 100; got: %d", sample.Lag)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Did not receive breach callback")
+	}
+	if !m.Breached() || m.BreachedSince().IsZero() {
+		t.Fatalf("Expected monitor to report breach")
+	}
+
+	s.setMirror(0, 0)
+	select {
+	case <-recovered:
+	case <-time.After(time.Second):
+		t.Fatalf("Did not receive recover callback")
+	}
+	m.Stop()
+	if m.Breached() || !m.BreachedSince().IsZero() {
+		t.Fatalf("Expected monitor to report SLO met")
+	}
+	if n := len(m.Samples()); n != 3 {
+		t.Fatalf("Expected 3 samples; got: %d", n)
+	}
+}
+
+func TestMonitorMirrorNotMirror(t *testing.T) {
+	s := &mirrorInfoStream{info: &StreamInfo{}}
+	if _, err := MonitorMirror(context.Background(), s, MirrorSLO{MaxLag: 5}); err != ErrStreamNotMirror {
+		t.Fatalf("Expected error: %v; got: %v", ErrStreamNotMirror, err)
+	}
+}
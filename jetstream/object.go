@@ -0,0 +1,1116 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nuid"
+)
+
+type (
+	// ObjectStoreManager creates, loads and deletes Object Stores.
+	ObjectStoreManager interface {
+		// ObjectStore will look up and bind to an existing object store instance.
+		ObjectStore(ctx context.Context, bucket string) (ObjectStore, error)
+		// CreateObjectStore will create an object store.
+		CreateObjectStore(ctx context.Context, cfg ObjectStoreConfig) (ObjectStore, error)
+		// DeleteObjectStore will delete the underlying stream for the named object store.
+		DeleteObjectStore(ctx context.Context, bucket string) error
+	}
+
+	// ObjectStore is a blob store capable of storing large objects efficiently in
+	// JetStream streams. Objects are split into chunks stored as separate messages,
+	// so that neither Put nor Get hold whole objects in memory.
+	ObjectStore interface {
+		// Put will place the contents from the reader into a new object,
+		// replacing the object with the same name if it exists.
+		Put(ctx context.Context, meta ObjectMeta, reader io.Reader, opts ...PutObjectOpt) (*ObjectInfo, error)
+		// PutBytes is convenience function to put a byte slice into this object store.
+		PutBytes(ctx context.Context, name string, data []byte) (*ObjectInfo, error)
+
+		// Get will write the contents of the named object to the writer,
+		// verifying its digest once all chunks are written.
+		Get(ctx context.Context, name string, writer io.Writer, opts ...GetObjectOpt) (*ObjectInfo, error)
+		// GetBytes is a convenience function to pull an object from this object store and return it as a byte slice.
+		GetBytes(ctx context.Context, name string, opts ...GetObjectOpt) ([]byte, error)
+
+		// GetInfo will retrieve the current information for the object.
+		GetInfo(ctx context.Context, name string, opts ...GetObjectInfoOpt) (*ObjectInfo, error)
+
+		// UpdateMeta will update the name, description and headers of an object
+		// without re-uploading its contents.
+		UpdateMeta(ctx context.Context, name string, meta ObjectMeta) error
+
+		// Delete will delete the named object.
+		Delete(ctx context.Context, name string) error
+
+		// AddLink will add a link to another object.
+		AddLink(ctx context.Context, name string, obj *ObjectInfo) (*ObjectInfo, error)
+		// AddBucketLink will add a link to another object store.
+		AddBucketLink(ctx context.Context, name string, bucket ObjectStore) (*ObjectInfo, error)
+
+		// Watch for any updates to objects in the store.
+		Watch(ctx context.Context, opts ...WatchOpt) (ObjectWatcher, error)
+
+		// List will list all the objects in this store.
+		List(ctx context.Context, opts ...ListObjectsOpt) ([]*ObjectInfo, error)
+
+		// Bucket returns the current bucket name.
+		Bucket() string
+
+		// Status retrieves run-time status about the backing store of the bucket.
+		Status(ctx context.Context) (ObjectStoreStatus, error)
+	}
+
+	// ObjectWatcher is what is returned when doing a watch.
+	ObjectWatcher interface {
+		// Updates returns a channel to read any updates to objects, with
+		// nil sent once the initial infos were received.
+		Updates() <-chan *ObjectInfo
+		// Stop will stop this watcher.
+		Stop() error
+	}
+
+	// ObjectStoreConfig is the config for the object store.
+	ObjectStoreConfig struct {
+		Bucket      string
+		Description string
+		TTL         time.Duration
+		MaxBytes    int64
+		Storage     StorageType
+		Replicas    int
+		Placement   *Placement
+	}
+
+	// ObjectStoreStatus is run-time status about a bucket.
+	ObjectStoreStatus interface {
+		// Bucket is the name of the bucket
+		Bucket() string
+		// Description is the description supplied when creating the bucket
+		Description() string
+		// TTL indicates how long objects are kept in the bucket
+		TTL() time.Duration
+		// Storage indicates the underlying JetStream storage technology used to store data
+		Storage() StorageType
+		// Replicas indicates how many storage replicas are kept for the data in the bucket
+		Replicas() int
+		// Sealed indicates the stream is sealed and cannot be modified in any way
+		Sealed() bool
+		// Size is the combined size of all data in the bucket including metadata, in bytes
+		Size() uint64
+		// BackingStore provides details about the underlying storage
+		BackingStore() string
+	}
+
+	// ObjectBucketStatus represents status of a Bucket, implements [ObjectStoreStatus].
+	ObjectBucketStatus struct {
+		nfo    *StreamInfo
+		bucket string
+	}
+
+	// ObjectMetaOptions are optional settings of an object.
+	ObjectMetaOptions struct {
+		Link      *ObjectLink `json:"link,omitempty"`
+		ChunkSize uint32      `json:"max_chunk_size,omitempty"`
+	}
+
+	// ObjectMeta is high level information about an object.
+	ObjectMeta struct {
+		Name        string      `json:"name"`
+		Description string      `json:"description,omitempty"`
+		Headers     nats.Header `json:"headers,omitempty"`
+
+		// Optional options.
+		Opts *ObjectMetaOptions `json:"options,omitempty"`
+	}
+
+	// ObjectInfo is meta plus instance information.
+	ObjectInfo struct {
+		ObjectMeta
+		Bucket  string    `json:"bucket"`
+		NUID    string    `json:"nuid"`
+		Size    uint64    `json:"size"`
+		ModTime time.Time `json:"mtime"`
+		Chunks  uint32    `json:"chunks"`
+		Digest  string    `json:"digest,omitempty"`
+		Deleted bool      `json:"deleted,omitempty"`
+	}
+
+	// ObjectLink is used to embed links to other buckets and objects.
+	ObjectLink struct {
+		// Bucket is the name of the other object store.
+		Bucket string `json:"bucket"`
+		// Name can be used to link to a single object.
+		// If empty means this is a link to the whole store, like a directory.
+		Name string `json:"name,omitempty"`
+	}
+
+	// PutObjectOpt configures [ObjectStore.Put].
+	PutObjectOpt func(*putObjectOpts) error
+
+	putObjectOpts struct {
+		upload     *ObjectUpload
+		progressCB func(stored uint64)
+	}
+
+	// ObjectUpload is the state of a resumable upload, see [PutObjectResumable].
+	ObjectUpload struct {
+		// NUID identifies the chunks of the upload, assigned by the first attempt.
+		NUID string
+	}
+
+	// GetObjectOpt configures [ObjectStore.Get].
+	GetObjectOpt func(*getObjectOpts) error
+
+	getObjectOpts struct {
+		// Include deleted object in the result.
+		showDeleted bool
+	}
+
+	// GetObjectInfoOpt configures [ObjectStore.GetInfo].
+	GetObjectInfoOpt func(*getObjectInfoOpts) error
+
+	getObjectInfoOpts struct {
+		// Include deleted object in the result.
+		showDeleted bool
+	}
+
+	// ListObjectsOpt configures [ObjectStore.List].
+	ListObjectsOpt func(*listObjectOpts) error
+
+	listObjectOpts struct {
+		// Include deleted objects in the result.
+		showDeleted bool
+	}
+
+	obs struct {
+		name   string
+		stream Stream
+		js     *jetStream
+	}
+
+	objWatcher struct {
+		sync.Mutex
+		updates  chan *ObjectInfo
+		cons     ConsumeContext
+		done     chan struct{}
+		stopped  bool
+		initDone bool
+		stopOnce sync.Once
+	}
+
+	objPendingChunk struct {
+		paf  PubAckFuture
+		size int
+	}
+
+	objStoredChunks struct {
+		it   MessagesContext
+		last uint64
+	}
+)
+
+const (
+	objNameTmpl         = "OBJ_%s"           // OBJ_<bucket> // stream name
+	objAllChunksPreTmpl = "$O.%s.C.>"        // $O.<bucket>.C.> // chunk stream subject
+	objAllMetaPreTmpl   = "$O.%s.M.>"        // $O.<bucket>.M.> // meta stream subject
+	objChunksPreTmpl    = "$O.%s.C.%s"       // $O.<bucket>.C.<object-nuid> // chunk message subject
+	objMetaPreTmpl      = "$O.%s.M.%s"       // $O.<bucket>.M.<name-encoded> // meta message subject
+	objDefaultChunkSize = uint32(128 * 1024) // 128k
+	objDigestType       = "SHA-256="
+	objDigestTmpl       = objDigestType + "%s"
+
+	// objMaxPendingChunks limits the number of chunks published but not yet
+	// acknowledged by the server, bounding memory used by Put.
+	objMaxPendingChunks = 64
+
+	// objPendingWait is the maximum time a failed Put waits for chunks
+	// in flight before purging them.
+	objPendingWait = 5 * time.Second
+)
+
+// PutObjectProgress sets a callback invoked with the number of bytes of the
+// object stored so far, each time a chunk is acknowledged by the server.
+func PutObjectProgress(cb func(stored uint64)) PutObjectOpt {
+	return func(opts *putObjectOpts) error {
+		opts.progressCB = cb
+		return nil
+	}
+}
+
+// PutObjectResumable makes Put() keep the chunks stored when it fails, so
+// that calling it again with the same upload and a reader providing the same
+// contents from the start only publishes the missing chunks. Stored chunks
+// are reused as long as they match the contents of the reader, the chunk
+// size must thus not change between attempts.
+//
+// Chunks of an abandoned upload remain in the bucket until it is resumed.
+func PutObjectResumable(upload *ObjectUpload) PutObjectOpt {
+	return func(opts *putObjectOpts) error {
+		if upload == nil {
+			return fmt.Errorf("%w: upload is required", ErrInvalidOption)
+		}
+		opts.upload = upload
+		return nil
+	}
+}
+
+// GetObjectShowDeleted makes Get() return object if it was marked as deleted.
+func GetObjectShowDeleted() GetObjectOpt {
+	return func(opts *getObjectOpts) error {
+		opts.showDeleted = true
+		return nil
+	}
+}
+
+// GetObjectInfoShowDeleted makes GetInfo() return object if it was marked as deleted.
+func GetObjectInfoShowDeleted() GetObjectInfoOpt {
+	return func(opts *getObjectInfoOpts) error {
+		opts.showDeleted = true
+		return nil
+	}
+}
+
+// ListObjectsShowDeleted makes List() return deleted objects.
+func ListObjectsShowDeleted() ListObjectsOpt {
+	return func(opts *listObjectOpts) error {
+		opts.showDeleted = true
+		return nil
+	}
+}
+
+// CreateObjectStore will create an object store.
+func (js *jetStream) CreateObjectStore(ctx context.Context, cfg ObjectStoreConfig) (ObjectStore, error) {
+	if !validBucketRe.MatchString(cfg.Bucket) {
+		return nil, ErrInvalidStoreName
+	}
+
+	name := cfg.Bucket
+	chunks := fmt.Sprintf(objAllChunksPreTmpl, name)
+	meta := fmt.Sprintf(objAllMetaPreTmpl, name)
+
+	replicas := cfg.Replicas
+	if replicas == 0 {
+		replicas = 1
+	}
+	maxBytes := cfg.MaxBytes
+	if maxBytes == 0 {
+		maxBytes = -1
+	}
+
+	scfg := StreamConfig{
+		Name:        fmt.Sprintf(objNameTmpl, name),
+		Description: cfg.Description,
+		Subjects:    []string{chunks, meta},
+		MaxAge:      cfg.TTL,
+		MaxBytes:    maxBytes,
+		Storage:     cfg.Storage,
+		Replicas:    replicas,
+		Placement:   cfg.Placement,
+		Discard:     DiscardNew,
+		AllowRollup: true,
+		AllowDirect: true,
+	}
+
+	s, err := js.CreateStream(ctx, scfg)
+	if err != nil {
+		return nil, err
+	}
+	return &obs{name: name, stream: s, js: js}, nil
+}
+
+// ObjectStore will look up and bind to an existing object store instance.
+func (js *jetStream) ObjectStore(ctx context.Context, bucket string) (ObjectStore, error) {
+	obs, err := js.objectStore(ctx, bucket)
+	if err != nil {
+		return nil, err
+	}
+	return obs, nil
+}
+
+func (js *jetStream) objectStore(ctx context.Context, bucket string) (*obs, error) {
+	if !validBucketRe.MatchString(bucket) {
+		return nil, ErrInvalidStoreName
+	}
+	s, err := js.Stream(ctx, fmt.Sprintf(objNameTmpl, bucket))
+	if err != nil {
+		if errors.Is(err, ErrStreamNotFound) {
+			err = ErrBucketNotFound
+		}
+		return nil, err
+	}
+	return &obs{name: bucket, stream: s, js: js}, nil
+}
+
+// DeleteObjectStore will delete the underlying stream for the named object store.
+func (js *jetStream) DeleteObjectStore(ctx context.Context, bucket string) error {
+	if !validBucketRe.MatchString(bucket) {
+		return ErrInvalidStoreName
+	}
+	err := js.DeleteStream(ctx, fmt.Sprintf(objNameTmpl, bucket))
+	if errors.Is(err, ErrStreamNotFound) {
+		return ErrBucketNotFound
+	}
+	return err
+}
+
+func encodeName(name string) string {
+	return base64.URLEncoding.EncodeToString([]byte(name))
+}
+
+// Put will place the contents from the reader into this object-store.
+// Chunks are published asynchronously, with a bounded number of chunks
+// awaiting acknowledgement, so the reader is never buffered as a whole.
+// If publishing fails, chunks already stored are purged, unless the upload
+// is resumable.
+func (obs *obs) Put(ctx context.Context, meta ObjectMeta, r io.Reader, opts ...PutObjectOpt) (*ObjectInfo, error) {
+	var o putObjectOpts
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+	if meta.Name == "" {
+		return nil, ErrBadObjectMeta
+	}
+	if meta.Opts == nil {
+		meta.Opts = &ObjectMetaOptions{ChunkSize: objDefaultChunkSize}
+	} else if meta.Opts.Link != nil {
+		return nil, ErrLinkNotAllowed
+	} else if meta.Opts.ChunkSize == 0 {
+		opts := *meta.Opts
+		opts.ChunkSize = objDefaultChunkSize
+		meta.Opts = &opts
+	}
+
+	// Create the new nuid so chunks go on a new subject if the name is re-used,
+	// unless resuming an upload with chunks already stored.
+	newnuid := nuid.Next()
+	if o.upload != nil {
+		if o.upload.NUID == "" {
+			o.upload.NUID = newnuid
+		}
+		newnuid = o.upload.NUID
+	}
+	chunkSubj := fmt.Sprintf(objChunksPreTmpl, obs.name, newnuid)
+
+	// Grab existing meta info. Ok to be found or not found, any other error is a problem.
+	// Chunks on the old nuid are cleaned up at the end.
+	einfo, err := obs.GetInfo(ctx, meta.Name, GetObjectInfoShowDeleted())
+	if err != nil && !errors.Is(err, ErrObjectNotFound) {
+		return nil, err
+	}
+
+	var total uint64
+	pending := make([]objPendingChunk, 0, objMaxPendingChunks)
+	// waitAcks waits until at most max chunks are awaiting acknowledgement.
+	waitAcks := func(max int) error {
+		for len(pending) > max {
+			select {
+			case <-pending[0].paf.Ok():
+			case err := <-pending[0].paf.Err():
+				return err
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			total += uint64(pending[0].size)
+			pending = pending[1:]
+			if o.progressCB != nil {
+				o.progressCB(total)
+			}
+		}
+		return nil
+	}
+	purgePartial := func(err error) (*ObjectInfo, error) {
+		// Wait for chunks in flight so that they are not stored after the purge.
+		timeout := time.NewTimer(objPendingWait)
+		defer timeout.Stop()
+	Pending:
+		for _, p := range pending {
+			select {
+			case <-p.paf.Ok():
+			case <-p.paf.Err():
+			case <-timeout.C:
+				break Pending
+			}
+		}
+		// Keep the chunks of a resumable upload for the next attempt.
+		if o.upload != nil {
+			return nil, err
+		}
+		// Purge without the original context, which may be done.
+		obs.stream.Purge(context.Background(), WithPurgeSubject(chunkSubj))
+		return nil, err
+	}
+
+	// stored iterates over chunks of a resumed upload, reused as long as
+	// they match the contents of the reader.
+	var stored *objStoredChunks
+	if o.upload != nil {
+		if stored, err = obs.storedChunks(ctx, chunkSubj); err != nil {
+			return nil, err
+		}
+		defer stored.stop()
+	}
+
+	h := sha256.New()
+	chunk := make([]byte, meta.Opts.ChunkSize)
+	var sent uint32
+	for r != nil {
+		if err := ctx.Err(); err != nil {
+			return purgePartial(err)
+		}
+		n, readErr := io.ReadFull(r, chunk)
+		if readErr != nil && readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+			return purgePartial(readErr)
+		}
+		if n > 0 {
+			h.Write(chunk[:n])
+			reused, err := stored.reuse(ctx, obs.stream, chunk[:n])
+			if err != nil {
+				return purgePartial(err)
+			}
+			if reused {
+				total += uint64(n)
+				if o.progressCB != nil {
+					o.progressCB(total)
+				}
+			} else {
+				m := nats.NewMsg(chunkSubj)
+				m.Data = chunk[:n]
+				paf, err := obs.js.PublishMsgAsync(ctx, m)
+				if err != nil {
+					return purgePartial(err)
+				}
+				// The chunk buffer is reused, the message is already sent.
+				pending = append(pending, objPendingChunk{paf: paf, size: n})
+				if err := waitAcks(objMaxPendingChunks - 1); err != nil {
+					return purgePartial(err)
+				}
+			}
+			sent++
+		}
+		if readErr != nil {
+			break
+		}
+	}
+	// Stored chunks beyond the end of the reader are not part of the object.
+	if err := stored.discard(ctx, obs.stream); err != nil {
+		return purgePartial(err)
+	}
+	if err := waitAcks(0); err != nil {
+		return purgePartial(err)
+	}
+
+	info := &ObjectInfo{
+		ObjectMeta: meta,
+		Bucket:     obs.name,
+		NUID:       newnuid,
+		Size:       total,
+		Chunks:     sent,
+		Digest:     fmt.Sprintf(objDigestTmpl, base64.URLEncoding.EncodeToString(h.Sum(nil))),
+	}
+	if err := obs.publishMeta(ctx, info); err != nil {
+		return purgePartial(err)
+	}
+	info.ModTime = time.Now().UTC()
+
+	// Delete any original chunks, unless a completed upload was resumed.
+	if einfo != nil && !einfo.Deleted && einfo.NUID != newnuid {
+		echunkSubj := fmt.Sprintf(objChunksPreTmpl, obs.name, einfo.NUID)
+		if _, err := obs.stream.Purge(ctx, WithPurgeSubject(echunkSubj)); err != nil {
+			return nil, err
+		}
+	}
+	return info, nil
+}
+
+// storedChunks returns an iterator over the chunks stored on the subject,
+// or nil if there are none.
+func (obs *obs) storedChunks(ctx context.Context, chunkSubj string) (*objStoredChunks, error) {
+	last, err := obs.stream.GetLastMsgForSubject(ctx, chunkSubj)
+	if errors.Is(err, ErrMsgNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cons, err := obs.stream.OrderedConsumer(ctx, OrderedConsumerConfig{
+		FilterSubjects: []string{chunkSubj},
+	})
+	if err != nil {
+		return nil, err
+	}
+	it, err := cons.Messages()
+	if err != nil {
+		return nil, err
+	}
+	return &objStoredChunks{it: it, last: last.Sequence}, nil
+}
+
+// next returns the next stored chunk, or nil once all were returned.
+func (s *objStoredChunks) next(ctx context.Context) (Msg, uint64, error) {
+	if s == nil || s.it == nil {
+		return nil, 0, nil
+	}
+	msg, err := s.it.NextWithContext(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	meta, err := msg.Metadata()
+	if err != nil {
+		return nil, 0, err
+	}
+	if meta.Sequence.Stream >= s.last {
+		s.stop()
+	}
+	return msg, meta.Sequence.Stream, nil
+}
+
+// reuse reports whether the next stored chunk matches the data. Once a
+// chunk does not match, it and all following stored chunks are deleted.
+func (s *objStoredChunks) reuse(ctx context.Context, stream Stream, data []byte) (bool, error) {
+	msg, seq, err := s.next(ctx)
+	if err != nil || msg == nil {
+		return false, err
+	}
+	if bytes.Equal(msg.Data(), data) {
+		return true, nil
+	}
+	if err := stream.DeleteMsg(ctx, seq); err != nil {
+		return false, err
+	}
+	return false, s.discard(ctx, stream)
+}
+
+// discard deletes the remaining stored chunks.
+func (s *objStoredChunks) discard(ctx context.Context, stream Stream) error {
+	for {
+		msg, seq, err := s.next(ctx)
+		if err != nil || msg == nil {
+			return err
+		}
+		if err := stream.DeleteMsg(ctx, seq); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *objStoredChunks) stop() {
+	if s != nil && s.it != nil {
+		s.it.Stop()
+		s.it = nil
+	}
+}
+
+// PutBytes is convenience function to put a byte slice into this object store.
+func (obs *obs) PutBytes(ctx context.Context, name string, data []byte) (*ObjectInfo, error) {
+	return obs.Put(ctx, ObjectMeta{Name: name}, bytes.NewReader(data))
+}
+
+// publishMeta replaces the meta message of the object with the info.
+func (obs *obs) publishMeta(ctx context.Context, info *ObjectInfo) error {
+	// Marshal the object into json, don't store an actual time.
+	meta := *info
+	meta.ModTime = time.Time{}
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	mm := nats.NewMsg(fmt.Sprintf(objMetaPreTmpl, obs.name, encodeName(info.Name)))
+	mm.Header.Set(MsgRollup, MsgRollupSubject)
+	mm.Data = data
+	_, err = obs.js.PublishMsg(ctx, mm)
+	return err
+}
+
+// Get will write the object to the writer, reading chunks from the
+// underlying stream one at a time. Links to objects are followed.
+func (obs *obs) Get(ctx context.Context, name string, w io.Writer, opts ...GetObjectOpt) (*ObjectInfo, error) {
+	var o getObjectOpts
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+	return obs.get(ctx, name, w, o, true)
+}
+
+// get writes the object to the writer, following a link to an object
+// if followLink is set.
+func (obs *obs) get(ctx context.Context, name string, w io.Writer, o getObjectOpts, followLink bool) (*ObjectInfo, error) {
+	var infoOpts []GetObjectInfoOpt
+	if o.showDeleted {
+		infoOpts = append(infoOpts, GetObjectInfoShowDeleted())
+	}
+	info, err := obs.GetInfo(ctx, name, infoOpts...)
+	if err != nil {
+		return nil, err
+	}
+	if info.NUID == "" {
+		return nil, ErrBadObjectMeta
+	}
+	if info.isLink() {
+		link := info.Opts.Link
+		if !followLink || link.Name == "" {
+			return nil, ErrObjectIsLink
+		}
+		lobs, err := obs.js.objectStore(ctx, link.Bucket)
+		if err != nil {
+			return nil, err
+		}
+		return lobs.get(ctx, link.Name, w, o, false)
+	}
+	if info.Size == 0 {
+		return info, nil
+	}
+
+	cons, err := obs.stream.OrderedConsumer(ctx, OrderedConsumerConfig{
+		FilterSubjects: []string{fmt.Sprintf(objChunksPreTmpl, obs.name, info.NUID)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	it, err := cons.Messages()
+	if err != nil {
+		return nil, err
+	}
+	defer it.Stop()
+
+	h := sha256.New()
+	for received := uint32(0); received < info.Chunks; received++ {
+		msg, err := it.NextWithContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(msg.Data()); err != nil {
+			return nil, err
+		}
+		h.Write(msg.Data())
+	}
+	if err := verifyDigest(h, info.Digest); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// GetBytes is a convenience function to pull an object from this object store and return it as a byte slice.
+func (obs *obs) GetBytes(ctx context.Context, name string, opts ...GetObjectOpt) ([]byte, error) {
+	var b bytes.Buffer
+	if _, err := obs.Get(ctx, name, &b, opts...); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func (info *ObjectInfo) isLink() bool {
+	return info.ObjectMeta.Opts != nil && info.ObjectMeta.Opts.Link != nil
+}
+
+// verifyDigest checks the sum of the object data against the digest stored in its info.
+func verifyDigest(h hash.Hash, digest string) error {
+	_, encoded, ok := strings.Cut(digest, "=")
+	if !ok {
+		return ErrInvalidDigestFormat
+	}
+	expected, err := base64.URLEncoding.DecodeString(encoded)
+	if err != nil {
+		return ErrInvalidDigestFormat
+	}
+	if !bytes.Equal(h.Sum(nil), expected) {
+		return ErrDigestMismatch
+	}
+	return nil
+}
+
+// GetInfo will retrieve the current information for the object.
+func (obs *obs) GetInfo(ctx context.Context, name string, opts ...GetObjectInfoOpt) (*ObjectInfo, error) {
+	if name == "" {
+		return nil, ErrObjectNameRequired
+	}
+	var o getObjectInfoOpts
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+
+	metaSubj := fmt.Sprintf(objMetaPreTmpl, obs.name, encodeName(name))
+	m, err := obs.stream.GetLastMsgForSubjectDirect(ctx, metaSubj)
+	if err != nil {
+		if errors.Is(err, ErrMsgNotFound) {
+			err = ErrObjectNotFound
+		}
+		return nil, err
+	}
+	var info ObjectInfo
+	if err := json.Unmarshal(m.Data, &info); err != nil {
+		return nil, ErrBadObjectMeta
+	}
+	if !o.showDeleted && info.Deleted {
+		return nil, ErrObjectNotFound
+	}
+	info.ModTime = m.Time
+	return &info, nil
+}
+
+// AddLink will add a link to another object, replacing a link with the same name.
+func (obs *obs) AddLink(ctx context.Context, name string, obj *ObjectInfo) (*ObjectInfo, error) {
+	if name == "" {
+		return nil, ErrObjectNameRequired
+	}
+	if obj == nil || obj.Name == "" {
+		return nil, ErrObjectRequired
+	}
+	if obj.Deleted {
+		return nil, ErrNoLinkToDeleted
+	}
+	if obj.isLink() {
+		return nil, ErrNoLinkToLink
+	}
+	return obs.addLink(ctx, name, &ObjectLink{Bucket: obj.Bucket, Name: obj.Name})
+}
+
+// AddBucketLink will add a link to another object store, replacing a link with the same name.
+func (obs *obs) AddBucketLink(ctx context.Context, name string, bucket ObjectStore) (*ObjectInfo, error) {
+	if name == "" {
+		return nil, ErrObjectNameRequired
+	}
+	if bucket == nil {
+		return nil, ErrBucketRequired
+	}
+	return obs.addLink(ctx, name, &ObjectLink{Bucket: bucket.Bucket()})
+}
+
+// addLink publishes the meta of a link, unless an object with the name exists.
+func (obs *obs) addLink(ctx context.Context, name string, link *ObjectLink) (*ObjectInfo, error) {
+	einfo, err := obs.GetInfo(ctx, name)
+	if err != nil && !errors.Is(err, ErrObjectNotFound) {
+		return nil, err
+	}
+	if einfo != nil && !einfo.isLink() {
+		return nil, ErrObjectAlreadyExists
+	}
+
+	info := &ObjectInfo{
+		ObjectMeta: ObjectMeta{
+			Name: name,
+			Opts: &ObjectMetaOptions{Link: link},
+		},
+		Bucket: obs.name,
+		NUID:   nuid.Next(),
+	}
+	if err := obs.publishMeta(ctx, info); err != nil {
+		return nil, err
+	}
+	info.ModTime = time.Now().UTC()
+	return info, nil
+}
+
+// UpdateMeta will update the name, description and headers of an object
+// without changing its contents. Options of the object cannot be updated.
+func (obs *obs) UpdateMeta(ctx context.Context, name string, meta ObjectMeta) error {
+	if meta.Name == "" {
+		return ErrBadObjectMeta
+	}
+	info, err := obs.GetInfo(ctx, name)
+	if err != nil {
+		if errors.Is(err, ErrObjectNotFound) {
+			return ErrUpdateMetaDeleted
+		}
+		return err
+	}
+
+	// The object cannot be renamed over an existing one.
+	if meta.Name != name {
+		_, err := obs.GetInfo(ctx, meta.Name)
+		if err == nil {
+			return ErrObjectAlreadyExists
+		}
+		if !errors.Is(err, ErrObjectNotFound) {
+			return err
+		}
+	}
+
+	info.Name = meta.Name
+	info.Description = meta.Description
+	info.Headers = meta.Headers
+	if err := obs.publishMeta(ctx, info); err != nil {
+		return err
+	}
+
+	// The meta is stored under the new name, remove it from the old one.
+	if meta.Name != name {
+		metaSubj := fmt.Sprintf(objMetaPreTmpl, obs.name, encodeName(name))
+		if _, err := obs.stream.Purge(ctx, WithPurgeSubject(metaSubj)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete will delete the object.
+func (obs *obs) Delete(ctx context.Context, name string) error {
+	info, err := obs.GetInfo(ctx, name, GetObjectInfoShowDeleted())
+	if err != nil {
+		return err
+	}
+	if info.NUID == "" {
+		return ErrBadObjectMeta
+	}
+
+	// Place a rollup delete marker and publish the info.
+	info.Deleted = true
+	info.Size, info.Chunks, info.Digest = 0, 0, ""
+	if err := obs.publishMeta(ctx, info); err != nil {
+		return err
+	}
+
+	// Purge chunks for the object.
+	chunkSubj := fmt.Sprintf(objChunksPreTmpl, obs.name, info.NUID)
+	_, err = obs.stream.Purge(ctx, WithPurgeSubject(chunkSubj))
+	return err
+}
+
+// List will list all the objects in this store.
+func (obs *obs) List(ctx context.Context, opts ...ListObjectsOpt) ([]*ObjectInfo, error) {
+	var o listObjectOpts
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+
+	cons, err := obs.stream.OrderedConsumer(ctx, OrderedConsumerConfig{
+		FilterSubjects: []string{fmt.Sprintf(objAllMetaPreTmpl, obs.name)},
+		DeliverPolicy:  DeliverLastPerSubjectPolicy,
+	})
+	if err != nil {
+		return nil, err
+	}
+	it, err := cons.Messages()
+	if err != nil {
+		return nil, err
+	}
+	defer it.Stop()
+
+	var objs []*ObjectInfo
+	if info := cons.CachedInfo(); info == nil || info.NumPending > 0 {
+		for {
+			msg, err := it.NextWithContext(ctx)
+			if err != nil {
+				return nil, err
+			}
+			meta, err := msg.Metadata()
+			if err != nil {
+				return nil, err
+			}
+			var info ObjectInfo
+			if err := json.Unmarshal(msg.Data(), &info); err != nil {
+				return nil, ErrBadObjectMeta
+			}
+			info.ModTime = meta.Timestamp
+			if o.showDeleted || !info.Deleted {
+				objs = append(objs, &info)
+			}
+			if meta.NumPending == 0 {
+				break
+			}
+		}
+	}
+	if len(objs) == 0 {
+		return nil, ErrNoObjectsFound
+	}
+	return objs, nil
+}
+
+// Watch for any updates to objects in the bucket, i.e. objects being put,
+// updated or deleted, and links being added. The latest info of each object
+// is sent first, followed by nil once all of them were received, unless
+// [UpdatesOnly] is used. Updates are read with an ordered consumer of the
+// meta subjects. [IncludeHistory], [IgnoreDeletes] and [UpdatesOnly] are
+// supported.
+func (obs *obs) Watch(ctx context.Context, opts ...WatchOpt) (ObjectWatcher, error) {
+	var o watchOpts
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+	if o.includeHistory && o.updatesOnly {
+		return nil, fmt.Errorf("%w: include history can not be used with updates only", ErrInvalidOption)
+	}
+	if o.metaOnly {
+		return nil, fmt.Errorf("%w: meta only is not supported by object watchers", ErrInvalidOption)
+	}
+
+	cfg := OrderedConsumerConfig{
+		FilterSubjects: []string{fmt.Sprintf(objAllMetaPreTmpl, obs.name)},
+		DeliverPolicy:  DeliverLastPerSubjectPolicy,
+	}
+	if o.includeHistory {
+		cfg.DeliverPolicy = DeliverAllPolicy
+	}
+	if o.updatesOnly {
+		cfg.DeliverPolicy = DeliverNewPolicy
+	}
+	cons, err := obs.stream.OrderedConsumer(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// We will block below on placing items on the chan. That is by design.
+	w := &objWatcher{updates: make(chan *ObjectInfo, 32), done: make(chan struct{})}
+
+	update := func(m Msg) {
+		meta, err := m.Metadata()
+		if err != nil {
+			return
+		}
+		var info ObjectInfo
+		// infos which cannot be decoded are skipped, but still
+		// count towards initial values
+		decoded := json.Unmarshal(m.Data(), &info) == nil
+		info.ModTime = meta.Timestamp
+
+		w.Lock()
+		defer w.Unlock()
+		if w.stopped {
+			return
+		}
+		if decoded && (!o.ignoreDeletes || !info.Deleted) {
+			if !w.send(&info) {
+				return
+			}
+		}
+		if !w.initDone && meta.NumPending == 0 {
+			w.initDone = true
+			w.send(nil)
+		}
+	}
+
+	// Start consuming and check the initial pending count under the lock,
+	// preventing the race between this code and the update callback.
+	w.Lock()
+	defer w.Unlock()
+	cc, err := cons.Consume(update)
+	if err != nil {
+		return nil, err
+	}
+	w.cons = cc
+	if o.updatesOnly {
+		w.initDone = true
+	} else if info := cons.CachedInfo(); info != nil && info.NumPending == 0 {
+		w.initDone = true
+		w.updates <- nil
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			w.Stop()
+		case <-w.done:
+		}
+	}()
+	return w, nil
+}
+
+// send blocks until the info is placed on the updates channel
+// or the watcher is stopped. It has to be called with the lock held.
+func (w *objWatcher) send(info *ObjectInfo) bool {
+	select {
+	case w.updates <- info:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+// Updates returns the interior channel.
+func (w *objWatcher) Updates() <-chan *ObjectInfo {
+	if w == nil {
+		return nil
+	}
+	return w.updates
+}
+
+// Stop will stop the watcher and close its updates channel.
+func (w *objWatcher) Stop() error {
+	if w == nil {
+		return nil
+	}
+	w.stopOnce.Do(func() {
+		// Unblock a pending update before acquiring the lock.
+		close(w.done)
+		w.cons.Stop()
+		w.Lock()
+		w.stopped = true
+		close(w.updates)
+		w.Unlock()
+	})
+	return nil
+}
+
+// Bucket returns the current bucket name.
+func (obs *obs) Bucket() string {
+	return obs.name
+}
+
+// Status retrieves run-time status about a bucket.
+func (obs *obs) Status(ctx context.Context) (ObjectStoreStatus, error) {
+	nfo, err := obs.stream.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &ObjectBucketStatus{nfo: nfo, bucket: obs.name}, nil
+}
+
+// Bucket is the name of the bucket
+func (s *ObjectBucketStatus) Bucket() string { return s.bucket }
+
+// Description is the description supplied when creating the bucket
+func (s *ObjectBucketStatus) Description() string { return s.nfo.Config.Description }
+
+// TTL indicates how long objects are kept in the bucket
+func (s *ObjectBucketStatus) TTL() time.Duration { return s.nfo.Config.MaxAge }
+
+// Storage indicates the underlying JetStream storage technology used to store data
+func (s *ObjectBucketStatus) Storage() StorageType { return s.nfo.Config.Storage }
+
+// Replicas indicates how many storage replicas are kept for the data in the bucket
+func (s *ObjectBucketStatus) Replicas() int { return s.nfo.Config.Replicas }
+
+// Sealed indicates the stream is sealed and cannot be modified in any way
+func (s *ObjectBucketStatus) Sealed() bool { return s.nfo.Config.Sealed }
+
+// Size is the combined size of all data in the bucket including metadata, in bytes
+func (s *ObjectBucketStatus) Size() uint64 { return s.nfo.State.Bytes }
+
+// BackingStore indicates what technology is used for storage of the bucket
+func (s *ObjectBucketStatus) BackingStore() string { return "JetStream" }
+
+// StreamInfo is the stream info retrieved to create the status
+func (s *ObjectBucketStatus) StreamInfo() *StreamInfo { return s.nfo }
@@ -56,6 +56,48 @@ func WithPublishAsyncMaxPending(max int) JetStreamOpt {
 	}
 }
 
+// WithPublishAsyncRetry sets the default number of attempts and the wait between them
+// to retry async publishes getting no responders, e.g. while the stream leader is being
+// elected, before failing with [ErrNoStreamResponse]. A negative number of attempts retries
+// indefinitely. It can be overridden per message using [WithRetryAttempts] and [WithRetryWait].
+// By default, async publishes are not retried.
+func WithPublishAsyncRetry(attempts int, wait time.Duration) JetStreamOpt {
+	return func(opts *jsOpts) error {
+		if wait < 0 {
+			return fmt.Errorf("%w: retry wait cannot be negative", ErrInvalidOption)
+		}
+		opts.publisherOpts.retryAttempts = attempts
+		opts.publisherOpts.retryWait = wait
+		return nil
+	}
+}
+
+// WithTimeouts sets the timeouts of JetStream API requests made with a context
+// without a deadline, e.g. [DefaultTimeouts]. Requests made with a context
+// that has a deadline are not affected. By default, no timeouts are applied.
+func WithTimeouts(timeouts Timeouts) JetStreamOpt {
+	return func(opts *jsOpts) error {
+		if timeouts.Get < 0 || timeouts.Create < 0 || timeouts.List < 0 || timeouts.Snapshot < 0 {
+			return fmt.Errorf("%w: timeouts cannot be negative", ErrInvalidOption)
+		}
+		opts.timeouts = timeouts
+		return nil
+	}
+}
+
+// WithUnavailableRetry retries API requests failing because JetStream, a stream
+// or a consumer is temporarily unavailable, e.g. during a leader election.
+// By default such requests are not retried.
+func WithUnavailableRetry(policy RetryPolicy) JetStreamOpt {
+	return func(opts *jsOpts) error {
+		if err := policy.validate(); err != nil {
+			return err
+		}
+		opts.retry = policy
+		return nil
+	}
+}
+
 // WithPurgeSubject sets a sprecific subject for which messages on a stream will be purged
 func WithPurgeSubject(subject string) StreamPurgeOpt {
 	return func(req *StreamPurgeRequest) error {
@@ -155,24 +197,50 @@ func (max PullMaxBytes) configureMessages(opts *consumeOpts) error {
 	return nil
 }
 
-// PullThresholdMessages sets the message count on which Consume will trigger
-// new pull request to the server. Defaults to 50% of MaxMessages.
+// PullThresholdMessages sets the message count on which Consume and Messages
+// will trigger new pull request to the server. The next pull request is sent
+// once fewer messages than the threshold are still expected from the
+// outstanding requests. Higher values keep more messages in flight for high
+// throughput, lower values keep fewer messages buffered. It cannot exceed
+// MaxMessages and defaults to 50% of MaxMessages.
 type PullThresholdMessages int
 
 func (t PullThresholdMessages) configureConsume(opts *consumeOpts) error {
+	if t <= 0 {
+		return fmt.Errorf("%w: threshold messages must be at least 1", ErrInvalidOption)
+	}
 	opts.ThresholdMessages = int(t)
 	return nil
 }
 
-// PullThresholdBytes sets the byte count on which Consume will trigger
-// new pull request to the server. Defaults to 50% of MaxBytes (if set).
-type PullThresholBytes int
+func (t PullThresholdMessages) configureMessages(opts *consumeOpts) error {
+	return t.configureConsume(opts)
+}
+
+// PullThresholdBytes sets the byte count on which Consume and Messages will
+// trigger new pull request to the server, when MaxBytes is set. The next pull
+// request is sent once fewer bytes than the threshold are still expected from
+// the outstanding requests. It cannot exceed MaxBytes and defaults to 50% of
+// MaxBytes.
+type PullThresholdBytes int
 
-func (t PullThresholBytes) configureConsume(opts *consumeOpts) error {
+// PullThresholBytes is the former name of [PullThresholdBytes].
+//
+// Deprecated: use [PullThresholdBytes] instead.
+type PullThresholBytes = PullThresholdBytes
+
+func (t PullThresholdBytes) configureConsume(opts *consumeOpts) error {
+	if t <= 0 {
+		return fmt.Errorf("%w: threshold bytes must be at least 1", ErrInvalidOption)
+	}
 	opts.ThresholdBytes = int(t)
 	return nil
 }
 
+func (t PullThresholdBytes) configureMessages(opts *consumeOpts) error {
+	return t.configureConsume(opts)
+}
+
 // PullHeartbeat sets the idle heartbeat duration for a pull subscription
 // If a client does not receive a heartbeat message from a stream for more
 // than the idle heartbeat setting, the subscription will be removed
@@ -206,6 +274,60 @@ func ConsumeErrHandler(cb ConsumeErrHandlerFunc) PullConsumeOpt {
 	})
 }
 
+// ConsumeStallTimeout enables detection of pull requests lost e.g. during reconnect.
+// If neither messages nor heartbeats are received within the timeout while
+// [ConsumerInfo] reports pending messages, the pull request is reissued and
+// [ErrConsumerStalled] is passed to the error handler.
+func ConsumeStallTimeout(timeout time.Duration) PullConsumeOpt {
+	return pullOptFunc(func(cfg *consumeOpts) error {
+		if timeout < time.Second {
+			return fmt.Errorf("%w: stall timeout must be at least 1s", ErrInvalidOption)
+		}
+		cfg.StallTimeout = timeout
+		return nil
+	})
+}
+
+// ConsumeOnHeartbeatMissed sets a callback invoked when no heartbeat was
+// received within twice the heartbeat interval. The pull request is reissued
+// afterwards.
+func ConsumeOnHeartbeatMissed(cb ConsumeEventFunc) PullConsumeOpt {
+	return pullOptFunc(func(cfg *consumeOpts) error {
+		cfg.OnHeartbeatMissed = cb
+		return nil
+	})
+}
+
+// ConsumeOnConsumerDeleted sets a callback invoked when the consumer was
+// deleted, either while consuming or while disconnected. Consume is stopped
+// afterwards, except for ordered consumers, which are recreated.
+func ConsumeOnConsumerDeleted(cb ConsumeEventFunc) PullConsumeOpt {
+	return pullOptFunc(func(cfg *consumeOpts) error {
+		cfg.OnConsumerDeleted = cb
+		return nil
+	})
+}
+
+// ConsumeOnServerShutdown sets a callback invoked when the server the pull
+// requests were sent to is shutting down. Consuming continues once the
+// client reconnected.
+func ConsumeOnServerShutdown(cb ConsumeEventFunc) PullConsumeOpt {
+	return pullOptFunc(func(cfg *consumeOpts) error {
+		cfg.OnServerShutdown = cb
+		return nil
+	})
+}
+
+// ConsumeOnOrderedReset sets a callback invoked once an ordered consumer
+// was recreated and consuming resumed, e.g. after missed heartbeats or a
+// sequence mismatch. It is ignored by other consumers.
+func ConsumeOnOrderedReset(cb OrderedResetFunc) PullConsumeOpt {
+	return pullOptFunc(func(cfg *consumeOpts) error {
+		cfg.OnOrderedReset = cb
+		return nil
+	})
+}
+
 // ConsumeErrHandler sets custom error handler invoked when an error was encountered while consuming messages
 // It will be invoked for both terminal (Consumer Deleted, invalid request body) and non-terminal (e.g. missing heartbeats) errors
 func WithMessagesErrOnMissingHeartbeat(hbErr bool) PullMessagesOpt {
@@ -226,6 +348,22 @@ func FetchMaxWait(timeout time.Duration) FetchOpt {
 	}
 }
 
+// FetchHeartbeat sets the idle heartbeat of a fetch request. Heartbeats
+// are filtered out internally, but if no heartbeat is received for twice
+// the idle heartbeat, fetching stops and [ErrNoHeartbeat] is returned by
+// [MessageBatch.Error], instead of waiting for the whole max wait. It must
+// not be greater than half of the max wait. By default, a heartbeat of 5s
+// is used if the max wait is at least 10s.
+func FetchHeartbeat(hb time.Duration) FetchOpt {
+	return func(req *pullRequest) error {
+		if hb <= 0 {
+			return fmt.Errorf("%w: idle heartbeat value must be greater than 0", ErrInvalidOption)
+		}
+		req.Heartbeat = hb
+		return nil
+	}
+}
+
 // WithDeletedDetails can be used to display the information about messages deleted from a stream on a stream info request
 func WithDeletedDetails(deletedDetails bool) StreamInfoOpt {
 	return func(req *streamInfoRequest) error {
@@ -291,6 +429,7 @@ func WithExpectLastMsgID(id string) PublishOpt {
 }
 
 // WithRetryWait sets the retry wait time when ErrNoResponders is encountered.
+// For async publishes, it overrides the default set with [WithPublishAsyncRetry].
 func WithRetryWait(dur time.Duration) PublishOpt {
 	return func(opts *pubOpts) error {
 		opts.retryWait = dur
@@ -299,6 +438,7 @@ func WithRetryWait(dur time.Duration) PublishOpt {
 }
 
 // WithRetryAttempts sets the retry number of attempts when ErrNoResponders is encountered.
+// For async publishes, it overrides the default set with [WithPublishAsyncRetry].
 func WithRetryAttempts(num int) PublishOpt {
 	return func(opts *pubOpts) error {
 		opts.retryAttempts = num
@@ -316,3 +456,87 @@ func WithStallWait(ttl time.Duration) PublishOpt {
 		return nil
 	}
 }
+
+// WithMsgTTL sets the TTL of the message, after which it is removed from
+// the stream, even if the stream MaxAge is longer. The stream has to allow
+// message TTLs using [StreamConfig.AllowMsgTTL]. The server rejects TTLs
+// shorter than a second.
+func WithMsgTTL(ttl time.Duration) PublishOpt {
+	return func(opts *pubOpts) error {
+		if ttl < time.Second {
+			return fmt.Errorf("%w: message TTL should be at least 1s", ErrInvalidOption)
+		}
+		opts.ttl = ttl
+		return nil
+	}
+}
+
+// WithBrowseSubject only returns messages with subjects matching the given
+// subject, which can contain wildcards.
+func WithBrowseSubject(subject string) BrowseOpt {
+	return func(opts *browseOpts) error {
+		if subject == "" {
+			return fmt.Errorf("%w: subject cannot be empty", ErrInvalidOption)
+		}
+		opts.subject = subject
+		return nil
+	}
+}
+
+// WithBrowseStartSeq starts browsing at the given stream sequence.
+// Cannot be combined with [WithBrowseStartTime].
+func WithBrowseStartSeq(seq uint64) BrowseOpt {
+	return func(opts *browseOpts) error {
+		opts.startSeq = seq
+		return nil
+	}
+}
+
+// WithBrowseStartTime starts browsing at the first message stored at or
+// after the given time. When browsing backward, browsing starts at the
+// last message stored before it. Cannot be combined with [WithBrowseStartSeq].
+func WithBrowseStartTime(t time.Time) BrowseOpt {
+	return func(opts *browseOpts) error {
+		opts.startTime = &t
+		return nil
+	}
+}
+
+// WithBrowseBackward browses from newer to older messages, starting at the
+// last message in the stream unless a start is provided.
+func WithBrowseBackward() BrowseOpt {
+	return func(opts *browseOpts) error {
+		opts.backward = true
+		return nil
+	}
+}
+
+// WithBrowseLimit sets the maximum number of messages returned on a page.
+// Defaults to [DefaultBrowseLimit].
+func WithBrowseLimit(limit int) BrowseOpt {
+	return func(opts *browseOpts) error {
+		if limit < 1 {
+			return fmt.Errorf("%w: limit has to be greater than 0", ErrInvalidOption)
+		}
+		opts.limit = limit
+		return nil
+	}
+}
+
+// WithBrowseHeadersOnly returns messages without payloads. The size of the
+// payload is set in the [MsgSizeHeader] header.
+func WithBrowseHeadersOnly() BrowseOpt {
+	return func(opts *browseOpts) error {
+		opts.headersOnly = true
+		return nil
+	}
+}
+
+// WithBrowseCursor continues browsing after the last message of a page, in
+// the same direction and with the same subject filter. It overrides start
+// options and [WithBrowseBackward] and [WithBrowseSubject] set before it.
+func WithBrowseCursor(cursor string) BrowseOpt {
+	return func(opts *browseOpts) error {
+		return decodeBrowseCursor(cursor, opts)
+	}
+}
@@ -35,7 +35,7 @@ type (
 		namePrefix      string
 		serial          int
 		consumerType    consumerType
-		doReset         chan struct{}
+		doReset         chan error
 		resetInProgress uint32
 		userErrHandler  ConsumeErrHandlerFunc
 		runningFetch    *fetchResult
@@ -118,7 +118,7 @@ func (c *orderedConsumer) Consume(handler MessageHandler, opts ...PullConsumeOpt
 	go func() {
 		for {
 			select {
-			case <-c.doReset:
+			case reason := <-c.doReset:
 				if err := c.reset(); err != nil {
 					c.errHandler(c.serial)(c.currentConsumer.subscriptions[""], err)
 				}
@@ -126,6 +126,8 @@ func (c *orderedConsumer) Consume(handler MessageHandler, opts ...PullConsumeOpt
 				opts[len(opts)-1] = ConsumeErrHandler(c.errHandler(c.serial))
 				if _, err := c.currentConsumer.Consume(internalHandler(c.serial), opts...); err != nil {
 					c.errHandler(c.serial)(c.currentConsumer.subscriptions[""], err)
+				} else if consumeOpts.OnOrderedReset != nil {
+					consumeOpts.OnOrderedReset(sub, reason)
 				}
 			case <-sub.done:
 				return
@@ -146,7 +148,7 @@ func (c *orderedConsumer) errHandler(serial int) func(cc ConsumeContext, err err
 			// only reset if serial matches the currect consumer serial and there is no reset in progress
 			if serial == c.serial && atomic.LoadUint32(&c.resetInProgress) == 0 {
 				atomic.StoreUint32(&c.resetInProgress, 1)
-				c.doReset <- struct{}{}
+				c.doReset <- err
 			}
 
 		}
@@ -188,11 +190,25 @@ func (c *orderedConsumer) Messages(opts ...PullMessagesOpt) (MessagesContext, er
 }
 
 func (s *orderedSubscription) Next() (Msg, error) {
+	return s.next(context.Background())
+}
+
+func (s *orderedSubscription) NextWithContext(ctx context.Context) (Msg, error) {
+	if ctx == nil {
+		return nil, nats.ErrInvalidContext
+	}
+	return s.next(ctx)
+}
+
+func (s *orderedSubscription) next(ctx context.Context) (Msg, error) {
 	next := func() (Msg, error) {
 		for {
 			currentConsumer := s.consumer.currentConsumer
-			msg, err := currentConsumer.subscriptions[""].Next()
+			msg, err := currentConsumer.subscriptions[""].next(ctx)
 			if err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
 				if err := s.consumer.reset(); err != nil {
 					return nil, err
 				}
@@ -388,6 +404,7 @@ func (c *orderedConsumer) getConsumerConfigForSeq(seq uint64) *ConsumerConfig {
 		AckPolicy:         AckNonePolicy,
 		InactiveThreshold: 5 * time.Minute,
 		Replicas:          1,
+		HeadersOnly:       c.cfg.HeadersOnly,
 	}
 	if len(c.cfg.FilterSubjects) == 1 {
 		cfg.FilterSubject = c.cfg.FilterSubjects[0]
@@ -455,3 +472,17 @@ func (c *orderedConsumer) CachedInfo() *ConsumerInfo {
 	}
 	return c.currentConsumer.info
 }
+
+// LeaderStepDown makes the leader of the current underlying consumer step
+// down. Ordered consumers have a single replica, so it is only useful to
+// move the consumer to another server.
+func (c *orderedConsumer) LeaderStepDown(ctx context.Context) error {
+	c.Lock()
+	if c.currentConsumer == nil {
+		c.Unlock()
+		return ErrOrderedConsumerNotCreated
+	}
+	subj := apiSubj(c.jetStream.apiPrefix, fmt.Sprintf(apiConsumerLeaderStepDownT, c.stream, c.currentConsumer.name))
+	c.Unlock()
+	return c.jetStream.leaderStepDown(ctx, subj)
+}
@@ -26,6 +26,7 @@ import (
 	"time"
 
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/retry"
 	"github.com/nats-io/nuid"
 )
 
@@ -35,16 +36,20 @@ type (
 		aecb MsgErrHandler
 		// Max async pub ack in flight
 		maxpa int
+		// Default retries of async publishes getting no responders.
+		retryAttempts int
+		retryWait     time.Duration
 	}
 
 	PublishOpt func(*pubOpts) error
 
 	pubOpts struct {
 		id             string
-		lastMsgID      string  // Expected last msgId
-		stream         string  // Expected stream name
-		lastSeq        *uint64 // Expected last sequence
-		lastSubjectSeq *uint64 // Expected last sequence per subject
+		lastMsgID      string        // Expected last msgId
+		stream         string        // Expected stream name
+		lastSeq        *uint64       // Expected last sequence
+		lastSubjectSeq *uint64       // Expected last sequence per subject
+		ttl            time.Duration // Message TTL
 
 		// Publish retries for NoResponders err.
 		retryWait     time.Duration // Retry wait between attempts
@@ -73,6 +78,13 @@ type (
 		err      error
 		errCh    chan error
 		doneCh   chan *PubAck
+		cb       PubAckHandler
+
+		// Retries of the publish on no responders.
+		reply         string
+		retries       int
+		retryAttempts int
+		retryWait     time.Duration
 	}
 
 	jetStreamClient struct {
@@ -80,6 +92,10 @@ type (
 		asyncPublisherOpts
 	}
 
+	// PubAckHandler is used to process the ack or error of a message
+	// published with [Publisher.PublishAsyncWithCallback].
+	PubAckHandler func(*PubAck, error)
+
 	// MsgErrHandler is used to process asynchronous errors from
 	// JetStream PublishAsynjs. It will return the original
 	// message sent to the server for possible retransmitting and the error encountered.
@@ -93,6 +109,9 @@ type (
 		stallCh      chan struct{}
 		doneCh       chan struct{}
 		rr           *rand.Rand
+
+		// Removes the shutdown hook registered along with replySubject.
+		removeHook func()
 	}
 
 	pubAckResponse struct {
@@ -100,6 +119,14 @@ type (
 		*PubAck
 	}
 
+	// PublishBatchResult is the result of publishing a message with [Publisher.PublishBatch].
+	PublishBatchResult struct {
+		// Ack is the ack of the message, nil if publishing it failed.
+		Ack *PubAck
+		// Err is the error publishing the message.
+		Err error
+	}
+
 	// PubAck is an ack received after successfully publishing a message.
 	PubAck struct {
 		Stream    string `json:"stream"`
@@ -164,6 +191,9 @@ func (js *jetStream) PublishMsg(ctx context.Context, m *nats.Msg, opts ...Publis
 	if o.lastSubjectSeq != nil {
 		m.Header.Set(ExpectedLastSubjSeqHeader, strconv.FormatUint(*o.lastSubjectSeq, 10))
 	}
+	if o.ttl > 0 {
+		m.Header.Set(MsgTTLHeader, o.ttl.String())
+	}
 
 	var resp *nats.Msg
 	var err error
@@ -171,11 +201,14 @@ func (js *jetStream) PublishMsg(ctx context.Context, m *nats.Msg, opts ...Publis
 	resp, err = js.conn.RequestMsgWithContext(ctx, m)
 
 	if err != nil {
-		for r := 0; errors.Is(err, nats.ErrNoResponders) && (r < o.retryAttempts || o.retryAttempts < 0); r++ {
+		backoff := retry.NewBackoff(retry.Policy{Attempts: o.retryAttempts, Wait: o.retryWait})
+		for errors.Is(err, nats.ErrNoResponders) {
 			// To protect against small blips in leadership changes etc, if we get a no responders here retry.
-			select {
-			case <-ctx.Done():
-			case <-time.After(o.retryWait):
+			if werr := backoff.Wait(ctx); werr != nil {
+				if !errors.Is(werr, retry.ErrExhausted) {
+					err = werr
+				}
+				break
 			}
 			resp, err = js.conn.RequestMsgWithContext(ctx, m)
 		}
@@ -192,6 +225,9 @@ func (js *jetStream) PublishMsg(ctx context.Context, m *nats.Msg, opts ...Publis
 		return nil, ErrInvalidJSAck
 	}
 	if ackResp.Error != nil {
+		if err := streamLimitsError(ackResp.Error); err != nil {
+			return nil, err
+		}
 		return nil, fmt.Errorf("nats: %w", ackResp.Error)
 	}
 	if ackResp.PubAck == nil || ackResp.PubAck.Stream == "" {
@@ -205,7 +241,30 @@ func (js *jetStream) PublishAsync(ctx context.Context, subj string, data []byte,
 }
 
 func (js *jetStream) PublishMsgAsync(ctx context.Context, m *nats.Msg, opts ...PublishOpt) (PubAckFuture, error) {
-	var o pubOpts
+	paf, err := js.publishMsgAsync(m, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	return paf, nil
+}
+
+// PublishAsyncWithCallback publishes the message asynchronously like [PublishMsgAsync],
+// delivering the ack or error to cb instead of a [PubAckFuture]. cb is invoked on the
+// goroutine dispatching acks, so it should not block. Errors are not reported to the
+// async error handler set with [WithPublishAsyncErrHandler].
+func (js *jetStream) PublishAsyncWithCallback(ctx context.Context, m *nats.Msg, cb PubAckHandler, opts ...PublishOpt) error {
+	if cb == nil {
+		return fmt.Errorf("%w: callback is required", ErrInvalidOption)
+	}
+	_, err := js.publishMsgAsync(m, cb, opts)
+	return err
+}
+
+func (js *jetStream) publishMsgAsync(m *nats.Msg, cb PubAckHandler, opts []PublishOpt) (*pubAckFuture, error) {
+	o := pubOpts{
+		retryWait:     js.publisher.retryWait,
+		retryAttempts: js.publisher.retryAttempts,
+	}
 	if len(opts) > 0 {
 		if m.Header == nil {
 			m.Header = nats.Header{}
@@ -238,11 +297,19 @@ func (js *jetStream) PublishMsgAsync(ctx context.Context, m *nats.Msg, opts ...P
 	if o.lastSubjectSeq != nil {
 		m.Header.Set(ExpectedLastSubjSeqHeader, strconv.FormatUint(*o.lastSubjectSeq, 10))
 	}
+	if o.ttl > 0 {
+		m.Header.Set(MsgTTLHeader, o.ttl.String())
+	}
 
 	// Reply
 	if m.Reply != "" {
 		return nil, ErrAsyncPublishReplySubjectSet
 	}
+	// Reject messages over the max payload before registering the ack
+	// future, as waiting for a stall would only delay the error.
+	if err := js.conn.CheckMaxPayload(m); err != nil {
+		return nil, err
+	}
 	var err error
 	reply := m.Reply
 	m.Reply, err = js.newAsyncReply()
@@ -252,7 +319,14 @@ func (js *jetStream) PublishMsgAsync(ctx context.Context, m *nats.Msg, opts ...P
 	defer func() { m.Reply = reply }()
 
 	id := m.Reply[aReplyPreLen:]
-	paf := &pubAckFuture{msg: m, jsClient: js.publisher}
+	paf := &pubAckFuture{
+		msg:           m,
+		jsClient:      js.publisher,
+		cb:            cb,
+		reply:         m.Reply,
+		retryAttempts: o.retryAttempts,
+		retryWait:     o.retryWait,
+	}
 	numPending, maxPending := js.registerPAF(id, paf)
 
 	if maxPending > 0 && numPending > maxPending {
@@ -279,6 +353,10 @@ const (
 
 func (js *jetStream) newAsyncReply() (string, error) {
 	js.publisher.Lock()
+	if js.publisher.replySubject != nil && !js.publisher.replySubject.IsValid() {
+		// The reply subscription is gone, e.g. it was closed by the server.
+		js.cleanupReplySub()
+	}
 	if js.publisher.replySubject == nil {
 		// Create our wildcard reply subject.
 		sha := sha256.New()
@@ -295,6 +373,7 @@ func (js *jetStream) newAsyncReply() (string, error) {
 		}
 		js.publisher.replySubject = sub
 		js.publisher.rr = rand.New(rand.NewSource(time.Now().UnixNano()))
+		js.publisher.removeHook = js.conn.AddShutdownHook(js.waitAsyncComplete)
 	}
 	var sb strings.Builder
 	sb.WriteString(js.publisher.replyPrefix)
@@ -316,6 +395,65 @@ func (js *jetStream) handleAsyncReply(m *nats.Msg) {
 	}
 	id := m.Subject[aReplyPreLen:]
 
+	js.publisher.Lock()
+	paf := js.getPAF(id)
+	if paf == nil {
+		js.publisher.Unlock()
+		return
+	}
+	// To protect against small blips in leadership changes etc, if we get a no responders here retry.
+	if len(m.Data) == 0 && m.Header.Get(statusHdr) == noResponders &&
+		(paf.retries < paf.retryAttempts || paf.retryAttempts < 0) {
+		paf.retries++
+		js.publisher.Unlock()
+		time.AfterFunc(paf.retryWait, func() { js.resendPAF(id, paf) })
+		return
+	}
+	js.publisher.Unlock()
+
+	ack, err := parseAsyncReply(m)
+	js.resolvePAF(id, ack, err)
+}
+
+// parseAsyncReply returns the ack or error of a reply to an async publish.
+func parseAsyncReply(m *nats.Msg) (*PubAck, error) {
+	// Process no responders etjs.
+	if len(m.Data) == 0 && m.Header.Get(statusHdr) == noResponders {
+		return nil, ErrNoStreamResponse
+	}
+
+	var pa pubAckResponse
+	if err := json.Unmarshal(m.Data, &pa); err != nil {
+		return nil, ErrInvalidJSAck
+	}
+	if pa.Error != nil {
+		if err := streamLimitsError(pa.Error); err != nil {
+			return nil, err
+		}
+		return nil, pa.Error
+	}
+	if pa.PubAck == nil || pa.PubAck.Stream == "" {
+		return nil, ErrInvalidJSAck
+	}
+	return pa.PubAck, nil
+}
+
+// resendPAF publishes the message of a PubAckFuture again, unless it was cleared meanwhile.
+func (js *jetStream) resendPAF(id string, paf *pubAckFuture) {
+	js.publisher.RLock()
+	pending := js.getPAF(id) == paf
+	js.publisher.RUnlock()
+	if !pending {
+		return
+	}
+	m := &nats.Msg{Subject: paf.msg.Subject, Reply: paf.reply, Header: paf.msg.Header, Data: paf.msg.Data}
+	if err := js.conn.PublishMsg(m); err != nil {
+		js.resolvePAF(id, nil, err)
+	}
+}
+
+// resolvePAF removes a PubAckFuture and delivers its ack or error.
+func (js *jetStream) resolvePAF(id string, ack *PubAck, err error) {
 	js.publisher.Lock()
 	paf := js.getPAF(id)
 	if paf == nil {
@@ -338,44 +476,30 @@ func (js *jetStream) handleAsyncReply(m *nats.Msg) {
 		defer close(dch)
 	}
 
-	doErr := func(err error) {
+	if err != nil {
 		paf.err = err
 		if paf.errCh != nil {
 			paf.errCh <- paf.err
 		}
 		cb := js.publisher.asyncPublisherOpts.aecb
 		js.publisher.Unlock()
-		if cb != nil {
+		if paf.cb != nil {
+			paf.cb(nil, err)
+		} else if cb != nil {
 			cb(js, paf.msg, err)
 		}
-	}
-
-	// Process no responders etjs.
-	if len(m.Data) == 0 && m.Header.Get(statusHdr) == noResponders {
-		doErr(nats.ErrNoResponders)
-		return
-	}
-
-	var pa pubAckResponse
-	if err := json.Unmarshal(m.Data, &pa); err != nil {
-		doErr(ErrInvalidJSAck)
-		return
-	}
-	if pa.Error != nil {
-		doErr(pa.Error)
-		return
-	}
-	if pa.PubAck == nil || pa.PubAck.Stream == "" {
-		doErr(ErrInvalidJSAck)
 		return
 	}
 
 	// So here we have received a proper puback.
-	paf.ack = pa.PubAck
+	paf.ack = ack
 	if paf.doneCh != nil {
 		paf.doneCh <- paf.ack
 	}
 	js.publisher.Unlock()
+	if paf.cb != nil {
+		paf.cb(ack, nil)
+	}
 }
 
 // registerPAF will register for a PubAckFuture.
@@ -450,6 +574,53 @@ func (paf *pubAckFuture) Msg() *nats.Msg {
 	return paf.msg
 }
 
+// PublishBatch publishes the messages without waiting for each ack, using [PublishMsgAsync],
+// and waits for all acks. It returns a result for each message, in the order of msgs.
+// If publishing any message failed, [ErrPublishBatchFailed] is returned along with the
+// results. If ctx is done before all acks are received, the results of the remaining
+// messages hold the context error. Failures are reported to the async error handler
+// as well, see [WithPublishAsyncErrHandler].
+func (js *jetStream) PublishBatch(ctx context.Context, msgs []*nats.Msg) ([]PublishBatchResult, error) {
+	results := make([]PublishBatchResult, len(msgs))
+	futures := make([]PubAckFuture, len(msgs))
+	for i, m := range msgs {
+		if m == nil {
+			results[i].Err = nats.ErrInvalidMsg
+			continue
+		}
+		futures[i], results[i].Err = js.PublishMsgAsync(ctx, m)
+	}
+
+	var failed int
+	for i, paf := range futures {
+		if paf != nil {
+			select {
+			case ack := <-paf.Ok():
+				results[i].Ack = ack
+			case err := <-paf.Err():
+				results[i].Err = err
+			case <-ctx.Done():
+				// prefer the outcome if already received
+				select {
+				case ack := <-paf.Ok():
+					results[i].Ack = ack
+				case err := <-paf.Err():
+					results[i].Err = err
+				default:
+					results[i].Err = ctx.Err()
+				}
+			}
+		}
+		if results[i].Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return results, fmt.Errorf("%w: %d of %d messages failed", ErrPublishBatchFailed, failed, len(msgs))
+	}
+	return results, nil
+}
+
 // PublishAsyncPending returns how many PubAckFutures are pending.
 func (js *jetStream) PublishAsyncPending() int {
 	js.publisher.RLock()
@@ -457,6 +628,31 @@ func (js *jetStream) PublishAsyncPending() int {
 	return len(js.publisher.acks)
 }
 
+// cleanupReplySub removes the async reply subscription and the shutdown
+// hook registered with it, so that the connection does not keep referencing
+// this context. A new subscription is created on the next async publish.
+// Lock should be held.
+func (js *jetStream) cleanupReplySub() {
+	if js.publisher.replySubject != nil {
+		js.publisher.replySubject.Unsubscribe()
+		js.publisher.replySubject = nil
+	}
+	if js.publisher.removeHook != nil {
+		js.publisher.removeHook()
+		js.publisher.removeHook = nil
+	}
+}
+
+// waitAsyncComplete waits for outstanding async publishes on connection shutdown.
+func (js *jetStream) waitAsyncComplete(ctx context.Context) error {
+	select {
+	case <-js.PublishAsyncComplete():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // PublishAsyncComplete returns a channel that will be closed when all outstanding messages have been ack'd.
 func (js *jetStream) PublishAsyncComplete() <-chan struct{} {
 	js.publisher.Lock()
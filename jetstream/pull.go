@@ -32,6 +32,9 @@ type (
 	MessagesContext interface {
 		// Next retreives nest message on a stream. It will block until the next message is available.
 		Next() (Msg, error)
+		// NextWithContext is like Next, but returns the context error
+		// if the context is done before a message is available.
+		NextWithContext(ctx context.Context) (Msg, error)
 		// Stop closes the iterator and cancels subscription.
 		Stop()
 	}
@@ -80,11 +83,25 @@ type (
 		ReportMissingHeartbeats bool
 		ThresholdMessages       int
 		ThresholdBytes          int
+		StallTimeout            time.Duration
+		OnHeartbeatMissed       ConsumeEventFunc
+		OnConsumerDeleted       ConsumeEventFunc
+		OnServerShutdown        ConsumeEventFunc
+		OnOrderedReset          OrderedResetFunc
 	}
 
 	ConsumeErrHandlerFunc func(consumeCtx ConsumeContext, err error)
 
+	// ConsumeEventFunc is invoked on consumer lifecycle events while consuming messages.
+	ConsumeEventFunc func(consumeCtx ConsumeContext)
+
+	// OrderedResetFunc is invoked after an ordered consumer was recreated,
+	// with the error which caused the reset.
+	OrderedResetFunc func(consumeCtx ConsumeContext, reason error)
+
 	pullSubscription struct {
+		// lastActivity is accessed atomically and kept first for alignment
+		lastActivity int64
 		sync.Mutex
 		id                string
 		consumer          *pullConsumer
@@ -141,8 +158,9 @@ const (
 // [ConsumeExpiry] - sets a timeout for individual batch request, default is set to 30 seconds
 // [ConsumeHeartbeat] - sets an idle heartbeat setting for a pull request, default is set to 5s
 // [ConsumeErrHandler] - sets custom consume error callback handler
-// [ConsumeThresholdMessages] - sets the byte count on which Consume will trigger new pull request to the server
-// [ConsumeThresholdBytes] - sets the message count on which Consume will trigger new pull request to the server
+// [PullThresholdMessages] - sets the message count on which Consume will trigger new pull request to the server
+// [PullThresholdBytes] - sets the byte count on which Consume will trigger new pull request to the server
+// [ConsumeStallTimeout] - reissues the pull request if no messages or heartbeats are received while messages are pending
 func (p *pullConsumer) Consume(handler MessageHandler, opts ...PullConsumeOpt) (ConsumeContext, error) {
 	if handler == nil {
 		return nil, ErrHandlerRequired
@@ -177,6 +195,7 @@ func (p *pullConsumer) Consume(handler MessageHandler, opts ...PullConsumeOpt) (
 	p.Unlock()
 
 	internalHandler := func(msg *nats.Msg) {
+		atomic.StoreInt64(&sub.lastActivity, time.Now().UnixNano())
 		if sub.hbMonitor != nil {
 			sub.hbMonitor.Reset(2 * consumeOpts.Heartbeat)
 		}
@@ -269,6 +288,9 @@ func (p *pullConsumer) Consume(handler MessageHandler, opts ...PullConsumeOpt) (
 									if sub.consumeOpts.ErrHandler != nil {
 										sub.consumeOpts.ErrHandler(sub, err)
 									}
+									if errors.Is(err, ErrConsumerNotFound) && sub.consumeOpts.OnConsumerDeleted != nil {
+										sub.consumeOpts.OnConsumerDeleted(sub)
+									}
 									sub.Unlock()
 									return
 								}
@@ -290,7 +312,15 @@ func (p *pullConsumer) Consume(handler MessageHandler, opts ...PullConsumeOpt) (
 				if sub.consumeOpts.ErrHandler != nil {
 					sub.consumeOpts.ErrHandler(sub, err)
 				}
-				if errors.Is(err, ErrNoHeartbeat) {
+				if errors.Is(err, ErrNoHeartbeat) && sub.consumeOpts.OnHeartbeatMissed != nil {
+					sub.consumeOpts.OnHeartbeatMissed(sub)
+				}
+				if errors.Is(err, ErrNoHeartbeat) || errors.Is(err, ErrConsumerStalled) {
+					// heartbeat monitor is stopped on disconnect and only
+					// rearmed by incoming messages, restart it for the new request
+					if errors.Is(err, ErrConsumerStalled) && sub.hbMonitor != nil {
+						sub.hbMonitor.Reset(2 * sub.consumeOpts.Heartbeat)
+					}
 					sub.fetchNext <- &pullRequest{
 						Expires:   sub.consumeOpts.Expires,
 						Batch:     sub.consumeOpts.MaxMessages,
@@ -304,10 +334,51 @@ func (p *pullConsumer) Consume(handler MessageHandler, opts ...PullConsumeOpt) (
 	}()
 
 	go sub.pullMessages(subject)
+	if consumeOpts.StallTimeout > 0 {
+		atomic.StoreInt64(&sub.lastActivity, time.Now().UnixNano())
+		go sub.monitorStalls()
+	}
 
 	return sub, nil
 }
 
+// monitorStalls detects pull requests lost e.g. during reconnect. If neither
+// messages nor heartbeats were received within the stall timeout while the
+// consumer has pending messages, ErrConsumerStalled is reported, which
+// reissues the pull request.
+func (s *pullSubscription) monitorStalls() {
+	timeout := s.consumeOpts.StallTimeout
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+		case <-s.done:
+			return
+		}
+		idle := time.Since(time.Unix(0, atomic.LoadInt64(&s.lastActivity)))
+		if idle < timeout {
+			timer.Reset(timeout - idle)
+			continue
+		}
+		timer.Reset(timeout)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		info, err := s.consumer.Info(ctx)
+		cancel()
+		if err != nil || info.NumPending == 0 {
+			// consumer info is not available while disconnected,
+			// reconnect is handled separately
+			continue
+		}
+		atomic.StoreInt64(&s.lastActivity, time.Now().UnixNano())
+		select {
+		case s.errs <- ErrConsumerStalled:
+		case <-s.done:
+			return
+		}
+	}
+}
+
 func (s *pullSubscription) resetPendingMsgs() {
 	s.Lock()
 	defer s.Unlock()
@@ -332,8 +403,8 @@ func (s *pullSubscription) decrementPendingMsgs(msg *nats.Msg) {
 // [ConsumeExpiry] - sets a timeout for individual batch request, default is set to 30 seconds
 // [ConsumeHeartbeat] - sets an idle heartbeat setting for a pull request, default is set to 5s
 // [ConsumeErrHandler] - sets custom consume error callback handler
-// [ConsumeThresholdMessages] - sets the byte count on which Consume will trigger new pull request to the server
-// [ConsumeThresholdBytes] - sets the message count on which Consume will trigger new pull request to the server
+// [PullThresholdMessages] - sets the message count on which Consume will trigger new pull request to the server
+// [PullThresholdBytes] - sets the byte count on which Consume will trigger new pull request to the server
 func (p *pullConsumer) Messages(opts ...PullMessagesOpt) (MessagesContext, error) {
 	consumeOpts, err := parseMessagesOpts(opts...)
 	if err != nil {
@@ -401,6 +472,17 @@ var (
 )
 
 func (s *pullSubscription) Next() (Msg, error) {
+	return s.next(context.Background())
+}
+
+func (s *pullSubscription) NextWithContext(ctx context.Context) (Msg, error) {
+	if ctx == nil {
+		return nil, nats.ErrInvalidContext
+	}
+	return s.next(ctx)
+}
+
+func (s *pullSubscription) next(ctx context.Context) (Msg, error) {
 	s.Lock()
 	defer s.Unlock()
 	if atomic.LoadUint32(&s.closed) == 1 {
@@ -452,6 +534,8 @@ func (s *pullSubscription) Next() (Msg, error) {
 				s.pending.byteCount -= msg.Size()
 			}
 			return s.consumer.jetStream.toJSMsg(msg), nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
 		case err := <-s.errs:
 			if errors.Is(err, ErrNoHeartbeat) {
 				s.pending.msgCount = 0
@@ -499,10 +583,21 @@ func (s *pullSubscription) handleStatusMsg(msg *nats.Msg, msgErr error) error {
 		if s.consumeOpts.ErrHandler != nil {
 			s.consumeOpts.ErrHandler(s, msgErr)
 		}
-		if errors.Is(msgErr, ErrConsumerDeleted) || errors.Is(msgErr, ErrBadRequest) {
+		if errors.Is(msgErr, ErrConsumerDeleted) {
+			if s.consumeOpts.OnConsumerDeleted != nil {
+				s.consumeOpts.OnConsumerDeleted(s)
+			}
+			return msgErr
+		}
+		if errors.Is(msgErr, ErrBadRequest) {
 			return msgErr
 		}
-		if errors.Is(msgErr, ErrConsumerLeadershipChanged) {
+		if errors.Is(msgErr, ErrServerShutdown) {
+			if s.consumeOpts.OnServerShutdown != nil {
+				s.consumeOpts.OnServerShutdown(s)
+			}
+		}
+		if errors.Is(msgErr, ErrConsumerLeadershipChanged) || errors.Is(msgErr, ErrServerShutdown) {
 			s.pending.msgCount = 0
 			s.pending.byteCount = 0
 		}
@@ -559,9 +654,8 @@ func (p *pullConsumer) Fetch(batch int, opts ...FetchOpt) (MessageBatch, error)
 			return nil, err
 		}
 	}
-	// for longer pulls, set heartbeat value
-	if req.Expires >= 10*time.Second {
-		req.Heartbeat = 5 * time.Second
+	if err := setFetchHeartbeat(req); err != nil {
+		return nil, err
 	}
 
 	return p.fetch(req)
@@ -582,14 +676,28 @@ func (p *pullConsumer) FetchBytes(maxBytes int, opts ...FetchOpt) (MessageBatch,
 			return nil, err
 		}
 	}
-	// for longer pulls, set heartbeat value
-	if req.Expires >= 10*time.Second {
-		req.Heartbeat = 5 * time.Second
+	if err := setFetchHeartbeat(req); err != nil {
+		return nil, err
 	}
 
 	return p.fetch(req)
 }
 
+// setFetchHeartbeat validates the heartbeat set with [FetchHeartbeat] or,
+// if none was set, sets the heartbeat for longer pulls.
+func setFetchHeartbeat(req *pullRequest) error {
+	if req.Heartbeat == 0 {
+		if req.Expires >= 10*time.Second {
+			req.Heartbeat = 5 * time.Second
+		}
+		return nil
+	}
+	if req.Heartbeat > req.Expires/2 {
+		return fmt.Errorf("%w: idle heartbeat cannot be greater than half of max wait", ErrInvalidOption)
+	}
+	return nil
+}
+
 // Fetch sends a single request to retrieve given number of messages.
 // If there are any messages available at the time of sending request,
 // FetchNoWait will return immediately.
@@ -630,6 +738,9 @@ func (p *pullConsumer) fetch(req *pullRequest) (MessageBatch, error) {
 	go func(res *fetchResult) {
 		defer sub.subscription.Unsubscribe()
 		defer close(res.msgs)
+		if hbTimer != nil {
+			defer hbTimer.Stop()
+		}
 		for {
 			if receivedMsgs == req.Batch || (req.MaxBytes != 0 && receivedBytes == req.MaxBytes) {
 				res.done = true
@@ -661,6 +772,12 @@ func (p *pullConsumer) fetch(req *pullRequest) (MessageBatch, error) {
 				if req.MaxBytes != 0 {
 					receivedBytes += msg.Size()
 				}
+			case err := <-sub.errs:
+				// no heartbeat was received, e.g. because the
+				// connection or the server went away
+				res.err = err
+				res.done = true
+				return
 			case <-time.After(req.Expires + 1*time.Second):
 				res.done = true
 				return
@@ -822,9 +939,15 @@ func (consumeOpts *consumeOpts) setDefaults() error {
 	if consumeOpts.ThresholdMessages == 0 {
 		consumeOpts.ThresholdMessages = int(math.Ceil(float64(consumeOpts.MaxMessages) / 2))
 	}
+	if consumeOpts.ThresholdMessages > consumeOpts.MaxMessages {
+		return fmt.Errorf("the value of ThresholdMessages cannot exceed MaxMessages")
+	}
 	if consumeOpts.ThresholdBytes == 0 {
 		consumeOpts.ThresholdBytes = int(math.Ceil(float64(consumeOpts.MaxBytes) / 2))
 	}
+	if consumeOpts.ThresholdBytes > consumeOpts.MaxBytes && consumeOpts.MaxBytes > 0 {
+		return fmt.Errorf("the value of ThresholdBytes cannot exceed MaxBytes")
+	}
 	if consumeOpts.Heartbeat == unset {
 		consumeOpts.Heartbeat = consumeOpts.Expires / 2
 		if consumeOpts.Heartbeat > 30*time.Second {
@@ -0,0 +1,316 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+type (
+	// QuotaUsage is the estimated usage of a stream, based on the latest
+	// stream info and the sequences of messages published since.
+	QuotaUsage struct {
+		Msgs     uint64
+		Bytes    uint64
+		MaxMsgs  int64
+		MaxBytes int64
+		// Rate is the number of messages stored per second during the
+		// last interval, derived from publish acknowledgements.
+		Rate float64
+	}
+
+	// QuotaGuardOpt configures a [QuotaGuard].
+	QuotaGuardOpt func(*quotaGuardOpts) error
+
+	quotaGuardOpts struct {
+		interval      time.Duration
+		throttleAt    float64
+		throttleDelay time.Duration
+		rejectAt      float64
+		errHandler    func(error)
+	}
+
+	// QuotaGuard publishes messages to a stream, throttling or rejecting
+	// them when the stream is projected to reach its MaxMsgs or MaxBytes
+	// limits. This avoids silently losing old messages on streams using
+	// [DiscardOld].
+	QuotaGuard struct {
+		sync.Mutex
+		js     Publisher
+		stream Stream
+		name   string
+		opts   quotaGuardOpts
+		info   *StreamInfo
+		acks   []quotaAck
+		cancel context.CancelFunc
+		done   chan struct{}
+	}
+
+	quotaAck struct {
+		time time.Time
+		seq  uint64
+		size int
+	}
+)
+
+const (
+	// DefaultQuotaGuardInterval is the default interval between stream info requests.
+	DefaultQuotaGuardInterval = 5 * time.Second
+
+	// DefaultQuotaRejectAt is the default fraction of the stream limits
+	// at which publishes are rejected.
+	DefaultQuotaRejectAt = 1.0
+)
+
+// Fraction returns the fraction of the closest limit which is used after
+// the given time at the current rate. It is 0 if the stream has no limits.
+func (u QuotaUsage) Fraction(within time.Duration) float64 {
+	added := u.Rate * within.Seconds()
+	var fraction float64
+	if u.MaxMsgs > 0 {
+		fraction = (float64(u.Msgs) + added) / float64(u.MaxMsgs)
+	}
+	if u.MaxBytes > 0 && u.Msgs > 0 {
+		avg := float64(u.Bytes) / float64(u.Msgs)
+		if f := (float64(u.Bytes) + added*avg) / float64(u.MaxBytes); f > fraction {
+			fraction = f
+		}
+	}
+	return fraction
+}
+
+// WithQuotaGuardInterval sets the interval between stream info requests.
+// Usage is projected over the interval using the current rate.
+func WithQuotaGuardInterval(interval time.Duration) QuotaGuardOpt {
+	return func(opts *quotaGuardOpts) error {
+		if interval <= 0 {
+			return fmt.Errorf("%w: interval must be positive", ErrInvalidOption)
+		}
+		opts.interval = interval
+		return nil
+	}
+}
+
+// WithQuotaThrottle delays each publish by the given time once the given
+// fraction of the stream limits is projected to be used.
+func WithQuotaThrottle(at float64, delay time.Duration) QuotaGuardOpt {
+	return func(opts *quotaGuardOpts) error {
+		if at <= 0 || at > 1 {
+			return fmt.Errorf("%w: throttle fraction must be in (0, 1]", ErrInvalidOption)
+		}
+		if delay <= 0 {
+			return fmt.Errorf("%w: throttle delay must be positive", ErrInvalidOption)
+		}
+		opts.throttleAt = at
+		opts.throttleDelay = delay
+		return nil
+	}
+}
+
+// WithQuotaReject rejects publishes with [ErrStreamQuotaExceeded] once the
+// given fraction of the stream limits is projected to be used.
+func WithQuotaReject(at float64) QuotaGuardOpt {
+	return func(opts *quotaGuardOpts) error {
+		if at <= 0 || at > 1 {
+			return fmt.Errorf("%w: reject fraction must be in (0, 1]", ErrInvalidOption)
+		}
+		opts.rejectAt = at
+		return nil
+	}
+}
+
+// WithQuotaGuardErrHandler sets the handler invoked when stream info cannot be retrieved.
+func WithQuotaGuardErrHandler(cb func(error)) QuotaGuardOpt {
+	return func(opts *quotaGuardOpts) error {
+		opts.errHandler = cb
+		return nil
+	}
+}
+
+// GuardStreamQuota returns a [QuotaGuard] publishing to the given stream
+// using js. Stream info is requested periodically, in between usage is
+// estimated from the sequences of acknowledged messages.
+// Requests stop when ctx is done or [QuotaGuard.Stop] is called.
+//
+// Available options:
+// [WithQuotaGuardInterval] - sets the interval between stream info requests, default is 5s
+// [WithQuotaThrottle] - delays publishes once the given fraction of limits is used
+// [WithQuotaReject] - rejects publishes once the given fraction of limits is used, default is 1
+// [WithQuotaGuardErrHandler] - sets the handler for errors retrieving stream info
+func GuardStreamQuota(ctx context.Context, js Publisher, stream Stream, opts ...QuotaGuardOpt) (*QuotaGuard, error) {
+	o := quotaGuardOpts{
+		interval: DefaultQuotaGuardInterval,
+		rejectAt: DefaultQuotaRejectAt,
+	}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+	if o.throttleAt > o.rejectAt {
+		return nil, fmt.Errorf("%w: throttle fraction must not exceed reject fraction", ErrInvalidOption)
+	}
+	info, err := stream.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	g := &QuotaGuard{
+		js:     js,
+		stream: stream,
+		name:   info.Config.Name,
+		opts:   o,
+		info:   info,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go g.run(ctx)
+	return g, nil
+}
+
+// Stop stops requesting stream info.
+func (g *QuotaGuard) Stop() {
+	g.cancel()
+	<-g.done
+}
+
+// Usage returns the estimated usage of the stream.
+func (g *QuotaGuard) Usage() QuotaUsage {
+	g.Lock()
+	defer g.Unlock()
+	return g.usage(time.Now())
+}
+
+// Publish publishes a message to the stream, see [QuotaGuard.PublishMsg].
+func (g *QuotaGuard) Publish(ctx context.Context, subj string, data []byte, opts ...PublishOpt) (*PubAck, error) {
+	return g.PublishMsg(ctx, &nats.Msg{Subject: subj, Data: data}, opts...)
+}
+
+// PublishMsg publishes a message to the stream and waits for the ack. If the
+// stream is projected to reach its limits, the message is delayed or rejected
+// with [ErrStreamQuotaExceeded].
+func (g *QuotaGuard) PublishMsg(ctx context.Context, m *nats.Msg, opts ...PublishOpt) (*PubAck, error) {
+	if err := g.admit(ctx); err != nil {
+		return nil, err
+	}
+	ack, err := g.js.PublishMsg(ctx, m, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if ack.Stream == g.name {
+		g.record(quotaAck{time: time.Now(), seq: ack.Sequence, size: len(m.Data)})
+	}
+	return ack, nil
+}
+
+// admit rejects the publish or waits if the stream is near its limits.
+func (g *QuotaGuard) admit(ctx context.Context) error {
+	fraction := g.Usage().Fraction(g.opts.interval)
+	if fraction >= g.opts.rejectAt {
+		return fmt.Errorf("%w: %.0f%% of stream %q limits projected to be used", ErrStreamQuotaExceeded, fraction*100, g.name)
+	}
+	if g.opts.throttleAt > 0 && fraction >= g.opts.throttleAt {
+		t := time.NewTimer(g.opts.throttleDelay)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (g *QuotaGuard) run(ctx context.Context) {
+	defer close(g.done)
+	t := time.NewTicker(g.opts.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			info, err := g.stream.Info(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				if g.opts.errHandler != nil {
+					g.opts.errHandler(err)
+				}
+				continue
+			}
+			g.Lock()
+			g.info = info
+			g.Unlock()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// record stores an ack.
+func (g *QuotaGuard) record(ack quotaAck) {
+	g.Lock()
+	defer g.Unlock()
+	g.acks = append(g.acks, ack)
+	g.pruneAcks(ack.time)
+}
+
+// pruneAcks removes acks older than the interval, keeping the latest one.
+// Lock is held on entry.
+func (g *QuotaGuard) pruneAcks(now time.Time) {
+	cutoff := now.Add(-g.opts.interval)
+	var i int
+	for i < len(g.acks)-1 && g.acks[i].time.Before(cutoff) {
+		i++
+	}
+	g.acks = g.acks[i:]
+}
+
+// usage estimates the usage from the latest stream info, counting messages
+// stored since by their sequence.
+// Lock is held on entry.
+func (g *QuotaGuard) usage(now time.Time) QuotaUsage {
+	state := g.info.State
+	u := QuotaUsage{
+		Msgs:     state.Msgs,
+		Bytes:    state.Bytes,
+		MaxMsgs:  g.info.Config.MaxMsgs,
+		MaxBytes: g.info.Config.MaxBytes,
+	}
+	if len(g.acks) == 0 {
+		return u
+	}
+	g.pruneAcks(now)
+	first, last := g.acks[0], g.acks[len(g.acks)-1]
+	if last.seq > state.LastSeq {
+		added := last.seq - state.LastSeq
+		var avg uint64
+		if state.Msgs > 0 {
+			avg = state.Bytes / state.Msgs
+		} else {
+			avg = uint64(last.size)
+		}
+		u.Msgs += added
+		u.Bytes += added * avg
+	}
+	if !last.time.Before(now.Add(-g.opts.interval)) && last.seq >= first.seq {
+		u.Rate = float64(last.seq-first.seq+1) / g.opts.interval.Seconds()
+	}
+	return u
+}
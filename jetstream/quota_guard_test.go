@@ -0,0 +1,150 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+type seqPublisher struct {
+	Publisher
+	stream string
+	seq    uint64
+}
+
+func (p *seqPublisher) PublishMsg(context.Context, *nats.Msg, ...PublishOpt) (*PubAck, error) {
+	p.seq++
+	return &PubAck{Stream: p.stream, Sequence: p.seq}, nil
+}
+
+func TestQuotaUsageFraction(t *testing.T) {
+	tests := []struct {
+		name     string
+		usage    QuotaUsage
+		within   time.Duration
+		expected float64
+	}{
+		{name: "no limits", usage: QuotaUsage{Msgs: 100, Bytes: 1000, MaxMsgs: -1, MaxBytes: -1}},
+		{name: "msgs limit", usage: QuotaUsage{Msgs: 50, Bytes: 500, MaxMsgs: 100, MaxBytes: -1}, expected: 0.5},
+		{name: "bytes limit closer", usage: QuotaUsage{Msgs: 50, Bytes: 800, MaxMsgs: 100, MaxBytes: 1000}, expected: 0.8},
+		{name: "projected with rate", usage: QuotaUsage{Msgs: 50, Bytes: 500, MaxMsgs: 100, MaxBytes: -1, Rate: 10}, within: 2 * time.Second, expected: 0.7},
+		{name: "projected bytes with rate", usage: QuotaUsage{Msgs: 50, Bytes: 500, MaxMsgs: -1, MaxBytes: 1000, Rate: 10}, within: 5 * time.Second, expected: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if f := test.usage.Fraction(test.within); f != test.expected {
+				t.Fatalf("Expected fraction %v; got: %v", test.expected, f)
+			}
+		})
+	}
+}
+
+func TestQuotaGuard(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	newStream := func(msgs uint64) *mirrorInfoStream {
+		return &mirrorInfoStream{info: &StreamInfo{
+			Config: StreamConfig{Name: "ORDERS", MaxMsgs: 100, MaxBytes: -1},
+			State:  StreamState{Msgs: msgs, Bytes: msgs * 10, LastSeq: msgs},
+		}}
+	}
+
+	t.Run("invalid options", func(t *testing.T) {
+		if _, err := GuardStreamQuota(ctx, &seqPublisher{}, newStream(90), WithQuotaReject(1.5)); !errors.Is(err, ErrInvalidOption) {
+			t.Fatalf("Expected error: %v; got: %v", ErrInvalidOption, err)
+		}
+		if _, err := GuardStreamQuota(ctx, &seqPublisher{}, newStream(90), WithQuotaThrottle(0.9, time.Millisecond), WithQuotaReject(0.8)); !errors.Is(err, ErrInvalidOption) {
+			t.Fatalf("Expected error: %v; got: %v", ErrInvalidOption, err)
+		}
+	})
+
+	t.Run("reject at limit", func(t *testing.T) {
+		pub := &seqPublisher{stream: "ORDERS", seq: 99}
+		g, err := GuardStreamQuota(ctx, pub, newStream(99), WithQuotaGuardInterval(time.Hour))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer g.Stop()
+
+		if _, err := g.Publish(ctx, "ORDERS.new", []byte("0123456789")); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if u := g.Usage(); u.Msgs != 100 || u.Bytes != 1000 {
+			t.Fatalf("Unexpected usage: %+v", u)
+		}
+		if _, err := g.Publish(ctx, "ORDERS.new", []byte("0123456789")); !errors.Is(err, ErrStreamQuotaExceeded) {
+			t.Fatalf("Expected error: %v; got: %v", ErrStreamQuotaExceeded, err)
+		}
+	})
+
+	t.Run("throttle and reject projected usage", func(t *testing.T) {
+		pub := &seqPublisher{stream: "ORDERS", seq: 90}
+		g, err := GuardStreamQuota(ctx, pub, newStream(90),
+			WithQuotaGuardInterval(time.Second),
+			WithQuotaThrottle(0.5, 20*time.Millisecond),
+			WithQuotaReject(0.95))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer g.Stop()
+
+		start := time.Now()
+		if _, err := g.Publish(ctx, "ORDERS.new", nil); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if time.Since(start) < 20*time.Millisecond {
+			t.Fatalf("Expected publish to be throttled")
+		}
+		// As many messages as stored within the last interval
+		// are projected to be stored within the next one.
+		for i := 0; i < 10; i++ {
+			_, err = g.Publish(ctx, "ORDERS.new", nil)
+			if err != nil {
+				break
+			}
+		}
+		if !errors.Is(err, ErrStreamQuotaExceeded) {
+			t.Fatalf("Expected error: %v; got: %v", ErrStreamQuotaExceeded, err)
+		}
+		if g.Usage().Rate <= 0 {
+			t.Fatalf("Expected rate to be measured")
+		}
+	})
+
+	t.Run("usage refreshed from stream info", func(t *testing.T) {
+		s := newStream(90)
+		pub := &seqPublisher{stream: "ORDERS", seq: 90}
+		g, err := GuardStreamQuota(ctx, pub, s, WithQuotaGuardInterval(10*time.Millisecond))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer g.Stop()
+
+		s.Lock()
+		s.info.State = StreamState{Msgs: 10, Bytes: 100, LastSeq: 90}
+		s.Unlock()
+		deadline := time.Now().Add(time.Second)
+		for g.Usage().Msgs != 10 {
+			if time.Now().After(deadline) {
+				t.Fatalf("Usage not refreshed: %+v", g.Usage())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+}
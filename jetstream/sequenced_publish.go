@@ -0,0 +1,325 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nuid"
+)
+
+type (
+	// SequencedPublisher publishes messages asynchronously to a single
+	// stream and verifies that acks are contiguous, detecting messages lost
+	// e.g. while reconnecting.
+	//
+	// The stream processes messages in the order they were published, so
+	// when a message is acknowledged while earlier messages were not, the
+	// earlier messages are considered lost and fail with [ErrPublishLost].
+	// If the stream sequence shows that they were stored and only their
+	// acks were lost, they are acknowledged instead.
+	//
+	// The publisher has to be the only one publishing to the stream, other
+	// messages stored in the stream are reported as gaps, see
+	// [WithSequenceGapHandler].
+	SequencedPublisher struct {
+		js     JetStream
+		stream string
+		opts   sequencedPublisherOpts
+
+		// pubMu serializes publishes, so that pending is in publish order
+		pubMu sync.Mutex
+
+		sync.Mutex
+		lastSeq      uint64
+		pending      []*sequencedPubAckFuture
+		republishing int
+		drained      chan struct{}
+		idPrefix     string
+		nextID       uint64
+	}
+
+	// SequencedPublisherOpt configures a [SequencedPublisher].
+	SequencedPublisherOpt func(*sequencedPublisherOpts) error
+
+	// SequenceGapHandler is invoked with the range of stream sequences which
+	// were not stored by a [SequencedPublisher].
+	SequenceGapHandler func(first, last uint64)
+
+	sequencedPublisherOpts struct {
+		republishAttempts int
+		gapHandler        SequenceGapHandler
+	}
+
+	sequencedPubAckFuture struct {
+		msg      *nats.Msg
+		opts     []PublishOpt
+		attempts int
+		okCh     chan *PubAck
+		errCh    chan error
+	}
+)
+
+// WithSequencedRepublish enables republishing lost messages up to the given
+// number of times. Messages without [MsgIDHeader] get a unique message ID, so
+// that messages which were stored despite being considered lost are
+// deduplicated by the stream. Republished messages are stored after messages
+// published in the meantime.
+func WithSequencedRepublish(attempts int) SequencedPublisherOpt {
+	return func(opts *sequencedPublisherOpts) error {
+		if attempts < 0 {
+			return fmt.Errorf("%w: republish attempts cannot be negative", ErrInvalidOption)
+		}
+		opts.republishAttempts = attempts
+		return nil
+	}
+}
+
+// WithSequenceGapHandler sets a handler invoked when the stream stored
+// messages which were not published by the [SequencedPublisher].
+func WithSequenceGapHandler(cb SequenceGapHandler) SequencedPublisherOpt {
+	return func(opts *sequencedPublisherOpts) error {
+		opts.gapHandler = cb
+		return nil
+	}
+}
+
+// NewSequencedPublisher creates a [SequencedPublisher] publishing to the
+// given stream. Sequence verification starts at the last sequence of the stream.
+//
+// Available options:
+// [WithSequencedRepublish] - republishes lost messages, by default they fail with [ErrPublishLost]
+// [WithSequenceGapHandler] - sets a handler invoked for messages stored by other publishers
+func NewSequencedPublisher(ctx context.Context, js JetStream, stream string, opts ...SequencedPublisherOpt) (*SequencedPublisher, error) {
+	var o sequencedPublisherOpts
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+	s, err := js.Stream(ctx, stream)
+	if err != nil {
+		return nil, err
+	}
+	return &SequencedPublisher{
+		js:       js,
+		stream:   stream,
+		opts:     o,
+		lastSeq:  s.CachedInfo().State.LastSeq,
+		idPrefix: nuid.Next(),
+	}, nil
+}
+
+// PublishAsync publishes data to the subject, which must be bound to the stream.
+func (p *SequencedPublisher) PublishAsync(ctx context.Context, subject string, data []byte, opts ...PublishOpt) (PubAckFuture, error) {
+	return p.PublishMsgAsync(ctx, &nats.Msg{Subject: subject, Data: data}, opts...)
+}
+
+// PublishMsgAsync publishes the message, which must be bound to the stream.
+// The returned future fails with [ErrPublishLost] if the message was lost.
+func (p *SequencedPublisher) PublishMsgAsync(ctx context.Context, msg *nats.Msg, opts ...PublishOpt) (PubAckFuture, error) {
+	hdr := nats.Header{}
+	for k, v := range msg.Header {
+		hdr[k] = v
+	}
+	hdr.Set(ExpectedStreamHeader, p.stream)
+	if p.opts.republishAttempts > 0 && hdr.Get(MsgIDHeader) == "" {
+		p.Lock()
+		p.nextID++
+		hdr.Set(MsgIDHeader, p.idPrefix+"."+strconv.FormatUint(p.nextID, 10))
+		p.Unlock()
+	}
+	paf := &sequencedPubAckFuture{
+		msg:   &nats.Msg{Subject: msg.Subject, Header: hdr, Data: msg.Data},
+		opts:  opts,
+		okCh:  make(chan *PubAck, 1),
+		errCh: make(chan error, 1),
+	}
+	if err := p.publish(ctx, paf); err != nil {
+		return nil, err
+	}
+	return paf, nil
+}
+
+// LastSequence returns the last stream sequence verified by the publisher.
+func (p *SequencedPublisher) LastSequence() uint64 {
+	p.Lock()
+	defer p.Unlock()
+	return p.lastSeq
+}
+
+// Flush waits until all published messages were acknowledged, failed or
+// republished, or the context is done.
+func (p *SequencedPublisher) Flush(ctx context.Context) error {
+	for {
+		p.Lock()
+		if len(p.pending) == 0 && p.republishing == 0 {
+			p.Unlock()
+			return nil
+		}
+		if p.drained == nil {
+			p.drained = make(chan struct{})
+		}
+		drained := p.drained
+		p.Unlock()
+		select {
+		case <-drained:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (p *SequencedPublisher) publish(ctx context.Context, paf *sequencedPubAckFuture) error {
+	p.pubMu.Lock()
+	defer p.pubMu.Unlock()
+	p.Lock()
+	p.pending = append(p.pending, paf)
+	p.Unlock()
+	err := p.js.PublishAsyncWithCallback(ctx, paf.msg, func(ack *PubAck, err error) {
+		p.handleAck(paf, ack, err)
+	}, paf.opts...)
+	if err != nil {
+		p.Lock()
+		p.remove(paf)
+		p.checkDrained()
+		p.Unlock()
+	}
+	return err
+}
+
+// handleAck verifies the ack against the last stream sequence and resolves
+// pending messages published before the acknowledged one.
+func (p *SequencedPublisher) handleAck(paf *sequencedPubAckFuture, ack *PubAck, err error) {
+	p.Lock()
+	idx := -1
+	for i, pending := range p.pending {
+		if pending == paf {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		// already resolved as lost
+		p.Unlock()
+		return
+	}
+	if err != nil || (ack.Duplicate && ack.Sequence <= p.lastSeq) {
+		p.remove(paf)
+		p.checkDrained()
+		p.Unlock()
+		if err != nil {
+			paf.errCh <- err
+		} else {
+			paf.okCh <- ack
+		}
+		return
+	}
+
+	earlier := p.pending[:idx]
+	var acked, lost []*sequencedPubAckFuture
+	var gapFirst, gapLast uint64
+	if ack.Sequence > p.lastSeq {
+		switch stored := ack.Sequence - p.lastSeq - 1; {
+		case stored == uint64(len(earlier)):
+			// earlier messages were stored, only their acks were lost
+			acked = earlier
+		case stored > uint64(len(earlier)):
+			gapFirst, gapLast = p.lastSeq+1, ack.Sequence-1
+			lost = earlier
+		default:
+			lost = earlier
+		}
+	} else {
+		lost = earlier
+	}
+	lastSeq := p.lastSeq
+	p.lastSeq = ack.Sequence
+	p.pending = append([]*sequencedPubAckFuture(nil), p.pending[idx+1:]...)
+	var republish, failed []*sequencedPubAckFuture
+	for _, l := range lost {
+		if l.attempts < p.opts.republishAttempts {
+			l.attempts++
+			republish = append(republish, l)
+		} else {
+			failed = append(failed, l)
+		}
+	}
+	p.republishing += len(republish)
+	p.checkDrained()
+	p.Unlock()
+
+	for i, a := range acked {
+		a.okCh <- &PubAck{Stream: ack.Stream, Sequence: lastSeq + uint64(i) + 1, Domain: ack.Domain}
+	}
+	for _, f := range failed {
+		f.errCh <- ErrPublishLost
+	}
+	if len(republish) > 0 {
+		// publishing may block on pending acks, which are delivered by the caller
+		go p.republish(republish)
+	}
+	if gapFirst > 0 && p.opts.gapHandler != nil {
+		p.opts.gapHandler(gapFirst, gapLast)
+	}
+	paf.okCh <- ack
+}
+
+func (p *SequencedPublisher) republish(pafs []*sequencedPubAckFuture) {
+	for _, paf := range pafs {
+		err := p.publish(context.Background(), paf)
+		if err != nil {
+			paf.errCh <- err
+		}
+		p.Lock()
+		p.republishing--
+		p.checkDrained()
+		p.Unlock()
+	}
+}
+
+// remove removes the future from pending, must be called with the lock held.
+func (p *SequencedPublisher) remove(paf *sequencedPubAckFuture) {
+	for i, pending := range p.pending {
+		if pending == paf {
+			p.pending = append(p.pending[:i], p.pending[i+1:]...)
+			return
+		}
+	}
+}
+
+// checkDrained notifies [SequencedPublisher.Flush] if nothing is pending,
+// must be called with the lock held.
+func (p *SequencedPublisher) checkDrained() {
+	if p.drained != nil && len(p.pending) == 0 && p.republishing == 0 {
+		close(p.drained)
+		p.drained = nil
+	}
+}
+
+func (paf *sequencedPubAckFuture) Ok() <-chan *PubAck {
+	return paf.okCh
+}
+
+func (paf *sequencedPubAckFuture) Err() <-chan error {
+	return paf.errCh
+}
+
+func (paf *sequencedPubAckFuture) Msg() *nats.Msg {
+	return paf.msg
+}
@@ -0,0 +1,248 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+type (
+	// SLATracker measures the end-to-end latency of messages, from the
+	// time they were stored in the stream until they were successfully
+	// acknowledged, per consumer. Unlike the number of pending messages,
+	// this reflects how long messages actually wait to be processed.
+	//
+	// Latencies are measured using the local clock against the stream
+	// timestamp, so they include any clock skew between the client and
+	// the server.
+	SLATracker struct {
+		sync.Mutex
+		sla       time.Duration
+		opts      slaTrackerOpts
+		consumers map[string]*slaSamples
+	}
+
+	// SLATrackerOpt configures a [SLATracker].
+	SLATrackerOpt func(*slaTrackerOpts) error
+
+	// SLAExceededHandler is invoked when a message was acknowledged later
+	// than the SLA after it was stored in the stream.
+	SLAExceededHandler func(consumer string, msg Msg, latency time.Duration)
+
+	slaTrackerOpts struct {
+		sampleSize int
+		exceededCB SLAExceededHandler
+	}
+
+	// SLAStats are the latency statistics of a consumer. Percentiles are
+	// computed over the most recent acknowledgements, while the counters
+	// cover all of them.
+	SLAStats struct {
+		// Acked is the number of acknowledged messages.
+		Acked uint64
+		// Exceeded is the number of messages acknowledged later than the SLA.
+		Exceeded uint64
+		P50      time.Duration
+		P90      time.Duration
+		P99      time.Duration
+		Max      time.Duration
+	}
+
+	slaSamples struct {
+		acked    uint64
+		exceeded uint64
+		samples  []time.Duration
+		next     int
+	}
+
+	slaMsg struct {
+		Msg
+		tracker *SLATracker
+	}
+)
+
+// DefaultSLASampleSize is the default number of latencies per consumer
+// percentiles are computed over.
+const DefaultSLASampleSize = 1024
+
+// WithSLASampleSize sets the number of most recent latencies per consumer
+// percentiles are computed over.
+func WithSLASampleSize(size int) SLATrackerOpt {
+	return func(opts *slaTrackerOpts) error {
+		if size <= 0 {
+			return fmt.Errorf("%w: sample size must be positive", ErrInvalidOption)
+		}
+		opts.sampleSize = size
+		return nil
+	}
+}
+
+// WithSLAExceededHandler sets the handler invoked when a message is
+// acknowledged later than the SLA.
+func WithSLAExceededHandler(cb SLAExceededHandler) SLATrackerOpt {
+	return func(opts *slaTrackerOpts) error {
+		opts.exceededCB = cb
+		return nil
+	}
+}
+
+// NewSLATracker creates a [SLATracker] for the given SLA. Messages are
+// tracked by wrapping the handler passed to [Consume] with
+// [SLATracker.Handler], or wrapping fetched messages with [SLATracker.Track].
+//
+// Available options:
+// [WithSLASampleSize] - sets the number of latencies percentiles are computed over, default is 1024
+// [WithSLAExceededHandler] - sets the handler invoked when a message exceeds the SLA
+func NewSLATracker(sla time.Duration, opts ...SLATrackerOpt) (*SLATracker, error) {
+	if sla <= 0 {
+		return nil, fmt.Errorf("%w: SLA must be positive", ErrInvalidOption)
+	}
+	o := slaTrackerOpts{sampleSize: DefaultSLASampleSize}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+	return &SLATracker{
+		sla:       sla,
+		opts:      o,
+		consumers: make(map[string]*slaSamples),
+	}, nil
+}
+
+// Handler wraps a [MessageHandler], tracking messages acknowledged by it.
+func (t *SLATracker) Handler(h MessageHandler) MessageHandler {
+	return func(msg Msg) {
+		h(t.Track(msg))
+	}
+}
+
+// Track returns a message recording its latency once successfully
+// acknowledged using Ack, DoubleAck or AckAfterPublish.
+func (t *SLATracker) Track(msg Msg) Msg {
+	return &slaMsg{Msg: msg, tracker: t}
+}
+
+// Stats returns the latency statistics of a consumer.
+func (t *SLATracker) Stats(consumer string) SLAStats {
+	t.Lock()
+	s, ok := t.consumers[consumer]
+	if !ok {
+		t.Unlock()
+		return SLAStats{}
+	}
+	stats := SLAStats{Acked: s.acked, Exceeded: s.exceeded}
+	samples := append([]time.Duration(nil), s.samples...)
+	t.Unlock()
+
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	stats.P50 = percentile(samples, 50)
+	stats.P90 = percentile(samples, 90)
+	stats.P99 = percentile(samples, 99)
+	stats.Max = samples[len(samples)-1]
+	return stats
+}
+
+// Consumers returns the names of consumers with tracked messages.
+func (t *SLATracker) Consumers() []string {
+	t.Lock()
+	defer t.Unlock()
+	names := make([]string, 0, len(t.consumers))
+	for name := range t.consumers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Reset clears the statistics of all consumers.
+func (t *SLATracker) Reset() {
+	t.Lock()
+	t.consumers = make(map[string]*slaSamples)
+	t.Unlock()
+}
+
+func (t *SLATracker) record(msg Msg) {
+	meta, err := msg.Metadata()
+	if err != nil {
+		return
+	}
+	latency := time.Since(meta.Timestamp)
+	if latency < 0 {
+		latency = 0
+	}
+
+	t.Lock()
+	s, ok := t.consumers[meta.Consumer]
+	if !ok {
+		s = &slaSamples{samples: make([]time.Duration, 0, t.opts.sampleSize)}
+		t.consumers[meta.Consumer] = s
+	}
+	s.acked++
+	if len(s.samples) < t.opts.sampleSize {
+		s.samples = append(s.samples, latency)
+	} else {
+		s.samples[s.next] = latency
+		s.next = (s.next + 1) % t.opts.sampleSize
+	}
+	exceeded := latency > t.sla
+	if exceeded {
+		s.exceeded++
+	}
+	t.Unlock()
+
+	if exceeded && t.opts.exceededCB != nil {
+		t.opts.exceededCB(meta.Consumer, msg, latency)
+	}
+}
+
+// percentile returns the nearest rank percentile of sorted samples.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (len(sorted)*p + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func (m *slaMsg) Ack() error {
+	if err := m.Msg.Ack(); err != nil {
+		return err
+	}
+	m.tracker.record(m.Msg)
+	return nil
+}
+
+func (m *slaMsg) DoubleAck(ctx context.Context) error {
+	if err := m.Msg.DoubleAck(ctx); err != nil {
+		return err
+	}
+	m.tracker.record(m.Msg)
+	return nil
+}
+
+func (m *slaMsg) AckAfterPublish(ctx context.Context, js Publisher, out *nats.Msg, opts ...PublishOpt) (*PubAck, error) {
+	ack, err := m.Msg.AckAfterPublish(ctx, js, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	m.tracker.record(m.Msg)
+	return ack, nil
+}
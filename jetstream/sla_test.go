@@ -0,0 +1,101 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type stampedMsg struct {
+	Msg
+	consumer string
+	stamp    time.Time
+	ackErr   error
+}
+
+func (m *stampedMsg) Metadata() (*MsgMetadata, error) {
+	return &MsgMetadata{Consumer: m.consumer, Timestamp: m.stamp}, nil
+}
+
+func (m *stampedMsg) Ack() error {
+	return m.ackErr
+}
+
+func TestSLATracker(t *testing.T) {
+	var exceeded []time.Duration
+	tracker, err := NewSLATracker(time.Minute+500*time.Millisecond,
+		WithSLASampleSize(100),
+		WithSLAExceededHandler(func(consumer string, msg Msg, latency time.Duration) {
+			if consumer != "cons" {
+				t.Fatalf("Invalid consumer; want: %q; got: %q", "cons", consumer)
+			}
+			exceeded = append(exceeded, latency)
+		}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	handler := tracker.Handler(func(msg Msg) {
+		msg.Ack()
+	})
+	now := time.Now()
+	// 200 messages, only the latest 100 with latencies of 1..100 seconds
+	// are used for percentiles
+	for i := 1; i <= 200; i++ {
+		handler(&stampedMsg{consumer: "cons", stamp: now.Add(-time.Duration(i%100+1) * time.Second)})
+	}
+	// failed acks are not recorded
+	handler(&stampedMsg{consumer: "cons", stamp: now.Add(-time.Hour), ackErr: errors.New("failed")})
+
+	stats := tracker.Stats("cons")
+	if stats.Acked != 200 {
+		t.Fatalf("Invalid acked count; want: %d; got: %d", 200, stats.Acked)
+	}
+	// latencies above 60s
+	if stats.Exceeded != 80 || len(exceeded) != 80 {
+		t.Fatalf("Invalid exceeded count; want: %d; got: %d, %d", 80, stats.Exceeded, len(exceeded))
+	}
+	for _, p := range []struct {
+		got, want time.Duration
+	}{
+		{stats.P50, 50 * time.Second},
+		{stats.P90, 90 * time.Second},
+		{stats.P99, 99 * time.Second},
+		{stats.Max, 100 * time.Second},
+	} {
+		if p.got < p.want || p.got > p.want+time.Second {
+			t.Fatalf("Invalid percentile; want: %v; got: %v", p.want, p.got)
+		}
+	}
+
+	if names := tracker.Consumers(); len(names) != 1 || names[0] != "cons" {
+		t.Fatalf("Invalid consumers: %v", names)
+	}
+	if stats := tracker.Stats("other"); stats != (SLAStats{}) {
+		t.Fatalf("Expected empty stats; got: %+v", stats)
+	}
+	tracker.Reset()
+	if stats := tracker.Stats("cons"); stats != (SLAStats{}) {
+		t.Fatalf("Expected empty stats; got: %+v", stats)
+	}
+
+	if _, err := NewSLATracker(0); !errors.Is(err, ErrInvalidOption) {
+		t.Fatalf("Expected error %v; got: %v", ErrInvalidOption, err)
+	}
+	if _, err := NewSLATracker(time.Second, WithSLASampleSize(0)); !errors.Is(err, ErrInvalidOption) {
+		t.Fatalf("Expected error %v; got: %v", ErrInvalidOption, err)
+	}
+}
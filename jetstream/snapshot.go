@@ -0,0 +1,115 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import (
+	"context"
+	"errors"
+)
+
+// SubjectsSnapshot holds the last message of each subject as of a single
+// stream sequence, i.e. a consistent point-in-time view of the subjects.
+type SubjectsSnapshot struct {
+	// Sequence is the last stream sequence the snapshot includes.
+	Sequence uint64
+	// Messages holds the last message of each subject, keyed by subject.
+	Messages map[string]*RawStreamMsg
+}
+
+// snapshotMaxAttempts limits the number of attempts to read a consistent
+// snapshot from servers not supporting batched direct gets.
+const snapshotMaxAttempts = 5
+
+// SnapshotSubjects returns the last message of each subject matching the
+// given subjects, as of the last sequence of the stream when called. Messages
+// stored later on the subjects are not included. Subjects can contain
+// wildcards.
+//
+// If the stream allows direct gets, the snapshot is read using a single
+// batched direct get request with the captured sequence as a ceiling.
+// Otherwise the last messages are read one at a time, which is retried if
+// any of the subjects received messages past the captured sequence, and
+// [ErrSnapshotConflict] is returned if a consistent snapshot could not be
+// read within a few attempts.
+func (s *stream) SnapshotSubjects(ctx context.Context, subjects ...string) (*SubjectsSnapshot, error) {
+	if len(subjects) == 0 {
+		return nil, ErrSubjectsRequired
+	}
+	info, err := s.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+	seq := info.State.LastSeq
+
+	if info.Config.AllowDirect {
+		req := &multiLastMsgGetRequest{MultiLastFor: subjects, UpToSeq: seq}
+		msgs, err := collectMsgs(func(msgs chan<- *RawStreamMsg) error {
+			return s.multiLastMsgs(ctx, req, msgs)
+		})
+		if err == nil {
+			return newSubjectsSnapshot(seq, msgs), nil
+		}
+		if !errors.Is(err, errMultiLastNotSupported) {
+			return nil, err
+		}
+	}
+
+	for attempt := 0; attempt < snapshotMaxAttempts; attempt++ {
+		msgs, err := collectMsgs(func(msgs chan<- *RawStreamMsg) error {
+			return s.lastMsgsBySubject(ctx, subjects, msgs)
+		})
+		if err != nil {
+			return nil, err
+		}
+		// The last messages were read after the sequence was captured, so
+		// if none is past it, no subject received messages in between.
+		latest := seq
+		for _, msg := range msgs {
+			if msg.Sequence > latest {
+				latest = msg.Sequence
+			}
+		}
+		if latest == seq {
+			return newSubjectsSnapshot(seq, msgs), nil
+		}
+		seq = latest
+	}
+	return nil, ErrSnapshotConflict
+}
+
+// collectMsgs gathers the messages delivered by fn.
+func collectMsgs(fn func(chan<- *RawStreamMsg) error) ([]*RawStreamMsg, error) {
+	ch := make(chan *RawStreamMsg)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- fn(ch)
+		close(ch)
+	}()
+	var msgs []*RawStreamMsg
+	for msg := range ch {
+		msgs = append(msgs, msg)
+	}
+	return msgs, <-errs
+}
+
+func newSubjectsSnapshot(seq uint64, msgs []*RawStreamMsg) *SubjectsSnapshot {
+	snapshot := &SubjectsSnapshot{
+		Sequence: seq,
+		Messages: make(map[string]*RawStreamMsg, len(msgs)),
+	}
+	for _, msg := range msgs {
+		snapshot.Messages[msg.Subject] = msg
+	}
+	return snapshot
+}
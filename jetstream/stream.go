@@ -35,13 +35,38 @@ type (
 		// CachedInfo returns *StreamInfo cached on a consumer struct
 		CachedInfo() *StreamInfo
 
-		// Purge removes messages from a stream
-		Purge(context.Context, ...StreamPurgeOpt) error
+		// Purge removes messages from a stream and returns the number of purged messages
+		Purge(context.Context, ...StreamPurgeOpt) (uint64, error)
 
-		// GetMsg retrieves a raw stream message stored in JetStream by sequence number
+		// GetMsg retrieves a raw stream message stored in JetStream by sequence number.
+		// The request is always answered by the stream leader.
 		GetMsg(context.Context, uint64, ...GetMsgOpt) (*RawStreamMsg, error)
-		// GetLastMsgForSubject retrieves the last raw stream message stored in JetStream by subject
+		// GetLastMsgForSubject retrieves the last raw stream message stored in JetStream by subject.
+		// The request is always answered by the stream leader.
 		GetLastMsgForSubject(context.Context, string) (*RawStreamMsg, error)
+		// GetMsgDirect is like GetMsg, but uses direct get requests if the stream allows them,
+		// which can be answered by any replica instead of the stream leader.
+		// Messages stored recently may not be returned by replicas which did not receive them yet.
+		GetMsgDirect(context.Context, uint64, ...GetMsgOpt) (*RawStreamMsg, error)
+		// GetLastMsgForSubjectDirect is like GetLastMsgForSubject, but uses direct get requests
+		// if the stream allows them.
+		GetLastMsgForSubjectDirect(context.Context, string) (*RawStreamMsg, error)
+		// GetLastMsgsForSubjects returns RawStreamMsgLister enabling iterating over the last messages
+		// of all subjects matching the given subjects, which can contain wildcards.
+		GetLastMsgsForSubjects(context.Context, ...string) RawStreamMsgLister
+		// SnapshotSubjects returns the last message of each subject matching the given subjects
+		// as of a single stream sequence, providing a consistent point-in-time view of the subjects.
+		SnapshotSubjects(context.Context, ...string) (*SubjectsSnapshot, error)
+		// Browse returns a page of stream messages, read forward or backward
+		// from a sequence, a time or a cursor returned with a previous page.
+		Browse(context.Context, ...BrowseOpt) (*BrowseResult, error)
+		// LeaderStepDown makes the current leader of a clustered stream step down,
+		// triggering the election of a new leader among the other replicas.
+		LeaderStepDown(context.Context) error
+		// RemovePeer removes the replica of a clustered stream placed on the
+		// server with the given name. A replacement replica is placed on
+		// another server if one is available.
+		RemovePeer(ctx context.Context, peer string) error
 		// DeleteMsg deletes a message from a stream.
 		// The message is marked as erased, but not overwritten
 		DeleteMsg(context.Context, uint64) error
@@ -56,6 +81,10 @@ type (
 		// Consumer interface is returned, serving as a hook to operate on a consumer (e.g. fetch messages).
 		AddConsumer(context.Context, ConsumerConfig) (Consumer, error)
 
+		// UpdateConsumer updates the configuration of an existing consumer.
+		// ErrConsumerNotFound is returned if the consumer does not exist.
+		UpdateConsumer(context.Context, ConsumerConfig) (Consumer, error)
+
 		// OrderedConsumer returns an OrderedConsumer instance.
 		// OrderedConsumer allows fetching messages from a stream (just like standard consumer),
 		// for in order delivery of messages. Underlying consumer is re-created when necessary,
@@ -122,6 +151,19 @@ type (
 		Purged  uint64 `json:"purged"`
 	}
 
+	leaderStepDownResponse struct {
+		apiResponse
+	}
+
+	streamRemovePeerRequest struct {
+		Peer string `json:"peer"`
+	}
+
+	streamRemovePeerResponse struct {
+		apiResponse
+		Success bool `json:"success,omitempty"`
+	}
+
 	consumerDeleteResponse struct {
 		apiResponse
 		Success bool `json:"success,omitempty"`
@@ -160,6 +202,11 @@ type (
 		Success bool `json:"success,omitempty"`
 	}
 
+	RawStreamMsgLister interface {
+		Messages() <-chan *RawStreamMsg
+		Err() <-chan error
+	}
+
 	ConsumerInfoLister interface {
 		Info() <-chan *ConsumerInfo
 		Err() <-chan error
@@ -197,13 +244,20 @@ func (s *stream) AddConsumer(ctx context.Context, cfg ConsumerConfig) (Consumer,
 	return upsertConsumer(ctx, s.jetStream, s.name, cfg)
 }
 
+func (s *stream) UpdateConsumer(ctx context.Context, cfg ConsumerConfig) (Consumer, error) {
+	return updateConsumer(ctx, s.jetStream, s.name, cfg)
+}
+
 func (s *stream) OrderedConsumer(ctx context.Context, cfg OrderedConsumerConfig) (Consumer, error) {
+	if err := validateFilterSubjects(cfg.FilterSubjects); err != nil {
+		return nil, err
+	}
 	oc := &orderedConsumer{
 		jetStream:  s.jetStream,
 		cfg:        &cfg,
 		stream:     s.name,
 		namePrefix: nuid.Next(),
-		doReset:    make(chan struct{}, 1),
+		doReset:    make(chan error, 1),
 	}
 	if cfg.OptStartSeq != 0 {
 		oc.cursor.streamSeq = cfg.OptStartSeq - 1
@@ -269,36 +323,72 @@ func (s *stream) CachedInfo() *StreamInfo {
 	return s.info
 }
 
-// Purge removes messages from a stream
+// Purge removes messages from a stream and returns the number of purged messages.
+// Without options, all messages are purged.
 //
 // Available options:
 // [WithPurgeSubject] - can be used set a sprecific subject for which messages on a stream will be purged
 // [WithPurgeSequence] - can be used to set a sprecific sequence number up to which (but not including) messages will be purged from a stream
 // [WithPurgeKeep] - can be used to set the number of messages to be kept in the stream after purge.
-func (s *stream) Purge(ctx context.Context, opts ...StreamPurgeOpt) error {
+func (s *stream) Purge(ctx context.Context, opts ...StreamPurgeOpt) (uint64, error) {
 	var purgeReq StreamPurgeRequest
 	for _, opt := range opts {
 		if err := opt(&purgeReq); err != nil {
-			return err
+			return 0, err
 		}
 	}
 	var req []byte
 	var err error
 	req, err = json.Marshal(purgeReq)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	purgeSubject := apiSubj(s.jetStream.apiPrefix, fmt.Sprintf(apiStreamPurgeT, s.name))
 
 	var resp streamPurgeResponse
 	if _, err = s.jetStream.apiRequestJSON(ctx, purgeSubject, &resp, req); err != nil {
+		return 0, err
+	}
+	if resp.Error != nil {
+		return 0, resp.Error
+	}
+
+	return resp.Purged, nil
+}
+
+// LeaderStepDown makes the current leader of the stream step down. It fails
+// with an [APIError] if the server does not run in clustered mode.
+func (s *stream) LeaderStepDown(ctx context.Context) error {
+	subj := apiSubj(s.jetStream.apiPrefix, fmt.Sprintf(apiStreamLeaderStepDownT, s.name))
+	return s.jetStream.leaderStepDown(ctx, subj)
+}
+
+// RemovePeer removes the replica of the stream placed on the server with the
+// given name. It returns [ErrPeerNotMember] if the server does not hold a
+// replica of the stream.
+func (s *stream) RemovePeer(ctx context.Context, peer string) error {
+	if peer == "" {
+		return ErrPeerRequired
+	}
+	req, err := json.Marshal(&streamRemovePeerRequest{Peer: peer})
+	if err != nil {
+		return err
+	}
+	subj := apiSubj(s.jetStream.apiPrefix, fmt.Sprintf(apiStreamRemovePeerT, s.name))
+	var resp streamRemovePeerResponse
+	if _, err := s.jetStream.apiRequestJSON(ctx, subj, &resp, req); err != nil {
 		return err
 	}
 	if resp.Error != nil {
+		switch resp.Error.ErrorCode {
+		case JSErrCodeStreamNotFound:
+			return ErrStreamNotFound
+		case JSErrCodeClusterPeerNotMember:
+			return ErrPeerNotMember
+		}
 		return resp.Error
 	}
-
 	return nil
 }
 
@@ -309,14 +399,33 @@ func (s *stream) GetMsg(ctx context.Context, seq uint64, opts ...GetMsgOpt) (*Ra
 			return nil, err
 		}
 	}
-	return s.getMsg(ctx, req)
+	return s.getMsg(ctx, req, false)
 }
 
+// GetLastMsgForSubject retrieves the last raw stream message stored on the given subject,
+// using a message get request by last subject, e.g. to read the latest value of a key.
+// If no message is stored on the subject, [ErrMsgNotFound] is returned.
 func (s *stream) GetLastMsgForSubject(ctx context.Context, subject string) (*RawStreamMsg, error) {
-	return s.getMsg(ctx, &apiMsgGetRequest{LastFor: subject})
+	return s.getMsg(ctx, &apiMsgGetRequest{LastFor: subject}, false)
 }
 
-func (s *stream) getMsg(ctx context.Context, mreq *apiMsgGetRequest) (*RawStreamMsg, error) {
+func (s *stream) GetMsgDirect(ctx context.Context, seq uint64, opts ...GetMsgOpt) (*RawStreamMsg, error) {
+	req := &apiMsgGetRequest{Seq: seq}
+	for _, opt := range opts {
+		if err := opt(req); err != nil {
+			return nil, err
+		}
+	}
+	return s.getMsg(ctx, req, true)
+}
+
+func (s *stream) GetLastMsgForSubjectDirect(ctx context.Context, subject string) (*RawStreamMsg, error) {
+	return s.getMsg(ctx, &apiMsgGetRequest{LastFor: subject}, true)
+}
+
+// getMsg retrieves a message using the stream message get API. If direct is
+// set and the stream allows direct gets, the direct get API is used instead.
+func (s *stream) getMsg(ctx context.Context, mreq *apiMsgGetRequest, direct bool) (*RawStreamMsg, error) {
 	req, err := json.Marshal(mreq)
 	if err != nil {
 		return nil, err
@@ -324,7 +433,7 @@ func (s *stream) getMsg(ctx context.Context, mreq *apiMsgGetRequest) (*RawStream
 	var gmSubj string
 
 	// handle direct gets
-	if s.info.Config.AllowDirect {
+	if direct && s.info != nil && s.info.Config.AllowDirect {
 		if mreq.LastFor != "" {
 			gmSubj = apiSubj(s.jetStream.apiPrefix, fmt.Sprintf(apiDirectMsgGetLastBySubjectT, s.name, mreq.LastFor))
 			r, err := s.jetStream.apiRequest(ctx, gmSubj, nil)
@@ -437,8 +546,8 @@ func (s *stream) DeleteMsg(ctx context.Context, seq uint64) error {
 	return s.deleteMsg(ctx, &msgDeleteRequest{Seq: seq, NoErase: true})
 }
 
-// SecureDeleteMsg deletes a message from a stream. The deleted message is overwritten with random data
-// As a result, this operation is slower than DeleteMsg()
+// SecureDeleteMsg deletes a message from a stream. The deleted message is overwritten with random data,
+// e.g. to comply with data removal requests. As a result, this operation is slower than DeleteMsg()
 func (s *stream) SecureDeleteMsg(ctx context.Context, seq uint64) error {
 	return s.deleteMsg(ctx, &msgDeleteRequest{Seq: seq})
 }
@@ -454,7 +563,10 @@ func (s *stream) deleteMsg(ctx context.Context, req *msgDeleteRequest) error {
 		return err
 	}
 	if !resp.Success {
-		return fmt.Errorf("%w: %s", ErrMsgDeleteUnsuccessful, err)
+		if resp.Error != nil {
+			return fmt.Errorf("%w: %s", ErrMsgDeleteUnsuccessful, resp.Error.Description)
+		}
+		return ErrMsgDeleteUnsuccessful
 	}
 	return nil
 }
@@ -26,12 +26,13 @@ import (
 type (
 	// StreamInfo shows config and current state for this stream.
 	StreamInfo struct {
-		Config  StreamConfig        `json:"config"`
-		Created time.Time           `json:"created"`
-		State   StreamState         `json:"state"`
-		Cluster *ClusterInfo        `json:"cluster,omitempty"`
-		Mirror  *StreamSourceInfo   `json:"mirror,omitempty"`
-		Sources []*StreamSourceInfo `json:"sources,omitempty"`
+		Config    StreamConfig        `json:"config"`
+		Created   time.Time           `json:"created"`
+		State     StreamState         `json:"state"`
+		Cluster   *ClusterInfo        `json:"cluster,omitempty"`
+		Mirror    *StreamSourceInfo   `json:"mirror,omitempty"`
+		Sources   []*StreamSourceInfo `json:"sources,omitempty"`
+		TimeStamp time.Time           `json:"ts"`
 	}
 
 	StreamConfig struct {
@@ -63,10 +64,33 @@ type (
 		// Allow republish of the message after being sequenced and stored.
 		RePublish *RePublish `json:"republish,omitempty"`
 
+		// Allow applying a subject transform to incoming messages before doing anything else.
+		SubjectTransform *SubjectTransformConfig `json:"subject_transform,omitempty"`
+
+		// Compression is the algorithm used to compress the stream storage.
+		// Requires nats-server v2.10.0 or later.
+		Compression StoreCompression `json:"compression,omitempty"`
+
 		// Allow higher performance, direct access to get individual messages. E.g. KeyValue
 		AllowDirect bool `json:"allow_direct"`
 		// Allow higher performance and unified direct access for mirrors as well.
 		MirrorDirect bool `json:"mirror_direct"`
+
+		// Metadata is additional metadata for the stream, e.g. owner or team labels.
+		// Requires nats-server v2.10.0 or later, older servers ignore it.
+		Metadata map[string]string `json:"metadata,omitempty"`
+
+		// AllowMsgTTL allows setting a TTL on individual messages using
+		// [WithMsgTTL], so they can expire before MaxAge.
+		// Requires nats-server v2.11.0 or later.
+		AllowMsgTTL bool `json:"allow_msg_ttl,omitempty"`
+
+		// SubjectDeleteMarkerTTL enables placing a marker when the last
+		// message on a subject is removed due to MaxAge or a message TTL,
+		// with the marker itself removed after the given TTL. Requires
+		// AllowMsgTTL.
+		// Requires nats-server v2.11.0 or later.
+		SubjectDeleteMarkerTTL time.Duration `json:"subject_delete_marker_ttl,omitempty"`
 	}
 
 	// StreamSourceInfo shows information about an upstream stream source.
@@ -94,29 +118,56 @@ type (
 	// ClusterInfo shows information about the underlying set of servers
 	// that make up the stream or consumer.
 	ClusterInfo struct {
-		Name     string      `json:"name,omitempty"`
-		Leader   string      `json:"leader,omitempty"`
+		// Name is the name of the cluster.
+		Name string `json:"name,omitempty"`
+		// RaftGroup is the name of the RAFT group of the stream or consumer.
+		RaftGroup string `json:"raft_group,omitempty"`
+		// Leader is the name of the server currently leading the group.
+		Leader string `json:"leader,omitempty"`
+		// LeaderSince is the time the current leader was elected.
+		LeaderSince *time.Time `json:"leader_since,omitempty"`
+		// SystemAcc is set if the request was made from the system account.
+		SystemAcc bool `json:"system_account,omitempty"`
+		// TrafficAcc is the account replication traffic is sent in.
+		TrafficAcc string `json:"traffic_account,omitempty"`
+		// Replicas are the peers other than the leader.
 		Replicas []*PeerInfo `json:"replicas,omitempty"`
 	}
 
 	// PeerInfo shows information about all the peers in the cluster that
 	// are supporting the stream or consumer.
 	PeerInfo struct {
-		Name    string        `json:"name"`
-		Current bool          `json:"current"`
-		Offline bool          `json:"offline,omitempty"`
-		Active  time.Duration `json:"active"`
-		Lag     uint64        `json:"lag,omitempty"`
+		Name string `json:"name"`
+		// Current is set if the peer is up to date with the leader.
+		Current bool `json:"current"`
+		// Observer is set if the peer does not take part in elections.
+		Observer bool `json:"observer,omitempty"`
+		Offline  bool `json:"offline,omitempty"`
+		// Active is the time since the peer was last seen.
+		Active time.Duration `json:"active"`
+		// Lag is the number of operations the peer is behind the leader.
+		Lag uint64 `json:"lag,omitempty"`
+		// Peer is the peer ID, as used when removing peers.
+		Peer string `json:"peer,omitempty"`
 	}
 
 	// RePublish is for republishing messages once committed to a stream. The original
-	// subject cis remapped from the subject pattern to the destination pattern.
+	// subject is remapped from the source pattern to the destination pattern.
+	// If HeadersOnly is set, messages are republished without payload, with
+	// the payload size in the Nats-Msg-Size header.
 	RePublish struct {
 		Source      string `json:"src,omitempty"`
 		Destination string `json:"dest"`
 		HeadersOnly bool   `json:"headers_only,omitempty"`
 	}
 
+	// SubjectTransformConfig is for applying a subject transform (to matching messages) before doing anything else
+	// when a new message is received. The subject is remapped from the source pattern to the destination pattern.
+	SubjectTransformConfig struct {
+		Source      string `json:"src,omitempty"`
+		Destination string `json:"dest"`
+	}
+
 	// Placement is used to guide placement of streams in clustered JetStream.
 	Placement struct {
 		Cluster string   `json:"cluster"`
@@ -149,6 +200,9 @@ type (
 
 	// StorageType determines how messages are stored for retention.
 	StorageType int
+
+	// StoreCompression determines how messages are compressed in storage.
+	StoreCompression int
 )
 
 const (
@@ -296,6 +350,52 @@ func (st *StorageType) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+const (
+	// NoCompression disables compression of the stream storage. It's the default.
+	NoCompression StoreCompression = iota
+	// S2Compression compresses the stream storage using s2.
+	S2Compression
+)
+
+const (
+	noCompressionString = "none"
+	s2CompressionString = "s2"
+)
+
+func (alg StoreCompression) String() string {
+	switch alg {
+	case NoCompression:
+		return "None"
+	case S2Compression:
+		return "S2"
+	default:
+		return "Unknown Store Compression"
+	}
+}
+
+func (alg StoreCompression) MarshalJSON() ([]byte, error) {
+	switch alg {
+	case NoCompression:
+		return json.Marshal(noCompressionString)
+	case S2Compression:
+		return json.Marshal(s2CompressionString)
+	default:
+		return nil, fmt.Errorf("nats: can not marshal %v", alg)
+	}
+}
+
+func (alg *StoreCompression) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case jsonString(noCompressionString):
+		*alg = NoCompression
+	case jsonString(s2CompressionString):
+		*alg = S2Compression
+	default:
+		return fmt.Errorf("nats: can not unmarshal %q", data)
+	}
+	return nil
+}
+
 func jsonString(s string) string {
 	return "\"" + s + "\""
 }
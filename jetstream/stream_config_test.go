@@ -0,0 +1,63 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestStoreCompressionJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		compression StoreCompression
+		expected    string
+	}{
+		{name: "none", compression: NoCompression},
+		{name: "s2", compression: S2Compression, expected: `"compression":"s2"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b, err := json.Marshal(StreamConfig{Name: "foo", Compression: test.compression})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if test.expected == "" && strings.Contains(string(b), "compression") {
+				t.Fatalf("Expected compression to be omitted; got: %s", b)
+			}
+			if !strings.Contains(string(b), test.expected) {
+				t.Fatalf("Expected JSON to contain %s; got: %s", test.expected, b)
+			}
+			var cfg StreamConfig
+			if err := json.Unmarshal(b, &cfg); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if cfg.Compression != test.compression {
+				t.Fatalf("Invalid compression; want: %v; got: %v", test.compression, cfg.Compression)
+			}
+		})
+	}
+
+	t.Run("invalid compression", func(t *testing.T) {
+		if _, err := json.Marshal(StreamConfig{Name: "foo", Compression: StoreCompression(5)}); err == nil {
+			t.Fatalf("Expected error")
+		}
+		var cfg StreamConfig
+		if err := json.Unmarshal([]byte(`{"compression":"lz4"}`), &cfg); err == nil {
+			t.Fatalf("Expected error")
+		}
+	})
+}
@@ -0,0 +1,191 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type (
+	// StreamTrimmerOpt configures a [StreamTrimmer].
+	StreamTrimmerOpt func(*streamTrimmerOpts) error
+
+	// StreamTrimHandler is invoked after a [StreamTrimmer] purged the stream
+	// up to and including the watermark.
+	StreamTrimHandler func(watermark, purged uint64)
+
+	streamTrimmerOpts struct {
+		interval   time.Duration
+		trimCB     StreamTrimHandler
+		errHandler func(error)
+	}
+
+	// StreamTrimmer periodically purges a stream up to the lowest ack floor
+	// of a set of consumers, i.e. removes messages acknowledged by all of
+	// them. This provides interest based retention for streams which can
+	// not use [InterestPolicy], e.g. because other consumers only read
+	// the stream occasionally.
+	//
+	// Messages are purged only once every consumer acknowledged them, so
+	// messages filtered out by all consumers are kept until each consumer
+	// acknowledged a later message.
+	StreamTrimmer struct {
+		sync.Mutex
+		stream    Stream
+		consumers []string
+		opts      streamTrimmerOpts
+		watermark uint64
+		cancel    context.CancelFunc
+		done      chan struct{}
+	}
+)
+
+// DefaultStreamTrimInterval is the default interval between purges of a [StreamTrimmer].
+const DefaultStreamTrimInterval = time.Minute
+
+// WithStreamTrimInterval sets the interval between purges.
+func WithStreamTrimInterval(interval time.Duration) StreamTrimmerOpt {
+	return func(opts *streamTrimmerOpts) error {
+		if interval <= 0 {
+			return fmt.Errorf("%w: interval must be positive", ErrInvalidOption)
+		}
+		opts.interval = interval
+		return nil
+	}
+}
+
+// WithStreamTrimHandler sets the handler invoked after each purge.
+func WithStreamTrimHandler(cb StreamTrimHandler) StreamTrimmerOpt {
+	return func(opts *streamTrimmerOpts) error {
+		opts.trimCB = cb
+		return nil
+	}
+}
+
+// WithStreamTrimErrHandler sets the handler invoked when the watermark
+// cannot be determined or the stream cannot be purged.
+func WithStreamTrimErrHandler(cb func(error)) StreamTrimmerOpt {
+	return func(opts *streamTrimmerOpts) error {
+		opts.errHandler = cb
+		return nil
+	}
+}
+
+// TrimStream starts purging the stream up to the lowest ack floor of the
+// given consumers, starting with a purge before returning. Trimming stops
+// when ctx is done or [StreamTrimmer.Stop] is called.
+//
+// Available options:
+// [WithStreamTrimInterval] - sets the interval between purges, default is 1m
+// [WithStreamTrimHandler] - sets the handler invoked after each purge
+// [WithStreamTrimErrHandler] - sets the handler for errors of periodic purges
+func TrimStream(ctx context.Context, stream Stream, consumers []string, opts ...StreamTrimmerOpt) (*StreamTrimmer, error) {
+	o := streamTrimmerOpts{interval: DefaultStreamTrimInterval}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+	if len(consumers) == 0 {
+		return nil, fmt.Errorf("%w: at least one consumer is required", ErrInvalidOption)
+	}
+	for _, name := range consumers {
+		if err := validateConsumerName(name); err != nil {
+			return nil, err
+		}
+	}
+	t := &StreamTrimmer{
+		stream:    stream,
+		consumers: append([]string(nil), consumers...),
+		opts:      o,
+		done:      make(chan struct{}),
+	}
+	if _, err := t.Trim(ctx); err != nil {
+		return nil, err
+	}
+	ctx, t.cancel = context.WithCancel(ctx)
+	go t.run(ctx)
+	return t, nil
+}
+
+// Stop stops purging the stream.
+func (t *StreamTrimmer) Stop() {
+	t.cancel()
+	<-t.done
+}
+
+// Watermark returns the last sequence the stream was purged up to.
+func (t *StreamTrimmer) Watermark() uint64 {
+	t.Lock()
+	defer t.Unlock()
+	return t.watermark
+}
+
+// Trim purges the stream up to the lowest ack floor of the consumers and
+// returns the number of purged messages. Nothing is purged if any of the
+// consumers cannot be retrieved.
+func (t *StreamTrimmer) Trim(ctx context.Context) (uint64, error) {
+	var watermark uint64
+	for i, name := range t.consumers {
+		cons, err := t.stream.Consumer(ctx, name)
+		if err != nil {
+			return 0, err
+		}
+		if floor := cons.CachedInfo().AckFloor.Stream; i == 0 || floor < watermark {
+			watermark = floor
+		}
+	}
+
+	t.Lock()
+	if watermark <= t.watermark {
+		t.Unlock()
+		return 0, nil
+	}
+	purged, err := t.stream.Purge(ctx, WithPurgeSequence(watermark+1))
+	if err != nil {
+		t.Unlock()
+		return 0, err
+	}
+	t.watermark = watermark
+	t.Unlock()
+
+	if t.opts.trimCB != nil {
+		t.opts.trimCB(watermark, purged)
+	}
+	return purged, nil
+}
+
+func (t *StreamTrimmer) run(ctx context.Context) {
+	defer close(t.done)
+	ticker := time.NewTicker(t.opts.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := t.Trim(ctx); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				if t.opts.errHandler != nil {
+					t.opts.errHandler(err)
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import (
+	"context"
+	"errors"
+)
+
+type (
+	// StuckReport is the result of [Consumer.AnalyzeStuck].
+	StuckReport struct {
+		// Info is the consumer info the analysis is based on.
+		Info *ConsumerInfo
+		// Gap is the number of stream sequences delivered past the ack floor.
+		Gap uint64
+		// Blocking is the first message past the ack floor matching the
+		// consumer filters, which is likely holding back the ack floor. It
+		// is nil if all delivered messages were acknowledged, or if the
+		// message was removed from the stream.
+		Blocking *RawStreamMsg
+		// Causes are the likely causes of the consumer not making progress.
+		Causes []StuckCause
+	}
+
+	// StuckCause is a likely cause of a consumer not making progress.
+	StuckCause int
+)
+
+const (
+	// StuckMaxAckPending means the number of messages pending
+	// acknowledgement reached MaxAckPending, so the server stopped
+	// delivering new messages until some of them are acknowledged.
+	StuckMaxAckPending StuckCause = iota
+
+	// StuckRedeliveryLoop means messages are redelivered without being
+	// acknowledged, e.g. because the handler keeps failing or negatively
+	// acknowledges them. Unless MaxDeliver is set, they are redelivered
+	// indefinitely.
+	StuckRedeliveryLoop
+
+	// StuckNoPullRequests means messages are pending but no pull requests
+	// are waiting, i.e. no client is fetching from the pull consumer.
+	StuckNoPullRequests
+)
+
+func (c StuckCause) String() string {
+	switch c {
+	case StuckMaxAckPending:
+		return "MaxAckPending"
+	case StuckRedeliveryLoop:
+		return "RedeliveryLoop"
+	case StuckNoPullRequests:
+		return "NoPullRequests"
+	default:
+		return "Unknown"
+	}
+}
+
+// Stuck reports whether any likely cause of the consumer not making
+// progress was found.
+func (r *StuckReport) Stuck() bool {
+	return len(r.Causes) > 0
+}
+
+// AnalyzeStuck compares the ack floor, delivered sequence and pending counts of the consumer
+func (p *pullConsumer) AnalyzeStuck(ctx context.Context) (*StuckReport, error) {
+	return analyzeStuck(ctx, p.jetStream, p.stream, p.Info)
+}
+
+// AnalyzeStuck compares the ack floor, delivered sequence and pending counts of the current underlying consumer
+func (c *orderedConsumer) AnalyzeStuck(ctx context.Context) (*StuckReport, error) {
+	return analyzeStuck(ctx, c.jetStream, c.stream, c.Info)
+}
+
+func analyzeStuck(ctx context.Context, js *jetStream, streamName string, info func(context.Context) (*ConsumerInfo, error)) (*StuckReport, error) {
+	ci, err := info(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report := &StuckReport{Info: ci}
+	cfg := ci.Config
+
+	if cfg.AckPolicy != AckNonePolicy {
+		if ci.Delivered.Stream > ci.AckFloor.Stream {
+			report.Gap = ci.Delivered.Stream - ci.AckFloor.Stream
+			s := &stream{name: streamName, jetStream: js}
+			report.Blocking, err = firstMsgAfter(ctx, s, ci.AckFloor.Stream, consumerFilters(cfg))
+			if err != nil {
+				return nil, err
+			}
+		}
+		if cfg.MaxAckPending > 0 && ci.NumAckPending >= cfg.MaxAckPending {
+			report.Causes = append(report.Causes, StuckMaxAckPending)
+		}
+		if ci.NumRedelivered > 0 {
+			report.Causes = append(report.Causes, StuckRedeliveryLoop)
+		}
+	}
+	if cfg.DeliverSubject == "" && ci.NumPending > 0 && ci.NumWaiting == 0 {
+		report.Causes = append(report.Causes, StuckNoPullRequests)
+	}
+	return report, nil
+}
+
+// firstMsgAfter returns the first message after seq on any of the subjects,
+// or nil if there is none.
+func firstMsgAfter(ctx context.Context, s *stream, seq uint64, filters []string) (*RawStreamMsg, error) {
+	var first *RawStreamMsg
+	for _, filter := range filters {
+		msg, err := s.getMsg(ctx, &apiMsgGetRequest{Seq: seq + 1, NextFor: filter}, false)
+		if errors.Is(err, ErrMsgNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if first == nil || msg.Sequence < first.Sequence {
+			first = msg
+		}
+	}
+	return first, nil
+}
@@ -0,0 +1,127 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+func TestAudit(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := jetstream.Audit(ctx, js, jetstream.WithAuditIdleThreshold(0)); !errors.Is(err, jetstream.ErrInvalidOption) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidOption, err)
+	}
+
+	orders, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "ORDERS", Subjects: []string{"ORDERS.*"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "EVENTS", Subjects: []string{"EVENTS.*"}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cons, err := orders.AddConsumer(ctx, jetstream.ConsumerConfig{Durable: "processor", AckPolicy: jetstream.AckExplicitPolicy})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	kv, err := js.CreateKeyValue(ctx, jetstream.KeyValueConfig{Bucket: "config"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := kv.Put(ctx, "key", []byte("value")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, subj := range []string{"ORDERS.new", "EVENTS.new"} {
+		if _, err := js.Publish(ctx, subj, []byte("msg")); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	consume := func(t *testing.T) {
+		t.Helper()
+		msg, err := cons.Next(jetstream.FetchMaxWait(time.Second))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := msg.Ack(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	consume(t)
+
+	t.Run("nothing idle within default threshold", func(t *testing.T) {
+		report, err := jetstream.Audit(ctx, js)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(report.IdleStreams) != 0 || len(report.IdleConsumers) != 0 || len(report.IdleBuckets) != 0 {
+			t.Fatalf("Expected empty report; got: %+v", report)
+		}
+	})
+
+	time.Sleep(200 * time.Millisecond)
+
+	t.Run("idle resources", func(t *testing.T) {
+		report, err := jetstream.Audit(ctx, js, jetstream.WithAuditIdleThreshold(100*time.Millisecond))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(report.IdleStreams) != 1 || report.IdleStreams[0].Name != "EVENTS" || report.IdleStreams[0].Msgs != 1 {
+			t.Fatalf("Expected EVENTS stream to be idle; got: %+v", report.IdleStreams)
+		}
+		if len(report.IdleConsumers) != 1 || report.IdleConsumers[0].Stream != "ORDERS" || report.IdleConsumers[0].Name != "processor" {
+			t.Fatalf("Expected processor consumer to be idle; got: %+v", report.IdleConsumers)
+		}
+		if report.IdleConsumers[0].LastActive.IsZero() {
+			t.Fatalf("Expected last activity of consumer to be set")
+		}
+		if len(report.IdleBuckets) != 1 || report.IdleBuckets[0].Bucket != "config" || report.IdleBuckets[0].Values != 1 {
+			t.Fatalf("Expected config bucket to be idle; got: %+v", report.IdleBuckets)
+		}
+	})
+
+	t.Run("active consumer", func(t *testing.T) {
+		if _, err := js.Publish(ctx, "ORDERS.new", []byte("msg")); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		consume(t)
+		report, err := jetstream.Audit(ctx, js, jetstream.WithAuditIdleThreshold(100*time.Millisecond))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(report.IdleConsumers) != 0 {
+			t.Fatalf("Expected no idle consumers; got: %+v", report.IdleConsumers)
+		}
+		if len(report.IdleStreams) != 1 {
+			t.Fatalf("Expected EVENTS stream to be idle; got: %+v", report.IdleStreams)
+		}
+	})
+}
@@ -0,0 +1,108 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+func TestBroadcast(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, name := range []string{"EMAIL", "SMS"} {
+		if _, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: name, Subjects: []string{name}}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	if _, err := jetstream.NewBroadcaster(js, nil); !errors.Is(err, jetstream.ErrInvalidOption) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidOption, err)
+	}
+
+	// PUSH is not bound to a stream until it is created below.
+	b, err := jetstream.NewBroadcaster(js, []jetstream.Destination{
+		{Subject: "EMAIL"},
+		{Subject: "SMS"},
+		{Subject: "PUSH", Retry: &jetstream.RetryPolicy{Attempts: 20, Wait: 50 * time.Millisecond}},
+	}, jetstream.WithBroadcastConcurrency(2))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	msg := nats.NewMsg("")
+	msg.Data = []byte("hello")
+	msg.Header.Set("type", "greeting")
+
+	t.Run("partial failure", func(t *testing.T) {
+		noRetry, err := jetstream.NewBroadcaster(js, []jetstream.Destination{{Subject: "EMAIL"}, {Subject: "PUSH"}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		res, err := noRetry.Broadcast(ctx, msg)
+		if !errors.Is(err, jetstream.ErrBroadcastFailed) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrBroadcastFailed, err)
+		}
+		if len(res.Acks) != 1 || res.Acks["EMAIL"] == nil {
+			t.Fatalf("Expected ack from EMAIL, got: %v", res.Acks)
+		}
+		if !errors.Is(res.Errors["PUSH"], jetstream.ErrNoStreamResponse) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrNoStreamResponse, res.Errors["PUSH"])
+		}
+	})
+
+	t.Run("retried destination", func(t *testing.T) {
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			js.CreateStream(ctx, jetstream.StreamConfig{Name: "PUSH", Subjects: []string{"PUSH"}})
+		}()
+		res, err := b.Broadcast(ctx, msg)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v, %v", err, res.Errors)
+		}
+		for _, subject := range []string{"EMAIL", "SMS", "PUSH"} {
+			if res.Acks[subject] == nil || res.Acks[subject].Stream != subject {
+				t.Fatalf("Unexpected ack for %q: %+v", subject, res.Acks[subject])
+			}
+			s, err := js.Stream(ctx, subject)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			m, err := s.GetLastMsgForSubject(ctx, subject)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(m.Data) != "hello" || m.Header.Get("type") != "greeting" {
+				t.Fatalf("Unexpected message on %q: %+v", subject, m)
+			}
+		}
+	})
+}
@@ -0,0 +1,144 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+func TestClockSkew(t *testing.T) {
+	// stream info is served by a responder with a skewed clock,
+	// as the server time is only included by nats-server 2.10+
+	srv := RunServerOnPort(-1)
+	defer srv.Shutdown()
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+
+	const skew = time.Hour
+	sub, err := nc.Subscribe("$JS.API.STREAM.INFO.foo", func(msg *nats.Msg) {
+		resp, _ := json.Marshal(map[string]interface{}{
+			"type":   "io.nats.jetstream.api.v1.stream_info_response",
+			"config": map[string]interface{}{"name": "foo", "subjects": []string{"FOO.*"}},
+			"ts":     time.Now().Add(skew),
+		})
+		msg.Respond(resp)
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	if _, err := jetstream.New(nc, jetstream.WithClockSkewHandler(0, func(jetstream.ClockSkew) {})); !errors.Is(err, jetstream.ErrInvalidOption) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidOption, err)
+	}
+	if _, err := jetstream.New(nc, jetstream.WithClockSkewHandler(time.Second, nil)); !errors.Is(err, jetstream.ErrInvalidOption) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidOption, err)
+	}
+
+	warnings := make(chan jetstream.ClockSkew, 10)
+	js, err := jetstream.New(nc, jetstream.WithClockSkewHandler(time.Minute, func(skew jetstream.ClockSkew) {
+		warnings <- skew
+	}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if estimate := js.ClockSkew(); estimate.Samples != 0 {
+		t.Fatalf("Expected no samples; got: %+v", estimate)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i := 0; i < 3; i++ {
+		s, err := js.Stream(ctx, "foo")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if s.CachedInfo().TimeStamp.IsZero() {
+			t.Fatalf("Expected server time to be set")
+		}
+	}
+
+	estimate := js.ClockSkew()
+	if estimate.Samples != 3 {
+		t.Fatalf("Expected 3 samples; got: %d", estimate.Samples)
+	}
+	if diff := estimate.Skew - skew; diff > estimate.RTT || diff < -estimate.RTT {
+		t.Fatalf("Expected skew of %v within %v; got: %v", skew, estimate.RTT, estimate.Skew)
+	}
+	if estimate.Updated.IsZero() {
+		t.Fatalf("Expected update time to be set")
+	}
+	select {
+	case warning := <-warnings:
+		if warning.Skew < time.Minute {
+			t.Fatalf("Unexpected warning: %+v", warning)
+		}
+	default:
+		t.Fatalf("Expected clock skew warning")
+	}
+}
+
+func TestClockSkewNotExceeded(t *testing.T) {
+	srv := RunServerOnPort(-1)
+	defer srv.Shutdown()
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+
+	sub, err := nc.Subscribe("$JS.API.CONSUMER.INFO.foo.cons", func(msg *nats.Msg) {
+		resp, _ := json.Marshal(map[string]interface{}{
+			"type":        "io.nats.jetstream.api.v1.consumer_info_response",
+			"stream_name": "foo",
+			"name":        "cons",
+			"config":      map[string]interface{}{"durable_name": "cons"},
+			"ts":          time.Now(),
+		})
+		msg.Respond(resp)
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	js, err := jetstream.New(nc, jetstream.WithClockSkewHandler(time.Minute, func(skew jetstream.ClockSkew) {
+		t.Errorf("Unexpected clock skew warning: %+v", skew)
+	}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := js.Consumer(ctx, "foo", "cons"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	estimate := js.ClockSkew()
+	if estimate.Samples != 1 {
+		t.Fatalf("Expected 1 sample; got: %d", estimate.Samples)
+	}
+	if estimate.Skew > time.Second || estimate.Skew < -time.Second {
+		t.Fatalf("Expected no skew; got: %v", estimate.Skew)
+	}
+}
@@ -15,7 +15,9 @@ package test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"reflect"
 	"testing"
 	"time"
 
@@ -180,3 +182,655 @@ func TestConsumerCachedInfo(t *testing.T) {
 	}
 
 }
+
+func TestInstrumentHandler(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	c, err := s.AddConsumer(ctx, jetstream.ConsumerConfig{Durable: "cons", AckPolicy: jetstream.AckExplicitPolicy})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	obs := make(chan nats.HandlerObservation, 10)
+	handler := jetstream.InstrumentHandler(func(msg jetstream.Msg) {
+		msg.Ack()
+		if msg.Subject() == "FOO.panic" {
+			panic("bad message")
+		}
+	}, nats.HandlerMetricsFunc(func(o nats.HandlerObservation) {
+		obs <- o
+	}), nil)
+
+	cc, err := c.Consume(handler)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer cc.Stop()
+
+	if _, err := js.Publish(ctx, "FOO.panic", []byte("abc")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := js.Publish(ctx, "FOO.ok", []byte("abcdef")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, exp := range []nats.HandlerObservation{
+		{Subject: "FOO.panic", PayloadSize: 3, Outcome: nats.HandlerPanicked},
+		{Subject: "FOO.ok", PayloadSize: 6, Outcome: nats.HandlerOK},
+	} {
+		select {
+		case o := <-obs:
+			if o.Subject != exp.Subject || o.PayloadSize != exp.PayloadSize || o.Outcome != exp.Outcome {
+				t.Fatalf("Expected observation %+v; got: %+v", exp, o)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Did not receive observation for %q", exp.Subject)
+		}
+	}
+}
+
+func TestConsumerWatchInfo(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	c, err := s.AddConsumer(ctx, jetstream.ConsumerConfig{Durable: "cons", AckPolicy: jetstream.AckExplicitPolicy})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := c.WatchInfo(ctx, 0); !errors.Is(err, jetstream.ErrInvalidOption) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidOption, err)
+	}
+	events, err := c.WatchInfo(ctx, 20*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	next := func() jetstream.ConsumerInfoEvent {
+		t.Helper()
+		select {
+		case e, ok := <-events:
+			if !ok {
+				t.Fatalf("Events channel closed")
+			}
+			return e
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Did not receive event")
+		}
+		return nil
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := js.Publish(ctx, "FOO.A", []byte("msg")); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	var pending *jetstream.ConsumerPendingEvent
+	for pending == nil || pending.To.NumPending != 3 {
+		e, ok := next().(*jetstream.ConsumerPendingEvent)
+		if !ok {
+			t.Fatalf("Expected pending event; got: %T", e)
+		}
+		pending = e
+	}
+
+	msgs, err := c.Fetch(3)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for msg := range msgs.Messages() {
+		if err := msg.DoubleAck(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	var delivered *jetstream.ConsumerDeliveredEvent
+	var ackFloor *jetstream.ConsumerAckFloorEvent
+	for delivered == nil || delivered.To.Stream != 3 || ackFloor == nil || ackFloor.To.Stream != 3 {
+		switch e := next().(type) {
+		case *jetstream.ConsumerDeliveredEvent:
+			delivered = e
+		case *jetstream.ConsumerAckFloorEvent:
+			ackFloor = e
+		case *jetstream.ConsumerPendingEvent:
+		default:
+			t.Fatalf("Unexpected event: %T", e)
+		}
+	}
+	if ackFloor.ConsumerInfo().AckFloor.Stream != 3 {
+		t.Fatalf("Expected ack floor 3; got: %d", ackFloor.ConsumerInfo().AckFloor.Stream)
+	}
+
+	if err := s.DeleteConsumer(ctx, "cons"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for {
+		e := next()
+		if errEvent, ok := e.(*jetstream.ConsumerInfoErrorEvent); ok {
+			if !errors.Is(errEvent.Err, jetstream.ErrConsumerNotFound) {
+				t.Fatalf("Expected error: %v; got: %v", jetstream.ErrConsumerNotFound, errEvent.Err)
+			}
+			break
+		}
+	}
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Fatalf("Expected events channel to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Events channel was not closed")
+	}
+}
+
+func TestConsumerClientIdentity(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL(), nats.Identity(nats.ClientIdentity{Service: "orders", Version: "1.2.0", Instance: "pod-1"}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	reqs, err := nc.SubscribeSync("$JS.API.CONSUMER.CREATE.foo.>")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	metadata := map[string]string{"team": "billing", nats.IdentityInstanceKey: "custom"}
+	if _, err := s.AddConsumer(ctx, jetstream.ConsumerConfig{Durable: "cons", Metadata: metadata}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(metadata) != 2 {
+		t.Fatalf("Consumer config metadata should not be modified: %v", metadata)
+	}
+	msg, err := reqs.NextMsg(time.Second)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var req struct {
+		Config jetstream.ConsumerConfig `json:"config"`
+	}
+	if err := json.Unmarshal(msg.Data, &req); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := map[string]string{
+		"team":                   "billing",
+		nats.IdentityServiceKey:  "orders",
+		nats.IdentityVersionKey:  "1.2.0",
+		nats.IdentityInstanceKey: "custom",
+	}
+	if !reflect.DeepEqual(req.Config.Metadata, expected) {
+		t.Fatalf("Expected metadata: %v; got: %v", expected, req.Config.Metadata)
+	}
+}
+
+func TestConsumerReplicas(t *testing.T) {
+	stream := jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}, Retention: jetstream.InterestPolicy, Replicas: 3}
+	withJSClusterAndStream(t, "R3S", 3, stream, func(t *testing.T, _ string, srvs ...*jsServer) {
+		nc, err := nats.Connect(srvs[0].ClientURL())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer nc.Close()
+		js, err := jetstream.New(nc)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if _, err := js.AddConsumer(ctx, "foo", jetstream.ConsumerConfig{Durable: "cons", Replicas: 5}); !errors.Is(err, jetstream.ErrConsumerReplicasExceedsStream) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrConsumerReplicasExceedsStream, err)
+		}
+		if _, err := js.AddConsumer(ctx, "foo", jetstream.ConsumerConfig{Durable: "cons", Replicas: 1}); !errors.Is(err, jetstream.ErrConsumerReplicasShouldMatchStream) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrConsumerReplicasShouldMatchStream, err)
+		}
+		c, err := js.AddConsumer(ctx, "foo", jetstream.ConsumerConfig{Durable: "cons", Replicas: 3, MemoryStorage: true})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if cfg := c.CachedInfo().Config; cfg.Replicas != 3 || !cfg.MemoryStorage {
+			t.Fatalf("Unexpected consumer config: %+v", cfg)
+		}
+	})
+}
+
+func TestEphemeralConsumerTuning(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := s.AddConsumer(ctx, jetstream.ConsumerConfig{MaxRequestExpires: time.Microsecond}); !errors.Is(err, jetstream.ErrInvalidConsumerConfig) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidConsumerConfig, err)
+	}
+	if _, err := s.AddConsumer(ctx, jetstream.ConsumerConfig{DeliverSubject: "deliver", MaxRequestBatch: 10}); !errors.Is(err, jetstream.ErrInvalidConsumerConfig) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidConsumerConfig, err)
+	}
+
+	c, err := s.AddConsumer(ctx, jetstream.ConsumerConfig{
+		Replicas:           1,
+		MemoryStorage:      true,
+		InactiveThreshold:  time.Minute,
+		MaxRequestBatch:    10,
+		MaxRequestExpires:  time.Second,
+		MaxRequestMaxBytes: 1024,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cfg := c.CachedInfo().Config
+	if cfg.Replicas != 1 || !cfg.MemoryStorage || cfg.InactiveThreshold != time.Minute {
+		t.Fatalf("Unexpected consumer config: %+v", cfg)
+	}
+	if cfg.MaxRequestBatch != 10 || cfg.MaxRequestExpires != time.Second || cfg.MaxRequestMaxBytes != 1024 {
+		t.Fatalf("Unexpected consumer config: %+v", cfg)
+	}
+}
+
+func TestMigrateConsumerStorage(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := js.Publish(ctx, "FOO.A", []byte("msg")); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	c, err := s.AddConsumer(ctx, jetstream.ConsumerConfig{Durable: "cons", AckPolicy: jetstream.AckExplicitPolicy})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// ack floor at 3, message 4 delivered but not acknowledged
+	msgs, err := c.Fetch(4)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var i int
+	for msg := range msgs.Messages() {
+		if i++; i < 4 {
+			if err := msg.DoubleAck(ctx); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+	}
+
+	if _, err := jetstream.MigrateConsumerStorage(ctx, js, "foo", "missing", jetstream.MemoryStorage); !errors.Is(err, jetstream.ErrConsumerNotFound) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrConsumerNotFound, err)
+	}
+	migrated, err := jetstream.MigrateConsumerStorage(ctx, js, "foo", "cons", jetstream.MemoryStorage)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	info, err := migrated.Info(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !info.Config.MemoryStorage || info.Config.AckPolicy != jetstream.AckExplicitPolicy {
+		t.Fatalf("Unexpected consumer config: %+v", info.Config)
+	}
+	if info.NumPending != 7 {
+		t.Fatalf("Expected 7 pending messages; got: %d", info.NumPending)
+	}
+	msg, err := migrated.Next()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	meta, err := msg.Metadata()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if meta.Sequence.Stream != 4 {
+		t.Fatalf("Expected redelivery of message 4; got: %d", meta.Sequence.Stream)
+	}
+
+	// already stored in memory
+	same, err := jetstream.MigrateConsumerStorage(ctx, js, "foo", "cons", jetstream.MemoryStorage)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if same.CachedInfo().Delivered.Consumer != 1 {
+		t.Fatalf("Expected consumer not to be recreated; got: %+v", same.CachedInfo().Delivered)
+	}
+}
+
+func TestMigratePushConsumer(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := js.Publish(ctx, "FOO.A", []byte("msg")); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	// ack floor at 3, message 4 delivered but not acknowledged
+	createPushConsumer := func(t *testing.T, name string) {
+		t.Helper()
+		deliver := nats.NewInbox()
+		sub, err := nc.SubscribeSync(deliver)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer sub.Unsubscribe()
+		_, err = s.AddConsumer(ctx, jetstream.ConsumerConfig{
+			Durable:        name,
+			AckPolicy:      jetstream.AckExplicitPolicy,
+			DeliverSubject: deliver,
+			FlowControl:    true,
+			Heartbeat:      time.Second,
+			MaxAckPending:  4,
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for i := 0; i < 4; i++ {
+			msg, err := sub.NextMsg(time.Second)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if i < 3 {
+				if err := msg.AckSync(); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+		}
+	}
+	checkMigrated := func(t *testing.T, c jetstream.Consumer, name string) {
+		t.Helper()
+		info, err := c.Info(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if info.Name != name || info.Config.DeliverSubject != "" || info.Config.FlowControl || info.Config.Heartbeat != 0 {
+			t.Fatalf("Unexpected consumer config: %+v", info.Config)
+		}
+		if info.Config.MaxAckPending != 4 {
+			t.Fatalf("Expected max ack pending to be preserved; got: %d", info.Config.MaxAckPending)
+		}
+		if info.NumPending != 7 {
+			t.Fatalf("Expected 7 pending messages; got: %d", info.NumPending)
+		}
+		msg, err := c.Next()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		meta, err := msg.Metadata()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if meta.Sequence.Stream != 4 {
+			t.Fatalf("Expected redelivery of message 4; got: %d", meta.Sequence.Stream)
+		}
+	}
+
+	t.Run("same name", func(t *testing.T) {
+		createPushConsumer(t, "push")
+		c, err := jetstream.MigratePushConsumer(ctx, js, "foo", "push", "")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		checkMigrated(t, c, "push")
+	})
+
+	t.Run("new name", func(t *testing.T) {
+		createPushConsumer(t, "old")
+		c, err := jetstream.MigratePushConsumer(ctx, js, "foo", "old", "new")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		checkMigrated(t, c, "new")
+		if _, err := s.Consumer(ctx, "old"); !errors.Is(err, jetstream.ErrConsumerNotFound) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrConsumerNotFound, err)
+		}
+	})
+
+	t.Run("not a push consumer", func(t *testing.T) {
+		if _, err := jetstream.MigratePushConsumer(ctx, js, "foo", "new", ""); !errors.Is(err, jetstream.ErrInvalidOption) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidOption, err)
+		}
+	})
+
+	t.Run("consumer not found", func(t *testing.T) {
+		if _, err := jetstream.MigratePushConsumer(ctx, js, "foo", "missing", ""); !errors.Is(err, jetstream.ErrConsumerNotFound) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrConsumerNotFound, err)
+		}
+	})
+}
+
+func TestConsumerInterestCheck(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	var reports []*jetstream.InterestReport
+	js, err := jetstream.New(nc, jetstream.WithInterestCheck(func(report *jetstream.InterestReport) error {
+		reports = append(reports, report)
+		if len(report.Overlapping) > 0 {
+			return report.Err()
+		}
+		return nil
+	}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s, err := js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:      "jobs",
+		Subjects:  []string{"jobs.email", "jobs.sms"},
+		Retention: jetstream.WorkQueuePolicy,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// "jobs.sms" is not consumed yet, which is reported
+	if _, err := s.AddConsumer(ctx, jetstream.ConsumerConfig{Durable: "email", FilterSubject: "jobs.email"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(reports) != 1 || !reflect.DeepEqual(reports[0].Uncovered, []string{"jobs.sms"}) {
+		t.Fatalf("Unexpected reports: %+v", reports)
+	}
+
+	// rejected by the handler before reaching the server
+	_, err = s.AddConsumer(ctx, jetstream.ConsumerConfig{Durable: "all"})
+	if !errors.Is(err, jetstream.ErrUnsafeInterestConsumers) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrUnsafeInterestConsumers, err)
+	}
+	if report := reports[1]; !reflect.DeepEqual(report.Overlapping, [][2]string{{"all", "email"}}) {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+
+	// all subjects consumed, the handler is not invoked
+	if _, err := s.AddConsumer(ctx, jetstream.ConsumerConfig{Durable: "sms", FilterSubject: "jobs.sms"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// updating a consumer replaces its previous filter
+	if _, err := s.UpdateConsumer(ctx, jetstream.ConsumerConfig{Durable: "email", FilterSubject: "jobs.email", Description: "emails"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(reports) != 2 {
+		t.Fatalf("Unexpected reports: %+v", reports[2:])
+	}
+
+	report, err := jetstream.CheckStreamInterest(ctx, s)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !report.OK() {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+}
+
+func TestConsumerAnalyzeStuck(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "orders", Subjects: []string{"orders.*"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := js.Publish(ctx, "orders.new", []byte("new")); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := js.Publish(ctx, "orders.paid", []byte("paid")); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	c, err := s.AddConsumer(ctx, jetstream.ConsumerConfig{
+		Durable:       "cons",
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		FilterSubject: "orders.paid",
+		MaxAckPending: 2,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	report, err := c.AnalyzeStuck(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if report.Gap != 0 || report.Blocking != nil {
+		t.Fatalf("Expected no gap; got: %d, %v", report.Gap, report.Blocking)
+	}
+	if !reflect.DeepEqual(report.Causes, []jetstream.StuckCause{jetstream.StuckNoPullRequests}) {
+		t.Fatalf("Unexpected causes: %v", report.Causes)
+	}
+
+	// the first message is left unacknowledged, the second one is redelivered
+	msgs, err := c.Fetch(2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var fetched []jetstream.Msg
+	for msg := range msgs.Messages() {
+		fetched = append(fetched, msg)
+	}
+	if len(fetched) != 2 {
+		t.Fatalf("Expected 2 messages; got: %d", len(fetched))
+	}
+	if err := fetched[1].Nak(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	msg, err := c.Next()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if meta, _ := msg.Metadata(); meta.NumDelivered != 2 {
+		t.Fatalf("Expected redelivered message; got: %+v", meta)
+	}
+
+	report, err = c.AnalyzeStuck(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if report.Gap != 4 {
+		t.Fatalf("Expected gap of 4; got: %d", report.Gap)
+	}
+	if report.Blocking == nil || report.Blocking.Sequence != 2 || string(report.Blocking.Data) != "paid" {
+		t.Fatalf("Unexpected blocking message: %+v", report.Blocking)
+	}
+	expected := []jetstream.StuckCause{jetstream.StuckMaxAckPending, jetstream.StuckRedeliveryLoop, jetstream.StuckNoPullRequests}
+	if !reflect.DeepEqual(report.Causes, expected) {
+		t.Fatalf("Expected causes: %v; got: %v", expected, report.Causes)
+	}
+	if !report.Stuck() {
+		t.Fatalf("Expected consumer to be stuck")
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -323,3 +324,37 @@ func restartBasicJSServer(t *testing.T, s *server.Server) *server.Server {
 	s.WaitForShutdown()
 	return RunServerWithOptions(opts)
 }
+
+// pausableDialer dials connections whose reads can be paused, e.g. to
+// simulate missed heartbeats without disconnecting.
+type pausableDialer struct {
+	paused int32
+}
+
+func (d *pausableDialer) Dial(network, address string) (net.Conn, error) {
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return &pausableConn{Conn: conn, dialer: d}, nil
+}
+
+func (d *pausableDialer) pause(paused bool) {
+	var v int32
+	if paused {
+		v = 1
+	}
+	atomic.StoreInt32(&d.paused, v)
+}
+
+type pausableConn struct {
+	net.Conn
+	dialer *pausableDialer
+}
+
+func (c *pausableConn) Read(b []byte) (int, error) {
+	for atomic.LoadInt32(&c.dialer.paused) == 1 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return c.Conn.Read(b)
+}
@@ -0,0 +1,183 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+func TestIdempotentPublisher(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("hash of subject and payload", func(t *testing.T) {
+		p, err := jetstream.NewIdempotentPublisher(js)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		ack, err := p.Publish(ctx, "FOO.A", []byte("msg"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		dup, err := p.Publish(ctx, "FOO.A", []byte("msg"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !dup.Duplicate || dup.Sequence != ack.Sequence {
+			t.Fatalf("Expected duplicate of sequence %d; got: %+v", ack.Sequence, dup)
+		}
+		for _, msg := range []*nats.Msg{
+			{Subject: "FOO.A", Data: []byte("other")},
+			{Subject: "FOO.B", Data: []byte("msg")},
+		} {
+			ack, err := p.PublishMsg(ctx, msg)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if ack.Duplicate {
+				t.Fatalf("Unexpected duplicate: %+v", ack)
+			}
+			if msg.Header != nil {
+				t.Fatalf("Expected message not to be modified; got headers: %v", msg.Header)
+			}
+		}
+
+		fut, err := p.PublishAsync(ctx, "FOO.A", []byte("msg"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		select {
+		case ack := <-fut.Ok():
+			if !ack.Duplicate {
+				t.Fatalf("Expected duplicate; got: %+v", ack)
+			}
+		case err := <-fut.Err():
+			t.Fatalf("Unexpected error: %v", err)
+		case <-time.After(time.Second):
+			t.Fatalf("Did not receive ack")
+		}
+
+		results, err := p.PublishBatch(ctx, []*nats.Msg{
+			{Subject: "FOO.A", Data: []byte("msg")},
+			{Subject: "FOO.A", Data: []byte("batch")},
+			{Subject: "FOO.A", Data: []byte("batch")},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !results[0].Ack.Duplicate || results[1].Ack.Duplicate || !results[2].Ack.Duplicate {
+			t.Fatalf("Unexpected results: %+v %+v %+v", results[0].Ack, results[1].Ack, results[2].Ack)
+		}
+	})
+
+	t.Run("with message ID func", func(t *testing.T) {
+		p, err := jetstream.NewIdempotentPublisher(js, jetstream.WithMsgIDFunc(func(msg *nats.Msg) (string, error) {
+			key := msg.Header.Get("Order-Id")
+			if key == "" {
+				return "", errors.New("missing order id")
+			}
+			return "order-" + key, nil
+		}))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		msg := nats.NewMsg("FOO.C")
+		msg.Header.Set("Order-Id", "1")
+		msg.Data = []byte("created")
+		ack, err := p.PublishMsg(ctx, msg)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		msg.Data = []byte("created, retried with different payload")
+		dup, err := p.PublishMsg(ctx, msg)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !dup.Duplicate || dup.Sequence != ack.Sequence {
+			t.Fatalf("Expected duplicate of sequence %d; got: %+v", ack.Sequence, dup)
+		}
+		if _, err := p.Publish(ctx, "FOO.C", []byte("no key")); err == nil || err.Error() != "missing order id" {
+			t.Fatalf("Expected key func error; got: %v", err)
+		}
+
+		// message ID set by the caller is kept
+		msg = nats.NewMsg("FOO.C")
+		msg.Header.Set(jetstream.MsgIDHeader, "custom")
+		if _, err := p.PublishMsg(ctx, msg); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		dup, err = p.PublishMsg(ctx, msg)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !dup.Duplicate {
+			t.Fatalf("Expected duplicate; got: %+v", dup)
+		}
+	})
+
+	t.Run("with expected stream", func(t *testing.T) {
+		p, err := jetstream.NewIdempotentPublisher(js, jetstream.WithIdempotentExpectStream("foo"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := p.Publish(ctx, "FOO.D", []byte("msg")); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		p, err = jetstream.NewIdempotentPublisher(js, jetstream.WithIdempotentExpectStream("bar"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var apiErr *jetstream.APIError
+		if _, err := p.Publish(ctx, "FOO.D", []byte("msg")); !errors.As(err, &apiErr) || apiErr.ErrorCode != 10060 {
+			t.Fatalf("Expected stream not match error; got: %v", err)
+		}
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		if _, err := jetstream.NewIdempotentPublisher(js, jetstream.WithMsgIDFunc(nil)); !errors.Is(err, jetstream.ErrInvalidOption) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidOption, err)
+		}
+		if _, err := jetstream.NewIdempotentPublisher(js, jetstream.WithIdempotentExpectStream("a.b")); !errors.Is(err, jetstream.ErrInvalidStreamName) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidStreamName, err)
+		}
+		p, err := jetstream.NewIdempotentPublisher(js, jetstream.WithMsgIDFunc(func(*nats.Msg) (string, error) { return "", nil }))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := p.Publish(ctx, "FOO.A", nil); !errors.Is(err, jetstream.ErrInvalidOption) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidOption, err)
+		}
+	})
+}
@@ -15,9 +15,11 @@ package test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -199,12 +201,301 @@ func TestWithClientTrace(t *testing.T) {
 	defer nc.Close()
 }
 
+func TestClientTraceRequestCompleted(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+
+	type roundTrip struct {
+		subj              string
+		request, response []byte
+		elapsed           time.Duration
+		err               error
+	}
+	var trips []roundTrip
+	js, err := jetstream.New(nc, jetstream.WithClientTrace(&jetstream.ClientTrace{
+		RequestCompleted: func(subj string, request, response []byte, elapsed time.Duration, err error) {
+			trips = append(trips, roundTrip{subj, request, response, elapsed, err})
+		},
+	}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := s.AddConsumer(ctx, jetstream.ConsumerConfig{Durable: "cons"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(trips) != 2 {
+		t.Fatalf("Expected 2 round trips; got %d", len(trips))
+	}
+	expected := []string{"$JS.API.STREAM.CREATE.foo", "$JS.API.CONSUMER.CREATE.foo.cons"}
+	for i, trip := range trips {
+		if trip.subj != expected[i] {
+			t.Fatalf("Expected subject %q; got %q", expected[i], trip.subj)
+		}
+		if trip.err != nil {
+			t.Fatalf("Unexpected error: %v", trip.err)
+		}
+		if !json.Valid(trip.request) || !json.Valid(trip.response) {
+			t.Fatalf("Expected JSON payloads; got request %q and response %q", trip.request, trip.response)
+		}
+		if trip.elapsed <= 0 {
+			t.Fatalf("Expected positive elapsed time; got %v", trip.elapsed)
+		}
+	}
+	if !strings.Contains(string(trips[0].request), `"name":"foo"`) || !strings.Contains(string(trips[0].response), `"created"`) {
+		t.Fatalf("Unexpected stream create round trip: %q, %q", trips[0].request, trips[0].response)
+	}
+}
+
+func TestTraceContextPropagation(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// observe API requests next to the server
+	requests, err := nc.SubscribeSync("$JS.API.STREAM.INFO.>")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := nc.Flush(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	origin := nats.NewMsg("FOO.1")
+	origin.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	origin.Header.Set("Foo", "bar")
+	if _, err := s.Info(nats.TraceContext(ctx, origin.Header)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	req, err := requests.NextMsg(time.Second)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if req.Header.Get("traceparent") != origin.Header.Get("traceparent") || req.Header.Get("Foo") != "" {
+		t.Fatalf("Unexpected request headers: %v", req.Header)
+	}
+
+	// requests without trace context do not have headers
+	if _, err := s.Info(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	req, err = requests.NextMsg(time.Second)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(req.Header) != 0 {
+		t.Fatalf("Expected no request headers; got: %v", req.Header)
+	}
+}
+
+func TestWithTimeouts(t *testing.T) {
+	srv := RunDefaultServer()
+	defer srv.Shutdown()
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+
+	// Subscribe to the API without responding, so that requests without
+	// a deadline would otherwise block forever.
+	if _, err := nc.Subscribe("$JS.API.>", func(*nats.Msg) {}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := jetstream.New(nc, jetstream.WithTimeouts(jetstream.Timeouts{Get: -1})); !errors.Is(err, jetstream.ErrInvalidOption) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidOption, err)
+	}
+
+	// Without timeouts, requests wait until the context is canceled.
+	noTimeouts, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(3*time.Second, cancel)
+	if _, err := noTimeouts.AccountInfo(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected error: %v; got: %v", context.Canceled, err)
+	}
+
+	js, err := jetstream.New(nc, jetstream.WithTimeouts(jetstream.Timeouts{
+		Get:    50 * time.Millisecond,
+		Create: 200 * time.Millisecond,
+	}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		call    func(context.Context) error
+		timeout time.Duration
+	}{
+		{
+			name: "get",
+			call: func(ctx context.Context) error {
+				_, err := js.AccountInfo(ctx)
+				return err
+			},
+			timeout: 50 * time.Millisecond,
+		},
+		{
+			name: "create",
+			call: func(ctx context.Context) error {
+				_, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo"})
+				return err
+			},
+			timeout: 200 * time.Millisecond,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			start := time.Now()
+			err := test.call(context.Background())
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("Expected error: %v; got: %v", context.DeadlineExceeded, err)
+			}
+			if elapsed := time.Since(start); elapsed < test.timeout || elapsed > test.timeout+time.Second {
+				t.Fatalf("Unexpected request duration: %v", elapsed)
+			}
+
+			// A deadline set by the caller takes precedence.
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			start = time.Now()
+			if err := test.call(ctx); !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("Expected error: %v; got: %v", context.DeadlineExceeded, err)
+			}
+			if elapsed := time.Since(start); elapsed >= test.timeout {
+				t.Fatalf("Expected context deadline to be used; request took: %v", elapsed)
+			}
+		})
+	}
+}
+
+func TestWithUnavailableRetry(t *testing.T) {
+	srv := RunDefaultServer()
+	defer srv.Shutdown()
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+
+	// Respond as a stream during a leader election for the first 3 requests.
+	var requests int
+	unavailable := `{"type":"io.nats.jetstream.api.v1.stream_info_response","error":{"code":503,"err_code":10008,"description":"JetStream system temporarily unavailable"}}`
+	info := `{"type":"io.nats.jetstream.api.v1.stream_info_response","config":{"name":"foo"}}`
+	_, err = nc.Subscribe("$JS.API.STREAM.INFO.foo", func(msg *nats.Msg) {
+		requests++
+		if requests <= 3 {
+			msg.Respond([]byte(unavailable))
+			return
+		}
+		msg.Respond([]byte(info))
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := jetstream.New(nc, jetstream.WithUnavailableRetry(jetstream.RetryPolicy{Attempts: -1})); !errors.Is(err, jetstream.ErrInvalidOption) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidOption, err)
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		requests = 0
+		js, err := jetstream.New(nc)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var apiErr *jetstream.APIError
+		if _, err := js.Stream(ctx, "foo"); !errors.As(err, &apiErr) || apiErr.Code != 503 {
+			t.Fatalf("Expected unavailable error; got: %v", err)
+		}
+	})
+
+	t.Run("retry until available", func(t *testing.T) {
+		requests = 0
+		js, err := jetstream.New(nc, jetstream.WithUnavailableRetry(jetstream.RetryPolicy{
+			Attempts: 5,
+			Wait:     10 * time.Millisecond,
+			MaxWait:  20 * time.Millisecond,
+		}))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		start := time.Now()
+		s, err := js.Stream(ctx, "foo")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if s.CachedInfo().Config.Name != "foo" {
+			t.Fatalf("Unexpected stream info: %+v", s.CachedInfo())
+		}
+		// Waits of 10ms, 20ms and 20ms.
+		if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+			t.Fatalf("Expected backoff between attempts; took: %v", elapsed)
+		}
+		if requests != 4 {
+			t.Fatalf("Expected 4 requests; got: %d", requests)
+		}
+	})
+
+	t.Run("attempts exhausted", func(t *testing.T) {
+		requests = 0
+		js, err := jetstream.New(nc, jetstream.WithUnavailableRetry(jetstream.RetryPolicy{Attempts: 2, Wait: time.Millisecond}))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var apiErr *jetstream.APIError
+		if _, err := js.Stream(ctx, "foo"); !errors.As(err, &apiErr) || apiErr.Code != 503 {
+			t.Fatalf("Expected unavailable error; got: %v", err)
+		}
+		if requests != 3 {
+			t.Fatalf("Expected 3 requests; got: %d", requests)
+		}
+	})
+}
+
 func TestCreateStream(t *testing.T) {
 	tests := []struct {
-		name      string
-		stream    string
-		subject   string
-		withError error
+		name             string
+		stream           string
+		subject          string
+		subjectTransform *jetstream.SubjectTransformConfig
+		compression      jetstream.StoreCompression
+		allowMsgTTL      bool
+		withError        error
 	}{
 		{
 			name:    "create stream, ok",
@@ -229,6 +520,27 @@ func TestCreateStream(t *testing.T) {
 			subject:   "BAR.123",
 			withError: jetstream.ErrStreamNameAlreadyInUse,
 		},
+		{
+			name:             "with subject transform, not supported by server",
+			stream:           "transform",
+			subject:          "TRANSFORM.*",
+			subjectTransform: &jetstream.SubjectTransformConfig{Source: "TRANSFORM.*", Destination: "transformed.{{wildcard(1)}}"},
+			withError:        jetstream.ErrStreamSubjectTransformNotSupported,
+		},
+		{
+			name:        "with compression, not supported by server",
+			stream:      "compressed",
+			subject:     "COMPRESSED.*",
+			compression: jetstream.S2Compression,
+			withError:   jetstream.ErrStreamCompressionNotSupported,
+		},
+		{
+			name:        "with message TTL, not supported by server",
+			stream:      "ttl",
+			subject:     "TTL.*",
+			allowMsgTTL: true,
+			withError:   jetstream.ErrStreamMsgTTLNotSupported,
+		},
 	}
 
 	srv := RunBasicJetStreamServer()
@@ -248,7 +560,7 @@ func TestCreateStream(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			_, err = js.CreateStream(ctx, jetstream.StreamConfig{Name: test.stream, Subjects: []string{test.subject}})
+			_, err = js.CreateStream(ctx, jetstream.StreamConfig{Name: test.stream, Subjects: []string{test.subject}, SubjectTransform: test.subjectTransform, Compression: test.compression, AllowMsgTTL: test.allowMsgTTL})
 			if test.withError != nil {
 				if !errors.Is(err, test.withError) {
 					t.Fatalf("Expected error: %v; got: %v", test.withError, err)
@@ -264,10 +576,12 @@ func TestCreateStream(t *testing.T) {
 
 func TestUpdateStream(t *testing.T) {
 	tests := []struct {
-		name      string
-		stream    string
-		subject   string
-		withError error
+		name             string
+		stream           string
+		subject          string
+		subjectTransform *jetstream.SubjectTransformConfig
+		compression      jetstream.StoreCompression
+		withError        error
 	}{
 		{
 			name:    "update existing stream",
@@ -292,6 +606,20 @@ func TestUpdateStream(t *testing.T) {
 			subject:   "FOO.123",
 			withError: jetstream.ErrStreamNotFound,
 		},
+		{
+			name:             "with subject transform, not supported by server",
+			stream:           "foo",
+			subject:          "BAR.123",
+			subjectTransform: &jetstream.SubjectTransformConfig{Source: "BAR.*", Destination: "BAZ.*"},
+			withError:        jetstream.ErrStreamSubjectTransformNotSupported,
+		},
+		{
+			name:        "with compression, not supported by server",
+			stream:      "foo",
+			subject:     "BAR.123",
+			compression: jetstream.S2Compression,
+			withError:   jetstream.ErrStreamCompressionNotSupported,
+		},
 	}
 
 	srv := RunBasicJetStreamServer()
@@ -314,7 +642,7 @@ func TestUpdateStream(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			s, err := js.UpdateStream(ctx, jetstream.StreamConfig{Name: test.stream, Subjects: []string{test.subject}})
+			s, err := js.UpdateStream(ctx, jetstream.StreamConfig{Name: test.stream, Subjects: []string{test.subject}, SubjectTransform: test.subjectTransform, Compression: test.compression})
 			if test.withError != nil {
 				if !errors.Is(err, test.withError) {
 					t.Fatalf("Expected error: %v; got: %v", test.withError, err)
@@ -492,6 +820,59 @@ func TestAccountInfo(t *testing.T) {
 		}
 	})
 
+	t.Run("account limits", func(t *testing.T) {
+		conf := createConfFile(t, []byte(`
+		listen: 127.0.0.1:-1
+		jetstream: enabled
+		no_auth_user: foo
+		accounts: {
+			JS: {
+				jetstream: {
+					max_memory: 1MB
+					max_file: 10MB
+					max_streams: 5
+					max_consumers: 10
+					max_ack_pending: 100
+					max_bytes_required: true
+				}
+				users: [ {user: foo, password: bar} ]
+			},
+		}
+	`))
+		defer os.Remove(conf)
+		srv, _ := RunServerWithConfig(conf)
+		defer shutdownJSServerAndRemoveStorage(t, srv)
+		nc, err := nats.Connect(srv.ClientURL())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer nc.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+		js, err := jetstream.New(nc)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		info, err := js.AccountInfo(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := jetstream.AccountLimits{
+			MaxMemory:            1024 * 1024,
+			MaxStore:             10 * 1024 * 1024,
+			MaxStreams:           5,
+			MaxConsumers:         10,
+			MaxAckPending:        100,
+			MemoryMaxStreamBytes: -1,
+			StoreMaxStreamBytes:  -1,
+			MaxBytesRequired:     true,
+		}
+		if info.Limits != expected {
+			t.Fatalf("Invalid account limits; want: %+v; got: %+v", expected, info.Limits)
+		}
+	})
+
 	t.Run("jetstream not enabled on server", func(t *testing.T) {
 		srv := RunDefaultServer()
 		defer shutdownJSServerAndRemoveStorage(t, srv)
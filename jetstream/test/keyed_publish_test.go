@@ -0,0 +1,118 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+func TestKeyedPublisher(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := jetstream.NewKeyedPublisher(js, jetstream.WithKeyedPublishTimeout(0)); !errors.Is(err, jetstream.ErrInvalidOption) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidOption, err)
+	}
+	p, err := jetstream.NewKeyedPublisher(js,
+		jetstream.WithKeyedPublishTimeout(time.Second),
+		jetstream.WithKeyedPublishRetry(jetstream.RetryPolicy{Attempts: 50, Wait: 10 * time.Millisecond, MaxWait: 50 * time.Millisecond}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := p.PublishAsync("", "ORDERS.1", nil); !errors.Is(err, jetstream.ErrKeyRequired) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrKeyRequired, err)
+	}
+
+	// The stream is created after publishing started, so that the first
+	// message of every key is retried while the others wait.
+	keys := []string{"1", "2", "3"}
+	var futures []jetstream.PubAckFuture
+	for i := 0; i < 10; i++ {
+		for _, key := range keys {
+			paf, err := p.PublishAsync(key, "ORDERS."+key, []byte(strconv.Itoa(i)))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			futures = append(futures, paf)
+		}
+	}
+	time.Sleep(100 * time.Millisecond)
+	s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "ORDERS", Subjects: []string{"ORDERS.*"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, paf := range futures {
+		select {
+		case <-paf.Ok():
+		case err := <-paf.Err():
+			t.Fatalf("Unexpected error: %v", err)
+		case <-ctx.Done():
+			t.Fatalf("Did not receive ack")
+		}
+	}
+	if err := p.Close(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := p.PublishAsync("1", "ORDERS.1", nil); !errors.Is(err, jetstream.ErrKeyedPublisherClosed) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrKeyedPublisherClosed, err)
+	}
+	if seq := p.Sequence("1"); seq != 10 {
+		t.Fatalf("Expected sequence 10; got: %d", seq)
+	}
+
+	for _, key := range keys {
+		c, err := s.OrderedConsumer(ctx, jetstream.OrderedConsumerConfig{FilterSubjects: []string{"ORDERS." + key}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		msgs, err := c.Fetch(10)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var i int
+		for msg := range msgs.Messages() {
+			if string(msg.Data()) != strconv.Itoa(i) {
+				t.Fatalf("Expected message %d of key %s; got: %s", i, key, msg.Data())
+			}
+			if seq := msg.Headers().Get(jetstream.KeySequenceHeader); seq != fmt.Sprint(i+1) {
+				t.Fatalf("Expected key sequence %d; got: %s", i+1, seq)
+			}
+			i++
+		}
+		if i != 10 {
+			t.Fatalf("Expected 10 messages of key %s; got: %d", key, i)
+		}
+	}
+}
@@ -0,0 +1,753 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+func TestKeyValueBasics(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := js.CreateKeyValue(ctx, jetstream.KeyValueConfig{Bucket: "TEST.1"}); !errors.Is(err, jetstream.ErrInvalidBucketName) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidBucketName, err)
+	}
+	if _, err := js.CreateKeyValue(ctx, jetstream.KeyValueConfig{Bucket: "TEST", History: 65}); !errors.Is(err, jetstream.ErrHistoryTooLarge) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrHistoryTooLarge, err)
+	}
+	if _, err := js.KeyValue(ctx, "TEST"); !errors.Is(err, jetstream.ErrBucketNotFound) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrBucketNotFound, err)
+	}
+
+	kv, err := js.CreateKeyValue(ctx, jetstream.KeyValueConfig{Bucket: "TEST", History: 5})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if kv.Bucket() != "TEST" {
+		t.Fatalf("Invalid bucket name: %s", kv.Bucket())
+	}
+	// Creating a bucket with the same configuration is idempotent.
+	if _, err := js.CreateKeyValue(ctx, jetstream.KeyValueConfig{Bucket: "TEST", History: 5}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := kv.Put(ctx, ".name", []byte("x")); !errors.Is(err, jetstream.ErrInvalidKey) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidKey, err)
+	}
+	if _, err := kv.Get(ctx, "name"); !errors.Is(err, jetstream.ErrKeyNotFound) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrKeyNotFound, err)
+	}
+
+	rev, err := kv.PutString(ctx, "name", "derek")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if rev != 1 {
+		t.Fatalf("Unexpected revision: %d", rev)
+	}
+	entry, err := kv.Get(ctx, "name")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(entry.Value()) != "derek" || entry.Revision() != 1 || entry.Operation() != jetstream.KeyValuePut {
+		t.Fatalf("Unexpected entry: %q rev %d op %s", entry.Value(), entry.Revision(), entry.Operation())
+	}
+
+	if _, err := kv.Create(ctx, "name", []byte("ivan")); !errors.Is(err, jetstream.ErrKeyExists) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrKeyExists, err)
+	}
+	var jsErr jetstream.JetStreamError
+	if _, err := kv.Update(ctx, "name", []byte("ivan"), 5); !errors.As(err, &jsErr) || !errors.Is(err, jetstream.ErrKeyExists) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrKeyExists, err)
+	}
+	rev, err = kv.Update(ctx, "name", []byte("ivan"), 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if rev != 2 {
+		t.Fatalf("Unexpected revision: %d", rev)
+	}
+	entry, err = kv.GetRevision(ctx, "name", 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(entry.Value()) != "derek" {
+		t.Fatalf("Unexpected value: %q", entry.Value())
+	}
+
+	if err := kv.Delete(ctx, "name", jetstream.LastRevision(1)); !errors.Is(err, jetstream.ErrKeyExists) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrKeyExists, err)
+	}
+	if err := kv.Delete(ctx, "name"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := kv.Get(ctx, "name"); !errors.Is(err, jetstream.ErrKeyNotFound) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrKeyNotFound, err)
+	}
+	// A deleted key can be created again.
+	if rev, err = kv.Create(ctx, "name", []byte("tomasz")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if rev != 4 {
+		t.Fatalf("Unexpected revision: %d", rev)
+	}
+
+	history, err := kv.History(ctx, "name")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectedOps := []jetstream.KeyValueOp{jetstream.KeyValuePut, jetstream.KeyValuePut, jetstream.KeyValueDelete, jetstream.KeyValuePut}
+	if len(history) != len(expectedOps) {
+		t.Fatalf("Expected %d entries; got: %d", len(expectedOps), len(history))
+	}
+	for i, e := range history {
+		if e.Operation() != expectedOps[i] || e.Revision() != uint64(i+1) {
+			t.Fatalf("Unexpected entry %d: op %s rev %d", i, e.Operation(), e.Revision())
+		}
+	}
+
+	if err := kv.Purge(ctx, "name"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	history, err = kv.History(ctx, "name")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(history) != 1 || history[0].Operation() != jetstream.KeyValuePurge {
+		t.Fatalf("Expected single purge marker; got: %v", history)
+	}
+	if _, err := kv.History(ctx, "other"); !errors.Is(err, jetstream.ErrKeyNotFound) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrKeyNotFound, err)
+	}
+
+	status, err := kv.Status(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if status.History() != 5 || status.Values() != 1 {
+		t.Fatalf("Unexpected status: history %d values %d", status.History(), status.Values())
+	}
+
+	if _, err := js.KeyValue(ctx, "TEST"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := js.DeleteKeyValue(ctx, "TEST"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := js.DeleteKeyValue(ctx, "TEST"); !errors.Is(err, jetstream.ErrBucketNotFound) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrBucketNotFound, err)
+	}
+}
+
+func TestKeyValueWatch(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	kv, err := js.CreateKeyValue(ctx, jetstream.KeyValueConfig{Bucket: "WATCH"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expectUpdate := func(t *testing.T, w jetstream.KeyWatcher, key, value string) {
+		t.Helper()
+		select {
+		case entry := <-w.Updates():
+			if value == "" {
+				if entry != nil {
+					t.Fatalf("Expected initial values marker; got: %s", entry.Key())
+				}
+				return
+			}
+			if entry == nil || entry.Key() != key || string(entry.Value()) != value {
+				t.Fatalf("Expected %s=%s; got: %v", key, value, entry)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Did not receive update")
+		}
+	}
+
+	t.Run("empty bucket", func(t *testing.T) {
+		w, err := kv.WatchAll(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer w.Stop()
+		expectUpdate(t, w, "", "")
+		if _, err := kv.PutString(ctx, "a", "1"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expectUpdate(t, w, "a", "1")
+	})
+
+	t.Run("initial values", func(t *testing.T) {
+		if _, err := kv.PutString(ctx, "a", "2"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := kv.PutString(ctx, "b.c", "3"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		w, err := kv.Watch(ctx, "a")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer w.Stop()
+		expectUpdate(t, w, "a", "2")
+		expectUpdate(t, w, "", "")
+
+		keys, err := kv.Keys(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(keys) != 2 || keys[0] != "a" || keys[1] != "b.c" {
+			t.Fatalf("Unexpected keys: %v", keys)
+		}
+	})
+
+	t.Run("stop when context is done", func(t *testing.T) {
+		wctx, wcancel := context.WithCancel(ctx)
+		w, err := kv.Watch(wctx, "b.*", jetstream.IgnoreDeletes())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expectUpdate(t, w, "b.c", "3")
+		expectUpdate(t, w, "", "")
+		wcancel()
+		select {
+		case _, ok := <-w.Updates():
+			if ok {
+				t.Fatalf("Expected updates channel to be closed")
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Watcher was not stopped")
+		}
+		if err := w.Stop(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+	t.Run("watch modes", func(t *testing.T) {
+		kv, err := js.CreateKeyValue(ctx, jetstream.KeyValueConfig{Bucket: "MODES", History: 5})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for _, v := range []string{"1", "2", "3"} {
+			if _, err := kv.PutString(ctx, "a", v); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+		if err := kv.Delete(ctx, "a"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := kv.PutString(ctx, "b", "1"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		// history includes all revisions and delete markers
+		w, err := kv.Watch(ctx, "a", jetstream.IncludeHistory())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expectUpdate(t, w, "a", "1")
+		expectUpdate(t, w, "a", "2")
+		expectUpdate(t, w, "a", "3")
+		select {
+		case entry := <-w.Updates():
+			if entry == nil || entry.Operation() != jetstream.KeyValueDelete {
+				t.Fatalf("Expected delete marker; got: %v", entry)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Did not receive update")
+		}
+		expectUpdate(t, w, "", "")
+		w.Stop()
+
+		w, err = kv.Watch(ctx, "a", jetstream.IncludeHistory(), jetstream.IgnoreDeletes())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expectUpdate(t, w, "a", "1")
+		expectUpdate(t, w, "a", "2")
+		expectUpdate(t, w, "a", "3")
+		expectUpdate(t, w, "", "")
+		w.Stop()
+
+		// updates only skips current values and the initial values marker
+		w, err = kv.WatchAll(ctx, jetstream.UpdatesOnly())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		select {
+		case entry := <-w.Updates():
+			t.Fatalf("Unexpected update: %v", entry)
+		case <-time.After(100 * time.Millisecond):
+		}
+		if _, err := kv.PutString(ctx, "b", "2"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expectUpdate(t, w, "b", "2")
+		w.Stop()
+
+		// meta only delivers entries without values
+		w, err = kv.Watch(ctx, "b", jetstream.MetaOnly())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		select {
+		case entry := <-w.Updates():
+			if entry == nil || entry.Key() != "b" || entry.Revision() != 6 || len(entry.Value()) != 0 {
+				t.Fatalf("Expected entry b without value; got: %v", entry)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Did not receive update")
+		}
+		expectUpdate(t, w, "", "")
+		w.Stop()
+
+		if _, err := kv.Watch(ctx, "a", jetstream.IncludeHistory(), jetstream.UpdatesOnly()); !errors.Is(err, jetstream.ErrInvalidOption) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidOption, err)
+		}
+	})
+}
+
+func TestWatchBuckets(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	buckets := make(map[string]jetstream.KeyValue)
+	for _, name := range []string{"TENANT_A", "TENANT_B", "TENANT_C"} {
+		kv, err := js.CreateKeyValue(ctx, jetstream.KeyValueConfig{Bucket: name})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		buckets[name] = kv
+	}
+	if _, err := buckets["TENANT_A"].PutString(ctx, "a", "1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := buckets["TENANT_B"].PutString(ctx, "b", "2"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expectUpdate := func(t *testing.T, w jetstream.KeyWatcher) jetstream.KeyValueEntry {
+		t.Helper()
+		select {
+		case entry := <-w.Updates():
+			return entry
+		case <-time.After(time.Second):
+			t.Fatalf("Did not receive update")
+		}
+		return nil
+	}
+
+	t.Run("merged updates", func(t *testing.T) {
+		w, err := js.WatchBuckets(ctx, "TENANT_A", "TENANT_B", "TENANT_C")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer w.Stop()
+
+		initial := make(map[string]string)
+		for i := 0; i < 2; i++ {
+			entry := expectUpdate(t, w)
+			if entry == nil {
+				t.Fatalf("Received initial values marker before all initial values")
+			}
+			initial[entry.Bucket()+"."+entry.Key()] = string(entry.Value())
+		}
+		if initial["TENANT_A.a"] != "1" || initial["TENANT_B.b"] != "2" {
+			t.Fatalf("Unexpected initial values: %v", initial)
+		}
+		if entry := expectUpdate(t, w); entry != nil {
+			t.Fatalf("Expected initial values marker; got: %s", entry.Key())
+		}
+
+		if _, err := buckets["TENANT_C"].PutString(ctx, "c", "3"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		entry := expectUpdate(t, w)
+		if entry == nil || entry.Bucket() != "TENANT_C" || entry.Key() != "c" || string(entry.Value()) != "3" {
+			t.Fatalf("Expected TENANT_C c=3; got: %v", entry)
+		}
+
+		if err := w.Stop(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, ok := <-w.Updates(); ok {
+			t.Fatalf("Expected updates channel to be closed")
+		}
+	})
+
+	t.Run("stop when context is done", func(t *testing.T) {
+		wctx, wcancel := context.WithCancel(ctx)
+		w, err := js.WatchBuckets(wctx, "TENANT_C")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if entry := expectUpdate(t, w); entry == nil || entry.Key() != "c" {
+			t.Fatalf("Expected TENANT_C c; got: %v", entry)
+		}
+		wcancel()
+		select {
+		case _, ok := <-w.Updates():
+			for ok {
+				_, ok = <-w.Updates()
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected updates channel to be closed")
+		}
+	})
+
+	t.Run("bucket not found", func(t *testing.T) {
+		_, err := js.WatchBuckets(ctx, "TENANT_A", "TENANT_X")
+		if !errors.Is(err, jetstream.ErrBucketNotFound) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrBucketNotFound, err)
+		}
+	})
+
+	t.Run("no buckets", func(t *testing.T) {
+		if _, err := js.WatchBuckets(ctx); !errors.Is(err, nats.ErrInvalidArg) {
+			t.Fatalf("Expected error: %v; got: %v", nats.ErrInvalidArg, err)
+		}
+	})
+}
+
+func TestKeyValuePutWithTTL(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// per-key TTLs require nats-server 2.11+
+	if _, err := js.CreateKeyValue(ctx, jetstream.KeyValueConfig{Bucket: "TTL", LimitMarkerTTL: time.Minute}); !errors.Is(err, jetstream.ErrStreamMsgTTLNotSupported) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrStreamMsgTTLNotSupported, err)
+	}
+
+	kv, err := js.CreateKeyValue(ctx, jetstream.KeyValueConfig{Bucket: "TEST"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := kv.PutWithTTL(ctx, "a", []byte("1"), time.Minute); !errors.Is(err, jetstream.ErrKeyTTLNotEnabled) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrKeyTTLNotEnabled, err)
+	}
+	if _, err := kv.PutWithTTL(ctx, "a.*", []byte("1"), time.Minute); !errors.Is(err, jetstream.ErrInvalidKey) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidKey, err)
+	}
+
+	t.Run("limit markers", func(t *testing.T) {
+		if _, err := kv.PutString(ctx, "a", "1"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		w, err := kv.Watch(ctx, "a")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer w.Stop()
+		for _, key := range []string{"a", ""} {
+			select {
+			case entry := <-w.Updates():
+				if (key == "") != (entry == nil) {
+					t.Fatalf("Unexpected update: %v", entry)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("Did not receive update")
+			}
+		}
+
+		// marker as placed by the server when the key expires
+		m := nats.NewMsg("$KV.TEST.a")
+		m.Header.Set(jetstream.MarkerReasonHeader, "MaxAge")
+		if _, err := js.PublishMsg(ctx, m); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		select {
+		case entry := <-w.Updates():
+			if entry == nil || entry.Key() != "a" || entry.Operation() != jetstream.KeyValuePurge {
+				t.Fatalf("Expected purge of a; got: %v", entry)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Did not receive update")
+		}
+		if _, err := kv.Get(ctx, "a"); !errors.Is(err, jetstream.ErrKeyNotFound) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrKeyNotFound, err)
+		}
+	})
+}
+
+type base64Codec struct{}
+
+func (base64Codec) Encode(_ string, value []byte) ([]byte, error) {
+	return []byte(base64.StdEncoding.EncodeToString(value)), nil
+}
+
+func (base64Codec) Decode(_ string, value []byte) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(string(value))
+}
+
+func TestKeyValueWithCodec(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	raw, err := js.CreateKeyValue(ctx, jetstream.KeyValueConfig{Bucket: "TEST", History: 5})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := jetstream.KeyValueWithCodec(raw, nil); !errors.Is(err, jetstream.ErrInvalidOption) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidOption, err)
+	}
+	kv, err := jetstream.KeyValueWithCodec(raw, base64Codec{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := kv.PutString(ctx, "a", "1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	rev, err := kv.Create(ctx, "b", []byte("2"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := kv.Update(ctx, "b", []byte("3"), rev); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// values are stored encoded
+	entry, err := raw.Get(ctx, "a")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(entry.Value()) != "MQ==" {
+		t.Fatalf("Expected encoded value; got: %q", entry.Value())
+	}
+	entry, err = kv.Get(ctx, "a")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(entry.Value()) != "1" {
+		t.Fatalf("Expected decoded value; got: %q", entry.Value())
+	}
+	if _, err := kv.GetRevision(ctx, "b", rev); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	history, err := kv.History(ctx, "b")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(history) != 2 || string(history[0].Value()) != "2" || string(history[1].Value()) != "3" {
+		t.Fatalf("Unexpected history: %v", history)
+	}
+
+	// values which cannot be decoded are skipped by watchers
+	if _, err := raw.PutString(ctx, "c", "not encoded"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := kv.Get(ctx, "c"); err == nil {
+		t.Fatalf("Expected decode error")
+	}
+	if err := kv.Delete(ctx, "a"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	w, err := kv.WatchAll(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer w.Stop()
+	var updates []string
+	for entry := range w.Updates() {
+		if entry == nil {
+			break
+		}
+		updates = append(updates, fmt.Sprintf("%s=%s/%s", entry.Key(), entry.Value(), entry.Operation()))
+	}
+	expected := []string{"b=3/KeyValuePutOp", "a=/KeyValueDeleteOp"}
+	if !reflect.DeepEqual(updates, expected) {
+		t.Fatalf("Expected updates %v; got: %v", expected, updates)
+	}
+	if _, err := kv.PutString(ctx, "d", "4"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	select {
+	case entry := <-w.Updates():
+		if entry == nil || string(entry.Value()) != "4" {
+			t.Fatalf("Expected decoded update; got: %v", entry)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Did not receive update")
+	}
+}
+
+func TestCachedKeyValue(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	kv, err := js.CreateKeyValue(ctx, jetstream.KeyValueConfig{Bucket: "TEST"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := kv.PutString(ctx, "a", "1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// count reads of keys, excluding verification of the cache
+	var reads int32
+	sub, err := nc.Subscribe("$JS.API.DIRECT.GET.KV_TEST.$KV.TEST.*", func(msg *nats.Msg) {
+		if !strings.HasSuffix(msg.Subject, ".>") {
+			atomic.AddInt32(&reads, 1)
+		}
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	if _, err := jetstream.NewCachedKeyValue(ctx, kv, jetstream.WithCacheMaxStaleness(0)); !errors.Is(err, jetstream.ErrInvalidOption) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidOption, err)
+	}
+	errs := make(chan error, 10)
+	cached, err := jetstream.NewCachedKeyValue(ctx, kv,
+		jetstream.WithCacheMaxStaleness(200*time.Millisecond),
+		jetstream.WithCacheErrHandler(func(err error) { errs <- err }))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer cached.Stop()
+
+	expectValue := func(t *testing.T, key, value string) {
+		t.Helper()
+		entry, err := cached.Get(ctx, key)
+		if value == "" {
+			if !errors.Is(err, jetstream.ErrKeyNotFound) {
+				t.Fatalf("Expected error: %v; got: %v", jetstream.ErrKeyNotFound, err)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if string(entry.Value()) != value {
+			t.Fatalf("Expected %s=%s; got: %q", key, value, entry.Value())
+		}
+	}
+	expectValue(t, "a", "1")
+	expectValue(t, "b", "")
+
+	if _, err := cached.PutString(ctx, "b", "2"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := cached.Delete(ctx, "a"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	expectValue(t, "a", "")
+	expectValue(t, "b", "2")
+
+	// cache is verified, so reads are still served from memory
+	time.Sleep(500 * time.Millisecond)
+	expectValue(t, "b", "2")
+	if n := atomic.LoadInt32(&reads); n != 0 {
+		t.Fatalf("Expected no reads from the bucket; got: %d", n)
+	}
+	select {
+	case err := <-errs:
+		t.Fatalf("Unexpected error: %v", err)
+	default:
+	}
+
+	// stopped cache reads from the bucket
+	cached.Stop()
+	expectValue(t, "b", "2")
+	if err := nc.Flush(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n := atomic.LoadInt32(&reads); n != 1 {
+		t.Fatalf("Expected a read from the bucket; got: %d", n)
+	}
+}
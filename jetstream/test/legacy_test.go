@@ -0,0 +1,350 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+func TestLegacyContext(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	legacy, err := jetstream.LegacyContext(js)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("manage streams", func(t *testing.T) {
+		info, err := legacy.AddStream(&nats.StreamConfig{
+			Name:      "foo",
+			Subjects:  []string{"FOO.*"},
+			MaxAge:    time.Hour,
+			Retention: nats.LimitsPolicy,
+			Storage:   nats.MemoryStorage,
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if info.Config.MaxAge != time.Hour || info.Config.Storage != nats.MemoryStorage {
+			t.Fatalf("Unexpected stream config: %+v", info.Config)
+		}
+		// created using the new API
+		s, err := js.Stream(ctx, "foo")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if cfg := s.CachedInfo().Config; cfg.MaxAge != time.Hour || cfg.Storage != jetstream.MemoryStorage {
+			t.Fatalf("Unexpected stream config: %+v", cfg)
+		}
+
+		info, err = legacy.UpdateStream(&nats.StreamConfig{
+			Name:     "foo",
+			Subjects: []string{"FOO.*"},
+			MaxAge:   time.Hour,
+			MaxMsgs:  100,
+			Storage:  nats.MemoryStorage,
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if info.Config.MaxMsgs != 100 {
+			t.Fatalf("Expected max msgs 100; got: %d", info.Config.MaxMsgs)
+		}
+
+		if _, err := legacy.AddStream(&nats.StreamConfig{Name: "foo", Subjects: []string{"BAR.*"}}); !errors.Is(err, nats.ErrStreamNameAlreadyInUse) {
+			t.Fatalf("Expected error: %v; got: %v", nats.ErrStreamNameAlreadyInUse, err)
+		}
+		if _, err := legacy.AddStream(nil); !errors.Is(err, nats.ErrStreamConfigRequired) {
+			t.Fatalf("Expected error: %v; got: %v", nats.ErrStreamConfigRequired, err)
+		}
+		if _, err := legacy.StreamInfo("missing"); err != nats.ErrStreamNotFound {
+			t.Fatalf("Expected error: %v; got: %v", nats.ErrStreamNotFound, err)
+		}
+		name, err := legacy.StreamNameBySubject("FOO.A")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if name != "foo" {
+			t.Fatalf("Expected stream foo; got: %q", name)
+		}
+	})
+
+	t.Run("messages", func(t *testing.T) {
+		for _, subj := range []string{"FOO.A", "FOO.B", "FOO.A", "FOO.B"} {
+			if _, err := js.Publish(ctx, subj, []byte(subj)); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+		msg, err := legacy.GetMsg("foo", 2)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if msg.Subject != "FOO.B" || msg.Sequence != 2 {
+			t.Fatalf("Unexpected message: %+v", msg)
+		}
+		msg, err = legacy.GetLastMsg("foo", "FOO.A")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if msg.Sequence != 3 {
+			t.Fatalf("Expected sequence 3; got: %d", msg.Sequence)
+		}
+		if err := legacy.DeleteMsg("foo", 1); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := legacy.GetMsg("foo", 1); !errors.Is(err, nats.ErrMsgNotFound) {
+			t.Fatalf("Expected error: %v; got: %v", nats.ErrMsgNotFound, err)
+		}
+		info, err := legacy.StreamInfo("foo", &nats.StreamInfoRequest{SubjectsFilter: "FOO.*"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if info.State.Msgs != 3 || len(info.State.Subjects) != 2 {
+			t.Fatalf("Unexpected stream state: %+v", info.State)
+		}
+
+		if err := legacy.PurgeStream("foo", &nats.StreamPurgeRequest{Subject: "FOO.B"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		info, err = legacy.StreamInfo("foo", nats.Context(ctx))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if info.State.Msgs != 1 {
+			t.Fatalf("Expected 1 message; got: %d", info.State.Msgs)
+		}
+	})
+
+	t.Run("manage consumers", func(t *testing.T) {
+		info, err := legacy.AddConsumer("foo", &nats.ConsumerConfig{
+			Durable:   "cons",
+			AckPolicy: nats.AckExplicitPolicy,
+			AckWait:   10 * time.Second,
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if info.Config.AckWait != 10*time.Second || info.Config.AckPolicy != nats.AckExplicitPolicy {
+			t.Fatalf("Unexpected consumer config: %+v", info.Config)
+		}
+		info, err = legacy.UpdateConsumer("foo", &nats.ConsumerConfig{
+			Durable:     "cons",
+			AckPolicy:   nats.AckExplicitPolicy,
+			AckWait:     10 * time.Second,
+			Description: "updated",
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if info.Config.Description != "updated" {
+			t.Fatalf("Expected updated description; got: %q", info.Config.Description)
+		}
+		if _, err := legacy.ConsumerInfo("foo", "missing"); err != nats.ErrConsumerNotFound {
+			t.Fatalf("Expected error: %v; got: %v", nats.ErrConsumerNotFound, err)
+		}
+
+		var names []string
+		for name := range legacy.ConsumerNames("foo") {
+			names = append(names, name)
+		}
+		if len(names) != 1 || names[0] != "cons" {
+			t.Fatalf("Unexpected consumer names: %v", names)
+		}
+		var infos []*nats.ConsumerInfo
+		for info := range legacy.Consumers("foo") {
+			infos = append(infos, info)
+		}
+		if len(infos) != 1 || infos[0].NumPending != 1 {
+			t.Fatalf("Unexpected consumer infos: %+v", infos)
+		}
+		if err := legacy.DeleteConsumer("foo", "cons"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("list streams", func(t *testing.T) {
+		if _, err := legacy.AddStream(&nats.StreamConfig{Name: "bar", Subjects: []string{"BAR.*"}}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var names []string
+		for name := range legacy.StreamNames() {
+			names = append(names, name)
+		}
+		if len(names) != 2 {
+			t.Fatalf("Expected 2 streams; got: %v", names)
+		}
+		var infos []*nats.StreamInfo
+		for info := range legacy.Streams() {
+			infos = append(infos, info)
+		}
+		if len(infos) != 2 {
+			t.Fatalf("Expected 2 streams; got: %d", len(infos))
+		}
+		accInfo, err := legacy.AccountInfo()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if accInfo.Streams != 2 {
+			t.Fatalf("Expected 2 streams; got: %d", accInfo.Streams)
+		}
+		if err := legacy.DeleteStream("bar"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("subscribe and publish", func(t *testing.T) {
+		sub, err := legacy.SubscribeSync("FOO.C")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer sub.Unsubscribe()
+		if _, err := legacy.Publish("FOO.C", []byte("msg")); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := sub.NextMsg(time.Second); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("unsupported option", func(t *testing.T) {
+		if _, err := legacy.StreamInfo("foo", nats.DirectGet()); !errors.Is(err, jetstream.ErrInvalidOption) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidOption, err)
+		}
+	})
+}
+
+func TestLegacyContextGetMsg(t *testing.T) {
+	tests := []struct {
+		name        string
+		allowDirect bool
+	}{
+		{name: "plain stream", allowDirect: false},
+		{name: "stream allowing direct gets", allowDirect: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv := RunBasicJetStreamServer()
+			defer shutdownJSServerAndRemoveStorage(t, srv)
+			nc, err := nats.Connect(srv.ClientURL())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			defer nc.Close()
+			js, err := jetstream.New(nc)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			if _, err := js.CreateStream(ctx, jetstream.StreamConfig{
+				Name:        "foo",
+				Subjects:    []string{"FOO.*"},
+				AllowDirect: test.allowDirect,
+			}); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, subj := range []string{"FOO.A", "FOO.B", "FOO.A"} {
+				if _, err := js.Publish(ctx, subj, []byte(subj)); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+
+			legacy, err := jetstream.LegacyContext(js)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			msg, err := legacy.GetMsg("foo", 2)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if msg.Subject != "FOO.B" || msg.Sequence != 2 || string(msg.Data) != "FOO.B" {
+				t.Fatalf("Unexpected message: %+v", msg)
+			}
+			msg, err = legacy.GetLastMsg("foo", "FOO.A")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if msg.Subject != "FOO.A" || msg.Sequence != 3 {
+				t.Fatalf("Unexpected message: %+v", msg)
+			}
+			if _, err := legacy.GetMsg("foo", 10); !errors.Is(err, nats.ErrMsgNotFound) {
+				t.Fatalf("Expected error: %v; got: %v", nats.ErrMsgNotFound, err)
+			}
+			if _, err := legacy.GetLastMsg("foo", "FOO.C"); !errors.Is(err, nats.ErrMsgNotFound) {
+				t.Fatalf("Expected error: %v; got: %v", nats.ErrMsgNotFound, err)
+			}
+		})
+	}
+}
+
+func TestLegacyContextWithDomain(t *testing.T) {
+	conf := createConfFile(t, []byte(`
+	listen: 127.0.0.1:-1
+	jetstream: { domain: "test-domain" }
+	`))
+	defer os.Remove(conf)
+	srv, _ := RunServerWithConfig(conf)
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	js, err := jetstream.NewWithDomain(nc, "test-domain")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	legacy, err := jetstream.LegacyContext(js)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := legacy.AddStream(&nats.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ack, err := legacy.Publish("FOO.A", []byte("msg"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ack.Domain != "test-domain" {
+		t.Fatalf("Expected domain test-domain; got: %q", ack.Domain)
+	}
+	info, err := legacy.AccountInfo()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info.Domain != "test-domain" {
+		t.Fatalf("Expected domain test-domain; got: %q", info.Domain)
+	}
+}
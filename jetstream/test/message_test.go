@@ -16,6 +16,7 @@ package test
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -392,3 +393,163 @@ func TestAckVariants(t *testing.T) {
 		}
 	})
 }
+
+func TestAckAfterPublish(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "bar", Subjects: []string{"BAR.*"}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	c, err := s.AddConsumer(ctx, jetstream.ConsumerConfig{Durable: "cons", AckPolicy: jetstream.AckExplicitPolicy})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := js.Publish(ctx, "FOO.1", []byte("msg")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	msgs, err := c.Fetch(1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	msg := <-msgs.Messages()
+	if msg == nil {
+		t.Fatalf("No messages available")
+	}
+
+	out := &nats.Msg{Subject: "BAR.1", Data: []byte("derived")}
+	ack, err := msg.AckAfterPublish(ctx, js, out)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ack.Stream != "bar" || ack.Duplicate {
+		t.Fatalf("Unexpected ack: %+v", ack)
+	}
+	if out.Header != nil {
+		t.Fatalf("Output message should not be modified")
+	}
+
+	// Output of a retried input is deduplicated.
+	ack, err = msg.AckAfterPublish(ctx, js, out)
+	if !errors.Is(err, jetstream.ErrMsgAlreadyAckd) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrMsgAlreadyAckd, err)
+	}
+	if ack == nil || !ack.Duplicate {
+		t.Fatalf("Expected duplicate ack, got: %+v", ack)
+	}
+
+	info, err := c.Info(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info.AckFloor.Stream != 1 {
+		t.Fatalf("Expected input message to be acked, ack floor: %d", info.AckFloor.Stream)
+	}
+}
+
+func TestHeadersOnlyFetchBody(t *testing.T) {
+	for _, allowDirect := range []bool{false, true} {
+		t.Run(fmt.Sprintf("allow direct %v", allowDirect), func(t *testing.T) {
+			srv := RunBasicJetStreamServer()
+			defer shutdownJSServerAndRemoveStorage(t, srv)
+			nc, err := nats.Connect(srv.ClientURL())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			defer nc.Close()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			js, err := jetstream.New(nc)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}, AllowDirect: allowDirect})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			c, err := s.AddConsumer(ctx, jetstream.ConsumerConfig{
+				Durable:     "cons",
+				AckPolicy:   jetstream.AckExplicitPolicy,
+				HeadersOnly: true,
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if _, err := js.Publish(ctx, "FOO.1", []byte("hello")); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			msgs, err := c.Fetch(1)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			msg := <-msgs.Messages()
+			if msg == nil {
+				t.Fatalf("No messages available")
+			}
+			if len(msg.Data()) != 0 {
+				t.Fatalf("Expected no payload; got: %q", string(msg.Data()))
+			}
+			meta, err := msg.Metadata()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !meta.HeadersOnly || meta.PayloadSize != 5 {
+				t.Fatalf("Invalid message metadata: %+v", meta)
+			}
+			body, err := msg.FetchBody(ctx)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(body) != "hello" || string(msg.Data()) != "hello" {
+				t.Fatalf("Invalid message body; want: 'hello'; got: %q", string(body))
+			}
+			if err := msg.Ack(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			// messages delivered with payload are returned as is
+			full, err := s.AddConsumer(ctx, jetstream.ConsumerConfig{Durable: "full", AckPolicy: jetstream.AckExplicitPolicy})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			msgs, err = full.Fetch(1)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			msg = <-msgs.Messages()
+			if msg == nil {
+				t.Fatalf("No messages available")
+			}
+			meta, err = msg.Metadata()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if meta.HeadersOnly {
+				t.Fatalf("Invalid message metadata: %+v", meta)
+			}
+			body, err = msg.FetchBody(ctx)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(body) != "hello" {
+				t.Fatalf("Invalid message body; want: 'hello'; got: %q", string(body))
+			}
+		})
+	}
+}
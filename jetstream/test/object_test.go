@@ -0,0 +1,502 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+func TestObjectStoreBasics(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := js.CreateObjectStore(ctx, jetstream.ObjectStoreConfig{Bucket: "FILES.1"}); !errors.Is(err, jetstream.ErrInvalidStoreName) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidStoreName, err)
+	}
+	if _, err := js.ObjectStore(ctx, "FILES"); !errors.Is(err, jetstream.ErrBucketNotFound) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrBucketNotFound, err)
+	}
+	obs, err := js.CreateObjectStore(ctx, jetstream.ObjectStoreConfig{Bucket: "FILES"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := obs.List(ctx); !errors.Is(err, jetstream.ErrNoObjectsFound) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrNoObjectsFound, err)
+	}
+
+	// Object spanning several chunks, read through a pipe so that it is never held in memory whole.
+	data := make([]byte, 1024*1024+100)
+	rand.Read(data)
+	pr, pw := io.Pipe()
+	go func() {
+		for b := data; len(b) > 0; {
+			n := 1000
+			if len(b) < n {
+				n = len(b)
+			}
+			pw.Write(b[:n])
+			b = b[n:]
+		}
+		pw.Close()
+	}()
+	info, err := obs.Put(ctx, jetstream.ObjectMeta{Name: "blob", Opts: &jetstream.ObjectMetaOptions{ChunkSize: 64 * 1024}}, pr)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info.Size != uint64(len(data)) || info.Chunks != 17 {
+		t.Fatalf("Unexpected object info: size %d chunks %d", info.Size, info.Chunks)
+	}
+
+	var buf bytes.Buffer
+	if _, err := obs.Get(ctx, "blob", &buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatalf("Object contents do not match")
+	}
+
+	// Replacing an object purges the chunks of the previous version.
+	if _, err := obs.PutBytes(ctx, "blob", []byte("hello")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	res, err := obs.GetBytes(ctx, "blob")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(res) != "hello" {
+		t.Fatalf("Unexpected contents: %q", res)
+	}
+	status, err := obs.Status(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if status.Size() > 1024 {
+		t.Fatalf("Expected previous chunks to be purged; bucket size: %d", status.Size())
+	}
+
+	if _, err := obs.PutBytes(ctx, "empty", nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if res, err := obs.GetBytes(ctx, "empty"); err != nil || len(res) != 0 {
+		t.Fatalf("Unexpected result: %q, %v", res, err)
+	}
+	list, err := obs.List(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(list) != 2 || list[0].Name != "blob" || list[1].Name != "empty" {
+		t.Fatalf("Unexpected objects: %v", list)
+	}
+
+	if err := obs.Delete(ctx, "blob"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := obs.GetInfo(ctx, "blob"); !errors.Is(err, jetstream.ErrObjectNotFound) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrObjectNotFound, err)
+	}
+	info, err = obs.GetInfo(ctx, "blob", jetstream.GetObjectInfoShowDeleted())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !info.Deleted {
+		t.Fatalf("Expected object to be marked as deleted")
+	}
+	if _, err := obs.GetBytes(ctx, "blob"); !errors.Is(err, jetstream.ErrObjectNotFound) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrObjectNotFound, err)
+	}
+	if list, err = obs.List(ctx); err != nil || len(list) != 1 {
+		t.Fatalf("Unexpected result: %v, %v", list, err)
+	}
+	if list, err = obs.List(ctx, jetstream.ListObjectsShowDeleted()); err != nil || len(list) != 2 {
+		t.Fatalf("Unexpected result: %v, %v", list, err)
+	}
+
+	if _, err := js.ObjectStore(ctx, "FILES"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := js.DeleteObjectStore(ctx, "FILES"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestObjectStorePutReaderError(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	obs, err := js.CreateObjectStore(ctx, jetstream.ObjectStoreConfig{Bucket: "FILES"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	readErr := errors.New("read failed")
+	r := io.MultiReader(bytes.NewReader(make([]byte, 300*1024)), iotest.ErrReader(readErr))
+	if _, err := obs.Put(ctx, jetstream.ObjectMeta{Name: "partial"}, r); !errors.Is(err, readErr) {
+		t.Fatalf("Expected error: %v; got: %v", readErr, err)
+	}
+	if _, err := obs.GetInfo(ctx, "partial"); !errors.Is(err, jetstream.ErrObjectNotFound) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrObjectNotFound, err)
+	}
+	status, err := obs.Status(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if status.Size() != 0 {
+		t.Fatalf("Expected partial chunks to be purged; bucket size: %d", status.Size())
+	}
+}
+
+func TestObjectStorePutResumable(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	obs, err := js.CreateObjectStore(ctx, jetstream.ObjectStoreConfig{Bucket: "FILES"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s, err := js.Stream(ctx, "OBJ_FILES")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	storedMsgs := func() uint64 {
+		t.Helper()
+		info, err := s.Info(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return info.State.Msgs
+	}
+
+	meta := jetstream.ObjectMeta{Name: "blob", Opts: &jetstream.ObjectMetaOptions{ChunkSize: 1024}}
+	data := make([]byte, 10*1024+100)
+	rand.Read(data)
+	readErr := errors.New("read failed")
+
+	// the first attempt fails after 5 chunks, which are kept
+	var upload jetstream.ObjectUpload
+	r := io.MultiReader(bytes.NewReader(data[:5*1024]), iotest.ErrReader(readErr))
+	if _, err := obs.Put(ctx, meta, r, jetstream.PutObjectResumable(&upload)); !errors.Is(err, readErr) {
+		t.Fatalf("Expected error: %v; got: %v", readErr, err)
+	}
+	if upload.NUID == "" {
+		t.Fatalf("Expected upload NUID to be set")
+	}
+	if msgs := storedMsgs(); msgs != 5 {
+		t.Fatalf("Expected 5 chunks to be kept; got: %d", msgs)
+	}
+
+	// resuming only publishes the missing chunks
+	var progress []uint64
+	info, err := obs.Put(ctx, meta, bytes.NewReader(data),
+		jetstream.PutObjectResumable(&upload),
+		jetstream.PutObjectProgress(func(stored uint64) {
+			progress = append(progress, stored)
+		}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info.NUID != upload.NUID || info.Chunks != 11 || info.Size != uint64(len(data)) {
+		t.Fatalf("Unexpected object info: %+v", info)
+	}
+	// 11 chunks and the meta message
+	if msgs := storedMsgs(); msgs != 12 {
+		t.Fatalf("Expected 12 messages; got: %d", msgs)
+	}
+	if len(progress) != 11 || progress[0] != 1024 || progress[10] != uint64(len(data)) {
+		t.Fatalf("Unexpected progress: %v", progress)
+	}
+	res, err := obs.GetBytes(ctx, "blob")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Equal(res, data) {
+		t.Fatalf("Object contents do not match")
+	}
+
+	// stored chunks not matching the reader are replaced
+	upload = jetstream.ObjectUpload{}
+	r = io.MultiReader(bytes.NewReader(data[:3*1024]), iotest.ErrReader(readErr))
+	if _, err := obs.Put(ctx, meta, r, jetstream.PutObjectResumable(&upload)); !errors.Is(err, readErr) {
+		t.Fatalf("Expected error: %v; got: %v", readErr, err)
+	}
+	changed := append([]byte(nil), data[:4*1024]...)
+	changed[1024] ^= 0xff
+	if _, err := obs.Put(ctx, meta, bytes.NewReader(changed), jetstream.PutObjectResumable(&upload)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	res, err = obs.GetBytes(ctx, "blob")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Equal(res, changed) {
+		t.Fatalf("Object contents do not match")
+	}
+	// chunks of the previous object are purged
+	if msgs := storedMsgs(); msgs != 5 {
+		t.Fatalf("Expected 5 messages; got: %d", msgs)
+	}
+}
+
+func TestObjectStoreLinks(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	files, err := js.CreateObjectStore(ctx, jetstream.ObjectStoreConfig{Bucket: "FILES"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	links, err := js.CreateObjectStore(ctx, jetstream.ObjectStoreConfig{Bucket: "LINKS"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data := []byte("report contents")
+	obj, err := files.PutBytes(ctx, "report", data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := links.PutBytes(ctx, "other", []byte("other")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// links to objects are followed by Get
+	link, err := links.AddLink(ctx, "latest", obj)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if link.Opts == nil || link.Opts.Link == nil || link.Opts.Link.Bucket != "FILES" || link.Opts.Link.Name != "report" {
+		t.Fatalf("Unexpected link: %+v", link.Opts)
+	}
+	res, err := links.GetBytes(ctx, "latest")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Equal(res, data) {
+		t.Fatalf("Expected %q; got: %q", data, res)
+	}
+	if _, err := links.AddLink(ctx, "other", obj); !errors.Is(err, jetstream.ErrObjectAlreadyExists) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrObjectAlreadyExists, err)
+	}
+	if _, err := links.AddLink(ctx, "chained", link); !errors.Is(err, jetstream.ErrNoLinkToLink) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrNoLinkToLink, err)
+	}
+	if _, err := links.AddLink(ctx, "empty", nil); !errors.Is(err, jetstream.ErrObjectRequired) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrObjectRequired, err)
+	}
+
+	// links to buckets cannot be read
+	if _, err := links.AddBucketLink(ctx, "files", files); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := links.GetBytes(ctx, "files"); !errors.Is(err, jetstream.ErrObjectIsLink) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrObjectIsLink, err)
+	}
+	if _, err := links.AddBucketLink(ctx, "files", nil); !errors.Is(err, jetstream.ErrBucketRequired) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrBucketRequired, err)
+	}
+
+	// meta can be updated without changing the contents
+	headers := nats.Header{"Owner": []string{"billing"}}
+	if err := files.UpdateMeta(ctx, "report", jetstream.ObjectMeta{Name: "report-2023", Description: "yearly", Headers: headers}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := files.GetInfo(ctx, "report"); !errors.Is(err, jetstream.ErrObjectNotFound) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrObjectNotFound, err)
+	}
+	info, err := files.GetInfo(ctx, "report-2023")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info.Description != "yearly" || info.Headers.Get("Owner") != "billing" || info.NUID != obj.NUID || info.Digest != obj.Digest {
+		t.Fatalf("Unexpected object info: %+v", info)
+	}
+	res, err = files.GetBytes(ctx, "report-2023")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Equal(res, data) {
+		t.Fatalf("Expected %q; got: %q", data, res)
+	}
+	if err := links.UpdateMeta(ctx, "other", jetstream.ObjectMeta{Name: "latest"}); !errors.Is(err, jetstream.ErrObjectAlreadyExists) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrObjectAlreadyExists, err)
+	}
+	if err := files.UpdateMeta(ctx, "report", jetstream.ObjectMeta{Name: "x"}); !errors.Is(err, jetstream.ErrUpdateMetaDeleted) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrUpdateMetaDeleted, err)
+	}
+
+	// links to deleted objects are not allowed
+	if err := files.Delete(ctx, "report-2023"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	deleted, err := files.GetInfo(ctx, "report-2023", jetstream.GetObjectInfoShowDeleted())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := links.AddLink(ctx, "deleted", deleted); !errors.Is(err, jetstream.ErrNoLinkToDeleted) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrNoLinkToDeleted, err)
+	}
+}
+
+func TestObjectStoreWatch(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	obs, err := js.CreateObjectStore(ctx, jetstream.ObjectStoreConfig{Bucket: "FILES"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expectUpdate := func(t *testing.T, w jetstream.ObjectWatcher, name string, deleted bool) {
+		t.Helper()
+		select {
+		case info := <-w.Updates():
+			if name == "" {
+				if info != nil {
+					t.Fatalf("Expected initial values marker; got: %+v", info)
+				}
+				return
+			}
+			if info == nil || info.Name != name || info.Deleted != deleted || info.ModTime.IsZero() {
+				t.Fatalf("Expected %q (deleted: %t); got: %+v", name, deleted, info)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Did not receive update")
+		}
+	}
+
+	// marker is sent right away for an empty bucket
+	w, err := obs.Watch(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectUpdate(t, w, "", false)
+
+	if _, err := obs.PutBytes(ctx, "a", []byte("1")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectUpdate(t, w, "a", false)
+	if _, err := obs.PutBytes(ctx, "b", []byte("2")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectUpdate(t, w, "b", false)
+	if err := obs.Delete(ctx, "a"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectUpdate(t, w, "a", true)
+	if err := w.Stop(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := <-w.Updates(); ok {
+		t.Fatalf("Expected updates channel to be closed")
+	}
+
+	// latest infos are sent first, without deleted objects if ignored
+	w, err = obs.Watch(ctx, jetstream.IgnoreDeletes())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectUpdate(t, w, "b", false)
+	expectUpdate(t, w, "", false)
+	w.Stop()
+
+	// only new updates
+	wctx, wcancel := context.WithCancel(ctx)
+	w, err = obs.Watch(wctx, jetstream.UpdatesOnly())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := obs.PutBytes(ctx, "c", []byte("3")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectUpdate(t, w, "c", false)
+	// canceling the context stops the watcher
+	wcancel()
+	select {
+	case _, ok := <-w.Updates():
+		if ok {
+			t.Fatalf("Expected updates channel to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Watcher was not stopped")
+	}
+
+	if _, err := obs.Watch(ctx, jetstream.MetaOnly()); !errors.Is(err, jetstream.ErrInvalidOption) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidOption, err)
+	}
+}
@@ -224,6 +224,60 @@ func TestOrderedConsumerMessages(t *testing.T) {
 		}
 	})
 
+	t.Run("next with context", func(t *testing.T) {
+		srv := RunBasicJetStreamServer()
+		defer shutdownJSServerAndRemoveStorage(t, srv)
+		nc, err := nats.Connect(srv.ClientURL())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		js, err := jetstream.New(nc)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer nc.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+		s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		c, err := s.OrderedConsumer(ctx, jetstream.OrderedConsumerConfig{})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		it, err := c.Messages()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer it.Stop()
+
+		nextCtx, nextCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer nextCancel()
+		if _, err := it.NextWithContext(nextCtx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected error: %v; got: %v", context.DeadlineExceeded, err)
+		}
+
+		// The consumer is not reset on context errors.
+		name := c.CachedInfo().Name
+		publishTestMsgs(t, nc)
+		for i := 0; i < len(testMsgs); i++ {
+			msg, err := it.NextWithContext(ctx)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if string(msg.Data()) != testMsgs[i] {
+				t.Fatalf("Invalid msg on index %d; expected: %s; got: %s", i, testMsgs[i], string(msg.Data()))
+			}
+		}
+		if c.CachedInfo().Name != name {
+			t.Fatalf("Expected consumer not to be recreated")
+		}
+	})
+
 	t.Run("consumer used as fetch", func(t *testing.T) {
 		srv := RunBasicJetStreamServer()
 		defer shutdownJSServerAndRemoveStorage(t, srv)
@@ -569,3 +623,106 @@ func TestOrderedConsumerInfo(t *testing.T) {
 		t.Fatalf("New consumer should be returned; got: %s", info.Name)
 	}
 }
+
+func TestOrderedConsumerFilterSubjects(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := s.OrderedConsumer(ctx, jetstream.OrderedConsumerConfig{FilterSubjects: []string{"FOO.A", "FOO.*"}}); !errors.Is(err, jetstream.ErrOverlappingFilterSubjects) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrOverlappingFilterSubjects, err)
+	}
+	if _, err := js.OrderedConsumer(ctx, "foo", jetstream.OrderedConsumerConfig{FilterSubjects: []string{"FOO.A", "FOO.A"}}); !errors.Is(err, jetstream.ErrDuplicateFilterSubjects) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrDuplicateFilterSubjects, err)
+	}
+
+	for _, subject := range []string{"FOO.A", "FOO.B", "FOO.A"} {
+		if _, err := js.Publish(ctx, subject, []byte(subject)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	c, err := s.OrderedConsumer(ctx, jetstream.OrderedConsumerConfig{FilterSubjects: []string{"FOO.A"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	msgs, err := c.Fetch(5, jetstream.FetchMaxWait(500*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var received int
+	for msg := range msgs.Messages() {
+		if msg.Subject() != "FOO.A" {
+			t.Fatalf("Unexpected subject: %s", msg.Subject())
+		}
+		received++
+	}
+	if received != 2 {
+		t.Fatalf("Expected 2 messages; got: %d", received)
+	}
+}
+
+func TestOrderedConsumerHeadersOnly(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := js.Publish(ctx, "FOO.A", []byte("payload")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	c, err := s.OrderedConsumer(ctx, jetstream.OrderedConsumerConfig{HeadersOnly: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	msgs, err := c.Fetch(1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	msg := <-msgs.Messages()
+	if msg == nil {
+		t.Fatalf("No messages available")
+	}
+	meta, err := msg.Metadata()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(msg.Data()) != 0 || !meta.HeadersOnly || meta.PayloadSize != 7 {
+		t.Fatalf("Expected headers only message; got: %q %+v", string(msg.Data()), meta)
+	}
+	body, err := msg.FetchBody(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(body) != "payload" {
+		t.Fatalf("Invalid message body; want: 'payload'; got: %q", string(body))
+	}
+}
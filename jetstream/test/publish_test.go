@@ -16,12 +16,15 @@ package test
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/nats-io/nats-server/v2/server"
+	natsserver "github.com/nats-io/nats-server/v2/test"
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
 )
@@ -1168,6 +1171,258 @@ func TestPublishMsgAsync(t *testing.T) {
 	}
 }
 
+func TestPublishMsgAsyncRetry(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.Run("stream becomes available", func(t *testing.T) {
+		js, err := jetstream.New(nc, jetstream.WithPublishAsyncRetry(10, 50*time.Millisecond))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		ack, err := js.PublishAsync(ctx, "FOO.1", []byte("msg"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+		if _, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		select {
+		case pa := <-ack.Ok():
+			if pa.Stream != "foo" || pa.Sequence != 1 {
+				t.Fatalf("Unexpected ack: %+v", pa)
+			}
+		case err := <-ack.Err():
+			t.Fatalf("Unexpected error: %v", err)
+		case <-time.After(time.Second):
+			t.Fatalf("Did not receive ack")
+		}
+	})
+
+	t.Run("retries exhausted", func(t *testing.T) {
+		errs := make(chan error, 1)
+		js, err := jetstream.New(nc, jetstream.WithPublishAsyncErrHandler(func(_ jetstream.JetStream, _ *nats.Msg, err error) {
+			errs <- err
+		}))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		start := time.Now()
+		ack, err := js.PublishAsync(ctx, "BAR.1", []byte("msg"), jetstream.WithRetryAttempts(3), jetstream.WithRetryWait(50*time.Millisecond))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		select {
+		case <-ack.Ok():
+			t.Fatalf("Expected error")
+		case err := <-ack.Err():
+			if !errors.Is(err, jetstream.ErrNoStreamResponse) {
+				t.Fatalf("Expected error: %v; got: %v", jetstream.ErrNoStreamResponse, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Did not receive error")
+		}
+		if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+			t.Fatalf("Expected 3 retries; got error after %v", elapsed)
+		}
+		if err := <-errs; !errors.Is(err, jetstream.ErrNoStreamResponse) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrNoStreamResponse, err)
+		}
+		if pending := js.PublishAsyncPending(); pending != 0 {
+			t.Fatalf("Expected no pending messages; got: %d", pending)
+		}
+	})
+
+	t.Run("invalid retry wait", func(t *testing.T) {
+		if _, err := jetstream.New(nc, jetstream.WithPublishAsyncRetry(1, -time.Second)); !errors.Is(err, jetstream.ErrInvalidOption) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidOption, err)
+		}
+	})
+}
+
+func TestPublishAsyncWithCallback(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	asyncErrs := make(chan error, 10)
+	js, err := jetstream.New(nc,
+		jetstream.WithPublishAsyncRetry(0, 0),
+		jetstream.WithPublishAsyncErrHandler(func(_ jetstream.JetStream, _ *nats.Msg, err error) {
+			asyncErrs <- err
+		}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("acks", func(t *testing.T) {
+		const count = 1000
+		var mu sync.Mutex
+		seqs := make(map[uint64]struct{})
+		done := make(chan struct{})
+		cb := func(ack *jetstream.PubAck, err error) {
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			seqs[ack.Sequence] = struct{}{}
+			if len(seqs) == count {
+				close(done)
+			}
+		}
+		for i := 0; i < count; i++ {
+			if err := js.PublishAsyncWithCallback(ctx, &nats.Msg{Subject: "FOO.A", Data: []byte("msg")}, cb); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Did not receive all acks")
+		}
+		select {
+		case <-js.PublishAsyncComplete():
+		case <-time.After(time.Second):
+			t.Fatalf("Expected no pending messages; got: %d", js.PublishAsyncPending())
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		errs := make(chan error, 1)
+		err := js.PublishAsyncWithCallback(ctx, &nats.Msg{Subject: "BAR.A"}, func(ack *jetstream.PubAck, err error) {
+			if ack != nil {
+				t.Errorf("Unexpected ack: %+v", ack)
+			}
+			errs <- err
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		select {
+		case err := <-errs:
+			if !errors.Is(err, jetstream.ErrNoStreamResponse) {
+				t.Fatalf("Expected error: %v; got: %v", jetstream.ErrNoStreamResponse, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Did not receive error")
+		}
+		select {
+		case err := <-asyncErrs:
+			t.Fatalf("Unexpected call of async error handler: %v", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		if err := js.PublishAsyncWithCallback(ctx, &nats.Msg{Subject: "FOO.A"}, nil); !errors.Is(err, jetstream.ErrInvalidOption) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidOption, err)
+		}
+		err := js.PublishAsyncWithCallback(ctx, &nats.Msg{Subject: "FOO.A", Reply: "BAR"}, func(*jetstream.PubAck, error) {})
+		if !errors.Is(err, jetstream.ErrAsyncPublishReplySubjectSet) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrAsyncPublishReplySubjectSet, err)
+		}
+	})
+}
+
+func TestPublishBatch(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	js, err := jetstream.New(nc, jetstream.WithPublishAsyncRetry(0, 0))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("all published", func(t *testing.T) {
+		msgs := make([]*nats.Msg, 1000)
+		for i := range msgs {
+			msgs[i] = &nats.Msg{Subject: "FOO.A", Data: []byte(fmt.Sprintf("msg %d", i))}
+		}
+		results, err := js.PublishBatch(ctx, msgs)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(results) != len(msgs) {
+			t.Fatalf("Expected %d results; got: %d", len(msgs), len(results))
+		}
+		for i, res := range results {
+			if res.Err != nil {
+				t.Fatalf("Unexpected error: %v", res.Err)
+			}
+			if res.Ack.Sequence != uint64(i+1) {
+				t.Fatalf("Expected sequence %d; got: %d", i+1, res.Ack.Sequence)
+			}
+		}
+	})
+
+	t.Run("partial failure", func(t *testing.T) {
+		msgs := []*nats.Msg{
+			{Subject: "FOO.A", Data: []byte("ok")},
+			{Subject: "BAR.A", Data: []byte("no stream")},
+			nil,
+			{Subject: "FOO.B", Data: []byte("ok")},
+		}
+		results, err := js.PublishBatch(ctx, msgs)
+		if !errors.Is(err, jetstream.ErrPublishBatchFailed) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrPublishBatchFailed, err)
+		}
+		if results[0].Err != nil || results[0].Ack == nil || results[3].Err != nil || results[3].Ack == nil {
+			t.Fatalf("Unexpected results: %+v", results)
+		}
+		if !errors.Is(results[1].Err, jetstream.ErrNoStreamResponse) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrNoStreamResponse, results[1].Err)
+		}
+		if !errors.Is(results[2].Err, nats.ErrInvalidMsg) {
+			t.Fatalf("Expected error: %v; got: %v", nats.ErrInvalidMsg, results[2].Err)
+		}
+	})
+
+	t.Run("context done", func(t *testing.T) {
+		// ack requests are not answered
+		sub, err := nc.Subscribe("NOACK.*", func(*nats.Msg) {})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer sub.Unsubscribe()
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		results, err := js.PublishBatch(ctx, []*nats.Msg{{Subject: "NOACK.A"}})
+		if !errors.Is(err, jetstream.ErrPublishBatchFailed) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrPublishBatchFailed, err)
+		}
+		if !errors.Is(results[0].Err, context.DeadlineExceeded) {
+			t.Fatalf("Expected error: %v; got: %v", context.DeadlineExceeded, results[0].Err)
+		}
+	})
+}
+
 func TestPublishMsgAsyncWithPendingMsgs(t *testing.T) {
 	t.Run("outstanding ack exceed limit", func(t *testing.T) {
 		srv := RunBasicJetStreamServer()
@@ -1234,3 +1489,208 @@ func TestPublishMsgAsyncWithPendingMsgs(t *testing.T) {
 		}
 	})
 }
+
+func TestPublishWithMsgTTL(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// the test server does not support message TTLs, but stores the header
+	s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := js.Publish(ctx, "FOO.1", []byte("msg"), jetstream.WithMsgTTL(90*time.Second)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ack, err := js.PublishAsync(ctx, "FOO.2", []byte("msg"), jetstream.WithMsgTTL(time.Hour))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	select {
+	case <-ack.Ok():
+	case err := <-ack.Err():
+		t.Fatalf("Unexpected error: %v", err)
+	case <-time.After(time.Second):
+		t.Fatalf("Did not receive ack")
+	}
+
+	for seq, expected := range map[uint64]string{1: "1m30s", 2: "1h0m0s"} {
+		msg, err := s.GetMsg(ctx, seq)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if ttl := msg.Header.Get(jetstream.MsgTTLHeader); ttl != expected {
+			t.Fatalf("Expected TTL header %q; got: %q", expected, ttl)
+		}
+	}
+
+	for _, ttl := range []time.Duration{0, -time.Second, 500 * time.Millisecond} {
+		_, err := js.Publish(ctx, "FOO.1", []byte("msg"), jetstream.WithMsgTTL(ttl))
+		if !errors.Is(err, jetstream.ErrInvalidOption) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidOption, err)
+		}
+	}
+}
+
+func TestPublishMaxPayloadExceeded(t *testing.T) {
+	opts := natsserver.DefaultTestOptions
+	opts.Port = -1
+	opts.JetStream = true
+	opts.MaxPayload = 1024
+	srv := RunServerWithOptions(opts)
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	checkErr := func(t *testing.T, err error) {
+		t.Helper()
+		if !errors.Is(err, nats.ErrMaxPayload) {
+			t.Fatalf("Expected error: %v; got: %v", nats.ErrMaxPayload, err)
+		}
+		var payloadErr *nats.ErrMaxPayloadExceeded
+		if !errors.As(err, &payloadErr) {
+			t.Fatalf("Expected error of type ErrMaxPayloadExceeded, got: %T", err)
+		}
+		if payloadErr.MaxPayload != 1024 {
+			t.Fatalf("Expected limit 1024; got: %d", payloadErr.MaxPayload)
+		}
+		if payloadErr.Size <= 1024 {
+			t.Fatalf("Expected size over 1024; got: %d", payloadErr.Size)
+		}
+	}
+	data := make([]byte, 1024)
+
+	t.Run("sync publish", func(t *testing.T) {
+		_, err := js.Publish(ctx, "FOO.1", data, jetstream.WithMsgID("1"))
+		if !errors.Is(err, nats.ErrMaxPayload) {
+			t.Fatalf("Expected error: %v; got: %v", nats.ErrMaxPayload, err)
+		}
+	})
+
+	t.Run("async publish", func(t *testing.T) {
+		_, err := js.PublishAsync(ctx, "FOO.1", data, jetstream.WithMsgID("1"))
+		checkErr(t, err)
+		if pending := js.PublishAsyncPending(); pending != 0 {
+			t.Fatalf("Expected no pending messages; got: %d", pending)
+		}
+	})
+
+	t.Run("within limit", func(t *testing.T) {
+		if _, err := js.Publish(ctx, "FOO.1", data[:512]); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+}
+
+func TestPublishStreamLimitsExceeded(t *testing.T) {
+	tests := []struct {
+		name   string
+		config jetstream.StreamConfig
+		limit  jetstream.UsageLimit
+	}{
+		{
+			name:   "max msgs",
+			config: jetstream.StreamConfig{MaxMsgs: 1},
+			limit:  jetstream.UsageLimitMsgs,
+		},
+		{
+			name:   "max bytes",
+			config: jetstream.StreamConfig{MaxBytes: 100},
+			limit:  jetstream.UsageLimitBytes,
+		},
+		{
+			name:   "max msgs per subject",
+			config: jetstream.StreamConfig{MaxMsgsPerSubject: 1, DiscardNewPerSubject: true},
+			limit:  jetstream.UsageLimitMsgsPerSubject,
+		},
+	}
+
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	checkErr := func(t *testing.T, err error, limit jetstream.UsageLimit) {
+		t.Helper()
+		var limitsErr *jetstream.ErrStreamLimitsExceeded
+		if !errors.As(err, &limitsErr) {
+			t.Fatalf("Expected error of type ErrStreamLimitsExceeded; got: %v", err)
+		}
+		if limitsErr.Limit != limit {
+			t.Fatalf("Expected limit %v; got: %v", limit, limitsErr.Limit)
+		}
+		if !errors.Is(err, &jetstream.APIError{ErrorCode: jetstream.JSErrCodeStreamStoreFailed}) {
+			t.Fatalf("Expected API error to be wrapped; got: %v", err)
+		}
+	}
+
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := test.config
+			cfg.Name = fmt.Sprintf("limits%d", i)
+			cfg.Subjects = []string{fmt.Sprintf("LIMITS%d.*", i)}
+			cfg.Discard = jetstream.DiscardNew
+			if _, err := js.CreateStream(ctx, cfg); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			subject := fmt.Sprintf("LIMITS%d.A", i)
+			data := make([]byte, 60)
+			if _, err := js.Publish(ctx, subject, data); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			_, err := js.Publish(ctx, subject, data)
+			checkErr(t, err, test.limit)
+
+			ack, err := js.PublishAsync(ctx, subject, data)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			select {
+			case err := <-ack.Err():
+				checkErr(t, err, test.limit)
+			case <-ack.Ok():
+				t.Fatalf("Expected publish to be rejected")
+			case <-time.After(time.Second):
+				t.Fatalf("Did not receive ack")
+			}
+		})
+	}
+}
@@ -15,8 +15,11 @@ package test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"reflect"
 	"sync"
 	"testing"
 	"time"
@@ -351,6 +354,106 @@ func TestPullConsumerFetch(t *testing.T) {
 			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidOption, err)
 		}
 	})
+
+	t.Run("with heartbeat", func(t *testing.T) {
+		srv := RunBasicJetStreamServer()
+		defer shutdownJSServerAndRemoveStorage(t, srv)
+		dialer := &pausableDialer{}
+		nc, err := nats.Connect(srv.ClientURL(), nats.SetCustomDialer(dialer))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		js, err := jetstream.New(nc)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer nc.Close()
+
+		s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		c, err := s.AddConsumer(ctx, jetstream.ConsumerConfig{AckPolicy: jetstream.AckExplicitPolicy})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		// heartbeats are filtered out while waiting for messages
+		msgs, err := c.Fetch(5, jetstream.FetchMaxWait(time.Second), jetstream.FetchHeartbeat(200*time.Millisecond))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		time.AfterFunc(500*time.Millisecond, func() {
+			for _, msg := range testMsgs {
+				nc.Publish(testSubject, []byte(msg))
+			}
+		})
+		var i int
+		for range msgs.Messages() {
+			i++
+		}
+		if msgs.Error() != nil {
+			t.Fatalf("Unexpected error during fetch: %v", msgs.Error())
+		}
+		if i != len(testMsgs) {
+			t.Fatalf("Invalid number of messages received; want: %d; got: %d", len(testMsgs), i)
+		}
+
+		// missing heartbeats stop fetching before max wait
+		msgs, err = c.Fetch(5, jetstream.FetchMaxWait(10*time.Second), jetstream.FetchHeartbeat(500*time.Millisecond))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		start := time.Now()
+		dialer.pause(true)
+		defer dialer.pause(false)
+		for range msgs.Messages() {
+		}
+		if !errors.Is(msgs.Error(), jetstream.ErrNoHeartbeat) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrNoHeartbeat, msgs.Error())
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Fatalf("Expected fetch to stop early; took %v", elapsed)
+		}
+	})
+
+	t.Run("with invalid heartbeat value", func(t *testing.T) {
+		srv := RunBasicJetStreamServer()
+		defer shutdownJSServerAndRemoveStorage(t, srv)
+		nc, err := nats.Connect(srv.ClientURL())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		js, err := jetstream.New(nc)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer nc.Close()
+
+		s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		c, err := s.AddConsumer(ctx, jetstream.ConsumerConfig{AckPolicy: jetstream.AckExplicitPolicy})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		_, err = c.Fetch(5, jetstream.FetchHeartbeat(-time.Second))
+		if !errors.Is(err, jetstream.ErrInvalidOption) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidOption, err)
+		}
+		_, err = c.Fetch(5, jetstream.FetchMaxWait(time.Second), jetstream.FetchHeartbeat(time.Second))
+		if !errors.Is(err, jetstream.ErrInvalidOption) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidOption, err)
+		}
+	})
 }
 
 func TestPullConsumerFetchBytes(t *testing.T) {
@@ -718,6 +821,57 @@ func TestPullConsumerMessages(t *testing.T) {
 		}
 	})
 
+	t.Run("next with context", func(t *testing.T) {
+		srv := RunBasicJetStreamServer()
+		defer shutdownJSServerAndRemoveStorage(t, srv)
+		nc, err := nats.Connect(srv.ClientURL())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		js, err := jetstream.New(nc)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer nc.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+		s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		c, err := s.AddConsumer(ctx, jetstream.ConsumerConfig{AckPolicy: jetstream.AckExplicitPolicy})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		it, err := c.Messages()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer it.Stop()
+
+		// No messages yet, so the context deadline is reached.
+		nextCtx, nextCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer nextCancel()
+		if _, err := it.NextWithContext(nextCtx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected error: %v; got: %v", context.DeadlineExceeded, err)
+		}
+
+		publishTestMsgs(t, nc)
+		for i := 0; i < len(testMsgs); i++ {
+			msg, err := it.NextWithContext(ctx)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(msg.Data()) != testMsgs[i] {
+				t.Fatalf("Invalid msg on index %d; expected: %s; got: %s", i, testMsgs[i], string(msg.Data()))
+			}
+			msg.Ack()
+		}
+	})
+
 	t.Run("with custom batch size", func(t *testing.T) {
 		srv := RunBasicJetStreamServer()
 		defer shutdownJSServerAndRemoveStorage(t, srv)
@@ -1799,6 +1953,123 @@ func TestPullConsumerConsume(t *testing.T) {
 		}
 	})
 
+	t.Run("with stall timeout", func(t *testing.T) {
+		// pull requests are denied until config reload, simulating
+		// a pull request lost e.g. during reconnect
+		conf := createConfFile(t, []byte(`
+			listen: 127.0.0.1:-1
+			jetstream: {max_mem_store: 64MB, max_file_store: 64MB}
+			authorization: {
+				users: [ {user: test, password: test, permissions: {publish: {deny: "$JS.API.CONSUMER.MSG.NEXT.>"}}} ]
+			}
+		`))
+		defer os.Remove(conf)
+		srv, _ := RunServerWithConfig(conf)
+		defer shutdownJSServerAndRemoveStorage(t, srv)
+		nc, err := nats.Connect(srv.ClientURL(), nats.UserInfo("test", "test"), nats.ErrorHandler(func(*nats.Conn, *nats.Subscription, error) {}))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		js, err := jetstream.New(nc)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer nc.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		c, err := s.AddConsumer(ctx, jetstream.ConsumerConfig{AckPolicy: jetstream.AckExplicitPolicy})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		stalled := make(chan struct{}, 1)
+		msgs := make(chan jetstream.Msg, len(testMsgs))
+		l, err := c.Consume(func(msg jetstream.Msg) {
+			msgs <- msg
+		},
+			jetstream.ConsumeStallTimeout(time.Second),
+			jetstream.ConsumeErrHandler(func(_ jetstream.ConsumeContext, err error) {
+				if errors.Is(err, jetstream.ErrConsumerStalled) {
+					select {
+					case stalled <- struct{}{}:
+					default:
+					}
+				}
+			}))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer l.Stop()
+		publishTestMsgs(t, nc)
+		if err := nc.Flush(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if err := os.WriteFile(conf, []byte(`
+			listen: 127.0.0.1:-1
+			jetstream: {max_mem_store: 64MB, max_file_store: 64MB}
+			authorization: {
+				users: [ {user: test, password: test} ]
+			}
+		`), 0666); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := srv.Reload(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		select {
+		case <-stalled:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected stall to be reported")
+		}
+		for i := 0; i < len(testMsgs); i++ {
+			select {
+			case msg := <-msgs:
+				if string(msg.Data()) != testMsgs[i] {
+					t.Fatalf("Invalid msg on index %d; expected: %s; got: %s", i, testMsgs[i], string(msg.Data()))
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("Timeout waiting for message %d", i)
+			}
+		}
+	})
+
+	t.Run("with invalid stall timeout", func(t *testing.T) {
+		srv := RunBasicJetStreamServer()
+		defer shutdownJSServerAndRemoveStorage(t, srv)
+		nc, err := nats.Connect(srv.ClientURL())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		js, err := jetstream.New(nc)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer nc.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		c, err := s.AddConsumer(ctx, jetstream.ConsumerConfig{AckPolicy: jetstream.AckExplicitPolicy})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		_, err = c.Consume(func(_ jetstream.Msg) {}, jetstream.ConsumeStallTimeout(100*time.Millisecond))
+		if err == nil || !errors.Is(err, jetstream.ErrInvalidOption) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidOption, err)
+		}
+	})
+
 	t.Run("with server restart", func(t *testing.T) {
 		srv := RunBasicJetStreamServer()
 		nc, err := nats.Connect(srv.ClientURL())
@@ -1985,6 +2256,353 @@ func TestPullConsumerConsume_WithCluster(t *testing.T) {
 	})
 }
 
+func TestPullConsumerConsumeEvents(t *testing.T) {
+	t.Run("heartbeat missed", func(t *testing.T) {
+		srv := RunBasicJetStreamServer()
+		defer shutdownJSServerAndRemoveStorage(t, srv)
+		dialer := &pausableDialer{}
+		nc, err := nats.Connect(srv.ClientURL(), nats.SetCustomDialer(dialer))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer nc.Close()
+		js, err := jetstream.New(nc)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		c, err := s.AddConsumer(ctx, jetstream.ConsumerConfig{AckPolicy: jetstream.AckExplicitPolicy})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		missed := make(chan struct{}, 10)
+		l, err := c.Consume(func(msg jetstream.Msg) {},
+			jetstream.PullHeartbeat(time.Second),
+			jetstream.ConsumeOnHeartbeatMissed(func(jetstream.ConsumeContext) {
+				missed <- struct{}{}
+			}))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer l.Stop()
+
+		dialer.pause(true)
+		select {
+		case <-missed:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timeout waiting for missed heartbeat")
+		}
+		dialer.pause(false)
+	})
+
+	t.Run("consumer deleted", func(t *testing.T) {
+		srv := RunBasicJetStreamServer()
+		defer shutdownJSServerAndRemoveStorage(t, srv)
+		nc, err := nats.Connect(srv.ClientURL())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer nc.Close()
+		js, err := jetstream.New(nc)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		c, err := s.AddConsumer(ctx, jetstream.ConsumerConfig{AckPolicy: jetstream.AckExplicitPolicy})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		deleted := make(chan struct{}, 10)
+		l, err := c.Consume(func(msg jetstream.Msg) {},
+			jetstream.ConsumeOnConsumerDeleted(func(jetstream.ConsumeContext) {
+				deleted <- struct{}{}
+			}))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer l.Stop()
+
+		waitForPullRequest(t, ctx, c)
+		if err := s.DeleteConsumer(ctx, c.CachedInfo().Name); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		select {
+		case <-deleted:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timeout waiting for consumer deleted event")
+		}
+	})
+
+	t.Run("server shutdown", func(t *testing.T) {
+		srv := RunBasicJetStreamServer()
+		nc, err := nats.Connect(srv.ClientURL())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer nc.Close()
+		js, err := jetstream.New(nc)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		c, err := s.AddConsumer(ctx, jetstream.ConsumerConfig{Durable: "cons", AckPolicy: jetstream.AckExplicitPolicy})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		shutdown := make(chan struct{}, 10)
+		received := make(chan jetstream.Msg, 10)
+		l, err := c.Consume(func(msg jetstream.Msg) {
+			received <- msg
+		}, jetstream.ConsumeOnServerShutdown(func(jetstream.ConsumeContext) {
+			shutdown <- struct{}{}
+		}))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer l.Stop()
+		time.Sleep(50 * time.Millisecond)
+
+		srv = restartBasicJSServer(t, srv)
+		defer shutdownJSServerAndRemoveStorage(t, srv)
+		select {
+		case <-shutdown:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timeout waiting for server shutdown event")
+		}
+
+		// consuming continues after reconnect
+		if _, err := js.Publish(ctx, "FOO.1", []byte("msg")); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		select {
+		case <-received:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("Timeout waiting for message after reconnect")
+		}
+	})
+
+	t.Run("ordered reset", func(t *testing.T) {
+		srv := RunBasicJetStreamServer()
+		defer shutdownJSServerAndRemoveStorage(t, srv)
+		nc, err := nats.Connect(srv.ClientURL())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer nc.Close()
+		js, err := jetstream.New(nc)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		c, err := s.OrderedConsumer(ctx, jetstream.OrderedConsumerConfig{})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		resets := make(chan error, 10)
+		l, err := c.Consume(func(msg jetstream.Msg) {},
+			jetstream.ConsumeOnOrderedReset(func(_ jetstream.ConsumeContext, reason error) {
+				resets <- reason
+			}))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer l.Stop()
+
+		waitForPullRequest(t, ctx, c)
+		name := c.CachedInfo().Name
+		if err := s.DeleteConsumer(ctx, name); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		select {
+		case reason := <-resets:
+			if !errors.Is(reason, jetstream.ErrConsumerDeleted) {
+				t.Fatalf("Expected reason: %v; got: %v", jetstream.ErrConsumerDeleted, reason)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timeout waiting for ordered reset event")
+		}
+		if c.CachedInfo().Name == name {
+			t.Fatalf("Expected consumer to be recreated")
+		}
+	})
+}
+
+func TestPullConsumerThresholds(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// pullRequests returns the batch sizes of the pull requests received within the timeout
+	pullRequests := func(t *testing.T, sub *nats.Subscription, timeout time.Duration) []int {
+		t.Helper()
+		var batches []int
+		for {
+			msg, err := sub.NextMsg(timeout)
+			if errors.Is(err, nats.ErrTimeout) {
+				return batches
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var req struct {
+				Batch int `json:"batch"`
+			}
+			if err := json.Unmarshal(msg.Data, &req); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			batches = append(batches, req.Batch)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		threshold int
+		expected  []int
+	}{
+		// 7 messages are still expected after receiving 3,
+		// which is below the threshold of 8
+		{"refill early", 8, []int{10, 3}},
+		// the default threshold of 5 is not reached
+		{"default threshold", 0, []int{10}},
+	}
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := s.AddConsumer(ctx, jetstream.ConsumerConfig{
+				Durable:       fmt.Sprintf("cons%d", i),
+				AckPolicy:     jetstream.AckExplicitPolicy,
+				DeliverPolicy: jetstream.DeliverNewPolicy,
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			sub, err := nc.SubscribeSync(fmt.Sprintf("$JS.API.CONSUMER.MSG.NEXT.foo.cons%d", i))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			defer sub.Unsubscribe()
+
+			opts := []jetstream.PullConsumeOpt{jetstream.PullMaxMessages(10)}
+			if test.threshold > 0 {
+				opts = append(opts, jetstream.PullThresholdMessages(test.threshold))
+			}
+			received := make(chan jetstream.Msg, 10)
+			cc, err := c.Consume(func(msg jetstream.Msg) {
+				msg.Ack()
+				received <- msg
+			}, opts...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			defer cc.Stop()
+
+			for j := 0; j < 3; j++ {
+				if _, err := js.Publish(ctx, "FOO.1", []byte("msg")); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				select {
+				case <-received:
+				case <-time.After(5 * time.Second):
+					t.Fatalf("Timeout waiting for message")
+				}
+			}
+			if batches := pullRequests(t, sub, 200*time.Millisecond); !reflect.DeepEqual(batches, test.expected) {
+				t.Fatalf("Expected pull requests %v; got %v", test.expected, batches)
+			}
+		})
+	}
+
+	t.Run("messages", func(t *testing.T) {
+		c, err := s.AddConsumer(ctx, jetstream.ConsumerConfig{Durable: "iter", AckPolicy: jetstream.AckExplicitPolicy})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		it, err := c.Messages(jetstream.PullMaxMessages(10), jetstream.PullThresholdMessages(8))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		it.Stop()
+	})
+
+	t.Run("invalid thresholds", func(t *testing.T) {
+		c, err := s.AddConsumer(ctx, jetstream.ConsumerConfig{Durable: "invalid", AckPolicy: jetstream.AckExplicitPolicy})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		invalid := [][]jetstream.PullConsumeOpt{
+			{jetstream.PullThresholdMessages(0)},
+			{jetstream.PullMaxMessages(10), jetstream.PullThresholdMessages(11)},
+			{jetstream.PullMaxBytes(1024), jetstream.PullThresholdBytes(2048)},
+		}
+		for _, opts := range invalid {
+			if _, err := c.Consume(func(jetstream.Msg) {}, opts...); !errors.Is(err, jetstream.ErrInvalidOption) {
+				t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidOption, err)
+			}
+		}
+		if _, err := c.Messages(jetstream.PullMaxMessages(10), jetstream.PullThresholdMessages(11)); !errors.Is(err, jetstream.ErrInvalidOption) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidOption, err)
+		}
+	})
+}
+
+// waitForPullRequest waits until a pull request of the consumer is waiting on the server.
+func waitForPullRequest(t *testing.T, ctx context.Context, c jetstream.Consumer) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		info, err := c.Info(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if info.NumWaiting > 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Timeout waiting for pull request")
+}
+
 func TestPullConsumerNext(t *testing.T) {
 	testSubject := "FOO.123"
 	testMsgs := []string{"m1", "m2", "m3", "m4", "m5"}
@@ -0,0 +1,199 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+func TestSequencedPublisher(t *testing.T) {
+	// publishes to FOO.lost are dropped by the server until config
+	// reload, simulating messages lost e.g. during reconnect
+	conf := createConfFile(t, []byte(`
+		listen: 127.0.0.1:-1
+		jetstream: {max_mem_store: 64MB, max_file_store: 64MB}
+		authorization: {
+			users: [ {user: test, password: test, permissions: {publish: {deny: "FOO.lost"}}} ]
+		}
+	`))
+	defer os.Remove(conf)
+	srv, _ := RunServerWithConfig(conf)
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL(), nats.UserInfo("test", "test"), nats.ErrorHandler(func(*nats.Conn, *nats.Subscription, error) {}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := jetstream.NewSequencedPublisher(ctx, js, "foo"); !errors.Is(err, jetstream.ErrStreamNotFound) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrStreamNotFound, err)
+	}
+	if _, err := jetstream.NewSequencedPublisher(ctx, js, "foo", jetstream.WithSequencedRepublish(-1)); !errors.Is(err, jetstream.ErrInvalidOption) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidOption, err)
+	}
+	if _, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := js.Publish(ctx, "FOO.A", []byte("existing")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	gaps := make(chan [2]uint64, 1)
+	p, err := jetstream.NewSequencedPublisher(ctx, js, "foo", jetstream.WithSequenceGapHandler(func(first, last uint64) {
+		gaps <- [2]uint64{first, last}
+	}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("contiguous acks", func(t *testing.T) {
+		var futures []jetstream.PubAckFuture
+		for i := 0; i < 100; i++ {
+			fut, err := p.PublishAsync(ctx, "FOO.A", []byte(fmt.Sprintf("msg %d", i)))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			futures = append(futures, fut)
+		}
+		if err := p.Flush(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for i, fut := range futures {
+			select {
+			case ack := <-fut.Ok():
+				if ack.Sequence != uint64(i+2) {
+					t.Fatalf("Expected sequence %d; got: %d", i+2, ack.Sequence)
+				}
+			case err := <-fut.Err():
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+		if seq := p.LastSequence(); seq != 101 {
+			t.Fatalf("Expected last sequence 101; got: %d", seq)
+		}
+	})
+
+	t.Run("lost publish", func(t *testing.T) {
+		lost, err := p.PublishAsync(ctx, "FOO.lost", []byte("lost"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		ok, err := p.PublishAsync(ctx, "FOO.A", []byte("ok"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := p.Flush(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		select {
+		case err := <-lost.Err():
+			if !errors.Is(err, jetstream.ErrPublishLost) {
+				t.Fatalf("Expected error: %v; got: %v", jetstream.ErrPublishLost, err)
+			}
+		default:
+			t.Fatalf("Expected lost publish to fail")
+		}
+		select {
+		case ack := <-ok.Ok():
+			if ack.Sequence != 102 {
+				t.Fatalf("Expected sequence 102; got: %d", ack.Sequence)
+			}
+		default:
+			t.Fatalf("Expected publish to be acknowledged")
+		}
+	})
+
+	t.Run("gap", func(t *testing.T) {
+		if _, err := js.Publish(ctx, "FOO.B", []byte("other publisher")); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := p.PublishAsync(ctx, "FOO.A", []byte("ok")); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := p.Flush(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		select {
+		case gap := <-gaps:
+			if gap != [2]uint64{103, 103} {
+				t.Fatalf("Expected gap 103-103; got: %v", gap)
+			}
+		default:
+			t.Fatalf("Expected gap to be reported")
+		}
+		if seq := p.LastSequence(); seq != 104 {
+			t.Fatalf("Expected last sequence 104; got: %d", seq)
+		}
+	})
+
+	t.Run("republish lost messages", func(t *testing.T) {
+		p, err := jetstream.NewSequencedPublisher(ctx, js, "foo", jetstream.WithSequencedRepublish(1))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		lost, err := p.PublishAsync(ctx, "FOO.lost", []byte("lost"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if lost.Msg().Header.Get(jetstream.MsgIDHeader) == "" {
+			t.Fatalf("Expected message ID to be set")
+		}
+		if err := nc.Flush(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := os.WriteFile(conf, []byte(`
+			listen: 127.0.0.1:-1
+			jetstream: {max_mem_store: 64MB, max_file_store: 64MB}
+			authorization: {
+				users: [ {user: test, password: test} ]
+			}
+		`), 0666); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := srv.Reload(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if _, err := p.PublishAsync(ctx, "FOO.A", []byte("ok")); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := p.Flush(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		select {
+		case ack := <-lost.Ok():
+			if ack.Sequence != 106 {
+				t.Fatalf("Expected sequence 106; got: %d", ack.Sequence)
+			}
+		case err := <-lost.Err():
+			t.Fatalf("Unexpected error: %v", err)
+		default:
+			t.Fatalf("Expected republished message to be acknowledged")
+		}
+	})
+}
@@ -15,6 +15,7 @@ package test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
@@ -49,9 +50,34 @@ func TestAddConsumer(t *testing.T) {
 			shouldCreate:   true,
 		},
 		{
+			name:           "with single filter subject in filter subjects",
+			consumerConfig: jetstream.ConsumerConfig{FilterSubjects: []string{"FOO.A"}},
+		},
+		{
+			// Test server does not support multiple filter subjects.
 			name:           "with multiple filter subjects",
 			consumerConfig: jetstream.ConsumerConfig{FilterSubjects: []string{"FOO.A", "FOO.B"}},
-			shouldCreate:   true,
+			withError:      jetstream.ErrConsumerMultipleFilterSubjectsNotSupported,
+		},
+		{
+			name:           "with filter subject and filter subjects",
+			consumerConfig: jetstream.ConsumerConfig{FilterSubject: "FOO.A", FilterSubjects: []string{"FOO.B"}},
+			withError:      jetstream.ErrConflictingFilterSubjects,
+		},
+		{
+			name:           "with empty filter subject",
+			consumerConfig: jetstream.ConsumerConfig{FilterSubjects: []string{"FOO.A", ""}},
+			withError:      jetstream.ErrEmptyFilter,
+		},
+		{
+			name:           "with duplicate filter subjects",
+			consumerConfig: jetstream.ConsumerConfig{FilterSubjects: []string{"FOO.A", "FOO.A"}},
+			withError:      jetstream.ErrDuplicateFilterSubjects,
+		},
+		{
+			name:           "with overlapping filter subjects",
+			consumerConfig: jetstream.ConsumerConfig{FilterSubjects: []string{"FOO.A", "FOO.*"}},
+			withError:      jetstream.ErrOverlappingFilterSubjects,
 		},
 		{
 			name:           "consumer already exists, update",
@@ -123,6 +149,106 @@ func TestAddConsumer(t *testing.T) {
 	}
 }
 
+func TestUpdateConsumer(t *testing.T) {
+	tests := []struct {
+		name           string
+		consumerConfig jetstream.ConsumerConfig
+		withError      error
+	}{
+		{
+			name:           "update description and max ack pending",
+			consumerConfig: jetstream.ConsumerConfig{Durable: "dur", AckPolicy: jetstream.AckExplicitPolicy, Description: "updated", MaxAckPending: 10},
+		},
+		{
+			name:           "update by name",
+			consumerConfig: jetstream.ConsumerConfig{Name: "dur", Durable: "dur", AckPolicy: jetstream.AckExplicitPolicy, Description: "by name"},
+		},
+		{
+			name:           "consumer does not exist",
+			consumerConfig: jetstream.ConsumerConfig{Durable: "abc", AckPolicy: jetstream.AckExplicitPolicy},
+			withError:      jetstream.ErrConsumerNotFound,
+		},
+		{
+			name:           "illegal update",
+			consumerConfig: jetstream.ConsumerConfig{Durable: "dur", AckPolicy: jetstream.AckNonePolicy},
+			withError:      jetstream.ErrConsumerCreate,
+		},
+		{
+			name:           "consumer name required",
+			consumerConfig: jetstream.ConsumerConfig{Description: "ephemeral"},
+			withError:      jetstream.ErrConsumerNameRequired,
+		},
+		{
+			name:           "invalid durable name",
+			consumerConfig: jetstream.ConsumerConfig{Durable: "dur.123"},
+			withError:      jetstream.ErrInvalidConsumerName,
+		},
+	}
+
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := s.AddConsumer(ctx, jetstream.ConsumerConfig{Durable: "dur", AckPolicy: jetstream.AckExplicitPolicy}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := s.UpdateConsumer(ctx, test.consumerConfig)
+			if test.withError != nil {
+				if err == nil || !errors.Is(err, test.withError) {
+					t.Fatalf("Expected error: %v; got: %v", test.withError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if c.CachedInfo().Config.Description != test.consumerConfig.Description {
+				t.Fatalf("Invalid description; want: %s; got: %s", test.consumerConfig.Description, c.CachedInfo().Config.Description)
+			}
+			ci, err := s.Consumer(ctx, "dur")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if ci.CachedInfo().Config.Description != test.consumerConfig.Description {
+				t.Fatalf("Invalid description; want: %s; got: %s", test.consumerConfig.Description, ci.CachedInfo().Config.Description)
+			}
+		})
+	}
+
+	t.Run("does not create missing consumer", func(t *testing.T) {
+		if _, err := js.UpdateConsumer(ctx, "foo", jetstream.ConsumerConfig{Durable: "abc"}); !errors.Is(err, jetstream.ErrConsumerNotFound) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrConsumerNotFound, err)
+		}
+		if _, err := s.Consumer(ctx, "abc"); !errors.Is(err, jetstream.ErrConsumerNotFound) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrConsumerNotFound, err)
+		}
+	})
+
+	t.Run("stream does not exist", func(t *testing.T) {
+		if _, err := js.UpdateConsumer(ctx, "bar", jetstream.ConsumerConfig{Durable: "dur"}); !errors.Is(err, jetstream.ErrStreamNotFound) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrStreamNotFound, err)
+		}
+	})
+}
+
 func TestConsumer(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -687,6 +813,224 @@ func TestGetLastMsgForSubject(t *testing.T) {
 	}
 }
 
+func TestBrowse(t *testing.T) {
+	for _, allowDirect := range []bool{false, true} {
+		t.Run(fmt.Sprintf("allow direct %t", allowDirect), func(t *testing.T) {
+			srv := RunBasicJetStreamServer()
+			defer shutdownJSServerAndRemoveStorage(t, srv)
+			nc, err := nats.Connect(srv.ClientURL())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			defer nc.Close()
+
+			js, err := jetstream.New(nc)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}, AllowDirect: allowDirect})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			// Odd sequences are published on FOO.A, even on FOO.B.
+			var midTime time.Time
+			for i := 1; i <= 10; i++ {
+				if i == 6 {
+					time.Sleep(10 * time.Millisecond)
+					midTime = time.Now()
+					time.Sleep(10 * time.Millisecond)
+				}
+				subject := "FOO.A"
+				if i%2 == 0 {
+					subject = "FOO.B"
+				}
+				if _, err := js.Publish(ctx, subject, []byte(fmt.Sprintf("msg %d", i))); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+			if err := s.DeleteMsg(ctx, 4); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			browse := func(t *testing.T, expected []uint64, opts ...jetstream.BrowseOpt) string {
+				t.Helper()
+				res, err := s.Browse(ctx, opts...)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				seqs := make([]uint64, 0, len(res.Messages))
+				for _, msg := range res.Messages {
+					seqs = append(seqs, msg.Sequence)
+				}
+				if !reflect.DeepEqual(seqs, expected) {
+					t.Fatalf("Expected sequences %v; got: %v", expected, seqs)
+				}
+				return res.Cursor
+			}
+
+			t.Run("forward pages", func(t *testing.T) {
+				cursor := browse(t, []uint64{1, 2, 3}, jetstream.WithBrowseLimit(3))
+				cursor = browse(t, []uint64{5, 6, 7}, jetstream.WithBrowseLimit(3), jetstream.WithBrowseCursor(cursor))
+				cursor = browse(t, []uint64{8, 9, 10}, jetstream.WithBrowseLimit(3), jetstream.WithBrowseCursor(cursor))
+				if cursor = browse(t, []uint64{}, jetstream.WithBrowseCursor(cursor)); cursor != "" {
+					t.Fatalf("Expected empty cursor; got: %q", cursor)
+				}
+			})
+
+			t.Run("backward pages", func(t *testing.T) {
+				cursor := browse(t, []uint64{10, 9, 8, 7}, jetstream.WithBrowseBackward(), jetstream.WithBrowseLimit(4))
+				// New messages do not shift the following pages.
+				if _, err := js.Publish(ctx, "FOO.A", []byte("msg 11")); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				defer s.DeleteMsg(ctx, 11)
+				cursor = browse(t, []uint64{6, 5, 3, 2}, jetstream.WithBrowseLimit(4), jetstream.WithBrowseCursor(cursor))
+				if cursor = browse(t, []uint64{1}, jetstream.WithBrowseLimit(4), jetstream.WithBrowseCursor(cursor)); cursor != "" {
+					t.Fatalf("Expected empty cursor; got: %q", cursor)
+				}
+			})
+
+			t.Run("subject filter", func(t *testing.T) {
+				cursor := browse(t, []uint64{1, 3}, jetstream.WithBrowseSubject("FOO.A"), jetstream.WithBrowseLimit(2))
+				browse(t, []uint64{5, 7, 9}, jetstream.WithBrowseCursor(cursor))
+				cursor = browse(t, []uint64{10, 8}, jetstream.WithBrowseSubject("FOO.B"), jetstream.WithBrowseBackward(), jetstream.WithBrowseLimit(2))
+				browse(t, []uint64{6, 2}, jetstream.WithBrowseCursor(cursor))
+				browse(t, []uint64{10, 9, 8, 7}, jetstream.WithBrowseSubject("FOO.*"), jetstream.WithBrowseBackward(), jetstream.WithBrowseLimit(4))
+			})
+
+			t.Run("start sequence", func(t *testing.T) {
+				browse(t, []uint64{5}, jetstream.WithBrowseStartSeq(4), jetstream.WithBrowseLimit(1))
+				browse(t, []uint64{3, 2}, jetstream.WithBrowseStartSeq(4), jetstream.WithBrowseBackward(), jetstream.WithBrowseLimit(2))
+			})
+
+			t.Run("start time", func(t *testing.T) {
+				browse(t, []uint64{6, 7}, jetstream.WithBrowseStartTime(midTime), jetstream.WithBrowseLimit(2))
+				browse(t, []uint64{5, 3}, jetstream.WithBrowseStartTime(midTime), jetstream.WithBrowseBackward(), jetstream.WithBrowseLimit(2))
+				browse(t, []uint64{}, jetstream.WithBrowseStartTime(time.Now().Add(time.Hour)))
+				browse(t, []uint64{1}, jetstream.WithBrowseStartTime(time.Now().Add(-time.Hour)), jetstream.WithBrowseLimit(1))
+			})
+
+			t.Run("headers only", func(t *testing.T) {
+				res, err := s.Browse(ctx, jetstream.WithBrowseHeadersOnly(), jetstream.WithBrowseLimit(1))
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if len(res.Messages) != 1 || res.Messages[0].Data != nil || res.Messages[0].Header.Get(jetstream.MsgSizeHeader) != "5" {
+					t.Fatalf("Unexpected message: %+v", res.Messages)
+				}
+			})
+
+			t.Run("invalid options", func(t *testing.T) {
+				for _, opts := range [][]jetstream.BrowseOpt{
+					{jetstream.WithBrowseLimit(0)},
+					{jetstream.WithBrowseSubject("")},
+					{jetstream.WithBrowseCursor("invalid")},
+					{jetstream.WithBrowseStartSeq(1), jetstream.WithBrowseStartTime(time.Now())},
+				} {
+					if _, err := s.Browse(ctx, opts...); !errors.Is(err, jetstream.ErrInvalidOption) {
+						t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidOption, err)
+					}
+				}
+			})
+		})
+	}
+}
+
+func TestGetMsgDirect(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, allowDirect := range []bool{false, true} {
+		t.Run(fmt.Sprintf("allow direct %t", allowDirect), func(t *testing.T) {
+			name := fmt.Sprintf("direct_%t", allowDirect)
+			s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: name, Subjects: []string{name + ".*"}, AllowDirect: allowDirect})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for i := 1; i <= 3; i++ {
+				if _, err := js.Publish(ctx, name+".A", []byte(fmt.Sprintf("msg %d", i))); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+			directReqs, err := nc.SubscribeSync("$JS.API.DIRECT.GET." + name)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			directLastReqs, err := nc.SubscribeSync("$JS.API.DIRECT.GET." + name + ".>")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			apiReqs, err := nc.SubscribeSync("$JS.API.STREAM.MSG.GET." + name)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			expectRequest := func(t *testing.T, sub *nats.Subscription) {
+				t.Helper()
+				if _, err := sub.NextMsg(time.Second); err != nil {
+					t.Fatalf("Expected request on %s; got: %v", sub.Subject, err)
+				}
+			}
+			directSub := apiReqs
+			directLastSub := apiReqs
+			if allowDirect {
+				directSub = directReqs
+				directLastSub = directLastReqs
+			}
+
+			msg, err := s.GetMsgDirect(ctx, 2)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(msg.Data) != "msg 2" || msg.Sequence != 2 || msg.Subject != name+".A" {
+				t.Fatalf("Unexpected message: %+v", msg)
+			}
+			expectRequest(t, directSub)
+
+			msg, err = s.GetLastMsgForSubjectDirect(ctx, name+".A")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(msg.Data) != "msg 3" {
+				t.Fatalf("Unexpected message: %+v", msg)
+			}
+			expectRequest(t, directLastSub)
+
+			if _, err := s.GetMsgDirect(ctx, 10); !errors.Is(err, jetstream.ErrMsgNotFound) {
+				t.Fatalf("Expected error: %v; got: %v", jetstream.ErrMsgNotFound, err)
+			}
+			expectRequest(t, directSub)
+			if _, err := s.GetLastMsgForSubjectDirect(ctx, name+".Z"); !errors.Is(err, jetstream.ErrMsgNotFound) {
+				t.Fatalf("Expected error: %v; got: %v", jetstream.ErrMsgNotFound, err)
+			}
+			expectRequest(t, directLastSub)
+
+			// Regular gets are always answered by the stream leader.
+			if _, err := s.GetMsg(ctx, 1); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			expectRequest(t, apiReqs)
+			if _, err := directReqs.NextMsg(50 * time.Millisecond); err == nil {
+				t.Fatalf("Unexpected direct get request")
+			}
+		})
+	}
+}
+
 func TestDeleteMsg(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -816,6 +1160,9 @@ func TestSecureDeleteMsg(t *testing.T) {
 				if err == nil || !errors.Is(err, test.withError) {
 					t.Fatalf("Expected error: %v; got: %v", test.withError, err)
 				}
+				if !strings.HasPrefix(err.Error(), jetstream.ErrMsgDeleteUnsuccessful.Error()+": ") || strings.Contains(err.Error(), "%!") {
+					t.Fatalf("Expected server error description; got: %v", err)
+				}
 				return
 			}
 			if err != nil {
@@ -1034,13 +1381,19 @@ func TestPurgeStream(t *testing.T) {
 					t.Fatalf("Unexpected error: %v", err)
 				}
 			}
-			err = s.Purge(ctx, test.opts...)
+			purged, err := s.Purge(ctx, test.opts...)
 			if test.withError != nil {
 				if err == nil || !errors.Is(err, test.withError) {
 					t.Fatalf("Expected error: %v; got: %v", test.withError, err)
 				}
 				return
 			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if expected := uint64(10 - len(test.expectedSeq)); purged != expected {
+				t.Fatalf("Invalid purged count; want: %d; got: %d", expected, purged)
+			}
 			c, err := s.AddConsumer(ctx, jetstream.ConsumerConfig{AckPolicy: jetstream.AckExplicitPolicy})
 			if err != nil {
 				t.Fatalf("Unexpected error: %v", err)
@@ -1074,3 +1427,497 @@ func TestPurgeStream(t *testing.T) {
 		})
 	}
 }
+
+func TestGetLastMsgsForSubjects(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	collect := func(t *testing.T, l jetstream.RawStreamMsgLister) ([]string, error) {
+		t.Helper()
+		var msgs []string
+		for {
+			select {
+			case msg := <-l.Messages():
+				msgs = append(msgs, fmt.Sprintf("%s:%s", msg.Subject, msg.Data))
+			case err := <-l.Err():
+				if errors.Is(err, jetstream.ErrEndOfData) {
+					return msgs, nil
+				}
+				return msgs, err
+			}
+		}
+	}
+
+	for _, allowDirect := range []bool{false, true} {
+		t.Run(fmt.Sprintf("allow direct %t", allowDirect), func(t *testing.T) {
+			name := fmt.Sprintf("last_%t", allowDirect)
+			s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: name, Subjects: []string{name + ".>"}, AllowDirect: allowDirect})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, m := range []struct{ subject, data string }{
+				{"orders.1", "a"},
+				{"users.1", "b"},
+				{"orders.2", "c"},
+				{"orders.1", "d"},
+				{"users.2", "e"},
+			} {
+				if _, err := js.Publish(ctx, name+"."+m.subject, []byte(m.data)); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+
+			msgs, err := collect(t, s.GetLastMsgsForSubjects(ctx, name+".orders.*", name+".users.2"))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			expected := []string{name + ".orders.2:c", name + ".orders.1:d", name + ".users.2:e"}
+			if !reflect.DeepEqual(msgs, expected) {
+				t.Fatalf("Expected messages: %v; got: %v", expected, msgs)
+			}
+
+			msgs, err = collect(t, s.GetLastMsgsForSubjects(ctx, name+".other.*"))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(msgs) != 0 {
+				t.Fatalf("Expected no messages; got: %v", msgs)
+			}
+
+			if _, err := collect(t, s.GetLastMsgsForSubjects(ctx)); !errors.Is(err, jetstream.ErrSubjectsRequired) {
+				t.Fatalf("Expected error: %v; got: %v", jetstream.ErrSubjectsRequired, err)
+			}
+		})
+	}
+}
+
+func TestSnapshotSubjects(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, allowDirect := range []bool{false, true} {
+		t.Run(fmt.Sprintf("allow direct %t", allowDirect), func(t *testing.T) {
+			name := fmt.Sprintf("snap_%t", allowDirect)
+			s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: name, Subjects: []string{name + ".>"}, AllowDirect: allowDirect})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, m := range []struct{ subject, data string }{
+				{"orders.1", "a"},
+				{"users.1", "b"},
+				{"orders.2", "c"},
+				{"orders.1", "d"},
+			} {
+				if _, err := js.Publish(ctx, name+"."+m.subject, []byte(m.data)); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+
+			snapshot, err := s.SnapshotSubjects(ctx, name+".orders.*", name+".users.1")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if snapshot.Sequence != 4 {
+				t.Fatalf("Expected sequence 4; got: %d", snapshot.Sequence)
+			}
+			got := make(map[string]string)
+			for subj, msg := range snapshot.Messages {
+				got[subj] = string(msg.Data)
+			}
+			expected := map[string]string{name + ".orders.1": "d", name + ".orders.2": "c", name + ".users.1": "b"}
+			if !reflect.DeepEqual(got, expected) {
+				t.Fatalf("Expected messages: %v; got: %v", expected, got)
+			}
+
+			// subjects are written round robin, so the last messages as of
+			// any sequence are the three messages up to it
+			for i := 0; i < 3; i++ {
+				if _, err := js.Publish(ctx, fmt.Sprintf("%s.rr.%d", name, i), nil); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+			pubCtx, stop := context.WithCancel(ctx)
+			published := make(chan struct{})
+			go func() {
+				defer close(published)
+				for i := 0; pubCtx.Err() == nil; i++ {
+					js.Publish(pubCtx, fmt.Sprintf("%s.rr.%d", name, i%3), nil)
+					time.Sleep(5 * time.Millisecond)
+				}
+			}()
+			var consistent int
+			for i := 0; i < 20; i++ {
+				snapshot, err := s.SnapshotSubjects(ctx, name+".rr.*")
+				if errors.Is(err, jetstream.ErrSnapshotConflict) {
+					continue
+				}
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if len(snapshot.Messages) < 3 {
+					continue
+				}
+				seqs := make(map[uint64]bool)
+				for _, msg := range snapshot.Messages {
+					seqs[msg.Sequence] = true
+				}
+				for seq := snapshot.Sequence - 2; seq <= snapshot.Sequence; seq++ {
+					if !seqs[seq] {
+						t.Fatalf("Inconsistent snapshot as of %d: %v", snapshot.Sequence, seqs)
+					}
+				}
+				consistent++
+			}
+			stop()
+			<-published
+			if consistent == 0 {
+				t.Fatalf("Expected at least one consistent snapshot")
+			}
+
+			if _, err := s.SnapshotSubjects(ctx); !errors.Is(err, jetstream.ErrSubjectsRequired) {
+				t.Fatalf("Expected error: %v; got: %v", jetstream.ErrSubjectsRequired, err)
+			}
+		})
+	}
+}
+
+func TestStreamRePublish(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	s, err := js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:     "foo",
+		Subjects: []string{"FOO.*"},
+		RePublish: &jetstream.RePublish{
+			Source:      "FOO.*",
+			Destination: "RP.{{wildcard(1)}}",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	info, err := s.Info(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if rp := info.Config.RePublish; rp == nil || rp.Source != "FOO.*" || rp.Destination != "RP.{{wildcard(1)}}" || rp.HeadersOnly {
+		t.Fatalf("Invalid republish config: %+v", rp)
+	}
+
+	sub, err := nc.SubscribeSync("RP.>")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := js.Publish(ctx, "FOO.A", []byte("hello")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	msg, err := sub.NextMsg(time.Second)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if msg.Subject != "RP.A" || string(msg.Data) != "hello" {
+		t.Fatalf("Invalid republished message: %s %q", msg.Subject, msg.Data)
+	}
+	if msg.Header.Get(jetstream.StreamHeader) != "foo" || msg.Header.Get(jetstream.SequenceHeader) != "1" || msg.Header.Get(jetstream.SubjectHeader) != "FOO.A" {
+		t.Fatalf("Invalid republished message headers: %v", msg.Header)
+	}
+
+	// headers only republish sends the payload size instead of the payload
+	_, err = js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:     "bar",
+		Subjects: []string{"BAR.*"},
+		RePublish: &jetstream.RePublish{
+			Source:      "BAR.*",
+			Destination: "RP.{{wildcard(1)}}",
+			HeadersOnly: true,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := js.Publish(ctx, "BAR.B", []byte("hello")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	msg, err = sub.NextMsg(time.Second)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if msg.Subject != "RP.B" || len(msg.Data) != 0 || msg.Header.Get(jetstream.MsgSizeHeader) != "5" {
+		t.Fatalf("Invalid republished message: %s %q %v", msg.Subject, msg.Data, msg.Header)
+	}
+}
+
+func TestStreamMetadata(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// older servers ignore metadata, so check the requests sent
+	nextConfig := func(t *testing.T, reqs *nats.Subscription, cfg any) {
+		t.Helper()
+		msg, err := reqs.NextMsg(time.Second)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := json.Unmarshal(msg.Data, cfg); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	t.Run("stream metadata", func(t *testing.T) {
+		reqs, err := nc.SubscribeSync("$JS.API.STREAM.*.foo")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer reqs.Unsubscribe()
+		metadata := map[string]string{"owner": "billing", "version": "1"}
+		streamCfg := jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}, Metadata: metadata}
+		if _, err := js.CreateStream(ctx, streamCfg); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var cfg jetstream.StreamConfig
+		nextConfig(t, reqs, &cfg)
+		if !reflect.DeepEqual(cfg.Metadata, metadata) {
+			t.Fatalf("Expected metadata: %v; got: %v", metadata, cfg.Metadata)
+		}
+
+		streamCfg.Metadata = map[string]string{"owner": "payments"}
+		if _, err := js.UpdateStream(ctx, streamCfg); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		cfg = jetstream.StreamConfig{}
+		nextConfig(t, reqs, &cfg)
+		if !reflect.DeepEqual(cfg.Metadata, streamCfg.Metadata) {
+			t.Fatalf("Expected metadata: %v; got: %v", streamCfg.Metadata, cfg.Metadata)
+		}
+	})
+
+	t.Run("consumer metadata", func(t *testing.T) {
+		reqs, err := nc.SubscribeSync("$JS.API.CONSUMER.CREATE.foo.>")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer reqs.Unsubscribe()
+		metadata := map[string]string{"owner": "billing"}
+		if _, err := js.AddConsumer(ctx, "foo", jetstream.ConsumerConfig{Durable: "cons", Metadata: metadata}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var req struct {
+			Config jetstream.ConsumerConfig `json:"config"`
+		}
+		nextConfig(t, reqs, &req)
+		if !reflect.DeepEqual(req.Config.Metadata, metadata) {
+			t.Fatalf("Expected metadata: %v; got: %v", metadata, req.Config.Metadata)
+		}
+
+		metadata = map[string]string{"owner": "payments"}
+		if _, err := js.UpdateConsumer(ctx, "foo", jetstream.ConsumerConfig{Durable: "cons", Metadata: metadata}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		req.Config = jetstream.ConsumerConfig{}
+		nextConfig(t, reqs, &req)
+		if !reflect.DeepEqual(req.Config.Metadata, metadata) {
+			t.Fatalf("Expected metadata: %v; got: %v", metadata, req.Config.Metadata)
+		}
+	})
+}
+
+func TestLeaderStepDown(t *testing.T) {
+	name := "stepdown"
+	stream := jetstream.StreamConfig{
+		Name:     name,
+		Replicas: 3,
+		Subjects: []string{"FOO.*"},
+	}
+	withJSClusterAndStream(t, name, 3, stream, func(t *testing.T, subject string, srvs ...*jsServer) {
+		nc, err := nats.Connect(srvs[0].ClientURL())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer nc.Close()
+		js, err := jetstream.New(nc)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+
+		s, err := js.Stream(ctx, name)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		c, err := s.AddConsumer(ctx, jetstream.ConsumerConfig{Durable: "cons", AckPolicy: jetstream.AckExplicitPolicy})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		// waitForNewLeader waits until a leader other than the given one is elected
+		waitForNewLeader := func(t *testing.T, info func(context.Context) (*jetstream.ClusterInfo, error), prev string) {
+			t.Helper()
+			for {
+				cluster, err := info(ctx)
+				if err == nil && cluster.Leader != "" && cluster.Leader != prev {
+					return
+				}
+				select {
+				case <-ctx.Done():
+					t.Fatalf("Leader did not change from %q", prev)
+				case <-time.After(100 * time.Millisecond):
+				}
+			}
+		}
+		streamCluster := func(ctx context.Context) (*jetstream.ClusterInfo, error) {
+			info, err := s.Info(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return info.Cluster, nil
+		}
+		consumerCluster := func(ctx context.Context) (*jetstream.ClusterInfo, error) {
+			info, err := c.Info(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return info.Cluster, nil
+		}
+
+		cluster, err := streamCluster(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if cluster.Name != name || cluster.Leader == "" || len(cluster.Replicas) != 2 {
+			t.Fatalf("Unexpected cluster info: %+v", cluster)
+		}
+		if err := s.LeaderStepDown(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		waitForNewLeader(t, streamCluster, cluster.Leader)
+
+		cluster, err = consumerCluster(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := c.LeaderStepDown(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		waitForNewLeader(t, consumerCluster, cluster.Leader)
+
+		if err := js.DeleteConsumer(ctx, name, "cons"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := c.LeaderStepDown(ctx); !errors.Is(err, jetstream.ErrConsumerNotFound) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrConsumerNotFound, err)
+		}
+	})
+}
+
+func TestStreamRemovePeer(t *testing.T) {
+	name := "removepeer"
+	stream := jetstream.StreamConfig{
+		Name:     name,
+		Replicas: 2,
+		Subjects: []string{"FOO.*"},
+	}
+	withJSClusterAndStream(t, name, 3, stream, func(t *testing.T, subject string, srvs ...*jsServer) {
+		nc, err := nats.Connect(srvs[0].ClientURL())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer nc.Close()
+		js, err := jetstream.New(nc)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+
+		s, err := js.Stream(ctx, name)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		info, err := s.Info(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(info.Cluster.Replicas) != 1 {
+			t.Fatalf("Expected 1 replica; got: %+v", info.Cluster)
+		}
+		removed := info.Cluster.Replicas[0].Name
+
+		if err := s.RemovePeer(ctx, ""); !errors.Is(err, jetstream.ErrPeerRequired) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrPeerRequired, err)
+		}
+		if err := s.RemovePeer(ctx, "unknown"); !errors.Is(err, jetstream.ErrPeerNotMember) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrPeerNotMember, err)
+		}
+		if err := s.RemovePeer(ctx, removed); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		// the removed replica is replaced by one on the remaining server
+		for {
+			info, err := s.Info(ctx)
+			if err == nil && len(info.Cluster.Replicas) == 1 && info.Cluster.Replicas[0].Name != removed && info.Cluster.Leader != removed {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				t.Fatalf("Peer %q was not replaced", removed)
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+
+		if err := js.DeleteStream(ctx, name); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := s.RemovePeer(ctx, removed); !errors.Is(err, jetstream.ErrStreamNotFound) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrStreamNotFound, err)
+		}
+	})
+}
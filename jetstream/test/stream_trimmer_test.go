@@ -0,0 +1,126 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+func TestTrimStream(t *testing.T) {
+	srv := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "foo", Subjects: []string{"FOO.*"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := js.Publish(ctx, "FOO.A", []byte("msg")); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	ack := func(t *testing.T, name string, n int) {
+		t.Helper()
+		c, err := s.AddConsumer(ctx, jetstream.ConsumerConfig{Durable: name, AckPolicy: jetstream.AckExplicitPolicy})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		msgs, err := c.Fetch(n)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for msg := range msgs.Messages() {
+			if err := msg.DoubleAck(ctx); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+	}
+	ack(t, "a", 6)
+	ack(t, "b", 4)
+
+	if _, err := jetstream.TrimStream(ctx, s, nil); !errors.Is(err, jetstream.ErrInvalidOption) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidOption, err)
+	}
+	if _, err := jetstream.TrimStream(ctx, s, []string{"a"}, jetstream.WithStreamTrimInterval(0)); !errors.Is(err, jetstream.ErrInvalidOption) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrInvalidOption, err)
+	}
+	if _, err := jetstream.TrimStream(ctx, s, []string{"a", "missing"}); !errors.Is(err, jetstream.ErrConsumerNotFound) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrConsumerNotFound, err)
+	}
+
+	trims := make(chan [2]uint64, 10)
+	trimmer, err := jetstream.TrimStream(ctx, s, []string{"a", "b"},
+		jetstream.WithStreamTrimInterval(50*time.Millisecond),
+		jetstream.WithStreamTrimHandler(func(watermark, purged uint64) {
+			trims <- [2]uint64{watermark, purged}
+		}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer trimmer.Stop()
+
+	expectTrim := func(t *testing.T, watermark, purged uint64) {
+		t.Helper()
+		select {
+		case trim := <-trims:
+			if trim != [2]uint64{watermark, purged} {
+				t.Fatalf("Expected purge of %d messages up to %d; got: %v", purged, watermark, trim)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Did not receive trim")
+		}
+		info, err := s.Info(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if info.State.FirstSeq != watermark+1 {
+			t.Fatalf("Expected first sequence %d; got: %d", watermark+1, info.State.FirstSeq)
+		}
+	}
+	// purged up to the lowest ack floor when started
+	expectTrim(t, 4, 4)
+	if trimmer.Watermark() != 4 {
+		t.Fatalf("Expected watermark 4; got: %d", trimmer.Watermark())
+	}
+
+	// consumer b catching up moves the watermark to the ack floor of a
+	ack(t, "b", 6)
+	expectTrim(t, 6, 2)
+
+	ack(t, "a", 4)
+	expectTrim(t, 10, 4)
+	select {
+	case trim := <-trims:
+		t.Fatalf("Unexpected trim: %v", trim)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
@@ -0,0 +1,429 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type (
+	// UsageLimit identifies a retention limit of a stream.
+	UsageLimit int
+
+	// StreamUsageSample is a single observation of stream usage.
+	StreamUsageSample struct {
+		Time      time.Time
+		Msgs      uint64
+		Bytes     uint64
+		FirstTime time.Time
+	}
+
+	// UsageForecast projects the time left until the retention limits of a
+	// stream are reached, after which messages are discarded. Durations are
+	// [NoUsageLimit] if the limit is not set or is not projected to be reached.
+	UsageForecast struct {
+		Time             time.Time
+		Msgs             uint64
+		Bytes            uint64
+		MaxMsgs          int64
+		MaxBytes         int64
+		MaxAge           time.Duration
+		TimeToMsgsLimit  time.Duration
+		TimeToBytesLimit time.Duration
+		TimeToAgeLimit   time.Duration
+	}
+
+	// UsageWarning is passed to the [UsageWarningHandler] when a limit is
+	// projected to be reached within the configured time.
+	UsageWarning struct {
+		Limit       UsageLimit
+		TimeToLimit time.Duration
+		Forecast    UsageForecast
+	}
+
+	// UsageWarningHandler is invoked when a limit is projected to be reached soon.
+	UsageWarningHandler func(UsageWarning)
+
+	// UsageForecasterOpt configures a [UsageForecaster].
+	UsageForecasterOpt func(*usageForecasterOpts) error
+
+	usageForecasterOpts struct {
+		interval   time.Duration
+		history    int
+		season     time.Duration
+		warnWithin time.Duration
+		warnCB     UsageWarningHandler
+		errHandler func(error)
+	}
+
+	// UsageForecaster periodically samples the usage of a stream and
+	// projects when its retention limits are reached.
+	UsageForecaster struct {
+		sync.Mutex
+		stream   Stream
+		opts     usageForecasterOpts
+		samples  []StreamUsageSample
+		forecast UsageForecast
+		warned   map[UsageLimit]bool
+		cancel   context.CancelFunc
+		done     chan struct{}
+	}
+)
+
+const (
+	// UsageLimitMsgs is the maximum number of messages of a stream.
+	UsageLimitMsgs UsageLimit = iota
+	// UsageLimitBytes is the maximum size of a stream.
+	UsageLimitBytes
+	// UsageLimitAge is the maximum age of messages in a stream.
+	UsageLimitAge
+	// UsageLimitMsgsPerSubject is the maximum number of messages per subject of a stream.
+	UsageLimitMsgsPerSubject
+)
+
+const (
+	// NoUsageLimit is the projected time to a limit which is not set
+	// or not projected to be reached.
+	NoUsageLimit time.Duration = -1
+
+	// DefaultUsageForecastInterval is the default interval between usage samples.
+	DefaultUsageForecastInterval = time.Minute
+
+	// DefaultUsageForecastHistory is the default number of samples kept by the forecaster.
+	DefaultUsageForecastHistory = 1440
+
+	// maxSeasons limits how far ahead the seasonal model projects usage.
+	maxSeasons = 1000
+)
+
+func (l UsageLimit) String() string {
+	switch l {
+	case UsageLimitMsgs:
+		return "max_msgs"
+	case UsageLimitBytes:
+		return "max_bytes"
+	case UsageLimitAge:
+		return "max_age"
+	case UsageLimitMsgsPerSubject:
+		return "max_msgs_per_subject"
+	}
+	return "unknown"
+}
+
+// TimeToLimit returns the projected time until the given limit is reached.
+func (f UsageForecast) TimeToLimit(limit UsageLimit) time.Duration {
+	switch limit {
+	case UsageLimitMsgs:
+		return f.TimeToMsgsLimit
+	case UsageLimitBytes:
+		return f.TimeToBytesLimit
+	case UsageLimitAge:
+		return f.TimeToAgeLimit
+	}
+	return NoUsageLimit
+}
+
+// WithUsageForecastInterval sets the interval between usage samples.
+func WithUsageForecastInterval(interval time.Duration) UsageForecasterOpt {
+	return func(opts *usageForecasterOpts) error {
+		if interval <= 0 {
+			return fmt.Errorf("%w: interval must be positive", ErrInvalidOption)
+		}
+		opts.interval = interval
+		return nil
+	}
+}
+
+// WithUsageForecastHistory sets the number of most recent samples the forecast is based on.
+func WithUsageForecastHistory(samples int) UsageForecasterOpt {
+	return func(opts *usageForecasterOpts) error {
+		if samples < 2 {
+			return fmt.Errorf("%w: history must be at least 2", ErrInvalidOption)
+		}
+		opts.history = samples
+		return nil
+	}
+}
+
+// WithUsageSeason selects the seasonal model, which assumes usage grows
+// within each period the same way it did within the last one, e.g. with
+// daily traffic patterns. Until samples span a whole period, the linear
+// model is used. The history has to be large enough to cover the period.
+func WithUsageSeason(period time.Duration) UsageForecasterOpt {
+	return func(opts *usageForecasterOpts) error {
+		if period <= 0 {
+			return fmt.Errorf("%w: season must be positive", ErrInvalidOption)
+		}
+		opts.season = period
+		return nil
+	}
+}
+
+// WithUsageWarning sets the handler invoked when a limit is projected to be
+// reached within the given time. It is invoked once per limit until the
+// projection exceeds the given time again.
+func WithUsageWarning(within time.Duration, cb UsageWarningHandler) UsageForecasterOpt {
+	return func(opts *usageForecasterOpts) error {
+		if within <= 0 {
+			return fmt.Errorf("%w: warning time must be positive", ErrInvalidOption)
+		}
+		opts.warnWithin = within
+		opts.warnCB = cb
+		return nil
+	}
+}
+
+// WithUsageForecastErrHandler sets the handler invoked when stream info cannot be retrieved.
+func WithUsageForecastErrHandler(cb func(error)) UsageForecasterOpt {
+	return func(opts *usageForecasterOpts) error {
+		opts.errHandler = cb
+		return nil
+	}
+}
+
+// ForecastStreamUsage starts sampling the usage of the given stream and
+// projecting the time left until its limits are reached. By default, a
+// linear trend fitted to the samples is extrapolated.
+// Sampling stops when ctx is done or [UsageForecaster.Stop] is called.
+//
+// Available options:
+// [WithUsageForecastInterval] - sets the interval between samples, default is 1m
+// [WithUsageForecastHistory] - sets the number of samples kept, default is 1440
+// [WithUsageSeason] - selects the seasonal model with the given period
+// [WithUsageWarning] - sets the handler invoked when a limit is projected to be reached soon
+// [WithUsageForecastErrHandler] - sets the handler for errors retrieving stream info
+func ForecastStreamUsage(ctx context.Context, stream Stream, opts ...UsageForecasterOpt) (*UsageForecaster, error) {
+	o := usageForecasterOpts{
+		interval: DefaultUsageForecastInterval,
+		history:  DefaultUsageForecastHistory,
+	}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+	info, err := stream.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	f := &UsageForecaster{
+		stream: stream,
+		opts:   o,
+		warned: make(map[UsageLimit]bool),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	f.record(info, time.Now())
+	go f.run(ctx)
+	return f, nil
+}
+
+// Stop stops sampling the stream.
+func (f *UsageForecaster) Stop() {
+	f.cancel()
+	<-f.done
+}
+
+// Forecast returns the forecast based on the latest sample.
+func (f *UsageForecaster) Forecast() UsageForecast {
+	f.Lock()
+	defer f.Unlock()
+	return f.forecast
+}
+
+// Samples returns the most recent usage samples, oldest first.
+func (f *UsageForecaster) Samples() []StreamUsageSample {
+	f.Lock()
+	defer f.Unlock()
+	return append([]StreamUsageSample(nil), f.samples...)
+}
+
+func (f *UsageForecaster) run(ctx context.Context) {
+	defer close(f.done)
+	t := time.NewTicker(f.opts.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			info, err := f.stream.Info(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				if f.opts.errHandler != nil {
+					f.opts.errHandler(err)
+				}
+				continue
+			}
+			f.record(info, time.Now())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// record stores a sample taken from stream info, updates the forecast
+// and invokes the warning handler for limits reached soon.
+func (f *UsageForecaster) record(info *StreamInfo, now time.Time) {
+	f.Lock()
+	f.samples = append(f.samples, StreamUsageSample{
+		Time:      now,
+		Msgs:      info.State.Msgs,
+		Bytes:     info.State.Bytes,
+		FirstTime: info.State.FirstTime,
+	})
+	if len(f.samples) > f.opts.history {
+		f.samples = f.samples[len(f.samples)-f.opts.history:]
+	}
+	f.forecast = forecastUsage(f.samples, info.Config, f.opts.season)
+
+	var warnings []UsageWarning
+	if f.opts.warnCB != nil {
+		for _, limit := range []UsageLimit{UsageLimitMsgs, UsageLimitBytes, UsageLimitAge} {
+			ttl := f.forecast.TimeToLimit(limit)
+			soon := ttl != NoUsageLimit && ttl <= f.opts.warnWithin
+			if soon && !f.warned[limit] {
+				warnings = append(warnings, UsageWarning{Limit: limit, TimeToLimit: ttl, Forecast: f.forecast})
+			}
+			f.warned[limit] = soon
+		}
+	}
+	f.Unlock()
+
+	for _, w := range warnings {
+		f.opts.warnCB(w)
+	}
+}
+
+// forecastUsage projects the time to the limits of a stream from usage
+// samples, the latest of which is the current usage.
+func forecastUsage(samples []StreamUsageSample, cfg StreamConfig, season time.Duration) UsageForecast {
+	last := samples[len(samples)-1]
+	f := UsageForecast{
+		Time:             last.Time,
+		Msgs:             last.Msgs,
+		Bytes:            last.Bytes,
+		MaxMsgs:          cfg.MaxMsgs,
+		MaxBytes:         cfg.MaxBytes,
+		MaxAge:           cfg.MaxAge,
+		TimeToMsgsLimit:  NoUsageLimit,
+		TimeToBytesLimit: NoUsageLimit,
+		TimeToAgeLimit:   NoUsageLimit,
+	}
+	if cfg.MaxMsgs > 0 {
+		f.TimeToMsgsLimit = timeToLimit(samples, season, uint64(cfg.MaxMsgs), func(s StreamUsageSample) float64 {
+			return float64(s.Msgs)
+		})
+	}
+	if cfg.MaxBytes > 0 {
+		f.TimeToBytesLimit = timeToLimit(samples, season, uint64(cfg.MaxBytes), func(s StreamUsageSample) float64 {
+			return float64(s.Bytes)
+		})
+	}
+	if cfg.MaxAge > 0 && last.Msgs > 0 && !last.FirstTime.IsZero() {
+		f.TimeToAgeLimit = last.FirstTime.Add(cfg.MaxAge).Sub(last.Time)
+		if f.TimeToAgeLimit < 0 {
+			f.TimeToAgeLimit = 0
+		}
+	}
+	return f
+}
+
+// timeToLimit projects the time until the value extracted from samples
+// reaches the limit, using the seasonal model if samples span a season.
+func timeToLimit(samples []StreamUsageSample, season time.Duration, limit uint64, value func(StreamUsageSample) float64) time.Duration {
+	last := samples[len(samples)-1]
+	current := value(last)
+	if current >= float64(limit) {
+		return 0
+	}
+	if season > 0 && !samples[0].Time.After(last.Time.Add(-season)) {
+		return seasonalTimeToLimit(samples, season, float64(limit), value)
+	}
+	return linearTimeToLimit(samples, float64(limit), value)
+}
+
+// linearTimeToLimit extrapolates the least squares trend of the samples.
+func linearTimeToLimit(samples []StreamUsageSample, limit float64, value func(StreamUsageSample) float64) time.Duration {
+	if len(samples) < 2 {
+		return NoUsageLimit
+	}
+	last := samples[len(samples)-1]
+	var sumX, sumY, sumXY, sumXX float64
+	for _, s := range samples {
+		x := s.Time.Sub(last.Time).Seconds()
+		y := value(s)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	n := float64(len(samples))
+	denom := n*sumXX - sumX*sumX
+	if denom == 0 {
+		return NoUsageLimit
+	}
+	slope := (n*sumXY - sumX*sumY) / denom
+	if slope <= 0 {
+		return NoUsageLimit
+	}
+	return time.Duration((limit - value(last)) / slope * float64(time.Second))
+}
+
+// seasonalTimeToLimit assumes the value changes in each upcoming season
+// the same way it changed in the last one.
+func seasonalTimeToLimit(samples []StreamUsageSample, season time.Duration, limit float64, value func(StreamUsageSample) float64) time.Duration {
+	last := samples[len(samples)-1]
+	start := len(samples) - 1
+	for start > 0 && samples[start-1].Time.After(last.Time.Add(-season)) {
+		start--
+	}
+	if start > 0 {
+		// Include the last sample before the season, so that the
+		// changes cover the whole season.
+		start--
+	}
+
+	type change struct {
+		dt time.Duration
+		dv float64
+	}
+	changes := make([]change, 0, len(samples)-start)
+	var growth float64
+	for i := start + 1; i < len(samples); i++ {
+		c := change{dt: samples[i].Time.Sub(samples[i-1].Time), dv: value(samples[i]) - value(samples[i-1])}
+		changes = append(changes, c)
+		growth += c.dv
+	}
+	if growth <= 0 {
+		return NoUsageLimit
+	}
+
+	v := value(last)
+	var elapsed time.Duration
+	for n := 0; n < maxSeasons; n++ {
+		for _, c := range changes {
+			if c.dv > 0 && v+c.dv >= limit {
+				return elapsed + time.Duration(float64(c.dt)*(limit-v)/c.dv)
+			}
+			v += c.dv
+			elapsed += c.dt
+		}
+	}
+	return NoUsageLimit
+}
@@ -0,0 +1,150 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestForecastUsage(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	linear := func(n int, step time.Duration, perStep uint64) []StreamUsageSample {
+		samples := make([]StreamUsageSample, 0, n)
+		for i := 0; i < n; i++ {
+			samples = append(samples, StreamUsageSample{
+				Time:      start.Add(time.Duration(i) * step),
+				Msgs:      uint64(i) * perStep,
+				Bytes:     uint64(i) * perStep * 100,
+				FirstTime: start,
+			})
+		}
+		return samples
+	}
+
+	t.Run("linear", func(t *testing.T) {
+		// 10 msgs per minute, 100 msgs after 10 minutes.
+		samples := linear(11, time.Minute, 10)
+		f := forecastUsage(samples, StreamConfig{MaxMsgs: 200, MaxBytes: -1, MaxAge: time.Hour}, 0)
+		if f.TimeToMsgsLimit != 10*time.Minute {
+			t.Fatalf("Expected 10m to msgs limit; got: %v", f.TimeToMsgsLimit)
+		}
+		if f.TimeToBytesLimit != NoUsageLimit {
+			t.Fatalf("Expected no bytes limit; got: %v", f.TimeToBytesLimit)
+		}
+		if f.TimeToAgeLimit != 50*time.Minute {
+			t.Fatalf("Expected 50m to age limit; got: %v", f.TimeToAgeLimit)
+		}
+	})
+
+	t.Run("limit reached", func(t *testing.T) {
+		samples := linear(11, time.Minute, 10)
+		f := forecastUsage(samples, StreamConfig{MaxBytes: 10000}, 0)
+		if f.TimeToBytesLimit != 0 {
+			t.Fatalf("Expected bytes limit to be reached; got: %v", f.TimeToBytesLimit)
+		}
+	})
+
+	t.Run("not growing", func(t *testing.T) {
+		samples := linear(11, time.Minute, 0)
+		f := forecastUsage(samples, StreamConfig{MaxMsgs: 100}, 0)
+		if f.TimeToMsgsLimit != NoUsageLimit {
+			t.Fatalf("Expected msgs limit not to be reached; got: %v", f.TimeToMsgsLimit)
+		}
+		if f := forecastUsage(samples[:1], StreamConfig{MaxMsgs: 100}, 0); f.TimeToMsgsLimit != NoUsageLimit {
+			t.Fatalf("Expected no projection from a single sample; got: %v", f.TimeToMsgsLimit)
+		}
+	})
+
+	t.Run("seasonal", func(t *testing.T) {
+		// Within each 4h season, 100 msgs are added during the first
+		// hour and none during the remaining three.
+		var samples []StreamUsageSample
+		var msgs uint64
+		for i := 0; i <= 8; i++ {
+			if i > 0 && (i-1)%4 == 0 {
+				msgs += 100
+			}
+			samples = append(samples, StreamUsageSample{Time: start.Add(time.Duration(i) * time.Hour), Msgs: msgs})
+		}
+		// 200 msgs now, the next season starts with the busy hour.
+		f := forecastUsage(samples, StreamConfig{MaxMsgs: 350}, 4*time.Hour)
+		if expected := 4*time.Hour + 30*time.Minute; f.TimeToMsgsLimit != expected {
+			t.Fatalf("Expected %v to msgs limit; got: %v", expected, f.TimeToMsgsLimit)
+		}
+		// Falls back to linear until samples cover a season.
+		f = forecastUsage(samples, StreamConfig{MaxMsgs: 350}, 24*time.Hour)
+		if f.TimeToMsgsLimit == NoUsageLimit || f.TimeToMsgsLimit == 4*time.Hour+30*time.Minute {
+			t.Fatalf("Expected linear projection; got: %v", f.TimeToMsgsLimit)
+		}
+	})
+}
+
+func TestUsageForecaster(t *testing.T) {
+	s := &mirrorInfoStream{info: &StreamInfo{
+		Config: StreamConfig{Name: "ORDERS", MaxMsgs: 1000, MaxBytes: -1},
+	}}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := ForecastStreamUsage(ctx, s, WithUsageForecastHistory(1)); !errors.Is(err, ErrInvalidOption) {
+		t.Fatalf("Expected error: %v; got: %v", ErrInvalidOption, err)
+	}
+
+	warnings := make(chan UsageWarning, 10)
+	f, err := ForecastStreamUsage(ctx, s,
+		WithUsageForecastInterval(10*time.Millisecond),
+		WithUsageWarning(time.Minute, func(w UsageWarning) {
+			warnings <- w
+		}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer f.Stop()
+
+	// Grow the stream quickly, so that the limit is projected to be reached within a minute.
+	go func() {
+		for i := 0; i < 20 && ctx.Err() == nil; i++ {
+			s.Lock()
+			s.info.State.Msgs += 10
+			s.Unlock()
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	select {
+	case w := <-warnings:
+		if w.Limit != UsageLimitMsgs {
+			t.Fatalf("Expected warning for %v; got: %v", UsageLimitMsgs, w.Limit)
+		}
+		if w.TimeToLimit < 0 || w.TimeToLimit > time.Minute {
+			t.Fatalf("Unexpected time to limit: %v", w.TimeToLimit)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Did not receive usage warning")
+	}
+	select {
+	case w := <-warnings:
+		t.Fatalf("Expected a single warning; got: %+v", w)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if len(f.Samples()) < 2 {
+		t.Fatalf("Expected samples to be recorded")
+	}
+	if f.Forecast().MaxMsgs != 1000 {
+		t.Fatalf("Unexpected forecast: %+v", f.Forecast())
+	}
+}
@@ -0,0 +1,683 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jetstreamcompat provides a conformance test suite verifying that
+// the JetStream features of the client behave correctly with a given server,
+// e.g. a fork, a specific version or the cluster of an air-gapped deployment.
+//
+// The suite is run from a regular Go test:
+//
+//	func TestCompat(t *testing.T) {
+//		jetstreamcompat.Run(t, "nats://localhost:4222", nats.UserCredentials("compat.creds"))
+//	}
+//
+// Each feature is a subtest, so a subset can be run using e.g.
+// go test -run 'TestCompat/kv'. Features requiring a newer server than the
+// one connected to are verified to report that they are not supported.
+package jetstreamcompat
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/nats-io/nuid"
+)
+
+// Notice: Experimental Preview
+//
+// This functionality is EXPERIMENTAL and may be changed in later releases.
+
+type (
+	// suite holds the connection shared by the features of a run.
+	suite struct {
+		nc *nats.Conn
+		js jetstream.JetStream
+		// prefix is used to name the resources created by the run.
+		prefix string
+	}
+
+	feature struct {
+		name string
+		run  func(*testing.T, *suite)
+	}
+)
+
+// features are the features verified by [Run], in order.
+var features = []feature{
+	{"streams", testStreams},
+	{"publish", testPublish},
+	{"async_publish", testAsyncPublish},
+	{"get_msg", testGetMsg},
+	{"pull_consumer", testPullConsumer},
+	{"ordered_consumer", testOrderedConsumer},
+	{"filter_subjects", testFilterSubjects},
+	{"stream_v2_10", testStreamV210},
+	{"msg_ttl", testMsgTTL},
+	{"kv", testKeyValue},
+	{"object_store", testObjectStore},
+}
+
+// Run connects to the server at url using the given options and verifies
+// the JetStream features of the client as subtests of t.
+//
+// Resources created by the suite are named with a unique COMPAT_ prefix
+// and removed when the subtests finish. The account needs to allow
+// creating a few small streams, consumers and buckets.
+func Run(t *testing.T, url string, opts ...nats.Option) {
+	t.Helper()
+	nc, err := nats.Connect(url, opts...)
+	if err != nil {
+		t.Fatalf("Unable to connect to %s: %v", url, err)
+	}
+	defer nc.Close()
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := js.AccountInfo(ctx); err != nil {
+		t.Fatalf("JetStream not available: %v", err)
+	}
+	t.Logf("Verifying JetStream features with nats-server %s", nc.ConnectedServerVersion())
+
+	s := &suite{
+		nc:     nc,
+		js:     js,
+		prefix: "COMPAT_" + nuid.Next(),
+	}
+	for _, f := range features {
+		f := f
+		t.Run(f.name, func(t *testing.T) {
+			f.run(t, s)
+		})
+	}
+}
+
+// name returns a unique resource name for the test.
+func (s *suite) name(t *testing.T) string {
+	t.Helper()
+	return s.prefix + "_" + strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		if r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, t.Name())
+}
+
+// context returns a context for a single operation of the test.
+func (s *suite) context(t *testing.T) context.Context {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+// createStream creates a stream named after the test, capturing all subjects
+// starting with the stream name, and removes it when the test finishes.
+// The stream is removed even if creating it returns an error, as servers
+// not supporting a setting create the stream without it.
+func (s *suite) createStream(t *testing.T, cfg jetstream.StreamConfig) (jetstream.Stream, error) {
+	t.Helper()
+	if cfg.Name == "" {
+		cfg.Name = s.name(t)
+	}
+	if len(cfg.Subjects) == 0 {
+		cfg.Subjects = []string{cfg.Name + ".>"}
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.js.DeleteStream(ctx, cfg.Name); err != nil && !errors.Is(err, jetstream.ErrStreamNotFound) {
+			t.Errorf("Unable to delete stream %q: %v", cfg.Name, err)
+		}
+	})
+	return s.js.CreateStream(s.context(t), cfg)
+}
+
+// mustCreateStream is like createStream, failing the test on errors.
+func (s *suite) mustCreateStream(t *testing.T, cfg jetstream.StreamConfig) jetstream.Stream {
+	t.Helper()
+	stream, err := s.createStream(t, cfg)
+	if err != nil {
+		t.Fatalf("Unable to create stream: %v", err)
+	}
+	return stream
+}
+
+// publish publishes count messages to subject.
+func (s *suite) publish(t *testing.T, subject string, count int) {
+	t.Helper()
+	for i := 0; i < count; i++ {
+		if _, err := s.js.Publish(s.context(t), subject, []byte(strconv.Itoa(i))); err != nil {
+			t.Fatalf("Unable to publish: %v", err)
+		}
+	}
+}
+
+// serverAtLeast returns true if the server version is at least major.minor.
+func (s *suite) serverAtLeast(major, minor int) bool {
+	version := strings.SplitN(s.nc.ConnectedServerVersion(), "-", 2)[0]
+	parts := strings.Split(version, ".")
+	if len(parts) < 2 {
+		return false
+	}
+	srvMajor, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return false
+	}
+	srvMinor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return false
+	}
+	return srvMajor > major || srvMajor == major && srvMinor >= minor
+}
+
+func testStreams(t *testing.T, s *suite) {
+	name := s.name(t)
+	stream := s.mustCreateStream(t, jetstream.StreamConfig{
+		Name:        name,
+		Description: "compat",
+		MaxMsgs:     100,
+		MaxAge:      time.Hour,
+		Storage:     jetstream.MemoryStorage,
+	})
+	cfg := stream.CachedInfo().Config
+	if cfg.MaxMsgs != 100 || cfg.MaxAge != time.Hour || cfg.Storage != jetstream.MemoryStorage || cfg.Description != "compat" {
+		t.Fatalf("Unexpected stream config: %+v", cfg)
+	}
+
+	cfg.MaxMsgs = 200
+	updated, err := s.js.UpdateStream(s.context(t), cfg)
+	if err != nil {
+		t.Fatalf("Unable to update stream: %v", err)
+	}
+	if updated.CachedInfo().Config.MaxMsgs != 200 {
+		t.Fatalf("Expected max msgs 200; got: %d", updated.CachedInfo().Config.MaxMsgs)
+	}
+
+	var found bool
+	names := s.js.StreamNames(s.context(t))
+Names:
+	for {
+		select {
+		case n := <-names.Name():
+			found = found || n == name
+		case err := <-names.Err():
+			if !errors.Is(err, jetstream.ErrEndOfData) {
+				t.Fatalf("Unable to list stream names: %v", err)
+			}
+			break Names
+		}
+	}
+	if !found {
+		t.Fatalf("Stream %q not listed", name)
+	}
+
+	s.publish(t, name+".a", 3)
+	purged, err := stream.Purge(s.context(t))
+	if err != nil {
+		t.Fatalf("Unable to purge stream: %v", err)
+	}
+	if purged != 3 {
+		t.Fatalf("Expected 3 purged messages; got: %d", purged)
+	}
+
+	if err := s.js.DeleteStream(s.context(t), name); err != nil {
+		t.Fatalf("Unable to delete stream: %v", err)
+	}
+	if _, err := s.js.Stream(s.context(t), name); !errors.Is(err, jetstream.ErrStreamNotFound) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrStreamNotFound, err)
+	}
+}
+
+func testPublish(t *testing.T, s *suite) {
+	name := s.name(t)
+	s.mustCreateStream(t, jetstream.StreamConfig{Name: name, Duplicates: time.Minute})
+	subject := name + ".a"
+
+	ack, err := s.js.Publish(s.context(t), subject, []byte("1"), jetstream.WithMsgID("1"))
+	if err != nil {
+		t.Fatalf("Unable to publish: %v", err)
+	}
+	if ack.Stream != name || ack.Sequence != 1 || ack.Duplicate {
+		t.Fatalf("Unexpected ack: %+v", ack)
+	}
+	ack, err = s.js.Publish(s.context(t), subject, []byte("1"), jetstream.WithMsgID("1"))
+	if err != nil {
+		t.Fatalf("Unable to publish: %v", err)
+	}
+	if !ack.Duplicate {
+		t.Fatalf("Expected duplicate ack; got: %+v", ack)
+	}
+
+	_, err = s.js.Publish(s.context(t), subject, []byte("2"), jetstream.WithExpectLastSequence(5))
+	var apiErr *jetstream.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode != jetstream.JSErrCodeStreamWrongLastSequence {
+		t.Fatalf("Expected wrong last sequence error; got: %v", err)
+	}
+	if _, err := s.js.Publish(s.context(t), subject, []byte("2"), jetstream.WithExpectLastSequence(1), jetstream.WithExpectStream(name)); err != nil {
+		t.Fatalf("Unable to publish: %v", err)
+	}
+	_, err = s.js.Publish(s.context(t), subject, []byte("3"), jetstream.WithExpectStream("OTHER"))
+	if err == nil {
+		t.Fatalf("Expected error publishing with wrong expected stream")
+	}
+}
+
+func testAsyncPublish(t *testing.T, s *suite) {
+	name := s.name(t)
+	stream := s.mustCreateStream(t, jetstream.StreamConfig{Name: name})
+
+	const count = 100
+	futures := make([]jetstream.PubAckFuture, 0, count)
+	for i := 0; i < count; i++ {
+		f, err := s.js.PublishAsync(s.context(t), name+".a", []byte(strconv.Itoa(i)))
+		if err != nil {
+			t.Fatalf("Unable to publish: %v", err)
+		}
+		futures = append(futures, f)
+	}
+	select {
+	case <-s.js.PublishAsyncComplete():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Did not receive acks, %d pending", s.js.PublishAsyncPending())
+	}
+	for i, f := range futures {
+		select {
+		case ack := <-f.Ok():
+			if ack.Sequence != uint64(i+1) {
+				t.Fatalf("Expected sequence %d; got: %d", i+1, ack.Sequence)
+			}
+		case err := <-f.Err():
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	info, err := stream.Info(s.context(t))
+	if err != nil {
+		t.Fatalf("Unable to get stream info: %v", err)
+	}
+	if info.State.Msgs != count {
+		t.Fatalf("Expected %d messages; got: %d", count, info.State.Msgs)
+	}
+}
+
+func testGetMsg(t *testing.T, s *suite) {
+	for _, direct := range []bool{false, true} {
+		direct := direct
+		t.Run(fmt.Sprintf("direct=%t", direct), func(t *testing.T) {
+			name := s.name(t)
+			stream := s.mustCreateStream(t, jetstream.StreamConfig{Name: name, AllowDirect: direct})
+			s.publish(t, name+".a", 2)
+			s.publish(t, name+".b", 1)
+
+			msg, err := stream.GetMsg(s.context(t), 2)
+			if err != nil {
+				t.Fatalf("Unable to get message: %v", err)
+			}
+			if msg.Sequence != 2 || msg.Subject != name+".a" || string(msg.Data) != "1" {
+				t.Fatalf("Unexpected message: %+v", msg)
+			}
+			msg, err = stream.GetLastMsgForSubject(s.context(t), name+".a")
+			if err != nil {
+				t.Fatalf("Unable to get last message: %v", err)
+			}
+			if msg.Sequence != 2 {
+				t.Fatalf("Expected sequence 2; got: %d", msg.Sequence)
+			}
+			if _, err := stream.GetMsg(s.context(t), 10); !errors.Is(err, jetstream.ErrMsgNotFound) {
+				t.Fatalf("Expected error: %v; got: %v", jetstream.ErrMsgNotFound, err)
+			}
+		})
+	}
+}
+
+func testPullConsumer(t *testing.T, s *suite) {
+	name := s.name(t)
+	stream := s.mustCreateStream(t, jetstream.StreamConfig{Name: name})
+	s.publish(t, name+".a", 10)
+
+	c, err := stream.AddConsumer(s.context(t), jetstream.ConsumerConfig{
+		Durable:   "cons",
+		AckPolicy: jetstream.AckExplicitPolicy,
+	})
+	if err != nil {
+		t.Fatalf("Unable to create consumer: %v", err)
+	}
+
+	batch, err := c.Fetch(5, jetstream.FetchMaxWait(time.Second))
+	if err != nil {
+		t.Fatalf("Unable to fetch: %v", err)
+	}
+	var fetched int
+	for msg := range batch.Messages() {
+		fetched++
+		if err := msg.DoubleAck(s.context(t)); err != nil {
+			t.Fatalf("Unable to ack: %v", err)
+		}
+	}
+	if batch.Error() != nil {
+		t.Fatalf("Unexpected fetch error: %v", batch.Error())
+	}
+	if fetched != 5 {
+		t.Fatalf("Expected 5 messages; got: %d", fetched)
+	}
+
+	received := make(chan jetstream.Msg, 10)
+	cc, err := c.Consume(func(msg jetstream.Msg) {
+		received <- msg
+	})
+	if err != nil {
+		t.Fatalf("Unable to consume: %v", err)
+	}
+	defer cc.Stop()
+	for i := 6; i <= 10; i++ {
+		select {
+		case msg := <-received:
+			meta, err := msg.Metadata()
+			if err != nil {
+				t.Fatalf("Unable to get metadata: %v", err)
+			}
+			if meta.Sequence.Stream != uint64(i) {
+				t.Fatalf("Expected sequence %d; got: %d", i, meta.Sequence.Stream)
+			}
+			if err := msg.DoubleAck(s.context(t)); err != nil {
+				t.Fatalf("Unable to ack: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Did not receive message %d", i)
+		}
+	}
+	cc.Stop()
+
+	info, err := c.Info(s.context(t))
+	if err != nil {
+		t.Fatalf("Unable to get consumer info: %v", err)
+	}
+	if info.AckFloor.Stream != 10 || info.NumPending != 0 || info.NumAckPending != 0 {
+		t.Fatalf("Unexpected consumer state: ack floor %d, pending %d, ack pending %d",
+			info.AckFloor.Stream, info.NumPending, info.NumAckPending)
+	}
+
+	if err := stream.DeleteConsumer(s.context(t), "cons"); err != nil {
+		t.Fatalf("Unable to delete consumer: %v", err)
+	}
+	if _, err := stream.Consumer(s.context(t), "cons"); !errors.Is(err, jetstream.ErrConsumerNotFound) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrConsumerNotFound, err)
+	}
+}
+
+func testOrderedConsumer(t *testing.T, s *suite) {
+	name := s.name(t)
+	s.mustCreateStream(t, jetstream.StreamConfig{Name: name})
+	s.publish(t, name+".a", 5)
+
+	c, err := s.js.OrderedConsumer(s.context(t), name, jetstream.OrderedConsumerConfig{})
+	if err != nil {
+		t.Fatalf("Unable to create ordered consumer: %v", err)
+	}
+	for i := 1; i <= 5; i++ {
+		msg, err := c.Next(jetstream.FetchMaxWait(time.Second))
+		if err != nil {
+			t.Fatalf("Unable to get message %d: %v", i, err)
+		}
+		meta, err := msg.Metadata()
+		if err != nil {
+			t.Fatalf("Unable to get metadata: %v", err)
+		}
+		if meta.Sequence.Stream != uint64(i) {
+			t.Fatalf("Expected sequence %d; got: %d", i, meta.Sequence.Stream)
+		}
+	}
+}
+
+func testFilterSubjects(t *testing.T, s *suite) {
+	name := s.name(t)
+	stream := s.mustCreateStream(t, jetstream.StreamConfig{Name: name})
+	for _, subj := range []string{"a", "b", "c", "a"} {
+		s.publish(t, name+"."+subj, 1)
+	}
+
+	c, err := stream.AddConsumer(s.context(t), jetstream.ConsumerConfig{
+		FilterSubjects: []string{name + ".a", name + ".b"},
+		AckPolicy:      jetstream.AckNonePolicy,
+	})
+	if !s.serverAtLeast(2, 10) {
+		if !errors.Is(err, jetstream.ErrConsumerMultipleFilterSubjectsNotSupported) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrConsumerMultipleFilterSubjectsNotSupported, err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("Unable to create consumer: %v", err)
+	}
+	if pending := c.CachedInfo().NumPending; pending != 3 {
+		t.Fatalf("Expected 3 pending messages; got: %d", pending)
+	}
+}
+
+func testStreamV210(t *testing.T, s *suite) {
+	supported := s.serverAtLeast(2, 10)
+
+	t.Run("compression", func(t *testing.T) {
+		stream, err := s.createStream(t, jetstream.StreamConfig{Compression: jetstream.S2Compression})
+		if !supported {
+			if !errors.Is(err, jetstream.ErrStreamCompressionNotSupported) {
+				t.Fatalf("Expected error: %v; got: %v", jetstream.ErrStreamCompressionNotSupported, err)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("Unable to create stream: %v", err)
+		}
+		if stream.CachedInfo().Config.Compression != jetstream.S2Compression {
+			t.Fatalf("Expected S2 compression; got: %v", stream.CachedInfo().Config.Compression)
+		}
+	})
+
+	t.Run("subject_transform", func(t *testing.T) {
+		name := s.name(t)
+		stream, err := s.createStream(t, jetstream.StreamConfig{
+			Name:             name,
+			SubjectTransform: &jetstream.SubjectTransformConfig{Source: name + ".*", Destination: name + ".transformed.{{wildcard(1)}}"},
+		})
+		if !supported {
+			if !errors.Is(err, jetstream.ErrStreamSubjectTransformNotSupported) {
+				t.Fatalf("Expected error: %v; got: %v", jetstream.ErrStreamSubjectTransformNotSupported, err)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("Unable to create stream: %v", err)
+		}
+		s.publish(t, name+".a", 1)
+		msg, err := stream.GetMsg(s.context(t), 1)
+		if err != nil {
+			t.Fatalf("Unable to get message: %v", err)
+		}
+		if msg.Subject != name+".transformed.a" {
+			t.Fatalf("Expected transformed subject; got: %q", msg.Subject)
+		}
+	})
+
+	t.Run("metadata", func(t *testing.T) {
+		stream, err := s.createStream(t, jetstream.StreamConfig{Metadata: map[string]string{"owner": "compat"}})
+		if err != nil {
+			t.Fatalf("Unable to create stream: %v", err)
+		}
+		// older servers ignore metadata
+		if supported && stream.CachedInfo().Config.Metadata["owner"] != "compat" {
+			t.Fatalf("Expected metadata to be stored; got: %v", stream.CachedInfo().Config.Metadata)
+		}
+	})
+}
+
+func testMsgTTL(t *testing.T, s *suite) {
+	name := s.name(t)
+	_, err := s.createStream(t, jetstream.StreamConfig{Name: name, AllowMsgTTL: true})
+	if !s.serverAtLeast(2, 11) {
+		if !errors.Is(err, jetstream.ErrStreamMsgTTLNotSupported) {
+			t.Fatalf("Expected error: %v; got: %v", jetstream.ErrStreamMsgTTLNotSupported, err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("Unable to create stream: %v", err)
+	}
+	stream, err := s.js.Stream(s.context(t), name)
+	if err != nil {
+		t.Fatalf("Unable to get stream: %v", err)
+	}
+	if _, err := s.js.Publish(s.context(t), name+".a", []byte("ttl"), jetstream.WithMsgTTL(time.Second)); err != nil {
+		t.Fatalf("Unable to publish: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, err := stream.GetMsg(s.context(t), 1)
+		if errors.Is(err, jetstream.ErrMsgNotFound) {
+			return
+		}
+		if err != nil {
+			t.Fatalf("Unable to get message: %v", err)
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Message did not expire")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func testKeyValue(t *testing.T, s *suite) {
+	bucket := s.name(t)
+	kv, err := s.js.CreateKeyValue(s.context(t), jetstream.KeyValueConfig{Bucket: bucket, History: 5})
+	if err != nil {
+		t.Fatalf("Unable to create bucket: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.js.DeleteKeyValue(ctx, bucket); err != nil {
+			t.Errorf("Unable to delete bucket %q: %v", bucket, err)
+		}
+	})
+
+	watcher, err := kv.Watch(s.context(t), "key")
+	if err != nil {
+		t.Fatalf("Unable to watch: %v", err)
+	}
+	defer watcher.Stop()
+	// initial values are done
+	select {
+	case entry := <-watcher.Updates():
+		if entry != nil {
+			t.Fatalf("Unexpected entry: %+v", entry)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Did not receive initial values marker")
+	}
+
+	rev, err := kv.Create(s.context(t), "key", []byte("1"))
+	if err != nil {
+		t.Fatalf("Unable to create key: %v", err)
+	}
+	if _, err := kv.Create(s.context(t), "key", []byte("1")); !errors.Is(err, jetstream.ErrKeyExists) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrKeyExists, err)
+	}
+	if _, err := kv.Update(s.context(t), "key", []byte("2"), rev); err != nil {
+		t.Fatalf("Unable to update key: %v", err)
+	}
+	if _, err := kv.Update(s.context(t), "key", []byte("3"), rev); err == nil {
+		t.Fatalf("Expected error updating with stale revision")
+	}
+	entry, err := kv.Get(s.context(t), "key")
+	if err != nil {
+		t.Fatalf("Unable to get key: %v", err)
+	}
+	if string(entry.Value()) != "2" || entry.Revision() != rev+1 {
+		t.Fatalf("Unexpected entry: %q @ %d", entry.Value(), entry.Revision())
+	}
+	history, err := kv.History(s.context(t), "key")
+	if err != nil {
+		t.Fatalf("Unable to get history: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("Expected 2 history entries; got: %d", len(history))
+	}
+
+	if err := kv.Delete(s.context(t), "key"); err != nil {
+		t.Fatalf("Unable to delete key: %v", err)
+	}
+	if _, err := kv.Get(s.context(t), "key"); !errors.Is(err, jetstream.ErrKeyNotFound) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrKeyNotFound, err)
+	}
+
+	for _, expected := range []string{"1", "2", ""} {
+		select {
+		case entry := <-watcher.Updates():
+			if string(entry.Value()) != expected {
+				t.Fatalf("Expected watched value %q; got: %q", expected, entry.Value())
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Did not receive update %q", expected)
+		}
+	}
+}
+
+func testObjectStore(t *testing.T, s *suite) {
+	bucket := s.name(t)
+	obs, err := s.js.CreateObjectStore(s.context(t), jetstream.ObjectStoreConfig{Bucket: bucket})
+	if err != nil {
+		t.Fatalf("Unable to create object store: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.js.DeleteObjectStore(ctx, bucket); err != nil {
+			t.Errorf("Unable to delete object store %q: %v", bucket, err)
+		}
+	})
+
+	// spans multiple chunks
+	data := bytes.Repeat([]byte("compat"), 100_000)
+	info, err := obs.PutBytes(s.context(t), "object", data)
+	if err != nil {
+		t.Fatalf("Unable to put object: %v", err)
+	}
+	if info.Size != uint64(len(data)) || info.Chunks < 2 {
+		t.Fatalf("Unexpected object info: size %d, chunks %d", info.Size, info.Chunks)
+	}
+	got, err := obs.GetBytes(s.context(t), "object")
+	if err != nil {
+		t.Fatalf("Unable to get object: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("Object data does not match")
+	}
+	if err := obs.Delete(s.context(t), "object"); err != nil {
+		t.Fatalf("Unable to delete object: %v", err)
+	}
+	if _, err := obs.GetBytes(s.context(t), "object"); !errors.Is(err, jetstream.ErrObjectNotFound) {
+		t.Fatalf("Expected error: %v; got: %v", jetstream.ErrObjectNotFound, err)
+	}
+}
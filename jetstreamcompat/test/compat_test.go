@@ -0,0 +1,52 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/internal/testutil"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/nats-io/nats.go/jetstreamcompat"
+)
+
+func TestRun(t *testing.T) {
+	srv := testutil.RunBasicJetStreamServer(t)
+	defer testutil.ShutdownJSServerAndRemoveStorage(t, srv)
+
+	jetstreamcompat.Run(t, srv.ClientURL(), nats.Name("compat"))
+
+	// all resources are removed
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	info, err := js.AccountInfo(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info.Streams != 0 {
+		t.Fatalf("Expected all streams to be removed; got: %d", info.Streams)
+	}
+}
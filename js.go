@@ -45,6 +45,7 @@ type JetStream interface {
 
 	// PublishMsgAsync publishes a Msg to JetStream and returns a PubAckFuture.
 	// The message should not be changed until the PubAckFuture has been processed.
+	// A message over the maximum payload is rejected with ErrMaxPayloadExceeded.
 	PublishMsgAsync(m *Msg, opts ...PubOpt) (PubAckFuture, error)
 
 	// PublishAsyncPending returns the number of async publishes outstanding for this context.
@@ -234,6 +235,9 @@ type js struct {
 	stc  chan struct{}
 	dch  chan struct{}
 	rr   *rand.Rand
+
+	// Removes the shutdown hook registered along with rsub.
+	rhook func()
 }
 
 type jsOpts struct {
@@ -321,6 +325,9 @@ func UseLegacyDurableConsumers() JSOpt {
 type ClientTrace struct {
 	RequestSent      func(subj string, payload []byte)
 	ResponseReceived func(subj string, payload []byte, hdr Header)
+	// Redactor masks payloads and headers before they are passed to the
+	// callbacks. Defaults to the redactor set with [TraceRedactor], if any.
+	Redactor Redactor
 }
 
 func (ct ClientTrace) configureJSContext(js *jsOpts) error {
@@ -645,6 +652,10 @@ const aReplyTokensize = 6
 
 func (js *js) newAsyncReply() string {
 	js.mu.Lock()
+	if js.rsub != nil && !js.rsub.IsValid() {
+		// The reply subscription is gone, e.g. it was closed by the server.
+		js.cleanupReplySub()
+	}
 	if js.rsub == nil {
 		// Create our wildcard reply subject.
 		sha := sha256.New()
@@ -665,6 +676,7 @@ func (js *js) newAsyncReply() string {
 		}
 		js.rsub = sub
 		js.rr = rand.New(rand.NewSource(time.Now().UnixNano()))
+		js.rhook = js.nc.AddShutdownHook(js.waitAsyncComplete)
 	}
 	var sb strings.Builder
 	sb.WriteString(js.rpre)
@@ -679,6 +691,21 @@ func (js *js) newAsyncReply() string {
 	return sb.String()
 }
 
+// cleanupReplySub removes the async reply subscription and the shutdown
+// hook registered with it, so that the connection does not keep referencing
+// this context. A new subscription is created on the next async publish.
+// Lock should be held.
+func (js *js) cleanupReplySub() {
+	if js.rsub != nil {
+		js.rsub.Unsubscribe()
+		js.rsub = nil
+	}
+	if js.rhook != nil {
+		js.rhook()
+		js.rhook = nil
+	}
+}
+
 // registerPAF will register for a PubAckFuture.
 func (js *js) registerPAF(id string, paf *pubAckFuture) (int, int) {
 	js.mu.Lock()
@@ -868,6 +895,11 @@ func (js *js) PublishMsgAsync(m *Msg, opts ...PubOpt) (PubAckFuture, error) {
 	if m.Reply != _EMPTY_ {
 		return nil, errors.New("nats: reply subject should be empty")
 	}
+	// Reject messages over the max payload before registering the ack
+	// future, as waiting for a stall would only delay the error.
+	if err := js.nc.CheckMaxPayload(m); err != nil {
+		return nil, err
+	}
 	reply := m.Reply
 	m.Reply = js.newAsyncReply()
 	defer func() { m.Reply = reply }()
@@ -911,6 +943,16 @@ func (js *js) PublishAsyncComplete() <-chan struct{} {
 	return dch
 }
 
+// waitAsyncComplete waits for outstanding async publishes on connection shutdown.
+func (js *js) waitAsyncComplete(ctx context.Context) error {
+	select {
+	case <-js.PublishAsyncComplete():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // MsgId sets the message ID used for deduplication.
 func MsgId(id string) PubOpt {
 	return pubOptFn(func(opts *pubOpts) error {
@@ -1244,7 +1286,7 @@ func (js *js) Subscribe(subj string, cb MsgHandler, opts ...SubOpt) (*Subscripti
 // SubscribeSync creates a Subscription that can be used to process messages synchronously.
 // See important note in Subscribe()
 func (js *js) SubscribeSync(subj string, opts ...SubOpt) (*Subscription, error) {
-	mch := make(chan *Msg, js.nc.Opts.SubChanLen)
+	mch := make(chan *Msg, js.nc.subChanLen())
 	return js.subscribe(subj, _EMPTY_, nil, mch, true, false, opts)
 }
 
@@ -1261,7 +1303,7 @@ func (js *js) QueueSubscribe(subj, queue string, cb MsgHandler, opts ...SubOpt)
 // QueueSubscribeSync creates a Subscription with a queue group that can be used to process messages synchronously.
 // See important note in QueueSubscribe()
 func (js *js) QueueSubscribeSync(subj, queue string, opts ...SubOpt) (*Subscription, error) {
-	mch := make(chan *Msg, js.nc.Opts.SubChanLen)
+	mch := make(chan *Msg, js.nc.subChanLen())
 	return js.subscribe(subj, queue, nil, mch, true, false, opts)
 }
 
@@ -1283,7 +1325,7 @@ func (js *js) ChanQueueSubscribe(subj, queue string, ch chan *Msg, opts ...SubOp
 // PullSubscribe creates a Subscription that can fetch messages.
 // See important note in Subscribe()
 func (js *js) PullSubscribe(subj, durable string, opts ...SubOpt) (*Subscription, error) {
-	mch := make(chan *Msg, js.nc.Opts.SubChanLen)
+	mch := make(chan *Msg, js.nc.subChanLen())
 	if durable != "" {
 		opts = append(opts, Durable(durable))
 	}
@@ -3081,7 +3123,8 @@ func (js *js) apiRequestWithContext(ctx context.Context, subj string, data []byt
 	if js.opts.shouldTrace {
 		ctrace := js.opts.ctrace
 		if ctrace.RequestSent != nil {
-			ctrace.RequestSent(subj, data)
+			payload, _ := js.redact(subj, data, nil)
+			ctrace.RequestSent(subj, payload)
 		}
 	}
 	resp, err := js.nc.RequestWithContext(ctx, subj, data)
@@ -3090,14 +3133,27 @@ func (js *js) apiRequestWithContext(ctx context.Context, subj string, data []byt
 	}
 	if js.opts.shouldTrace {
 		ctrace := js.opts.ctrace
-		if ctrace.RequestSent != nil {
-			ctrace.ResponseReceived(subj, resp.Data, resp.Header)
+		if ctrace.ResponseReceived != nil {
+			payload, hdr := js.redact(subj, resp.Data, resp.Header)
+			ctrace.ResponseReceived(subj, payload, hdr)
 		}
 	}
 
 	return resp, nil
 }
 
+// redact applies the trace redactor, if any, to the data passed to the trace callbacks.
+func (js *js) redact(subj string, payload []byte, hdr Header) ([]byte, Header) {
+	r := js.opts.ctrace.Redactor
+	if r == nil {
+		r = js.nc.TraceRedactor()
+	}
+	if r == nil {
+		return payload, hdr
+	}
+	return r.RedactPayload(subj, payload), r.RedactHeader(subj, hdr)
+}
+
 func (m *Msg) checkReply() error {
 	if m == nil || m.Sub == nil {
 		return ErrMsgNotBound
@@ -923,6 +923,122 @@ func TestJetStreamTracing(t *testing.T) {
 	}
 }
 
+func TestJetStreamTracingRedaction(t *testing.T) {
+	s := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, s)
+
+	nc, err := Connect(s.ClientURL(), TraceRedactor(DefaultRedactor()))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+
+	var sent, received []byte
+	js, err := nc.JetStream(&ClientTrace{
+		RequestSent: func(subj string, payload []byte) {
+			sent = payload
+		},
+		ResponseReceived: func(subj string, payload []byte, hdr Header) {
+			received = payload
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err = js.AddStream(&StreamConfig{Name: "X"}); err != nil {
+		t.Fatalf("add stream failed: %s", err)
+	}
+	if !strings.HasPrefix(string(sent), "[REDACTED ") || !strings.HasPrefix(string(received), "[REDACTED ") {
+		t.Fatalf("Expected redacted payloads; got: %q, %q", sent, received)
+	}
+
+	// Redactor set on the trace takes precedence.
+	js, err = nc.JetStream(&ClientTrace{
+		RequestSent: func(subj string, payload []byte) {
+			sent = payload
+		},
+		Redactor: &MaskRedactor{KeepPayload: true},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err = js.StreamInfo("X"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.HasPrefix(string(sent), "[REDACTED") {
+		t.Fatalf("Expected payload to be kept; got: %q", sent)
+	}
+}
+
+func TestMaskRedactorHeader(t *testing.T) {
+	hdr := Header{
+		"authorization": []string{"Bearer token"},
+		"X-Api-Key":     []string{"a", "b"},
+		"Content-Type":  []string{"application/json"},
+	}
+	redacted := DefaultRedactor().RedactHeader("foo", hdr)
+	if v := redacted.Values("authorization"); len(v) != 1 || v[0] != RedactedValue {
+		t.Fatalf("Expected authorization to be masked; got: %v", v)
+	}
+	if v := redacted.Values("X-Api-Key"); len(v) != 2 || v[0] != RedactedValue || v[1] != RedactedValue {
+		t.Fatalf("Expected api key to be masked; got: %v", v)
+	}
+	if v := redacted.Get("Content-Type"); v != "application/json" {
+		t.Fatalf("Expected content type to be kept; got: %q", v)
+	}
+	if hdr.Get("X-Api-Key") != "a" {
+		t.Fatalf("Original header was modified")
+	}
+	if p := DefaultRedactor().RedactPayload("foo", nil); p != nil {
+		t.Fatalf("Expected empty payload to be kept; got: %q", p)
+	}
+}
+
+func TestRecordRequestsRedaction(t *testing.T) {
+	s := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, s)
+
+	var req, resp *Msg
+	nc, err := Connect(s.ClientURL(), TraceRedactor(DefaultRedactor()), RecordRequests(func(rq, rs *Msg, _ error) {
+		req, resp = rq, rs
+	}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	sub, err := nc.Subscribe("secret", func(m *Msg) {
+		r := NewMsg(m.Reply)
+		r.Header.Set("Set-Cookie", "session")
+		r.Data = []byte("token")
+		m.RespondMsg(r)
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	m := NewMsg("secret")
+	m.Header.Set("Authorization", "Bearer token")
+	m.Data = []byte("password")
+	got, err := nc.RequestMsg(m, time.Second)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if req == nil || resp == nil {
+		t.Fatalf("Expected request to be recorded")
+	}
+	if req.Header.Get("Authorization") != RedactedValue || !strings.HasPrefix(string(req.Data), "[REDACTED ") {
+		t.Fatalf("Expected redacted request; got: %v, %q", req.Header, req.Data)
+	}
+	if resp.Header.Get("Set-Cookie") != RedactedValue || !strings.HasPrefix(string(resp.Data), "[REDACTED ") {
+		t.Fatalf("Expected redacted response; got: %v, %q", resp.Header, resp.Data)
+	}
+	// The caller receives the response unchanged.
+	if string(got.Data) != "token" || got.Header.Get("Set-Cookie") != "session" {
+		t.Fatalf("Unexpected response: %v, %q", got.Header, got.Data)
+	}
+}
+
 func TestJetStreamExpiredPullRequests(t *testing.T) {
 	s := RunBasicJetStreamServer()
 	defer shutdownJSServerAndRemoveStorage(t, s)
@@ -0,0 +1,378 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package kvschema defines the layout of keys stored in a key value bucket
+// and validates keys against it.
+package kvschema
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Notice: Experimental Preview
+//
+// This functionality is EXPERIMENTAL and may be changed in later releases.
+
+type (
+	// Template is a key pattern, such as "users.<id>.profile", in which
+	// tokens enclosed in angle brackets are parameters.
+	Template struct {
+		pattern     string
+		description string
+		tokens      []string
+		params      []int // indexes of parameter tokens
+	}
+
+	// DefineOpt configures a [Template].
+	DefineOpt func(*Template)
+
+	// Bucket is a key value bucket only accepting keys matching one of
+	// its templates. Methods taking a key validate it before calling the
+	// underlying bucket; other methods are passed through.
+	Bucket struct {
+		nats.KeyValue
+		templates []*Template
+	}
+
+	// Accessor reads and writes the keys of a single template, taking
+	// parameter values instead of keys.
+	Accessor struct {
+		kv nats.KeyValue
+		t  *Template
+	}
+)
+
+var (
+	// ErrInvalidTemplate is returned when defining a malformed template.
+	ErrInvalidTemplate = errors.New("invalid key template")
+
+	// ErrInvalidParam is returned when a parameter value is not a valid key token
+	// or the number of values does not match the template.
+	ErrInvalidParam = errors.New("invalid key parameter")
+
+	// ErrKeyNotInSchema is returned when a key does not match any template of a bucket.
+	ErrKeyNotInSchema = errors.New("key does not match schema")
+)
+
+var (
+	paramRe = regexp.MustCompile(`\A<([a-zA-Z_][a-zA-Z0-9_]*)>\z`)
+	tokenRe = regexp.MustCompile(`\A[-/_=a-zA-Z0-9]+\z`)
+)
+
+// WithDescription sets a description of the keys, used in [Bucket.Layout].
+func WithDescription(description string) DefineOpt {
+	return func(t *Template) {
+		t.description = description
+	}
+}
+
+// Define parses a key template. Tokens are separated by "." and are either
+// literals or parameters of the form "<name>", which match a single token.
+func Define(pattern string, opts ...DefineOpt) (*Template, error) {
+	t := &Template{pattern: pattern, tokens: strings.Split(pattern, ".")}
+	names := make(map[string]struct{})
+	for i, token := range t.tokens {
+		if m := paramRe.FindStringSubmatch(token); m != nil {
+			if _, ok := names[m[1]]; ok {
+				return nil, fmt.Errorf("%w: duplicate parameter %q in %q", ErrInvalidTemplate, m[1], pattern)
+			}
+			names[m[1]] = struct{}{}
+			t.tokens[i] = m[1]
+			t.params = append(t.params, i)
+			continue
+		}
+		if !tokenRe.MatchString(token) {
+			return nil, fmt.Errorf("%w: invalid token %q in %q", ErrInvalidTemplate, token, pattern)
+		}
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t, nil
+}
+
+// MustDefine is like [Define] but panics on error.
+func MustDefine(pattern string, opts ...DefineOpt) *Template {
+	t, err := Define(pattern, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Pattern returns the template pattern.
+func (t *Template) Pattern() string {
+	return t.pattern
+}
+
+// Description returns the template description.
+func (t *Template) Description() string {
+	return t.description
+}
+
+// Params returns the names of the template parameters in order.
+func (t *Template) Params() []string {
+	names := make([]string, 0, len(t.params))
+	for _, i := range t.params {
+		names = append(names, t.tokens[i])
+	}
+	return names
+}
+
+// Key returns the key for the given parameter values, in the order of [Template.Params].
+func (t *Template) Key(values ...string) (string, error) {
+	if len(values) != len(t.params) {
+		return "", fmt.Errorf("%w: %q expects %d values, got %d", ErrInvalidParam, t.pattern, len(t.params), len(values))
+	}
+	tokens := append([]string(nil), t.tokens...)
+	for i, p := range t.params {
+		if !tokenRe.MatchString(values[i]) {
+			return "", fmt.Errorf("%w: invalid value %q for %q", ErrInvalidParam, values[i], t.tokens[p])
+		}
+		tokens[p] = values[i]
+	}
+	return strings.Join(tokens, "."), nil
+}
+
+// Parse returns the parameter values of a key matching the template.
+func (t *Template) Parse(key string) (map[string]string, error) {
+	tokens := strings.Split(key, ".")
+	if len(tokens) != len(t.tokens) {
+		return nil, fmt.Errorf("%w: %q does not match %q", ErrKeyNotInSchema, key, t.pattern)
+	}
+	values := make(map[string]string, len(t.params))
+	p := 0
+	for i, token := range tokens {
+		if p < len(t.params) && t.params[p] == i {
+			if !tokenRe.MatchString(token) {
+				return nil, fmt.Errorf("%w: %q does not match %q", ErrKeyNotInSchema, key, t.pattern)
+			}
+			values[t.tokens[i]] = token
+			p++
+			continue
+		}
+		if token != t.tokens[i] {
+			return nil, fmt.Errorf("%w: %q does not match %q", ErrKeyNotInSchema, key, t.pattern)
+		}
+	}
+	return values, nil
+}
+
+// Matches returns true if the key matches the template.
+func (t *Template) Matches(key string) bool {
+	_, err := t.Parse(key)
+	return err == nil
+}
+
+// Filter returns a key filter matching all keys of the template,
+// which can be used with [nats.KeyValue.Watch].
+func (t *Template) Filter() string {
+	tokens := append([]string(nil), t.tokens...)
+	for _, p := range t.params {
+		tokens[p] = "*"
+	}
+	return strings.Join(tokens, ".")
+}
+
+// Bind returns an accessor for the keys of the template in a bucket.
+func (t *Template) Bind(kv nats.KeyValue) *Accessor {
+	return &Accessor{kv: kv, t: t}
+}
+
+// New returns a bucket validating keys against the given templates.
+// Keys are matched against templates in order.
+func New(kv nats.KeyValue, templates ...*Template) (*Bucket, error) {
+	if len(templates) == 0 {
+		return nil, fmt.Errorf("%w: at least one template is required", ErrInvalidTemplate)
+	}
+	filters := make(map[string]string, len(templates))
+	for _, t := range templates {
+		if other, ok := filters[t.Filter()]; ok {
+			return nil, fmt.Errorf("%w: %q overlaps with %q", ErrInvalidTemplate, t.pattern, other)
+		}
+		filters[t.Filter()] = t.pattern
+	}
+	return &Bucket{KeyValue: kv, templates: templates}, nil
+}
+
+// Templates returns the templates of the bucket.
+func (b *Bucket) Templates() []*Template {
+	return append([]*Template(nil), b.templates...)
+}
+
+// Template returns the template matching the key, or [ErrKeyNotInSchema].
+func (b *Bucket) Template(key string) (*Template, error) {
+	for _, t := range b.templates {
+		if t.Matches(key) {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrKeyNotInSchema, key)
+}
+
+// Layout returns a description of the bucket layout, listing the
+// templates and their descriptions.
+func (b *Bucket) Layout() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Bucket %s\n", b.Bucket())
+	for _, t := range b.templates {
+		sb.WriteString("  ")
+		sb.WriteString(t.pattern)
+		if t.description != "" {
+			sb.WriteString(" - ")
+			sb.WriteString(t.description)
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+func (b *Bucket) validate(key string) error {
+	_, err := b.Template(key)
+	return err
+}
+
+// Get returns the latest value for the key.
+func (b *Bucket) Get(key string) (nats.KeyValueEntry, error) {
+	if err := b.validate(key); err != nil {
+		return nil, err
+	}
+	return b.KeyValue.Get(key)
+}
+
+// GetRevision returns a specific revision value for the key.
+func (b *Bucket) GetRevision(key string, revision uint64) (nats.KeyValueEntry, error) {
+	if err := b.validate(key); err != nil {
+		return nil, err
+	}
+	return b.KeyValue.GetRevision(key, revision)
+}
+
+// Put will place the new value for the key into the store.
+func (b *Bucket) Put(key string, value []byte) (uint64, error) {
+	if err := b.validate(key); err != nil {
+		return 0, err
+	}
+	return b.KeyValue.Put(key, value)
+}
+
+// PutString will place the string for the key into the store.
+func (b *Bucket) PutString(key string, value string) (uint64, error) {
+	if err := b.validate(key); err != nil {
+		return 0, err
+	}
+	return b.KeyValue.PutString(key, value)
+}
+
+// Create will add the key/value pair iff it does not exist.
+func (b *Bucket) Create(key string, value []byte) (uint64, error) {
+	if err := b.validate(key); err != nil {
+		return 0, err
+	}
+	return b.KeyValue.Create(key, value)
+}
+
+// Update will update the value iff the latest revision matches.
+func (b *Bucket) Update(key string, value []byte, last uint64) (uint64, error) {
+	if err := b.validate(key); err != nil {
+		return 0, err
+	}
+	return b.KeyValue.Update(key, value, last)
+}
+
+// Delete will place a delete marker and leave all revisions.
+func (b *Bucket) Delete(key string, opts ...nats.DeleteOpt) error {
+	if err := b.validate(key); err != nil {
+		return err
+	}
+	return b.KeyValue.Delete(key, opts...)
+}
+
+// Purge will place a delete marker and remove all previous revisions.
+func (b *Bucket) Purge(key string, opts ...nats.DeleteOpt) error {
+	if err := b.validate(key); err != nil {
+		return err
+	}
+	return b.KeyValue.Purge(key, opts...)
+}
+
+// History will return all historical values for the key.
+func (b *Bucket) History(key string, opts ...nats.WatchOpt) ([]nats.KeyValueEntry, error) {
+	if err := b.validate(key); err != nil {
+		return nil, err
+	}
+	return b.KeyValue.History(key, opts...)
+}
+
+// Template returns the template of the accessor.
+func (a *Accessor) Template() *Template {
+	return a.t
+}
+
+// Get returns the latest value for the key with the given parameter values.
+func (a *Accessor) Get(values ...string) (nats.KeyValueEntry, error) {
+	key, err := a.t.Key(values...)
+	if err != nil {
+		return nil, err
+	}
+	return a.kv.Get(key)
+}
+
+// Put places the value for the key with the given parameter values.
+func (a *Accessor) Put(value []byte, values ...string) (uint64, error) {
+	key, err := a.t.Key(values...)
+	if err != nil {
+		return 0, err
+	}
+	return a.kv.Put(key, value)
+}
+
+// Delete places a delete marker for the key with the given parameter values.
+func (a *Accessor) Delete(values ...string) error {
+	key, err := a.t.Key(values...)
+	if err != nil {
+		return err
+	}
+	return a.kv.Delete(key)
+}
+
+// Watch watches all keys of the template.
+func (a *Accessor) Watch(opts ...nats.WatchOpt) (nats.KeyWatcher, error) {
+	return a.kv.Watch(a.t.Filter(), opts...)
+}
+
+// List returns the parameter values of all keys of the template in the bucket.
+func (a *Accessor) List() ([]map[string]string, error) {
+	w, err := a.Watch(nats.IgnoreDeletes(), nats.MetaOnly())
+	if err != nil {
+		return nil, err
+	}
+	defer w.Stop()
+	var res []map[string]string
+	for entry := range w.Updates() {
+		if entry == nil {
+			break
+		}
+		values, err := a.t.Parse(entry.Key())
+		if err != nil {
+			continue
+		}
+		res = append(res, values)
+	}
+	return res, nil
+}
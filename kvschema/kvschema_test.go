@@ -0,0 +1,65 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kvschema
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTemplate(t *testing.T) {
+	for _, pattern := range []string{"", "users..profile", "users.<id>.<id>", "users.<i d>", "users.*", "users.>"} {
+		if _, err := Define(pattern); !errors.Is(err, ErrInvalidTemplate) {
+			t.Fatalf("Expected error %v for %q; got: %v", ErrInvalidTemplate, pattern, err)
+		}
+	}
+
+	tmpl, err := Define("users.<id>.devices.<device>", WithDescription("user devices"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if params := tmpl.Params(); !reflect.DeepEqual(params, []string{"id", "device"}) {
+		t.Fatalf("Unexpected params: %v", params)
+	}
+	if filter := tmpl.Filter(); filter != "users.*.devices.*" {
+		t.Fatalf("Unexpected filter: %q", filter)
+	}
+
+	key, err := tmpl.Key("42", "phone")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if key != "users.42.devices.phone" {
+		t.Fatalf("Unexpected key: %q", key)
+	}
+	for _, values := range [][]string{{"42"}, {"42", "a.b"}, {"42", ""}, {"4 2", "phone"}} {
+		if _, err := tmpl.Key(values...); !errors.Is(err, ErrInvalidParam) {
+			t.Fatalf("Expected error %v for %v; got: %v", ErrInvalidParam, values, err)
+		}
+	}
+
+	values, err := tmpl.Parse("users.7.devices.tablet")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(values, map[string]string{"id": "7", "device": "tablet"}) {
+		t.Fatalf("Unexpected values: %v", values)
+	}
+	for _, key := range []string{"users.7.devices", "users.7.phones.tablet", "users.7.devices.tablet.x", "admins.7.devices.tablet"} {
+		if tmpl.Matches(key) {
+			t.Fatalf("Expected %q not to match", key)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/internal/testutil"
+	"github.com/nats-io/nats.go/kvschema"
+)
+
+func TestSchemaBucket(t *testing.T) {
+	s := testutil.RunBasicJetStreamServer(t)
+	defer testutil.ShutdownJSServerAndRemoveStorage(t, s)
+
+	nc, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	js, err := nc.JetStream()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	kv, err := js.CreateKeyValue(&nats.KeyValueConfig{Bucket: "USERS"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	profile := kvschema.MustDefine("users.<id>.profile", kvschema.WithDescription("user profile as JSON"))
+	settings := kvschema.MustDefine("users.<id>.settings.<name>")
+
+	if _, err := kvschema.New(kv, profile, kvschema.MustDefine("users.<uid>.profile")); !errors.Is(err, kvschema.ErrInvalidTemplate) {
+		t.Fatalf("Expected error: %v; got: %v", kvschema.ErrInvalidTemplate, err)
+	}
+	bucket, err := kvschema.New(kv, profile, settings)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := bucket.Put("users.1.profile", []byte(`{"name":"a"}`)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := bucket.Put("users.1.avatar", []byte("png")); !errors.Is(err, kvschema.ErrKeyNotInSchema) {
+		t.Fatalf("Expected error: %v; got: %v", kvschema.ErrKeyNotInSchema, err)
+	}
+	if _, err := bucket.Get("users.1"); !errors.Is(err, kvschema.ErrKeyNotInSchema) {
+		t.Fatalf("Expected error: %v; got: %v", kvschema.ErrKeyNotInSchema, err)
+	}
+	entry, err := bucket.Get("users.1.profile")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(entry.Value()) != `{"name":"a"}` {
+		t.Fatalf("Unexpected value: %q", entry.Value())
+	}
+	if tmpl, err := bucket.Template("users.1.settings.theme"); err != nil || tmpl != settings {
+		t.Fatalf("Expected settings template; got: %v, %v", tmpl, err)
+	}
+
+	layout := bucket.Layout()
+	if !strings.Contains(layout, "users.<id>.profile - user profile as JSON") || !strings.Contains(layout, "users.<id>.settings.<name>") {
+		t.Fatalf("Unexpected layout:\n%s", layout)
+	}
+
+	userSettings := settings.Bind(kv)
+	for _, values := range [][]string{{"1", "theme"}, {"1", "lang"}, {"2", "theme"}} {
+		if _, err := userSettings.Put([]byte("value"), values...); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if _, err := userSettings.Put([]byte("value"), "1"); !errors.Is(err, kvschema.ErrInvalidParam) {
+		t.Fatalf("Expected error: %v; got: %v", kvschema.ErrInvalidParam, err)
+	}
+	entry, err = userSettings.Get("2", "theme")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if entry.Key() != "users.2.settings.theme" {
+		t.Fatalf("Unexpected key: %q", entry.Key())
+	}
+	if err := userSettings.Delete("1", "lang"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	list, err := userSettings.List()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var got []string
+	for _, values := range list {
+		got = append(got, values["id"]+"/"+values["name"])
+	}
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, []string{"1/theme", "2/theme"}) {
+		t.Fatalf("Unexpected keys: %v", got)
+	}
+}
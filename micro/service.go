@@ -305,6 +305,15 @@ func AddService(nc *nats.Conn, config Config) (Service, error) {
 	if config.Metadata == nil {
 		config.Metadata = map[string]string{}
 	}
+	if id := nc.Identity(); id != nil {
+		// identity of the client is reported in service info and stats,
+		// unless overridden by the user
+		md := id.Metadata()
+		for k, v := range config.Metadata {
+			md[k] = v
+		}
+		config.Metadata = md
+	}
 
 	id := nuid.Next()
 	svc := &service{
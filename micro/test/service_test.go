@@ -1403,3 +1403,46 @@ func TestControlSubject(t *testing.T) {
 		})
 	}
 }
+
+func TestServiceClientIdentity(t *testing.T) {
+	s := RunServerOnPort(-1)
+	defer s.Shutdown()
+
+	nc, err := nats.Connect(s.ClientURL(), nats.Identity(nats.ClientIdentity{Service: "orders", Version: "1.2.0", Instance: "pod-1"}))
+	if err != nil {
+		t.Fatalf("Expected to connect to server, got %v", err)
+	}
+	defer nc.Close()
+
+	metadata := map[string]string{"team": "billing", nats.IdentityVersionKey: "custom"}
+	srv, err := micro.AddService(nc, micro.Config{
+		Name:     "test_service",
+		Version:  "0.1.0",
+		Metadata: metadata,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer srv.Stop()
+	if len(metadata) != 2 {
+		t.Fatalf("Service config metadata should not be modified: %v", metadata)
+	}
+
+	expected := map[string]string{
+		"team":                   "billing",
+		nats.IdentityServiceKey:  "orders",
+		nats.IdentityVersionKey:  "custom",
+		nats.IdentityInstanceKey: "pod-1",
+	}
+	resp, err := nc.Request("$SRV.STATS.test_service", nil, time.Second)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var stats micro.Stats
+	if err := json.Unmarshal(resp.Data, &stats); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(stats.Metadata, expected) {
+		t.Fatalf("Expected metadata: %v; got: %v", expected, stats.Metadata)
+	}
+}
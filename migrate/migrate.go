@@ -0,0 +1,214 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package migrate versions message payloads and upgrades old payloads when
+// they are consumed. Publishers stamp messages with the version of the
+// payload in a header, and consumers register migrations upcasting payloads
+// from one version to the next, so that events stored in a stream long ago
+// are decoded into the current shape of the type.
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Notice: Experimental Preview
+//
+// This functionality is EXPERIMENTAL and may be changed in later releases.
+
+type (
+	// Registry holds the current payload version of a type and the
+	// migrations upgrading older payloads to it.
+	Registry struct {
+		sync.RWMutex
+		current    int
+		migrations map[int]migration
+	}
+
+	// Migration upgrades a payload to the next version it was registered
+	// for. Migrations operate on encoded payloads, so that they keep working
+	// after the type has changed.
+	Migration func(data []byte) ([]byte, error)
+
+	// ErrHandler is invoked by [Handler] when a message cannot be decoded.
+	ErrHandler func(jetstream.Msg, error)
+
+	migration struct {
+		to int
+		fn Migration
+	}
+)
+
+// VersionHeader holds the payload version of a message. Messages without
+// it have version 0, so that payloads published before versioning was
+// introduced can be migrated by registering a migration from version 0.
+const VersionHeader = "Nats-Payload-Version"
+
+var (
+	// ErrInvalidMigration is returned when registering a migration which
+	// does not upgrade payloads towards the current version.
+	ErrInvalidMigration = errors.New("invalid migration")
+
+	// ErrUnsupportedVersion is returned for payloads with an invalid version
+	// or a version newer than the current one, e.g. published by an
+	// application already running a newer release.
+	ErrUnsupportedVersion = errors.New("unsupported payload version")
+
+	// ErrNoMigration is returned when no migration is registered to upgrade
+	// a payload from its version.
+	ErrNoMigration = errors.New("no migration registered")
+)
+
+// NewRegistry creates a [Registry] for payloads whose current version is
+// current.
+func NewRegistry(current int) (*Registry, error) {
+	if current < 0 {
+		return nil, fmt.Errorf("%w: version cannot be negative", ErrInvalidMigration)
+	}
+	return &Registry{current: current, migrations: make(map[int]migration)}, nil
+}
+
+// Current returns the current payload version.
+func (r *Registry) Current() int {
+	return r.current
+}
+
+// RegisterMigration registers a migration upgrading payloads of version
+// from to version to. Payloads are upgraded by applying migrations one after
+// another until the current version is reached, so to has to be newer than
+// from and not newer than the current version. A single migration can be
+// registered from each version.
+func (r *Registry) RegisterMigration(from, to int, fn Migration) error {
+	if fn == nil {
+		return fmt.Errorf("%w: migration function cannot be nil", ErrInvalidMigration)
+	}
+	if from < 0 || to <= from || to > r.current {
+		return fmt.Errorf("%w: from version %d to %d with current version %d", ErrInvalidMigration, from, to, r.current)
+	}
+	r.Lock()
+	defer r.Unlock()
+	if m, ok := r.migrations[from]; ok {
+		return fmt.Errorf("%w: migration from version %d to %d already registered", ErrInvalidMigration, from, m.to)
+	}
+	r.migrations[from] = migration{to: to, fn: fn}
+	return nil
+}
+
+// Version returns the payload version of a message with the given headers.
+func Version(header nats.Header) (int, error) {
+	v := header.Get(VersionHeader)
+	if v == "" {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(v)
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("%w: %q", ErrUnsupportedVersion, v)
+	}
+	return version, nil
+}
+
+// Stamp sets the current payload version on the message.
+func (r *Registry) Stamp(msg *nats.Msg) {
+	if msg.Header == nil {
+		msg.Header = nats.Header{}
+	}
+	msg.Header.Set(VersionHeader, strconv.Itoa(r.current))
+}
+
+// Upgrade returns the payload of a message with the given headers upgraded
+// to the current version.
+func (r *Registry) Upgrade(header nats.Header, data []byte) ([]byte, error) {
+	version, err := Version(header)
+	if err != nil {
+		return nil, err
+	}
+	if version > r.current {
+		return nil, fmt.Errorf("%w: %d is newer than current version %d", ErrUnsupportedVersion, version, r.current)
+	}
+	for version < r.current {
+		r.RLock()
+		m, ok := r.migrations[version]
+		r.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("%w: from version %d", ErrNoMigration, version)
+		}
+		if data, err = m.fn(data); err != nil {
+			return nil, fmt.Errorf("migrating from version %d to %d: %w", version, m.to, err)
+		}
+		version = m.to
+	}
+	return data, nil
+}
+
+// Publish encodes v as JSON and publishes it on subject, stamped with the
+// current payload version.
+func Publish[T any](ctx context.Context, js jetstream.Publisher, r *Registry, subject string, v T, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	r.Stamp(msg)
+	return js.PublishMsg(ctx, msg, opts...)
+}
+
+// Decode upgrades the payload of a message to the current version and
+// decodes it from JSON.
+func Decode[T any](r *Registry, msg jetstream.Msg) (T, error) {
+	var v T
+	data, err := r.Upgrade(msg.Headers(), msg.Data())
+	if err != nil {
+		return v, err
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return v, err
+	}
+	return v, nil
+}
+
+// Handler returns a [jetstream.MessageHandler] decoding messages using
+// [Decode] before passing them to h, which is responsible for
+// acknowledging them.
+//
+// Messages which cannot be decoded are passed to errHandler, if set.
+// Messages with a version newer than the current one are negatively
+// acknowledged, so that they can be processed by an instance running a
+// newer release, while other messages are terminated.
+func Handler[T any](r *Registry, h func(jetstream.Msg, T), errHandler ErrHandler) jetstream.MessageHandler {
+	return func(msg jetstream.Msg) {
+		v, err := Decode[T](r, msg)
+		if err == nil {
+			h(msg, v)
+			return
+		}
+		if errHandler != nil {
+			errHandler(msg, err)
+		}
+		if errors.Is(err, ErrUnsupportedVersion) {
+			if version, verr := Version(msg.Headers()); verr == nil && version > r.current {
+				msg.Nak()
+				return
+			}
+		}
+		msg.Term()
+	}
+}
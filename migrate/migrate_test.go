@@ -0,0 +1,124 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migrate
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestRegisterMigration(t *testing.T) {
+	r, err := NewRegistry(3)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	noop := func(data []byte) ([]byte, error) { return data, nil }
+
+	tests := []struct {
+		name     string
+		from, to int
+		fn       Migration
+		withErr  bool
+	}{
+		{name: "from 0 to 1", from: 0, to: 1, fn: noop},
+		{name: "skipping a version", from: 1, to: 3, fn: noop},
+		{name: "already registered", from: 1, to: 2, fn: noop, withErr: true},
+		{name: "downgrade", from: 2, to: 1, fn: noop, withErr: true},
+		{name: "same version", from: 2, to: 2, fn: noop, withErr: true},
+		{name: "past current version", from: 2, to: 4, fn: noop, withErr: true},
+		{name: "negative version", from: -1, to: 1, fn: noop, withErr: true},
+		{name: "nil function", from: 2, to: 3, withErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := r.RegisterMigration(test.from, test.to, test.fn)
+			if test.withErr {
+				if !errors.Is(err, ErrInvalidMigration) {
+					t.Fatalf("Expected error: %v; got: %v", ErrInvalidMigration, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	r, err := NewRegistry(3)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	appendVersion := func(v string) Migration {
+		return func(data []byte) ([]byte, error) {
+			return append(data, v...), nil
+		}
+	}
+	if err := r.RegisterMigration(0, 1, appendVersion("1")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := r.RegisterMigration(1, 3, appendVersion("3")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		version  string
+		expected string
+		withErr  error
+	}{
+		{name: "unversioned", expected: "v13"},
+		{name: "version 1", version: "1", expected: "v3"},
+		{name: "current version", version: "3", expected: "v"},
+		{name: "no migration", version: "2", withErr: ErrNoMigration},
+		{name: "newer version", version: "4", withErr: ErrUnsupportedVersion},
+		{name: "invalid version", version: "v2", withErr: ErrUnsupportedVersion},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			header := nats.Header{}
+			if test.version != "" {
+				header.Set(VersionHeader, test.version)
+			}
+			data, err := r.Upgrade(header, []byte("v"))
+			if test.withErr != nil {
+				if !errors.Is(err, test.withErr) {
+					t.Fatalf("Expected error: %v; got: %v", test.withErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(data) != test.expected {
+				t.Fatalf("Expected payload %q; got: %q", test.expected, data)
+			}
+		})
+	}
+
+	failing := errors.New("failing")
+	r, err = NewRegistry(1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := r.RegisterMigration(0, 1, func([]byte) ([]byte, error) { return nil, failing }); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := r.Upgrade(nil, []byte("v")); !errors.Is(err, failing) {
+		t.Fatalf("Expected error: %v; got: %v", failing, err)
+	}
+}
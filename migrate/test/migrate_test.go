@@ -0,0 +1,146 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/internal/testutil"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/nats-io/nats.go/migrate"
+)
+
+type user struct {
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+func TestMigrateHandler(t *testing.T) {
+	s := testutil.RunBasicJetStreamServer(t)
+	defer testutil.ShutdownJSServerAndRemoveStorage(t, s)
+
+	nc, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	stream, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "USERS", Subjects: []string{"users.>"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// version 0 payloads hold the full name in a single field
+	registry, err := migrate.NewRegistry(1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err = registry.RegisterMigration(0, 1, func(data []byte) ([]byte, error) {
+		var old struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(data, &old); err != nil {
+			return nil, err
+		}
+		first, last, _ := strings.Cut(old.Name, " ")
+		return json.Marshal(user{FirstName: first, LastName: last})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := js.Publish(ctx, "users.1", []byte(`{"name":"Jane Doe"}`)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := migrate.Publish(ctx, js, registry, "users.2", user{FirstName: "John", LastName: "Smith"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	newer := nats.NewMsg("users.3")
+	newer.Header.Set(migrate.VersionHeader, "2")
+	newer.Data = []byte(`{"given_name":"Ann"}`)
+	if _, err := js.PublishMsg(ctx, newer); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := js.Publish(ctx, "users.4", []byte(`{"name":`)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	cons, err := stream.AddConsumer(ctx, jetstream.ConsumerConfig{Durable: "cons", AckPolicy: jetstream.AckExplicitPolicy})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	users := make(chan user, 10)
+	errs := make(chan error, 10)
+	cc, err := cons.Consume(migrate.Handler(registry, func(msg jetstream.Msg, u user) {
+		msg.Ack()
+		users <- u
+	}, func(msg jetstream.Msg, err error) {
+		// the newer message is redelivered until it is processed
+		select {
+		case errs <- err:
+		default:
+		}
+	}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer cc.Stop()
+
+	expected := []user{{"Jane", "Doe"}, {"John", "Smith"}}
+	for _, exp := range expected {
+		select {
+		case u := <-users:
+			if !reflect.DeepEqual(u, exp) {
+				t.Fatalf("Expected user: %+v; got: %+v", exp, u)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Did not receive user")
+		}
+	}
+	for _, exp := range []error{migrate.ErrUnsupportedVersion, nil} {
+		select {
+		case err := <-errs:
+			if exp != nil && !errors.Is(err, exp) {
+				t.Fatalf("Expected error: %v; got: %v", exp, err)
+			}
+			if err == nil {
+				t.Fatalf("Expected error")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Did not receive error")
+		}
+	}
+
+	// the invalid message is terminated, leaving the newer one pending
+	time.Sleep(100 * time.Millisecond)
+	info, err := cons.Info(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info.AckFloor.Stream != 2 {
+		t.Fatalf("Expected ack floor 2; got: %d", info.AckFloor.Stream)
+	}
+}
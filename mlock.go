@@ -0,0 +1,53 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build darwin || linux
+// +build darwin linux
+
+package nats
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// allocLocked returns a zeroed buffer of n bytes in its own page-aligned
+// mapping outside the Go heap, locked so that it is not swapped to disk.
+// As no other data shares its pages, unlocking it does not unlock other
+// buffers. Locking is best effort, e.g. the limit of locked memory may be
+// exceeded. The buffer must be released with freeLocked.
+func allocLocked(n int) ([]byte, error) {
+	if n == 0 {
+		return []byte{}, nil
+	}
+	page := os.Getpagesize()
+	size := (n + page - 1) / page * page
+	buf, err := syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		return nil, fmt.Errorf("nats: unable to allocate locked memory: %w", err)
+	}
+	syscall.Mlock(buf)
+	return buf[:n], nil
+}
+
+// freeLocked wipes, unlocks and unmaps a buffer returned by allocLocked.
+func freeLocked(buf []byte) {
+	if cap(buf) == 0 {
+		return
+	}
+	buf = buf[:cap(buf)]
+	wipeSlice(buf)
+	syscall.Munlock(buf)
+	syscall.Munmap(buf)
+}
@@ -0,0 +1,393 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Default multi cluster failover settings.
+const (
+	DefaultFailoverWindow        = 10 * time.Second
+	DefaultFailoverCheckInterval = 500 * time.Millisecond
+)
+
+var ErrMultiClusterClosed = errors.New("nats: multi cluster connection closed")
+
+// MultiClusterOpt configures a MultiClusterConn.
+type MultiClusterOpt func(*multiClusterOpts) error
+
+// FailoverHandler is invoked after traffic was moved from one cluster to the other.
+type FailoverHandler func(from, to *Conn)
+
+// ReconcileHandler is invoked before failing back to the primary cluster,
+// e.g. to replay JetStream state written to the standby cluster while the
+// primary was unreachable. Returning an error postpones the failback.
+type ReconcileHandler func(primary, standby *Conn) error
+
+type multiClusterOpts struct {
+	window        time.Duration
+	checkInterval time.Duration
+	failoverCB    FailoverHandler
+	failbackCB    FailoverHandler
+	reconcileCB   ReconcileHandler
+}
+
+// MultiClusterConn maintains connections to a primary and a standby (DR)
+// cluster. Publishes, requests and subscriptions go to the primary cluster;
+// when it has been unreachable for the failover window they are moved to
+// the standby cluster, and moved back once the primary has been available
+// for the same window.
+type MultiClusterConn struct {
+	mu       sync.Mutex
+	primary  *Conn
+	standby  *Conn
+	active   *Conn
+	opts     multiClusterOpts
+	subs     map[*MultiClusterSubscription]struct{}
+	changed  time.Time
+	closed   bool
+	done     chan struct{}
+	wg       sync.WaitGroup
+	lastFail error
+}
+
+// MultiClusterSubscription is a subscription which follows the active cluster
+// of a MultiClusterConn.
+type MultiClusterSubscription struct {
+	mc      *MultiClusterConn
+	subject string
+	queue   string
+	cb      MsgHandler
+	sub     *Subscription
+}
+
+// FailoverWindow sets how long the active cluster must be unreachable (or the
+// primary reachable again) before traffic is switched. Defaults to 10s.
+func FailoverWindow(window time.Duration) MultiClusterOpt {
+	return func(o *multiClusterOpts) error {
+		if window <= 0 {
+			return fmt.Errorf("%w: failover window must be positive", ErrInvalidArg)
+		}
+		o.window = window
+		return nil
+	}
+}
+
+// FailoverCheckInterval sets how often the connection states are checked.
+// Defaults to 500ms.
+func FailoverCheckInterval(interval time.Duration) MultiClusterOpt {
+	return func(o *multiClusterOpts) error {
+		if interval <= 0 {
+			return fmt.Errorf("%w: check interval must be positive", ErrInvalidArg)
+		}
+		o.checkInterval = interval
+		return nil
+	}
+}
+
+// FailoverCB sets the handler invoked after failing over to the standby cluster.
+func FailoverCB(cb FailoverHandler) MultiClusterOpt {
+	return func(o *multiClusterOpts) error {
+		o.failoverCB = cb
+		return nil
+	}
+}
+
+// FailbackCB sets the handler invoked after failing back to the primary cluster.
+func FailbackCB(cb FailoverHandler) MultiClusterOpt {
+	return func(o *multiClusterOpts) error {
+		o.failbackCB = cb
+		return nil
+	}
+}
+
+// ReconcileCB sets the handler invoked before failing back to the primary cluster.
+func ReconcileCB(cb ReconcileHandler) MultiClusterOpt {
+	return func(o *multiClusterOpts) error {
+		o.reconcileCB = cb
+		return nil
+	}
+}
+
+// ConnectMultiCluster will connect to both the primary and the standby cluster.
+// Both connections are kept open for the lifetime of the MultiClusterConn,
+// so that the standby is ready to take over traffic.
+func ConnectMultiCluster(primary, standby Options, opts ...MultiClusterOpt) (*MultiClusterConn, error) {
+	o := multiClusterOpts{
+		window:        DefaultFailoverWindow,
+		checkInterval: DefaultFailoverCheckInterval,
+	}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+	pnc, err := primary.Connect()
+	if err != nil {
+		return nil, err
+	}
+	snc, err := standby.Connect()
+	if err != nil {
+		pnc.Close()
+		return nil, err
+	}
+	mc := &MultiClusterConn{
+		primary: pnc,
+		standby: snc,
+		active:  pnc,
+		opts:    o,
+		subs:    make(map[*MultiClusterSubscription]struct{}),
+		done:    make(chan struct{}),
+	}
+	mc.wg.Add(1)
+	go mc.monitor()
+	return mc, nil
+}
+
+// Primary returns the connection to the primary cluster.
+func (mc *MultiClusterConn) Primary() *Conn {
+	return mc.primary
+}
+
+// Standby returns the connection to the standby cluster.
+func (mc *MultiClusterConn) Standby() *Conn {
+	return mc.standby
+}
+
+// Active returns the connection currently used for traffic.
+func (mc *MultiClusterConn) Active() *Conn {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	return mc.active
+}
+
+// IsFailedOver reports whether traffic is currently sent to the standby cluster.
+func (mc *MultiClusterConn) IsFailedOver() bool {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	return mc.active == mc.standby
+}
+
+// LastReconcileError returns the error returned by the reconcile handler on the
+// last failback attempt, if any.
+func (mc *MultiClusterConn) LastReconcileError() error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	return mc.lastFail
+}
+
+func (mc *MultiClusterConn) activeConn() (*Conn, error) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if mc.closed {
+		return nil, ErrMultiClusterClosed
+	}
+	return mc.active, nil
+}
+
+// Publish publishes the data argument to the given subject on the active cluster.
+func (mc *MultiClusterConn) Publish(subj string, data []byte) error {
+	nc, err := mc.activeConn()
+	if err != nil {
+		return err
+	}
+	return nc.Publish(subj, data)
+}
+
+// PublishMsg publishes the Msg structure on the active cluster.
+func (mc *MultiClusterConn) PublishMsg(m *Msg) error {
+	nc, err := mc.activeConn()
+	if err != nil {
+		return err
+	}
+	return nc.PublishMsg(m)
+}
+
+// Request will send a request on the active cluster and wait for a response.
+func (mc *MultiClusterConn) Request(subj string, data []byte, timeout time.Duration) (*Msg, error) {
+	nc, err := mc.activeConn()
+	if err != nil {
+		return nil, err
+	}
+	return nc.Request(subj, data, timeout)
+}
+
+// RequestWithContext will send a request on the active cluster and wait for a response.
+func (mc *MultiClusterConn) RequestWithContext(ctx context.Context, subj string, data []byte) (*Msg, error) {
+	nc, err := mc.activeConn()
+	if err != nil {
+		return nil, err
+	}
+	return nc.RequestWithContext(ctx, subj, data)
+}
+
+// Subscribe will express interest in the given subject on the active cluster.
+// The subscription is moved along with the traffic on failover and failback.
+func (mc *MultiClusterConn) Subscribe(subj string, cb MsgHandler) (*MultiClusterSubscription, error) {
+	return mc.QueueSubscribe(subj, _EMPTY_, cb)
+}
+
+// QueueSubscribe creates a queue subscriber on the active cluster.
+// The subscription is moved along with the traffic on failover and failback.
+func (mc *MultiClusterConn) QueueSubscribe(subj, queue string, cb MsgHandler) (*MultiClusterSubscription, error) {
+	if cb == nil {
+		return nil, ErrBadSubscription
+	}
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if mc.closed {
+		return nil, ErrMultiClusterClosed
+	}
+	s := &MultiClusterSubscription{mc: mc, subject: subj, queue: queue, cb: cb}
+	if err := s.subscribe(mc.active); err != nil {
+		return nil, err
+	}
+	mc.subs[s] = struct{}{}
+	return s, nil
+}
+
+// Subject returns the subject of the subscription.
+func (s *MultiClusterSubscription) Subject() string {
+	return s.subject
+}
+
+// Unsubscribe will remove interest in the subject on the active cluster.
+func (s *MultiClusterSubscription) Unsubscribe() error {
+	s.mc.mu.Lock()
+	defer s.mc.mu.Unlock()
+	if _, ok := s.mc.subs[s]; !ok {
+		return ErrBadSubscription
+	}
+	delete(s.mc.subs, s)
+	return s.sub.Unsubscribe()
+}
+
+// Lock should be held.
+func (s *MultiClusterSubscription) subscribe(nc *Conn) error {
+	var err error
+	if s.queue != _EMPTY_ {
+		s.sub, err = nc.QueueSubscribe(s.subject, s.queue, s.cb)
+	} else {
+		s.sub, err = nc.Subscribe(s.subject, s.cb)
+	}
+	return err
+}
+
+// Close will close both connections.
+func (mc *MultiClusterConn) Close() {
+	mc.mu.Lock()
+	if mc.closed {
+		mc.mu.Unlock()
+		return
+	}
+	mc.closed = true
+	close(mc.done)
+	mc.mu.Unlock()
+
+	mc.wg.Wait()
+	mc.primary.Close()
+	mc.standby.Close()
+}
+
+func (mc *MultiClusterConn) monitor() {
+	defer mc.wg.Done()
+	t := time.NewTicker(mc.opts.checkInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			mc.check()
+		case <-mc.done:
+			return
+		}
+	}
+}
+
+// check switches the active connection when the primary was unreachable
+// (or reachable again) for the whole failover window.
+func (mc *MultiClusterConn) check() {
+	mc.mu.Lock()
+	failedOver := mc.active == mc.standby
+	// Retry moving subscriptions which failed to move on the last switch.
+	mc.moveSubs()
+	mc.mu.Unlock()
+
+	// changed tracks since when the primary is in the state triggering a switch.
+	trigger := mc.primary.IsConnected() == failedOver
+	if !trigger {
+		mc.changed = time.Time{}
+		return
+	}
+	if mc.changed.IsZero() {
+		mc.changed = time.Now()
+	}
+	if time.Since(mc.changed) < mc.opts.window {
+		return
+	}
+	if !failedOver {
+		if !mc.standby.IsConnected() {
+			return
+		}
+		mc.switchTo(mc.standby)
+		if mc.opts.failoverCB != nil {
+			mc.opts.failoverCB(mc.primary, mc.standby)
+		}
+		return
+	}
+	if mc.opts.reconcileCB != nil {
+		err := mc.opts.reconcileCB(mc.primary, mc.standby)
+		mc.mu.Lock()
+		mc.lastFail = err
+		mc.mu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+	mc.switchTo(mc.primary)
+	if mc.opts.failbackCB != nil {
+		mc.opts.failbackCB(mc.standby, mc.primary)
+	}
+}
+
+// switchTo moves the subscriptions to the given connection and makes it active.
+func (mc *MultiClusterConn) switchTo(nc *Conn) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.changed = time.Time{}
+	mc.active = nc
+	mc.moveSubs()
+}
+
+// moveSubs moves the subscriptions which are not bound to the active
+// connection yet. A subscription failing to move keeps receiving from the
+// previous connection until it is moved by a later check.
+// Lock should be held.
+func (mc *MultiClusterConn) moveSubs() {
+	for s := range mc.subs {
+		if s.sub.conn == mc.active {
+			continue
+		}
+		old := s.sub
+		if err := s.subscribe(mc.active); err != nil {
+			s.sub = old
+			continue
+		}
+		old.Unsubscribe()
+	}
+}
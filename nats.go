@@ -15,8 +15,8 @@
 package nats
 
 import (
-	"bufio"
 	"bytes"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/base64"
@@ -42,6 +42,8 @@ import (
 	"github.com/nats-io/nkeys"
 	"github.com/nats-io/nuid"
 
+	"github.com/nats-io/nats.go/retry"
+	"github.com/nats-io/nats.go/subjects"
 	"github.com/nats-io/nats.go/util"
 )
 
@@ -207,6 +209,10 @@ type ConnErrHandler func(*Conn, error)
 // while processing inbound messages.
 type ErrHandler func(*Conn, *Subscription, error)
 
+// LeakedSubsHandler is used to report subscriptions which were still
+// active when the connection was closed.
+type LeakedSubsHandler func(*Conn, []*Subscription)
+
 // UserJWTHandler is used to fetch and return the account signed
 // JWT for this user.
 type UserJWTHandler func() (string, error)
@@ -471,6 +477,11 @@ type Options struct {
 	// it fails to connect (after exhausting the MaxReconnect attempts).
 	RetryOnFailedConnect bool
 
+	// FailFastOnDisconnect makes publishes and requests fail with
+	// ErrDisconnected while the connection is not connected, e.g. during
+	// a reconnect, instead of buffering them until reconnected.
+	FailFastOnDisconnect bool
+
 	// For websocket connections, indicates to the server that the connection
 	// supports compression. If the server does too, then data will be compressed.
 	Compression bool
@@ -488,6 +499,31 @@ type Options struct {
 
 	// SkipHostLookup skips the DNS lookup for the server hostname.
 	SkipHostLookup bool
+
+	// LeakedSubsCB is a debugging aid invoked on Close with the subscriptions
+	// which were not unsubscribed or drained before.
+	LeakedSubsCB LeakedSubsHandler
+
+	// RequestMuxPartitions is the number of wildcard subscriptions responses
+	// to requests are spread over. Defaults to 1.
+	RequestMuxPartitions int
+
+	// Redactor masks data passed to JetStream client traces and to
+	// RequestRecordCB.
+	Redactor Redactor
+
+	// Identity identifies the deployment the client belongs to.
+	// See [ClientIdentity].
+	Identity *ClientIdentity
+
+	// ServerInfoCB sets the callback invoked when the server info changes,
+	// e.g. when servers join the cluster or after reconnecting to an
+	// upgraded server. See [ServerInfoChange].
+	ServerInfoCB ServerInfoHandler
+
+	// RequestRecordCB sets the callback invoked with each request made with
+	// the connection and its response. See [RecordRequests].
+	RequestRecordCB RequestRecordHandler
 }
 
 const (
@@ -556,10 +592,20 @@ type Conn struct {
 	respSubPrefix string               // the wildcard prefix including trailing .
 	respSubLen    int                  // the length of the wildcard prefix excluding trailing .
 	respScanf     string               // The scanf template to extract mux token
-	respMux       *Subscription        // A single response subscription
+	respMux       []*Subscription      // Response subscriptions, one per partition
 	respMap       map[string]chan *Msg // Request map for the response msg channels
 	respRand      *rand.Rand           // Used for generating suffix
 
+	// Number of running Go routines owned by the connection
+	routines int32
+
+	// Set to 1 if requests are recorded, read atomically so that
+	// requests do not take the lock when they are not.
+	recording int32
+
+	// Hooks invoked by ShutdownGracefully before draining
+	shutdownHooks []*shutdownHook
+
 	// Msg filters for testing.
 	// Protected by subsMu
 	filters map[string]msgFilter
@@ -623,6 +669,52 @@ type Subscription struct {
 	pMsgsLimit  int
 	pBytesLimit int
 	dropped     int
+
+	// Subject interning, only accessed from the readLoop.
+	literal  string            // set if Subject contains no wildcards
+	interned map[string]string // received subjects of wildcard subscriptions
+	noIntern bool              // set for wildcard inbox subscriptions
+}
+
+// maxInternedSubjects limits the number of received subjects interned
+// per wildcard subscription.
+const maxInternedSubjects = 1024
+
+// initInterning sets up interning of the subjects received by the
+// subscription. Wildcard subscriptions on inboxes, e.g. the response mux
+// subscription, receive replies whose subjects are unique to a request,
+// so they would only fill the table and are not interned.
+func (s *Subscription) initInterning(inboxPrefix string) {
+	if !subjects.HasWildcard(s.Subject) {
+		s.literal = s.Subject
+		return
+	}
+	s.noIntern = strings.HasPrefix(s.Subject, inboxPrefix)
+}
+
+// internSubject returns the subject of a received message as a string,
+// avoiding an allocation if the same subject was received before. Messages
+// of literal subscriptions are matched against the subscription subject,
+// other subjects are kept in a bounded table.
+// Only called from the readLoop.
+func (s *Subscription) internSubject(subj []byte) string {
+	if s.literal != _EMPTY_ && string(subj) == s.literal {
+		return s.literal
+	}
+	if s.noIntern {
+		return string(subj)
+	}
+	if is, ok := s.interned[string(subj)]; ok {
+		return is
+	}
+	is := string(subj)
+	if len(s.interned) < maxInternedSubjects {
+		if s.interned == nil {
+			s.interned = make(map[string]string)
+		}
+		s.interned[is] = is
+	}
+	return is
 }
 
 // Msg represents a message delivered by NATS. This structure is used
@@ -646,9 +738,13 @@ type Subscription struct {
 type Msg struct {
 	Subject string
 	Reply   string
-	Header  Header
-	Data    []byte
-	Sub     *Subscription
+	// Header of a received message is decoded when the message is received.
+	// Its keys and values share the memory of the received header block, so
+	// retaining any of them keeps the whole block in memory. Use strings.Clone
+	// to retain a value of a large header independently of the message.
+	Header Header
+	Data   []byte
+	Sub    *Subscription
 	// Internal
 	next    *Msg
 	wsz     int
@@ -1060,6 +1156,15 @@ func ReconnectHandler(cb ConnHandler) Option {
 	}
 }
 
+// LeakedSubscriptionsHandler is an Option to set the handler reporting
+// subscriptions still active when the connection is closed.
+func LeakedSubscriptionsHandler(cb LeakedSubsHandler) Option {
+	return func(o *Options) error {
+		o.LeakedSubsCB = cb
+		return nil
+	}
+}
+
 // ClosedHandler is an Option to set the closed handler.
 func ClosedHandler(cb ConnHandler) Option {
 	return func(o *Options) error {
@@ -1265,6 +1370,16 @@ func RetryOnFailedConnect(retry bool) Option {
 	}
 }
 
+// FailFastOnDisconnect is an Option to make publishes and requests fail with
+// ErrDisconnected while reconnecting instead of buffering them.
+// See FailFastContext to select this behavior per call.
+func FailFastOnDisconnect(failFast bool) Option {
+	return func(o *Options) error {
+		o.FailFastOnDisconnect = failFast
+		return nil
+	}
+}
+
 // Compression is an Option to indicate if this connection supports
 // compression. Currently only supported for Websocket connections.
 func Compression(enabled bool) Option {
@@ -1311,6 +1426,19 @@ func SkipHostLookup() Option {
 	}
 }
 
+// RequestMuxPartitions is an Option to spread responses to requests over n
+// subscriptions, each delivering on its own goroutine. This improves request
+// throughput under high request concurrency on many-core machines.
+func RequestMuxPartitions(n int) Option {
+	return func(o *Options) error {
+		if n < 1 {
+			return fmt.Errorf("%w: request mux partitions must be at least 1", ErrInvalidArg)
+		}
+		o.RequestMuxPartitions = n
+		return nil
+	}
+}
+
 // Handler processing
 
 // SetDisconnectHandler will set the disconnect event handler.
@@ -1442,6 +1570,7 @@ func processUrlString(url string) []string {
 // Connect will attempt to connect to a NATS server with multiple options.
 func (o Options) Connect() (*Conn, error) {
 	nc := &Conn{Opts: o}
+	nc.setRecording()
 
 	// Some default options processing.
 	if nc.Opts.MaxPingsOut == 0 {
@@ -1499,10 +1628,10 @@ func (o Options) Connect() (*Conn, error) {
 	}
 
 	// Spin up the async cb dispatcher on success
-	go nc.ach.asyncCBDispatcher()
+	nc.spawn(nc.ach.asyncCBDispatcher)
 
-	if connectionEstablished && nc.Opts.ConnectedCB != nil {
-		nc.ach.push(func() { nc.Opts.ConnectedCB(nc) })
+	if connectedCB := nc.Opts.ConnectedCB; connectionEstablished && connectedCB != nil {
+		nc.ach.push(func() { connectedCB(nc) })
 	}
 
 	return nc, nil
@@ -2258,8 +2387,8 @@ func (nc *Conn) processConnectInit() error {
 
 	// Start the readLoop and flusher go routines, we will wait on both on a reconnect event.
 	nc.wg.Add(2)
-	go nc.readLoop()
-	go nc.flusher()
+	nc.spawn(nc.readLoop)
+	nc.spawn(nc.flusher)
 
 	// Notify the reader that we are done with the connect handshake, where
 	// reads were done synchronously and under the connection lock.
@@ -2323,7 +2452,7 @@ func (nc *Conn) connect() (bool, error) {
 		nc.setup()
 		nc.changeConnStatus(RECONNECTING)
 		nc.bw.switchToPending()
-		go nc.doReconnect(ErrNoServers)
+		nc.spawn(func() { nc.doReconnect(ErrNoServers) })
 		err = nil
 	} else {
 		nc.current = nil
@@ -2514,8 +2643,8 @@ func (nc *Conn) sendConnect() error {
 	// reading byte-by-byte here is ok.
 	proto, err := nc.readProto()
 	if err != nil {
-		if !nc.initc && nc.Opts.AsyncErrorCB != nil {
-			nc.ach.push(func() { nc.Opts.AsyncErrorCB(nc, nil, err) })
+		if errCB := nc.Opts.AsyncErrorCB; !nc.initc && errCB != nil {
+			nc.ach.push(func() { errCB(nc, nil, err) })
 		}
 		return err
 	}
@@ -2525,8 +2654,8 @@ func (nc *Conn) sendConnect() error {
 		// Read the rest now...
 		proto, err = nc.readProto()
 		if err != nil {
-			if !nc.initc && nc.Opts.AsyncErrorCB != nil {
-				nc.ach.push(func() { nc.Opts.AsyncErrorCB(nc, nil, err) })
+			if errCB := nc.Opts.AsyncErrorCB; !nc.initc && errCB != nil {
+				nc.ach.push(func() { errCB(nc, nil, err) })
 			}
 			return err
 		}
@@ -2633,10 +2762,10 @@ func (nc *Conn) doReconnect(err error) {
 	// Perform appropriate callback if needed for a disconnect.
 	// DisconnectedErrCB has priority over deprecated DisconnectedCB
 	if !nc.initc {
-		if nc.Opts.DisconnectedErrCB != nil {
-			nc.ach.push(func() { nc.Opts.DisconnectedErrCB(nc, err) })
-		} else if nc.Opts.DisconnectedCB != nil {
-			nc.ach.push(func() { nc.Opts.DisconnectedCB(nc) })
+		if disconnectedErrCB := nc.Opts.DisconnectedErrCB; disconnectedErrCB != nil {
+			nc.ach.push(func() { disconnectedErrCB(nc, err) })
+		} else if disconnectedCB := nc.Opts.DisconnectedCB; disconnectedCB != nil {
+			nc.ach.push(func() { disconnectedCB(nc) })
 		}
 	}
 
@@ -2649,18 +2778,17 @@ func (nc *Conn) doReconnect(err error) {
 	// Counter that is increased when the whole list of servers has been tried.
 	var wlf int
 
-	var jitter time.Duration
-	var rw time.Duration
+	var backoff retry.Policy
 	// If a custom reconnect delay handler is set, this takes precedence.
 	crd := nc.Opts.CustomReconnectDelayCB
 	if crd == nil {
-		rw = nc.Opts.ReconnectWait
+		backoff.Wait = nc.Opts.ReconnectWait
 		// TODO: since we sleep only after the whole list has been tried, we can't
 		// rely on individual *srv to know if it is a TLS or non-TLS url.
 		// We have to pick which type of jitter to use, for now, we use these hints:
-		jitter = nc.Opts.ReconnectJitter
+		backoff.Jitter = nc.Opts.ReconnectJitter
 		if nc.Opts.Secure || nc.Opts.TLSConfig != nil {
-			jitter = nc.Opts.ReconnectJitterTLS
+			backoff.Jitter = nc.Opts.ReconnectJitterTLS
 		}
 	}
 
@@ -2685,10 +2813,7 @@ func (nc *Conn) doReconnect(err error) {
 				wlf++
 				st = crd(wlf)
 			} else {
-				st = rw
-				if jitter > 0 {
-					st += time.Duration(rand.Int63n(int64(jitter)))
-				}
+				st = backoff.Delay(wlf)
 			}
 			if rt == nil {
 				rt = time.NewTimer(st)
@@ -2775,8 +2900,8 @@ func (nc *Conn) doReconnect(err error) {
 		nc.initc = false
 
 		// Queue up the reconnect callback.
-		if nc.Opts.ReconnectedCB != nil {
-			nc.ach.push(func() { nc.Opts.ReconnectedCB(nc) })
+		if reconnectedCB := nc.Opts.ReconnectedCB; reconnectedCB != nil {
+			nc.ach.push(func() { reconnectedCB(nc) })
 		}
 
 		// Release lock here, we will return below.
@@ -2821,7 +2946,7 @@ func (nc *Conn) processOpErr(err error) {
 		// Clear any queued pongs, e.g. pending flush calls.
 		nc.clearPendingFlushCalls()
 
-		go nc.doReconnect(err)
+		nc.spawn(func() { nc.doReconnect(err) })
 		nc.mu.Unlock()
 		return
 	}
@@ -3079,8 +3204,8 @@ func (nc *Conn) processMsg(data []byte) {
 		return
 	}
 
-	// Copy them into string
-	subj := string(nc.ps.ma.subject)
+	// Copy them into string, reusing the subject of previous messages if possible.
+	subj := sub.internSubject(nc.ps.ma.subject)
 	reply := string(nc.ps.ma.reply)
 
 	// Doing message create outside of the sub's lock to reduce contention.
@@ -3108,8 +3233,8 @@ func (nc *Conn) processMsg(data []byte) {
 			// We will pass the message through but send async error.
 			nc.mu.Lock()
 			nc.err = ErrBadHeaderMsg
-			if nc.Opts.AsyncErrorCB != nil {
-				nc.ach.push(func() { nc.Opts.AsyncErrorCB(nc, sub, ErrBadHeaderMsg) })
+			if errCB := nc.Opts.AsyncErrorCB; errCB != nil {
+				nc.ach.push(func() { errCB(nc, sub, ErrBadHeaderMsg) })
 			}
 			nc.mu.Unlock()
 		}
@@ -3263,8 +3388,8 @@ slowConsumer:
 		// is already experiencing client-side slow consumer situation.
 		nc.mu.Lock()
 		nc.err = ErrSlowConsumer
-		if nc.Opts.AsyncErrorCB != nil {
-			nc.ach.push(func() { nc.Opts.AsyncErrorCB(nc, sub, ErrSlowConsumer) })
+		if errCB := nc.Opts.AsyncErrorCB; errCB != nil {
+			nc.ach.push(func() { errCB(nc, sub, ErrSlowConsumer) })
 		}
 		nc.mu.Unlock()
 	}
@@ -3277,8 +3402,8 @@ func (nc *Conn) processPermissionsViolation(err string) {
 	// create error here so we can pass it as a closure to the async cb dispatcher.
 	e := errors.New("nats: " + err)
 	nc.err = e
-	if nc.Opts.AsyncErrorCB != nil {
-		nc.ach.push(func() { nc.Opts.AsyncErrorCB(nc, nil, e) })
+	if errCB := nc.Opts.AsyncErrorCB; errCB != nil {
+		nc.ach.push(func() { errCB(nc, nil, e) })
 	}
 	nc.mu.Unlock()
 }
@@ -3290,8 +3415,8 @@ func (nc *Conn) processPermissionsViolation(err string) {
 // Connection lock is held on entry
 func (nc *Conn) processAuthError(err error) bool {
 	nc.err = err
-	if !nc.initc && nc.Opts.AsyncErrorCB != nil {
-		nc.ach.push(func() { nc.Opts.AsyncErrorCB(nc, nil, err) })
+	if errCB := nc.Opts.AsyncErrorCB; !nc.initc && errCB != nil {
+		nc.ach.push(func() { errCB(nc, nil, err) })
 	}
 	// We should give up if we tried twice on this server and got the
 	// same error. This behavior can be modified using IgnoreAuthErrorAbort.
@@ -3336,8 +3461,8 @@ func (nc *Conn) flusher() {
 				if nc.err == nil {
 					nc.err = err
 				}
-				if nc.Opts.AsyncErrorCB != nil {
-					nc.ach.push(func() { nc.Opts.AsyncErrorCB(nc, nil, err) })
+				if errCB := nc.Opts.AsyncErrorCB; errCB != nil {
+					nc.ach.push(func() { errCB(nc, nil, err) })
 				}
 			}
 		}
@@ -3385,6 +3510,12 @@ func (nc *Conn) processInfo(info string) error {
 		return err
 	}
 
+	if !nc.initc && nc.Opts.ServerInfoCB != nil {
+		if change, ok := nc.serverInfoChange(&ncInfo); ok {
+			cb := nc.Opts.ServerInfoCB
+			nc.ach.push(func() { cb(nc, change) })
+		}
+	}
 	// Copy content into connection's info structure.
 	nc.info = ncInfo
 	// The array could be empty/not present on initial connect,
@@ -3392,8 +3523,8 @@ func (nc *Conn) processInfo(info string) error {
 	// did not include themselves in the async INFO protocol.
 	// If empty, do not remove the implicit servers from the pool.
 	if len(nc.info.ConnectURLs) == 0 {
-		if !nc.initc && ncInfo.LameDuckMode && nc.Opts.LameDuckModeHandler != nil {
-			nc.ach.push(func() { nc.Opts.LameDuckModeHandler(nc) })
+		if lameDuckModeHandler := nc.Opts.LameDuckModeHandler; !nc.initc && ncInfo.LameDuckMode && lameDuckModeHandler != nil {
+			nc.ach.push(func() { lameDuckModeHandler(nc) })
 		}
 		return nil
 	}
@@ -3451,12 +3582,12 @@ func (nc *Conn) processInfo(info string) error {
 		if !nc.Opts.NoRandomize {
 			nc.shufflePool(1)
 		}
-		if !nc.initc && nc.Opts.DiscoveredServersCB != nil {
-			nc.ach.push(func() { nc.Opts.DiscoveredServersCB(nc) })
+		if discoveredServersCB := nc.Opts.DiscoveredServersCB; !nc.initc && discoveredServersCB != nil {
+			nc.ach.push(func() { discoveredServersCB(nc) })
 		}
 	}
-	if !nc.initc && ncInfo.LameDuckMode && nc.Opts.LameDuckModeHandler != nil {
-		nc.ach.push(func() { nc.Opts.LameDuckModeHandler(nc) })
+	if lameDuckModeHandler := nc.Opts.LameDuckModeHandler; !nc.initc && ncInfo.LameDuckMode && lameDuckModeHandler != nil {
+		nc.ach.push(func() { lameDuckModeHandler(nc) })
 	}
 	return nil
 }
@@ -3618,16 +3749,17 @@ const (
 	statusLen          = 3 // e.g. 20x, 40x, 50x
 )
 
-// DecodeHeadersMsg will decode and headers.
+// DecodeHeadersMsg will decode and headers. The header block is copied once
+// and the keys and values of the returned header share that copy.
 func DecodeHeadersMsg(data []byte) (Header, error) {
-	br := bufio.NewReaderSize(bytes.NewReader(data), 128)
-	tp := textproto.NewReader(br)
-	l, err := tp.ReadLine()
-	if err != nil || len(l) < hdrPreEnd || l[:hdrPreEnd] != hdrLine[:hdrPreEnd] {
+	// Copy the header block into a single string, keys and values
+	// are sliced from it to avoid an allocation for each of them.
+	l, rest, _ := cutHeaderLine(string(data))
+	if len(l) < hdrPreEnd || l[:hdrPreEnd] != hdrLine[:hdrPreEnd] {
 		return nil, ErrBadHeaderMsg
 	}
 
-	mh, err := readMIMEHeader(tp)
+	mh, err := readMIMEHeader(rest)
 	if err != nil {
 		return nil, err
 	}
@@ -3650,19 +3782,24 @@ func DecodeHeadersMsg(data []byte) (Header, error) {
 
 // readMIMEHeader returns a MIMEHeader that preserves the
 // original case of the MIME header, based on the implementation
-// of textproto.ReadMIMEHeader.
+// of textproto.ReadMIMEHeader. The header block has to be
+// terminated by an empty line.
 //
 // https://golang.org/pkg/net/textproto/#Reader.ReadMIMEHeader
-func readMIMEHeader(tp *textproto.Reader) (textproto.MIMEHeader, error) {
+func readMIMEHeader(data string) (textproto.MIMEHeader, error) {
 	m := make(textproto.MIMEHeader)
 	for {
-		kv, err := tp.ReadLine()
+		kv, rest, ok := cutHeaderLine(data)
 		if len(kv) == 0 {
-			return m, err
+			if !ok {
+				return m, io.EOF
+			}
+			return m, nil
 		}
+		data = rest
 
 		// Process key fetching original case.
-		i := bytes.IndexByte([]byte(kv), ':')
+		i := strings.IndexByte(kv, ':')
 		if i < 0 {
 			return nil, ErrBadHeaderMsg
 		}
@@ -3675,12 +3812,22 @@ func readMIMEHeader(tp *textproto.Reader) (textproto.MIMEHeader, error) {
 		for i < len(kv) && (kv[i] == ' ' || kv[i] == '\t') {
 			i++
 		}
-		value := string(kv[i:])
-		m[key] = append(m[key], value)
-		if err != nil {
-			return m, err
-		}
+		m[key] = append(m[key], kv[i:])
+	}
+}
+
+// cutHeaderLine returns the first line of data without the trailing
+// CRLF or LF, the remaining data and whether the line was terminated.
+func cutHeaderLine(data string) (line, rest string, ok bool) {
+	i := strings.IndexByte(data, '\n')
+	if i < 0 {
+		return data, _EMPTY_, false
 	}
+	line = data[:i]
+	if len(line) > 0 && line[len(line)-1] == '\r' {
+		line = line[:len(line)-1]
+	}
+	return line, data[i+1:], true
 }
 
 // PublishMsg publishes the Msg structure, which includes the
@@ -3734,6 +3881,11 @@ func (nc *Conn) publish(subj, reply string, hdr, data []byte) error {
 		return ErrConnectionDraining
 	}
 
+	if nc.Opts.FailFastOnDisconnect && !nc.isConnected() {
+		nc.mu.Unlock()
+		return ErrDisconnected
+	}
+
 	// Proactively reject payloads over the threshold set by server.
 	msgSize := int64(len(data) + len(hdr))
 	// Skip this check if we are not yet connected (RetryOnFailedConnect)
@@ -3871,16 +4023,32 @@ func (nc *Conn) createNewRequestAndSend(subj string, hdr, data []byte) (chan *Ms
 
 	nc.respMap[token] = mch
 	if nc.respMux == nil {
-		// Create the response subscription we will use for all new style responses.
+		// Create the response subscriptions we will use for all new style responses.
 		// This will be on an _INBOX with an additional terminal token. The subscription
-		// will be on a wildcard.
-		s, err := nc.subscribeLocked(nc.respSub, _EMPTY_, nc.respHandler, nil, false, nil)
-		if err != nil {
-			nc.mu.Unlock()
-			return nil, token, err
+		// will be on a wildcard. With multiple partitions, the partition number is
+		// inserted before the terminal token and a subscription is created for each.
+		muxSubjects := []string{nc.respSub}
+		if n := nc.respPartitions(); n > 1 {
+			muxSubjects = make([]string, 0, n)
+			for p := 0; p < n; p++ {
+				muxSubjects = append(muxSubjects, fmt.Sprintf("%s%d.*", nc.respSubPrefix, p))
+			}
+		}
+		muxes := make([]*Subscription, 0, len(muxSubjects))
+		for _, subject := range muxSubjects {
+			s, err := nc.subscribeLocked(subject, _EMPTY_, nc.respHandler, nil, false, nil)
+			if err != nil {
+				delete(nc.respMap, token)
+				nc.mu.Unlock()
+				for _, s := range muxes {
+					s.Unsubscribe()
+				}
+				return nil, token, err
+			}
+			muxes = append(muxes, s)
 		}
 		nc.respScanf = strings.Replace(nc.respSub, "*", "%s", -1)
-		nc.respMux = s
+		nc.respMux = muxes
 	}
 	nc.mu.Unlock()
 
@@ -3936,6 +4104,7 @@ func (nc *Conn) request(subj string, hdr, data []byte, timeout time.Duration) (*
 	if err == nil && len(m.Data) == 0 && m.Header.Get(statusHdr) == noResponders {
 		m, err = nil, ErrNoResponders
 	}
+	nc.recordRequest(subj, hdr, data, m, err)
 	return m, err
 }
 
@@ -4009,6 +4178,14 @@ func NewInbox() string {
 	return string(b[:])
 }
 
+// inboxPrefix returns the prefix of the inboxes created by the connection.
+func (nc *Conn) inboxPrefix() string {
+	if nc.Opts.InboxPrefix == _EMPTY_ {
+		return InboxPrefix
+	}
+	return nc.Opts.InboxPrefix + "."
+}
+
 // Create a new inbox that is prefix aware.
 func (nc *Conn) NewInbox() string {
 	if nc.Opts.InboxPrefix == _EMPTY_ {
@@ -4043,6 +4220,10 @@ func (nc *Conn) newRespInbox() string {
 	sb.WriteString(nc.respSubPrefix)
 
 	rn := nc.respRand.Int63()
+	if n := nc.respPartitions(); n > 1 {
+		sb.WriteString(strconv.FormatInt(rn%int64(n), 10))
+		sb.WriteByte('.')
+	}
 	for i := 0; i < replySuffixLen; i++ {
 		sb.WriteByte(rdigits[rn%base])
 		rn /= base
@@ -4051,6 +4232,25 @@ func (nc *Conn) newRespInbox() string {
 	return sb.String()
 }
 
+// respPartitions returns the number of response mux subscriptions.
+func (nc *Conn) respPartitions() int {
+	if nc.Opts.RequestMuxPartitions > 1 {
+		return nc.Opts.RequestMuxPartitions
+	}
+	return 1
+}
+
+// isRespMux returns true if the subscription is one of the response mux subscriptions.
+// Lock should be held.
+func (nc *Conn) isRespMux(s *Subscription) bool {
+	for _, mux := range nc.respMux {
+		if s == mux {
+			return true
+		}
+	}
+	return false
+}
+
 // NewRespInbox is the new format used for _INBOX.
 func (nc *Conn) NewRespInbox() string {
 	nc.mu.Lock()
@@ -4107,7 +4307,7 @@ func (nc *Conn) SubscribeSync(subj string) (*Subscription, error) {
 	if nc == nil {
 		return nil, ErrInvalidConnection
 	}
-	mch := make(chan *Msg, nc.Opts.SubChanLen)
+	mch := make(chan *Msg, nc.subChanLen())
 	return nc.subscribe(subj, _EMPTY_, nil, mch, true, nil)
 }
 
@@ -4124,7 +4324,7 @@ func (nc *Conn) QueueSubscribe(subj, queue string, cb MsgHandler) (*Subscription
 // group and only one member of the group will be selected to receive any
 // given message synchronously using Subscription.NextMsg().
 func (nc *Conn) QueueSubscribeSync(subj, queue string) (*Subscription, error) {
-	mch := make(chan *Msg, nc.Opts.SubChanLen)
+	mch := make(chan *Msg, nc.subChanLen())
 	return nc.subscribe(subj, queue, nil, mch, true, nil)
 }
 
@@ -4198,6 +4398,7 @@ func (nc *Conn) subscribeLocked(subj, queue string, cb MsgHandler, ch chan *Msg,
 		conn:    nc,
 		jsi:     js,
 	}
+	sub.initInterning(nc.inboxPrefix())
 	// Set pending limits.
 	if ch != nil {
 		sub.pMsgsLimit = cap(ch)
@@ -4229,7 +4430,7 @@ func (nc *Conn) subscribeLocked(subj, queue string, cb MsgHandler, ch chan *Msg,
 
 	// Let's start the go routine now that it is fully setup and registered.
 	if sr {
-		go nc.waitForMsgs(sub)
+		nc.spawn(func() { nc.waitForMsgs(sub) })
 	}
 
 	// We will send these for all subs when we reconnect
@@ -5052,8 +5253,12 @@ func (nc *Conn) close(status Status, doCBs bool, err error) {
 
 	// Close sync subscriber channels and release any
 	// pending NextMsg() calls.
+	var leaked []*Subscription
 	nc.subsMu.Lock()
 	for _, s := range nc.subs {
+		if nc.Opts.LeakedSubsCB != nil && !nc.isRespMux(s) {
+			leaked = append(leaked, s)
+		}
 		s.mu.Lock()
 
 		// Release callers on NextMsg for SyncSubscription only
@@ -5086,8 +5291,11 @@ func (nc *Conn) close(status Status, doCBs bool, err error) {
 				nc.ach.push(func() { disconnectedCB(nc) })
 			}
 		}
-		if nc.Opts.ClosedCB != nil {
-			nc.ach.push(func() { nc.Opts.ClosedCB(nc) })
+		if closedCB := nc.Opts.ClosedCB; closedCB != nil {
+			nc.ach.push(func() { closedCB(nc) })
+		}
+		if leakedSubsCB := nc.Opts.LeakedSubsCB; leakedSubsCB != nil && len(leaked) > 0 {
+			nc.ach.push(func() { leakedSubsCB(nc, leaked) })
 		}
 	}
 	// If this is terminal, then we have to notify the asyncCB handler that
@@ -5133,6 +5341,15 @@ func (nc *Conn) IsConnected() bool {
 	return nc.isConnected()
 }
 
+// IsOperational tests if a Conn is connected and not draining, meaning
+// that publishes and requests are sent to the server right away rather
+// than buffered or rejected.
+func (nc *Conn) IsOperational() bool {
+	nc.mu.RLock()
+	defer nc.mu.RUnlock()
+	return nc.status == CONNECTED
+}
+
 // drainConnection will run in a separate Go routine and will
 // flush all publishes and drain all active subscriptions.
 func (nc *Conn) drainConnection() {
@@ -5153,7 +5370,7 @@ func (nc *Conn) drainConnection() {
 
 	subs := make([]*Subscription, 0, len(nc.subs))
 	for _, s := range nc.subs {
-		if s == nc.respMux {
+		if nc.isRespMux(s) {
 			// Skip since might be in use while messages
 			// are being processed (can miss responses).
 			continue
@@ -5185,12 +5402,7 @@ func (nc *Conn) drainConnection() {
 
 	// Wait for the subscriptions to drop to zero.
 	timeout := time.Now().Add(drainWait)
-	var min int
-	if respMux != nil {
-		min = 1
-	} else {
-		min = 0
-	}
+	min := len(respMux)
 	for time.Now().Before(timeout) {
 		if nc.NumSubscriptions() == min {
 			break
@@ -5201,9 +5413,11 @@ func (nc *Conn) drainConnection() {
 	// In case there was a request/response handler
 	// then need to call drain at the end.
 	if respMux != nil {
-		if err := respMux.Drain(); err != nil {
-			// We will notify about these but continue.
-			pushErr(err)
+		for _, s := range respMux {
+			if err := s.Drain(); err != nil {
+				// We will notify about these but continue.
+				pushErr(err)
+			}
 		}
 		for time.Now().Before(timeout) {
 			if nc.NumSubscriptions() == 0 {
@@ -5262,6 +5476,121 @@ func (nc *Conn) Drain() error {
 	return nil
 }
 
+// Resources describes the resources currently held by a connection.
+type Resources struct {
+	// Subscriptions is the number of active subscriptions,
+	// excluding the internal response subscription.
+	Subscriptions int
+	// PendingRequests is the number of requests waiting for a response.
+	PendingRequests int
+	// Goroutines is the number of running Go routines owned by the connection.
+	Goroutines int
+}
+
+// ActiveResources reports the resources currently held by the connection,
+// e.g. to detect subscriptions which were never unsubscribed.
+func (nc *Conn) ActiveResources() Resources {
+	nc.mu.RLock()
+	defer nc.mu.RUnlock()
+	r := Resources{PendingRequests: len(nc.respMap)}
+
+	nc.subsMu.RLock()
+	for _, s := range nc.subs {
+		if !nc.isRespMux(s) {
+			r.Subscriptions++
+		}
+	}
+	nc.subsMu.RUnlock()
+	r.Goroutines = int(atomic.LoadInt32(&nc.routines))
+	return r
+}
+
+// spawn starts a Go routine owned by the connection.
+func (nc *Conn) spawn(fn func()) {
+	atomic.AddInt32(&nc.routines, 1)
+	go func() {
+		defer atomic.AddInt32(&nc.routines, -1)
+		fn()
+	}()
+}
+
+// ShutdownHook is invoked by ShutdownGracefully before the connection is
+// drained, e.g. to wait for outstanding asynchronous publishes. It should
+// return once done or when the context is canceled.
+type ShutdownHook func(context.Context) error
+
+// shutdownHook is a registered hook, identified by its address
+// so that it can be removed.
+type shutdownHook struct {
+	fn ShutdownHook
+}
+
+// AddShutdownHook registers a hook invoked by ShutdownGracefully.
+// Hooks are invoked in the order they were added. The returned function
+// removes the hook, e.g. once its owner is no longer used, and may be
+// called more than once.
+func (nc *Conn) AddShutdownHook(hook ShutdownHook) (remove func()) {
+	h := &shutdownHook{fn: hook}
+	nc.mu.Lock()
+	nc.shutdownHooks = append(nc.shutdownHooks, h)
+	nc.mu.Unlock()
+	return func() {
+		nc.mu.Lock()
+		defer nc.mu.Unlock()
+		for i, sh := range nc.shutdownHooks {
+			if sh == h {
+				nc.shutdownHooks = append(nc.shutdownHooks[:i], nc.shutdownHooks[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// ShutdownGracefully shuts down the connection in a coordinated way.
+// It first invokes the shutdown hooks, which wait for outstanding asynchronous
+// JetStream publishes to be acknowledged, then drains the subscriptions and
+// finally closes the connection. If the context is done before the connection
+// is closed, the connection is closed immediately and the context error is
+// returned. Otherwise the first error returned by a hook, if any, is returned.
+func (nc *Conn) ShutdownGracefully(ctx context.Context) error {
+	if ctx == nil {
+		return ErrInvalidContext
+	}
+	nc.mu.Lock()
+	if nc.isClosed() {
+		nc.mu.Unlock()
+		return ErrConnectionClosed
+	}
+	hooks := append([]*shutdownHook(nil), nc.shutdownHooks...)
+	nc.mu.Unlock()
+
+	var err error
+	for _, hook := range hooks {
+		if herr := hook.fn(ctx); herr != nil && err == nil {
+			err = herr
+		}
+		if ctx.Err() != nil {
+			nc.Close()
+			return ctx.Err()
+		}
+	}
+
+	if derr := nc.Drain(); derr != nil {
+		return derr
+	}
+	t := time.NewTicker(10 * time.Millisecond)
+	defer t.Stop()
+	for !nc.IsClosed() {
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			nc.Close()
+			return ctx.Err()
+		}
+	}
+	return err
+}
+
 // IsDraining tests if a Conn is in the draining state.
 func (nc *Conn) IsDraining() bool {
 	nc.mu.RLock()
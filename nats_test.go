@@ -1590,6 +1590,86 @@ func TestUserJWTAndSeed(t *testing.T) {
 	nc.Close()
 }
 
+func TestSealedUserCredentials(t *testing.T) {
+	if server.VERSION[0] == '1' {
+		t.Skip()
+	}
+	ts := runTrustServer()
+	defer ts.Shutdown()
+
+	url := fmt.Sprintf("nats://127.0.0.1:%d", TEST_PORT)
+
+	seed := append([]byte(nil), uSeed...)
+	nc, err := Connect(url, SealedUserJWTAndSeed(uJWT, seed))
+	if err != nil {
+		t.Fatalf("Expected to connect, got %v", err)
+	}
+	nc.Close()
+	if bytes.Contains(seed, uSeed) {
+		t.Fatalf("Expected seed to be wiped")
+	}
+
+	chainedFile := createTmpFile(t, []byte(chained))
+	defer os.Remove(chainedFile)
+	nc, err = Connect(url, SealedUserCredentials(chainedFile))
+	if err != nil {
+		t.Fatalf("Expected to connect, got %v", err)
+	}
+	nc.Close()
+
+	// the files are only read once
+	userJWTFile := createTmpFile(t, []byte(uJWT))
+	defer os.Remove(userJWTFile)
+	userSeedFile := createTmpFile(t, uSeed)
+	defer os.Remove(userSeedFile)
+	opts := GetDefaultOptions()
+	opts.Url = url
+	if err := SealedUserCredentials(userJWTFile, userSeedFile)(&opts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	os.Remove(userSeedFile)
+	nc, err = opts.Connect()
+	if err != nil {
+		t.Fatalf("Expected to connect, got %v", err)
+	}
+	nc.Close()
+
+	if _, err := Connect(url, SealedUserJWTAndSeed(uJWT, []byte("invalid"))); err == nil ||
+		!strings.Contains(err.Error(), "unable to extract key pair from seed") {
+		t.Fatalf("Expected error about invalid seed, got %v", err)
+	}
+	if _, err := Connect(url, SealedUserCredentials("missing")); err == nil {
+		t.Fatalf("Expected error about missing creds file")
+	}
+}
+
+func TestSealedSecret(t *testing.T) {
+	secret := []byte("SUAIO3FHUX5PNV2LQIIP7TZ3N4L7TX3W53MQGEIVYFIGA635OZCKEYHFLM")
+	s, err := sealSecret(secret)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if bytes.Contains(s.sealed, secret) {
+		t.Fatalf("Expected secret to be encrypted")
+	}
+	// The plaintext is wiped and its memory released once the function
+	// returns, so it must not be retained.
+	err = s.open(func(plain []byte) error {
+		if !bytes.Equal(plain, secret) {
+			t.Fatalf("Expected %q, got %q", secret, plain)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	s.sealed[len(s.sealed)-1] ^= 1
+	if err := s.open(func([]byte) error { return nil }); err == nil {
+		t.Fatalf("Expected error opening tampered secret")
+	}
+}
+
 func TestExpiredAuthentication(t *testing.T) {
 	// The goal of these tests was to check how a client with an expiring JWT
 	// behaves. It should receive an async -ERR indicating that the auth
@@ -3010,3 +3090,105 @@ func BenchmarkHeaderDecode(b *testing.B) {
 		})
 	}
 }
+
+func TestSubscriptionInternSubject(t *testing.T) {
+	literal := &Subscription{Subject: "foo.bar", literal: "foo.bar"}
+	if subj := literal.internSubject([]byte("foo.bar")); subj != "foo.bar" {
+		t.Fatalf("Unexpected subject: %q", subj)
+	}
+	if allocs := testing.AllocsPerRun(100, func() {
+		literal.internSubject([]byte("foo.bar"))
+	}); allocs != 0 {
+		t.Fatalf("Expected no allocations for literal subject; got: %v", allocs)
+	}
+
+	wc := &Subscription{Subject: "foo.*"}
+	for i := 0; i < maxInternedSubjects+10; i++ {
+		subj := fmt.Sprintf("foo.%d", i)
+		if is := wc.internSubject([]byte(subj)); is != subj {
+			t.Fatalf("Expected subject %q; got: %q", subj, is)
+		}
+	}
+	if len(wc.interned) != maxInternedSubjects {
+		t.Fatalf("Expected %d interned subjects; got: %d", maxInternedSubjects, len(wc.interned))
+	}
+	received := []byte("foo.1")
+	if allocs := testing.AllocsPerRun(100, func() {
+		wc.internSubject(received)
+	}); allocs != 0 {
+		t.Fatalf("Expected no allocations for interned subject; got: %v", allocs)
+	}
+	if is := wc.internSubject([]byte("foo.bar")); is != "foo.bar" {
+		t.Fatalf("Unexpected subject: %q", is)
+	}
+}
+
+func TestSubscriptionInternSubjectRequestReply(t *testing.T) {
+	// Under request/reply load, the response mux subscription receives a
+	// unique subject per request, while a service receives few subjects.
+	nc := &Conn{}
+	nc.initNewResp()
+	mux := &Subscription{Subject: nc.respSub}
+	mux.initInterning(nc.inboxPrefix())
+	svc := &Subscription{Subject: "svc.*"}
+	svc.initInterning(nc.inboxPrefix())
+	custom := &Conn{Opts: Options{InboxPrefix: "_custom"}}
+	inbox := &Subscription{Subject: custom.NewInbox() + ".>"}
+	inbox.initInterning(custom.inboxPrefix())
+
+	services := make([][]byte, 10)
+	for i := range services {
+		services[i] = []byte(fmt.Sprintf("svc.%d", i))
+	}
+	var hits, total int
+	for i := 0; i < 10*maxInternedSubjects; i++ {
+		subj := services[i%len(services)]
+		if _, ok := svc.interned[string(subj)]; ok {
+			hits++
+		}
+		total++
+		svc.internSubject(subj)
+		mux.internSubject([]byte(nc.newRespInbox()))
+		inbox.internSubject([]byte(fmt.Sprintf("%s.%d", inbox.Subject[:len(inbox.Subject)-2], i)))
+	}
+	if len(mux.interned) != 0 || len(inbox.interned) != 0 {
+		t.Fatalf("Expected inbox subjects not to be interned; got: %d and %d", len(mux.interned), len(inbox.interned))
+	}
+	if rate := float64(hits) / float64(total); rate < 0.99 {
+		t.Fatalf("Expected hit rate of service subjects over 99%%; got: %.2f%%", rate*100)
+	}
+	if allocs := testing.AllocsPerRun(100, func() {
+		svc.internSubject(services[1])
+	}); allocs != 0 {
+		t.Fatalf("Expected no allocations for interned subject; got: %v", allocs)
+	}
+}
+
+func BenchmarkInternSubject(b *testing.B) {
+	subjects := make([][]byte, 100)
+	for i := range subjects {
+		subjects[i] = []byte(fmt.Sprintf("orders.region-%d.created", i))
+	}
+	b.Run("literal", func(b *testing.B) {
+		b.ReportAllocs()
+		sub := &Subscription{literal: "orders.region-1.created"}
+		for i := 0; i < b.N; i++ {
+			sub.internSubject(subjects[1])
+		}
+	})
+	b.Run("wildcard", func(b *testing.B) {
+		b.ReportAllocs()
+		sub := &Subscription{}
+		for i := 0; i < b.N; i++ {
+			sub.internSubject(subjects[i%len(subjects)])
+		}
+	})
+	b.Run("copy", func(b *testing.B) {
+		b.ReportAllocs()
+		var s string
+		for i := 0; i < b.N; i++ {
+			s = string(subjects[i%len(subjects)])
+		}
+		_ = s
+	})
+}
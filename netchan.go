@@ -46,13 +46,13 @@ func chPublish(c *EncodedConn, chVal reflect.Value, subject string) {
 			c.Conn.mu.Lock()
 			defer c.Conn.mu.Unlock()
 
-			if c.Conn.Opts.AsyncErrorCB != nil {
+			if errCB := c.Conn.Opts.AsyncErrorCB; errCB != nil {
 				// FIXME(dlc) - Not sure this is the right thing to do.
 				// FIXME(ivan) - If the connection is not yet closed, try to schedule the callback
 				if c.Conn.isClosed() {
-					go c.Conn.Opts.AsyncErrorCB(c.Conn, nil, e)
+					go errCB(c.Conn, nil, e)
 				} else {
-					c.Conn.ach.push(func() { c.Conn.Opts.AsyncErrorCB(c.Conn, nil, e) })
+					c.Conn.ach.push(func() { errCB(c.Conn, nil, e) })
 				}
 			}
 			return
@@ -87,8 +87,8 @@ func (c *EncodedConn) bindRecvChan(subject, queue string, channel interface{}) (
 		}
 		if err := c.Enc.Decode(m.Subject, m.Data, oPtr.Interface()); err != nil {
 			c.Conn.err = errors.New("nats: Got an error trying to unmarshal: " + err.Error())
-			if c.Conn.Opts.AsyncErrorCB != nil {
-				c.Conn.ach.push(func() { c.Conn.Opts.AsyncErrorCB(c.Conn, m.Sub, c.Conn.err) })
+			if errCB := c.Conn.ErrorHandler(); errCB != nil {
+				c.Conn.ach.push(func() { errCB(c.Conn, m.Sub, c.Conn.err) })
 			}
 			return
 		}
@@ -0,0 +1,485 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package notify implements the delivery end of notification pipelines.
+// Messages consumed from a stream are grouped per recipient into digests,
+// collected during a batching window, and passed to a delivery callback
+// while respecting a per recipient rate limit. Delivery times used for
+// rate limiting can be stored in a KV bucket to survive restarts.
+// Messages are only acknowledged once the digest holding them was delivered.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Notice: Experimental Preview
+//
+// This functionality is EXPERIMENTAL and may be changed in later releases.
+
+type (
+	// Notifier exposes methods to operate on a running notifier.
+	Notifier interface {
+		// Stats returns delivery statistics of the notifier.
+		Stats() Stats
+
+		// Stop stops consuming from the source, waits for running
+		// deliveries and naks messages of undelivered digests, so
+		// that they are redelivered.
+		Stop()
+
+		// Stopped informs whether [Stop] was executed on the notifier.
+		Stopped() bool
+	}
+
+	// Config is a configuration of a notifier.
+	Config struct {
+		// Source is the consumer messages are read from. Its AckWait has
+		// to exceed InProgressInterval.
+		Source jetstream.Consumer
+
+		// Recipient returns the recipient of a message. If State is set,
+		// recipients have to be valid KV keys.
+		Recipient func(jetstream.Msg) string
+
+		// Deliver delivers a digest of messages to a recipient. Messages
+		// are acknowledged when it returns nil and retried otherwise. Its
+		// context is canceled after InProgressInterval.
+		Deliver DeliverFunc
+
+		// Window is the time messages of a recipient are collected into a
+		// digest, starting with the first message. Zero delivers every
+		// message on its own, unless rate limited.
+		Window time.Duration
+
+		// MaxDigest is the maximum number of messages in a digest.
+		// A full digest is delivered before its window ends. Defaults to 100.
+		MaxDigest int
+
+		// RateLimit is the maximum number of digests delivered to a recipient
+		// within RatePeriod. Messages arriving while a recipient is rate
+		// limited are collected into the next digest. Zero disables rate limiting.
+		RateLimit int
+
+		// RatePeriod is the period RateLimit applies to.
+		RatePeriod time.Duration
+
+		// State is the bucket delivery times of recipients are stored in.
+		// If not set, rate limits are tracked in memory only.
+		State nats.KeyValue
+
+		// RetryBackoff is the delay before a failed delivery is retried.
+		// Defaults to 1s.
+		RetryBackoff time.Duration
+
+		// InProgressInterval is the interval at which messages of pending
+		// digests are marked in progress, to prevent redelivery. Defaults to 10s.
+		InProgressInterval time.Duration
+
+		// ErrorHandler is invoked when delivering a digest or storing state fails.
+		ErrorHandler func(recipient string, err error)
+	}
+
+	// DeliverFunc delivers a digest of messages to a recipient.
+	DeliverFunc func(ctx context.Context, recipient string, msgs []jetstream.Msg) error
+
+	// Stats contains delivery statistics of a notifier.
+	Stats struct {
+		// Received is the number of messages received from the source.
+		Received uint64 `json:"received"`
+		// Delivered is the number of digests delivered.
+		Delivered uint64 `json:"delivered"`
+		// DeliveredMsgs is the number of messages in delivered digests.
+		DeliveredMsgs uint64 `json:"delivered_msgs"`
+		// Throttled is the number of ready digests held back by the rate limit.
+		Throttled uint64 `json:"throttled"`
+		// Failed is the number of failed deliveries.
+		Failed uint64 `json:"failed"`
+		// Errors is the number of errors handled by the notifier.
+		Errors uint64 `json:"errors"`
+	}
+
+	notifier struct {
+		cfg   Config
+		stats Stats
+		cc    jetstream.ConsumeContext
+		done  chan struct{}
+		wg    sync.WaitGroup
+
+		// stateMu serializes writes to the state bucket, so that the
+		// latest delivery times of a recipient are stored last.
+		stateMu sync.Mutex
+
+		// The lock only protects the fields below and is never held while
+		// communicating with the server or invoking callbacks.
+		sync.Mutex
+		digests    map[string]*digest
+		deliveries map[string][]time.Time
+		stopped    bool
+	}
+
+	digest struct {
+		msgs       []jetstream.Msg
+		started    time.Time
+		progressed time.Time
+		retryAt    time.Time
+		delivering bool
+		throttled  bool
+	}
+
+	// recipientState is stored in the state bucket.
+	recipientState struct {
+		Deliveries []time.Time `json:"deliveries"`
+	}
+)
+
+const (
+	DefaultMaxDigest          = 100
+	DefaultRetryBackoff       = time.Second
+	DefaultInProgressInterval = 10 * time.Second
+)
+
+var (
+	// ErrConfigValidation is returned when notifier configuration is invalid.
+	ErrConfigValidation = errors.New("validation")
+)
+
+// Run starts a notifier consuming from [Config.Source].
+// Messages are processed until [Notifier.Stop] is called.
+func Run(config Config) (Notifier, error) {
+	if err := config.valid(); err != nil {
+		return nil, err
+	}
+	if config.MaxDigest == 0 {
+		config.MaxDigest = DefaultMaxDigest
+	}
+	if config.RetryBackoff == 0 {
+		config.RetryBackoff = DefaultRetryBackoff
+	}
+	if config.InProgressInterval == 0 {
+		config.InProgressInterval = DefaultInProgressInterval
+	}
+
+	n := &notifier{
+		cfg:        config,
+		done:       make(chan struct{}),
+		digests:    make(map[string]*digest),
+		deliveries: make(map[string][]time.Time),
+	}
+	cc, err := config.Source.Consume(n.add)
+	if err != nil {
+		return nil, err
+	}
+	n.cc = cc
+	n.wg.Add(1)
+	go n.loop()
+	return n, nil
+}
+
+func (c Config) valid() error {
+	if c.Source == nil {
+		return fmt.Errorf("%w: source consumer is required", ErrConfigValidation)
+	}
+	if c.Recipient == nil {
+		return fmt.Errorf("%w: recipient function is required", ErrConfigValidation)
+	}
+	if c.Deliver == nil {
+		return fmt.Errorf("%w: deliver function is required", ErrConfigValidation)
+	}
+	if c.Window < 0 {
+		return fmt.Errorf("%w: window cannot be negative", ErrConfigValidation)
+	}
+	if c.MaxDigest < 0 {
+		return fmt.Errorf("%w: max digest cannot be negative", ErrConfigValidation)
+	}
+	if c.RateLimit < 0 {
+		return fmt.Errorf("%w: rate limit cannot be negative", ErrConfigValidation)
+	}
+	if c.RateLimit > 0 && c.RatePeriod <= 0 {
+		return fmt.Errorf("%w: rate period is required with rate limit", ErrConfigValidation)
+	}
+	if c.RetryBackoff < 0 {
+		return fmt.Errorf("%w: retry backoff cannot be negative", ErrConfigValidation)
+	}
+	if c.InProgressInterval < 0 {
+		return fmt.Errorf("%w: in progress interval cannot be negative", ErrConfigValidation)
+	}
+	return nil
+}
+
+func (n *notifier) Stats() Stats {
+	return Stats{
+		Received:      atomic.LoadUint64(&n.stats.Received),
+		Delivered:     atomic.LoadUint64(&n.stats.Delivered),
+		DeliveredMsgs: atomic.LoadUint64(&n.stats.DeliveredMsgs),
+		Throttled:     atomic.LoadUint64(&n.stats.Throttled),
+		Failed:        atomic.LoadUint64(&n.stats.Failed),
+		Errors:        atomic.LoadUint64(&n.stats.Errors),
+	}
+}
+
+func (n *notifier) Stop() {
+	n.Lock()
+	if n.stopped {
+		n.Unlock()
+		return
+	}
+	n.stopped = true
+	n.Unlock()
+
+	n.cc.Stop()
+	close(n.done)
+	n.wg.Wait()
+
+	n.Lock()
+	digests := n.digests
+	n.digests = nil
+	n.Unlock()
+	for recipient, d := range digests {
+		for _, msg := range d.msgs {
+			if err := msg.Nak(); err != nil {
+				n.handleError(recipient, err)
+			}
+		}
+	}
+}
+
+func (n *notifier) Stopped() bool {
+	n.Lock()
+	defer n.Unlock()
+	return n.stopped
+}
+
+// add appends a consumed message to the digest of its recipient.
+func (n *notifier) add(msg jetstream.Msg) {
+	atomic.AddUint64(&n.stats.Received, 1)
+	recipient := n.cfg.Recipient(msg)
+	now := time.Now()
+
+	n.Lock()
+	if n.stopped {
+		n.Unlock()
+		if err := msg.Nak(); err != nil {
+			n.handleError(recipient, err)
+		}
+		return
+	}
+	d, ok := n.digests[recipient]
+	if !ok {
+		d = &digest{started: now, progressed: now}
+		n.digests[recipient] = d
+	}
+	d.msgs = append(d.msgs, msg)
+	n.Unlock()
+}
+
+// loop periodically delivers ready digests and marks pending ones in progress.
+func (n *notifier) loop() {
+	defer n.wg.Done()
+	tick := n.cfg.Window / 10
+	if tick < 10*time.Millisecond {
+		tick = 10 * time.Millisecond
+	}
+	if tick > time.Second {
+		tick = time.Second
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			n.flush(time.Now())
+		case <-n.done:
+			return
+		}
+	}
+}
+
+func (n *notifier) flush(now time.Time) {
+	n.loadStates()
+
+	var inProgress []jetstream.Msg
+	n.Lock()
+	for recipient, d := range n.digests {
+		if d.delivering {
+			continue
+		}
+		if now.Sub(d.progressed) >= n.cfg.InProgressInterval {
+			inProgress = append(inProgress, d.msgs...)
+			d.progressed = now
+		}
+		if len(d.msgs) < n.cfg.MaxDigest && now.Sub(d.started) < n.cfg.Window {
+			continue
+		}
+		if now.Before(d.retryAt) {
+			continue
+		}
+		if !n.allowed(recipient, now) {
+			if !d.throttled {
+				atomic.AddUint64(&n.stats.Throttled, 1)
+				d.throttled = true
+			}
+			continue
+		}
+		d.throttled = false
+		msgs := d.msgs
+		if len(msgs) > n.cfg.MaxDigest {
+			msgs = msgs[:n.cfg.MaxDigest]
+		}
+		d.delivering = true
+		n.wg.Add(1)
+		go n.deliver(recipient, d, msgs)
+	}
+	n.Unlock()
+
+	for _, msg := range inProgress {
+		msg.InProgress()
+	}
+}
+
+// loadStates loads the stored delivery times of recipients with pending
+// digests which were not loaded yet.
+func (n *notifier) loadStates() {
+	if n.cfg.RateLimit == 0 || n.cfg.State == nil {
+		return
+	}
+	var recipients []string
+	n.Lock()
+	for recipient := range n.digests {
+		if _, ok := n.deliveries[recipient]; !ok {
+			recipients = append(recipients, recipient)
+		}
+	}
+	n.Unlock()
+
+	for _, recipient := range recipients {
+		deliveries := n.loadState(recipient)
+		n.Lock()
+		if _, ok := n.deliveries[recipient]; !ok {
+			n.deliveries[recipient] = deliveries
+		}
+		n.Unlock()
+	}
+}
+
+// allowed checks the rate limit of the recipient. The lock has to be held.
+func (n *notifier) allowed(recipient string, now time.Time) bool {
+	if n.cfg.RateLimit == 0 {
+		return true
+	}
+	deliveries := trimDeliveries(n.deliveries[recipient], now.Add(-n.cfg.RatePeriod))
+	n.deliveries[recipient] = deliveries
+	return len(deliveries) < n.cfg.RateLimit
+}
+
+func trimDeliveries(deliveries []time.Time, after time.Time) []time.Time {
+	i := 0
+	for i < len(deliveries) && !deliveries[i].After(after) {
+		i++
+	}
+	return deliveries[i:]
+}
+
+func (n *notifier) loadState(recipient string) []time.Time {
+	entry, err := n.cfg.State.Get(recipient)
+	if errors.Is(err, nats.ErrKeyNotFound) {
+		return nil
+	}
+	if err != nil {
+		n.handleError(recipient, err)
+		return nil
+	}
+	var state recipientState
+	if err := json.Unmarshal(entry.Value(), &state); err != nil {
+		n.handleError(recipient, err)
+		return nil
+	}
+	return state.Deliveries
+}
+
+// deliver invokes the callback with the digest messages and acknowledges
+// them on success, leaving them in the digest for a retry otherwise.
+func (n *notifier) deliver(recipient string, d *digest, msgs []jetstream.Msg) {
+	defer n.wg.Done()
+	ctx, cancel := context.WithTimeout(context.Background(), n.cfg.InProgressInterval)
+	defer cancel()
+	err := n.cfg.Deliver(ctx, recipient, msgs)
+
+	if err != nil {
+		n.Lock()
+		d.delivering = false
+		d.retryAt = time.Now().Add(n.cfg.RetryBackoff)
+		n.Unlock()
+		atomic.AddUint64(&n.stats.Failed, 1)
+		n.handleError(recipient, err)
+		return
+	}
+	atomic.AddUint64(&n.stats.Delivered, 1)
+	atomic.AddUint64(&n.stats.DeliveredMsgs, uint64(len(msgs)))
+
+	n.Lock()
+	d.delivering = false
+	d.msgs = d.msgs[len(msgs):]
+	now := time.Now()
+	if len(d.msgs) == 0 {
+		delete(n.digests, recipient)
+	} else {
+		d.started, d.progressed = now, now
+	}
+	if n.cfg.RateLimit > 0 {
+		n.deliveries[recipient] = append(n.deliveries[recipient], now)
+	}
+	n.Unlock()
+
+	for _, msg := range msgs {
+		if err := msg.Ack(); err != nil {
+			n.handleError(recipient, err)
+		}
+	}
+	if n.cfg.RateLimit > 0 && n.cfg.State != nil {
+		n.storeState(recipient)
+	}
+}
+
+// storeState stores the current delivery times of the recipient.
+func (n *notifier) storeState(recipient string) {
+	n.stateMu.Lock()
+	defer n.stateMu.Unlock()
+	n.Lock()
+	state, err := json.Marshal(recipientState{Deliveries: n.deliveries[recipient]})
+	n.Unlock()
+	if err == nil {
+		_, err = n.cfg.State.Put(recipient, state)
+	}
+	if err != nil {
+		n.handleError(recipient, err)
+	}
+}
+
+// handleError counts the error and invokes the error handler. It must not
+// be called with the lock held.
+func (n *notifier) handleError(recipient string, err error) {
+	atomic.AddUint64(&n.stats.Errors, 1)
+	if n.cfg.ErrorHandler != nil {
+		n.cfg.ErrorHandler(recipient, err)
+	}
+}
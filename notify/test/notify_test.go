@@ -0,0 +1,218 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/internal/testutil"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/nats-io/nats.go/notify"
+)
+
+func TestNotifier(t *testing.T) {
+	srv := testutil.RunBasicJetStreamServer(t)
+	defer testutil.ShutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "NOTIFY", Subjects: []string{"notify.*"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	c, err := s.AddConsumer(ctx, jetstream.ConsumerConfig{Durable: "notifier", AckPolicy: jetstream.AckExplicitPolicy})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	legacy, err := nc.JetStream()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	kv, err := legacy.CreateKeyValue(&nats.KeyValueConfig{Bucket: "NOTIFY_STATE"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := notify.Run(notify.Config{Source: c}); !errors.Is(err, notify.ErrConfigValidation) {
+		t.Fatalf("Expected error: %v; got: %v", notify.ErrConfigValidation, err)
+	}
+
+	type delivery struct {
+		recipient string
+		msgs      []string
+	}
+	var mu sync.Mutex
+	var bobFailed bool
+	var n notify.Notifier
+	deliveries := make(chan delivery, 10)
+	handled := make(chan error, 10)
+	notifier, err := notify.Run(notify.Config{
+		Source: c,
+		Recipient: func(msg jetstream.Msg) string {
+			return strings.TrimPrefix(msg.Subject(), "notify.")
+		},
+		Deliver: func(_ context.Context, recipient string, msgs []jetstream.Msg) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if recipient == "bob" && !bobFailed {
+				bobFailed = true
+				return errors.New("mail server unavailable")
+			}
+			d := delivery{recipient: recipient}
+			for _, msg := range msgs {
+				d.msgs = append(d.msgs, string(msg.Data()))
+			}
+			deliveries <- d
+			return nil
+		},
+		Window:       100 * time.Millisecond,
+		RateLimit:    1,
+		RatePeriod:   time.Second,
+		State:        kv,
+		RetryBackoff: 50 * time.Millisecond,
+		// The error handler can use the notifier, as it is not invoked
+		// while the notifier is locked.
+		ErrorHandler: func(_ string, err error) {
+			mu.Lock()
+			n := n
+			mu.Unlock()
+			if n != nil && !n.Stopped() {
+				handled <- err
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	mu.Lock()
+	n = notifier
+	mu.Unlock()
+	defer n.Stop()
+
+	publish := func(recipient string, msgs ...string) {
+		for _, msg := range msgs {
+			if _, err := js.Publish(ctx, "notify."+recipient, []byte(msg)); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+	}
+	next := func() delivery {
+		t.Helper()
+		select {
+		case d := <-deliveries:
+			return d
+		case <-time.After(3 * time.Second):
+			t.Fatalf("Did not receive delivery")
+		}
+		return delivery{}
+	}
+
+	publish("alice", "a1", "a2", "a3")
+	publish("bob", "b1")
+	got := map[string]string{}
+	for i := 0; i < 2; i++ {
+		d := next()
+		got[d.recipient] = strings.Join(d.msgs, ",")
+	}
+	if got["alice"] != "a1,a2,a3" || got["bob"] != "b1" {
+		t.Fatalf("Unexpected digests: %v", got)
+	}
+	select {
+	case err := <-handled:
+		if err.Error() != "mail server unavailable" {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected delivery error to be handled")
+	}
+
+	// Alice is rate limited, so the next messages are delivered after the rate period.
+	start := time.Now()
+	publish("alice", "a4", "a5")
+	d := next()
+	if d.recipient != "alice" || strings.Join(d.msgs, ",") != "a4,a5" {
+		t.Fatalf("Unexpected digest: %+v", d)
+	}
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+		t.Fatalf("Expected digest to be rate limited; delivered after: %v", elapsed)
+	}
+
+	// The digest is acknowledged and the state stored after the callback returned.
+	checkFor(t, 5*time.Second, 10*time.Millisecond, func() error {
+		stats := n.Stats()
+		if stats.Received != 6 || stats.Delivered != 3 || stats.DeliveredMsgs != 6 || stats.Failed != 1 || stats.Throttled != 1 {
+			return fmt.Errorf("Unexpected stats: %+v", stats)
+		}
+		entry, err := kv.Get("alice")
+		if err != nil {
+			return fmt.Errorf("Expected rate limit state to be stored: %v", err)
+		}
+		var state struct {
+			Deliveries []time.Time `json:"deliveries"`
+		}
+		if err := json.Unmarshal(entry.Value(), &state); err != nil {
+			return err
+		}
+		// The state is stored after each delivery, so it holds the last
+		// delivery once the state after it is stored.
+		if len(state.Deliveries) == 0 || state.Deliveries[len(state.Deliveries)-1].Before(start) {
+			return fmt.Errorf("Expected state of the last delivery; got: %+v", state)
+		}
+		info, err := c.Info(ctx)
+		if err != nil {
+			return err
+		}
+		if info.NumAckPending != 0 || info.AckFloor.Stream != 6 {
+			return fmt.Errorf("Expected all messages to be acknowledged; got: %+v", info)
+		}
+		return nil
+	})
+	n.Stop()
+	if !n.Stopped() {
+		t.Fatalf("Expected notifier to be stopped")
+	}
+}
+
+func checkFor(t *testing.T, totalWait, sleepDur time.Duration, f func() error) {
+	t.Helper()
+	timeout := time.Now().Add(totalWait)
+	var err error
+	for time.Now().Before(timeout) {
+		err = f()
+		if err == nil {
+			return
+		}
+		time.Sleep(sleepDur)
+	}
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
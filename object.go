@@ -25,6 +25,8 @@ import (
 	"io"
 	"net"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -78,6 +80,11 @@ type ObjectStore interface {
 	// GetFile is a convenience function to pull an object from this object store and place it in a file.
 	GetFile(name, file string, opts ...GetObjectOpt) error
 
+	// GetReadSeeker will return a reader with random access to the named object.
+	// Chunks are fetched from the underlying stream on demand, so partial reads
+	// do not require downloading the whole object.
+	GetReadSeeker(name string, opts ...GetObjectOpt) (ObjectReadSeeker, error)
+
 	// GetInfo will retrieve the current information for the object.
 	GetInfo(name string, opts ...GetObjectInfoOpt) (*ObjectInfo, error)
 	// UpdateMeta will update the metadata for the object.
@@ -146,6 +153,8 @@ var (
 	ErrBucketRequired       = errors.New("nats: bucket required")
 	ErrBucketMalformed      = errors.New("nats: bucket malformed")
 	ErrUpdateMetaDeleted    = errors.New("nats: cannot update meta for a deleted object")
+	ErrInvalidObjectOffset  = errors.New("nats: invalid object offset")
+	ErrObjectReaderClosed   = errors.New("nats: object reader closed")
 )
 
 // ObjectStoreConfig is the config for the object store.
@@ -222,6 +231,15 @@ type ObjectResult interface {
 	Error() error
 }
 
+// ObjectReadSeeker provides random access to the contents of an object.
+// It is safe to use with http.ServeContent for partial content requests.
+type ObjectReadSeeker interface {
+	io.ReadSeekCloser
+	io.ReaderAt
+	// Info returns the information of the object being read.
+	Info() *ObjectInfo
+}
+
 const (
 	objNameTmpl         = "OBJ_%s"     // OBJ_<bucket> // stream name
 	objAllChunksPreTmpl = "$O.%s.C.>"  // $O.<bucket>.C.> // chunk stream subject
@@ -873,6 +891,230 @@ func (obs *obs) GetFile(name, file string, opts ...GetObjectOpt) error {
 	return err
 }
 
+// objChunk is the location of a single chunk in the object stream.
+type objChunk struct {
+	seq  uint64
+	off  int64
+	size int64
+}
+
+// ObjectReadSeeker impl.
+type objReadSeeker struct {
+	sync.Mutex
+	obs    *obs
+	info   *ObjectInfo
+	ctx    context.Context
+	chunks []objChunk
+	off    int64
+	closed bool
+
+	// Last fetched chunk, kept around for sequential reads.
+	cur  int
+	data []byte
+}
+
+// GetReadSeeker will return a reader with random access to the named object.
+// The chunk layout is loaded up front using a headers only consumer, chunk
+// data is then retrieved from the stream only when it is read.
+//
+// Since the object is not necessarily read as a whole, its digest is not verified.
+func (obs *obs) GetReadSeeker(name string, opts ...GetObjectOpt) (ObjectReadSeeker, error) {
+	var o getObjectOpts
+	for _, opt := range opts {
+		if opt != nil {
+			if err := opt.configureGetObject(&o); err != nil {
+				return nil, err
+			}
+		}
+	}
+	infoOpts := make([]GetObjectInfoOpt, 0)
+	if o.ctx != nil {
+		infoOpts = append(infoOpts, Context(o.ctx))
+	}
+	if o.showDeleted {
+		infoOpts = append(infoOpts, GetObjectInfoShowDeleted())
+	}
+
+	info, err := obs.GetInfo(name, infoOpts...)
+	if err != nil {
+		return nil, err
+	}
+	if info.NUID == _EMPTY_ {
+		return nil, ErrBadObjectMeta
+	}
+
+	// Check for object links. If single objects we do a pass through.
+	if info.isLink() {
+		if info.ObjectMeta.Opts.Link.Name == _EMPTY_ {
+			return nil, ErrCantGetBucket
+		}
+		lbuck := info.ObjectMeta.Opts.Link.Bucket
+		if lbuck == obs.name {
+			return obs.GetReadSeeker(info.ObjectMeta.Opts.Link.Name, opts...)
+		}
+		lobs, err := obs.js.ObjectStore(lbuck)
+		if err != nil {
+			return nil, err
+		}
+		return lobs.GetReadSeeker(info.ObjectMeta.Opts.Link.Name, opts...)
+	}
+
+	r := &objReadSeeker{obs: obs, info: info, ctx: o.ctx, cur: -1}
+	if info.Size == 0 {
+		return r, nil
+	}
+	if err := r.loadChunks(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// loadChunks builds the index of chunk sequences and offsets.
+// Only headers are delivered, so no chunk data is transferred.
+func (r *objReadSeeker) loadChunks() error {
+	chunkSubj := fmt.Sprintf(objChunksPreTmpl, r.obs.name, r.info.NUID)
+	sub, err := r.obs.js.SubscribeSync(chunkSubj, OrderedConsumer(), HeadersOnly())
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	r.chunks = make([]objChunk, 0, r.info.Chunks)
+	var off int64
+	for i := uint32(0); i < r.info.Chunks; i++ {
+		var m *Msg
+		if r.ctx != nil {
+			m, err = sub.NextMsgWithContext(r.ctx)
+		} else {
+			m, err = sub.NextMsg(r.obs.js.opts.wait)
+		}
+		if err != nil {
+			return err
+		}
+		meta, err := m.Metadata()
+		if err != nil {
+			return err
+		}
+		size, err := strconv.ParseInt(m.Header.Get(MsgSize), 10, 64)
+		if err != nil {
+			return ErrBadObjectMeta
+		}
+		r.chunks = append(r.chunks, objChunk{seq: meta.Sequence.Stream, off: off, size: size})
+		off += size
+	}
+	if uint64(off) != r.info.Size {
+		return ErrBadObjectMeta
+	}
+	return nil
+}
+
+// Read impl.
+func (r *objReadSeeker) Read(p []byte) (int, error) {
+	r.Lock()
+	defer r.Unlock()
+	n, err := r.readAt(p, r.off)
+	r.off += int64(n)
+	return n, err
+}
+
+// ReadAt impl.
+func (r *objReadSeeker) ReadAt(p []byte, off int64) (int, error) {
+	r.Lock()
+	defer r.Unlock()
+	if off < 0 {
+		return 0, ErrInvalidObjectOffset
+	}
+	n, err := r.readAt(p, off)
+	if err == nil && n < len(p) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// Seek impl.
+func (r *objReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	r.Lock()
+	defer r.Unlock()
+	if r.closed {
+		return 0, ErrObjectReaderClosed
+	}
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = r.off + offset
+	case io.SeekEnd:
+		abs = int64(r.info.Size) + offset
+	default:
+		return 0, ErrInvalidObjectOffset
+	}
+	if abs < 0 {
+		return 0, ErrInvalidObjectOffset
+	}
+	r.off = abs
+	return abs, nil
+}
+
+// Close impl.
+func (r *objReadSeeker) Close() error {
+	r.Lock()
+	defer r.Unlock()
+	r.closed = true
+	r.cur, r.data = -1, nil
+	return nil
+}
+
+// Info impl.
+func (r *objReadSeeker) Info() *ObjectInfo {
+	return r.info
+}
+
+// Lock should be held.
+func (r *objReadSeeker) readAt(p []byte, off int64) (int, error) {
+	if r.closed {
+		return 0, ErrObjectReaderClosed
+	}
+	size := int64(r.info.Size)
+	if off >= size {
+		return 0, io.EOF
+	}
+	var n int
+	for n < len(p) && off < size {
+		i := sort.Search(len(r.chunks), func(i int) bool {
+			return r.chunks[i].off+r.chunks[i].size > off
+		})
+		data, err := r.chunkData(i)
+		if err != nil {
+			return n, err
+		}
+		c := copy(p[n:], data[off-r.chunks[i].off:])
+		n += c
+		off += int64(c)
+	}
+	return n, nil
+}
+
+// Lock should be held.
+func (r *objReadSeeker) chunkData(i int) ([]byte, error) {
+	if i == r.cur {
+		return r.data, nil
+	}
+	var opts []JSOpt
+	if r.ctx != nil {
+		opts = append(opts, Context(r.ctx))
+	}
+	m, err := r.obs.js.GetMsg(r.obs.stream, r.chunks[i].seq, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(m.Data)) != r.chunks[i].size {
+		return nil, ErrBadObjectMeta
+	}
+	r.cur, r.data = i, m.Data
+	return r.data, nil
+}
+
 type GetObjectInfoOpt interface {
 	configureGetInfo(opts *getObjectInfoOpts) error
 }
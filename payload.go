@@ -0,0 +1,60 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nats
+
+import "fmt"
+
+// ErrMaxPayloadExceeded is returned by [Conn.CheckMaxPayload] and by
+// asynchronous JetStream publishes of a message larger than the maximum
+// payload advertised by the server. It matches [ErrMaxPayload] when using
+// errors.Is, but not when compared with ==. Other publishes return
+// [ErrMaxPayload] itself.
+type ErrMaxPayloadExceeded struct {
+	// MaxPayload is the maximum payload size advertised by the server.
+	MaxPayload int64
+
+	// Size is the size of the message headers and data.
+	Size int64
+}
+
+func (e *ErrMaxPayloadExceeded) Error() string {
+	return fmt.Sprintf("%s: message size %d exceeds limit of %d bytes", ErrMaxPayload, e.Size, e.MaxPayload)
+}
+
+// Is returns true for [ErrMaxPayload].
+func (e *ErrMaxPayloadExceeded) Is(target error) bool {
+	return target == ErrMaxPayload
+}
+
+// CheckMaxPayload returns [ErrMaxPayloadExceeded] if the size of the
+// message headers and data exceeds the maximum payload advertised by the
+// server. It allows rejecting a message before e.g. registering it for an
+// asynchronous acknowledgement. If not yet connected, no error is returned.
+func (nc *Conn) CheckMaxPayload(m *Msg) error {
+	if m == nil {
+		return ErrInvalidMsg
+	}
+	hdr, err := m.headerBytes()
+	if err != nil {
+		return err
+	}
+	size := int64(len(hdr) + len(m.Data))
+	nc.mu.RLock()
+	defer nc.mu.RUnlock()
+	// Skip this check if we are not yet connected (RetryOnFailedConnect)
+	if !nc.initc && size > nc.info.MaxPayload {
+		return &ErrMaxPayloadExceeded{MaxPayload: nc.info.MaxPayload, Size: size}
+	}
+	return nil
+}
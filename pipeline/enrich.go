@@ -0,0 +1,255 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+type (
+	// EnrichConfig is a configuration of a stage joining records with
+	// reference data stored in a KV bucket.
+	EnrichConfig struct {
+		// Table is the bucket holding the reference data.
+		Table nats.KeyValue
+
+		// Key returns the bucket key to look up for a record.
+		Key func(*Record) string
+
+		// Join merges the looked up entry into the record. On a miss
+		// with [MissPass] policy it is not invoked.
+		Join func(*Record, nats.KeyValueEntry) (*Record, error)
+
+		// OnMiss defines how records without a matching entry are handled.
+		// Defaults to [MissFail].
+		OnMiss MissPolicy
+
+		// MaxStaleness is the time after which a cached entry not
+		// confirmed by the watcher is considered stale. Zero means
+		// entries never become stale.
+		MaxStaleness time.Duration
+
+		// OnStale defines how stale entries are handled. Defaults to [StaleRefresh].
+		OnStale StalePolicy
+	}
+
+	// MissPolicy defines how records without a matching entry are handled.
+	MissPolicy int
+
+	// StalePolicy defines how stale cached entries are handled.
+	StalePolicy int
+
+	// Enricher is a stage joining records with reference data stored in a KV
+	// bucket. Entries are cached locally and kept up to date by a bucket watcher.
+	Enricher struct {
+		cfg     EnrichConfig
+		watcher nats.KeyWatcher
+		ready   chan struct{}
+		stop    chan struct{}
+		done    chan struct{}
+		once    sync.Once
+		cache   map[string]*cachedEntry
+		sync.RWMutex
+	}
+
+	cachedEntry struct {
+		entry   nats.KeyValueEntry
+		updated time.Time
+	}
+)
+
+const (
+	// MissFail fails processing of the record, so that it is retried.
+	MissFail MissPolicy = iota
+	// MissDrop drops the record.
+	MissDrop
+	// MissPass passes on the record without enrichment.
+	MissPass
+)
+
+const (
+	// StaleRefresh reads the entry from the bucket.
+	StaleRefresh StalePolicy = iota
+	// StaleServe uses the stale entry.
+	StaleServe
+	// StaleFail fails processing of the record, so that it is retried.
+	StaleFail
+)
+
+var (
+	// ErrLookupMiss is returned by the enrichment stage when no entry matches
+	// a record and [MissFail] policy is used.
+	ErrLookupMiss = errors.New("no reference entry for key")
+
+	// ErrStaleEntry is returned by the enrichment stage when a stale entry
+	// is looked up and [StaleFail] policy is used.
+	ErrStaleEntry = errors.New("reference entry is stale")
+
+	// ErrEnricherStopped is returned when using a stopped enricher.
+	ErrEnricherStopped = errors.New("enricher stopped")
+)
+
+// NewEnricher starts watching the bucket and returns an enricher.
+// The returned stage waits for the initial values to be loaded before
+// processing the first record.
+func NewEnricher(config EnrichConfig) (*Enricher, error) {
+	if config.Table == nil {
+		return nil, fmt.Errorf("%w: enrichment table is required", ErrConfigValidation)
+	}
+	if config.Key == nil {
+		return nil, fmt.Errorf("%w: enrichment key function is required", ErrConfigValidation)
+	}
+	if config.Join == nil {
+		return nil, fmt.Errorf("%w: join function is required", ErrConfigValidation)
+	}
+	if config.MaxStaleness < 0 {
+		return nil, fmt.Errorf("%w: max staleness cannot be negative", ErrConfigValidation)
+	}
+	watcher, err := config.Table.WatchAll()
+	if err != nil {
+		return nil, err
+	}
+	e := &Enricher{
+		cfg:     config,
+		watcher: watcher,
+		ready:   make(chan struct{}),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+		cache:   make(map[string]*cachedEntry),
+	}
+	go e.watch()
+	return e, nil
+}
+
+// Stage returns the enrichment stage.
+func (e *Enricher) Stage() Stage {
+	return e.enrich
+}
+
+// Stop stops watching the bucket. Records processed afterwards fail with [ErrEnricherStopped].
+func (e *Enricher) Stop() error {
+	e.once.Do(func() { close(e.stop) })
+	<-e.done
+	return e.watcher.Stop()
+}
+
+func (e *Enricher) watch() {
+	defer close(e.done)
+	updates := e.watcher.Updates()
+	for {
+		select {
+		case entry, ok := <-updates:
+			if !ok {
+				return
+			}
+			if entry == nil {
+				select {
+				case <-e.ready:
+				default:
+					close(e.ready)
+				}
+				continue
+			}
+			e.Lock()
+			if entry.Operation() == nats.KeyValuePut {
+				e.cache[entry.Key()] = &cachedEntry{entry: entry, updated: time.Now()}
+			} else {
+				delete(e.cache, entry.Key())
+			}
+			e.Unlock()
+		case <-e.stop:
+			return
+		}
+	}
+}
+
+func (e *Enricher) enrich(ctx context.Context, r *Record) ([]*Record, error) {
+	select {
+	case <-e.ready:
+	case <-e.done:
+		return nil, ErrEnricherStopped
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	select {
+	case <-e.done:
+		return nil, ErrEnricherStopped
+	default:
+	}
+
+	key := e.cfg.Key(r)
+	entry, err := e.lookup(key)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		switch e.cfg.OnMiss {
+		case MissDrop:
+			return nil, nil
+		case MissPass:
+			return []*Record{r}, nil
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrLookupMiss, key)
+		}
+	}
+	out, err := e.cfg.Join(r, entry)
+	if err != nil || out == nil {
+		return nil, err
+	}
+	return []*Record{out}, nil
+}
+
+// lookup returns the cached entry for a key, applying the stale policy.
+func (e *Enricher) lookup(key string) (nats.KeyValueEntry, error) {
+	e.RLock()
+	cached, ok := e.cache[key]
+	e.RUnlock()
+	if !ok {
+		return nil, nil
+	}
+	if e.cfg.MaxStaleness == 0 || time.Since(cached.updated) <= e.cfg.MaxStaleness {
+		return cached.entry, nil
+	}
+
+	switch e.cfg.OnStale {
+	case StaleServe:
+		return cached.entry, nil
+	case StaleFail:
+		return nil, fmt.Errorf("%w: %q", ErrStaleEntry, key)
+	}
+	entry, err := e.cfg.Table.Get(key)
+	if errors.Is(err, nats.ErrKeyNotFound) {
+		e.Lock()
+		if e.cache[key] == cached {
+			delete(e.cache, key)
+		}
+		e.Unlock()
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	e.Lock()
+	if e.cache[key] == cached {
+		e.cache[key] = &cachedEntry{entry: entry, updated: time.Now()}
+	}
+	e.Unlock()
+	return entry, nil
+}
@@ -0,0 +1,355 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pipeline implements stream processing between JetStream streams.
+// A pipeline consumes messages from a source consumer, runs them through
+// a chain of stages and publishes the resulting records to sink streams.
+// Source messages are only acknowledged after all records derived from them
+// were stored, failed messages are retried and eventually dead lettered.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Notice: Experimental Preview
+//
+// This functionality is EXPERIMENTAL and may be changed in later releases.
+
+type (
+	// Pipeline exposes methods to operate on a running pipeline.
+	Pipeline interface {
+		// Stats returns processing statistics of the pipeline.
+		Stats() Stats
+
+		// Stop stops consuming from the source and waits for in flight messages to be processed.
+		Stop()
+
+		// Stopped informs whether [Stop] was executed on the pipeline.
+		Stopped() bool
+	}
+
+	// Config is a configuration of a pipeline.
+	Config struct {
+		// Source is the consumer messages are read from.
+		Source jetstream.Consumer
+
+		// Stages are applied to each source message in order.
+		Stages []Stage
+
+		// Sink is the subject records without a subject are published on.
+		Sink string
+
+		// DeadLetter is the subject messages are published on when they
+		// failed processing more than MaxRetries times. If not set,
+		// such messages are terminated.
+		DeadLetter string
+
+		// MaxRetries is the number of redeliveries of a failed message
+		// before it is dead lettered. Defaults to 3, negative value
+		// retries indefinitely.
+		MaxRetries int
+
+		// RetryBackoff is the delay before a failed message is redelivered.
+		// Defaults to 1s.
+		RetryBackoff time.Duration
+
+		// Concurrency is the number of messages processed in parallel.
+		// Defaults to 1.
+		Concurrency int
+
+		// ProcessTimeout limits the time spent on a single message,
+		// including publishing the records and acknowledging it.
+		// Defaults to 5s.
+		ProcessTimeout time.Duration
+
+		// ErrorHandler is invoked when processing of a message fails.
+		ErrorHandler ErrHandler
+	}
+
+	// ErrHandler is a function used to handle errors occurring while processing a source message.
+	ErrHandler func(jetstream.Msg, error)
+
+	// Stats contains processing statistics of a pipeline.
+	Stats struct {
+		// Received is the number of messages received from the source.
+		Received uint64 `json:"received"`
+		// Processed is the number of messages successfully processed and acknowledged.
+		Processed uint64 `json:"processed"`
+		// Dropped is the number of processed messages which produced no records.
+		Dropped uint64 `json:"dropped"`
+		// Published is the number of records published to sinks.
+		Published uint64 `json:"published"`
+		// Retried is the number of failed messages scheduled for redelivery.
+		Retried uint64 `json:"retried"`
+		// Failed is the number of messages given up after MaxRetries.
+		Failed uint64 `json:"failed"`
+		// DeadLettered is the number of failed messages published to the dead letter subject.
+		DeadLettered uint64 `json:"dead_lettered"`
+		// Errors is the number of errors handled by the pipeline.
+		Errors uint64 `json:"errors"`
+	}
+
+	pipeline struct {
+		js      jetstream.JetStream
+		cfg     Config
+		stats   Stats
+		work    chan jetstream.Msg
+		cc      jetstream.ConsumeContext
+		done    chan struct{}
+		wg      sync.WaitGroup
+		stopped bool
+		sync.Mutex
+	}
+)
+
+const (
+	DefaultMaxRetries     = 3
+	DefaultRetryBackoff   = time.Second
+	DefaultProcessTimeout = 5 * time.Second
+)
+
+// Headers set on dead lettered messages, in addition to
+// [jetstream.StreamHeader], [jetstream.SequenceHeader] and [jetstream.SubjectHeader]
+// identifying the source message.
+const (
+	ErrorHeader     = "Nats-Pipeline-Error"
+	DeliveredHeader = "Nats-Pipeline-Delivered"
+)
+
+var (
+	// ErrConfigValidation is returned when pipeline configuration is invalid.
+	ErrConfigValidation = errors.New("validation")
+
+	// ErrNoSink is returned when a record has no subject and no sink is configured.
+	ErrNoSink = errors.New("record has no sink subject")
+)
+
+// Run starts a pipeline consuming from [Config.Source].
+// Messages are processed until [Pipeline.Stop] is called.
+func Run(js jetstream.JetStream, config Config) (Pipeline, error) {
+	if err := config.valid(); err != nil {
+		return nil, err
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = DefaultMaxRetries
+	}
+	if config.RetryBackoff == 0 {
+		config.RetryBackoff = DefaultRetryBackoff
+	}
+	if config.Concurrency == 0 {
+		config.Concurrency = 1
+	}
+	if config.ProcessTimeout == 0 {
+		config.ProcessTimeout = DefaultProcessTimeout
+	}
+
+	p := &pipeline{
+		js:   js,
+		cfg:  config,
+		work: make(chan jetstream.Msg),
+		done: make(chan struct{}),
+	}
+	for i := 0; i < config.Concurrency; i++ {
+		p.wg.Add(1)
+		go p.worker()
+	}
+	cc, err := config.Source.Consume(func(msg jetstream.Msg) {
+		select {
+		case p.work <- msg:
+		case <-p.done:
+		}
+	})
+	if err != nil {
+		close(p.done)
+		p.wg.Wait()
+		return nil, err
+	}
+	p.cc = cc
+	return p, nil
+}
+
+func (c Config) valid() error {
+	if c.Source == nil {
+		return fmt.Errorf("%w: source consumer is required", ErrConfigValidation)
+	}
+	if c.Concurrency < 0 {
+		return fmt.Errorf("%w: concurrency cannot be negative", ErrConfigValidation)
+	}
+	if c.RetryBackoff < 0 {
+		return fmt.Errorf("%w: retry backoff cannot be negative", ErrConfigValidation)
+	}
+	if c.ProcessTimeout < 0 {
+		return fmt.Errorf("%w: process timeout cannot be negative", ErrConfigValidation)
+	}
+	return nil
+}
+
+func (p *pipeline) Stats() Stats {
+	return Stats{
+		Received:     atomic.LoadUint64(&p.stats.Received),
+		Processed:    atomic.LoadUint64(&p.stats.Processed),
+		Dropped:      atomic.LoadUint64(&p.stats.Dropped),
+		Published:    atomic.LoadUint64(&p.stats.Published),
+		Retried:      atomic.LoadUint64(&p.stats.Retried),
+		Failed:       atomic.LoadUint64(&p.stats.Failed),
+		DeadLettered: atomic.LoadUint64(&p.stats.DeadLettered),
+		Errors:       atomic.LoadUint64(&p.stats.Errors),
+	}
+}
+
+func (p *pipeline) Stop() {
+	p.Lock()
+	if p.stopped {
+		p.Unlock()
+		return
+	}
+	p.stopped = true
+	p.Unlock()
+
+	p.cc.Stop()
+	close(p.done)
+	p.wg.Wait()
+}
+
+func (p *pipeline) Stopped() bool {
+	p.Lock()
+	defer p.Unlock()
+	return p.stopped
+}
+
+func (p *pipeline) worker() {
+	defer p.wg.Done()
+	for {
+		select {
+		case msg := <-p.work:
+			p.process(msg)
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// process runs a source message through the stages, publishes the
+// resulting records and acknowledges the message.
+func (p *pipeline) process(msg jetstream.Msg) {
+	atomic.AddUint64(&p.stats.Received, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), p.cfg.ProcessTimeout)
+	defer cancel()
+
+	meta, err := msg.Metadata()
+	if err != nil {
+		p.handleError(msg, err)
+		return
+	}
+	in := &Record{Header: cloneHeader(msg.Headers()), Data: msg.Data(), Source: msg}
+	out, err := apply(ctx, p.cfg.Stages, []*Record{in})
+	if err == nil {
+		err = p.publish(ctx, meta, out)
+	}
+	if err != nil {
+		p.fail(ctx, msg, meta, err)
+		return
+	}
+	if err := msg.DoubleAck(ctx); err != nil {
+		p.handleError(msg, err)
+		return
+	}
+	if len(out) == 0 {
+		atomic.AddUint64(&p.stats.Dropped, 1)
+	}
+	atomic.AddUint64(&p.stats.Processed, 1)
+}
+
+// publish stores the records in sink streams. Message IDs are derived from
+// the source message, so records of a redelivered message are deduplicated.
+func (p *pipeline) publish(ctx context.Context, meta *jetstream.MsgMetadata, records []*Record) error {
+	for i, r := range records {
+		subject := r.Subject
+		if subject == "" {
+			subject = p.cfg.Sink
+		}
+		if subject == "" {
+			return ErrNoSink
+		}
+		m := &nats.Msg{Subject: subject, Header: r.Header, Data: r.Data}
+		id := fmt.Sprintf("%s.%d.%d", meta.Stream, meta.Sequence.Stream, i)
+		if _, err := p.js.PublishMsg(ctx, m, jetstream.WithMsgID(id)); err != nil {
+			return err
+		}
+		atomic.AddUint64(&p.stats.Published, 1)
+	}
+	return nil
+}
+
+// fail schedules a redelivery of the message or gives up on it once
+// it failed more than MaxRetries times.
+func (p *pipeline) fail(ctx context.Context, msg jetstream.Msg, meta *jetstream.MsgMetadata, err error) {
+	p.handleError(msg, err)
+	if p.cfg.MaxRetries < 0 || meta.NumDelivered <= uint64(p.cfg.MaxRetries) {
+		atomic.AddUint64(&p.stats.Retried, 1)
+		if err := msg.Nak(jetstream.WithNakDelay(p.cfg.RetryBackoff)); err != nil {
+			p.handleError(msg, err)
+		}
+		return
+	}
+	if p.cfg.DeadLetter == "" {
+		if err := msg.Term(); err != nil {
+			p.handleError(msg, err)
+			return
+		}
+		atomic.AddUint64(&p.stats.Failed, 1)
+		return
+	}
+
+	dlq := &nats.Msg{Subject: p.cfg.DeadLetter, Header: cloneHeader(msg.Headers()), Data: msg.Data()}
+	dlq.Header.Set(ErrorHeader, err.Error())
+	dlq.Header.Set(DeliveredHeader, strconv.FormatUint(meta.NumDelivered, 10))
+	dlq.Header.Set(jetstream.StreamHeader, meta.Stream)
+	dlq.Header.Set(jetstream.SequenceHeader, strconv.FormatUint(meta.Sequence.Stream, 10))
+	dlq.Header.Set(jetstream.SubjectHeader, msg.Subject())
+	id := fmt.Sprintf("%s.%d.dlq", meta.Stream, meta.Sequence.Stream)
+	if _, err := msg.AckAfterPublish(ctx, p.js, dlq, jetstream.WithMsgID(id)); err != nil {
+		p.handleError(msg, err)
+		if err := msg.Nak(jetstream.WithNakDelay(p.cfg.RetryBackoff)); err != nil {
+			p.handleError(msg, err)
+		}
+		return
+	}
+	atomic.AddUint64(&p.stats.Failed, 1)
+	atomic.AddUint64(&p.stats.DeadLettered, 1)
+}
+
+func (p *pipeline) handleError(msg jetstream.Msg, err error) {
+	atomic.AddUint64(&p.stats.Errors, 1)
+	if p.cfg.ErrorHandler != nil {
+		p.cfg.ErrorHandler(msg, err)
+	}
+}
+
+func cloneHeader(h nats.Header) nats.Header {
+	c := nats.Header{}
+	for k, v := range h {
+		c[k] = append([]string(nil), v...)
+	}
+	return c
+}
@@ -0,0 +1,112 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pipeline
+
+import (
+	"context"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+type (
+	// Record is a message flowing through the pipeline stages.
+	// Records leaving the last stage are published on their Subject,
+	// or on [Config.Sink] if Subject is empty.
+	Record struct {
+		Subject string
+		Header  nats.Header
+		Data    []byte
+
+		// Source is the message consumed from the source stream
+		// this record was derived from.
+		Source jetstream.Msg
+	}
+
+	// Stage processes a single record and returns the records passed
+	// on to the next stage. Returning no records drops the input record,
+	// returning an error fails processing of the source message.
+	Stage func(context.Context, *Record) ([]*Record, error)
+
+	// Route is a branch of a [Branch] stage.
+	Route struct {
+		// Match reports whether the record should be processed by this route.
+		Match func(*Record) bool
+		// Stages are applied to records matching this route.
+		Stages []Stage
+	}
+)
+
+// Map returns a stage transforming each record using fn.
+// Returning a nil record drops it.
+func Map(fn func(context.Context, *Record) (*Record, error)) Stage {
+	return func(ctx context.Context, r *Record) ([]*Record, error) {
+		out, err := fn(ctx, r)
+		if err != nil || out == nil {
+			return nil, err
+		}
+		return []*Record{out}, nil
+	}
+}
+
+// Filter returns a stage passing on only the records for which fn returns true.
+func Filter(fn func(*Record) bool) Stage {
+	return func(_ context.Context, r *Record) ([]*Record, error) {
+		if !fn(r) {
+			return nil, nil
+		}
+		return []*Record{r}, nil
+	}
+}
+
+// Branch returns a stage applying the stages of the first route matching
+// a record. Records not matching any route are passed on unchanged.
+func Branch(routes ...Route) Stage {
+	return func(ctx context.Context, r *Record) ([]*Record, error) {
+		for _, route := range routes {
+			if route.Match(r) {
+				return apply(ctx, route.Stages, []*Record{r})
+			}
+		}
+		return []*Record{r}, nil
+	}
+}
+
+// To returns a stage setting the subject records are published on.
+// Subject has to be bound to a sink stream.
+func To(subject string) Stage {
+	return func(_ context.Context, r *Record) ([]*Record, error) {
+		r.Subject = subject
+		return []*Record{r}, nil
+	}
+}
+
+// apply runs the records through the stages in order.
+func apply(ctx context.Context, stages []Stage, records []*Record) ([]*Record, error) {
+	for _, stage := range stages {
+		var next []*Record
+		for _, r := range records {
+			out, err := stage(ctx, r)
+			if err != nil {
+				return nil, err
+			}
+			next = append(next, out...)
+		}
+		if len(next) == 0 {
+			return nil, nil
+		}
+		records = next
+	}
+	return records, nil
+}
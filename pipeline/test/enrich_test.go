@@ -0,0 +1,166 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/internal/testutil"
+	"github.com/nats-io/nats.go/pipeline"
+)
+
+func TestEnricher(t *testing.T) {
+	srv := testutil.RunBasicJetStreamServer(t)
+	defer testutil.ShutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	js, err := nc.JetStream()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	kv, err := js.CreateKeyValue(&nats.KeyValueConfig{Bucket: "CUSTOMERS"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := kv.PutString("c1", "Alice"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	newEnricher := func(t *testing.T, miss pipeline.MissPolicy, maxStaleness time.Duration, stale pipeline.StalePolicy) *pipeline.Enricher {
+		t.Helper()
+		e, err := pipeline.NewEnricher(pipeline.EnrichConfig{
+			Table: kv,
+			Key: func(r *pipeline.Record) string {
+				return r.Header.Get("customer")
+			},
+			Join: func(r *pipeline.Record, entry nats.KeyValueEntry) (*pipeline.Record, error) {
+				r.Header.Set("name", string(entry.Value()))
+				return r, nil
+			},
+			OnMiss:       miss,
+			MaxStaleness: maxStaleness,
+			OnStale:      stale,
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return e
+	}
+	record := func(customer string) *pipeline.Record {
+		h := nats.Header{}
+		h.Set("customer", customer)
+		return &pipeline.Record{Header: h}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := pipeline.NewEnricher(pipeline.EnrichConfig{Table: kv}); !errors.Is(err, pipeline.ErrConfigValidation) {
+		t.Fatalf("Expected error: %v; got: %v", pipeline.ErrConfigValidation, err)
+	}
+
+	t.Run("lookup and updates", func(t *testing.T) {
+		e := newEnricher(t, pipeline.MissFail, 0, pipeline.StaleRefresh)
+		out, err := e.Stage()(ctx, record("c1"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(out) != 1 || out[0].Header.Get("name") != "Alice" {
+			t.Fatalf("Unexpected output: %+v", out)
+		}
+
+		if _, err := kv.PutString("c1", "Alicia"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			out, err := e.Stage()(ctx, record("c1"))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if out[0].Header.Get("name") == "Alicia" {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Update was not picked up by the watcher")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		if err := e.Stop(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := e.Stage()(ctx, record("c1")); !errors.Is(err, pipeline.ErrEnricherStopped) {
+			t.Fatalf("Expected error: %v; got: %v", pipeline.ErrEnricherStopped, err)
+		}
+	})
+
+	t.Run("miss policies", func(t *testing.T) {
+		for _, test := range []struct {
+			policy    pipeline.MissPolicy
+			expected  int
+			withError error
+		}{
+			{policy: pipeline.MissFail, withError: pipeline.ErrLookupMiss},
+			{policy: pipeline.MissDrop, expected: 0},
+			{policy: pipeline.MissPass, expected: 1},
+		} {
+			e := newEnricher(t, test.policy, 0, pipeline.StaleRefresh)
+			out, err := e.Stage()(ctx, record("unknown"))
+			e.Stop()
+			if test.withError != nil {
+				if !errors.Is(err, test.withError) {
+					t.Fatalf("Expected error: %v; got: %v", test.withError, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(out) != test.expected {
+				t.Fatalf("Expected %d records; got: %d", test.expected, len(out))
+			}
+		}
+	})
+
+	t.Run("stale policies", func(t *testing.T) {
+		failing := newEnricher(t, pipeline.MissFail, 50*time.Millisecond, pipeline.StaleFail)
+		defer failing.Stop()
+		refreshing := newEnricher(t, pipeline.MissFail, 50*time.Millisecond, pipeline.StaleRefresh)
+		defer refreshing.Stop()
+		for _, e := range []*pipeline.Enricher{failing, refreshing} {
+			if _, err := e.Stage()(ctx, record("c1")); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+
+		if _, err := failing.Stage()(ctx, record("c1")); !errors.Is(err, pipeline.ErrStaleEntry) {
+			t.Fatalf("Expected error: %v; got: %v", pipeline.ErrStaleEntry, err)
+		}
+		out, err := refreshing.Stage()(ctx, record("c1"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if out[0].Header.Get("name") != "Alicia" {
+			t.Fatalf("Unexpected output: %+v", out)
+		}
+	})
+}
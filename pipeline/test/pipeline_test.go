@@ -0,0 +1,144 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/internal/testutil"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/nats-io/nats.go/pipeline"
+)
+
+func TestPipeline(t *testing.T) {
+	srv := testutil.RunBasicJetStreamServer(t)
+	defer testutil.ShutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	source, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "ORDERS", Subjects: []string{"orders.*"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	out, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "OUT", Subjects: []string{"out.>"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	dlq, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "DLQ", Subjects: []string{"dlq"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cons, err := source.AddConsumer(ctx, jetstream.ConsumerConfig{Durable: "pipeline", AckPolicy: jetstream.AckExplicitPolicy})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := pipeline.Run(js, pipeline.Config{}); !errors.Is(err, pipeline.ErrConfigValidation) {
+		t.Fatalf("Expected error: %v; got: %v", pipeline.ErrConfigValidation, err)
+	}
+
+	p, err := pipeline.Run(js, pipeline.Config{
+		Source: cons,
+		Stages: []pipeline.Stage{
+			pipeline.Filter(func(r *pipeline.Record) bool {
+				return string(r.Data) != "skip"
+			}),
+			pipeline.Map(func(_ context.Context, r *pipeline.Record) (*pipeline.Record, error) {
+				if string(r.Data) == "fail" {
+					return nil, errors.New("cannot process")
+				}
+				r.Data = bytes.ToUpper(r.Data)
+				return r, nil
+			}),
+			pipeline.Branch(pipeline.Route{
+				Match: func(r *pipeline.Record) bool {
+					return strings.HasPrefix(string(r.Data), "BIG")
+				},
+				Stages: []pipeline.Stage{pipeline.To("out.big")},
+			}),
+		},
+		Sink:         "out.default",
+		DeadLetter:   "dlq",
+		MaxRetries:   1,
+		RetryBackoff: 10 * time.Millisecond,
+		Concurrency:  2,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer p.Stop()
+
+	for _, data := range []string{"a", "skip", "big1", "fail"} {
+		if _, err := js.Publish(ctx, "orders.new", []byte(data)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for p.Stats().Processed != 3 || p.Stats().DeadLettered != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Pipeline did not process all messages: %+v", p.Stats())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	stats := p.Stats()
+	if stats.Published != 2 || stats.Dropped != 1 || stats.Retried != 1 || stats.Failed != 1 {
+		t.Fatalf("Unexpected stats: %+v", stats)
+	}
+
+	for subject, expected := range map[string]string{"out.default": "A", "out.big": "BIG1"} {
+		msg, err := out.GetLastMsgForSubject(ctx, subject)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if string(msg.Data) != expected {
+			t.Fatalf("Expected %q on %q; got: %q", expected, subject, string(msg.Data))
+		}
+	}
+	msg, err := dlq.GetLastMsgForSubject(ctx, "dlq")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if msg.Header.Get(pipeline.ErrorHeader) != "cannot process" || msg.Header.Get(jetstream.SequenceHeader) != "4" {
+		t.Fatalf("Unexpected dead letter headers: %v", msg.Header)
+	}
+
+	info, err := cons.Info(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info.NumAckPending != 0 || info.AckFloor.Stream != 4 {
+		t.Fatalf("Expected all source messages to be acked: %+v", info)
+	}
+
+	p.Stop()
+	if !p.Stopped() {
+		t.Fatalf("Expected pipeline to be stopped")
+	}
+}
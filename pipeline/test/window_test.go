@@ -0,0 +1,215 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/internal/testutil"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/nats-io/nats.go/pipeline"
+)
+
+func TestWindow(t *testing.T) {
+	srv := testutil.RunBasicJetStreamServer(t)
+	defer testutil.ShutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	legacyJS, err := nc.JetStream()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	kv, err := legacyJS.CreateKeyValue(&nats.KeyValueConfig{Bucket: "WINDOWS"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	base := time.Unix(1700000000, 0)
+	eventTime := func(r *pipeline.Record) time.Time {
+		secs, _ := strconv.Atoi(r.Header.Get("ts"))
+		return base.Add(time.Duration(secs) * time.Second)
+	}
+	event := func(key string, secs int) *pipeline.Record {
+		h := nats.Header{}
+		h.Set("key", key)
+		h.Set("ts", strconv.Itoa(secs))
+		return &pipeline.Record{Header: h}
+	}
+	results := func(t *testing.T, records []*pipeline.Record) []pipeline.WindowResult {
+		t.Helper()
+		var res []pipeline.WindowResult
+		for _, r := range records {
+			var wr pipeline.WindowResult
+			if err := json.Unmarshal(r.Data, &wr); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			res = append(res, wr)
+		}
+		return res
+	}
+
+	if _, err := pipeline.Window(pipeline.WindowConfig{Name: "x", State: kv, Aggregate: pipeline.Count()}); !errors.Is(err, pipeline.ErrConfigValidation) {
+		t.Fatalf("Expected error: %v; got: %v", pipeline.ErrConfigValidation, err)
+	}
+
+	t.Run("tumbling survives restart", func(t *testing.T) {
+		cfg := pipeline.WindowConfig{
+			Name:      "tumbling",
+			State:     kv,
+			Size:      10 * time.Second,
+			Key:       func(r *pipeline.Record) string { return r.Header.Get("key") },
+			Time:      eventTime,
+			Aggregate: pipeline.Count(),
+		}
+		stage, err := pipeline.Window(cfg)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		ctx := context.Background()
+		for _, r := range []*pipeline.Record{event("a", 1), event("a", 5), event("b", 3)} {
+			out, err := stage(ctx, r)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(out) != 0 {
+				t.Fatalf("Expected no results for open windows, got %d", len(out))
+			}
+		}
+		out, err := stage(ctx, event("a", 12))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		res := results(t, out)
+		if len(res) != 1 || res[0].Key != "a" || res[0].Count != 2 || res[0].Value != 2 || !res[0].Start.Equal(base) {
+			t.Fatalf("Unexpected results: %+v", res)
+		}
+
+		// Late record for the closed window is ignored.
+		if out, err := stage(ctx, event("a", 2)); err != nil || len(out) != 0 {
+			t.Fatalf("Expected late record to be ignored, got %v, %v", out, err)
+		}
+
+		restarted, err := pipeline.Window(cfg)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		out, err = restarted(ctx, event("a", 25))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		res = results(t, out)
+		if len(res) != 1 || res[0].Count != 1 || !res[0].Start.Equal(base.Add(10*time.Second)) {
+			t.Fatalf("Unexpected results: %+v", res)
+		}
+	})
+
+	t.Run("sliding sum", func(t *testing.T) {
+		stage, err := pipeline.Window(pipeline.WindowConfig{
+			Name:  "sliding",
+			State: kv,
+			Size:  10 * time.Second,
+			Slide: 5 * time.Second,
+			Time:  eventTime,
+			Aggregate: pipeline.Sum(func(r *pipeline.Record) (float64, error) {
+				return strconv.ParseFloat(string(r.Data), 64)
+			}),
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		r := event("", 7)
+		r.Data = []byte("1.5")
+		if _, err := stage(context.Background(), r); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		r = event("", 16)
+		r.Data = []byte("2")
+		out, err := stage(context.Background(), r)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		res := results(t, out)
+		if len(res) != 2 || res[0].Value != 1.5 || res[1].Value != 1.5 || !res[1].Start.Equal(base.Add(5*time.Second)) {
+			t.Fatalf("Unexpected results: %+v", res)
+		}
+	})
+
+	t.Run("in pipeline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		js, err := jetstream.New(nc)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		source, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "EVENTS", Subjects: []string{"events"}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		sink, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "COUNTS", Subjects: []string{"counts"}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		cons, err := source.AddConsumer(ctx, jetstream.ConsumerConfig{Durable: "counter", AckPolicy: jetstream.AckExplicitPolicy})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		window, err := pipeline.Window(pipeline.WindowConfig{
+			Name:      "events",
+			State:     kv,
+			Size:      10 * time.Second,
+			Time:      eventTime,
+			Aggregate: pipeline.Count(),
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		p, err := pipeline.Run(js, pipeline.Config{Source: cons, Stages: []pipeline.Stage{window}, Sink: "counts"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer p.Stop()
+		for _, secs := range []int{1, 2, 3, 11} {
+			if _, err := js.PublishMsg(ctx, &nats.Msg{Subject: "events", Header: event("", secs).Header}); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for p.Stats().Processed != 4 {
+			if time.Now().After(deadline) {
+				t.Fatalf("Pipeline did not process all messages: %+v", p.Stats())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		msg, err := sink.GetLastMsgForSubject(ctx, "counts")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var res pipeline.WindowResult
+		if err := json.Unmarshal(msg.Data, &res); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if res.Count != 3 {
+			t.Fatalf("Expected count of 3, got: %+v", res)
+		}
+	})
+}
@@ -0,0 +1,251 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+type (
+	// WindowConfig is a configuration of a windowed aggregation stage.
+	WindowConfig struct {
+		// Name identifies the aggregation, it prefixes the keys of the
+		// state stored in the bucket.
+		Name string
+
+		// State is the bucket window state is checkpointed to.
+		State nats.KeyValue
+
+		// Size is the length of a window.
+		Size time.Duration
+
+		// Slide is the interval at which windows start. Defaults to Size,
+		// resulting in tumbling windows; a smaller value results in
+		// overlapping, sliding windows.
+		Slide time.Duration
+
+		// Key groups records into separately aggregated windows. The result
+		// has to be a valid bucket key. If not set, all records are aggregated together.
+		Key func(*Record) string
+
+		// Time returns the event time of a record. Defaults to the time
+		// the source message was stored in the stream.
+		Time func(*Record) time.Time
+
+		// Aggregate folds a record into the window value.
+		Aggregate Aggregator
+
+		// Subject is the subject results are published on. If not set,
+		// results are published on [Config.Sink].
+		Subject string
+	}
+
+	// Aggregator folds a record into the current value of a window.
+	Aggregator func(value float64, r *Record) (float64, error)
+
+	// WindowResult is emitted for each window once it is closed.
+	WindowResult struct {
+		Name  string    `json:"name"`
+		Key   string    `json:"key"`
+		Start time.Time `json:"start"`
+		End   time.Time `json:"end"`
+		Value float64   `json:"value"`
+		Count uint64    `json:"count"`
+	}
+
+	windowState struct {
+		LastSeq   uint64         `json:"last_seq,omitempty"`
+		Watermark time.Time      `json:"watermark"`
+		Windows   []windowValue  `json:"windows,omitempty"`
+		Closed    []WindowResult `json:"closed,omitempty"`
+	}
+
+	windowValue struct {
+		Start time.Time `json:"start"`
+		Value float64   `json:"value"`
+		Count uint64    `json:"count"`
+	}
+)
+
+const defaultWindowKey = "all"
+
+// Count returns an aggregator counting the records in a window.
+func Count() Aggregator {
+	return func(value float64, _ *Record) (float64, error) {
+		return value + 1, nil
+	}
+}
+
+// Sum returns an aggregator summing the values extracted from records.
+func Sum(fn func(*Record) (float64, error)) Aggregator {
+	return func(value float64, r *Record) (float64, error) {
+		v, err := fn(r)
+		if err != nil {
+			return 0, err
+		}
+		return value + v, nil
+	}
+}
+
+// Window returns a stage aggregating records over tumbling or sliding windows.
+// Windows are closed once a record of the same key with an event time past
+// the end of the window is processed; records arriving for closed windows
+// are ignored. For each closed window a record with a JSON encoded
+// [WindowResult] is passed on.
+//
+// The state of open windows is stored in [WindowConfig.State] after each
+// record, so aggregations survive restarts. Records redelivered after their
+// state was stored are not aggregated again, which requires the source
+// messages of a key to be processed in order, i.e. with a concurrency of 1.
+func Window(config WindowConfig) (Stage, error) {
+	if config.Name == "" {
+		return nil, fmt.Errorf("%w: window name is required", ErrConfigValidation)
+	}
+	if config.State == nil {
+		return nil, fmt.Errorf("%w: window state bucket is required", ErrConfigValidation)
+	}
+	if config.Size <= 0 {
+		return nil, fmt.Errorf("%w: window size must be positive", ErrConfigValidation)
+	}
+	if config.Slide == 0 {
+		config.Slide = config.Size
+	}
+	if config.Slide < 0 || config.Slide > config.Size {
+		return nil, fmt.Errorf("%w: window slide must be positive and not exceed size", ErrConfigValidation)
+	}
+	if config.Aggregate == nil {
+		return nil, fmt.Errorf("%w: window aggregator is required", ErrConfigValidation)
+	}
+	return func(_ context.Context, r *Record) ([]*Record, error) {
+		return config.process(r)
+	}, nil
+}
+
+func (c *WindowConfig) process(r *Record) ([]*Record, error) {
+	key := defaultWindowKey
+	if c.Key != nil {
+		key = c.Key(r)
+	}
+	stateKey := fmt.Sprintf("%s.%s", c.Name, key)
+
+	var state windowState
+	var revision uint64
+	entry, err := c.State.Get(stateKey)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(entry.Value(), &state); err != nil {
+			return nil, fmt.Errorf("invalid window state %q: %w", stateKey, err)
+		}
+		revision = entry.Revision()
+	case !errors.Is(err, nats.ErrKeyNotFound):
+		return nil, err
+	}
+
+	var seq uint64
+	eventTime := time.Now()
+	if r.Source != nil {
+		if meta, err := r.Source.Metadata(); err == nil {
+			seq = meta.Sequence.Stream
+			eventTime = meta.Timestamp
+		}
+	}
+	if c.Time != nil {
+		eventTime = c.Time(r)
+	}
+
+	if seq != 0 && seq <= state.LastSeq {
+		// Already aggregated, re-emit the results in case they were not published.
+		if seq == state.LastSeq {
+			return c.results(state.Closed)
+		}
+		return nil, nil
+	}
+
+	if eventTime.After(state.Watermark) {
+		state.Watermark = eventTime
+	}
+	for start := eventTime.Truncate(c.Slide); start.Add(c.Size).After(eventTime); start = start.Add(-c.Slide) {
+		if !start.Add(c.Size).After(state.Watermark) {
+			// Window already closed, the record is late.
+			break
+		}
+		i := sort.Search(len(state.Windows), func(i int) bool {
+			return !state.Windows[i].Start.Before(start)
+		})
+		if i == len(state.Windows) || !state.Windows[i].Start.Equal(start) {
+			state.Windows = append(state.Windows, windowValue{})
+			copy(state.Windows[i+1:], state.Windows[i:])
+			state.Windows[i] = windowValue{Start: start}
+		}
+		value, err := c.Aggregate(state.Windows[i].Value, r)
+		if err != nil {
+			return nil, err
+		}
+		state.Windows[i].Value = value
+		state.Windows[i].Count++
+	}
+
+	state.Closed = nil
+	open := state.Windows[:0]
+	for _, w := range state.Windows {
+		end := w.Start.Add(c.Size)
+		if end.After(state.Watermark) {
+			open = append(open, w)
+			continue
+		}
+		state.Closed = append(state.Closed, WindowResult{
+			Name:  c.Name,
+			Key:   key,
+			Start: w.Start,
+			End:   end,
+			Value: w.Value,
+			Count: w.Count,
+		})
+	}
+	state.Windows = open
+	state.LastSeq = seq
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	if revision == 0 {
+		_, err = c.State.Create(stateKey, data)
+	} else {
+		_, err = c.State.Update(stateKey, data, revision)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c.results(state.Closed)
+}
+
+func (c *WindowConfig) results(closed []WindowResult) ([]*Record, error) {
+	records := make([]*Record, 0, len(closed))
+	for _, res := range closed {
+		data, err := json.Marshal(res)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, &Record{Subject: c.Subject, Header: nats.Header{}, Data: data})
+	}
+	return records, nil
+}
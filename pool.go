@@ -0,0 +1,334 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nats
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Default pool health check settings.
+const (
+	DefaultPoolHealthCheckInterval = 5 * time.Second
+	DefaultPoolMaxRTT              = 2 * time.Second
+	DefaultPoolMaxFailedChecks     = 3
+)
+
+var (
+	ErrPoolClosed           = errors.New("nats: connection pool closed")
+	ErrNoHealthyConnections = errors.New("nats: no healthy connections in pool")
+	ErrInvalidPoolSize      = errors.New("nats: pool size must be at least 1")
+)
+
+// PoolOpt configures a ConnPool.
+type PoolOpt func(*poolOpts) error
+
+// PoolEvictedHandler is used to process connections evicted from the pool.
+// The error describes why the connection was considered unhealthy.
+type PoolEvictedHandler func(*Conn, error)
+
+type poolOpts struct {
+	healthInterval time.Duration
+	maxRTT         time.Duration
+	maxFailed      int
+	warmup         []func(*Conn) error
+	evictedCB      PoolEvictedHandler
+}
+
+// ConnPool maintains a fixed number of connections created from the same
+// Options and hands them out in a round robin fashion. Members are actively
+// health checked; a member failing consecutive checks is evicted and
+// replaced by a new connection, on which the warmup functions are run.
+type ConnPool struct {
+	mu      sync.Mutex
+	opts    Options
+	popts   poolOpts
+	size    int
+	members []*poolMember
+	next    int
+	closed  bool
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+type poolMember struct {
+	nc      *Conn
+	failed  int
+	lastErr error
+}
+
+// PoolHealthCheckInterval sets how often pool members are health checked.
+// Defaults to 5s.
+func PoolHealthCheckInterval(interval time.Duration) PoolOpt {
+	return func(o *poolOpts) error {
+		if interval <= 0 {
+			return fmt.Errorf("%w: health check interval must be positive", ErrInvalidArg)
+		}
+		o.healthInterval = interval
+		return nil
+	}
+}
+
+// PoolMaxRTT sets the round trip time above which a health check fails.
+// Defaults to 2s.
+func PoolMaxRTT(rtt time.Duration) PoolOpt {
+	return func(o *poolOpts) error {
+		if rtt <= 0 {
+			return fmt.Errorf("%w: max RTT must be positive", ErrInvalidArg)
+		}
+		o.maxRTT = rtt
+		return nil
+	}
+}
+
+// PoolMaxFailedChecks sets the number of consecutive failed health checks
+// after which a member is evicted. Defaults to 3.
+func PoolMaxFailedChecks(max int) PoolOpt {
+	return func(o *poolOpts) error {
+		if max < 1 {
+			return fmt.Errorf("%w: max failed checks must be at least 1", ErrInvalidArg)
+		}
+		o.maxFailed = max
+		return nil
+	}
+}
+
+// PoolWarmup registers a function invoked on every new pool member before it
+// is handed out, e.g. to create the subscriptions each member should carry.
+// If it returns an error, the connection is closed and replaced later.
+func PoolWarmup(fn func(*Conn) error) PoolOpt {
+	return func(o *poolOpts) error {
+		o.warmup = append(o.warmup, fn)
+		return nil
+	}
+}
+
+// PoolEvictedCB sets the handler invoked when a member is evicted.
+// The connection is closed after the handler returns.
+func PoolEvictedCB(cb PoolEvictedHandler) PoolOpt {
+	return func(o *poolOpts) error {
+		o.evictedCB = cb
+		return nil
+	}
+}
+
+// ConnectPool will create a pool of size connections using these options.
+func (o Options) ConnectPool(size int, opts ...PoolOpt) (*ConnPool, error) {
+	if size < 1 {
+		return nil, ErrInvalidPoolSize
+	}
+	popts := poolOpts{
+		healthInterval: DefaultPoolHealthCheckInterval,
+		maxRTT:         DefaultPoolMaxRTT,
+		maxFailed:      DefaultPoolMaxFailedChecks,
+	}
+	for _, opt := range opts {
+		if err := opt(&popts); err != nil {
+			return nil, err
+		}
+	}
+	p := &ConnPool{
+		opts:  o,
+		popts: popts,
+		size:  size,
+		done:  make(chan struct{}),
+	}
+	for i := 0; i < size; i++ {
+		nc, err := p.connect()
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		p.members = append(p.members, &poolMember{nc: nc})
+	}
+	p.wg.Add(1)
+	go p.healthLoop()
+	return p, nil
+}
+
+// Get returns the next healthy connection of the pool.
+// Members which failed their last health check are skipped as long as
+// a healthy member is available.
+func (p *ConnPool) Get() (*Conn, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, ErrPoolClosed
+	}
+	var fallback *Conn
+	for i := 0; i < len(p.members); i++ {
+		m := p.members[(p.next+i)%len(p.members)]
+		if !m.nc.IsConnected() {
+			continue
+		}
+		if m.failed > 0 {
+			if fallback == nil {
+				fallback = m.nc
+			}
+			continue
+		}
+		p.next = (p.next + i + 1) % len(p.members)
+		return m.nc, nil
+	}
+	if fallback != nil {
+		return fallback, nil
+	}
+	return nil, ErrNoHealthyConnections
+}
+
+// Size returns the number of current pool members.
+func (p *ConnPool) Size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.members)
+}
+
+// Close will close all the connections of the pool.
+func (p *ConnPool) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.done)
+	members := p.members
+	p.members = nil
+	p.mu.Unlock()
+
+	p.wg.Wait()
+	for _, m := range members {
+		m.nc.Close()
+	}
+}
+
+// connect creates a new member and runs the warmup functions on it.
+func (p *ConnPool) connect() (*Conn, error) {
+	nc, err := p.opts.Connect()
+	if err != nil {
+		return nil, err
+	}
+	for _, fn := range p.popts.warmup {
+		if err := fn(nc); err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+	return nc, nil
+}
+
+func (p *ConnPool) healthLoop() {
+	defer p.wg.Done()
+	t := time.NewTicker(p.popts.healthInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			p.checkHealth()
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// checkHealth pings all members, evicts the ones exceeding the failed checks
+// threshold and refills the pool up to its size.
+func (p *ConnPool) checkHealth() {
+	p.mu.Lock()
+	members := append([]*poolMember(nil), p.members...)
+	p.mu.Unlock()
+
+	var evicted []*poolMember
+	for _, m := range members {
+		err := p.ping(m.nc)
+		p.mu.Lock()
+		if err != nil {
+			m.failed++
+			m.lastErr = err
+		} else {
+			m.failed, m.lastErr = 0, nil
+		}
+		if m.failed >= p.popts.maxFailed || m.nc.IsClosed() {
+			p.removeMember(m)
+			evicted = append(evicted, m)
+		}
+		p.mu.Unlock()
+	}
+	for _, m := range evicted {
+		if p.popts.evictedCB != nil {
+			p.popts.evictedCB(m.nc, m.lastErr)
+		}
+		m.nc.Close()
+	}
+
+	for {
+		p.mu.Lock()
+		missing := !p.closed && len(p.members) < p.size
+		p.mu.Unlock()
+		if !missing {
+			return
+		}
+		nc, err := p.connect()
+		if err != nil {
+			// Try again on the next health check.
+			return
+		}
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			nc.Close()
+			return
+		}
+		p.members = append(p.members, &poolMember{nc: nc})
+		p.mu.Unlock()
+	}
+}
+
+// ping checks that a round trip to the server completes within max RTT.
+func (p *ConnPool) ping(nc *Conn) error {
+	if nc.IsClosed() {
+		return ErrConnectionClosed
+	}
+	if !nc.IsConnected() {
+		return ErrDisconnected
+	}
+	start := time.Now()
+	if err := nc.FlushTimeout(p.popts.maxRTT); err != nil {
+		return err
+	}
+	if rtt := time.Since(start); rtt > p.popts.maxRTT {
+		return fmt.Errorf("nats: rtt %v exceeds %v", rtt, p.popts.maxRTT)
+	}
+	return nil
+}
+
+// Lock should be held.
+func (p *ConnPool) removeMember(m *poolMember) {
+	for i, pm := range p.members {
+		if pm == m {
+			p.members = append(p.members[:i], p.members[i+1:]...)
+			if p.next > i {
+				p.next--
+			}
+			if len(p.members) > 0 {
+				p.next %= len(p.members)
+			} else {
+				p.next = 0
+			}
+			return
+		}
+	}
+}
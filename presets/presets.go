@@ -0,0 +1,132 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package presets provides JetStream consumer configurations for common use
+// cases. Each preset sets all fields relevant to its use case to values
+// known to work together, so that only the name and the filter subjects
+// have to be set:
+//
+//	cfg := presets.WorkQueueWorker(30*time.Second, 5)
+//	cfg.Durable = "workers"
+//	cons, err := stream.AddConsumer(ctx, cfg)
+package presets
+
+import (
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Notice: Experimental Preview
+//
+// This functionality is EXPERIMENTAL and may be changed in later releases.
+
+const (
+	// DefaultAckWait is the ack wait of [WorkQueueWorker] if none is given.
+	DefaultAckWait = 30 * time.Second
+
+	// DefaultMaxAckPending is the number of unacknowledged messages workers
+	// of a [WorkQueueWorker] consumer can process concurrently.
+	DefaultMaxAckPending = 1000
+
+	// DefaultMaxWaiting is the number of pull requests which can wait for
+	// messages at the same time.
+	DefaultMaxWaiting = 512
+
+	// FanoutInactiveThreshold is the time after which [LowLatencyFanout]
+	// consumers are removed once their subscriber is gone.
+	FanoutInactiveThreshold = time.Minute
+
+	// BatchSize is the maximum number of messages of a single [BatchETL]
+	// pull request.
+	BatchSize = 1000
+
+	// BatchAckWait is the time a [BatchETL] batch can take to be processed
+	// before it is redelivered.
+	BatchAckWait = 5 * time.Minute
+
+	// BatchMaxDeliver is the number of times a [BatchETL] batch is
+	// delivered before it is given up.
+	BatchMaxDeliver = 5
+)
+
+// WorkQueueWorker returns the configuration of a pull consumer shared by
+// workers, each message being processed by one of them. Messages have to be
+// acknowledged explicitly and are redelivered if they are not acknowledged
+// within ackWait, up to maxDeliver times in total. If ackWait is not
+// positive, [DefaultAckWait] is used. If maxDeliver is not positive,
+// messages are redelivered until they are acknowledged.
+//
+// Set Durable, so that the consumer outlives the workers, and FilterSubjects
+// if needed. The consumer can be used with streams using any retention
+// policy, including [jetstream.WorkQueuePolicy].
+func WorkQueueWorker(ackWait time.Duration, maxDeliver int) jetstream.ConsumerConfig {
+	if ackWait <= 0 {
+		ackWait = DefaultAckWait
+	}
+	if maxDeliver <= 0 {
+		maxDeliver = -1
+	}
+	return jetstream.ConsumerConfig{
+		DeliverPolicy: jetstream.DeliverAllPolicy,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       ackWait,
+		MaxDeliver:    maxDeliver,
+		ReplayPolicy:  jetstream.ReplayInstantPolicy,
+		MaxAckPending: DefaultMaxAckPending,
+		MaxWaiting:    DefaultMaxWaiting,
+	}
+}
+
+// LowLatencyFanout returns the configuration of a pull consumer created by
+// each subscriber receiving all new messages, e.g. for live updates. Messages
+// are not acknowledged and are never redelivered. The consumer state is kept
+// in memory without replicas, and the consumer is removed once it was not
+// used for [FanoutInactiveThreshold].
+//
+// Leave Durable unset, so that each subscriber creates its own consumer.
+// The consumer cannot be used with streams using [jetstream.WorkQueuePolicy]
+// or [jetstream.InterestPolicy] retention, which require acknowledgements.
+func LowLatencyFanout() jetstream.ConsumerConfig {
+	return jetstream.ConsumerConfig{
+		DeliverPolicy:     jetstream.DeliverNewPolicy,
+		AckPolicy:         jetstream.AckNonePolicy,
+		ReplayPolicy:      jetstream.ReplayInstantPolicy,
+		MaxWaiting:        DefaultMaxWaiting,
+		InactiveThreshold: FanoutInactiveThreshold,
+		Replicas:          1,
+		MemoryStorage:     true,
+	}
+}
+
+// BatchETL returns the configuration of a pull consumer read by a single
+// process in large batches, e.g. to load messages into another system.
+// Acknowledging the last message of a batch acknowledges the whole batch.
+// Batches not acknowledged within [BatchAckWait] are redelivered, up to
+// [BatchMaxDeliver] times in total.
+//
+// Set Durable, so that processing resumes after the last acknowledged batch.
+// The consumer must not be shared by multiple processes, as acknowledging
+// a batch would also acknowledge messages delivered to the others.
+func BatchETL() jetstream.ConsumerConfig {
+	return jetstream.ConsumerConfig{
+		DeliverPolicy:   jetstream.DeliverAllPolicy,
+		AckPolicy:       jetstream.AckAllPolicy,
+		AckWait:         BatchAckWait,
+		MaxDeliver:      BatchMaxDeliver,
+		ReplayPolicy:    jetstream.ReplayInstantPolicy,
+		MaxAckPending:   BatchSize,
+		MaxWaiting:      DefaultMaxWaiting,
+		MaxRequestBatch: BatchSize,
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package presets
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWorkQueueWorker(t *testing.T) {
+	tests := []struct {
+		name               string
+		ackWait            time.Duration
+		maxDeliver         int
+		expectedAckWait    time.Duration
+		expectedMaxDeliver int
+	}{
+		{"given values", 10 * time.Second, 3, 10 * time.Second, 3},
+		{"defaults", 0, 0, DefaultAckWait, -1},
+		{"negative values", -time.Second, -5, DefaultAckWait, -1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := WorkQueueWorker(test.ackWait, test.maxDeliver)
+			if cfg.AckWait != test.expectedAckWait {
+				t.Fatalf("Expected ack wait %v; got %v", test.expectedAckWait, cfg.AckWait)
+			}
+			if cfg.MaxDeliver != test.expectedMaxDeliver {
+				t.Fatalf("Expected max deliver %d; got %d", test.expectedMaxDeliver, cfg.MaxDeliver)
+			}
+		})
+	}
+}
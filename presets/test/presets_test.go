@@ -0,0 +1,132 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/internal/testutil"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/nats-io/nats.go/presets"
+)
+
+func TestPresets(t *testing.T) {
+	srv := testutil.RunBasicJetStreamServer(t)
+	defer testutil.ShutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	workQueue := func() jetstream.ConsumerConfig {
+		cfg := presets.WorkQueueWorker(10*time.Second, 3)
+		cfg.Durable = "workers"
+		return cfg
+	}
+	batchETL := func() jetstream.ConsumerConfig {
+		cfg := presets.BatchETL()
+		cfg.Durable = "etl"
+		return cfg
+	}
+
+	tests := []struct {
+		name      string
+		retention jetstream.RetentionPolicy
+		cfg       jetstream.ConsumerConfig
+		// messages published before the consumer was created
+		// which it is expected to receive
+		existing int
+	}{
+		{"work queue worker", jetstream.LimitsPolicy, workQueue(), 5},
+		{"work queue worker on work queue stream", jetstream.WorkQueuePolicy, workQueue(), 5},
+		{"low latency fanout", jetstream.LimitsPolicy, presets.LowLatencyFanout(), 0},
+		{"batch etl", jetstream.LimitsPolicy, batchETL(), 5},
+		{"batch etl on interest stream", jetstream.InterestPolicy, batchETL(), 0},
+	}
+
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			name := fmt.Sprintf("S%d", i)
+			s, err := js.CreateStream(ctx, jetstream.StreamConfig{
+				Name:      name,
+				Subjects:  []string{name + ".>"},
+				Retention: test.retention,
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			publish := func() {
+				for j := 0; j < 5; j++ {
+					if _, err := js.Publish(ctx, name+".events", []byte("msg")); err != nil {
+						t.Fatalf("Unexpected error: %v", err)
+					}
+				}
+			}
+			publish()
+
+			cons, err := s.AddConsumer(ctx, test.cfg)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			publish()
+
+			msgs, err := cons.Fetch(test.existing+5, jetstream.FetchMaxWait(time.Second))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var last jetstream.Msg
+			var received int
+			for msg := range msgs.Messages() {
+				if test.cfg.AckPolicy == jetstream.AckExplicitPolicy {
+					if err := msg.DoubleAck(ctx); err != nil {
+						t.Fatalf("Unexpected error: %v", err)
+					}
+				}
+				last = msg
+				received++
+			}
+			if msgs.Error() != nil {
+				t.Fatalf("Unexpected error: %v", msgs.Error())
+			}
+			if received != test.existing+5 {
+				t.Fatalf("Expected %d messages; got %d", test.existing+5, received)
+			}
+			if test.cfg.AckPolicy == jetstream.AckAllPolicy {
+				if err := last.DoubleAck(ctx); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+			if test.cfg.AckPolicy != jetstream.AckNonePolicy {
+				info, err := cons.Info(ctx)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if info.NumAckPending != 0 {
+					t.Fatalf("Expected all messages to be acknowledged; got %d pending", info.NumAckPending)
+				}
+			}
+		})
+	}
+}
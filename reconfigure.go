@@ -0,0 +1,182 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nats
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// ErrOptionNotReconfigurable is returned by [Conn.Reconfigure] when an option
+// changes a setting which can only be applied when connecting.
+var ErrOptionNotReconfigurable = errors.New("nats: option cannot be changed without reconnecting")
+
+// reconfigurableOptions are the fields of [Options] which can be changed
+// using [Conn.Reconfigure].
+var reconfigurableOptions = map[string]struct{}{
+	// timeouts
+	"Timeout":        {},
+	"DrainTimeout":   {},
+	"FlusherTimeout": {},
+	"PingInterval":   {},
+	"MaxPingsOut":    {},
+	// reconnect behavior
+	"MaxReconnect":           {},
+	"ReconnectWait":          {},
+	"ReconnectJitter":        {},
+	"ReconnectJitterTLS":     {},
+	"CustomReconnectDelayCB": {},
+	// pending limits
+	"ReconnectBufSize": {},
+	"SubChanLen":       {},
+	// handlers
+	"ClosedCB":            {},
+	"DisconnectedCB":      {},
+	"DisconnectedErrCB":   {},
+	"ConnectedCB":         {},
+	"ReconnectedCB":       {},
+	"DiscoveredServersCB": {},
+	"AsyncErrorCB":        {},
+	"LameDuckModeHandler": {},
+	"LeakedSubsCB":        {},
+	"ServerInfoCB":        {},
+	"RequestRecordCB":     {},
+	// tracing
+	"Redactor": {},
+}
+
+// Reconfigure applies options to the connection at runtime, without
+// reconnecting. Only the following settings can be changed:
+//   - timeouts: [Timeout], [DrainTimeout], [FlusherTimeout], [PingInterval] and [MaxPingsOutstanding]
+//   - reconnect behavior: [MaxReconnects], [ReconnectWait], [ReconnectJitter] and [CustomReconnectDelay]
+//   - pending limits: [ReconnectBufSize], applied on the next disconnect, and [SyncQueueLen],
+//     applied to subscriptions created afterwards
+//   - handlers, e.g. [ErrorHandler] or [ClosedHandler]
+//   - the [Redactor] used for traces and recorded requests
+//
+// If any option changes a different setting, [ErrOptionNotReconfigurable]
+// is returned and none of the options is applied.
+func (nc *Conn) Reconfigure(options ...Option) error {
+	if nc == nil {
+		return ErrInvalidConnection
+	}
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	if nc.isClosed() {
+		return ErrConnectionClosed
+	}
+
+	opts := nc.Opts
+	for _, opt := range options {
+		if opt == nil {
+			continue
+		}
+		if err := opt(&opts); err != nil {
+			return err
+		}
+	}
+	if err := checkReconfigurable(&nc.Opts, &opts); err != nil {
+		return err
+	}
+	pingInterval := nc.Opts.PingInterval
+	if opts.PingInterval != pingInterval && opts.PingInterval <= 0 {
+		return fmt.Errorf("nats: ping interval has to be positive")
+	}
+	// Same defaults as applied on connect.
+	if opts.MaxPingsOut == 0 {
+		opts.MaxPingsOut = DefaultMaxPingOut
+	}
+	if opts.SubChanLen == 0 {
+		opts.SubChanLen = DefaultMaxChanLen
+	}
+	if opts.ReconnectBufSize == 0 {
+		opts.ReconnectBufSize = DefaultReconnectBufSize
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.AsyncErrorCB == nil {
+		opts.AsyncErrorCB = defaultErrHandler
+	}
+
+	// Only write the fields which changed, other settings may be read
+	// without holding the connection lock.
+	applyReconfigured(&nc.Opts, &opts)
+	nc.setRecording()
+	if opts.PingInterval != pingInterval && nc.status == CONNECTED {
+		// Apply the new interval right away instead of after the next ping.
+		if nc.ptmr == nil {
+			nc.ptmr = time.AfterFunc(opts.PingInterval, nc.processPingTimer)
+		} else {
+			nc.ptmr.Reset(opts.PingInterval)
+		}
+	}
+	return nil
+}
+
+// timeout returns the connection timeout, which can change with
+// [Conn.Reconfigure].
+func (nc *Conn) timeout() time.Duration {
+	nc.mu.RLock()
+	defer nc.mu.RUnlock()
+	return nc.Opts.Timeout
+}
+
+// subChanLen returns the pending limit of new synchronous subscriptions,
+// which can change with [Conn.Reconfigure].
+func (nc *Conn) subChanLen() int {
+	nc.mu.RLock()
+	defer nc.mu.RUnlock()
+	return nc.Opts.SubChanLen
+}
+
+// applyReconfigured copies the reconfigurable settings of next which differ
+// from cur into cur.
+func applyReconfigured(cur, next *Options) {
+	cv, nv := reflect.ValueOf(cur).Elem(), reflect.ValueOf(next).Elem()
+	for name := range reconfigurableOptions {
+		cf, nf := cv.FieldByName(name), nv.FieldByName(name)
+		if !optionChanged(cf, nf) {
+			continue
+		}
+		cf.Set(nf)
+	}
+}
+
+// optionChanged reports whether an option field differs. Functions are
+// compared by pointer.
+func optionChanged(cf, nf reflect.Value) bool {
+	if cf.Kind() == reflect.Func {
+		return cf.Pointer() != nf.Pointer()
+	}
+	return !reflect.DeepEqual(cf.Interface(), nf.Interface())
+}
+
+// checkReconfigurable returns an error if any setting which is not
+// reconfigurable differs.
+func checkReconfigurable(cur, next *Options) error {
+	cv, nv := reflect.ValueOf(cur).Elem(), reflect.ValueOf(next).Elem()
+	for i := 0; i < cv.NumField(); i++ {
+		field := cv.Type().Field(i)
+		if _, ok := reconfigurableOptions[field.Name]; ok || !field.IsExported() {
+			continue
+		}
+		if optionChanged(cv.Field(i), nv.Field(i)) {
+			return fmt.Errorf("%w: %s", ErrOptionNotReconfigurable, field.Name)
+		}
+	}
+	return nil
+}
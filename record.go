@@ -0,0 +1,67 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nats
+
+import "sync/atomic"
+
+// RequestRecordHandler is used to record requests made with a connection.
+// It is invoked with the request and either its response or the error the
+// request failed with, e.g. [ErrNoResponders] or [ErrTimeout].
+type RequestRecordHandler func(req *Msg, resp *Msg, err error)
+
+// RecordRequests is an Option to set the handler invoked once each request
+// made with the connection completed, including requests made by JetStream
+// contexts using it. The handler is invoked from the goroutine which made
+// the request and must not modify the messages. If a [Redactor] is set with
+// [TraceRedactor], the handler receives redacted copies of the messages.
+func RecordRequests(cb RequestRecordHandler) Option {
+	return func(o *Options) error {
+		o.RequestRecordCB = cb
+		return nil
+	}
+}
+
+// setRecording updates whether requests are recorded.
+// Lock should be held.
+func (nc *Conn) setRecording() {
+	var recording int32
+	if nc.Opts.RequestRecordCB != nil {
+		recording = 1
+	}
+	atomic.StoreInt32(&nc.recording, recording)
+}
+
+func (nc *Conn) recordRequest(subj string, hdr, data []byte, resp *Msg, err error) {
+	if atomic.LoadInt32(&nc.recording) == 0 {
+		return
+	}
+	nc.mu.RLock()
+	cb := nc.Opts.RequestRecordCB
+	r := nc.Opts.Redactor
+	nc.mu.RUnlock()
+	if cb == nil {
+		return
+	}
+	req := &Msg{Subject: subj, Data: data}
+	if len(hdr) > 0 {
+		req.Header, _ = DecodeHeadersMsg(hdr)
+	}
+	if r != nil {
+		req = redactMsg(r, subj, req)
+		if resp != nil {
+			resp = redactMsg(r, subj, resp)
+		}
+	}
+	cb(req, resp, err)
+}
@@ -0,0 +1,125 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nats
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Redactor masks sensitive data, such as payloads and authentication
+// headers, before messages are passed to observability hooks: JetStream
+// client traces and handlers set with [RecordRequests]. The client has no
+// other hooks receiving payloads or headers. Implementations must not
+// modify the given payload or header.
+type Redactor interface {
+	RedactPayload(subj string, payload []byte) []byte
+	RedactHeader(subj string, hdr Header) Header
+}
+
+// RedactedValue replaces masked header values.
+const RedactedValue = "[REDACTED]"
+
+// DefaultSensitiveHeaders are the headers masked by [DefaultRedactor].
+var DefaultSensitiveHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Api-Key",
+}
+
+// MaskRedactor is a [Redactor] masking the values of the listed headers
+// and, unless KeepPayload is set, replacing payloads with a placeholder
+// holding their size.
+type MaskRedactor struct {
+	// Headers lists the names of headers to mask, compared case insensitively.
+	Headers []string
+	// KeepPayload passes payloads through unchanged.
+	KeepPayload bool
+}
+
+// DefaultRedactor returns a redactor masking payloads and [DefaultSensitiveHeaders].
+func DefaultRedactor() *MaskRedactor {
+	return &MaskRedactor{Headers: DefaultSensitiveHeaders}
+}
+
+// RedactPayload returns a placeholder for a non-empty payload, unless KeepPayload is set.
+func (r *MaskRedactor) RedactPayload(_ string, payload []byte) []byte {
+	if r.KeepPayload || len(payload) == 0 {
+		return payload
+	}
+	return []byte(fmt.Sprintf("[REDACTED %d bytes]", len(payload)))
+}
+
+// RedactHeader returns a copy of the header with the values of the listed headers masked.
+func (r *MaskRedactor) RedactHeader(_ string, hdr Header) Header {
+	if len(hdr) == 0 {
+		return hdr
+	}
+	res := make(Header, len(hdr))
+	for k, v := range hdr {
+		if r.sensitive(k) {
+			masked := make([]string, len(v))
+			for i := range masked {
+				masked[i] = RedactedValue
+			}
+			res[k] = masked
+			continue
+		}
+		res[k] = v
+	}
+	return res
+}
+
+func (r *MaskRedactor) sensitive(key string) bool {
+	for _, h := range r.Headers {
+		if strings.EqualFold(h, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// TraceRedactor is an Option to set the [Redactor] applied to the data passed
+// to JetStream client traces, unless the trace sets its own, and to the
+// requests and responses passed to the [RecordRequests] handler.
+func TraceRedactor(r Redactor) Option {
+	return func(o *Options) error {
+		o.Redactor = r
+		return nil
+	}
+}
+
+// TraceRedactor returns the [Redactor] set on the connection, if any.
+func (nc *Conn) TraceRedactor() Redactor {
+	if nc == nil {
+		return nil
+	}
+	nc.mu.RLock()
+	defer nc.mu.RUnlock()
+	return nc.Opts.Redactor
+}
+
+// redactMsg returns a copy of the message with its payload and header
+// redacted for the request subject subj.
+func redactMsg(r Redactor, subj string, m *Msg) *Msg {
+	return &Msg{
+		Subject: m.Subject,
+		Reply:   m.Reply,
+		Header:  r.RedactHeader(subj, m.Header),
+		Data:    r.RedactPayload(subj, m.Data),
+		Sub:     m.Sub,
+	}
+}
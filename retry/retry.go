@@ -0,0 +1,178 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package retry computes exponential backoff delays with jitter and retries
+// operations using them. It is used by the client for reconnect delays,
+// JetStream API and publish retries, and can be used by applications to
+// retry operations the same way.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Notice: Experimental Preview
+//
+// This functionality is EXPERIMENTAL and may be changed in later releases.
+
+type (
+	// Policy defines how failed operations are retried.
+	Policy struct {
+		// Attempts is the number of retries after the first failed attempt.
+		// A negative value retries indefinitely.
+		Attempts int
+		// Wait is the delay before the first retry.
+		Wait time.Duration
+		// MaxWait, if set, doubles the delay after each retry up to MaxWait.
+		MaxWait time.Duration
+		// Jitter, if set, adds a random duration up to Jitter to each delay,
+		// so that clients failing at the same time do not retry in lockstep.
+		Jitter time.Duration
+		// Budget, if set, limits the total time spent waiting between retries.
+		Budget time.Duration
+		// Retryable classifies errors returned by the operation. Errors it
+		// returns false for are returned right away. If not set, all errors
+		// are retried.
+		Retryable func(error) bool
+	}
+
+	// Backoff iterates over the delays of a [Policy], waiting before each
+	// retry and keeping track of the attempts and the budget. A Backoff is
+	// not safe for concurrent use.
+	Backoff struct {
+		policy  Policy
+		attempt int
+		waited  time.Duration
+	}
+)
+
+var (
+	// ErrInvalidPolicy is returned by [Policy.Validate].
+	ErrInvalidPolicy = errors.New("invalid retry policy")
+
+	// ErrExhausted is returned by [Backoff.Wait] when no retries are left.
+	ErrExhausted = errors.New("retry attempts exhausted")
+
+	// ErrBudgetExceeded is returned by [Backoff.Wait] when waiting for the
+	// next retry would exceed the budget of the policy.
+	ErrBudgetExceeded = errors.New("retry budget exceeded")
+)
+
+var (
+	rndMu sync.Mutex
+	rnd   = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// Validate returns an error wrapping [ErrInvalidPolicy] if any of the
+// durations is negative.
+func (p Policy) Validate() error {
+	if p.Wait < 0 || p.MaxWait < 0 || p.Jitter < 0 || p.Budget < 0 {
+		return fmt.Errorf("%w: durations cannot be negative", ErrInvalidPolicy)
+	}
+	return nil
+}
+
+// Delay returns the delay before the retry following the given attempt,
+// counted from 0, including jitter.
+func (p Policy) Delay(attempt int) time.Duration {
+	wait := p.Wait
+	if p.MaxWait > 0 {
+		for i := 0; i < attempt && wait < p.MaxWait; i++ {
+			wait *= 2
+		}
+		if wait > p.MaxWait {
+			wait = p.MaxWait
+		}
+	}
+	if p.Jitter > 0 {
+		rndMu.Lock()
+		wait += time.Duration(rnd.Int63n(int64(p.Jitter)))
+		rndMu.Unlock()
+	}
+	return wait
+}
+
+// IsRetryable reports whether an operation failing with err is retried.
+func (p Policy) IsRetryable(err error) bool {
+	return err != nil && (p.Retryable == nil || p.Retryable(err))
+}
+
+// NewBackoff returns a [Backoff] starting with the first retry of the policy.
+func NewBackoff(p Policy) *Backoff {
+	return &Backoff{policy: p}
+}
+
+// Attempt returns the number of retries waited for so far.
+func (b *Backoff) Attempt() int {
+	return b.attempt
+}
+
+// Wait waits for the delay before the next retry. It returns
+// [ErrExhausted] if no retries are left, [ErrBudgetExceeded] if waiting
+// would exceed the budget, or the context error if ctx is done while
+// waiting.
+func (b *Backoff) Wait(ctx context.Context) error {
+	if b.policy.Attempts >= 0 && b.attempt >= b.policy.Attempts {
+		return ErrExhausted
+	}
+	delay := b.policy.Delay(b.attempt)
+	if b.policy.Budget > 0 && b.waited+delay > b.policy.Budget {
+		return ErrBudgetExceeded
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if delay > 0 {
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+	}
+	b.attempt++
+	b.waited += delay
+	return nil
+}
+
+// Reset starts over with the first retry, e.g. after an operation
+// succeeded on a long lived connection.
+func (b *Backoff) Reset() {
+	b.attempt = 0
+	b.waited = 0
+}
+
+// Do calls fn until it succeeds, fails with an error the policy does not
+// retry, or no retries are left, and returns the last error of fn. If ctx
+// is done while waiting for a retry, the context error is returned.
+func Do(ctx context.Context, p Policy, fn func(context.Context) error) error {
+	b := NewBackoff(p)
+	for {
+		err := fn(ctx)
+		if !p.IsRetryable(err) {
+			return err
+		}
+		if werr := b.Wait(ctx); werr != nil {
+			if errors.Is(werr, ErrExhausted) || errors.Is(werr, ErrBudgetExceeded) {
+				return err
+			}
+			return werr
+		}
+	}
+}
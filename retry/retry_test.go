@@ -0,0 +1,183 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPolicyDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   Policy
+		attempt  int
+		expected time.Duration
+	}{
+		{"constant", Policy{Wait: time.Second}, 5, time.Second},
+		{"first retry", Policy{Wait: time.Second, MaxWait: time.Minute}, 0, time.Second},
+		{"doubled", Policy{Wait: time.Second, MaxWait: time.Minute}, 3, 8 * time.Second},
+		{"capped", Policy{Wait: time.Second, MaxWait: 5 * time.Second}, 3, 5 * time.Second},
+		{"no wait", Policy{}, 3, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if d := test.policy.Delay(test.attempt); d != test.expected {
+				t.Fatalf("Expected delay %v; got %v", test.expected, d)
+			}
+		})
+	}
+
+	t.Run("jitter", func(t *testing.T) {
+		p := Policy{Wait: time.Second, Jitter: 100 * time.Millisecond}
+		for i := 0; i < 100; i++ {
+			if d := p.Delay(0); d < time.Second || d >= 1100*time.Millisecond {
+				t.Fatalf("Delay out of range: %v", d)
+			}
+		}
+	})
+}
+
+func TestPolicyValidate(t *testing.T) {
+	if err := (Policy{Attempts: -1, Wait: time.Second}).Validate(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := (Policy{Jitter: -time.Second}).Validate(); !errors.Is(err, ErrInvalidPolicy) {
+		t.Fatalf("Expected error: %v; got: %v", ErrInvalidPolicy, err)
+	}
+}
+
+func TestBackoffWait(t *testing.T) {
+	t.Run("exhausted", func(t *testing.T) {
+		b := NewBackoff(Policy{Attempts: 2, Wait: time.Millisecond})
+		for i := 0; i < 2; i++ {
+			if err := b.Wait(context.Background()); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+		if b.Attempt() != 2 {
+			t.Fatalf("Expected 2 attempts; got %d", b.Attempt())
+		}
+		if err := b.Wait(context.Background()); !errors.Is(err, ErrExhausted) {
+			t.Fatalf("Expected error: %v; got: %v", ErrExhausted, err)
+		}
+		b.Reset()
+		if err := b.Wait(context.Background()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("indefinitely", func(t *testing.T) {
+		b := NewBackoff(Policy{Attempts: -1})
+		for i := 0; i < 100; i++ {
+			if err := b.Wait(context.Background()); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+	})
+
+	t.Run("budget", func(t *testing.T) {
+		b := NewBackoff(Policy{Attempts: -1, Wait: 10 * time.Millisecond, Budget: 25 * time.Millisecond})
+		for i := 0; i < 2; i++ {
+			if err := b.Wait(context.Background()); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+		if err := b.Wait(context.Background()); !errors.Is(err, ErrBudgetExceeded) {
+			t.Fatalf("Expected error: %v; got: %v", ErrBudgetExceeded, err)
+		}
+	})
+
+	t.Run("context canceled", func(t *testing.T) {
+		b := NewBackoff(Policy{Attempts: -1, Wait: time.Hour})
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if err := b.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected error: %v; got: %v", context.DeadlineExceeded, err)
+		}
+		if b.Attempt() != 0 {
+			t.Fatalf("Expected no attempts; got %d", b.Attempt())
+		}
+	})
+}
+
+func TestDo(t *testing.T) {
+	errTemporary := errors.New("temporary")
+	errPermanent := errors.New("permanent")
+
+	t.Run("succeeds after retries", func(t *testing.T) {
+		var calls int
+		err := Do(context.Background(), Policy{Attempts: 5}, func(context.Context) error {
+			calls++
+			if calls < 3 {
+				return errTemporary
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if calls != 3 {
+			t.Fatalf("Expected 3 calls; got %d", calls)
+		}
+	})
+
+	t.Run("returns last error when exhausted", func(t *testing.T) {
+		var calls int
+		err := Do(context.Background(), Policy{Attempts: 2}, func(context.Context) error {
+			calls++
+			return errTemporary
+		})
+		if !errors.Is(err, errTemporary) {
+			t.Fatalf("Expected error: %v; got: %v", errTemporary, err)
+		}
+		if calls != 3 {
+			t.Fatalf("Expected 3 calls; got %d", calls)
+		}
+	})
+
+	t.Run("does not retry unretryable errors", func(t *testing.T) {
+		var calls int
+		p := Policy{
+			Attempts:  5,
+			Retryable: func(err error) bool { return !errors.Is(err, errPermanent) },
+		}
+		err := Do(context.Background(), p, func(context.Context) error {
+			calls++
+			if calls == 2 {
+				return errPermanent
+			}
+			return errTemporary
+		})
+		if !errors.Is(err, errPermanent) {
+			t.Fatalf("Expected error: %v; got: %v", errPermanent, err)
+		}
+		if calls != 2 {
+			t.Fatalf("Expected 2 calls; got %d", calls)
+		}
+	})
+
+	t.Run("context canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		err := Do(ctx, Policy{Attempts: -1, Wait: time.Hour}, func(context.Context) error {
+			cancel()
+			return errTemporary
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected error: %v; got: %v", context.Canceled, err)
+		}
+	})
+}
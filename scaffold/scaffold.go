@@ -0,0 +1,319 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package scaffold generates ready to run Go programs from a declarative
+// description of streams, consumers and services. Generated consumers ack
+// explicitly, publish messages failing their last delivery to a dead letter
+// subject, and stop gracefully on SIGINT or SIGTERM; counters of processed
+// messages and requests are published with expvar. Only the message and
+// request handlers are left to be implemented.
+package scaffold
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/format"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+)
+
+// Notice: Experimental Preview
+//
+// This functionality is EXPERIMENTAL and may be changed in later releases.
+
+type (
+	// Spec describes the program to generate.
+	Spec struct {
+		// Name is the name of the program, used as the connection name
+		// and the name of the expvar map holding its metrics.
+		Name string `json:"name"`
+
+		// Streams are created, or updated if they exist, on startup.
+		Streams []Stream `json:"streams,omitempty"`
+
+		// Consumers are created, or updated if they exist, and consumed.
+		Consumers []Consumer `json:"consumers,omitempty"`
+
+		// Services are registered using the micro package.
+		Services []Service `json:"services,omitempty"`
+	}
+
+	// Stream describes a stream.
+	Stream struct {
+		Name     string   `json:"name"`
+		Subjects []string `json:"subjects"`
+		// Storage is either "file" or "memory", defaults to "file".
+		Storage  string   `json:"storage,omitempty"`
+		Replicas int      `json:"replicas,omitempty"`
+		MaxAge   Duration `json:"max_age,omitempty"`
+	}
+
+	// Consumer describes a durable pull consumer.
+	Consumer struct {
+		Name          string   `json:"name"`
+		Stream        string   `json:"stream"`
+		FilterSubject string   `json:"filter_subject,omitempty"`
+		AckWait       Duration `json:"ack_wait,omitempty"`
+		// MaxDeliver is the maximum number of deliveries of a message,
+		// required when DeadLetter is set.
+		MaxDeliver int `json:"max_deliver,omitempty"`
+		// DeadLetter is the subject messages failing their last delivery
+		// are published on, it has to be bound to a stream.
+		DeadLetter string `json:"dead_letter,omitempty"`
+	}
+
+	// Service describes a micro service.
+	Service struct {
+		Name string `json:"name"`
+		// Version is a SemVer version, defaults to "0.1.0".
+		Version     string     `json:"version,omitempty"`
+		Description string     `json:"description,omitempty"`
+		Endpoints   []Endpoint `json:"endpoints"`
+	}
+
+	// Endpoint describes a service endpoint.
+	Endpoint struct {
+		Name string `json:"name"`
+		// Subject defaults to the endpoint name.
+		Subject string `json:"subject,omitempty"`
+	}
+
+	// Duration is a [time.Duration] encoded as a string, such as "1m30s".
+	Duration time.Duration
+
+	// program is the template data, with the parts of the
+	// program required by the spec
+	program struct {
+		*Spec
+		JetStream  bool
+		DeadLetter bool
+	}
+)
+
+// ErrInvalidSpec is returned when a spec is malformed.
+var ErrInvalidSpec = errors.New("invalid scaffold spec")
+
+var nameRe = regexp.MustCompile(`\A[A-Za-z0-9_-]+\z`)
+
+// MarshalText implements [encoding.TextMarshaler].
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText implements [encoding.TextUnmarshaler].
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Parse parses and validates a JSON encoded spec.
+func Parse(data []byte) (*Spec, error) {
+	var spec Spec
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSpec, err)
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+// Validate checks that the spec can be generated.
+func (s *Spec) Validate() error {
+	if !nameRe.MatchString(s.Name) {
+		return fmt.Errorf("%w: invalid name %q", ErrInvalidSpec, s.Name)
+	}
+	if len(s.Consumers) == 0 && len(s.Services) == 0 {
+		return fmt.Errorf("%w: at least one consumer or service is required", ErrInvalidSpec)
+	}
+	streams := make(map[string]struct{})
+	for _, st := range s.Streams {
+		if !nameRe.MatchString(st.Name) {
+			return fmt.Errorf("%w: invalid stream name %q", ErrInvalidSpec, st.Name)
+		}
+		if _, ok := streams[st.Name]; ok {
+			return fmt.Errorf("%w: duplicate stream %q", ErrInvalidSpec, st.Name)
+		}
+		streams[st.Name] = struct{}{}
+		if len(st.Subjects) == 0 {
+			return fmt.Errorf("%w: stream %q has no subjects", ErrInvalidSpec, st.Name)
+		}
+		for _, subj := range st.Subjects {
+			if !validSubject(subj, true) {
+				return fmt.Errorf("%w: invalid subject %q of stream %q", ErrInvalidSpec, subj, st.Name)
+			}
+		}
+		if st.Storage != "" && st.Storage != "file" && st.Storage != "memory" {
+			return fmt.Errorf("%w: invalid storage %q of stream %q", ErrInvalidSpec, st.Storage, st.Name)
+		}
+		if st.Replicas < 0 || st.MaxAge < 0 {
+			return fmt.Errorf("%w: negative limits of stream %q", ErrInvalidSpec, st.Name)
+		}
+	}
+	idents := make(map[string]string)
+	for _, c := range s.Consumers {
+		if !nameRe.MatchString(c.Name) {
+			return fmt.Errorf("%w: invalid consumer name %q", ErrInvalidSpec, c.Name)
+		}
+		if !nameRe.MatchString(c.Stream) {
+			return fmt.Errorf("%w: invalid stream name %q of consumer %q", ErrInvalidSpec, c.Stream, c.Name)
+		}
+		if err := checkIdent(idents, ident(c.Name)+"Msg", c.Name); err != nil {
+			return err
+		}
+		if c.FilterSubject != "" && !validSubject(c.FilterSubject, true) {
+			return fmt.Errorf("%w: invalid filter subject %q of consumer %q", ErrInvalidSpec, c.FilterSubject, c.Name)
+		}
+		if c.AckWait < 0 || c.MaxDeliver < 0 {
+			return fmt.Errorf("%w: negative limits of consumer %q", ErrInvalidSpec, c.Name)
+		}
+		if c.DeadLetter != "" {
+			if !validSubject(c.DeadLetter, false) {
+				return fmt.Errorf("%w: invalid dead letter subject %q of consumer %q", ErrInvalidSpec, c.DeadLetter, c.Name)
+			}
+			if c.MaxDeliver == 0 {
+				return fmt.Errorf("%w: consumer %q with dead letter subject requires max deliver", ErrInvalidSpec, c.Name)
+			}
+		}
+	}
+	for _, svc := range s.Services {
+		if !nameRe.MatchString(svc.Name) {
+			return fmt.Errorf("%w: invalid service name %q", ErrInvalidSpec, svc.Name)
+		}
+		if err := checkIdent(idents, ident(svc.Name)+"Service", svc.Name); err != nil {
+			return err
+		}
+		if len(svc.Endpoints) == 0 {
+			return fmt.Errorf("%w: service %q has no endpoints", ErrInvalidSpec, svc.Name)
+		}
+		for _, e := range svc.Endpoints {
+			if !nameRe.MatchString(e.Name) {
+				return fmt.Errorf("%w: invalid endpoint name %q of service %q", ErrInvalidSpec, e.Name, svc.Name)
+			}
+			if err := checkIdent(idents, ident(svc.Name)+ident(e.Name)+"Request", svc.Name+"."+e.Name); err != nil {
+				return err
+			}
+			if e.Subject != "" && !validSubject(e.Subject, true) {
+				return fmt.Errorf("%w: invalid subject %q of endpoint %q", ErrInvalidSpec, e.Subject, e.Name)
+			}
+		}
+	}
+	return nil
+}
+
+func validSubject(subject string, wildcards bool) bool {
+	tokens := strings.Split(subject, ".")
+	for i, token := range tokens {
+		switch {
+		case token == "" || strings.ContainsAny(token, " \t\r\n"):
+			return false
+		case token == "*" || (token == ">" && i == len(tokens)-1):
+			if !wildcards {
+				return false
+			}
+		case strings.ContainsAny(token, "*>"):
+			return false
+		}
+	}
+	return true
+}
+
+// checkIdent ensures that generated function names are unique.
+func checkIdent(idents map[string]string, id, name string) error {
+	if other, ok := idents[id]; ok {
+		return fmt.Errorf("%w: %q and %q map to the same function name", ErrInvalidSpec, other, name)
+	}
+	idents[id] = name
+	return nil
+}
+
+// Generate writes the gofmt-ed source of a main package implementing the spec.
+func Generate(w io.Writer, spec *Spec) error {
+	if err := spec.Validate(); err != nil {
+		return err
+	}
+	p := program{
+		Spec:      spec,
+		JetStream: len(spec.Streams) > 0 || len(spec.Consumers) > 0,
+	}
+	for _, c := range spec.Consumers {
+		if c.DeadLetter != "" {
+			p.DeadLetter = true
+		}
+	}
+	var buf bytes.Buffer
+	if err := programTmpl.Execute(&buf, p); err != nil {
+		return err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("formatting generated source: %w", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// ident converts a name such as "orders-processor" to "OrdersProcessor".
+func ident(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if r == '-' || r == '_' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// goDuration returns a Go expression of the duration.
+func goDuration(d Duration) string {
+	for _, unit := range []struct {
+		d    time.Duration
+		name string
+	}{
+		{time.Hour, "time.Hour"},
+		{time.Minute, "time.Minute"},
+		{time.Second, "time.Second"},
+		{time.Millisecond, "time.Millisecond"},
+	} {
+		if time.Duration(d)%unit.d == 0 {
+			return fmt.Sprintf("%d * %s", time.Duration(d)/unit.d, unit.name)
+		}
+	}
+	return fmt.Sprintf("time.Duration(%d)", d)
+}
+
+var programTmpl = template.Must(template.New("program").Funcs(template.FuncMap{
+	"ident":    ident,
+	"quote":    strconv.Quote,
+	"duration": goDuration,
+}).Parse(programSrc))
@@ -0,0 +1,122 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scaffold
+
+import (
+	"bytes"
+	"errors"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+	"time"
+)
+
+const ordersSpec = `{
+	"name": "orders",
+	"streams": [
+		{"name": "ORDERS", "subjects": ["orders.>"], "max_age": "24h"},
+		{"name": "DLQ", "subjects": ["dlq.*"], "storage": "memory"}
+	],
+	"consumers": [
+		{"name": "orders-processor", "stream": "ORDERS", "filter_subject": "orders.new", "ack_wait": "30s", "max_deliver": 5, "dead_letter": "dlq.orders"},
+		{"name": "audit", "stream": "ORDERS"}
+	],
+	"services": [
+		{"name": "orders-api", "version": "1.0.0", "endpoints": [{"name": "create", "subject": "orders.api.create"}, {"name": "get"}]}
+	]
+}`
+
+func TestGenerate(t *testing.T) {
+	spec, err := Parse([]byte(ordersSpec))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if time.Duration(spec.Consumers[0].AckWait) != 30*time.Second {
+		t.Fatalf("Unexpected ack wait: %v", spec.Consumers[0].AckWait)
+	}
+	var buf bytes.Buffer
+	if err := Generate(&buf, spec); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	f, err := parser.ParseFile(token.NewFileSet(), "main.go", buf.Bytes(), parser.ImportsOnly)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var imports []string
+	for _, imp := range f.Imports {
+		imports = append(imports, imp.Path.Value)
+	}
+	for _, expected := range []string{`"github.com/nats-io/nats.go/jetstream"`, `"github.com/nats-io/nats.go/micro"`, `"expvar"`} {
+		if !strings.Contains(strings.Join(imports, " "), expected) {
+			t.Fatalf("Expected import %s; got: %v", expected, imports)
+		}
+	}
+	for _, expected := range []string{
+		"func setupStreams(",
+		"MaxAge:   24 * time.Hour,",
+		"Storage:  jetstream.MemoryStorage,",
+		"func handleOrdersProcessorMsg(ctx context.Context, msg jetstream.Msg) error {",
+		"AckWait:       30 * time.Second,",
+		`meta.NumDelivered >= 5 {`,
+		`deadLetter(ctx, js, "orders-processor", "dlq.orders", msg, err)`,
+		"func handleAuditMsg(",
+		"func addOrdersApiService(nc *nats.Conn) (micro.Service, error) {",
+		`micro.WithEndpointSubject("orders.api.create")`,
+		`micro.WithEndpointSubject("get")`,
+		"func handleOrdersApiGetRequest(req micro.Request) {",
+		"cc.Stop()",
+		"nc.Drain()",
+	} {
+		if !bytes.Contains(buf.Bytes(), []byte(expected)) {
+			t.Fatalf("Expected generated source to contain %q:\n%s", expected, buf.String())
+		}
+	}
+
+	// only the required parts are generated
+	buf.Reset()
+	spec = &Spec{Name: "svc", Services: []Service{{Name: "echo", Endpoints: []Endpoint{{Name: "echo"}}}}}
+	if err := Generate(&buf, spec); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, unexpected := range []string{"jetstream", "setupStreams", "deadLetter", "consumers"} {
+		if bytes.Contains(buf.Bytes(), []byte(unexpected)) {
+			t.Fatalf("Unexpected %q in generated source:\n%s", unexpected, buf.String())
+		}
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`Version: "0.1.0",`)) {
+		t.Fatalf("Expected default version:\n%s", buf.String())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, spec := range []string{
+		`{"name": "x"}`,
+		`{"name": "a b", "services": [{"name": "s", "endpoints": [{"name": "e"}]}]}`,
+		`{"name": "x", "unknown": true, "services": [{"name": "s", "endpoints": [{"name": "e"}]}]}`,
+		`{"name": "x", "streams": [{"name": "S"}], "consumers": [{"name": "c", "stream": "S"}]}`,
+		`{"name": "x", "streams": [{"name": "S", "subjects": ["a.>.b"]}], "consumers": [{"name": "c", "stream": "S"}]}`,
+		`{"name": "x", "streams": [{"name": "S", "subjects": ["a"], "storage": "disk"}], "consumers": [{"name": "c", "stream": "S"}]}`,
+		`{"name": "x", "streams": [{"name": "S", "subjects": ["a"], "max_age": "1 day"}], "consumers": [{"name": "c", "stream": "S"}]}`,
+		`{"name": "x", "consumers": [{"name": "c", "stream": "S", "dead_letter": "dlq"}]}`,
+		`{"name": "x", "consumers": [{"name": "c", "stream": "S", "max_deliver": 3, "dead_letter": "dlq.*"}]}`,
+		`{"name": "x", "consumers": [{"name": "a-b", "stream": "S"}, {"name": "a_b", "stream": "S"}]}`,
+		`{"name": "x", "services": [{"name": "s", "endpoints": []}]}`,
+		`{"name": "x", "services": [{"name": "s", "endpoints": [{"name": "e", "subject": "a..b"}]}]}`,
+	} {
+		if _, err := Parse([]byte(spec)); !errors.Is(err, ErrInvalidSpec) {
+			t.Fatalf("Expected error %v for %s; got: %v", ErrInvalidSpec, spec, err)
+		}
+	}
+}
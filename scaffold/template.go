@@ -0,0 +1,260 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scaffold
+
+// programSrc is the template of the generated program, executed with a
+// program and formatted afterwards.
+const programSrc = `// Code generated by scaffold for {{quote .Name}}.
+// Implement the handlers and remove this notice to take ownership of the file.
+
+package main
+
+import (
+	"context"
+{{- if .Streams}}
+	"errors"
+	"fmt"
+{{- end}}
+	"expvar"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/nats-io/nats.go"
+{{- if .JetStream}}
+	"github.com/nats-io/nats.go/jetstream"
+{{- end}}
+{{- if .Services}}
+	"github.com/nats-io/nats.go/micro"
+{{- end}}
+)
+
+// metrics are served on /debug/vars if METRICS_ADDR is set.
+var metrics = expvar.NewMap({{quote .Name}})
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	url := os.Getenv("NATS_URL")
+	if url == "" {
+		url = nats.DefaultURL
+	}
+	closed := make(chan struct{})
+	nc, err := nats.Connect(url,
+		nats.Name({{quote .Name}}),
+		nats.MaxReconnects(-1),
+		nats.ClosedHandler(func(*nats.Conn) { close(closed) }))
+	if err != nil {
+		log.Fatalf("connecting to %s: %v", url, err)
+	}
+{{- if .JetStream}}
+	js, err := jetstream.New(nc)
+	if err != nil {
+		log.Fatalf("creating JetStream context: %v", err)
+	}
+{{- end}}
+{{- if .Streams}}
+	if err := setupStreams(ctx, js); err != nil {
+		log.Fatalf("setting up streams: %v", err)
+	}
+{{- end}}
+
+{{- if .Consumers}}
+
+	var consumers []jetstream.ConsumeContext
+{{- range .Consumers}}
+	cc{{ident .Name}}, err := consume{{ident .Name}}(ctx, js)
+	if err != nil {
+		log.Fatalf("consuming %s: %v", {{quote .Name}}, err)
+	}
+	consumers = append(consumers, cc{{ident .Name}})
+{{- end}}
+{{- end}}
+{{- range .Services}}
+	if _, err := add{{ident .Name}}Service(nc); err != nil {
+		log.Fatalf("adding service %s: %v", {{quote .Name}}, err)
+	}
+{{- end}}
+
+	if addr := os.Getenv("METRICS_ADDR"); addr != "" {
+		go func() {
+			log.Printf("serving metrics: %v", http.ListenAndServe(addr, nil))
+		}()
+	}
+
+	log.Printf("%s running", {{quote .Name}})
+	<-ctx.Done()
+	log.Printf("%s shutting down", {{quote .Name}})
+{{- if .Consumers}}
+
+	// stop pulling new messages, then let in-flight messages and
+	// requests complete before closing the connection
+	for _, cc := range consumers {
+		cc.Stop()
+	}
+{{- end}}
+	if err := nc.Drain(); err != nil {
+		log.Printf("draining connection: %v", err)
+		nc.Close()
+	}
+	select {
+	case <-closed:
+	case <-time.After(30 * time.Second):
+		log.Printf("timed out draining connection")
+	}
+}
+{{- if .Streams}}
+
+// setupStreams creates the streams, or updates them if they exist.
+func setupStreams(ctx context.Context, js jetstream.JetStream) error {
+	for _, cfg := range []jetstream.StreamConfig{
+{{- range .Streams}}
+		{
+			Name:     {{quote .Name}},
+			Subjects: []string{ {{- range $i, $s := .Subjects}}{{if $i}}, {{end}}{{quote $s}}{{end -}} },
+{{- if eq .Storage "memory"}}
+			Storage:  jetstream.MemoryStorage,
+{{- else}}
+			Storage:  jetstream.FileStorage,
+{{- end}}
+{{- if .Replicas}}
+			Replicas: {{.Replicas}},
+{{- end}}
+{{- if .MaxAge}}
+			MaxAge:   {{duration .MaxAge}},
+{{- end}}
+		},
+{{- end}}
+	} {
+		_, err := js.CreateStream(ctx, cfg)
+		if errors.Is(err, jetstream.ErrStreamNameAlreadyInUse) {
+			_, err = js.UpdateStream(ctx, cfg)
+		}
+		if err != nil {
+			return fmt.Errorf("stream %s: %w", cfg.Name, err)
+		}
+	}
+	return nil
+}
+{{- end}}
+{{- range .Consumers}}
+
+// handle{{ident .Name}}Msg processes a message of consumer {{quote .Name}}.
+// Returning an error redelivers the message
+{{- if .DeadLetter}}, until it is published on
+// {{quote .DeadLetter}} after {{.MaxDeliver}} deliveries
+{{- end}}.
+func handle{{ident .Name}}Msg(ctx context.Context, msg jetstream.Msg) error {
+	// TODO: process the message
+	return nil
+}
+
+func consume{{ident .Name}}(ctx context.Context, js jetstream.JetStream) (jetstream.ConsumeContext, error) {
+	cons, err := js.AddConsumer(ctx, {{quote .Stream}}, jetstream.ConsumerConfig{
+		Durable:       {{quote .Name}},
+		AckPolicy:     jetstream.AckExplicitPolicy,
+{{- if .FilterSubject}}
+		FilterSubject: {{quote .FilterSubject}},
+{{- end}}
+{{- if .AckWait}}
+		AckWait:       {{duration .AckWait}},
+{{- end}}
+{{- if .MaxDeliver}}
+		MaxDeliver:    {{.MaxDeliver}},
+{{- end}}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cons.Consume(func(msg jetstream.Msg) {
+		err := handle{{ident .Name}}Msg(ctx, msg)
+		if err == nil {
+			metrics.Add({{quote (print .Name ".processed")}}, 1)
+			if err := msg.Ack(); err != nil {
+				log.Printf("%s: acking message: %v", {{quote .Name}}, err)
+			}
+			return
+		}
+		metrics.Add({{quote (print .Name ".failed")}}, 1)
+		log.Printf("%s: processing message: %v", {{quote .Name}}, err)
+{{- if .DeadLetter}}
+		if meta, metaErr := msg.Metadata(); metaErr == nil && meta.NumDelivered >= {{.MaxDeliver}} {
+			deadLetter(ctx, js, {{quote .Name}}, {{quote .DeadLetter}}, msg, err)
+			return
+		}
+{{- end}}
+		if err := msg.Nak(); err != nil {
+			log.Printf("%s: naking message: %v", {{quote .Name}}, err)
+		}
+	})
+}
+{{- end}}
+{{- if .DeadLetter}}
+
+// deadLetter publishes a message which failed its last delivery on the
+// dead letter subject and terminates it.
+func deadLetter(ctx context.Context, js jetstream.JetStream, consumer, subject string, msg jetstream.Msg, cause error) {
+	dlq := nats.NewMsg(subject)
+	for k, v := range msg.Headers() {
+		dlq.Header[k] = v
+	}
+	dlq.Header.Set("Nats-Dead-Letter-Subject", msg.Subject())
+	dlq.Header.Set("Nats-Dead-Letter-Error", cause.Error())
+	dlq.Data = msg.Data()
+	if _, err := js.PublishMsg(ctx, dlq); err != nil {
+		log.Printf("%s: publishing dead letter: %v", consumer, err)
+		msg.Nak()
+		return
+	}
+	metrics.Add(consumer+".dead_lettered", 1)
+	if err := msg.Term(); err != nil {
+		log.Printf("%s: terminating message: %v", consumer, err)
+	}
+}
+{{- end}}
+{{- range $svc := .Services}}
+
+func add{{ident .Name}}Service(nc *nats.Conn) (micro.Service, error) {
+	svc, err := micro.AddService(nc, micro.Config{
+		Name:        {{quote .Name}},
+		Version:     {{if .Version}}{{quote .Version}}{{else}}"0.1.0"{{end}},
+{{- if .Description}}
+		Description: {{quote .Description}},
+{{- end}}
+	})
+	if err != nil {
+		return nil, err
+	}
+{{- range .Endpoints}}
+	if err := svc.AddEndpoint({{quote .Name}}, micro.HandlerFunc(handle{{ident $svc.Name}}{{ident .Name}}Request), micro.WithEndpointSubject({{if .Subject}}{{quote .Subject}}{{else}}{{quote .Name}}{{end}})); err != nil {
+		return nil, err
+	}
+{{- end}}
+	return svc, nil
+}
+{{- range .Endpoints}}
+
+// handle{{ident $svc.Name}}{{ident .Name}}Request handles requests of endpoint {{quote .Name}}.
+func handle{{ident $svc.Name}}{{ident .Name}}Request(req micro.Request) {
+	metrics.Add({{quote (print $svc.Name "." .Name ".requests")}}, 1)
+	// TODO: handle the request
+	req.Error("501", "not implemented", nil)
+}
+{{- end}}
+{{- end}}
+`
@@ -0,0 +1,182 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nats
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	crand "crypto/rand"
+	"fmt"
+	"io"
+	"runtime"
+
+	"github.com/nats-io/nkeys"
+)
+
+// sealedSecret holds secret material encrypted with a random key, so that
+// the plaintext is only present in memory while it is used.
+//
+// On Linux and macOS the key and the decrypted plaintext are kept in
+// dedicated page-aligned mappings outside the Go heap, locked so that they
+// are not swapped to disk; only the cipher derived from the key is briefly
+// on the heap while sealing or opening. This protects the secret from leaking through
+// swap, through heap contents exposed by e.g. a heap profile or a buffer
+// reused after a bug, and keeps the plaintext short-lived. It does not
+// protect against an attacker able to read the whole process memory, e.g.
+// a debugger, /proc/<pid>/mem or a full core dump: the key and the
+// ciphertext can be found there and the secret decrypted. On other
+// platforms memory is not locked and the key is kept on the Go heap.
+type sealedSecret struct {
+	key    []byte
+	sealed []byte
+}
+
+// SealedUserCredentials is like [UserCredentials], but reads the JWT and
+// seed once and keeps them encrypted in memory. They are only decrypted
+// while connecting, so changes to the files are not picked up on reconnect.
+func SealedUserCredentials(userOrChainedFile string, seedFiles ...string) Option {
+	return func(o *Options) error {
+		ujwt, err := userFromFile(userOrChainedFile)
+		if err != nil {
+			return err
+		}
+		keyFile := userOrChainedFile
+		if len(seedFiles) > 0 {
+			keyFile = seedFiles[0]
+		}
+		kp, err := nkeyPairFromSeedFile(keyFile)
+		if err != nil {
+			return fmt.Errorf("unable to extract key pair from file %q: %w", keyFile, err)
+		}
+		defer kp.Wipe()
+		seed, err := kp.Seed()
+		if err != nil {
+			return err
+		}
+		defer wipeSlice(seed)
+		return sealedUserJWT([]byte(ujwt), seed)(o)
+	}
+}
+
+// SealedUserJWTAndSeed is like [UserJWTAndSeed], but keeps the JWT and
+// seed encrypted in memory, only decrypted while connecting. The seed is
+// wiped once sealed.
+func SealedUserJWTAndSeed(jwt string, seed []byte) Option {
+	return func(o *Options) error {
+		defer wipeSlice(seed)
+		return sealedUserJWT([]byte(jwt), seed)(o)
+	}
+}
+
+func sealedUserJWT(ujwt, seed []byte) Option {
+	return func(o *Options) error {
+		if _, err := nkeys.FromSeed(seed); err != nil {
+			return fmt.Errorf("unable to extract key pair from seed: %w", err)
+		}
+		sealedJWT, err := sealSecret(ujwt)
+		if err != nil {
+			return err
+		}
+		sealedSeed, err := sealSecret(seed)
+		if err != nil {
+			return err
+		}
+		userCB := func() (string, error) {
+			var ujwt string
+			err := sealedJWT.open(func(plain []byte) error {
+				// the JWT is not secret without the seed,
+				// the copy handed out is not wiped
+				ujwt = string(plain)
+				return nil
+			})
+			return ujwt, err
+		}
+		sigCB := func(nonce []byte) ([]byte, error) {
+			var sig []byte
+			err := sealedSeed.open(func(seed []byte) error {
+				kp, err := nkeys.FromSeed(seed)
+				if err != nil {
+					return fmt.Errorf("unable to extract key pair from seed: %w", err)
+				}
+				// Wipe our key on exit.
+				defer kp.Wipe()
+				sig, err = kp.Sign(nonce)
+				return err
+			})
+			return sig, err
+		}
+		return UserJWT(userCB, sigCB)(o)
+	}
+}
+
+// sealSecret encrypts a copy of the secret, the caller should wipe it.
+func sealSecret(secret []byte) (*sealedSecret, error) {
+	key, err := allocLocked(32)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(crand.Reader, key); err != nil {
+		freeLocked(key)
+		return nil, fmt.Errorf("nats: %w", err)
+	}
+	aead, err := newSealCipher(key)
+	if err != nil {
+		freeLocked(key)
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(crand.Reader, nonce); err != nil {
+		freeLocked(key)
+		return nil, fmt.Errorf("nats: %w", err)
+	}
+	s := &sealedSecret{key: key, sealed: aead.Seal(nonce, nonce, secret, nil)}
+	runtime.SetFinalizer(s, (*sealedSecret).destroy)
+	return s, nil
+}
+
+// open decrypts the secret into locked memory for the duration of fn,
+// wiping it afterwards.
+func (s *sealedSecret) open(fn func([]byte) error) error {
+	aead, err := newSealCipher(s.key)
+	if err != nil {
+		return err
+	}
+	n := aead.NonceSize()
+	buf, err := allocLocked(len(s.sealed) - n - aead.Overhead())
+	if err != nil {
+		return err
+	}
+	defer freeLocked(buf)
+	plain, err := aead.Open(buf[:0], s.sealed[:n], s.sealed[n:], nil)
+	if err != nil {
+		return fmt.Errorf("nats: unable to open sealed secret: %w", err)
+	}
+	return fn(plain)
+}
+
+func (s *sealedSecret) destroy() {
+	freeLocked(s.key)
+}
+
+func newSealCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("nats: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("nats: %w", err)
+	}
+	return aead, nil
+}
@@ -0,0 +1,129 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nats
+
+// ServerInfoChange describes how the server info changed, either when the
+// server sent an updated INFO, e.g. on cluster topology changes, or after
+// reconnecting, possibly to an upgraded or different server.
+type ServerInfoChange struct {
+	// PrevServerID and ServerID identify the servers the previous and
+	// the current info were received from. They differ after reconnecting
+	// to another server.
+	PrevServerID string
+	ServerID     string
+
+	// PrevVersion and Version are the versions of the servers.
+	PrevVersion string
+	Version     string
+
+	// PrevMaxPayload and MaxPayload are the maximum payload sizes
+	// accepted by the servers.
+	PrevMaxPayload int64
+	MaxPayload     int64
+
+	// AddedURLs are the client connect URLs advertised by the cluster
+	// which were not in the server pool yet, RemovedURLs the ones no
+	// longer advertised.
+	AddedURLs   []string
+	RemovedURLs []string
+
+	// LameDuckMode is set if the server entered lame duck mode.
+	LameDuckMode bool
+}
+
+// ServerInfoHandler is used to process changes of the server info.
+type ServerInfoHandler func(*Conn, ServerInfoChange)
+
+// ServerInfoChangeHandler is an Option to set the handler invoked when the
+// server info changes. It is not invoked for the info received on the
+// initial connect.
+func ServerInfoChangeHandler(cb ServerInfoHandler) Option {
+	return func(o *Options) error {
+		o.ServerInfoCB = cb
+		return nil
+	}
+}
+
+// SetServerInfoChangeHandler will set the server info change handler.
+func (nc *Conn) SetServerInfoChangeHandler(cb ServerInfoHandler) {
+	if nc == nil {
+		return
+	}
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	nc.Opts.ServerInfoCB = cb
+}
+
+// ServerInfoChangeHandler will return the server info change handler.
+func (nc *Conn) ServerInfoChangeHandler() ServerInfoHandler {
+	if nc == nil {
+		return nil
+	}
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	return nc.Opts.ServerInfoCB
+}
+
+// VersionChanged returns true if the server version changed.
+func (c ServerInfoChange) VersionChanged() bool {
+	return c.PrevVersion != c.Version
+}
+
+// MaxPayloadShrank returns true if the maximum payload size decreased.
+func (c ServerInfoChange) MaxPayloadShrank() bool {
+	return c.MaxPayload < c.PrevMaxPayload
+}
+
+// serverInfoChange returns the change between the current info of the
+// connection and the given one and whether anything the application may
+// react to changed. Added URLs are the ones not yet in the server pool.
+// If the new info does not list connect URLs, URL changes are not
+// reported, as servers may not advertise them.
+// Lock is held on entry.
+func (nc *Conn) serverInfoChange(info *serverInfo) (ServerInfoChange, bool) {
+	prev := &nc.info
+	change := ServerInfoChange{
+		PrevServerID:   prev.ID,
+		ServerID:       info.ID,
+		PrevVersion:    prev.Version,
+		Version:        info.Version,
+		PrevMaxPayload: prev.MaxPayload,
+		MaxPayload:     info.MaxPayload,
+		LameDuckMode:   info.LameDuckMode && !prev.LameDuckMode,
+	}
+	if len(info.ConnectURLs) > 0 {
+		known := make(map[string]struct{}, len(nc.srvPool))
+		for _, srv := range nc.srvPool {
+			known[srv.url.Host] = struct{}{}
+		}
+		cur := make(map[string]struct{}, len(info.ConnectURLs))
+		for _, u := range info.ConnectURLs {
+			cur[u] = struct{}{}
+			if _, ok := known[u]; !ok {
+				change.AddedURLs = append(change.AddedURLs, u)
+			}
+		}
+		for _, u := range prev.ConnectURLs {
+			if _, ok := cur[u]; !ok {
+				change.RemovedURLs = append(change.RemovedURLs, u)
+			}
+		}
+	}
+	changed := change.PrevServerID != change.ServerID ||
+		change.VersionChanged() ||
+		change.PrevMaxPayload != change.MaxPayload ||
+		len(change.AddedURLs) > 0 || len(change.RemovedURLs) > 0 ||
+		change.LameDuckMode
+	return change, changed
+}
@@ -0,0 +1,174 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package subjects validates NATS subjects and compares subjects which can
+// contain wildcards, using the same semantics as the server: a "*" token
+// matches any single token and a ">" token, only valid as the last token,
+// matches one or more tokens. Tokens merely containing those characters,
+// such as "foo*", are literal.
+package subjects
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Notice: Experimental Preview
+//
+// This functionality is EXPERIMENTAL and may be changed in later releases.
+
+const (
+	// Wildcard matches any single token.
+	Wildcard = "*"
+	// FullWildcard matches one or more tokens, only valid as the last token.
+	FullWildcard = ">"
+
+	separator  = "."
+	whitespace = " \t\n\f\r"
+)
+
+// ErrInvalidSubject is returned by [Validate] for subjects the server
+// would reject.
+var ErrInvalidSubject = errors.New("invalid subject")
+
+// Validate returns an error wrapping [ErrInvalidSubject] if the subject is
+// empty, has empty tokens or contains whitespace. Wildcard tokens are only
+// allowed if wildcards is set, in which case ">" has to be the last token.
+func Validate(subject string, wildcards bool) error {
+	if subject == "" {
+		return fmt.Errorf("%w: subject cannot be empty", ErrInvalidSubject)
+	}
+	if strings.ContainsAny(subject, whitespace) {
+		return fmt.Errorf("%w: %q contains whitespace", ErrInvalidSubject, subject)
+	}
+	tokens := strings.Split(subject, separator)
+	for i, token := range tokens {
+		switch token {
+		case "":
+			return fmt.Errorf("%w: %q contains an empty token", ErrInvalidSubject, subject)
+		case Wildcard, FullWildcard:
+			if !wildcards {
+				return fmt.Errorf("%w: %q contains wildcards", ErrInvalidSubject, subject)
+			}
+			if token == FullWildcard && i != len(tokens)-1 {
+				return fmt.Errorf("%w: %q contains %q before the last token", ErrInvalidSubject, subject, FullWildcard)
+			}
+		}
+	}
+	return nil
+}
+
+// Valid reports whether [Validate] accepts the subject.
+func Valid(subject string, wildcards bool) bool {
+	return Validate(subject, wildcards) == nil
+}
+
+// HasWildcard reports whether the subject contains a wildcard token.
+func HasWildcard(subject string) bool {
+	for _, token := range strings.Split(subject, separator) {
+		if token == Wildcard || token == FullWildcard {
+			return true
+		}
+	}
+	return false
+}
+
+// Match reports whether the literal subject matches pattern, which can
+// contain wildcards.
+func Match(pattern, subject string) bool {
+	pts := strings.Split(pattern, separator)
+	sts := strings.Split(subject, separator)
+	for i, pt := range pts {
+		if pt == FullWildcard {
+			return i < len(sts)
+		}
+		if i >= len(sts) || (pt != Wildcard && pt != sts[i]) {
+			return false
+		}
+	}
+	return len(pts) == len(sts)
+}
+
+// IsSubset reports whether every subject matched by subset is also matched
+// by superset, both of which can contain wildcards.
+func IsSubset(subset, superset string) bool {
+	sts := strings.Split(subset, separator)
+	pts := strings.Split(superset, separator)
+	for i, pt := range pts {
+		if pt == FullWildcard {
+			return i < len(sts)
+		}
+		if i >= len(sts) || sts[i] == FullWildcard {
+			return false
+		}
+		if pt != Wildcard && (sts[i] == Wildcard || sts[i] != pt) {
+			return false
+		}
+	}
+	return len(pts) == len(sts)
+}
+
+// Overlap reports whether any subject is matched by both a and b, both of
+// which can contain wildcards.
+func Overlap(a, b string) bool {
+	ats := strings.Split(a, separator)
+	bts := strings.Split(b, separator)
+	for i := 0; i < len(ats) && i < len(bts); i++ {
+		if ats[i] == FullWildcard || bts[i] == FullWildcard {
+			return true
+		}
+		if ats[i] != Wildcard && bts[i] != Wildcard && ats[i] != bts[i] {
+			return false
+		}
+	}
+	return len(ats) == len(bts)
+}
+
+// Covering returns the minimal set of patterns matching the same subjects
+// as all patterns, i.e. the sorted and deduplicated patterns which are not
+// a subset of another pattern. The given slice is not modified.
+func Covering(patterns []string) []string {
+	sorted := append([]string(nil), patterns...)
+	sort.Strings(sorted)
+	cover := make([]string, 0, len(sorted))
+	for i, p := range sorted {
+		if i > 0 && sorted[i-1] == p {
+			continue
+		}
+		covered := false
+		for _, q := range sorted {
+			if q != p && IsSubset(p, q) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			cover = append(cover, p)
+		}
+	}
+	return cover
+}
+
+// CoveredBy reports whether subject is a subset of any of the patterns, as
+// used to decide whether a subscription on one of the patterns receives all
+// messages on subject.
+func CoveredBy(subject string, patterns []string) bool {
+	for _, p := range patterns {
+		if IsSubset(subject, p) {
+			return true
+		}
+	}
+	return false
+}
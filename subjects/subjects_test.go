@@ -0,0 +1,199 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package subjects
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		subject   string
+		wildcards bool
+		valid     bool
+	}{
+		{"events.orders", false, true},
+		{"events.orders", true, true},
+		{"events.*", true, true},
+		{"events.>", true, true},
+		{"events.*", false, false},
+		{"events.>", false, false},
+		{"events.b*", false, true},
+		{"events.>x", false, true},
+		{"", true, false},
+		{"events..x", true, false},
+		{".events", true, false},
+		{"events.", true, false},
+		{"events.>.x", true, false},
+		{"events orders", true, false},
+		{"events\torders", true, false},
+	}
+	for _, test := range tests {
+		err := Validate(test.subject, test.wildcards)
+		if test.valid && err != nil {
+			t.Fatalf("Subject %q: unexpected error: %v", test.subject, err)
+		}
+		if !test.valid && !errors.Is(err, ErrInvalidSubject) {
+			t.Fatalf("Subject %q: expected error: %v; got: %v", test.subject, ErrInvalidSubject, err)
+		}
+		if res := Valid(test.subject, test.wildcards); res != test.valid {
+			t.Fatalf("Subject %q: expected %t; got: %t", test.subject, test.valid, res)
+		}
+	}
+}
+
+func TestHasWildcard(t *testing.T) {
+	for subject, expected := range map[string]bool{
+		"foo":       false,
+		"foo.bar":   false,
+		"foo.*":     true,
+		"foo.>":     true,
+		"foo.b*":    false,
+		"*":         true,
+		">":         true,
+		"foo.*.bar": true,
+		"foo.>bar":  false,
+	} {
+		if res := HasWildcard(subject); res != expected {
+			t.Fatalf("Subject %q: expected %t; got: %t", subject, expected, res)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, subject string
+		expected         bool
+	}{
+		{"foo.bar", "foo.bar", true},
+		{"foo.bar", "foo.baz", false},
+		{"foo.*", "foo.bar", true},
+		{"foo.*", "foo", false},
+		{"foo.*", "foo.bar.baz", false},
+		{"foo.>", "foo.bar.baz", true},
+		{"foo.>", "foo", false},
+		{"*.bar", "foo.bar", true},
+		{">", "foo", true},
+		{"foo.b*", "foo.bar", false},
+	}
+	for _, test := range tests {
+		if res := Match(test.pattern, test.subject); res != test.expected {
+			t.Fatalf("Invalid result for %q and %q; want: %t; got: %t", test.pattern, test.subject, test.expected, res)
+		}
+	}
+}
+
+func TestIsSubset(t *testing.T) {
+	tests := []struct {
+		subset, superset string
+		expected         bool
+	}{
+		{"events.orders", "events.orders", true},
+		{"events.users", "events.orders", false},
+		{"events.orders", "events.*", true},
+		{"events.*", "events.*", true},
+		{"events.>", "events.*", false},
+		{"events.orders.created", "events.*", false},
+		{"events.orders.created", "events.>", true},
+		{"events.*.created", "events.>", true},
+		{"events.>", "events.>", true},
+		{"events", "events.>", false},
+		{"events.*.deleted", "events.*.created", false},
+		{"anything.at.all", ">", true},
+		{"events.*", "events.orders", false},
+		{">", "events.>", false},
+	}
+	for _, test := range tests {
+		if res := IsSubset(test.subset, test.superset); res != test.expected {
+			t.Fatalf("Invalid result for %q and %q; want: %t; got: %t", test.subset, test.superset, test.expected, res)
+		}
+	}
+}
+
+func TestOverlap(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"foo.bar", "foo.bar", true},
+		{"foo.bar", "foo.baz", false},
+		{"foo.*", "*.bar", true},
+		{"foo.*", "foo", false},
+		{"foo.>", "foo", false},
+		{"foo.>", "foo.bar.baz", true},
+		{">", "foo", true},
+		{"foo.*.baz", "foo.bar.*", true},
+		{"foo.*", "foo.bar.baz", false},
+	}
+	for _, test := range tests {
+		if res := Overlap(test.a, test.b); res != test.expected {
+			t.Fatalf("Invalid result for %q and %q; want: %t; got: %t", test.a, test.b, test.expected, res)
+		}
+		if res := Overlap(test.b, test.a); res != test.expected {
+			t.Fatalf("Invalid result for %q and %q; want: %t; got: %t", test.b, test.a, test.expected, res)
+		}
+	}
+}
+
+func TestCovering(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		expected []string
+	}{
+		{
+			name:     "empty",
+			patterns: nil,
+			expected: []string{},
+		},
+		{
+			name:     "disjoint",
+			patterns: []string{"orders.new", "invoices.*"},
+			expected: []string{"invoices.*", "orders.new"},
+		},
+		{
+			name:     "duplicates",
+			patterns: []string{"orders.*", "orders.*"},
+			expected: []string{"orders.*"},
+		},
+		{
+			name:     "covered by wildcards",
+			patterns: []string{"orders.new", "orders.*", "orders.*.eu", "orders.>"},
+			expected: []string{"orders.>"},
+		},
+		{
+			name:     "overlapping but not covered",
+			patterns: []string{"orders.*.eu", "orders.new.*"},
+			expected: []string{"orders.*.eu", "orders.new.*"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patterns := append([]string(nil), test.patterns...)
+			if res := Covering(patterns); !reflect.DeepEqual(res, test.expected) {
+				t.Fatalf("Expected %v; got: %v", test.expected, res)
+			}
+			if !reflect.DeepEqual(patterns, test.patterns) {
+				t.Fatalf("Patterns were modified: %v", patterns)
+			}
+			for _, p := range test.patterns {
+				if !CoveredBy(p, test.expected) {
+					t.Fatalf("Expected %q to be covered by %v", p, test.expected)
+				}
+			}
+		})
+	}
+}
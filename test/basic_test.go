@@ -757,6 +757,66 @@ func TestSimultaneousRequests(t *testing.T) {
 	checkErrChannel(t, errCh)
 }
 
+func TestRequestMuxPartitions(t *testing.T) {
+	s := RunDefaultServer()
+	defer s.Shutdown()
+
+	if _, err := nats.Connect(s.ClientURL(), nats.RequestMuxPartitions(0)); err == nil {
+		t.Fatal("Expected error for invalid number of partitions")
+	}
+	nc, err := nats.Connect(s.ClientURL(), nats.RequestMuxPartitions(4))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+
+	nc.Subscribe("foo", func(m *nats.Msg) {
+		m.Respond(m.Data)
+	})
+
+	wg := sync.WaitGroup{}
+	wg.Add(100)
+	errCh := make(chan error, 100)
+	for i := 0; i < 100; i++ {
+		go func(i int) {
+			defer wg.Done()
+			data := []byte(fmt.Sprintf("req-%d", i))
+			resp, err := nc.Request("foo", data, 2*time.Second)
+			if err != nil {
+				errCh <- fmt.Errorf("Error on request: %v", err)
+				return
+			}
+			if !bytes.Equal(resp.Data, data) {
+				errCh <- fmt.Errorf("Expected response %q; got: %q", data, resp.Data)
+			}
+		}(i)
+	}
+	wg.Wait()
+	checkErrChannel(t, errCh)
+
+	// The responder and one subscription per partition.
+	if n := nc.NumSubscriptions(); n != 5 {
+		t.Fatalf("Expected 5 subscriptions; got: %d", n)
+	}
+	if res := nc.ActiveResources(); res.Subscriptions != 1 {
+		t.Fatalf("Expected 1 user subscription; got: %d", res.Subscriptions)
+	}
+	if inbox := nc.NewRespInbox(); len(strings.Split(inbox, ".")) != 4 {
+		t.Fatalf("Expected partition token in response inbox; got: %q", inbox)
+	}
+
+	closed := make(chan struct{})
+	nc.SetClosedHandler(func(*nats.Conn) { close(closed) })
+	if err := nc.Drain(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Connection was not closed after drain")
+	}
+}
+
 func TestRequestClose(t *testing.T) {
 	s := RunDefaultServer()
 	defer s.Shutdown()
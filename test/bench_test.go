@@ -82,6 +82,51 @@ func BenchmarkPubSubSpeed(b *testing.B) {
 	b.StopTimer()
 }
 
+func BenchmarkPubSubHeadersSpeed(b *testing.B) {
+	b.StopTimer()
+	s := RunDefaultServer()
+	defer s.Shutdown()
+	nc := NewDefaultConnection(b)
+	defer nc.Close()
+
+	ch := make(chan bool)
+
+	nc.SetErrorHandler(func(nc *nats.Conn, s *nats.Subscription, err error) {
+		b.Fatalf("Error : %v\n", err)
+	})
+
+	received := int32(0)
+
+	nc.Subscribe("foo", func(m *nats.Msg) {
+		if nr := atomic.AddInt32(&received, 1); nr >= int32(b.N) {
+			ch <- true
+		}
+	})
+
+	msg := nats.NewMsg("foo")
+	msg.Data = []byte("Hello World")
+	msg.Header.Set("Nats-Msg-Id", "1234567890")
+	msg.Header.Set("Content-Type", "text/plain")
+
+	b.ReportAllocs()
+	b.StartTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := nc.PublishMsg(msg); err != nil {
+			b.Fatalf("Error in benchmark during Publish: %v\n", err)
+		}
+	}
+
+	// Make sure they are all processed.
+	err := WaitTime(ch, 10*time.Second)
+	if err != nil {
+		b.Fatal("Timed out waiting for messages")
+	} else if atomic.LoadInt32(&received) != int32(b.N) {
+		b.Fatalf("Received: %d, err:%v", received, nc.LastError())
+	}
+	b.StopTimer()
+}
+
 func BenchmarkAsyncSubscriptionCreationSpeed(b *testing.B) {
 	b.StopTimer()
 	s := RunDefaultServer()
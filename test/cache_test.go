@@ -0,0 +1,115 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestResponseCache(t *testing.T) {
+	s := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, s)
+
+	nc, js := jsClient(t, s)
+	defer nc.Close()
+
+	var served int32
+	if _, err := nc.Subscribe("catalog.>", func(m *nats.Msg) {
+		n := atomic.AddInt32(&served, 1)
+		m.Respond([]byte(strconv.Itoa(int(n))))
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	kv, err := js.CreateKeyValue(&nats.KeyValueConfig{Bucket: "CATALOG"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	cache, err := nc.NewResponseCache(
+		nats.CacheTTL(200*time.Millisecond),
+		nats.CacheMaxEntries(2),
+		nats.CacheInvalidationSubject("invalidate.catalog"),
+		nats.CacheInvalidationKV(kv),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer cache.Stop()
+
+	expect := func(t *testing.T, subj, data, expected string) {
+		t.Helper()
+		msg, err := cache.Request(subj, []byte(data), time.Second)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if string(msg.Data) != expected {
+			t.Fatalf("Expected response %q; got: %q", expected, msg.Data)
+		}
+	}
+	waitInvalidated := func(t *testing.T, len int) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for cache.Len() != len {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected %d cached responses; got: %d", len, cache.Len())
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	expect(t, "catalog.items.1", "", "1")
+	expect(t, "catalog.items.1", "", "1")
+	// Different payload is cached separately.
+	expect(t, "catalog.items.1", "v2", "2")
+	if cache.Hits() != 1 || cache.Misses() != 2 {
+		t.Fatalf("Unexpected hits/misses: %d/%d", cache.Hits(), cache.Misses())
+	}
+
+	// Entries expire after TTL.
+	time.Sleep(250 * time.Millisecond)
+	expect(t, "catalog.items.1", "", "3")
+
+	// Invalidation subject drops matching entries.
+	expect(t, "catalog.items.2", "", "4")
+	if err := nc.Publish("invalidate.catalog", []byte("catalog.items.*")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	waitInvalidated(t, 0)
+	expect(t, "catalog.items.2", "", "5")
+
+	// KV updates drop entries for the subject equal to the key.
+	if _, err := kv.PutString("catalog.items.2", "changed"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	waitInvalidated(t, 0)
+	expect(t, "catalog.items.2", "", "6")
+
+	// Number of entries is bounded.
+	expect(t, "catalog.items.3", "", "7")
+	expect(t, "catalog.items.4", "", "8")
+	if cache.Len() != 2 {
+		t.Fatalf("Expected 2 cached responses; got: %d", cache.Len())
+	}
+
+	cache.Invalidate("")
+	if cache.Len() != 0 {
+		t.Fatalf("Expected empty cache; got: %d", cache.Len())
+	}
+}
@@ -0,0 +1,292 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestCoalesceRequests(t *testing.T) {
+	s := RunDefaultServer()
+	defer s.Shutdown()
+
+	nc, err := nats.Connect(nats.DefaultURL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	r := nats.NewCoalescingRequester(nc)
+
+	var received int32
+	sub, err := nc.Subscribe("lookup", func(m *nats.Msg) {
+		atomic.AddInt32(&received, 1)
+		time.Sleep(100 * time.Millisecond)
+		m.Respond(append([]byte("re: "), m.Data...))
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer sub.Unsubscribe()
+	nc.Flush()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		payload := "a"
+		if i%2 == 1 {
+			payload = "b"
+		}
+		go func(payload string, useCtx bool) {
+			defer wg.Done()
+			var msg *nats.Msg
+			var err error
+			if useCtx {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+				defer cancel()
+				msg, err = r.RequestWithContext(ctx, "lookup", []byte(payload))
+			} else {
+				msg, err = r.Request("lookup", []byte(payload), time.Second)
+			}
+			if err != nil {
+				errs <- err
+				return
+			}
+			if string(msg.Data) != "re: "+payload {
+				errs <- nats.ErrBadSubscription
+			}
+			// Responses are not shared between callers.
+			msg.Data[0] = 'x'
+		}(payload, i%4 < 2)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n := atomic.LoadInt32(&received); n != 2 {
+		t.Fatalf("Expected 2 requests on the wire, got %d", n)
+	}
+
+	// A caller giving up does not fail the others waiting for the same response.
+	atomic.StoreInt32(&received, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, err := r.RequestWithContext(ctx, "lookup", []byte("c"))
+		done <- err
+	}()
+	time.Sleep(5 * time.Millisecond)
+	msg, err := r.Request("lookup", []byte("c"), time.Second)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(msg.Data) != "re: c" {
+		t.Fatalf("Unexpected response: %q", msg.Data)
+	}
+	if err := <-done; err != context.DeadlineExceeded {
+		t.Fatalf("Expected error: %v; got: %v", context.DeadlineExceeded, err)
+	}
+	if n := atomic.LoadInt32(&received); n != 1 {
+		t.Fatalf("Expected 1 request on the wire, got %d", n)
+	}
+
+	// Errors are reported to all callers as usual.
+	if _, err := r.Request("nobody", nil, 50*time.Millisecond); err != nats.ErrNoResponders {
+		t.Fatalf("Expected error: %v; got: %v", nats.ErrNoResponders, err)
+	}
+
+	// Requests made directly on the connection are not coalesced.
+	atomic.StoreInt32(&received, 0)
+	requestConcurrently(t, 2, func() (*nats.Msg, error) {
+		return nc.Request("lookup", []byte("d"), time.Second)
+	})
+	if n := atomic.LoadInt32(&received); n != 2 {
+		t.Fatalf("Expected 2 requests on the wire, got %d", n)
+	}
+}
+
+func TestCoalesceRequestsNotCoalesced(t *testing.T) {
+	s := RunDefaultServer()
+	defer s.Shutdown()
+
+	nc, err := nats.Connect(nats.DefaultURL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	r := nats.NewCoalescingRequester(nc)
+
+	var received int32
+	for _, subj := range []string{"$JS.>", "orders"} {
+		sub, err := nc.Subscribe(subj, func(m *nats.Msg) {
+			atomic.AddInt32(&received, 1)
+			time.Sleep(100 * time.Millisecond)
+			m.Respond([]byte("ok"))
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer sub.Unsubscribe()
+	}
+	nc.Flush()
+
+	tests := []struct {
+		name string
+		msg  func() *nats.Msg
+	}{
+		{"JetStream API", func() *nats.Msg { return &nats.Msg{Subject: "$JS.API.INFO"} }},
+		{"JetStream API with domain", func() *nats.Msg { return &nats.Msg{Subject: "$JS.hub.API.INFO"} }},
+		{"message ID", func() *nats.Msg {
+			m := nats.NewMsg("orders")
+			m.Header.Set(nats.MsgIdHdr, "1")
+			return m
+		}},
+		{"expected last sequence", func() *nats.Msg {
+			m := nats.NewMsg("orders")
+			m.Header.Set(nats.ExpectedLastSeqHdr, "1")
+			return m
+		}},
+		{"expected last subject sequence", func() *nats.Msg {
+			m := nats.NewMsg("orders")
+			m.Header.Set(nats.ExpectedLastSubjSeqHdr, "1")
+			return m
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			atomic.StoreInt32(&received, 0)
+			requestConcurrently(t, 2, func() (*nats.Msg, error) {
+				return r.RequestMsg(test.msg(), time.Second)
+			})
+			if n := atomic.LoadInt32(&received); n != 2 {
+				t.Fatalf("Expected 2 requests on the wire, got %d", n)
+			}
+		})
+	}
+}
+
+func TestCoalesceRequestsJetStreamPublish(t *testing.T) {
+	s := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, s)
+
+	nc, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	// Using a requester on the connection does not affect JetStream.
+	_ = nats.NewCoalescingRequester(nc)
+	js, err := nc.JetStream()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := js.AddStream(&nats.StreamConfig{Name: "ORDERS", Subjects: []string{"orders"}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	requestConcurrently(t, 2, func() (*nats.Msg, error) {
+		_, err := js.Publish("orders", []byte("same"))
+		return nil, err
+	})
+	info, err := js.StreamInfo("ORDERS")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info.State.Msgs != 2 {
+		t.Fatalf("Expected 2 messages stored, got %d", info.State.Msgs)
+	}
+}
+
+func TestCoalesceRequestsTraceContext(t *testing.T) {
+	s := RunDefaultServer()
+	defer s.Shutdown()
+
+	nc, err := nats.Connect(nats.DefaultURL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	r := nats.NewCoalescingRequester(nc)
+
+	var received int32
+	traceparents := make(chan string, 10)
+	sub, err := nc.Subscribe("lookup", func(m *nats.Msg) {
+		atomic.AddInt32(&received, 1)
+		traceparents <- m.Header.Get("traceparent")
+		time.Sleep(100 * time.Millisecond)
+		m.Respond([]byte("ok"))
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer sub.Unsubscribe()
+	nc.Flush()
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	trace := nats.TraceContext(context.Background(), nats.Header{"traceparent": []string{traceparent}})
+	ctx, cancel := context.WithTimeout(trace, time.Second)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, err := r.RequestWithContext(ctx, "lookup", []byte("a"))
+		done <- err
+	}()
+	select {
+	case tp := <-traceparents:
+		if tp != traceparent {
+			t.Fatalf("Expected traceparent %q, got %q", traceparent, tp)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Did not receive the request")
+	}
+	// Joins the request sent with the trace headers of the first caller.
+	if _, err := r.Request("lookup", []byte("a"), time.Second); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n := atomic.LoadInt32(&received); n != 1 {
+		t.Fatalf("Expected 1 request on the wire, got %d", n)
+	}
+}
+
+// requestConcurrently calls request n times concurrently and fails on errors.
+func requestConcurrently(t *testing.T, n int, request func() (*nats.Msg, error)) {
+	t.Helper()
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := request(); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
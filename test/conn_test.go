@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"fmt"
 	"net"
 	"os"
@@ -726,6 +727,17 @@ func TestErrOnMaxPayloadLimit(t *testing.T) {
 	if err != nats.ErrMaxPayload {
 		t.Fatalf("Expected to fail trying to send more than max payload, got: %s", err)
 	}
+	err = nc.CheckMaxPayload(&nats.Msg{Subject: "hello", Data: []byte("hello world")})
+	if !errors.Is(err, nats.ErrMaxPayload) {
+		t.Fatalf("Expected error: %v; got: %v", nats.ErrMaxPayload, err)
+	}
+	var payloadErr *nats.ErrMaxPayloadExceeded
+	if !errors.As(err, &payloadErr) {
+		t.Fatalf("Expected error of type ErrMaxPayloadExceeded, got: %T", err)
+	}
+	if payloadErr.MaxPayload != expectedMaxPayload || payloadErr.Size != 11 {
+		t.Fatalf("Expected limit %d and size 11, got: %d and %d", expectedMaxPayload, payloadErr.MaxPayload, payloadErr.Size)
+	}
 	err = nc.Publish("hello", []byte("a"))
 	if err != nil {
 		t.Fatalf("Expected to succeed trying to send less than max payload, got: %s", err)
@@ -2863,3 +2875,79 @@ func TestConnStatusChangedEvents(t *testing.T) {
 		time.Sleep(100 * time.Millisecond)
 	})
 }
+
+func TestActiveResourcesAndLeakedSubscriptions(t *testing.T) {
+	s := RunDefaultServer()
+	defer s.Shutdown()
+
+	leaked := make(chan []*nats.Subscription, 1)
+	nc, err := nats.Connect(nats.DefaultURL, nats.LeakedSubscriptionsHandler(func(_ *nats.Conn, subs []*nats.Subscription) {
+		leaked <- subs
+	}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+
+	waitResources := func(t *testing.T, expected nats.Resources) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			r := nc.ActiveResources()
+			if r == expected {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected resources %+v; got: %+v", expected, r)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// readLoop, flusher and async callbacks dispatcher.
+	waitResources(t, nats.Resources{Goroutines: 3})
+
+	if _, err := nc.Subscribe("svc", func(m *nats.Msg) { m.Respond(nil) }); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	forgotten, err := nc.Subscribe("foo", func(*nats.Msg) {})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	unsubscribed, err := nc.Subscribe("bar", func(*nats.Msg) {})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	syncSub, err := nc.SubscribeSync("baz")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Response subscription is not reported.
+	if _, err := nc.Request("svc", nil, time.Second); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	waitResources(t, nats.Resources{Subscriptions: 4, Goroutines: 7})
+
+	if err := unsubscribed.Unsubscribe(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	waitResources(t, nats.Resources{Subscriptions: 3, Goroutines: 6})
+
+	nc.Close()
+	select {
+	case subs := <-leaked:
+		if len(subs) != 3 {
+			t.Fatalf("Expected 3 leaked subscriptions; got: %d", len(subs))
+		}
+		found := make(map[*nats.Subscription]bool)
+		for _, sub := range subs {
+			found[sub] = true
+		}
+		if !found[forgotten] || !found[syncSub] || found[unsubscribed] {
+			t.Fatalf("Unexpected leaked subscriptions: %v", subs)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Leaked subscriptions were not reported")
+	}
+	waitResources(t, nats.Resources{})
+}
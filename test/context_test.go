@@ -1140,3 +1140,146 @@ func TestUnsubscribeAndNextMsgWithContext(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestSubscribeWithLifetime(t *testing.T) {
+	s := RunDefaultServer()
+	defer s.Shutdown()
+
+	nc := NewDefaultConnection(t)
+	defer nc.Close()
+
+	//lint:ignore SA1012 testing that passing nil fails
+	if _, err := nc.SubscribeWithLifetime(nil, "foo", func(*nats.Msg) {}); err != nats.ErrInvalidContext {
+		t.Fatalf("Expected error: %v; got: %v", nats.ErrInvalidContext, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var mu sync.Mutex
+	var received int
+	sub, err := nc.SubscribeWithLifetime(ctx, "foo", func(*nats.Msg) {
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		received++
+		mu.Unlock()
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		nc.Publish("foo", []byte("hello"))
+	}
+	nc.Flush()
+
+	// Canceling the context drains the subscription, messages already
+	// received are still processed.
+	cancel()
+	deadline := time.Now().Add(2 * time.Second)
+	for sub.IsValid() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Subscription was not drained")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	mu.Lock()
+	if received != 10 {
+		t.Fatalf("Expected 10 messages to be processed; got: %d", received)
+	}
+	mu.Unlock()
+
+	if _, err := nc.SubscribeWithLifetime(ctx, "foo", func(*nats.Msg) {}); err != context.Canceled {
+		t.Fatalf("Expected error: %v; got: %v", context.Canceled, err)
+	}
+}
+
+func TestTraceContext(t *testing.T) {
+	ctx := context.Background()
+	if got := nats.TraceContext(ctx, nats.Header{"Foo": []string{"bar"}}); got != ctx {
+		t.Fatalf("Expected context to be unchanged without trace headers")
+	}
+	if hdr := nats.TraceHeadersFromContext(ctx); hdr != nil {
+		t.Fatalf("Expected no trace headers; got: %v", hdr)
+	}
+
+	hdr := nats.Header{
+		"Traceparent":     []string{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		"tracestate":      []string{"vendor=value"},
+		"Nats-Trace-Dest": []string{"traces"},
+		"Nats-Trace-Only": []string{"true"},
+		"Foo":             []string{"bar"},
+	}
+	ctx = nats.TraceContext(ctx, hdr)
+	got := nats.TraceHeadersFromContext(ctx)
+	if len(got) != 3 {
+		t.Fatalf("Expected 3 trace headers; got: %v", got)
+	}
+	if got.Get("Traceparent") != hdr.Get("Traceparent") || got.Get("tracestate") != "vendor=value" || got.Get("Nats-Trace-Dest") != "traces" {
+		t.Fatalf("Unexpected trace headers: %v", got)
+	}
+
+	// returned headers are a copy
+	got.Set("tracestate", "changed")
+	hdr.Set("Nats-Trace-Dest", "changed")
+	if got := nats.TraceHeadersFromContext(ctx); got.Get("tracestate") != "vendor=value" || got.Get("Nats-Trace-Dest") != "traces" {
+		t.Fatalf("Expected trace headers to be unchanged; got: %v", got)
+	}
+}
+
+func TestTraceContextPublishAndRequest(t *testing.T) {
+	s := RunDefaultServer()
+	defer s.Shutdown()
+
+	nc, err := nats.Connect(nats.DefaultURL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+
+	sub, err := nc.Subscribe("svc", func(m *nats.Msg) {
+		m.RespondMsg(&nats.Msg{Header: m.Header})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer sub.Unsubscribe()
+	events, err := nc.SubscribeSync("events")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	origin := nats.Header{"traceparent": []string{traceparent}}
+	ctx, cancel := context.WithTimeout(nats.TraceContext(context.Background(), origin), time.Second)
+	defer cancel()
+
+	resp, err := nc.RequestWithContext(ctx, "svc", []byte("req"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := resp.Header.Get("traceparent"); got != traceparent {
+		t.Fatalf("Expected traceparent %q; got: %q", traceparent, got)
+	}
+
+	// Headers set on the message take precedence, other ones are kept.
+	msg := &nats.Msg{Subject: "svc", Header: nats.Header{"Traceparent": []string{"own"}, "Foo": []string{"bar"}}}
+	resp, err = nc.RequestMsgWithContext(ctx, msg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := resp.Header.Values("Traceparent"); len(got) != 1 || got[0] != "own" || resp.Header.Get("traceparent") != "" {
+		t.Fatalf("Expected own traceparent only; got: %v", resp.Header)
+	}
+	if resp.Header.Get("Foo") != "bar" || len(msg.Header) != 2 {
+		t.Fatalf("Unexpected headers: %v, message headers: %v", resp.Header, msg.Header)
+	}
+
+	if err := nc.PublishMsgWithContext(ctx, &nats.Msg{Subject: "events", Data: []byte("event")}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	event, err := events.NextMsg(time.Second)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := event.Header.Get("traceparent"); got != traceparent || string(event.Data) != "event" {
+		t.Fatalf("Unexpected event: %+v", event)
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestDispatcher(t *testing.T) {
+	s := RunDefaultServer()
+	defer s.Shutdown()
+
+	nc := NewDefaultConnection(t)
+	defer nc.Close()
+
+	d, err := nc.NewDispatcher("orders.>")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer d.Close()
+
+	if _, err := d.Handle("payments.*", func(*nats.Msg) {}); !errors.Is(err, nats.ErrInvalidArg) {
+		t.Fatalf("Expected error: %v; got: %v", nats.ErrInvalidArg, err)
+	}
+	if _, err := d.Handle("orders..foo", func(*nats.Msg) {}); !errors.Is(err, nats.ErrBadSubject) {
+		t.Fatalf("Expected error: %v; got: %v", nats.ErrBadSubject, err)
+	}
+
+	received := make(chan string, 100)
+	handle := func(name, pattern string) *nats.DispatchHandler {
+		t.Helper()
+		h, err := d.Handle(pattern, func(m *nats.Msg) {
+			received <- name + ":" + m.Subject
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return h
+	}
+	created := handle("created", "orders.*.created")
+	eu := handle("eu", "orders.eu.>")
+	euCreated := handle("eu-created", "orders.eu.created")
+
+	for i := 0; i < 100; i++ {
+		if _, err := d.Handle("orders.fine.grained", func(*nats.Msg) {}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if n := nc.NumSubscriptions(); n != 1 {
+		t.Fatalf("Expected a single subscription; got: %d", n)
+	}
+
+	publish := func(subjects ...string) {
+		t.Helper()
+		for _, subj := range subjects {
+			if err := nc.Publish(subj, []byte("data")); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+		if err := nc.Flush(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	expect := func(expected ...string) {
+		t.Helper()
+		got := make(map[string]int)
+		for range expected {
+			select {
+			case r := <-received:
+				got[r]++
+			case <-time.After(time.Second):
+				t.Fatalf("Did not receive all messages, expected: %v; got: %v", expected, got)
+			}
+		}
+		for _, e := range expected {
+			if got[e] == 0 {
+				t.Fatalf("Expected %q to be delivered; got: %v", e, got)
+			}
+			got[e]--
+		}
+		select {
+		case r := <-received:
+			t.Fatalf("Unexpected delivery: %q", r)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	publish("orders.eu.created", "orders.us.created", "orders.eu.shipped", "orders.us.shipped")
+	expect(
+		"created:orders.eu.created",
+		"eu:orders.eu.created",
+		"eu-created:orders.eu.created",
+		"created:orders.us.created",
+		"eu:orders.eu.shipped",
+	)
+	if unmatched := d.Unmatched(); unmatched != 1 {
+		t.Fatalf("Expected 1 unmatched message; got: %d", unmatched)
+	}
+	if stats := eu.Stats(); stats.Msgs != 2 || stats.Bytes != 8 {
+		t.Fatalf("Unexpected stats: %+v", stats)
+	}
+	if stats := created.Stats(); stats.Msgs != 2 {
+		t.Fatalf("Unexpected stats: %+v", stats)
+	}
+
+	eu.Remove()
+	euCreated.Remove()
+	publish("orders.eu.created", "orders.eu.shipped")
+	expect("created:orders.eu.created")
+	if stats := euCreated.Stats(); stats.Msgs != 1 {
+		t.Fatalf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestAutoDispatcher(t *testing.T) {
+	s := RunDefaultServer()
+	defer s.Shutdown()
+
+	nc := NewDefaultConnection(t)
+	defer nc.Close()
+
+	d := nc.NewAutoDispatcher()
+	defer d.Close()
+
+	received := make(chan string, 100)
+	handle := func(pattern string) *nats.DispatchHandler {
+		t.Helper()
+		h, err := d.Handle(pattern, func(m *nats.Msg) {
+			received <- pattern + ":" + m.Subject
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return h
+	}
+	checkSubjects := func(expected ...string) {
+		t.Helper()
+		subjects := d.Subjects()
+		if !reflect.DeepEqual(subjects, expected) {
+			t.Fatalf("Expected subjects %v; got: %v", expected, subjects)
+		}
+		if n := nc.NumSubscriptions(); n != len(expected) {
+			t.Fatalf("Expected %d subscriptions; got: %d", len(expected), n)
+		}
+	}
+	expect := func(subject string, expected ...string) {
+		t.Helper()
+		if err := nc.Publish(subject, nil); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		got := make([]string, 0, len(expected))
+		for range expected {
+			select {
+			case r := <-received:
+				got = append(got, r)
+			case <-time.After(time.Second):
+				t.Fatalf("Did not receive all messages, expected: %v; got: %v", expected, got)
+			}
+		}
+		select {
+		case r := <-received:
+			t.Fatalf("Unexpected delivery: %q", r)
+		case <-time.After(50 * time.Millisecond):
+		}
+		sort.Strings(got)
+		sort.Strings(expected)
+		if len(expected) > 0 && !reflect.DeepEqual(got, expected) {
+			t.Fatalf("Expected deliveries %v; got: %v", expected, got)
+		}
+	}
+
+	created := handle("orders.*.created")
+	handle("orders.eu.created")
+	handle("orders.eu.created")
+	shipped := handle("orders.eu.shipped")
+	checkSubjects("orders.*.created", "orders.eu.shipped")
+	expect("orders.eu.created", "orders.*.created:orders.eu.created",
+		"orders.eu.created:orders.eu.created", "orders.eu.created:orders.eu.created")
+
+	all := handle("orders.>")
+	checkSubjects("orders.>")
+	expect("orders.eu.shipped", "orders.>:orders.eu.shipped", "orders.eu.shipped:orders.eu.shipped")
+
+	if err := all.Remove(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	checkSubjects("orders.*.created", "orders.eu.shipped")
+	expect("orders.us.created", "orders.*.created:orders.us.created")
+
+	if err := created.Remove(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	checkSubjects("orders.eu.created", "orders.eu.shipped")
+	expect("orders.us.created")
+	if err := shipped.Remove(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	checkSubjects("orders.eu.created")
+}
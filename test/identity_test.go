@@ -0,0 +1,83 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+func TestClientIdentity(t *testing.T) {
+	s := RunDefaultServer()
+	defer s.Shutdown()
+
+	nc, err := nats.Connect(nats.DefaultURL,
+		nats.Name("ignored"),
+		nats.Identity(nats.ClientIdentity{Service: "orders", Version: "1.2.0", Instance: "pod-1"}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+
+	connz, err := s.Connz(&server.ConnzOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(connz.Conns) != 1 || connz.Conns[0].Name != "orders@1.2.0/pod-1" {
+		t.Fatalf("Unexpected connections: %+v", connz.Conns)
+	}
+	expected := map[string]string{
+		nats.IdentityServiceKey:  "orders",
+		nats.IdentityVersionKey:  "1.2.0",
+		nats.IdentityInstanceKey: "pod-1",
+	}
+	if md := nc.Identity().Metadata(); !reflect.DeepEqual(md, expected) {
+		t.Fatalf("Expected metadata: %v; got: %v", expected, md)
+	}
+
+	// instance defaults to host name
+	host, _ := os.Hostname()
+	nc2, err := nats.Connect(nats.DefaultURL, nats.Identity(nats.ClientIdentity{Service: "orders"}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc2.Close()
+	if name := nc2.Opts.Name; name != "orders/"+host {
+		t.Fatalf("Unexpected connection name: %q", name)
+	}
+
+	for _, id := range []nats.ClientIdentity{
+		{},
+		{Service: "orders/v1"},
+		{Service: "orders", Version: "1 2"},
+		{Service: "orders", Instance: "pod@1"},
+	} {
+		if _, err := nats.Connect(nats.DefaultURL, nats.Identity(id)); err == nil {
+			t.Fatalf("Expected error for identity: %+v", id)
+		}
+	}
+
+	nc3, err := nats.Connect(nats.DefaultURL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc3.Close()
+	if id := nc3.Identity(); id != nil {
+		t.Fatalf("Expected no identity; got: %+v", id)
+	}
+}
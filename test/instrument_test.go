@@ -0,0 +1,108 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+type testLogger struct {
+	sync.Mutex
+	lines []string
+}
+
+func (l *testLogger) Printf(format string, v ...interface{}) {
+	l.Lock()
+	defer l.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func TestInstrumentHandler(t *testing.T) {
+	s := RunDefaultServer()
+	defer s.Shutdown()
+
+	nc := NewDefaultConnection(t)
+	defer nc.Close()
+
+	obs := make(chan nats.HandlerObservation, 10)
+	metrics := nats.HandlerMetricsFunc(func(o nats.HandlerObservation) {
+		obs <- o
+	})
+	logger := &testLogger{}
+
+	handler := nats.InstrumentHandler(func(m *nats.Msg) {
+		if string(m.Data) == "boom" {
+			panic("bad message")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}, metrics, logger)
+
+	sub, err := nc.Subscribe("foo", handler)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	for _, data := range []string{"hello", "boom", "world!"} {
+		if err := nc.Publish("foo", []byte(data)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	expected := []struct {
+		size    int
+		outcome nats.HandlerOutcome
+	}{
+		{5, nats.HandlerOK},
+		{4, nats.HandlerPanicked},
+		{6, nats.HandlerOK},
+	}
+	for i, exp := range expected {
+		select {
+		case o := <-obs:
+			if o.Subject != "foo" {
+				t.Fatalf("Expected subject %q; got: %q", "foo", o.Subject)
+			}
+			if o.PayloadSize != exp.size {
+				t.Fatalf("Observation %d: expected payload size %d; got: %d", i, exp.size, o.PayloadSize)
+			}
+			if o.Outcome != exp.outcome {
+				t.Fatalf("Observation %d: expected outcome %v; got: %v", i, exp.outcome, o.Outcome)
+			}
+			if exp.outcome == nats.HandlerOK && o.Latency < 10*time.Millisecond {
+				t.Fatalf("Observation %d: expected latency of at least 10ms; got: %v", i, o.Latency)
+			}
+			if exp.outcome == nats.HandlerPanicked && o.Panic != "bad message" {
+				t.Fatalf("Observation %d: expected recovered panic; got: %v", i, o.Panic)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Did not receive observation %d", i)
+		}
+	}
+
+	logger.Lock()
+	defer logger.Unlock()
+	if len(logger.lines) != 1 || !strings.Contains(logger.lines[0], "bad message") {
+		t.Fatalf("Expected panic to be logged; got: %v", logger.lines)
+	}
+
+	// Nil metrics and logger are allowed.
+	nats.InstrumentHandler(func(*nats.Msg) { panic("ignored") }, nil, nil)(nats.NewMsg("foo"))
+}
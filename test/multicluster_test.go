@@ -0,0 +1,111 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestMultiClusterFailover(t *testing.T) {
+	primaryPort, standbyPort := 24222, 24223
+	ps := RunServerOnPort(primaryPort)
+	defer func() { ps.Shutdown() }()
+	ss := RunServerOnPort(standbyPort)
+	defer ss.Shutdown()
+
+	popts := nats.GetDefaultOptions()
+	popts.Url = fmt.Sprintf("nats://127.0.0.1:%d", primaryPort)
+	popts.MaxReconnect = -1
+	popts.ReconnectWait = 20 * time.Millisecond
+	sopts := nats.GetDefaultOptions()
+	sopts.Url = fmt.Sprintf("nats://127.0.0.1:%d", standbyPort)
+
+	failover := make(chan struct{}, 1)
+	failback := make(chan struct{}, 1)
+	var reconcileAttempts int32
+	mc, err := nats.ConnectMultiCluster(popts, sopts,
+		nats.FailoverWindow(100*time.Millisecond),
+		nats.FailoverCheckInterval(10*time.Millisecond),
+		nats.FailoverCB(func(from, to *nats.Conn) { failover <- struct{}{} }),
+		nats.FailbackCB(func(from, to *nats.Conn) { failback <- struct{}{} }),
+		nats.ReconcileCB(func(primary, standby *nats.Conn) error {
+			// Fail the first attempt to check that failback is postponed.
+			if atomic.AddInt32(&reconcileAttempts, 1) == 1 {
+				return errors.New("not yet")
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer mc.Close()
+
+	received := make(chan *nats.Msg, 10)
+	if _, err := mc.Subscribe("foo", func(m *nats.Msg) { received <- m }); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if mc.Active() != mc.Primary() {
+		t.Fatalf("Expected primary to be active")
+	}
+
+	expectMsg := func() {
+		t.Helper()
+		if err := mc.Publish("foo", []byte("hello")); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		select {
+		case <-received:
+		case <-time.After(time.Second):
+			t.Fatalf("Did not receive message")
+		}
+	}
+	expectMsg()
+
+	ps.Shutdown()
+	select {
+	case <-failover:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Did not fail over")
+	}
+	if !mc.IsFailedOver() || mc.Active() != mc.Standby() {
+		t.Fatalf("Expected standby to be active")
+	}
+	expectMsg()
+
+	ps = RunServerOnPort(primaryPort)
+	select {
+	case <-failback:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Did not fail back")
+	}
+	if n := atomic.LoadInt32(&reconcileAttempts); n != 2 {
+		t.Fatalf("Expected 2 reconcile attempts, got %d", n)
+	}
+	if mc.IsFailedOver() || mc.LastReconcileError() != nil {
+		t.Fatalf("Expected primary to be active")
+	}
+	expectMsg()
+
+	mc.Close()
+	if err := mc.Publish("foo", nil); err != nats.ErrMultiClusterClosed {
+		t.Fatalf("Expected error: %v; got: %v", nats.ErrMultiClusterClosed, err)
+	}
+}
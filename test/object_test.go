@@ -983,3 +983,109 @@ func TestDecodeObjectDigest(t *testing.T) {
 		})
 	}
 }
+
+func TestObjectGetReadSeeker(t *testing.T) {
+	s := RunBasicJetStreamServer()
+	defer shutdownJSServerAndRemoveStorage(t, s)
+
+	nc, js := jsClient(t, s)
+	defer nc.Close()
+
+	obs, err := js.CreateObjectStore(&nats.ObjectStoreConfig{Bucket: "OBJS"})
+	expectOk(t, err)
+
+	blob := make([]byte, 10*1024+100)
+	rand.Read(blob)
+	meta := &nats.ObjectMeta{Name: "BLOB", Opts: &nats.ObjectMetaOptions{ChunkSize: 1024}}
+	_, err = obs.Put(meta, bytes.NewReader(blob))
+	expectOk(t, err)
+
+	rs, err := obs.GetReadSeeker("BLOB")
+	expectOk(t, err)
+	defer rs.Close()
+
+	if info := rs.Info(); info.Size != uint64(len(blob)) || info.Chunks != 11 {
+		t.Fatalf("Unexpected object info: %+v", info)
+	}
+
+	// Read across chunk boundaries from the middle of the object.
+	pos, err := rs.Seek(1000, io.SeekStart)
+	expectOk(t, err)
+	if pos != 1000 {
+		t.Fatalf("Expected position 1000, got %d", pos)
+	}
+	buf := make([]byte, 2100)
+	_, err = io.ReadFull(rs, buf)
+	expectOk(t, err)
+	if !bytes.Equal(buf, blob[1000:3100]) {
+		t.Fatalf("Data read after seek does not match")
+	}
+
+	// Relative to the end.
+	pos, err = rs.Seek(-50, io.SeekEnd)
+	expectOk(t, err)
+	if pos != int64(len(blob)-50) {
+		t.Fatalf("Expected position %d, got %d", len(blob)-50, pos)
+	}
+	rest, err := io.ReadAll(rs)
+	expectOk(t, err)
+	if !bytes.Equal(rest, blob[len(blob)-50:]) {
+		t.Fatalf("Data read from the end does not match")
+	}
+
+	// ReadAt does not change the current position.
+	buf = make([]byte, 100)
+	n, err := rs.ReadAt(buf, 5000)
+	expectOk(t, err)
+	if n != 100 || !bytes.Equal(buf, blob[5000:5100]) {
+		t.Fatalf("Data read at offset does not match")
+	}
+	n, err = rs.ReadAt(buf, int64(len(blob)-10))
+	expectErr(t, err, io.EOF)
+	if n != 10 {
+		t.Fatalf("Expected 10 bytes, got %d", n)
+	}
+
+	// Read the whole thing.
+	_, err = rs.Seek(0, io.SeekStart)
+	expectOk(t, err)
+	all, err := io.ReadAll(rs)
+	expectOk(t, err)
+	if !bytes.Equal(all, blob) {
+		t.Fatalf("Result not the same")
+	}
+
+	_, err = rs.Seek(-1, io.SeekStart)
+	expectErr(t, err, nats.ErrInvalidObjectOffset)
+
+	expectOk(t, rs.Close())
+	_, err = rs.Read(buf)
+	expectErr(t, err, nats.ErrObjectReaderClosed)
+
+	// Links are followed.
+	info, err := obs.GetInfo("BLOB")
+	expectOk(t, err)
+	_, err = obs.AddLink("LINK", info)
+	expectOk(t, err)
+	lrs, err := obs.GetReadSeeker("LINK")
+	expectOk(t, err)
+	defer lrs.Close()
+	buf = make([]byte, 10)
+	_, err = lrs.ReadAt(buf, 2048)
+	expectOk(t, err)
+	if !bytes.Equal(buf, blob[2048:2058]) {
+		t.Fatalf("Data read through link does not match")
+	}
+
+	// Empty objects.
+	_, err = obs.PutBytes("EMPTY", nil)
+	expectOk(t, err)
+	ers, err := obs.GetReadSeeker("EMPTY")
+	expectOk(t, err)
+	defer ers.Close()
+	_, err = ers.Read(buf)
+	expectErr(t, err, io.EOF)
+
+	_, err = obs.GetReadSeeker("FOO")
+	expectErr(t, err, nats.ErrObjectNotFound)
+}
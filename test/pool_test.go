@@ -0,0 +1,126 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestConnPoolHealthEviction(t *testing.T) {
+	s := RunDefaultServer()
+	defer s.Shutdown()
+
+	opts := nats.GetDefaultOptions()
+	opts.Url = nats.DefaultURL
+
+	if _, err := opts.ConnectPool(0); err != nats.ErrInvalidPoolSize {
+		t.Fatalf("Expected error: %v; got: %v", nats.ErrInvalidPoolSize, err)
+	}
+
+	var warmups int32
+	received := make(chan struct{}, 10)
+	evicted := make(chan *nats.Conn, 1)
+	pool, err := opts.ConnectPool(3,
+		nats.PoolHealthCheckInterval(50*time.Millisecond),
+		nats.PoolMaxFailedChecks(1),
+		nats.PoolWarmup(func(nc *nats.Conn) error {
+			atomic.AddInt32(&warmups, 1)
+			_, err := nc.Subscribe("foo", func(*nats.Msg) {
+				received <- struct{}{}
+			})
+			return err
+		}),
+		nats.PoolEvictedCB(func(nc *nats.Conn, _ error) {
+			evicted <- nc
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer pool.Close()
+
+	if n := atomic.LoadInt32(&warmups); n != 3 {
+		t.Fatalf("Expected 3 warmups, got %d", n)
+	}
+
+	// Connections are handed out in a round robin fashion.
+	seen := make(map[*nats.Conn]struct{})
+	for i := 0; i < 3; i++ {
+		nc, err := pool.Get()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		seen[nc] = struct{}{}
+	}
+	if len(seen) != 3 {
+		t.Fatalf("Expected 3 distinct connections, got %d", len(seen))
+	}
+
+	// Break one of the members, it should be evicted and replaced.
+	bad, err := pool.Get()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	bad.Close()
+
+	select {
+	case nc := <-evicted:
+		if nc != bad {
+			t.Fatalf("Unexpected connection evicted")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Did not receive eviction callback")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt32(&warmups) != 4 || pool.Size() != 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Pool was not refilled, size: %d, warmups: %d", pool.Size(), atomic.LoadInt32(&warmups))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// All members, including the new one, carry the warmed up subscription.
+	for i := 0; i < 3; i++ {
+		nc, err := pool.Get()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if nc == bad {
+			t.Fatalf("Evicted connection should not be returned")
+		}
+	}
+	pub, err := pool.Get()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := pub.Publish("foo", []byte("hello")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case <-received:
+		case <-time.After(time.Second):
+			t.Fatalf("Expected message on every pool member, got %d", i)
+		}
+	}
+
+	pool.Close()
+	if _, err := pool.Get(); err != nats.ErrPoolClosed {
+		t.Fatalf("Expected error: %v; got: %v", nats.ErrPoolClosed, err)
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestReconfigure(t *testing.T) {
+	s := RunDefaultServer()
+	defer s.Shutdown()
+
+	nc, err := nats.Connect(nats.DefaultURL, nats.Name("daemon"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+
+	closed := make(chan struct{})
+	errs := make(chan error, 1)
+	err = nc.Reconfigure(
+		nats.Timeout(5*time.Second),
+		nats.DrainTimeout(time.Second),
+		nats.MaxReconnects(3),
+		nats.SyncQueueLen(10),
+		nats.ClosedHandler(func(*nats.Conn) { close(closed) }),
+		nats.ErrorHandler(func(_ *nats.Conn, _ *nats.Subscription, err error) { errs <- err }),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if nc.Opts.Timeout != 5*time.Second || nc.Opts.DrainTimeout != time.Second || nc.Opts.MaxReconnect != 3 || nc.Opts.SubChanLen != 10 {
+		t.Fatalf("Options were not applied: %+v", nc.Opts)
+	}
+
+	// Subscriptions created afterwards use the new pending limit.
+	sub, err := nc.SubscribeSync("foo")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		nc.Publish("foo", []byte("msg"))
+	}
+	nc.Flush()
+	select {
+	case err := <-errs:
+		if !errors.Is(err, nats.ErrSlowConsumer) {
+			t.Fatalf("Expected error: %v; got: %v", nats.ErrSlowConsumer, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected error handler to be invoked")
+	}
+	sub.Unsubscribe()
+
+	// None of the options is applied if one of them is not reconfigurable.
+	err = nc.Reconfigure(nats.Timeout(time.Second), nats.Name("other"))
+	if !errors.Is(err, nats.ErrOptionNotReconfigurable) {
+		t.Fatalf("Expected error: %v; got: %v", nats.ErrOptionNotReconfigurable, err)
+	}
+	if nc.Opts.Timeout != 5*time.Second || nc.Opts.Name != "daemon" {
+		t.Fatalf("Options should not be applied: %+v", nc.Opts)
+	}
+	if err := nc.Reconfigure(nats.UserInfo("user", "pass")); !errors.Is(err, nats.ErrOptionNotReconfigurable) {
+		t.Fatalf("Expected error: %v; got: %v", nats.ErrOptionNotReconfigurable, err)
+	}
+	if err := nc.Reconfigure(nats.PingInterval(0)); err == nil {
+		t.Fatalf("Expected error for ping interval")
+	}
+
+	nc.Close()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatalf("Expected closed handler to be invoked")
+	}
+	if err := nc.Reconfigure(nats.Timeout(time.Second)); !errors.Is(err, nats.ErrConnectionClosed) {
+		t.Fatalf("Expected error: %v; got: %v", nats.ErrConnectionClosed, err)
+	}
+}
+
+func TestReconfigurePingInterval(t *testing.T) {
+	s := RunDefaultServer()
+	defer s.Shutdown()
+
+	nc, err := nats.Connect(nats.DefaultURL, nats.PingInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+
+	// With no outstanding pings allowed, the first ping sent using
+	// the new interval marks the connection as stale.
+	errCh := make(chan error, 1)
+	err = nc.Reconfigure(
+		nats.PingInterval(20*time.Millisecond),
+		nats.MaxPingsOutstanding(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			select {
+			case errCh <- err:
+			default:
+			}
+		}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, nats.ErrStaleConnection) {
+			t.Fatalf("Expected error: %v; got: %v", nats.ErrStaleConnection, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected ping to be sent using the new interval")
+	}
+}
+
+func TestReconfigureHandlersWhileReconnecting(t *testing.T) {
+	s := RunDefaultServer()
+	defer s.Shutdown()
+
+	reconnected := make(chan struct{}, 1)
+	onReconnect := func(*nats.Conn) {
+		select {
+		case reconnected <- struct{}{}:
+		default:
+		}
+	}
+	nc, err := nats.Connect(nats.DefaultURL,
+		nats.ReconnectWait(10*time.Millisecond),
+		nats.MaxReconnects(-1),
+		nats.ReconnectHandler(onReconnect),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+
+	// Keep setting and clearing the handlers while they are invoked,
+	// neither must race with the callbacks nor make them panic.
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		noop := func(*nats.Conn) {}
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if i%2 == 0 {
+				nc.Reconfigure(
+					nats.DisconnectHandler(noop),
+					nats.ClosedHandler(noop),
+					nats.Timeout(time.Second),
+				)
+			} else {
+				nc.Reconfigure(
+					nats.DisconnectHandler(nil),
+					nats.ClosedHandler(nil),
+					nats.Timeout(2*time.Second),
+				)
+			}
+		}
+	}()
+
+	for i := 0; i < 3; i++ {
+		s.Shutdown()
+		s = RunDefaultServer()
+		select {
+		case <-reconnected:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected reconnect")
+		}
+	}
+	nc.Close()
+	close(done)
+	<-stopped
+}
@@ -14,6 +14,7 @@
 package test
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"net/url"
@@ -785,6 +786,79 @@ func TestConnCloseNoCallback(t *testing.T) {
 	}
 }
 
+func TestFailFastOnDisconnect(t *testing.T) {
+	s := RunDefaultServer()
+	defer s.Shutdown()
+
+	dch := make(chan bool)
+	nc, err := nats.Connect(nats.DefaultURL,
+		nats.DisconnectErrHandler(func(_ *nats.Conn, _ error) {
+			dch <- true
+		}))
+	if err != nil {
+		t.Fatalf("Should have connected ok: %v", err)
+	}
+	defer nc.Close()
+
+	ncff, err := nats.Connect(nats.DefaultURL,
+		nats.FailFastOnDisconnect(true),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, _ error) {
+			dch <- true
+		}))
+	if err != nil {
+		t.Fatalf("Should have connected ok: %v", err)
+	}
+	defer ncff.Close()
+
+	if !nc.IsOperational() || !ncff.IsOperational() {
+		t.Fatalf("Expected connections to be operational")
+	}
+
+	// Force disconnected state.
+	s.Shutdown()
+	for i := 0; i < 2; i++ {
+		if e := Wait(dch); e != nil {
+			t.Fatal("DisconnectedErrCB should have been triggered")
+		}
+	}
+	if nc.IsOperational() {
+		t.Fatalf("Expected connection not to be operational while reconnecting")
+	}
+
+	// Buffered by default.
+	if err := nc.Publish("foo", []byte("buffered")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := ncff.Publish("foo", []byte("food")); err != nats.ErrDisconnected {
+		t.Fatalf("Expected error: %v; got: %v", nats.ErrDisconnected, err)
+	}
+	if _, err := ncff.Request("foo", []byte("food"), time.Second); err != nats.ErrDisconnected {
+		t.Fatalf("Expected error: %v; got: %v", nats.ErrDisconnected, err)
+	}
+
+	// Selected per call.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg := nats.NewMsg("foo")
+	msg.Data = []byte("food")
+	if err := nc.PublishMsgWithContext(nats.FailFastContext(ctx), msg); err != nats.ErrDisconnected {
+		t.Fatalf("Expected error: %v; got: %v", nats.ErrDisconnected, err)
+	}
+	start := time.Now()
+	if _, err := nc.RequestWithContext(nats.FailFastContext(ctx), "foo", []byte("food")); err != nats.ErrDisconnected {
+		t.Fatalf("Expected error: %v; got: %v", nats.ErrDisconnected, err)
+	}
+	if time.Since(start) > 100*time.Millisecond {
+		t.Fatalf("Expected request to fail immediately")
+	}
+	if err := nc.PublishMsgWithContext(ctx, msg); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, _ := nc.Buffered(); got == 0 {
+		t.Fatalf("Expected publishes to be buffered")
+	}
+}
+
 func TestReconnectBufSizeDisable(t *testing.T) {
 	s := RunDefaultServer()
 	defer s.Shutdown()
@@ -0,0 +1,124 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	natsserver "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+)
+
+func TestServerInfoChangeOnReconnect(t *testing.T) {
+	opts := natsserver.DefaultTestOptions
+	opts.Port = 8232
+	opts.MaxPayload = 1024 * 1024
+	s := RunServerWithOptions(opts)
+	defer s.Shutdown()
+
+	changes := make(chan nats.ServerInfoChange, 10)
+	nc, err := nats.Connect(s.ClientURL(),
+		nats.ReconnectWait(50*time.Millisecond),
+		nats.ServerInfoChangeHandler(func(_ *nats.Conn, change nats.ServerInfoChange) {
+			changes <- change
+		}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+
+	// no change is reported for the initial connect
+	select {
+	case change := <-changes:
+		t.Fatalf("Unexpected change: %+v", change)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	s.Shutdown()
+	opts.MaxPayload = 512
+	s = RunServerWithOptions(opts)
+	defer s.Shutdown()
+
+	select {
+	case change := <-changes:
+		if !change.MaxPayloadShrank() || change.PrevMaxPayload != 1024*1024 || change.MaxPayload != 512 {
+			t.Fatalf("Unexpected change: %+v", change)
+		}
+		if change.PrevServerID == change.ServerID || change.ServerID != s.ID() {
+			t.Fatalf("Expected change of server ID: %+v", change)
+		}
+		if change.VersionChanged() {
+			t.Fatalf("Unexpected version change: %+v", change)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected server info change")
+	}
+	if nc.MaxPayload() != 512 {
+		t.Fatalf("Unexpected max payload: %d", nc.MaxPayload())
+	}
+}
+
+func TestServerInfoChangeOnClusterUpdate(t *testing.T) {
+	s1Opts := natsserver.DefaultTestOptions
+	s1Opts.Host = "127.0.0.1"
+	s1Opts.Port = 4222
+	s1Opts.Cluster.Name = "testing"
+	s1Opts.Cluster.Host = "127.0.0.1"
+	s1Opts.Cluster.Port = 6222
+	s1 := RunServerWithOptions(s1Opts)
+	defer s1.Shutdown()
+
+	changes := make(chan nats.ServerInfoChange, 10)
+	nc, err := nats.Connect(s1.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	nc.SetServerInfoChangeHandler(func(_ *nats.Conn, change nats.ServerInfoChange) {
+		changes <- change
+	})
+
+	s2Opts := natsserver.DefaultTestOptions
+	s2Opts.Host = "127.0.0.1"
+	s2Opts.Port = 4223
+	s2Opts.Cluster.Name = "testing"
+	s2Opts.Cluster.Host = "127.0.0.1"
+	s2Opts.Cluster.Port = 6223
+	s2Opts.Routes = server.RoutesFromStr("nats://127.0.0.1:6222")
+	s2 := RunServerWithOptions(s2Opts)
+
+	select {
+	case change := <-changes:
+		if len(change.AddedURLs) != 1 || change.AddedURLs[0] != "127.0.0.1:4223" || len(change.RemovedURLs) != 0 {
+			t.Fatalf("Unexpected change: %+v", change)
+		}
+		if change.PrevServerID != change.ServerID {
+			t.Fatalf("Unexpected change of server: %+v", change)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected server info change")
+	}
+
+	s2.Shutdown()
+	select {
+	case change := <-changes:
+		if len(change.RemovedURLs) != 1 || change.RemovedURLs[0] != "127.0.0.1:4223" || len(change.AddedURLs) != 0 {
+			t.Fatalf("Unexpected change: %+v", change)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected server info change")
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestShutdownGracefully(t *testing.T) {
+	s := RunDefaultServer()
+	defer s.Shutdown()
+
+	// Simulates a stream acknowledging publishes slowly.
+	stream := NewDefaultConnection(t)
+	defer stream.Close()
+	var seq int
+	if _, err := stream.Subscribe("slow", func(m *nats.Msg) {
+		seq++
+		ack := fmt.Sprintf(`{"stream":"SLOW","seq":%d}`, seq)
+		time.AfterFunc(200*time.Millisecond, func() {
+			m.Respond([]byte(ack))
+		})
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Never acknowledges publishes.
+	if _, err := stream.Subscribe("blackhole", func(*nats.Msg) {}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	stream.Flush()
+
+	t.Run("waits for async publishes", func(t *testing.T) {
+		nc := NewDefaultConnection(t)
+		defer nc.Close()
+		js, err := nc.JetStream()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var futures []nats.PubAckFuture
+		for i := 0; i < 10; i++ {
+			f, err := js.PublishAsync("slow", []byte("hello"))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			futures = append(futures, f)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if err := nc.ShutdownGracefully(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !nc.IsClosed() {
+			t.Fatalf("Expected connection to be closed")
+		}
+		for _, f := range futures {
+			select {
+			case <-f.Ok():
+			default:
+				t.Fatalf("Expected publish to be acknowledged before close")
+			}
+		}
+		if err := nc.ShutdownGracefully(ctx); err != nats.ErrConnectionClosed {
+			t.Fatalf("Expected error: %v; got: %v", nats.ErrConnectionClosed, err)
+		}
+	})
+
+	t.Run("context expires", func(t *testing.T) {
+		nc := NewDefaultConnection(t)
+		defer nc.Close()
+		js, err := nc.JetStream()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := js.PublishAsync("blackhole", []byte("hello")); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var hookCalled bool
+		nc.AddShutdownHook(func(context.Context) error {
+			hookCalled = true
+			return nil
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		if err := nc.ShutdownGracefully(ctx); err != context.DeadlineExceeded {
+			t.Fatalf("Expected error: %v; got: %v", context.DeadlineExceeded, err)
+		}
+		if !nc.IsClosed() {
+			t.Fatalf("Expected connection to be closed")
+		}
+		if hookCalled {
+			t.Fatalf("Hooks should not be called after the context expired")
+		}
+	})
+
+	t.Run("removed hooks", func(t *testing.T) {
+		nc := NewDefaultConnection(t)
+		defer nc.Close()
+		var called []int
+		for i := 0; i < 3; i++ {
+			i := i
+			remove := nc.AddShutdownHook(func(context.Context) error {
+				called = append(called, i)
+				return nil
+			})
+			if i == 1 {
+				remove()
+				// Removing a hook twice is a no-op.
+				remove()
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if err := nc.ShutdownGracefully(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(called) != 2 || called[0] != 0 || called[1] != 2 {
+			t.Fatalf("Expected hooks 0 and 2 to be called; got: %v", called)
+		}
+	})
+}
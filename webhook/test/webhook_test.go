@@ -0,0 +1,154 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/internal/testutil"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/nats-io/nats.go/webhook"
+)
+
+func TestWebhookHandler(t *testing.T) {
+	srv := testutil.RunBasicJetStreamServer(t)
+	defer testutil.ShutdownJSServerAndRemoveStorage(t, srv)
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer nc.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "HOOKS", Subjects: []string{"hooks.>"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := webhook.NewHandler(js, webhook.Config{Subject: "hooks.github"}); !errors.Is(err, webhook.ErrConfigValidation) {
+		t.Fatalf("Expected error: %v; got: %v", webhook.ErrConfigValidation, err)
+	}
+	var rejected []error
+	h, err := webhook.NewHandler(js, webhook.Config{
+		SubjectFunc: func(r *http.Request) string {
+			return "hooks.github." + r.Header.Get("X-GitHub-Event")
+		},
+		Verifier:    webhook.GitHub([]byte("secret")),
+		Headers:     []string{"Content-Type", "X-GitHub-Event"},
+		MaxBodySize: 64,
+		ErrorHandler: func(_ *http.Request, err error) {
+			rejected = append(rejected, err)
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+
+	post := func(body, signature string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-GitHub-Event", "push")
+		req.Header.Set("X-GitHub-Delivery", "delivery-1")
+		if signature != "" {
+			req.Header.Set("X-Hub-Signature-256", signature)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return resp
+	}
+	sign := func(body string) string {
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write([]byte(body))
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	body := `{"ref":"refs/heads/main"}`
+	resp := post(body, sign(body))
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected status 202; got: %d", resp.StatusCode)
+	}
+	var res webhook.Response
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if res.Stream != "HOOKS" || res.Sequence != 1 || res.Duplicate {
+		t.Fatalf("Unexpected response: %+v", res)
+	}
+
+	// Redelivery is deduplicated by the delivery ID.
+	resp = post(body, sign(body))
+	json.NewDecoder(resp.Body).Decode(&res)
+	resp.Body.Close()
+	if !res.Duplicate {
+		t.Fatalf("Expected duplicate; got: %+v", res)
+	}
+
+	msg, err := s.GetMsg(ctx, 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if msg.Subject != "hooks.github.push" || string(msg.Data) != body {
+		t.Fatalf("Unexpected message: %s %s", msg.Subject, msg.Data)
+	}
+	if msg.Header.Get("X-GitHub-Event") != "push" || msg.Header.Get(jetstream.MsgIDHeader) != "delivery-1" {
+		t.Fatalf("Unexpected headers: %v", msg.Header)
+	}
+
+	if resp := post(body, sign("other")); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected status 401; got: %d", resp.StatusCode)
+	}
+	large := `{"data":"` + strings.Repeat("x", 64) + `"}`
+	if resp := post(large, sign(large)); resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected status 413; got: %d", resp.StatusCode)
+	}
+	if resp, err := http.Get(ts.URL); err != nil || resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("Expected status 405; got: %v, %v", resp, err)
+	}
+	if len(rejected) != 2 || !errors.Is(rejected[0], webhook.ErrInvalidSignature) || !errors.Is(rejected[1], webhook.ErrBodyTooLarge) {
+		t.Fatalf("Unexpected rejections: %v", rejected)
+	}
+
+	// Publishing fails without a stream bound to the subject.
+	if err := js.DeleteStream(ctx, "HOOKS"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resp := post(body, sign(body)); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status 503; got: %d", resp.StatusCode)
+	}
+}
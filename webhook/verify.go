@@ -0,0 +1,134 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// Verifier verifies the signature of a webhook request.
+	Verifier interface {
+		// Verify returns an error wrapping [ErrInvalidSignature] if the
+		// request signature does not match the body.
+		Verify(r *http.Request, body []byte) error
+	}
+
+	// VerifierFunc is an adapter allowing a function to be used as a [Verifier].
+	VerifierFunc func(r *http.Request, body []byte) error
+
+	hmacVerifier struct {
+		secret []byte
+		header string
+		prefix string
+	}
+
+	stripeVerifier struct {
+		secret    []byte
+		tolerance time.Duration
+		now       func() time.Time
+	}
+)
+
+// Verify calls f(r, body).
+func (f VerifierFunc) Verify(r *http.Request, body []byte) error {
+	return f(r, body)
+}
+
+// NoVerify is a [Verifier] accepting all requests.
+var NoVerify Verifier = VerifierFunc(func(*http.Request, []byte) error { return nil })
+
+// HMACSHA256 returns a [Verifier] expecting the header to hold the given
+// prefix followed by the hex encoded HMAC-SHA256 of the body.
+func HMACSHA256(secret []byte, header, prefix string) Verifier {
+	return &hmacVerifier{secret: secret, header: header, prefix: prefix}
+}
+
+// GitHub returns a [Verifier] of GitHub webhook signatures, sent in
+// the X-Hub-Signature-256 header.
+func GitHub(secret []byte) Verifier {
+	return HMACSHA256(secret, "X-Hub-Signature-256", "sha256=")
+}
+
+// Stripe returns a [Verifier] of Stripe webhook signatures, sent in the
+// Stripe-Signature header. Requests signed longer than tolerance ago are
+// rejected to prevent replays; zero tolerance disables the check.
+func Stripe(secret []byte, tolerance time.Duration) Verifier {
+	return &stripeVerifier{secret: secret, tolerance: tolerance, now: time.Now}
+}
+
+func (v *hmacVerifier) Verify(r *http.Request, body []byte) error {
+	sig := r.Header.Get(v.header)
+	if !strings.HasPrefix(sig, v.prefix) {
+		return fmt.Errorf("%w: missing %s header", ErrInvalidSignature, v.header)
+	}
+	if !validMAC(v.secret, body, strings.TrimPrefix(sig, v.prefix)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (v *stripeVerifier) Verify(r *http.Request, body []byte) error {
+	var timestamp string
+	var sigs []string
+	for _, part := range strings.Split(r.Header.Get("Stripe-Signature"), ",") {
+		k, val, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			timestamp = val
+		case "v1":
+			sigs = append(sigs, val)
+		}
+	}
+	if timestamp == "" || len(sigs) == 0 {
+		return fmt.Errorf("%w: missing Stripe-Signature header", ErrInvalidSignature)
+	}
+	if v.tolerance > 0 {
+		ts, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: invalid timestamp", ErrInvalidSignature)
+		}
+		if age := v.now().Sub(time.Unix(ts, 0)); age > v.tolerance || age < -v.tolerance {
+			return fmt.Errorf("%w: timestamp outside of tolerance", ErrInvalidSignature)
+		}
+	}
+	signed := make([]byte, 0, len(timestamp)+1+len(body))
+	signed = append(signed, timestamp...)
+	signed = append(signed, '.')
+	signed = append(signed, body...)
+	for _, sig := range sigs {
+		if validMAC(v.secret, signed, sig) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// validMAC reports whether sig is the hex encoded HMAC-SHA256 of data.
+func validMAC(secret, data []byte, sig string) bool {
+	expected, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(data)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
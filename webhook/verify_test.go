@@ -0,0 +1,87 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func sign(secret, data string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(data))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestGitHubVerifier(t *testing.T) {
+	body := []byte(`{"action":"opened"}`)
+	v := GitHub([]byte("secret"))
+
+	tests := []struct {
+		name   string
+		header string
+		err    error
+	}{
+		{name: "valid", header: "sha256=" + sign("secret", string(body))},
+		{name: "wrong secret", header: "sha256=" + sign("other", string(body)), err: ErrInvalidSignature},
+		{name: "missing", err: ErrInvalidSignature},
+		{name: "malformed", header: "sha256=zz", err: ErrInvalidSignature},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/", nil)
+			if test.header != "" {
+				r.Header.Set("X-Hub-Signature-256", test.header)
+			}
+			if err := v.Verify(r, body); !errors.Is(err, test.err) {
+				t.Fatalf("Expected error: %v; got: %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestStripeVerifier(t *testing.T) {
+	body := `{"id":"evt_1"}`
+	now := time.Unix(1700000000, 0)
+	v := &stripeVerifier{secret: []byte("whsec"), tolerance: 5 * time.Minute, now: func() time.Time { return now }}
+
+	tests := []struct {
+		name   string
+		header string
+		err    error
+	}{
+		{name: "valid", header: fmt.Sprintf("t=%d,v1=%s", now.Unix(), sign("whsec", fmt.Sprintf("%d.%s", now.Unix(), body)))},
+		{name: "one of many valid", header: fmt.Sprintf("t=%d,v1=00,v1=%s", now.Unix(), sign("whsec", fmt.Sprintf("%d.%s", now.Unix(), body)))},
+		{name: "expired", header: fmt.Sprintf("t=%d,v1=%s", now.Unix()-600, sign("whsec", fmt.Sprintf("%d.%s", now.Unix()-600, body))), err: ErrInvalidSignature},
+		{name: "tampered timestamp", header: fmt.Sprintf("t=%d,v1=%s", now.Unix()+1, sign("whsec", fmt.Sprintf("%d.%s", now.Unix(), body))), err: ErrInvalidSignature},
+		{name: "missing", err: ErrInvalidSignature},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/", nil)
+			if test.header != "" {
+				r.Header.Set("Stripe-Signature", test.header)
+			}
+			if err := v.Verify(r, []byte(body)); !errors.Is(err, test.err) {
+				t.Fatalf("Expected error: %v; got: %v", test.err, err)
+			}
+		})
+	}
+}
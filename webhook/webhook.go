@@ -0,0 +1,196 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package webhook provides an HTTP handler ingesting webhooks into
+// JetStream. Request signatures are verified before the payload is
+// published, using an idempotency key as the message ID so that
+// redelivered webhooks are deduplicated by the stream.
+package webhook
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Notice: Experimental Preview
+//
+// This functionality is EXPERIMENTAL and may be changed in later releases.
+
+type (
+	// Config is a configuration of a webhook handler.
+	Config struct {
+		// Subject is the subject payloads are published on. It has to be
+		// bound to a stream. Ignored if SubjectFunc is set.
+		Subject string
+
+		// SubjectFunc returns the subject for a request, e.g. based on the
+		// event type header.
+		SubjectFunc func(r *http.Request) string
+
+		// Verifier verifies request signatures. Required, use [NoVerify]
+		// to accept unsigned requests.
+		Verifier Verifier
+
+		// IdempotencyKey returns the message ID for a request. Defaults to
+		// [DefaultIdempotencyKey].
+		IdempotencyKey func(r *http.Request, body []byte) string
+
+		// Headers lists request headers copied to the published message.
+		// Defaults to Content-Type.
+		Headers []string
+
+		// MaxBodySize is the maximum accepted payload size in bytes.
+		// Defaults to 1MB.
+		MaxBodySize int64
+
+		// PublishTimeout limits the time spent publishing a payload.
+		// Defaults to 5s.
+		PublishTimeout time.Duration
+
+		// ErrorHandler is invoked when a request is rejected or publishing fails.
+		ErrorHandler func(r *http.Request, err error)
+	}
+
+	// Response is the JSON body of successful responses.
+	Response struct {
+		Stream    string `json:"stream"`
+		Sequence  uint64 `json:"seq"`
+		Duplicate bool   `json:"duplicate,omitempty"`
+	}
+
+	handler struct {
+		js  jetstream.Publisher
+		cfg Config
+	}
+)
+
+const (
+	DefaultMaxBodySize    = 1024 * 1024
+	DefaultPublishTimeout = 5 * time.Second
+)
+
+var (
+	// ErrConfigValidation is returned when handler configuration is invalid.
+	ErrConfigValidation = errors.New("validation")
+
+	// ErrInvalidSignature is returned by verifiers when a request signature is missing or invalid.
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+
+	// ErrBodyTooLarge is passed to the error handler when a payload exceeds MaxBodySize.
+	ErrBodyTooLarge = errors.New("webhook body too large")
+)
+
+// NewHandler returns an HTTP handler accepting POST requests, verifying
+// their signature and publishing their body. It responds with 202 and a
+// [Response] once the payload is stored, 401 if the signature is invalid,
+// 413 if the body is too large and 503 if publishing failed, in which case
+// the sender is expected to redeliver.
+func NewHandler(js jetstream.Publisher, config Config) (http.Handler, error) {
+	if config.Subject == "" && config.SubjectFunc == nil {
+		return nil, fmt.Errorf("%w: subject is required", ErrConfigValidation)
+	}
+	if config.Verifier == nil {
+		return nil, fmt.Errorf("%w: verifier is required", ErrConfigValidation)
+	}
+	if config.MaxBodySize < 0 {
+		return nil, fmt.Errorf("%w: max body size cannot be negative", ErrConfigValidation)
+	}
+	if config.PublishTimeout < 0 {
+		return nil, fmt.Errorf("%w: publish timeout cannot be negative", ErrConfigValidation)
+	}
+	if config.IdempotencyKey == nil {
+		config.IdempotencyKey = DefaultIdempotencyKey
+	}
+	if config.Headers == nil {
+		config.Headers = []string{"Content-Type"}
+	}
+	if config.MaxBodySize == 0 {
+		config.MaxBodySize = DefaultMaxBodySize
+	}
+	if config.PublishTimeout == 0 {
+		config.PublishTimeout = DefaultPublishTimeout
+	}
+	return &handler{js: js, cfg: config}, nil
+}
+
+// DefaultIdempotencyKey uses the Idempotency-Key or X-GitHub-Delivery
+// request header if present, and the SHA-256 digest of the body otherwise.
+func DefaultIdempotencyKey(r *http.Request, body []byte) string {
+	for _, h := range []string{"Idempotency-Key", "X-GitHub-Delivery"} {
+		if key := r.Header.Get(h); key != "" {
+			return key
+		}
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, h.cfg.MaxBodySize+1))
+	if err != nil {
+		h.fail(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if int64(len(body)) > h.cfg.MaxBodySize {
+		h.fail(w, r, http.StatusRequestEntityTooLarge, ErrBodyTooLarge)
+		return
+	}
+	if err := h.cfg.Verifier.Verify(r, body); err != nil {
+		h.fail(w, r, http.StatusUnauthorized, err)
+		return
+	}
+
+	subject := h.cfg.Subject
+	if h.cfg.SubjectFunc != nil {
+		subject = h.cfg.SubjectFunc(r)
+	}
+	msg := nats.NewMsg(subject)
+	msg.Data = body
+	for _, name := range h.cfg.Headers {
+		for _, v := range r.Header.Values(name) {
+			msg.Header.Add(name, v)
+		}
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.PublishTimeout)
+	defer cancel()
+	ack, err := h.js.PublishMsg(ctx, msg, jetstream.WithMsgID(h.cfg.IdempotencyKey(r, body)))
+	if err != nil {
+		h.fail(w, r, http.StatusServiceUnavailable, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(Response{Stream: ack.Stream, Sequence: ack.Sequence, Duplicate: ack.Duplicate})
+}
+
+func (h *handler) fail(w http.ResponseWriter, r *http.Request, code int, err error) {
+	if h.cfg.ErrorHandler != nil {
+		h.cfg.ErrorHandler(r, err)
+	}
+	http.Error(w, http.StatusText(code), code)
+}